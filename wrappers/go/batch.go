@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Wire formats of --batch and --listen, selected by --format.
+const (
+	formatJSON = "json"
+	// formatCBOR carries each request and Result as a CBOR map (RFC 8949)
+	// with the keys of their JSON objects. Request fields read as bytes
+	// accept byte strings, and binary result fields are byte strings
+	// unless the request sets "output_encoding".
+	formatCBOR = "cbor"
+	// formatProtobuf carries InvokeRequest and InvokeResponse messages of
+	// proto/wrapper.proto, each preceded by its length as a varint. It
+	// applies to --batch only.
+	formatProtobuf = "protobuf"
+)
+
+// errNoStdin is what "stdin": true reads in batch and server modes, where
+// standard input carries the requests.
+var errNoStdin = errors.New("\"stdin\" is not available in batch and server modes")
+
+type errReader struct{ err error }
+
+func (r errReader) Read([]byte) (int, error) { return 0, r.err }
+
+// runBatch answers the requests on standard input, one JSON object per
+// line, with one Result per line in the same order. Each request names its
+// operation in "algorithm" and "operation", which default to those on the
+// command line. Blank lines are skipped. A failed request only fails its
+// own line: the exit code is 0 once the input is exhausted and reports
+// only I/O errors.
+func runBatch(inv *invocation, stdout io.Writer) int {
+	return serveLines(stdout, func(line []byte) interface{} {
+		return batchRequest(inv, line)
+	})
+}
+
+// serveLines passes every non-blank line of standard input to answer and
+// writes the replies that are not nil to stdout, one per line. "stdin":
+// true fails meanwhile, since standard input carries the requests. It
+// returns the exit code: 0 at the end of the input, 1 on an I/O error.
+func serveLines(stdout io.Writer, answer func(line []byte) interface{}) int {
+	r := bufio.NewReader(stdin)
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = errReader{errNoStdin}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if reply := answer(line); reply != nil {
+				if err := enc.Encode(reply); err != nil {
+					fmt.Fprintln(os.Stderr, err)
+					return 1
+				}
+			}
+		}
+		if err == io.EOF {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+}
+
+// batchRequest runs the request on one line of batch input or in one
+// frame of the socket mode.
+func batchRequest(inv *invocation, line []byte) *Result {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var in map[string]interface{}
+	if err := dec.Decode(&in); err != nil {
+		return errorResult(&codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)})
+	}
+	if in == nil || dec.More() {
+		return errorResult(&codedError{codeInvalidRequest, errors.New("invalid request: expected one JSON object")})
+	}
+	algorithm, operation, err := requestOperation(inv, in)
+	if err != nil {
+		return errorResult(err)
+	}
+	if inv.remote {
+		return serverDispatch(algorithm, operation, in)
+	}
+	return dispatch(algorithm, operation, in)
+}
+
+// requestOperation takes "algorithm" and "operation" out of a batch
+// request, defaulting to those of inv.
+func requestOperation(inv *invocation, in map[string]interface{}) (algorithm, operation string, err error) {
+	algorithm, operation = inv.algorithm, inv.operation
+	for name, dst := range map[string]*string{"algorithm": &algorithm, "operation": &operation} {
+		s, ok, err := stringField(in, name)
+		if err != nil {
+			return "", "", err
+		}
+		if ok {
+			*dst = strings.ToLower(s)
+			delete(in, name)
+		}
+	}
+	if algorithm == "" || operation == "" {
+		return "", "", &codedError{codeInvalidRequest, errors.New("request without \"algorithm\" and \"operation\"")}
+	}
+	return algorithm, operation, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+
+	batch := func(args []string, input string) []Result {
+		t.Helper()
+		stdin = strings.NewReader(input)
+		var out bytes.Buffer
+		if code := run(args, &out); code != 0 {
+			t.Fatalf("%q exited %d: %s", args, code, out.String())
+		}
+		var results []Result
+		sc := bufio.NewScanner(&out)
+		for sc.Scan() {
+			var res Result
+			if err := json.Unmarshal(sc.Bytes(), &res); err != nil {
+				t.Fatalf("output line is not JSON: %q", sc.Text())
+			}
+			results = append(results, res)
+		}
+		return results
+	}
+
+	res := batch([]string{"--batch"}, `{"algorithm": "sm3", "operation": "hash", "data": "abc"}
+
+{"algorithm": "SM3", "operation": "hash", "data": "abc", "output_encoding": "base64"}
+not json
+{"algorithm": "sm3"}
+{"algorithm": "sm3", "operation": "hash", "stdin": true}
+[1]
+{"algorithm": "sm2", "operation": "keygen"}`)
+	if len(res) != 7 {
+		t.Fatalf("%d results, want 7: %+v", len(res), res)
+	}
+	if res[0].Output != abc || res[1].Output != "Zsfw9GLu7dnR8tRr3BDk4kFnxIdc8veiKX2gK49LqOA=" {
+		t.Errorf("hash results: %+v %+v", res[0], res[1])
+	}
+	for i := 2; i <= 5; i++ {
+		if res[i].Status != statusError {
+			t.Errorf("line %d succeeded: %+v", i, res[i])
+		}
+	}
+	if res[6].Status != statusSuccess || res[6].PrivateKey == "" {
+		t.Errorf("keygen: %+v", res[6])
+	}
+
+	// The command line supplies a default operation.
+	res = batch([]string{"sm3", "hash", "--batch"}, "{\"data\": \"abc\"}\n{\"data\": \"abc\", \"algorithm\": \"sm9\"}\n")
+	if len(res) != 2 || res[0].Output != abc || res[1].Status != statusError {
+		t.Errorf("default operation: %+v", res)
+	}
+
+	var out bytes.Buffer
+	if code := run([]string{"--batch", "--input", "{}"}, &out); code == 0 {
+		t.Error("--batch with --input was accepted")
+	}
+}
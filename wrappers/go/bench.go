@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	mrand "math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const benchUsage = "bench [--algorithm sm2|sm3|sm4|sm9|zuc|all] [--duration D] [--sizes N,...] [--output <file>]"
+
+// benchCase is an operation measured by bench, with the options that
+// select what it runs, such as the SM4 mode.
+type benchCase struct {
+	algorithm, operation string
+	mode                 string
+	// sized cases are measured at each payload size of at least
+	// minSize; the others, such as key generation, once.
+	sized   bool
+	minSize int
+	// request returns the request to repeat for a payload of n bytes,
+	// with keys and inputs drawn from g.
+	request func(g *vectorGen, n int) (map[string]interface{}, error)
+}
+
+// benchReport is the output of bench. The build fields identify what was
+// measured, to compare runs across library upgrades.
+type benchReport struct {
+	Wrapper  string        `json:"wrapper"`
+	Library  libraryInfo   `json:"library"`
+	Go       string        `json:"go"`
+	Platform string        `json:"platform"`
+	Duration string        `json:"duration"`
+	Results  []benchResult `json:"results"`
+}
+
+// benchResult is the throughput of a case at a payload size, or the
+// error that stopped it.
+type benchResult struct {
+	Algorithm  string  `json:"algorithm"`
+	Operation  string  `json:"operation"`
+	Mode       string  `json:"mode,omitempty"`
+	Size       int     `json:"size"`
+	Iterations int     `json:"iterations"`
+	NsPerOp    int64   `json:"ns_per_op"`
+	OpsPerSec  float64 `json:"ops_per_sec"`
+	MBPerSec   float64 `json:"mb_per_sec,omitempty"`
+	Error      string  `json:"error,omitempty"`
+}
+
+// benchCommand measures the operations of the selected algorithms at each
+// payload size and writes a benchReport. Requests go through dispatch as
+// they do in the other modes, so the figures include the wrapper's
+// decoding and encoding. --duration is the time of the whole run, shared
+// evenly by the measurements. The exit code is exitFailure if a case
+// failed.
+func benchCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("bench", flag.ContinueOnError)
+	algorithm := fs.String("algorithm", "all", "algorithm to measure: sm2, sm3, sm4, sm9, zuc or all")
+	duration := fs.Duration("duration", 10*time.Second, "time of the whole run")
+	sizeList := fs.String("sizes", "16,1024,65536", "payload sizes in bytes, comma-separated")
+	output := fs.String("output", "", "file to write instead of standard output")
+	if ok, code := parseCommand(fs, benchUsage, args, 0, stdout); !ok {
+		return code
+	}
+	algorithms := map[string]bool{}
+	var cases []benchCase
+	for _, c := range benchCases() {
+		algorithms[c.algorithm] = true
+		if *algorithm == "all" || c.algorithm == *algorithm {
+			cases = append(cases, c)
+		}
+	}
+	if *algorithm != "all" && !algorithms[*algorithm] {
+		return commandFailed(stdout, fmt.Errorf("unsupported --algorithm %q (supported: %s, all)", *algorithm, strings.Join(sortedKeys(algorithms), ", ")))
+	}
+	if *duration <= 0 {
+		return commandFailed(stdout, fmt.Errorf("--duration must be positive, got %v", *duration))
+	}
+	var sizes []int
+	for _, s := range strings.Split(*sizeList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 0 {
+			return commandFailed(stdout, fmt.Errorf("--sizes must be non-negative integers, got %q", s))
+		}
+		sizes = append(sizes, n)
+	}
+
+	runs := 0
+	for _, c := range cases {
+		if !c.sized {
+			runs++
+		}
+		for _, n := range sizes {
+			if c.sized && n >= c.minSize {
+				runs++
+			}
+		}
+	}
+	each := *duration / time.Duration(max(runs, 1))
+	info := buildVersion()
+	report := &benchReport{Wrapper: info.Wrapper, Library: info.Library, Go: info.Go, Platform: info.Platform,
+		Duration: duration.String(), Results: []benchResult{}}
+	// The keys and inputs are drawn apart from the wrapper's randomness
+	// source, which stays the system's: seeding it would change what
+	// signing and encryption cost.
+	g := &vectorGen{r: mrand.New(mrand.NewPCG(1, 2))}
+	failed := false
+	for _, c := range cases {
+		caseSizes := sizes
+		if !c.sized {
+			caseSizes = []int{0}
+		}
+		for _, n := range caseSizes {
+			if n < c.minSize {
+				continue
+			}
+			r := benchResult{Algorithm: c.algorithm, Operation: c.operation, Mode: c.mode, Size: n}
+			in, err := c.request(g, n)
+			if err == nil {
+				err = measure(&r, in, each)
+			}
+			if err != nil {
+				r.Error, failed = err.Error(), true
+			}
+			report.Results = append(report.Results, r)
+		}
+	}
+
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return commandFailed(stdout, &codedError{codeIO, err})
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return commandFailed(stdout, &codedError{codeIO, err})
+	}
+	if failed {
+		return exitFailure
+	}
+	return exitSuccess
+}
+
+// measure repeats the request in of r for d, after a first call that is
+// not timed, and records the throughput in r. Every call must succeed.
+func measure(r *benchResult, in map[string]interface{}, d time.Duration) error {
+	call := func() error {
+		if res := dispatch(r.Algorithm, r.Operation, maps.Clone(in)); res.Status != statusSuccess {
+			return fmt.Errorf("%s %s failed: %s", r.Algorithm, r.Operation, res.Message)
+		}
+		return nil
+	}
+	if err := call(); err != nil {
+		return err
+	}
+	start := time.Now()
+	var elapsed time.Duration
+	for elapsed < d || r.Iterations == 0 {
+		if err := call(); err != nil {
+			return err
+		}
+		r.Iterations++
+		elapsed = time.Since(start)
+	}
+	perOp := elapsed.Seconds() / float64(r.Iterations)
+	r.NsPerOp = elapsed.Nanoseconds() / int64(r.Iterations)
+	r.OpsPerSec = math.Round(100/perOp) / 100
+	if r.Size > 0 {
+		r.MBPerSec = math.Round(float64(r.Size)/perOp/1e4) / 100
+	}
+	return nil
+}
+
+// benchCases lists the measured operations: SM3 hashing and HMAC, SM4
+// encryption and decryption in every mode and CMAC, SM2 key generation,
+// signatures and encryption, SM9 signatures and encryption, and ZUC-128
+// encryption and MAC.
+func benchCases() []benchCase {
+	hashed := func(algorithm, operation string, keySize int) benchCase {
+		return benchCase{algorithm, operation, "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			return map[string]interface{}{"key": g.hex(keySize), "data": g.hex(n), "data_encoding": encodingHex}, nil
+		}}
+	}
+	cases := []benchCase{
+		{"sm3", "hash", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			return map[string]interface{}{"data": g.hex(n), "data_encoding": encodingHex}, nil
+		}},
+		hashed("sm3", "hmac", 32),
+	}
+	for _, mode := range sm4Modes {
+		encrypt := func(g *vectorGen, n int) (map[string]interface{}, error) {
+			in := map[string]interface{}{"key": g.hex(16), "mode": mode, "plaintext_hex": g.hex(n)}
+			switch mode {
+			case "ECB":
+			case "XTS":
+				in["key"], in["tweak"] = g.hex(32), g.hex(16)
+			case "GCM", "CCM":
+				in["iv"] = g.hex(12)
+			default:
+				in["iv"] = g.hex(16)
+			}
+			return in, nil
+		}
+		decrypt := func(g *vectorGen, n int) (map[string]interface{}, error) {
+			in, _ := encrypt(g, n)
+			res, err := g.call("sm4", "encrypt", maps.Clone(in))
+			if err != nil {
+				return nil, err
+			}
+			delete(in, "plaintext_hex")
+			in["ciphertext"], in["plaintext_encoding"] = res.Output, encodingHex
+			if res.Tag != "" {
+				in["tag"] = res.Tag
+			}
+			return in, nil
+		}
+		// XTS encrypts at least a block.
+		minSize := 0
+		if mode == "XTS" {
+			minSize = 16
+		}
+		cases = append(cases, benchCase{"sm4", "encrypt", mode, true, minSize, encrypt}, benchCase{"sm4", "decrypt", mode, true, minSize, decrypt})
+	}
+	cases = append(cases, hashed("sm4", "cmac", 16))
+
+	sm2Key := func(g *vectorGen) (*Result, error) {
+		return g.call("sm2", "keygen", map[string]interface{}{})
+	}
+	cases = append(cases,
+		benchCase{"sm2", "keygen", "", false, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			return map[string]interface{}{}, nil
+		}},
+		benchCase{"sm2", "sign", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			kp, err := sm2Key(g)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"private_key": kp.PrivateKey, "message": g.text(n)}, nil
+		}},
+		benchCase{"sm2", "verify", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			kp, err := sm2Key(g)
+			if err != nil {
+				return nil, err
+			}
+			msg := g.text(n)
+			sig, err := g.call("sm2", "sign", map[string]interface{}{"private_key": kp.PrivateKey, "message": msg})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"public_key": kp.PublicKey, "message": msg, "signature": sig.Output}, nil
+		}},
+		benchCase{"sm2", "encrypt", "", true, 1, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			kp, err := sm2Key(g)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"public_key": kp.PublicKey, "plaintext": g.text(n)}, nil
+		}},
+		benchCase{"sm2", "decrypt", "", true, 1, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			kp, err := sm2Key(g)
+			if err != nil {
+				return nil, err
+			}
+			ct, err := g.call("sm2", "encrypt", map[string]interface{}{"public_key": kp.PublicKey, "plaintext": g.text(n)})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"private_key": kp.PrivateKey, "ciphertext": ct.Output}, nil
+		}},
+	)
+
+	cases = append(cases,
+		benchCase{"sm9", "sign", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			master, user, _, err := sm9Keys(g, "sign")
+			return map[string]interface{}{"private_key": user, "master_public_key": master, "message_hex": g.hex(n)}, err
+		}},
+		benchCase{"sm9", "verify", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			master, user, id, err := sm9Keys(g, "sign")
+			if err != nil {
+				return nil, err
+			}
+			msg := g.hex(n)
+			sig, err := g.call("sm9", "sign", map[string]interface{}{"private_key": user, "master_public_key": master, "message_hex": msg})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"id": id, "master_public_key": master, "message_hex": msg, "signature": sig.Output}, nil
+		}},
+		benchCase{"sm9", "encrypt", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			master, _, id, err := sm9Keys(g, "encrypt")
+			return map[string]interface{}{"id": id, "master_public_key": master, "plaintext_hex": g.hex(n)}, err
+		}},
+		benchCase{"sm9", "decrypt", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			master, user, id, err := sm9Keys(g, "encrypt")
+			if err != nil {
+				return nil, err
+			}
+			ct, err := g.call("sm9", "encrypt", map[string]interface{}{"id": id, "master_public_key": master, "plaintext_hex": g.hex(n)})
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"id": id, "private_key": user, "ciphertext": ct.Output, "plaintext_encoding": encodingHex}, nil
+		}},
+	)
+
+	zuc := func(g *vectorGen) map[string]interface{} {
+		return map[string]interface{}{"key": g.hex(16), "count": json.Number(fmt.Sprint(g.r.Uint32())),
+			"bearer": json.Number(fmt.Sprint(g.r.IntN(32))), "direction": json.Number(fmt.Sprint(g.r.IntN(2)))}
+	}
+	cases = append(cases,
+		benchCase{"zuc", "encrypt", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			in := zuc(g)
+			in["plaintext_hex"] = g.hex(n)
+			return in, nil
+		}},
+		benchCase{"zuc", "mac", "", true, 0, func(g *vectorGen, n int) (map[string]interface{}, error) {
+			in := zuc(g)
+			in["data_hex"] = g.hex(n)
+			return in, nil
+		}},
+	)
+	return cases
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestBench(t *testing.T) {
+	bench := func(args ...string) (*benchReport, int) {
+		t.Helper()
+		var out bytes.Buffer
+		code := run(append([]string{"bench"}, args...), &out)
+		var report benchReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil || report.Results == nil {
+			t.Fatalf("bench %q: %s", args, out.String())
+		}
+		return &report, code
+	}
+	report, code := bench("--algorithm", "sm4", "--duration", "20ms", "--sizes", "0, 16")
+	// Every mode encrypts and decrypts at both sizes but XTS, which needs
+	// a block; CMAC takes both.
+	if code != 0 || len(report.Results) != 2*2*len(sm4Modes)-2+2 || report.Duration != "20ms" || report.Go == "" {
+		t.Fatalf("exit %d: %+v", code, report)
+	}
+	for _, r := range report.Results {
+		if r.Algorithm != "sm4" || r.Iterations < 1 || r.NsPerOp <= 0 || r.OpsPerSec <= 0 || r.Error != "" ||
+			(r.Size == 0) != (r.MBPerSec == 0) || r.Operation != "cmac" && r.Mode == "" {
+			t.Errorf("%+v", r)
+		}
+	}
+	if report, code = bench("--algorithm", "sm2", "--duration", "1ms", "--sizes", "0"); code != 0 || len(report.Results) != 3 {
+		t.Errorf("SM2 without a payload: exit %d, %+v", code, report.Results)
+	}
+
+	// A failing operation is reported and fails the run.
+	mac := handlers["zuc"]["mac"]
+	defer func() { handlers["zuc"]["mac"] = mac }()
+	handlers["zuc"]["mac"] = func(in map[string]interface{}) (*Result, error) { return nil, errors.New("broken") }
+	report, code = bench("--algorithm", "zuc", "--duration", "1ms", "--sizes", "16")
+	if code != exitFailure || len(report.Results) != 2 || report.Results[0].Error != "" || report.Results[1].Error != "zuc mac failed: broken" {
+		t.Errorf("failed MAC: exit %d, %+v", code, report.Results)
+	}
+
+	for _, args := range [][]string{{"--algorithm", "sm7"}, {"--duration", "0s"}, {"--sizes", "16,-1"}, {"--sizes", "x"}, {"extra"}} {
+		var out bytes.Buffer
+		if code := run(append([]string{"bench"}, args...), &out); code != exitUsage || !bytes.Contains(out.Bytes(), []byte(`"status":"error"`)) {
+			t.Errorf("bench %q exited %d: %s", args, code, out.String())
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tlcp"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tls13"
+)
+
+// capabilities is the Result.Capabilities of "wrapper capabilities": what
+// a test harness may ask of this wrapper, so that it can skip the rest
+// rather than keep a feature table per wrapper.
+type capabilities struct {
+	Operations []capOperation `json:"operations"`
+	SM4Modes   []string       `json:"sm4_modes"`
+	Padding    []string       `json:"padding"`
+	// Encodings are the values of the *_encoding request fields ("input")
+	// and of "output_encoding".
+	Encodings struct {
+		Input  []string `json:"input"`
+		Output []string `json:"output"`
+	} `json:"encodings"`
+	// Options maps an algorithm and a request field to the values its
+	// operations accept, the first being the default where there is one.
+	Options map[string]map[string][]string `json:"options"`
+}
+
+type capOperation struct {
+	Algorithm string `json:"algorithm"`
+	Operation string `json:"operation"`
+	// Files reports support for "input_file" and "output_file".
+	Files bool `json:"files,omitempty"`
+	// Required lists the required fields, each as its alternatives.
+	Required [][]string `json:"required,omitempty"`
+}
+
+// buildCapabilities lists the operations of opDocs, which has an entry for
+// every handler and, unlike handlers, can be read by one.
+func buildCapabilities() *capabilities {
+	c := &capabilities{
+		SM4Modes: sm4Modes,
+		Padding:  paddings,
+		Options: map[string]map[string][]string{
+			"sm2": {
+				"signature_format":  {sigFormatDER, sigFormatRS},
+				"ciphertext_format": {ctFormatC1C3C2, ctFormatC1C2C3, ctFormatAuto},
+				"encoding":          {"raw", "asn1"},
+				"key_format":        {keyFormatHex, keyFormatDER, keyFormatPEM},
+				"key_cipher":        {"sm4", "aes"},
+				"point_format":      {"uncompressed", "compressed"},
+				"content_cipher":    {"sm4-gcm", "sm4-cbc"},
+				"role":              {roleInitiator, roleResponder},
+				"hash_algorithm":    {"sm3", "sha256", "sha1"},
+				"key_usage":         sortedKeys(keyUsages),
+				"ext_key_usage":     sortedKeys(extKeyUsages),
+				"reason":            reasonNames(),
+			},
+			"sm3": {
+				"stage": {"extract", "expand"},
+			},
+			"sm4": {
+				"mode":    sm4Modes,
+				"padding": paddings,
+				"variant": {"plain", "length_prefixed"},
+			},
+			"sm9": {
+				"signature_format": {sigFormatDER, sm9SigFormatRaw},
+				"type":             {sm9TypeSign, sm9TypeEncrypt},
+			},
+			"keystore": {
+				"type": {"sm2", "sm4"},
+			},
+			"tlcp": {
+				"cipher_suites": suiteNames(tlcp.CipherSuites(), tlcp.CipherSuiteName),
+			},
+			"tls13": {
+				"cipher_suites": suiteNames(tls13.CipherSuites(), tls13.CipherSuiteName),
+			},
+		},
+	}
+	c.Encodings.Input = []string{encodingUTF8, encodingHex, encodingBase64}
+	c.Encodings.Output = []string{encodingHex, encodingBase64, encodingBase64URL}
+	for _, alg := range sortedKeys(opDocs) {
+		for _, op := range sortedKeys(opDocs[alg]) {
+			_, files := ioSpecs[alg][op]
+			required := schemas()[[2]string{alg, op}].required
+			c.Operations = append(c.Operations, capOperation{alg, op, files, required})
+		}
+	}
+	return c
+}
+
+// reasonNames lists revocationReasons without the unused code.
+func reasonNames() []string {
+	var names []string
+	for _, r := range revocationReasons {
+		if r != "" {
+			names = append(names, r)
+		}
+	}
+	return names
+}
+
+func suiteNames(ids []uint16, name func(uint16) string) []string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = name(id)
+	}
+	return names
+}
+
+// wrapperCapabilities reports the operations, modes, padding schemes,
+// encodings and options the wrapper supports.
+func wrapperCapabilities(in map[string]interface{}) (*Result, error) {
+	return &Result{Capabilities: buildCapabilities()}, nil
+}
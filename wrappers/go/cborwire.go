@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cbor"
+)
+
+// runCBORBatch answers the CBOR sequence (RFC 8742) of requests on
+// standard input with a sequence of Results, in order. A request that
+// cannot be decoded ends the sequence, which cannot be resynchronized,
+// with an error Result and exit code 1.
+func runCBORBatch(inv *invocation, stdout io.Writer) int {
+	dec := cbor.NewDecoder(stdin, maxFrameSize)
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = errReader{errNoStdin}
+	for {
+		item, err := dec.Decode()
+		if err == io.EOF {
+			return 0
+		}
+		var reply []byte
+		if err != nil {
+			reply = cborReply(errorResult(&codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)}), false, false)
+		} else {
+			reply = cborAnswer(inv, item)
+		}
+		if _, werr := stdout.Write(reply); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			return 1
+		}
+		if err != nil {
+			return 1
+		}
+	}
+}
+
+// frameAnswer answers one frame of the socket mode in format.
+func frameAnswer(format string, frame []byte) []byte {
+	if format == formatCBOR {
+		item, err := cbor.Unmarshal(frame)
+		if err != nil {
+			return cborReply(errorResult(&codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)}), false, false)
+		}
+		return cborAnswer(&invocation{remote: true}, item)
+	}
+	return encodeReply(format, batchRequest(&invocation{remote: true}, frame))
+}
+
+// encodeReply encodes a Result that is not the answer to a decoded
+// request, such as the error for a bad frame.
+func encodeReply(format string, res *Result) []byte {
+	if format == formatCBOR {
+		return cborReply(res, false, false)
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(res)
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+// cborAnswer runs the CBOR request item and encodes its Result. Binary
+// result fields become byte strings, and so does decrypted plaintext,
+// unless output_encoding or plaintext_encoding asks for text.
+func cborAnswer(inv *invocation, item interface{}) []byte {
+	in, err := cborRequest(item)
+	if err != nil {
+		return cborReply(errorResult(err), false, false)
+	}
+	algorithm, operation, err := requestOperation(inv, in)
+	if err != nil {
+		return cborReply(errorResult(err), false, false)
+	}
+	defaults.apply(algorithm, in)
+	output, outputs := false, false
+	if v, ok := in["output_encoding"]; !ok || v == nil {
+		output, outputs = binaryOutputs(algorithm, operation, in)
+	}
+	if inv.remote {
+		return cborReply(serverDispatch(algorithm, operation, in), output, outputs)
+	}
+	return cborReply(dispatch(algorithm, operation, in), output, outputs)
+}
+
+// cborRequest converts a decoded CBOR map to a request as readInput
+// would decode it from JSON, keeping byte strings.
+func cborRequest(item interface{}) (map[string]interface{}, error) {
+	if _, ok := item.(cbor.Map); !ok {
+		return nil, &codedError{codeInvalidRequest, errors.New("invalid request: expected a CBOR map")}
+	}
+	v, err := fromCBOR(item)
+	if err != nil {
+		return nil, &codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)}
+	}
+	return v.(map[string]interface{}), nil
+}
+
+func fromCBOR(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case int64:
+		return json.Number(strconv.FormatInt(v, 10)), nil
+	case uint64:
+		return json.Number(strconv.FormatUint(v, 10)), nil
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, e := range v {
+			var err error
+			if list[i], err = fromCBOR(e); err != nil {
+				return nil, err
+			}
+		}
+		return list, nil
+	case cbor.Map:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			name, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("map key %v is not a text string", k)
+			}
+			var err error
+			if m[name], err = fromCBOR(e); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case cbor.Tag:
+		return nil, fmt.Errorf("unsupported tag %d", v.Number)
+	}
+	return v, nil
+}
+
+// cborReply encodes res as a CBOR map. The fields binaryFields names,
+// with Output if output is set, and the elements of Outputs if outputs is
+// set, are decoded from hex into byte strings.
+func cborReply(res *Result, output, outputs bool) []byte {
+	raw := map[string]bool{}
+	for name := range binaryFields(res, output) {
+		raw[name] = true
+	}
+	out := cbor.Map{}
+	for k, v := range resultObject(res) {
+		switch {
+		case raw[k]:
+			out[k] = hexToCBOR(v)
+		case k == "outputs" && outputs:
+			list := v.([]interface{})
+			for i := range list {
+				list[i] = hexToCBOR(list[i])
+			}
+			out[k] = list
+		default:
+			out[k] = toCBOR(v)
+		}
+	}
+	enc, err := cbor.Marshal(out)
+	if err != nil {
+		panic(err)
+	}
+	return enc
+}
+
+// hexToCBOR turns a hex string into a byte string. Other values, such as
+// PEM keys, are kept as text.
+func hexToCBOR(v interface{}) interface{} {
+	if s, ok := v.(string); ok {
+		if b, err := hex.DecodeString(s); err == nil {
+			return b
+		}
+	}
+	return toCBOR(v)
+}
+
+// toCBOR converts a value decoded from JSON. The CBOR subset has no
+// floats, so numbers that are not integers are kept as text.
+func toCBOR(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		return v.String()
+	case []interface{}:
+		for i := range v {
+			v[i] = toCBOR(v[i])
+		}
+		return v
+	case map[string]interface{}:
+		m := cbor.Map{}
+		for k, e := range v {
+			m[k] = toCBOR(e)
+		}
+		return m
+	}
+	return v
+}
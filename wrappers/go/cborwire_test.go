@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cbor"
+)
+
+func TestCBORBatch(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	abc, _ := hex.DecodeString("66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0")
+	key := bytes.Repeat([]byte{1}, 16)
+	plaintext := []byte{0, 1, 2, 0xff}
+
+	batch := func(args []string, want int, requests ...interface{}) []cbor.Map {
+		t.Helper()
+		var in []byte
+		for _, req := range requests {
+			if b, ok := req.([]byte); ok {
+				in = append(in, b...)
+				continue
+			}
+			b, err := cbor.Marshal(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			in = append(in, b...)
+		}
+		stdin = bytes.NewReader(in)
+		var out bytes.Buffer
+		if code := run(args, &out); code != want {
+			t.Fatalf("%q exited %d, want %d", args, code, want)
+		}
+		var results []cbor.Map
+		dec := cbor.NewDecoder(&out, maxFrameSize)
+		for {
+			v, err := dec.Decode()
+			if err != nil {
+				break
+			}
+			results = append(results, v.(cbor.Map))
+		}
+		return results
+	}
+
+	res := batch([]string{"--batch", "--format", "cbor"}, 0,
+		cbor.Map{"algorithm": "sm3", "operation": "hash", "data": []byte("abc")},
+		cbor.Map{"algorithm": "sm3", "operation": "hash", "data": "abc", "output_encoding": "base64"},
+		cbor.Map{"algorithm": "sm4", "operation": "encrypt", "key": key, "plaintext": plaintext, "mode": "ECB"},
+		[]interface{}{"not a map"},
+		cbor.Map{"algorithm": "sm3", "operation": "hash", "data": cbor.Tag{Number: 1, Content: int64(1)}},
+		cbor.Map{"algorithm": "sm3", "operation": "hmac", "key": int64(1), "data": "abc"},
+	)
+	if len(res) != 6 {
+		t.Fatalf("%d results, want 6: %v", len(res), res)
+	}
+	if out, ok := res[0]["output"].([]byte); !ok || !bytes.Equal(out, abc) {
+		t.Errorf("hash of a byte string: %v", res[0])
+	}
+	if res[1]["output"] != "Zsfw9GLu7dnR8tRr3BDk4kFnxIdc8veiKX2gK49LqOA=" {
+		t.Errorf("output_encoding base64: %v", res[1])
+	}
+	ciphertext, ok := res[2]["output"].([]byte)
+	if !ok || len(ciphertext) != 16 {
+		t.Fatalf("sm4 encrypt: %v", res[2])
+	}
+	for i, code := range []string{codeInvalidRequest, codeInvalidRequest, codeInvalidField} {
+		if r := res[3+i]; r["status"] != statusError || r["error_code"] != code {
+			t.Errorf("result %d = %v, want %s", 3+i, r, code)
+		}
+	}
+
+	// Decrypted plaintext comes back as bytes, with the operation from the
+	// command line; a truncated request ends the batch with exit code 1.
+	res = batch([]string{"sm4", "decrypt", "--batch", "--format=cbor"}, 1,
+		cbor.Map{"key": key, "ciphertext": ciphertext, "mode": "ECB"},
+		[]byte{0xa2, 0x61},
+	)
+	if len(res) != 2 {
+		t.Fatalf("%d results, want 2: %v", len(res), res)
+	}
+	if out, ok := res[0]["output"].([]byte); !ok || !bytes.Equal(out, plaintext) {
+		t.Errorf("sm4 decrypt: %v", res[0])
+	}
+	if res[1]["error_code"] != codeInvalidRequest {
+		t.Errorf("truncated request: %v", res[1])
+	}
+}
+
+func TestCBORFrames(t *testing.T) {
+	req, _ := cbor.Marshal(cbor.Map{"algorithm": "sm2", "operation": "keygen"})
+	v, err := cbor.Unmarshal(frameAnswer(formatCBOR, req))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := v.(cbor.Map)
+	if priv, ok := res["private_key"].([]byte); !ok || len(priv) != 32 || res["status"] != statusSuccess {
+		t.Errorf("keygen: %v", res)
+	}
+	if pub, ok := res["public_key"].([]byte); !ok || len(pub) != 65 {
+		t.Errorf("keygen public key: %v", res)
+	}
+	v, err = cbor.Unmarshal(frameAnswer(formatCBOR, []byte("{}")))
+	if err != nil || v.(cbor.Map)["error_code"] != codeInvalidRequest {
+		t.Errorf("JSON frame in CBOR mode: %v, %v", v, err)
+	}
+	if b := frameAnswer(formatJSON, []byte(`{"algorithm": "sm3", "operation": "hash", "data": "abc"}`)); !strings.HasPrefix(string(b), `{"status":"success"`) {
+		t.Errorf("JSON frame: %s", b)
+	}
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// fieldKind is the JSON type of a request field, which decides how a
+// --<field> flag turns its argument into the field's value.
+type fieldKind int
+
+const (
+	// fieldText is a string: hex, PEM, UTF-8 text and the like.
+	fieldText fieldKind = iota
+	fieldInt
+	// fieldBool flags take no argument; --<field>=false clears them.
+	fieldBool
+	// fieldList is an array of strings, one element per flag.
+	fieldList
+	// fieldJSON is any other JSON value, such as an object.
+	fieldJSON
+)
+
+// fieldKinds gives the kind of every request field that is not a string.
+var fieldKinds = map[string]fieldKind{
+	"accept_timeout":  fieldInt,
+	"accuracy_ms":     fieldInt,
+	"bearer":          fieldInt,
+	"clock_skew":      fieldInt,
+	"connections":     fieldInt,
+	"count":           fieldInt,
+	"crl_number":      fieldInt,
+	"direction":       fieldInt,
+	"expires_in":      fieldInt,
+	"feedback_size":   fieldInt,
+	"hid":             fieldInt,
+	"iterations":      fieldInt,
+	"key_length":      fieldInt,
+	"length":          fieldInt,
+	"mac_length":      fieldInt,
+	"max_path_len":    fieldInt,
+	"response_length": fieldInt,
+	"sector":          fieldInt,
+	"share_count":     fieldInt,
+	"tag_length":      fieldInt,
+	"threshold":       fieldInt,
+	"timeout":         fieldInt,
+	"validity_days":   fieldInt,
+
+	"cert_req":             fieldBool,
+	"client_auth":          fieldBool,
+	"detached":             fieldBool,
+	"deterministic":        fieldBool,
+	"insecure_skip_verify": fieldBool,
+	"is_ca":                fieldBool,
+	"no_nonce":             fieldBool,
+	"overwrite":            fieldBool,
+	"per_item":             fieldBool,
+	"prehashed":            fieldBool,
+	"reject_weak_key":      fieldBool,
+	"require_exp":          fieldBool,
+	"signed_attributes":    fieldBool,
+	"stdin":                fieldBool,
+	"untagged":             fieldBool,
+
+	"ca_certificates": fieldList,
+	"cipher_suites":   fieldList,
+	"data_list":       fieldList,
+	"dns_names":       fieldList,
+	"emails":          fieldList,
+	"ext_key_usage":   fieldList,
+	"intermediates":   fieldList,
+	"ip_addresses":    fieldList,
+	"key_usage":       fieldList,
+	"roots":           fieldList,
+	"shares":          fieldList,
+
+	"claims":  fieldJSON,
+	"header":  fieldJSON,
+	"items":   fieldJSON,
+	"revoked": fieldJSON,
+	"subject": fieldJSON,
+}
+
+// fileFields are the request fields that name a file, so that --<field>
+// sets the path rather than reading the file as --<field>-file would.
+var fileFields = map[string]bool{
+	"input_file":    true,
+	"output_file":   true,
+	"keystore_file": true,
+	"log_file":      true,
+}
+
+// modeFlags are the flags of the wrapper itself, with whether they take an
+// argument; every other flag after "<algorithm> <operation>" sets a field.
+var modeFlags = map[string]bool{
+	"input":        true,
+	"input-format": true,
+	"batch":        false,
+	"format":       true,
+	"serve-stdio":  false,
+	"grpc":         true,
+	"allow-origin": true,
+	"listen":       true,
+	"metrics":      true,
+	"selftest":     false,
+	"seed":         true,
+	"help":         false,
+	"h":            false,
+}
+
+// fieldFlags takes the field flags out of args: --<field> <value> (or
+// --<field>=<value>) with the field name in kebab or snake case, and
+// --<field>-file <path> for the contents of a file, less a trailing
+// newline. Relative paths of key fields (those ending in "key") are
+// resolved against the default "key_dir" of algorithm. The remaining
+// arguments are returned for the flag package.
+func fieldFlags(algorithm, operation string, args []string) (map[string]interface{}, []string, error) {
+	fields := map[string]interface{}{}
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "-") || arg == "-" || arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if takesArg, ok := modeFlags[name]; ok && !(name == "seed" && seedIsField(algorithm, operation)) {
+			rest = append(rest, arg)
+			if takesArg && !hasValue && i+1 < len(args) {
+				rest = append(rest, args[i+1])
+				i++
+			}
+			continue
+		}
+		field := strings.ReplaceAll(name, "-", "_")
+		readFile := false
+		if f, ok := strings.CutSuffix(field, "_file"); ok && !fileFields[field] {
+			field, readFile = f, true
+		}
+		kind := fieldKinds[field]
+		if kind == fieldBool && !readFile {
+			if !hasValue {
+				value = "true"
+			}
+			if value != "true" && value != "false" {
+				return nil, nil, fmt.Errorf("flag %s takes no value, or =true or =false", arg)
+			}
+			fields[field] = value == "true"
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, nil, fmt.Errorf("flag %s needs a value", arg)
+			}
+			i++
+			value = args[i]
+		}
+		if readFile {
+			if strings.HasSuffix(field, "key") {
+				// The flags are parsed before run loads the defaults.
+				cfg, err := loadDefaults()
+				if err != nil {
+					return nil, nil, err
+				}
+				if dir, ok := cfg.value(algorithm, "key_dir"); ok {
+					s, _ := dir.(string)
+					value = keyPath(s, value)
+				}
+			}
+			data, err := os.ReadFile(value)
+			if err != nil {
+				return nil, nil, &codedError{codeIO, err}
+			}
+			value = strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		}
+		v, err := fieldValue(kind, value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("flag %s: %v", arg, err)
+		}
+		if kind == fieldList {
+			list, _ := fields[field].([]interface{})
+			v = append(list, v)
+		}
+		fields[field] = v
+	}
+	return fields, rest, nil
+}
+
+// seedIsField reports whether --seed sets the "seed" field of the
+// operation, as for sm3 mgf1, rather than the randomness source, which
+// such operations do not draw from.
+func seedIsField(algorithm, operation string) bool {
+	sc := schemas()[[2]string{algorithm, operation}]
+	if sc == nil {
+		return false
+	}
+	_, ok := sc.fields["seed"]
+	return ok
+}
+
+// fieldValue converts a flag argument to the JSON value of a field of
+// kind; list fields get one element.
+func fieldValue(kind fieldKind, s string) (interface{}, error) {
+	switch kind {
+	case fieldInt:
+		if _, err := strconv.ParseInt(s, 10, 64); err != nil {
+			return nil, fmt.Errorf("%q is not an integer", s)
+		}
+		return json.Number(s), nil
+	case fieldBool:
+		switch s {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not true or false", s)
+	case fieldJSON:
+		dec := json.NewDecoder(strings.NewReader(s))
+		dec.UseNumber()
+		var v interface{}
+		if err := dec.Decode(&v); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %v", err)
+		}
+		return v, nil
+	}
+	return s, nil
+}
+
+// writeHelp writes the usage of the wrapper, of the operations of
+// algorithm, or of one operation. The fields of an operation are those of
+// its schema.
+func writeHelp(w io.Writer, algorithm, operation string) error {
+	var b bytes.Buffer
+	switch {
+	case algorithm == "":
+		fmt.Fprintf(&b, "%s\n\nOperations (wrapper <algorithm> <operation> --help for their fields):\n", usage)
+		for _, alg := range sortedKeys(opDocs) {
+			writeWrapped(&b, fmt.Sprintf("  %-9s", alg), sortedKeys(opDocs[alg]), " ")
+		}
+		b.WriteString("\nCommands (wrapper <command> --help for their flags):\n")
+		for _, name := range sortedKeys(commands) {
+			fmt.Fprintf(&b, "  %s\n", commands[name].usage)
+		}
+	case operation == "":
+		ops, ok := opDocs[algorithm]
+		if !ok {
+			_, err := lookup(algorithm, "")
+			return err
+		}
+		fmt.Fprintf(&b, "usage: wrapper %s <operation> [--<field> <value>...] [--input '<json>' | --input -]\n\nOperations:\n", algorithm)
+		for _, op := range sortedKeys(ops) {
+			var fields []string
+			for _, name := range sortedKeys(schemas()[[2]string{algorithm, op}].fields) {
+				if !slices.Contains(commonFields, name) {
+					fields = append(fields, name)
+				}
+			}
+			writeWrapped(&b, fmt.Sprintf("  %-21s", op), fields, ", ")
+		}
+		fmt.Fprintf(&b, "\nEvery operation also takes %s.\n", strings.Join(commonFields, " and "))
+	default:
+		result, ok := opDocs[algorithm][operation]
+		if !ok {
+			_, err := lookup(algorithm, operation)
+			return err
+		}
+		sc := schemas()[[2]string{algorithm, operation}]
+		fmt.Fprintf(&b, "usage: wrapper %s %s [--<field> <value>...] [--input '<json>' | --input -]\n\n", algorithm, operation)
+		if len(sc.required) > 0 {
+			var groups []string
+			for _, group := range sc.required {
+				groups = append(groups, strings.Join(group, " or "))
+			}
+			fmt.Fprintf(&b, "Requires: %s\n", strings.Join(groups, ", "))
+		}
+		fmt.Fprintf(&b, "Result:  %s\n", strings.ReplaceAll(result, "`", ""))
+		b.WriteString("\nFlags:\n")
+		for _, field := range sortedKeys(sc.fields) {
+			fmt.Fprintf(&b, "  %s\n", flagUsage(field))
+		}
+		b.WriteString("\nEvery request field can be given as a flag, and --<field>-file <path>\n" +
+			"reads a field from a file. Flags override the fields of --input.\n")
+	}
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// writeWrapped writes prefix and then words joined by sep, wrapping the
+// line at 78 columns under the end of prefix.
+func writeWrapped(b *bytes.Buffer, prefix string, words []string, sep string) {
+	line := prefix
+	for i, word := range words {
+		if i > 0 {
+			line += strings.TrimRight(sep, " ")
+		}
+		if len(line)+1+len(word) > 78 && i > 0 {
+			b.WriteString(line + "\n")
+			line = strings.Repeat(" ", len(prefix))
+		}
+		line += " " + word
+	}
+	b.WriteString(line + "\n")
+}
+
+// flagUsage renders the flag of field for --help.
+func flagUsage(field string) string {
+	flag := "--" + strings.ReplaceAll(field, "_", "-")
+	switch fieldKinds[field] {
+	case fieldInt:
+		return flag + " <n>"
+	case fieldBool:
+		return flag
+	case fieldList:
+		return flag + " <value> (repeatable)"
+	case fieldJSON:
+		return flag + " <json>"
+	}
+	if fileFields[field] {
+		return flag + " <path>"
+	}
+	return flag + " <value>"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOpDocsCoverHandlers(t *testing.T) {
+	for alg, ops := range handlers {
+		for op := range ops {
+			if _, ok := opDocs[alg][op]; !ok {
+				t.Errorf("%s %s has no opDoc", alg, op)
+			}
+		}
+	}
+	for alg, ops := range opDocs {
+		for op := range ops {
+			if _, err := lookup(alg, op); err != nil {
+				t.Errorf("opDoc for %s %s: %v", alg, op, err)
+			}
+		}
+	}
+}
+
+func TestFieldFlags(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte(testSM4Key+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fields, rest, err := fieldFlags("sm2", "sign", []string{
+		"--key-file", keyFile, "--plaintext=a b", "--tag_length", "12", "--reject-weak-key",
+		"--roots", "r1", "--roots", "r2", "--subject", `{"CN": "x"}`, "--untagged=false",
+		"--output-file", "out", "--input", "{}", "--help",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"key": testSM4Key, "plaintext": "a b", "tag_length": json.Number("12"), "reject_weak_key": true,
+		"roots": []interface{}{"r1", "r2"}, "subject": map[string]interface{}{"CN": "x"}, "untagged": false,
+		"output_file": "out",
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("fields = %v, want %v", fields, want)
+	}
+	if !reflect.DeepEqual(rest, []string{"--input", "{}", "--help"}) {
+		t.Errorf("rest = %q", rest)
+	}
+	for _, args := range [][]string{
+		{"--plaintext"},
+		{"--detached=yes"},
+		{"--subject", "{"},
+	} {
+		if _, _, err := fieldFlags("sm2", "sign", args); err == nil {
+			t.Errorf("fieldFlags(%q) succeeded", args)
+		}
+	}
+}
+
+func TestSubcommands(t *testing.T) {
+	digest := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc"}).Output
+	for _, args := range [][]string{
+		{"sm3", "hash", "--data", "abc"},
+		// Flags override --input.
+		{"sm3", "hash", "--input", `{"data": "xyz"}`, "--data", "abc"},
+		{"SM3", "HASH", "--data-encoding", "hex", "--data", "616263"},
+	} {
+		var out bytes.Buffer
+		if code := run(args, &out); code != 0 || !strings.Contains(out.String(), digest) {
+			t.Errorf("run(%q) = %d, %s", args, code, out.String())
+		}
+	}
+	var out bytes.Buffer
+	if code := run([]string{"sm3", "hash", "--batch", "--data", "abc"}, &out); code != exitUsage {
+		t.Errorf("field flags with --batch exited %d", code)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	for _, c := range []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"--help"}, []string{"usage: wrapper", "sm2 ", "keystore"}},
+		{[]string{"help"}, []string{"usage: wrapper", "tls13"}},
+		{[]string{"sm4", "--help"}, []string{"usage: wrapper sm4 <operation>", "encrypt-init", "cbcmac"}},
+		{[]string{"help", "sm2", "sign"}, []string{"usage: wrapper sm2 sign", "Result:  output (signature)", "--user-id <value>", "--input-file <path>"}},
+		{[]string{"sm3", "pbkdf2", "-h"}, []string{"--iterations <n>", "--key-length <n>"}},
+		// The flags are those of the schema, not only the documented ones.
+		{[]string{"help", "sm2", "sign"}, []string{"Requires: message or input_file or digest\n", "--deterministic\n", "--prehashed\n", "--key-format <value>", "--passphrase <value>"}},
+		{[]string{"help", "sm4", "encrypt"}, []string{"Requires: key, plaintext\n", "--tag-length <n>", "--reject-weak-key\n", "--output-file <path>"}},
+		{[]string{"help", "sm4"}, []string{"  encrypt-update        context, input_file, output_file, plaintext,\n", "also takes output_encoding and key_dir"}},
+	} {
+		var out bytes.Buffer
+		if code := run(c.args, &out); code != 0 {
+			t.Errorf("run(%q) exited %d: %s", c.args, code, out.String())
+		}
+		for _, w := range c.want {
+			if !strings.Contains(out.String(), w) {
+				t.Errorf("run(%q) lacks %q:\n%s", c.args, w, out.String())
+			}
+		}
+	}
+	var out bytes.Buffer
+	if code := run([]string{"help", "sm5"}, &out); code != exitUsage {
+		t.Errorf("help for an unknown algorithm exited %d", code)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// command is a subcommand of the wrapper that is not an operation: it
+// parses its own flags and writes its own output.
+type command struct {
+	// usage is the synopsis after "wrapper".
+	usage string
+	run   func(args []string, stdout io.Writer) int
+}
+
+// commands are run by name as "wrapper <name> [flags]", before the
+// defaults of loadDefaults are read: the requests they make must not
+// depend on the environment.
+var commands = map[string]command{
+	"bench":          {benchUsage, benchCommand},
+	"difftest":       {difftestUsage, difftestCommand},
+	"fuzz-corpus":    {fuzzCorpusUsage, fuzzCorpusCommand},
+	"gen-vectors":    {genVectorsUsage, genVectorsCommand},
+	"report":         {reportUsage, reportCommand},
+	"roundtrip":      {roundtripUsage, roundtripCommand},
+	"verify-vectors": {verifyVectorsUsage, verifyVectorsCommand},
+}
+
+// parseCommand parses the flags of the command with the synopsis usage,
+// which takes nargs arguments after them, or one or more if nargs is
+// negative. For --help it writes the usage and the flags; on an error it
+// writes an error Result. In both cases it returns false with the exit
+// code.
+func parseCommand(fs *flag.FlagSet, usage string, args []string, nargs int, stdout io.Writer) (bool, int) {
+	fs.SetOutput(io.Discard)
+	err := fs.Parse(args)
+	least := nargs
+	if nargs < 0 {
+		least = 1
+	}
+	if err == nil && nargs >= 0 && fs.NArg() > nargs {
+		err = fmt.Errorf("unexpected argument %q", fs.Arg(nargs))
+	} else if err == nil && fs.NArg() < least {
+		err = errors.New("missing argument")
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(stdout, "usage: wrapper %s\n\nFlags:\n", usage)
+		fs.SetOutput(stdout)
+		fs.PrintDefaults()
+		return false, exitSuccess
+	}
+	if err != nil {
+		return false, commandFailed(stdout, fmt.Errorf("%v; usage: wrapper %s", err, usage))
+	}
+	return true, exitSuccess
+}
+
+// commandFailed writes the error Result of a command, as run does for a
+// request, and returns its exit code. Errors without a code are usage
+// errors.
+func commandFailed(stdout io.Writer, err error) int {
+	var ce *codedError
+	if !errors.As(err, &ce) {
+		err = &codedError{codeUsage, err}
+	}
+	res := errorResult(err)
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	enc.Encode(res)
+	return exitCode(res)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configEnv names the config file; the other SM_WRAPPER_* variables set
+// defaults themselves.
+const (
+	configEnv    = "SM_WRAPPER_CONFIG"
+	configPrefix = "SM_WRAPPER_"
+)
+
+// config holds default request fields, from the config file and then the
+// environment. Explicit request fields take precedence over both.
+type config struct {
+	// fields apply to every operation, and algorithms[alg] to those of
+	// alg, over fields.
+	fields     map[string]interface{}
+	algorithms map[string]map[string]interface{}
+}
+
+// defaults is the config of the running process, loaded by run.
+var defaults = &config{}
+
+func (c *config) set(algorithm, field string, v interface{}) {
+	if algorithm == "" {
+		if c.fields == nil {
+			c.fields = map[string]interface{}{}
+		}
+		c.fields[field] = v
+		return
+	}
+	if c.algorithms == nil {
+		c.algorithms = map[string]map[string]interface{}{}
+	}
+	if c.algorithms[algorithm] == nil {
+		c.algorithms[algorithm] = map[string]interface{}{}
+	}
+	c.algorithms[algorithm][field] = v
+}
+
+// value returns the default of field for the operations of algorithm.
+func (c *config) value(algorithm, field string) (interface{}, bool) {
+	if v, ok := c.algorithms[algorithm][field]; ok {
+		return v, true
+	}
+	v, ok := c.fields[field]
+	return v, ok
+}
+
+// apply adds the defaults of algorithm to the request in where it does not
+// set the field, and resolves a relative "keystore_file" against
+// "key_dir".
+func (c *config) apply(algorithm string, in map[string]interface{}) {
+	for _, m := range []map[string]interface{}{c.algorithms[algorithm], c.fields} {
+		for k, v := range m {
+			if _, ok := in[k]; !ok {
+				in[k] = v
+			}
+		}
+	}
+	if p, ok := in["keystore_file"].(string); ok {
+		dir, _ := in["key_dir"].(string)
+		in["keystore_file"] = keyPath(dir, p)
+	}
+}
+
+// keyPath resolves the relative path p against the key directory dir.
+func keyPath(dir, p string) string {
+	if dir == "" || p == "" || filepath.IsAbs(p) {
+		return p
+	}
+	return filepath.Join(dir, p)
+}
+
+// loadDefaults reads the config file named by SM_WRAPPER_CONFIG, if any,
+// and then SM_WRAPPER_<FIELD> and SM_WRAPPER_<ALGORITHM>_<FIELD>.
+func loadDefaults() (*config, error) {
+	c := &config{}
+	if path := os.Getenv(configEnv); path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, &codedError{codeIO, err}
+		}
+		defer f.Close()
+		if err := c.parse(f, path); err != nil {
+			return nil, err
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, value, _ := strings.Cut(kv, "=")
+		field, ok := strings.CutPrefix(name, configPrefix)
+		if !ok || name == configEnv || field == "" {
+			continue
+		}
+		field = strings.ToLower(field)
+		algorithm := ""
+		if alg, f, ok := strings.Cut(field, "_"); ok && handlers[alg] != nil {
+			algorithm, field = alg, f
+		}
+		kind := fieldKinds[field]
+		if kind == fieldList {
+			var list []interface{}
+			for _, s := range strings.Split(value, ",") {
+				list = append(list, strings.TrimSpace(s))
+			}
+			c.set(algorithm, field, list)
+			continue
+		}
+		v, err := fieldValue(kind, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		c.set(algorithm, field, v)
+	}
+	return c, nil
+}
+
+// parse reads a config file: a YAML document if path ends in .yaml or
+// .yml, else a TOML document, whose keys are default fields, with an
+// object (a TOML [algorithm] table) per algorithm for the defaults of
+// that algorithm.
+func (c *config) parse(r io.Reader, path string) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return &codedError{codeIO, err}
+	}
+	decode := parseTOML
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decode = parseYAML
+	}
+	doc, err := decode(string(data), path)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedKeys(doc) {
+		field := strings.ReplaceAll(strings.ToLower(key), "-", "_")
+		table, ok := doc[key].(map[string]interface{})
+		if !ok {
+			c.set("", field, doc[key])
+			continue
+		}
+		if handlers[field] == nil {
+			return fmt.Errorf("%s: unknown algorithm %q", path, key)
+		}
+		for k, v := range table {
+			c.set(field, strings.ReplaceAll(strings.ToLower(k), "-", "_"), v)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigParse(t *testing.T) {
+	var c config
+	err := c.parse(strings.NewReader(`# defaults
+output_encoding = "base64"   # binary results
+count = 1_000
+detached = true
+roots = ['a.pem', "b\"c.pem"]
+
+[sm2]
+signature-format = "rs"
+`), "test.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config{
+		fields: map[string]interface{}{
+			"output_encoding": "base64",
+			"count":           json.Number("1000"),
+			"detached":        true,
+			"roots":           []interface{}{"a.pem", `b"c.pem`},
+		},
+		algorithms: map[string]map[string]interface{}{"sm2": {"signature_format": "rs"}},
+	}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("parse = %+v, want %+v", c, want)
+	}
+	var y config
+	err = y.parse(strings.NewReader(`# defaults
+output_encoding: base64
+count: 1000
+detached: true
+roots: [a.pem, 'b"c.pem']
+sm2:
+  signature-format: rs
+`), "test.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(y, want) {
+		t.Errorf("parse(YAML) = %+v, want %+v", y, want)
+	}
+	for _, bad := range []string{
+		"output_encoding",
+		`output_encoding = "base64`,
+		"output_encoding = base64",
+		`output_encoding = "hex" "base64"`,
+		"roots = ['a' 'b']",
+		"[sm5]",
+		"count = 1\ncount = 2",
+	} {
+		var c config
+		if err := c.parse(strings.NewReader(bad), "bad.toml"); err == nil || !strings.HasPrefix(err.Error(), "bad.toml:") {
+			t.Errorf("parse(%q) = %v", bad, err)
+		}
+	}
+}
+
+func TestConfigDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "wrapper.toml")
+	err := os.WriteFile(path, []byte("output_encoding = \"hex\"\n[sm2]\nuser_id = \"alice@example.com\"\n"), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(configEnv, path)
+	// The environment overrides the file, and algorithm defaults the
+	// global ones.
+	t.Setenv("SM_WRAPPER_OUTPUT_ENCODING", "base64")
+	t.Setenv("SM_WRAPPER_SM2_SIGNATURE_FORMAT", "rs")
+	t.Setenv("SM_WRAPPER_KEY_DIR", dir)
+
+	res := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc"})
+	if _, err := base64.StdEncoding.DecodeString(res.Output); err != nil || len(res.Output) != 44 {
+		t.Errorf("hash with base64 default = %q", res.Output)
+	}
+	res = mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc", "output_encoding": "hex"})
+	if len(res.Output) != 64 {
+		t.Errorf("explicit hex hash = %q", res.Output)
+	}
+
+	keys := mustCall(t, "sm2", "keygen", map[string]interface{}{"output_encoding": "hex"})
+	sig := mustCall(t, "sm2", "sign", map[string]interface{}{
+		"message": "hi", "private_key": keys.PrivateKey, "output_encoding": "hex",
+	})
+	if len(sig.Output) != 128 {
+		t.Fatalf("signature_format default not applied: %q", sig.Output)
+	}
+	verify := map[string]interface{}{"message": "hi", "public_key": keys.PublicKey, "signature": sig.Output}
+	if res := mustCall(t, "sm2", "verify", verify); !*res.Valid {
+		t.Error("verification under the default user_id failed")
+	}
+	verify["user_id"] = "1234567812345678"
+	if res := mustCall(t, "sm2", "verify", verify); *res.Valid {
+		t.Error("explicit user_id did not override the default")
+	}
+
+	// key_dir resolves keystore files and key file flags.
+	mustCall(t, "keystore", "create", map[string]interface{}{"keystore_file": "ks.json", "password": "pw"})
+	if _, err := os.Stat(filepath.Join(dir, "ks.json")); err != nil {
+		t.Errorf("keystore not created in key_dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sm2.key"), []byte(keys.PrivateKey+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if code := run([]string{"sm2", "sign", "--message", "hi", "--private-key-file", "sm2.key"}, &out); code != 0 {
+		t.Errorf("--private-key-file under key_dir exited %d: %s", code, out.String())
+	}
+
+	t.Setenv(configEnv, filepath.Join(dir, "missing.toml"))
+	res, code := call(t, "sm3", "hash", map[string]interface{}{"data": "abc"})
+	if code != exitFailure || res.ErrorCode != codeIO {
+		t.Errorf("missing config file: exit %d, %+v", code, res)
+	}
+	t.Setenv(configEnv, "")
+	t.Setenv("SM_WRAPPER_COUNT", "x")
+	if res, code := call(t, "sm3", "hash", map[string]interface{}{"data": "abc"}); code != exitUsage {
+		t.Errorf("invalid environment default: exit %d, %+v", code, res)
+	}
+
+	// Help and version do not depend on the defaults.
+	for _, args := range [][]string{{"--version"}, {"--help"}, {"help", "sm3", "hash"}, {"sm3", "hash", "--help"}} {
+		out.Reset()
+		if code := run(args, &out); code != exitSuccess {
+			t.Errorf("run(%q) with a broken default exited %d: %s", args, code, out.String())
+		}
+	}
+}
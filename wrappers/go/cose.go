@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/hex"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cose"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// coseOptions reads the fields common to COSE messages: "kid" and
+// "external_aad" (each UTF-8 text, or "_hex" / "_base64") and "untagged".
+func coseOptions(in map[string]interface{}) (cose.Options, error) {
+	var opts cose.Options
+	var err error
+	if opts.KID, _, err = bytesField(in, "kid"); err != nil {
+		return opts, err
+	}
+	if opts.ExternalAAD, _, err = bytesField(in, "external_aad"); err != nil {
+		return opts, err
+	}
+	if opts.Untagged, err = boolField(in, "untagged"); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// sm2COSESign signs "payload" (or "payload_hex" / "payload_base64") as a
+// COSE_Sign1 with alg SM2-SM3, under "user_id", with "private_key" or a
+// generated key. "detached" leaves the payload out and "deterministic"
+// makes the signature, and so the message, reproducible. The output is
+// the hex CBOR message.
+func sm2COSESign(in map[string]interface{}) (*Result, error) {
+	payload, err := requireBytes(in, "payload")
+	if err != nil {
+		return nil, err
+	}
+	opts := cose.SignOptions{}
+	if opts.Options, err = coseOptions(in); err != nil {
+		return nil, err
+	}
+	if opts.Detached, err = boolField(in, "detached"); err != nil {
+		return nil, err
+	}
+	if opts.Deterministic, err = boolField(in, "deterministic"); err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	priv, generated, err := sm2PrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := cose.Sign1(rand, payload, priv, uid, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Output: hex.EncodeToString(msg)}
+	if res.PublicKey, err = encodePublicKey(in, &priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if generated {
+		if res.PrivateKey, err = encodePrivateKey(in, priv); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Reason codes of sm2COSEVerify for messages that do not verify.
+var coseReasons = map[error]string{
+	cose.ErrAlgorithm: "COSE_UNSUPPORTED_ALG",
+	cose.ErrCritical:  "COSE_UNSUPPORTED_CRIT",
+	cose.ErrSignature: "COSE_BAD_SIGNATURE",
+}
+
+// sm2COSEVerify checks the hex COSE_Sign1 "message" against "public_key"
+// under "user_id" and "external_aad". A detached payload is given in
+// "payload". A valid message gives its payload in "plaintext_encoding";
+// one that cannot be parsed is invalid with reason COSE_MALFORMED.
+func sm2COSEVerify(in map[string]interface{}) (*Result, error) {
+	data, err := requireHex(in, "message")
+	if err != nil {
+		return nil, err
+	}
+	detached, _, err := bytesField(in, "payload")
+	if err != nil {
+		return nil, err
+	}
+	aad, _, err := bytesField(in, "external_aad")
+	if err != nil {
+		return nil, err
+	}
+	pub, err := sm2PublicKey(in)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	msg, err := cose.ParseSign1(data, detached)
+	if err != nil {
+		return &Result{Valid: boolPtr(false), Reason: "COSE_MALFORMED"}, nil
+	}
+	if err := msg.Verify(pub, uid, aad); err != nil {
+		return &Result{Valid: boolPtr(false), Reason: coseReasons[err]}, nil
+	}
+	res := &Result{Valid: boolPtr(true)}
+	if res.Output, err = encodePlaintext(in, msg.Payload); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// sm4COSEEncrypt encrypts "plaintext" (or "plaintext_hex" /
+// "plaintext_base64") as a COSE_Encrypt0 with alg SM4-GCM under the
+// 16-byte "key". The 12-byte "iv" is random unless given. The output is
+// the hex CBOR message.
+func sm4COSEEncrypt(in map[string]interface{}) (*Result, error) {
+	key, err := sm4KeyField(in, sm4.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := requireBytes(in, "plaintext")
+	if err != nil {
+		return nil, err
+	}
+	opts := cose.EncryptOptions{}
+	if opts.Options, err = coseOptions(in); err != nil {
+		return nil, err
+	}
+	if opts.IV, _, err = hexField(in, "iv"); err != nil {
+		return nil, err
+	}
+	msg, err := cose.Encrypt0(rand, plaintext, key, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(msg)}, nil
+}
+
+// sm4COSEDecrypt decrypts the hex COSE_Encrypt0 "message" under "key" and
+// "external_aad" and returns the plaintext in "plaintext_encoding".
+func sm4COSEDecrypt(in map[string]interface{}) (*Result, error) {
+	key, err := sm4KeyField(in, sm4.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	data, err := requireHex(in, "message")
+	if err != nil {
+		return nil, err
+	}
+	aad, _, err := bytesField(in, "external_aad")
+	if err != nil {
+		return nil, err
+	}
+	msg, err := cose.ParseEncrypt0(data)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := msg.Decrypt(key, aad)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if res.Output, err = encodePlaintext(in, plaintext); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
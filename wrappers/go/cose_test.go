@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM2COSE(t *testing.T) {
+	key := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	sign := func(extra map[string]interface{}) string {
+		in := map[string]interface{}{"payload": "temp=21.5", "private_key": key.PrivateKey, "kid": "sensor-1", "deterministic": true}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "cose-sign", in).Output
+	}
+	msg := sign(nil)
+	if !strings.HasPrefix(msg, "d2844aa10167534d322d534d33") || sign(nil) != msg {
+		t.Fatalf("sign: %s", msg)
+	}
+	verify := func(extra map[string]interface{}) *Result {
+		in := map[string]interface{}{"message": msg, "public_key": key.PublicKey}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "cose-verify", in)
+	}
+	if res := verify(nil); !*res.Valid || res.Output != "temp=21.5" {
+		t.Errorf("valid: %+v", res)
+	}
+	other := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	for _, tc := range []struct {
+		extra  map[string]interface{}
+		reason string
+	}{
+		{map[string]interface{}{"public_key": other.PublicKey}, "COSE_BAD_SIGNATURE"},
+		{map[string]interface{}{"external_aad": "context"}, "COSE_BAD_SIGNATURE"},
+		{map[string]interface{}{"message": msg[:len(msg)-2]}, "COSE_MALFORMED"},
+		// alg -7 (ES256) in place of "SM2-SM3".
+		{map[string]interface{}{"message": "d28443a10126" + msg[26:]}, "COSE_UNSUPPORTED_ALG"},
+	} {
+		if res := verify(tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%v: %+v, want %s", tc.extra, res, tc.reason)
+		}
+	}
+
+	detached := sign(map[string]interface{}{"detached": true, "external_aad": "context", "untagged": true})
+	if !strings.HasPrefix(detached, "84") {
+		t.Errorf("untagged: %s", detached)
+	}
+	if res := verify(map[string]interface{}{"message": detached, "payload": "temp=21.5", "external_aad": "context"}); !*res.Valid {
+		t.Errorf("detached: %+v", res)
+	}
+}
+
+func TestSM4COSE(t *testing.T) {
+	const key = "0123456789abcdeffedcba9876543210"
+	enc := func(extra map[string]interface{}) string {
+		in := map[string]interface{}{"key": key, "plaintext": "open valve 3"}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm4", "cose-encrypt", in).Output
+	}
+	fixed := enc(map[string]interface{}{"iv": "000102030405060708090a0b"})
+	if !strings.HasPrefix(fixed, "d0834aa10167534d342d47434da1054c000102030405060708090a0b") || enc(map[string]interface{}{"iv": "000102030405060708090a0b"}) != fixed {
+		t.Fatalf("fixed IV: %s", fixed)
+	}
+	if res := mustCall(t, "sm4", "cose-decrypt", map[string]interface{}{"key": key, "message": fixed}); res.Output != "open valve 3" {
+		t.Errorf("decrypt: %+v", res)
+	}
+	msg := enc(map[string]interface{}{"external_aad": "valve", "kid_hex": "01", "plaintext": nil, "plaintext_hex": "00ff"})
+	if res := mustCall(t, "sm4", "cose-decrypt", map[string]interface{}{"key": key, "message": msg, "external_aad": "valve", "plaintext_encoding": "hex"}); res.Output != "00ff" {
+		t.Errorf("binary: %+v", res)
+	}
+	mustFail(t, "sm4", "cose-decrypt", map[string]interface{}{"key": key, "message": msg})
+	mustFail(t, "sm4", "cose-decrypt", map[string]interface{}{"key": "00112233445566778899aabbccddeeff", "message": fixed})
+	mustFail(t, "sm4", "cose-encrypt", map[string]interface{}{"key": key, "plaintext": "x", "iv": "00"})
+	mustFail(t, "sm4", "cose-encrypt", map[string]interface{}{"key": key[:30], "plaintext": "x"})
+}
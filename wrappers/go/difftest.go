@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const difftestUsage = `difftest --peer "<command>" [--algorithm sm2|sm3|sm4|zuc|all] [--count N] [--seed N] [--timeout D]`
+
+// diffReport is the output of difftest.
+type diffReport struct {
+	Peer string `json:"peer"`
+	// Seed reproduces the inputs with gen-vectors or another difftest run.
+	Seed       int64      `json:"seed"`
+	Total      int        `json:"total"`
+	Mismatches []mismatch `json:"mismatches"`
+}
+
+// mismatch is a request whose Results differ between the wrapper and the
+// peer, after minimization.
+type mismatch struct {
+	ID        string                 `json:"id"`
+	Algorithm string                 `json:"algorithm"`
+	Operation string                 `json:"operation"`
+	Request   map[string]interface{} `json:"request"`
+	// Reproducer is the peer's command line for Request.
+	Reproducer []string               `json:"reproducer"`
+	Local      map[string]interface{} `json:"local"`
+	Peer       map[string]interface{} `json:"peer"`
+	Diffs      []vectorDiff           `json:"diffs"`
+}
+
+// comparedFields are compared even when the peer leaves them out.
+// Messages and warnings are free text and never compared.
+var comparedFields = []string{"status", "output", "outputs", "valid"}
+
+// shrinkFields are the payload fields a mismatch is minimized over.
+var shrinkFields = []string{"data", "data_hex", "plaintext", "plaintext_hex", "message"}
+
+// maxShrinkRuns bounds the peer runs spent minimizing one mismatch.
+const maxShrinkRuns = 64
+
+// difftestCommand runs generated requests through the wrapper and through
+// the peer, a wrapper of the cross-language suite run as
+// "<peer> <algorithm> <operation> --input '<json>'", and reports the
+// requests whose Results differ. The exit code is exitInvalid if one did.
+func difftestCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("difftest", flag.ContinueOnError)
+	peer := fs.String("peer", "", "command line of the peer wrapper, split at spaces")
+	algorithm := fs.String("algorithm", "all", "algorithm of the requests: sm2, sm3, sm4, zuc or all")
+	count := fs.Int("count", 100, "number of requests per algorithm")
+	seed := fs.Int64("seed", 0, "seed of the inputs (default: from the clock)")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit of each peer run")
+	if ok, code := parseCommand(fs, difftestUsage, args, 0, stdout); !ok {
+		return code
+	}
+	command := strings.Fields(*peer)
+	if len(command) == 0 {
+		return commandFailed(stdout, fmt.Errorf("--peer is required; usage: wrapper %s", difftestUsage))
+	}
+	algorithms := []string{*algorithm}
+	if *algorithm == "all" {
+		algorithms = sortedKeys(vectorGenerators)
+	} else if _, ok := vectorGenerators[*algorithm]; !ok {
+		return commandFailed(stdout, fmt.Errorf("unsupported --algorithm %q (supported: %s, all)", *algorithm, strings.Join(sortedKeys(vectorGenerators), ", ")))
+	}
+	if *count < 0 {
+		return commandFailed(stdout, fmt.Errorf("--count must not be negative, got %d", *count))
+	}
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		*seed = time.Now().UnixNano()
+	}
+	vf, err := generateVectors(vectorGenerators, algorithms, *count, *seed)
+	if err != nil {
+		return commandFailed(stdout, err)
+	}
+	p := &peerWrapper{command: command, timeout: *timeout}
+	report := &diffReport{Peer: *peer, Seed: *seed, Mismatches: []mismatch{}}
+	for _, v := range vf.Vectors {
+		m, err := p.compare(&v)
+		if err != nil {
+			return commandFailed(stdout, err)
+		}
+		report.Total++
+		if m != nil {
+			report.Mismatches = append(report.Mismatches, *m)
+		}
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if len(report.Mismatches) > 0 {
+		return exitInvalid
+	}
+	return exitSuccess
+}
+
+// peerWrapper runs requests through the peer's command line.
+type peerWrapper struct {
+	command []string
+	timeout time.Duration
+}
+
+// args returns the peer's command line for a request.
+func (p *peerWrapper) args(algorithm, operation string, in map[string]interface{}) []string {
+	b, err := json.Marshal(in)
+	if err != nil {
+		panic(err)
+	}
+	return append(append([]string{}, p.command...), algorithm, operation, "--input", string(b))
+}
+
+// run returns the Result object the peer writes for a request. A peer
+// that cannot be started is an error; output that is not a Result is
+// returned as an error Result of its own, to be reported as a mismatch.
+func (p *peerWrapper) run(algorithm, operation string, in map[string]interface{}) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+	args := p.args(algorithm, operation, in)
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = stderr
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, &codedError{codeIO, fmt.Errorf("running peer: %v", err)}
+	}
+	if ctx.Err() != nil {
+		return map[string]interface{}{"status": statusError, "message": fmt.Sprintf("peer timed out after %v", p.timeout)}, nil
+	}
+	dec := json.NewDecoder(&out)
+	dec.UseNumber()
+	var res map[string]interface{}
+	if err := dec.Decode(&res); err != nil || res == nil {
+		return map[string]interface{}{"status": statusError, "message": fmt.Sprintf("peer output is not a Result: %q", out.String())}, nil
+	}
+	return res, nil
+}
+
+// compare runs the request of v through the peer and, when the Results
+// differ, returns the mismatch for the smallest request still found to
+// differ.
+func (p *peerWrapper) compare(v *vector) (*mismatch, error) {
+	m, err := p.try(v.Algorithm, v.Operation, v.Request, v.Expected)
+	if m == nil || err != nil {
+		return nil, err
+	}
+	m.ID = v.ID
+	for runs := 0; runs < maxShrinkRuns; {
+		shrunk := false
+		for _, in := range shrinkCandidates(m.Request) {
+			runs++
+			local := resultObject(dispatch(v.Algorithm, v.Operation, maps.Clone(in)))
+			smaller, err := p.try(v.Algorithm, v.Operation, in, local)
+			if err != nil {
+				return nil, err
+			}
+			if smaller != nil {
+				smaller.ID = m.ID
+				m, shrunk = smaller, true
+				break
+			}
+			if runs >= maxShrinkRuns {
+				break
+			}
+		}
+		if !shrunk {
+			break
+		}
+	}
+	return m, nil
+}
+
+// try runs a request through the peer and returns the mismatch with the
+// local Result, if any.
+func (p *peerWrapper) try(algorithm, operation string, in, local map[string]interface{}) (*mismatch, error) {
+	peer, err := p.run(algorithm, operation, in)
+	if err != nil {
+		return nil, err
+	}
+	diffs := diffResults(local, peer)
+	if len(diffs) == 0 {
+		return nil, nil
+	}
+	return &mismatch{
+		Algorithm:  algorithm,
+		Operation:  operation,
+		Request:    in,
+		Reproducer: p.args(algorithm, operation, in),
+		Local:      local,
+		Peer:       peer,
+		Diffs:      diffs,
+	}, nil
+}
+
+// diffResults compares the comparedFields and the other fields both
+// Results have, except for messages and warnings.
+func diffResults(local, peer map[string]interface{}) []vectorDiff {
+	fields := map[string]bool{}
+	for _, f := range comparedFields {
+		fields[f] = true
+	}
+	for f := range local {
+		if _, ok := peer[f]; ok && f != "message" && f != "warning" {
+			fields[f] = true
+		}
+	}
+	var diffs []vectorDiff
+	for _, f := range sortedKeys(fields) {
+		if !sameValue(local[f], peer[f]) {
+			diffs = append(diffs, vectorDiff{Field: f, Expected: local[f], Actual: peer[f]})
+		}
+	}
+	return diffs
+}
+
+// shrinkCandidates returns smaller versions of the request in, most
+// reduced first: with a payload field halved from either end, or one byte
+// shorter. Hex payloads are cut at byte boundaries.
+func shrinkCandidates(in map[string]interface{}) []map[string]interface{} {
+	var out []map[string]interface{}
+	for _, name := range shrinkFields {
+		s, ok := in[name].(string)
+		if !ok || s == "" {
+			continue
+		}
+		unit := 1
+		if strings.HasSuffix(name, "_hex") || in[name+"_encoding"] == encodingHex {
+			unit = 2
+		}
+		n := len(s) / unit
+		cuts := [][2]int{{0, n / 2}, {n / 2, n}, {0, n - 1}, {1, n}}
+		seen := map[[2]int]bool{}
+		for _, c := range cuts {
+			if c[1]-c[0] >= n || seen[c] {
+				continue
+			}
+			seen[c] = true
+			smaller := maps.Clone(in)
+			smaller[name] = s[c[0]*unit : c[1]*unit]
+			out = append(out, smaller)
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+)
+
+// peerEnv makes the test binary a peer of difftest: "ok" runs the
+// wrapper, "buggy" gets SM3 hashes of 16 bytes or more wrong.
+const peerEnv = "WRAPPER_TEST_PEER"
+
+func TestMain(m *testing.M) {
+	if mode := os.Getenv(peerEnv); mode != "" {
+		var out bytes.Buffer
+		code := run(os.Args[1:], &out)
+		if mode == "buggy" && strings.Join(os.Args[1:3], " ") == "sm3 hash" {
+			var in map[string]interface{}
+			json.Unmarshal([]byte(os.Args[4]), &in)
+			if len(in["data"].(string)) >= 32 {
+				out.Reset()
+				out.WriteString(`{"status": "success", "output": "00"}`)
+			}
+		}
+		os.Stdout.Write(out.Bytes())
+		os.Exit(code)
+	}
+	os.Exit(m.Run())
+}
+
+func TestDifftest(t *testing.T) {
+	difftest := func(mode string, args ...string) (*diffReport, int) {
+		t.Helper()
+		t.Setenv(peerEnv, mode)
+		var out bytes.Buffer
+		code := run(append([]string{"difftest", "--peer", os.Args[0], "--seed", "7"}, args...), &out)
+		var report diffReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("difftest: %s", out.String())
+		}
+		return &report, code
+	}
+	if report, code := difftest("ok", "--count", "3"); code != 0 || report.Total != 12 || len(report.Mismatches) != 0 || report.Seed != 7 {
+		t.Errorf("same peer: exit %d, %+v", code, report)
+	}
+
+	// The hashes of 17 and 31 bytes differ, and shrink to 16 bytes.
+	report, code := difftest("buggy", "--algorithm", "sm3", "--count", "8")
+	if code != exitInvalid || report.Total != 8 || len(report.Mismatches) != 2 {
+		t.Fatalf("buggy peer: exit %d, %+v", code, report)
+	}
+	for _, m := range report.Mismatches {
+		if data := m.Request["data"].(string); len(data) != 32 || m.Diffs[0].Field != "output" || m.Reproducer[len(m.Reproducer)-2] != "--input" {
+			t.Errorf("%s: %+v", m.ID, m)
+		}
+	}
+
+	for _, args := range [][]string{{"difftest"}, {"difftest", "--peer", "/nonexistent/wrapper"}, {"difftest", "--peer", "x", "--algorithm", "sm9"}} {
+		var out bytes.Buffer
+		if code := run(args, &out); code == 0 || !bytes.Contains(out.Bytes(), []byte(`"status":"error"`)) {
+			t.Errorf("%q exited %d: %s", args, code, out.String())
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// bytesField reads a byte string that may be given as UTF-8 text in name,
+// or as name+"_hex" or name+"_base64". At most one form may be present.
+// A CBOR byte string stands for the bytes themselves in any form.
+func bytesField(in map[string]interface{}, name string) ([]byte, bool, error) {
+	var out []byte
+	found := ""
+	for _, form := range []string{name, name + "_hex", name + "_base64"} {
+		s, ok, err := stringField(in, form)
+		raw, isRaw := in[form].([]byte)
+		if isRaw {
+			ok, err = true, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		if !ok {
+			continue
+		}
+		if found != "" {
+			return nil, false, &codedError{codeInvalidField, fmt.Errorf("fields %q and %q are mutually exclusive", found, form)}
+		}
+		found = form
+		switch {
+		case isRaw:
+			out = raw
+		case form == name:
+			out = []byte(s)
+		case form == name+"_hex":
+			if out, err = hex.DecodeString(s); err != nil {
+				return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid hex: %v", form, err)}
+			}
+		default:
+			if out, err = base64.StdEncoding.DecodeString(s); err != nil {
+				return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid base64: %v", form, err)}
+			}
+		}
+	}
+	return out, found != "", nil
+}
+
+func requireBytes(in map[string]interface{}, name string) ([]byte, error) {
+	b, ok, err := bytesField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q (or %q / %q)", name, name+"_hex", name+"_base64")}
+	}
+	return b, nil
+}
+
+// Text encodings selectable through the *_encoding fields.
+const (
+	encodingUTF8   = "utf8"
+	encodingHex    = "hex"
+	encodingBase64 = "base64"
+	// encodingBase64URL is the URL-safe alphabet without padding; only
+	// "output_encoding" offers it.
+	encodingBase64URL = "base64url"
+)
+
+// encodingField reads the text encoding named by field, defaulting to
+// UTF-8.
+func encodingField(in map[string]interface{}, field string) (string, error) {
+	enc, _, err := stringField(in, field)
+	if err != nil {
+		return "", err
+	}
+	return textEncoding(field, enc)
+}
+
+// textEncoding normalizes enc, the value of the encoding field named
+// field; "" is UTF-8.
+func textEncoding(field, enc string) (string, error) {
+	switch strings.ToLower(enc) {
+	case "", "utf8", "utf-8":
+		return encodingUTF8, nil
+	case encodingHex:
+		return encodingHex, nil
+	case encodingBase64:
+		return encodingBase64, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported %s %q (supported: utf8, hex, base64)", field, enc)}
+}
+
+// decodeText converts s from the text encoding enc to bytes.
+func decodeText(s, enc string) ([]byte, error) {
+	switch enc {
+	case encodingHex:
+		return hex.DecodeString(s)
+	case encodingBase64:
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return []byte(s), nil
+}
+
+// encodedField reads the string field name and decodes it as selected by
+// name+"_encoding" (utf8 by default). A CBOR byte string is taken as is.
+func encodedField(in map[string]interface{}, name string) ([]byte, error) {
+	if b, ok := in[name].([]byte); ok {
+		return b, nil
+	}
+	s, err := requireString(in, name)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := encodingField(in, name+"_encoding")
+	if err != nil {
+		return nil, err
+	}
+	b, err := decodeText(s, enc)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid %s: %v", name, enc, err)}
+	}
+	return b, nil
+}
+
+// plaintextEncoding holds "plaintext_encoding" for the operations that
+// return decrypted data.
+type plaintextEncoding struct {
+	PlaintextEncoding string `json:"plaintext_encoding"`
+}
+
+// encode renders data as selected by "plaintext_encoding": utf8
+// (default), hex or base64. UTF-8 output is refused for data that is not
+// valid UTF-8 rather than silently mangled.
+func (p plaintextEncoding) encode(data []byte) (string, error) {
+	enc, err := textEncoding("plaintext_encoding", p.PlaintextEncoding)
+	if err != nil {
+		return "", err
+	}
+	switch enc {
+	case encodingHex:
+		return hex.EncodeToString(data), nil
+	case encodingBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	}
+	if !utf8.Valid(data) {
+		return "", &codedError{codeInvalidEncoding, fmt.Errorf("plaintext is not valid UTF-8; set plaintext_encoding to hex or base64")}
+	}
+	return string(data), nil
+}
+
+// encodePlaintext renders data as plaintextEncoding.encode does for the
+// "plaintext_encoding" of in.
+func encodePlaintext(in map[string]interface{}, data []byte) (string, error) {
+	enc, _, err := stringField(in, "plaintext_encoding")
+	if err != nil {
+		return "", err
+	}
+	return plaintextEncoding{enc}.encode(data)
+}
+
+// outputEncodingField reads "output_encoding", the rendering of binary
+// result fields: hex (default), base64 or base64url.
+func outputEncodingField(in map[string]interface{}) (string, error) {
+	enc, ok, err := stringField(in, "output_encoding")
+	if err != nil || !ok {
+		return encodingHex, err
+	}
+	switch enc = strings.ToLower(enc); enc {
+	case encodingHex, encodingBase64, encodingBase64URL:
+		return enc, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported output_encoding %q (supported: hex, base64, base64url)", enc)}
+}
+
+// reencode converts the hex string s to enc. Values that are not hex,
+// such as PEM keys, are returned unchanged.
+func reencode(s, enc string) string {
+	b, err := hex.DecodeString(s)
+	if s == "" || err != nil {
+		return s
+	}
+	switch enc {
+	case encodingBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	case encodingBase64URL:
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	return s
+}
+
+// withOutputEncoding wraps h so that the binary fields of its result are
+// rendered in "output_encoding" instead of hex. Output is converted when
+// ioSpecs says it is binary; decrypted plaintext follows output_encoding
+// unless "plaintext_encoding" is given. Inputs remain hex.
+func withOutputEncoding(algorithm, operation string, h handler) handler {
+	return func(in map[string]interface{}) (*Result, error) {
+		enc, err := outputEncodingField(in)
+		if err != nil {
+			return nil, err
+		}
+		if enc == encodingHex {
+			return h(in)
+		}
+		output, outputs := binaryOutputs(algorithm, operation, in)
+		res, err := h(in)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range binaryFields(res, output) {
+			*f = reencode(*f, enc)
+		}
+		if outputs {
+			for i, s := range res.Outputs {
+				res.Outputs[i] = reencode(s, enc)
+			}
+		}
+		return res, nil
+	}
+}
+
+// binaryOutputs reports whether Output and the elements of Outputs are
+// binary for the operation. Decrypted plaintext counts as binary unless
+// the request sets "plaintext_encoding", which is then set to hex.
+func binaryOutputs(algorithm, operation string, in map[string]interface{}) (output, outputs bool) {
+	p := ioSpecs[algorithm][operation]
+	output = p.out == outputHex
+	if _, ok := in["plaintext_encoding"]; p.out == outputPlaintext && !ok {
+		in["plaintext_encoding"] = encodingHex
+		output = true
+	}
+	return output, p.hexOutputs
+}
+
+// binaryFields returns the hex string fields of res by their JSON names:
+// those that always hold bytes, and Output if output is set.
+func binaryFields(res *Result, output bool) map[string]*string {
+	m := map[string]*string{
+		"iv": &res.IV, "tag": &res.Tag, "private_key": &res.PrivateKey, "public_key": &res.PublicKey,
+		"public_key_compressed": &res.PublicKeyCompressed, "fingerprint": &res.Fingerprint,
+		"ephemeral_private_key": &res.EphemeralPrivateKey, "ephemeral_public_key": &res.EphemeralPublicKey,
+		"confirmation": &res.Confirmation, "public_share": &res.PublicShare, "nonce": &res.Nonce,
+		"serial": &res.Serial, "encapsulation": &res.Encapsulation, "derived_key": &res.DerivedKey,
+	}
+	if output {
+		m["output"] = &res.Output
+	}
+	return m
+}
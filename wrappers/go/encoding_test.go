@@ -0,0 +1,75 @@
+package main
+
+import "testing"
+
+func TestSM4BinaryPlaintext(t *testing.T) {
+	// 0xff and 0x80 are not valid UTF-8 and must survive the round trip.
+	const binary = "00ff80fe7f"
+	for _, mode := range []string{"ECB", "CTR", "GCM"} {
+		enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": mode, "plaintext_hex": binary,
+		})
+		in := map[string]interface{}{
+			"key": testSM4Key, "mode": mode, "iv": enc.IV, "tag": enc.Tag,
+			"ciphertext": enc.Output, "plaintext_encoding": "hex",
+		}
+		if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != binary {
+			t.Fatalf("%s: hex round trip = %q", mode, dec.Output)
+		}
+		in["plaintext_encoding"] = "base64"
+		if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != "AP+A/n8=" {
+			t.Fatalf("%s: base64 output = %q", mode, dec.Output)
+		}
+		delete(in, "plaintext_encoding")
+		mustFail(t, "sm4", "decrypt", in)
+	}
+
+	b64 := mustCall(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "plaintext_base64": "AP+A/n8="})
+	hx := mustCall(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "plaintext_hex": binary})
+	if b64.Output != hx.Output {
+		t.Fatal("plaintext_base64 and plaintext_hex disagree")
+	}
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "plaintext": "a", "plaintext_hex": "61"})
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "plaintext_hex": "6"})
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key})
+}
+
+func TestOutputEncoding(t *testing.T) {
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	for enc, want := range map[string]string{
+		"hex":       abc,
+		"base64":    "Zsfw9GLu7dnR8tRr3BDk4kFnxIdc8veiKX2gK49LqOA=",
+		"base64url": "Zsfw9GLu7dnR8tRr3BDk4kFnxIdc8veiKX2gK49LqOA",
+	} {
+		if res := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc", "output_encoding": enc}); res.Output != want {
+			t.Errorf("%s: %s", enc, res.Output)
+		}
+	}
+
+	// Every binary field is converted; plaintext follows output_encoding
+	// unless plaintext_encoding is given.
+	in := map[string]interface{}{"key": testSM4Key, "mode": "GCM", "plaintext": "hi", "output_encoding": "base64"}
+	enc := mustCall(t, "sm4", "encrypt", in)
+	if len(enc.Output) != 4 || len(enc.IV) != 16 || len(enc.Tag) != 24 {
+		t.Fatalf("base64 encryption result: %+v", enc)
+	}
+	hx := mustCall(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "plaintext": "hi", "iv": "000102030405060708090a0b"})
+	dec := map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "iv": "000102030405060708090a0b", "tag": hx.Tag,
+		"ciphertext": hx.Output, "output_encoding": "base64",
+	}
+	if res := mustCall(t, "sm4", "decrypt", dec); res.Output != "aGk=" {
+		t.Errorf("plaintext under output_encoding: %q", res.Output)
+	}
+	dec["plaintext_encoding"] = "utf8"
+	if res := mustCall(t, "sm4", "decrypt", dec); res.Output != "hi" {
+		t.Errorf("plaintext_encoding wins: %q", res.Output)
+	}
+
+	// Text results and PEM keys are left alone.
+	key := mustCall(t, "sm2", "keygen", map[string]interface{}{"key_format": "pem", "output_encoding": "base64"})
+	if len(key.PrivateKey) < 50 || key.PrivateKey[:5] != "-----" {
+		t.Errorf("PEM key re-encoded: %q", key.PrivateKey)
+	}
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "abc", "output_encoding": "base32"})
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// payloadKind is how an operation takes its main input field, and so how
+// the raw contents of "input_file" are passed to it.
+type payloadKind int
+
+const (
+	// payloadBytes fields accept name, name+"_hex" or name+"_base64".
+	payloadBytes payloadKind = iota
+	// payloadHex fields are hex only.
+	payloadHex
+	// payloadText fields are UTF-8 strings; the file must be valid UTF-8.
+	payloadText
+	// payloadEncoded is "data" with "data_encoding" (see encodedField).
+	payloadEncoded
+	// payloadDER fields take PEM text or hex DER (see decodeDER).
+	payloadDER
+)
+
+// outputKind is how an operation renders Output, and so how it is turned
+// back into the raw bytes written to "output_file".
+type outputKind int
+
+const (
+	outputNone outputKind = iota
+	// outputHex is binary data in hex.
+	outputHex
+	// outputPlaintext follows "plaintext_encoding", which is forced to hex.
+	outputPlaintext
+	// outputText is a string written as is, such as a JWS token.
+	outputText
+)
+
+// ioSpec describes the main input and output of an operation.
+type ioSpec struct {
+	field string
+	in    payloadKind
+	out   outputKind
+	// hexOutputs marks operations whose Outputs are binary values in hex.
+	hexOutputs bool
+	// readsFile marks handlers that read "input_file" themselves.
+	readsFile bool
+	// streams reports whether the handler handles both "input_file" and
+	// "output_file" itself for a request.
+	streams func(in map[string]interface{}) bool
+}
+
+// ioSpecs lists the operations that accept "input_file" and
+// "output_file", and which of them return binary data in Output for
+// "output_encoding". Operations without an entry reject both files.
+var ioSpecs = map[string]map[string]ioSpec{
+	"sm2": {
+		"sign":    {out: outputHex, readsFile: true},
+		"verify":  {readsFile: true},
+		"digest":  {out: outputHex, readsFile: true},
+		"encrypt": {field: "plaintext", in: payloadBytes, out: outputHex},
+		"decrypt": {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+
+		"envelope-encrypt":   {field: "plaintext", in: payloadBytes, out: outputHex},
+		"envelope-decrypt":   {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+		"cms-encrypt":        {field: "plaintext", in: payloadBytes, out: outputHex},
+		"cms-decrypt":        {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+		"p7-sign":            {field: "message", in: payloadBytes, out: outputHex},
+		"p7-verify":          {field: "signed_data", in: payloadHex, out: outputPlaintext},
+		"cert-selfsign":      {out: outputHex},
+		"cert-issue":         {out: outputHex},
+		"cert-verify-chain":  {field: "certificate", in: payloadDER},
+		"cert-parse":         {field: "certificate", in: payloadDER},
+		"csr-create":         {out: outputHex},
+		"csr-verify":         {field: "csr", in: payloadDER},
+		"p12-create":         {out: outputHex},
+		"p12-parse":          {field: "pfx", in: payloadHex, hexOutputs: true},
+		"crl-create":         {out: outputHex},
+		"crl-check":          {field: "crl", in: payloadDER},
+		"ocsp-request":       {out: outputHex},
+		"ocsp-respond":       {field: "request", in: payloadHex, out: outputHex},
+		"ocsp-verify":        {field: "response", in: payloadHex},
+		"tsa-request":        {field: "message", in: payloadBytes, out: outputHex},
+		"tsa-sign":           {field: "request", in: payloadHex, out: outputHex},
+		"tsa-verify":         {field: "response", in: payloadHex},
+		"jws-sign":           {field: "payload", in: payloadBytes, out: outputText},
+		"jws-verify":         {field: "token", in: payloadText, out: outputPlaintext},
+		"jwt-sign":           {out: outputText},
+		"jwt-verify":         {field: "token", in: payloadText},
+		"jwe-encrypt":        {field: "plaintext", in: payloadBytes, out: outputText},
+		"jwe-decrypt":        {field: "token", in: payloadText, out: outputPlaintext},
+		"cose-sign":          {field: "payload", in: payloadBytes, out: outputHex},
+		"cose-verify":        {field: "message", in: payloadHex, out: outputPlaintext},
+		"convert-signature":  {field: "signature", in: payloadHex, out: outputHex},
+		"recover-pub":        {hexOutputs: true},
+		"key-split":          {hexOutputs: true},
+		"cosign-sign-client": {out: outputHex, readsFile: true},
+		"cosign-sign-server": {out: outputHex},
+		"cosign-sign-finish": {out: outputHex},
+
+		"keyexchange-respond": {out: outputHex},
+		"keyexchange-confirm": {out: outputHex},
+		"encapsulate":         {out: outputHex},
+		"decapsulate":         {out: outputHex},
+		"ecdh":                {out: outputHex},
+	},
+	"sm3": {
+		"hash":   {out: outputHex, readsFile: true, hexOutputs: true},
+		"hmac":   {field: "data", in: payloadEncoded, out: outputHex},
+		"hkdf":   {out: outputHex},
+		"kdf":    {field: "shared_secret", in: payloadHex, out: outputHex},
+		"pbkdf2": {out: outputHex},
+		"mgf1":   {field: "seed", in: payloadHex, out: outputHex},
+		"init":   {field: "data", in: payloadEncoded},
+		"update": {field: "data", in: payloadEncoded},
+		"final":  {out: outputHex},
+	},
+	"sm9": {
+		"sign":        {field: "message", in: payloadBytes, out: outputHex},
+		"verify":      {field: "message", in: payloadBytes},
+		"encrypt":     {field: "plaintext", in: payloadBytes, out: outputHex},
+		"decrypt":     {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+		"encapsulate": {out: outputHex},
+		"decapsulate": {out: outputHex},
+	},
+	"sm4": {
+		"encrypt": {field: "plaintext", in: payloadBytes, out: outputHex, streams: sm4Streams},
+		"decrypt": {field: "ciphertext", in: payloadHex, out: outputPlaintext, streams: sm4Streams},
+		"wrap":    {field: "key_data", in: payloadHex, out: outputHex},
+		"unwrap":  {field: "wrapped_key", in: payloadHex, out: outputHex},
+		"cmac":    {field: "data", in: payloadEncoded, out: outputHex},
+		"cbcmac":  {field: "data", in: payloadEncoded, out: outputHex},
+
+		"cose-encrypt": {field: "plaintext", in: payloadBytes, out: outputHex},
+		"cose-decrypt": {field: "message", in: payloadHex, out: outputPlaintext},
+
+		"encrypt-update": {field: "plaintext", in: payloadBytes, out: outputHex},
+		"decrypt-update": {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+		"encrypt-final":  {field: "plaintext", in: payloadBytes, out: outputHex},
+		"decrypt-final":  {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+	},
+	"zuc": {
+		"encrypt":   {field: "plaintext", in: payloadBytes, out: outputHex},
+		"decrypt":   {field: "ciphertext", in: payloadHex, out: outputPlaintext},
+		"mac":       {field: "data", in: payloadBytes, out: outputHex},
+		"keystream": {out: outputHex},
+	},
+	"tlcp": {
+		"client": {field: "probe", in: payloadBytes, out: outputPlaintext},
+	},
+	"tls13": {
+		"client": {field: "probe", in: payloadBytes, out: outputPlaintext},
+	},
+}
+
+// sm4Streams reports whether sm4Encrypt and sm4Decrypt stream the request
+// through sm4CryptFile; the modes that cannot are read into memory instead.
+func sm4Streams(in map[string]interface{}) bool {
+	var req struct {
+		InputFile *string `json:"input_file"`
+		Mode      string  `json:"mode"`
+	}
+	if err := decodeRequest(in, &req); err != nil || req.InputFile == nil {
+		return false
+	}
+	switch strings.ToUpper(req.Mode) {
+	case "", "ECB", "CBC", "CTR", "CFB", "OFB":
+		return true
+	}
+	return false
+}
+
+// localFields are the request fields that reach the file system or
+// standard input of the wrapper, which the peers of the server modes may
+// not use.
+var localFields = []string{"input_file", "output_file", "stdin", "keystore_file", "log_file"}
+
+// checkRemote rejects a request from a server mode that names one of
+// localFields.
+func checkRemote(in map[string]interface{}) error {
+	for _, f := range localFields {
+		if _, ok := in[f]; ok {
+			return &codedError{codeInvalidField, fmt.Errorf("field %q is not available in server modes", f)}
+		}
+	}
+	return nil
+}
+
+// withFiles wraps h so that the main input of the operation can come from
+// "input_file" and its main output go to "output_file", both as raw bytes.
+// A written output is left out of Output and counted in Result.Bytes.
+func withFiles(algorithm, operation string, h handler) handler {
+	return func(in map[string]interface{}) (*Result, error) {
+		inPath, hasIn, err := stringField(in, "input_file")
+		if err != nil {
+			return nil, err
+		}
+		outPath, hasOut, err := stringField(in, "output_file")
+		if err != nil {
+			return nil, err
+		}
+		if !hasIn && !hasOut {
+			return h(in)
+		}
+		p := ioSpecs[algorithm][operation]
+		if p.streams != nil && p.streams(in) {
+			return h(in)
+		}
+		if hasIn && !p.readsFile {
+			if p.field == "" {
+				return nil, &codedError{codeInvalidField, fmt.Errorf("%s %s does not take \"input_file\"", algorithm, operation)}
+			}
+			data, err := os.ReadFile(inPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := setPayload(in, p, data); err != nil {
+				return nil, err
+			}
+			delete(in, "input_file")
+		}
+		if hasOut {
+			if p.out == outputNone {
+				return nil, &codedError{codeInvalidField, fmt.Errorf("%s %s does not take \"output_file\"", algorithm, operation)}
+			}
+			if p.out == outputPlaintext {
+				in["plaintext_encoding"] = encodingHex
+			}
+			delete(in, "output_file")
+		}
+		res, err := h(in)
+		if err != nil || !hasOut || (res.Valid != nil && !*res.Valid) {
+			return res, err
+		}
+		data := []byte(res.Output)
+		if p.out != outputText {
+			if data, err = hex.DecodeString(res.Output); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.WriteFile(outPath, data, 0o644); err != nil {
+			return nil, err
+		}
+		res.Output = ""
+		res.Bytes = int64(len(data))
+		return res, nil
+	}
+}
+
+// setPayload stores the contents of "input_file" in the input field p
+// describes, which must not also be given.
+func setPayload(in map[string]interface{}, p ioSpec, data []byte) error {
+	forms := []string{p.field}
+	if p.in == payloadBytes {
+		forms = append(forms, p.field+"_hex", p.field+"_base64")
+	}
+	for _, f := range forms {
+		if _, ok := in[f]; ok {
+			return &codedError{codeInvalidField, fmt.Errorf("fields %q and \"input_file\" are mutually exclusive", f)}
+		}
+	}
+	switch p.in {
+	case payloadBytes:
+		in[p.field+"_hex"] = hex.EncodeToString(data)
+	case payloadHex:
+		in[p.field] = hex.EncodeToString(data)
+	case payloadEncoded:
+		in[p.field] = hex.EncodeToString(data)
+		in["data_encoding"] = encodingHex
+	case payloadDER:
+		if strings.HasPrefix(strings.TrimSpace(string(data)), "-----BEGIN") {
+			in[p.field] = string(data)
+		} else {
+			in[p.field] = hex.EncodeToString(data)
+		}
+	case payloadText:
+		if !utf8.Valid(data) {
+			return &codedError{codeInvalidEncoding, fmt.Errorf("\"input_file\" is not valid UTF-8, which field %q requires", p.field)}
+		}
+		in[p.field] = string(data)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestInputOutputFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	content := []byte{0x00, 0xff, 0x10, 'a', 0x80, 0xfe}
+	if err := os.WriteFile(path("plain"), content, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	zuc := func(extra map[string]interface{}) map[string]interface{} {
+		m := map[string]interface{}{"key": "00000000000000000000000000000000", "count": 7}
+		for k, v := range extra {
+			m[k] = v
+		}
+		return m
+	}
+	enc := mustCall(t, "zuc", "encrypt", zuc(map[string]interface{}{"input_file": path("plain"), "output_file": path("ct")}))
+	ct, _ := os.ReadFile(path("ct"))
+	if enc.Output != "" || enc.Bytes != int64(len(content)) || len(ct) != len(content) {
+		t.Fatalf("encrypt to a file: %+v, wrote %x", enc, ct)
+	}
+	// The file holds the bytes the in-memory call returns in hex.
+	mem := mustCall(t, "zuc", "encrypt", zuc(map[string]interface{}{"plaintext_hex": hex.EncodeToString(content)}))
+	if mem.Output != hex.EncodeToString(ct) {
+		t.Fatalf("file ciphertext %x, in-memory %s", ct, mem.Output)
+	}
+	// Decryption renders no plaintext_encoding into the file.
+	mustCall(t, "zuc", "decrypt", zuc(map[string]interface{}{"input_file": path("ct"), "output_file": path("pt")}))
+	if pt, _ := os.ReadFile(path("pt")); !bytes.Equal(pt, content) {
+		t.Fatalf("decrypted %x, want %x", pt, content)
+	}
+	// Only the input from a file; the output stays in the result.
+	if res := mustCall(t, "zuc", "decrypt", zuc(map[string]interface{}{"input_file": path("ct"), "plaintext_encoding": "hex"})); res.Output != hex.EncodeToString(content) {
+		t.Errorf("decrypt from a file: %s", res.Output)
+	}
+
+	// Binary plaintext through SM2, and binary data through a MAC.
+	key := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	if err := os.WriteFile(path("msg"), []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mustCall(t, "sm2", "encrypt", map[string]interface{}{"public_key": key.PublicKey, "input_file": path("plain"), "output_file": path("sm2ct")})
+	mustCall(t, "sm2", "decrypt", map[string]interface{}{"private_key": key.PrivateKey, "input_file": path("sm2ct"), "output_file": path("sm2pt")})
+	if pt, _ := os.ReadFile(path("sm2pt")); !bytes.Equal(pt, content) {
+		t.Errorf("SM2 round trip: %x", pt)
+	}
+	// MACs take binary files too.
+	mac := mustCall(t, "sm3", "hmac", map[string]interface{}{"key": "6b6579", "input_file": path("plain")})
+	if want := mustCall(t, "sm3", "hmac", map[string]interface{}{"key": "6b6579", "data": hex.EncodeToString(content), "data_encoding": "hex"}); mac.Output != want.Output {
+		t.Errorf("HMAC of a file = %s, want %s", mac.Output, want.Output)
+	}
+	// Handlers that read the file themselves still write output_file.
+	sign := mustCall(t, "sm2", "sign", map[string]interface{}{"private_key": key.PrivateKey, "input_file": path("msg"), "output_file": path("sig")})
+	sig, _ := os.ReadFile(path("sig"))
+	if sign.Bytes != int64(len(sig)) {
+		t.Fatalf("signature: %+v", sign)
+	}
+	if res := mustCall(t, "sm2", "verify", map[string]interface{}{"public_key": key.PublicKey, "message": "hello", "signature": hex.EncodeToString(sig)}); !*res.Valid {
+		t.Error("the signature written to a file does not verify")
+	}
+
+	for _, tc := range []struct {
+		algorithm, operation string
+		in                   map[string]interface{}
+	}{
+		// Operations without a payload.
+		{"sm2", "keygen", map[string]interface{}{"input_file": path("plain")}},
+		{"sm2", "keygen", map[string]interface{}{"output_file": path("x")}},
+		{"sm2", "cert-parse", map[string]interface{}{"certificate": "00", "output_file": path("x")}},
+		// The payload given twice.
+		{"zuc", "encrypt", zuc(map[string]interface{}{"plaintext": "a", "input_file": path("plain")})},
+		// Binary data for a text field.
+		{"sm2", "jwt-verify", map[string]interface{}{"public_key": "00", "input_file": path("plain")}},
+		{"zuc", "encrypt", zuc(map[string]interface{}{"input_file": path("missing")})},
+	} {
+		mustFail(t, tc.algorithm, tc.operation, tc.in)
+	}
+}
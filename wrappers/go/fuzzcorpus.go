@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const fuzzCorpusUsage = "fuzz-corpus export [--dir <dir>] [--target <name>] [--output <file>]"
+
+// fuzzTarget is the request a fuzz target of fuzzcorpus_test.go makes
+// from its arguments. The targets and fuzz-corpus export share it, so that
+// a corpus entry becomes the same request in a vector.
+type fuzzTarget struct {
+	algorithm, operation string
+	// args are the Go types of the fuzz arguments, as written in corpus
+	// files.
+	args    []string
+	request func(args []interface{}) map[string]interface{}
+}
+
+// fuzzTargets are the fuzz targets by the name of their test function.
+var fuzzTargets = map[string]fuzzTarget{
+	"FuzzSM4Decrypt": {"sm4", "decrypt", []string{"[]byte", "[]byte", "[]byte", "[]byte", "byte"}, func(a []interface{}) map[string]interface{} {
+		return sm4DecryptFuzzRequest(a[0].([]byte), a[1].([]byte), a[2].([]byte), a[3].([]byte), a[4].(byte))
+	}},
+	"FuzzSM2Verify": {"sm2", "verify", []string{"[]byte", "[]byte", "string", "byte"}, func(a []interface{}) map[string]interface{} {
+		return sm2VerifyFuzzRequest(a[0].([]byte), a[1].([]byte), a[2].(string), a[3].(byte))
+	}},
+	"FuzzSM2Decrypt": {"sm2", "decrypt", []string{"[]byte", "byte"}, func(a []interface{}) map[string]interface{} {
+		return sm2DecryptFuzzRequest(a[0].([]byte), a[1].(byte))
+	}},
+	"FuzzCertParse": {"sm2", "cert-parse", []string{"[]byte"}, func(a []interface{}) map[string]interface{} {
+		return certParseFuzzRequest(a[0].([]byte))
+	}},
+}
+
+// sm4DecryptFuzzRequest decrypts ct in the mode selected by mode. The iv
+// is the tweak of XTS; the tag is used by GCM and CCM only.
+func sm4DecryptFuzzRequest(key, iv, ct, tag []byte, mode byte) map[string]interface{} {
+	m := sm4Modes[int(mode)%len(sm4Modes)]
+	in := map[string]interface{}{"key": hex.EncodeToString(key), "mode": m, "ciphertext": hex.EncodeToString(ct),
+		"plaintext_encoding": encodingHex}
+	switch m {
+	case "ECB":
+	case "XTS":
+		in["tweak"] = hex.EncodeToString(iv)
+	default:
+		in["iv"] = hex.EncodeToString(iv)
+	}
+	if m == "GCM" || m == "CCM" {
+		in["tag"] = hex.EncodeToString(tag)
+	}
+	return in
+}
+
+// sm2VerifyFuzzRequest verifies sig over message in the signature format
+// selected by format.
+func sm2VerifyFuzzRequest(pub, sig []byte, message string, format byte) map[string]interface{} {
+	return map[string]interface{}{"public_key": hex.EncodeToString(pub), "signature": hex.EncodeToString(sig),
+		"message": message, "signature_format": []string{sigFormatRS, sigFormatDER}[format%2]}
+}
+
+// sm2DecryptFuzzRequest decrypts ct with the key of the GM/T 0003.5
+// example, so that the standard's ciphertext is a seed that decrypts.
+// format selects C1C3C2, C1C2C3, auto detection or the ASN.1 encoding.
+func sm2DecryptFuzzRequest(ct []byte, format byte) map[string]interface{} {
+	in := map[string]interface{}{"private_key": katSM2Private, "ciphertext": hex.EncodeToString(ct)}
+	switch format % 4 {
+	case 0:
+		in["ciphertext_format"] = ctFormatC1C3C2
+	case 1:
+		in["ciphertext_format"] = ctFormatC1C2C3
+	case 2:
+		in["ciphertext_format"] = ctFormatAuto
+	case 3:
+		in["encoding"] = "asn1"
+	}
+	return in
+}
+
+// certParseFuzzRequest parses der as a certificate.
+func certParseFuzzRequest(der []byte) map[string]interface{} {
+	return map[string]interface{}{"certificate": hex.EncodeToString(der)}
+}
+
+// fuzzExpectedFields are the fields of the wrapper's Result kept in an
+// exported vector: what other wrappers must agree on, without the
+// wrapper's own descriptions of parsed objects.
+var fuzzExpectedFields = []string{"status", "error_code", "output", "tag", "valid"}
+
+// fuzzCorpusCommand exports the corpus entries of the fuzz targets as a
+// vectorFile, each with the Result the wrapper gives for it, so that
+// inputs found by go test -fuzz are replayed against the other wrappers
+// with verify-vectors.
+func fuzzCorpusCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("fuzz-corpus", flag.ContinueOnError)
+	dir := fs.String("dir", filepath.Join("testdata", "fuzz"), "corpus directory, with a directory per fuzz target")
+	target := fs.String("target", "", "fuzz target to export (default: all)")
+	output := fs.String("output", "", "file to write instead of standard output")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		// The flags follow the subcommand.
+		args = append(args[1:], args[0])
+	}
+	if ok, code := parseCommand(fs, fuzzCorpusUsage, args, 1, stdout); !ok {
+		return code
+	}
+	if fs.Arg(0) != "export" {
+		return commandFailed(stdout, fmt.Errorf("unknown fuzz-corpus command %q; usage: wrapper %s", fs.Arg(0), fuzzCorpusUsage))
+	}
+	targets := sortedKeys(fuzzTargets)
+	if *target != "" {
+		if _, ok := fuzzTargets[*target]; !ok {
+			return commandFailed(stdout, fmt.Errorf("unknown --target %q (supported: %s)", *target, strings.Join(targets, ", ")))
+		}
+		targets = []string{*target}
+	}
+	vf, err := exportCorpus(*dir, targets)
+	if err != nil {
+		return commandFailed(stdout, err)
+	}
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return commandFailed(stdout, &codedError{codeIO, err})
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vf); err != nil {
+		return commandFailed(stdout, &codedError{codeIO, err})
+	}
+	return exitSuccess
+}
+
+// exportCorpus makes a vector of every entry in dir/<target> for targets.
+// A target without a corpus directory has no entries.
+func exportCorpus(dir string, targets []string) (*vectorFile, error) {
+	vf := &vectorFile{Generator: "sm-bc-test go wrapper " + buildVersion().Wrapper + " fuzz corpus", Vectors: []vector{}}
+	for _, name := range targets {
+		t := fuzzTargets[name]
+		entries, err := os.ReadDir(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, &codedError{codeIO, err}
+		}
+		for _, e := range entries {
+			if e.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, name, e.Name())
+			b, err := os.ReadFile(path)
+			if err != nil {
+				return nil, &codedError{codeIO, err}
+			}
+			values, err := parseCorpusEntry(b, t.args)
+			if err != nil {
+				return nil, &codedError{codeInvalidField, fmt.Errorf("%s: %v", path, err)}
+			}
+			v := vector{ID: fmt.Sprintf("%s-%s-fuzz-%s", t.algorithm, t.operation, e.Name()), Algorithm: t.algorithm,
+				Operation: t.operation, Request: t.request(values), Expected: map[string]interface{}{}}
+			res, err := fuzzDispatch(t.algorithm, t.operation, maps.Clone(v.Request))
+			if err != nil {
+				return nil, fmt.Errorf("%s: %v", path, err)
+			}
+			got := resultObject(res)
+			for _, f := range fuzzExpectedFields {
+				if x, ok := got[f]; ok {
+					v.Expected[f] = x
+				}
+			}
+			vf.Vectors = append(vf.Vectors, v)
+		}
+	}
+	return vf, nil
+}
+
+// fuzzDispatch runs a request made from a corpus entry. An entry may be a
+// crash that go test -fuzz saved and that is not fixed yet; it is an
+// error rather than a vector that expects whatever the wrapper gives.
+func fuzzDispatch(algorithm, operation string, in map[string]interface{}) (res *Result, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &codedError{codeOperationFailed, fmt.Errorf("the wrapper panics: %v", p)}
+		}
+	}()
+	return dispatch(algorithm, operation, in), nil
+}
+
+// parseCorpusEntry parses a corpus file of go test -fuzz ("go test fuzz
+// v1" and a line per argument) into values of the types in types.
+func parseCorpusEntry(b []byte, types []string) ([]interface{}, error) {
+	sc := bufio.NewScanner(bytes.NewReader(b))
+	sc.Buffer(nil, len(b)+1)
+	if !sc.Scan() || sc.Text() != "go test fuzz v1" {
+		return nil, errors.New(`not a corpus file: the first line is not "go test fuzz v1"`)
+	}
+	var values []interface{}
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		if len(values) == len(types) {
+			return nil, fmt.Errorf("more than %d arguments", len(types))
+		}
+		typ, lit, ok := strings.Cut(line, "(")
+		if !ok || !strings.HasSuffix(lit, ")") {
+			return nil, fmt.Errorf("malformed argument %q", line)
+		}
+		lit = lit[:len(lit)-1]
+		want := types[len(values)]
+		if typ == "uint8" {
+			typ = "byte"
+		}
+		if typ != want {
+			return nil, fmt.Errorf("argument %d is %s, want %s", len(values)+1, typ, want)
+		}
+		v, err := parseCorpusValue(typ, lit)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %v", len(values)+1, err)
+		}
+		values = append(values, v)
+	}
+	if len(values) < len(types) {
+		return nil, fmt.Errorf("%d arguments, want %d", len(values), len(types))
+	}
+	return values, nil
+}
+
+// parseCorpusValue parses the literal of a corpus argument of type typ:
+// a quoted string, or a byte as a character or an integer.
+func parseCorpusValue(typ, lit string) (interface{}, error) {
+	switch typ {
+	case "[]byte", "string":
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, fmt.Errorf("malformed string %s", lit)
+		}
+		if typ == "string" {
+			return s, nil
+		}
+		return []byte(s), nil
+	case "byte":
+		if len(lit) >= 3 && lit[0] == '\'' && lit[len(lit)-1] == '\'' {
+			r, _, tail, err := strconv.UnquoteChar(lit[1:len(lit)-1], '\'')
+			if err != nil || tail != "" {
+				return nil, fmt.Errorf("malformed byte %s", lit)
+			}
+			if r > 0xff {
+				return nil, fmt.Errorf("byte %s out of range", lit)
+			}
+			return byte(r), nil
+		}
+		n, err := strconv.ParseUint(lit, 0, 8)
+		if err != nil {
+			return nil, fmt.Errorf("malformed byte %s", lit)
+		}
+		return byte(n), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", typ)
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// fuzzCall runs the request of target for args and checks what every
+// Result must satisfy: an error has a code and a message. A panic fails
+// the target by itself.
+func fuzzCall(t *testing.T, name string, args ...interface{}) (map[string]interface{}, *Result) {
+	t.Helper()
+	target := fuzzTargets[name]
+	in := target.request(args)
+	res := dispatch(target.algorithm, target.operation, maps.Clone(in))
+	switch res.Status {
+	case statusSuccess:
+	case statusError:
+		if res.ErrorCode == "" || res.Message == "" {
+			t.Fatalf("%v: error without code or message: %+v", in, res)
+		}
+	default:
+		t.Fatalf("%v: status %q", in, res.Status)
+	}
+	return in, res
+}
+
+// fuzzSeed returns the output of a request that must succeed, for seeds
+// that reach past the input checks.
+func fuzzSeed(f *testing.F, algorithm, operation string, in map[string]interface{}) *Result {
+	f.Helper()
+	res := dispatch(algorithm, operation, in)
+	if res.Status != statusSuccess {
+		f.Fatalf("%s %s: %s", algorithm, operation, res.Message)
+	}
+	return res
+}
+
+// FuzzSM4Decrypt checks that a ciphertext that decrypts is what the
+// plaintext encrypts to again, with the same key, IV and tag length.
+func FuzzSM4Decrypt(f *testing.F) {
+	key := "0123456789abcdeffedcba9876543210"
+	for i, mode := range sm4Modes {
+		in := map[string]interface{}{"key": key, "mode": mode, "plaintext": "fuzz seed plaintext"}
+		var iv string
+		switch mode {
+		case "ECB":
+		case "XTS":
+			in["key"] = key + "fedcba98765432100123456789abcdef"
+			iv, in["tweak"] = strings.Repeat("00", 16), strings.Repeat("00", 16)
+		case "GCM", "CCM":
+			iv, in["iv"] = strings.Repeat("00", 12), strings.Repeat("00", 12)
+		default:
+			iv, in["iv"] = strings.Repeat("00", 16), strings.Repeat("00", 16)
+		}
+		res := fuzzSeed(f, "sm4", "encrypt", in)
+		f.Add(mustDecodeHex(f, in["key"].(string)), mustDecodeHex(f, iv), mustDecodeHex(f, res.Output), mustDecodeHex(f, res.Tag), byte(i))
+	}
+	f.Fuzz(func(t *testing.T, key, iv, ct, tag []byte, mode byte) {
+		in, res := fuzzCall(t, "FuzzSM4Decrypt", key, iv, ct, tag, mode)
+		if res.Status != statusSuccess {
+			return
+		}
+		enc := maps.Clone(in)
+		delete(enc, "ciphertext")
+		delete(enc, "tag")
+		delete(enc, "plaintext_encoding")
+		enc["plaintext_hex"] = res.Output
+		if _, ok := in["tag"]; ok {
+			enc["tag_length"] = json.Number(strconv.Itoa(len(tag)))
+		}
+		again := dispatch("sm4", "encrypt", enc)
+		wantTag, _ := in["tag"].(string)
+		if again.Status != statusSuccess || again.Output != in["ciphertext"] || again.Tag != wantTag {
+			t.Fatalf("%v decrypts, but its plaintext encrypts to %+v", in, again)
+		}
+	})
+}
+
+// FuzzSM2Verify checks that verification gives a verdict for any
+// signature under a valid public key.
+func FuzzSM2Verify(f *testing.F) {
+	for _, ka := range knownAnswers {
+		if ka.ID == "sm2-verify" {
+			r := ka.Request
+			f.Add(mustDecodeHex(f, r["public_key"].(string)), mustDecodeHex(f, r["signature"].(string)), r["message"].(string), byte(0))
+		}
+	}
+	der := fuzzSeed(f, "sm2", "sign", map[string]interface{}{"private_key": katSM2Private, "message": "message digest",
+		"signature_format": sigFormatDER})
+	f.Add(mustDecodeHex(f, katSM2Public), mustDecodeHex(f, der.Output), "message digest", byte(1))
+	f.Fuzz(func(t *testing.T, pub, sig []byte, message string, format byte) {
+		in, res := fuzzCall(t, "FuzzSM2Verify", pub, sig, message, format)
+		if res.Status == statusSuccess && res.Valid == nil {
+			t.Fatalf("%v: no verdict: %+v", in, res)
+		}
+	})
+}
+
+// FuzzSM2Decrypt checks that decryption of any ciphertext returns or
+// fails with a code.
+func FuzzSM2Decrypt(f *testing.F) {
+	for _, ka := range knownAnswers {
+		if ka.ID == "sm2-decrypt" {
+			f.Add(mustDecodeHex(f, ka.Request["ciphertext"].(string)), byte(0))
+		}
+	}
+	for format := range 4 {
+		in := sm2DecryptFuzzRequest(nil, byte(format))
+		delete(in, "ciphertext")
+		delete(in, "private_key")
+		in["public_key"], in["plaintext"] = katSM2Public, "fuzz seed plaintext"
+		if in["ciphertext_format"] == ctFormatAuto {
+			delete(in, "ciphertext_format")
+		}
+		f.Add(mustDecodeHex(f, fuzzSeed(f, "sm2", "encrypt", in).Output), byte(format))
+	}
+	f.Fuzz(func(t *testing.T, ct []byte, format byte) {
+		fuzzCall(t, "FuzzSM2Decrypt", ct, format)
+	})
+}
+
+// FuzzCertParse checks that a certificate that parses is described.
+func FuzzCertParse(f *testing.F) {
+	res := fuzzSeed(f, "sm2", "cert-selfsign", map[string]interface{}{"private_key": katSM2Private,
+		"subject": map[string]interface{}{"CN": "fuzz seed"}, "serial": "01",
+		"not_before": "2026-01-01T00:00:00Z", "not_after": "2036-01-01T00:00:00Z"})
+	f.Add(mustDecodeHex(f, res.Output))
+	f.Fuzz(func(t *testing.T, der []byte) {
+		in, res := fuzzCall(t, "FuzzCertParse", der)
+		if res.Status == statusSuccess && res.Certificate == nil {
+			t.Fatalf("%v: no description: %+v", in, res)
+		}
+	})
+}
+
+func TestFuzzCorpusExport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(target, name, content string) {
+		t.Helper()
+		os.MkdirAll(filepath.Join(dir, target), 0o755)
+		if err := os.WriteFile(filepath.Join(dir, target, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var katCiphertext string
+	for _, ka := range knownAnswers {
+		if ka.ID == "sm2-decrypt" {
+			katCiphertext = ka.Request["ciphertext"].(string)
+		}
+	}
+	// Bytes as go test -fuzz writes them, and as integers.
+	write("FuzzSM2Decrypt", "kat", fmt.Sprintf("go test fuzz v1\n[]byte(%q)\nbyte('\\x00')\n",
+		mustDecodeHex(t, katCiphertext)))
+	write("FuzzSM4Decrypt", "short", "go test fuzz v1\n[]byte(\"\\x01#Eg\\x89\\xab\\xcd\\xef\\xfe\\xdc\\xba\\x98vT2\\x10\")\n"+
+		"[]byte(\"\")\n[]byte(\"\\x00\")\n[]byte(\"\")\nuint8(8)\n")
+
+	path := filepath.Join(dir, "corpus.json")
+	var out bytes.Buffer
+	if code := run([]string{"fuzz-corpus", "export", "--dir", dir, "--output", path}, &out); code != 0 {
+		t.Fatalf("fuzz-corpus export: %s", out.String())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vf vectorFile
+	if err := json.Unmarshal(b, &vf); err != nil || len(vf.Vectors) != 2 {
+		t.Fatalf("%d vectors, %v: %s", len(vf.Vectors), err, b)
+	}
+	if v := vf.Vectors[0]; v.ID != "sm2-decrypt-fuzz-kat" || v.Expected["output"] != "encryption standard" || v.Request["private_key"] != katSM2Private {
+		t.Errorf("SM2 vector: %+v", v)
+	}
+	if v := vf.Vectors[1]; v.Request["mode"] != "ECB" || v.Expected["error_code"] != codeInvalidDataLength || v.Expected["message"] != nil {
+		t.Errorf("SM4 vector: %+v", v)
+	}
+	out.Reset()
+	if code := run([]string{"verify-vectors", path}, &out); code != 0 {
+		t.Errorf("verify-vectors: %s", out.String())
+	}
+
+	out.Reset()
+	if code := run([]string{"fuzz-corpus", "export", "--dir", dir, "--target", "FuzzCertParse"}, &out); code != 0 ||
+		!bytes.Contains(out.Bytes(), []byte(`"vectors": []`)) {
+		t.Errorf("target without a corpus: exit %d, %s", code, out.String())
+	}
+	write("FuzzSM2Verify", "bad", "go test fuzz v1\n[]byte(\"\")\nbyte('\\x00')\n")
+	for _, args := range [][]string{{"export", "--dir", dir}, {"import"}, {}, {"export", "--target", "FuzzNothing"}} {
+		out.Reset()
+		if code := run(append([]string{"fuzz-corpus"}, args...), &out); code == 0 || !bytes.Contains(out.Bytes(), []byte(`"status":"error"`)) {
+			t.Errorf("fuzz-corpus %q exited %d: %s", args, code, out.String())
+		}
+	}
+}
+
+func TestParseCorpusEntry(t *testing.T) {
+	types := []string{"[]byte", "string", "byte"}
+	values, err := parseCorpusEntry([]byte("go test fuzz v1\n[]byte(\"\\xff\")\nstring(\"é\")\nbyte('ÿ')\n"), types)
+	if err != nil || !bytes.Equal(values[0].([]byte), []byte{0xff}) || values[1] != "é" || values[2] != byte(0xff) {
+		t.Fatalf("%v, %v", values, err)
+	}
+	for _, entry := range []string{
+		"[]byte(\"\")\nstring(\"\")\nbyte(0)\n",                         // no header
+		"go test fuzz v1\n[]byte(\"\")\nstring(\"\")\n",                 // too few
+		"go test fuzz v1\n[]byte(\"\")\nstring(\"\")\nbyte(0)\nbyte(0)", // too many
+		"go test fuzz v1\nstring(\"\")\nstring(\"\")\nbyte(0)\n",        // wrong type
+		"go test fuzz v1\n[]byte(\"\")\nstring(\"\")\nbyte(256)\n",
+		"go test fuzz v1\n[]byte(\"\")\nstring(\"\")\nbyte('ā')\n",
+		"go test fuzz v1\n[]byte(\"\")\nstring(\"\")\nbyte\n",
+	} {
+		if _, err := parseCorpusEntry([]byte(entry), types); err == nil {
+			t.Errorf("%q parsed", entry)
+		}
+	}
+}
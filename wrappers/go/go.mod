@@ -0,0 +1,3 @@
+module github.com/lihongjie0209/sm-bc-test/wrappers/go
+
+go 1.24
@@ -0,0 +1,345 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/grpc"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pb"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/websocket"
+)
+
+// grpcService is the service name of proto/wrapper.proto.
+const grpcService = "smwrapper.v1.Wrapper"
+
+// newGRPCServer returns the methods of proto/wrapper.proto. The typed
+// methods build a request for dispatch, so they behave like the JSON
+// interface; a failed operation is INVALID_ARGUMENT.
+func newGRPCServer() *grpc.Server {
+	return &grpc.Server{Service: grpcService, Methods: map[string]grpc.Handler{
+		"Invoke":           unary(grpcInvoke),
+		"Sm3Hash":          unary(grpcSM3Hash),
+		"Sm3HashStream":    grpcSM3HashStream,
+		"Sm4Encrypt":       unary(grpcSM4("encrypt")),
+		"Sm4Decrypt":       unary(grpcSM4("decrypt")),
+		"Sm4EncryptStream": grpcSM4Stream("encrypt"),
+		"Sm4DecryptStream": grpcSM4Stream("decrypt"),
+		"Sm2Sign":          unary(grpcSM2Sign),
+		"Sm2Verify":        unary(grpcSM2Verify),
+	}}
+}
+
+// newHTTPHandler serves the gRPC service, at /ws JSON-RPC 2.0 over
+// WebSocket for the browser tests, and at /metrics the request metrics.
+// WebSocket handshakes from web pages of other origins than origins and
+// the server itself are refused.
+func newHTTPHandler(origins []string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/", newGRPCServer())
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocket(w, r, origins)
+	})
+	mux.Handle("GET /metrics", metrics)
+	return mux
+}
+
+// serveWebSocket answers each message of a WebSocket connection as
+// --serve-stdio answers a line (see rpcAnswer), with a text message.
+func serveWebSocket(w http.ResponseWriter, r *http.Request, origins []string) {
+	c, err := websocket.Upgrade(w, r, origins)
+	if err != nil {
+		return
+	}
+	defer c.Close()
+	for {
+		_, msg, err := c.ReadMessage()
+		if err != nil {
+			return
+		}
+		reply := rpcAnswer(msg)
+		if reply == nil {
+			continue
+		}
+		var b bytes.Buffer
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.Encode(reply)
+		if err := c.WriteMessage(websocket.OpText, bytes.TrimSuffix(b.Bytes(), []byte("\n"))); err != nil {
+			return
+		}
+	}
+}
+
+// serveGRPC serves the gRPC service and the WebSocket endpoint on addr,
+// over cleartext HTTP/2 and HTTP/1.1, until the process is stopped. The
+// listening event goes to stderr as in the TLCP and TLS server modes.
+func serveGRPC(addr string, origins []string) int {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	log := json.NewEncoder(stderr)
+	log.Encode(&serverEvent{Event: "listening", Time: now(), Address: ln.Addr().String(), Protocol: "gRPC"})
+	stdin = errReader{errNoStdin}
+	err = grpc.NewH2CServer(newHTTPHandler(origins)).Serve(ln)
+	fmt.Fprintln(os.Stderr, err)
+	return 1
+}
+
+// unary adapts a function from request to response message.
+func unary(f func(msg []byte) ([]byte, error)) grpc.Handler {
+	return func(s *grpc.Stream) error {
+		msg, err := s.Recv()
+		if err == io.EOF {
+			return grpc.Errorf(grpc.InvalidArgument, "missing request message")
+		}
+		if err != nil {
+			return err
+		}
+		out, err := f(msg)
+		if err != nil {
+			return err
+		}
+		return s.Send(out)
+	}
+}
+
+// parseMessage stores the fields of msg in fields, keyed by field number:
+// *[]byte and *string for length-delimited fields, *bool for varints.
+// Unknown fields are skipped.
+func parseMessage(msg []byte, fields map[int]interface{}) error {
+	parsed, err := pb.Parse(msg)
+	if err != nil {
+		return grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	for _, f := range parsed {
+		var err error
+		switch dst := fields[f.Num].(type) {
+		case *[]byte:
+			*dst, err = f.Bytes, f.Check(pb.TypeBytes)
+		case *string:
+			*dst, err = string(f.Bytes), f.Check(pb.TypeBytes)
+		case *bool:
+			*dst, err = f.Varint != 0, f.Check(pb.TypeVarint)
+		}
+		if err != nil {
+			return grpc.Errorf(grpc.InvalidArgument, "%v", err)
+		}
+	}
+	return nil
+}
+
+// grpcDispatch runs an operation for a typed method.
+func grpcDispatch(algorithm, operation string, in map[string]interface{}) (*Result, error) {
+	res := serverDispatch(algorithm, operation, in)
+	if res.Status != statusSuccess {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%s", res.Message)
+	}
+	return res, nil
+}
+
+// setHex sets field name of in to b in hex unless b is empty.
+func setHex(in map[string]interface{}, name string, b []byte) {
+	if len(b) > 0 {
+		in[name] = hex.EncodeToString(b)
+	}
+}
+
+// setString sets field name of in to s unless s is empty.
+func setString(in map[string]interface{}, name, s string) {
+	if s != "" {
+		in[name] = s
+	}
+}
+
+func grpcInvoke(msg []byte) ([]byte, error) {
+	var algorithm, operation, input string
+	if err := parseMessage(msg, map[int]interface{}{1: &algorithm, 2: &operation, 3: &input}); err != nil {
+		return nil, err
+	}
+	in, _, err := readInput(strings.NewReader(input), true)
+	if err != nil {
+		return nil, grpc.Errorf(grpc.InvalidArgument, "%v", err)
+	}
+	res, err := grpcDispatch(strings.ToLower(algorithm), strings.ToLower(operation), in)
+	if err != nil {
+		return nil, err
+	}
+	out, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return pb.AppendBytes(nil, 1, out), nil
+}
+
+func grpcSM3Hash(msg []byte) ([]byte, error) {
+	var data []byte
+	if err := parseMessage(msg, map[int]interface{}{1: &data}); err != nil {
+		return nil, err
+	}
+	res, err := grpcDispatch("sm3", "hash", map[string]interface{}{"data": hex.EncodeToString(data), "data_encoding": encodingHex})
+	if err != nil {
+		return nil, err
+	}
+	digest, _ := hex.DecodeString(res.Output)
+	return pb.AppendBytes(nil, 1, digest), nil
+}
+
+func grpcSM3HashStream(s *grpc.Stream) error {
+	h := newSM3()
+	for {
+		msg, err := s.Recv()
+		if err == io.EOF {
+			return s.Send(pb.AppendBytes(nil, 1, h.Sum(nil)))
+		}
+		if err != nil {
+			return err
+		}
+		var data []byte
+		if err := parseMessage(msg, map[int]interface{}{1: &data}); err != nil {
+			return err
+		}
+		h.Write(data)
+	}
+}
+
+// grpcSM4 returns Sm4Encrypt or Sm4Decrypt.
+func grpcSM4(op string) func(msg []byte) ([]byte, error) {
+	return func(msg []byte) ([]byte, error) {
+		var key, iv, data, aad, tag []byte
+		var mode, padding string
+		if err := parseMessage(msg, map[int]interface{}{
+			1: &key, 2: &mode, 3: &iv, 4: &data, 5: &aad, 6: &tag, 7: &padding,
+		}); err != nil {
+			return nil, err
+		}
+		in := map[string]interface{}{"key": hex.EncodeToString(key)}
+		setString(in, "mode", mode)
+		setString(in, "padding", padding)
+		setHex(in, "iv", iv)
+		setHex(in, "aad", aad)
+		setHex(in, "tag", tag)
+		if op == "encrypt" {
+			in["plaintext_hex"] = hex.EncodeToString(data)
+		} else {
+			in["ciphertext"] = hex.EncodeToString(data)
+			in["plaintext_encoding"] = encodingHex
+		}
+		res, err := grpcDispatch("sm4", op, in)
+		if err != nil {
+			return nil, err
+		}
+		out, _ := hex.DecodeString(res.Output)
+		resIV, _ := hex.DecodeString(res.IV)
+		resTag, _ := hex.DecodeString(res.Tag)
+		return pb.AppendBytes(pb.AppendBytes(pb.AppendBytes(nil, 1, out), 2, resIV), 3, resTag), nil
+	}
+}
+
+// grpcSM4Stream returns Sm4EncryptStream or Sm4DecryptStream, which run
+// the incremental operations of sm4incremental.go over the messages.
+func grpcSM4Stream(op string) grpc.Handler {
+	field := "plaintext_hex"
+	if op == "decrypt" {
+		field = "ciphertext"
+	}
+	return func(s *grpc.Stream) error {
+		var ctx string
+		var pendingIV []byte
+		// step runs one incremental operation and sends its output.
+		step := func(operation string, in map[string]interface{}) error {
+			in["context"], in["plaintext_encoding"] = ctx, encodingHex
+			res, err := grpcDispatch("sm4", operation, in)
+			if err != nil {
+				return err
+			}
+			ctx = res.Context
+			out, _ := hex.DecodeString(res.Output)
+			if len(out) == 0 && pendingIV == nil {
+				return nil
+			}
+			reply := pb.AppendBytes(pb.AppendBytes(nil, 1, out), 2, pendingIV)
+			pendingIV = nil
+			return s.Send(reply)
+		}
+		for first := true; ; first = false {
+			msg, err := s.Recv()
+			if err == io.EOF {
+				if first {
+					return grpc.Errorf(grpc.InvalidArgument, "missing request message")
+				}
+				return step(op+"-final", map[string]interface{}{})
+			}
+			if err != nil {
+				return err
+			}
+			var key, iv, data []byte
+			var mode, padding string
+			if err := parseMessage(msg, map[int]interface{}{1: &key, 2: &mode, 3: &iv, 4: &padding, 5: &data}); err != nil {
+				return err
+			}
+			if first {
+				in := map[string]interface{}{"key": hex.EncodeToString(key)}
+				setString(in, "mode", mode)
+				setString(in, "padding", padding)
+				setHex(in, "iv", iv)
+				res, err := grpcDispatch("sm4", op+"-init", in)
+				if err != nil {
+					return err
+				}
+				ctx = res.Context
+				pendingIV, _ = hex.DecodeString(res.IV)
+			} else if len(key) > 0 || len(iv) > 0 || mode != "" || padding != "" {
+				return grpc.Errorf(grpc.InvalidArgument, "parameters are only accepted in the first message")
+			}
+			if len(data) > 0 {
+				if err := step(op+"-update", map[string]interface{}{field: hex.EncodeToString(data)}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+func grpcSM2Sign(msg []byte) ([]byte, error) {
+	var priv, message []byte
+	var uid, format string
+	if err := parseMessage(msg, map[int]interface{}{1: &priv, 2: &message, 3: &uid, 4: &format}); err != nil {
+		return nil, err
+	}
+	in := map[string]interface{}{"message": string(message)}
+	setHex(in, "private_key", priv)
+	setString(in, "user_id", uid)
+	setString(in, "signature_format", format)
+	res, err := grpcDispatch("sm2", "sign", in)
+	if err != nil {
+		return nil, err
+	}
+	sig, _ := hex.DecodeString(res.Output)
+	return pb.AppendBytes(nil, 1, sig), nil
+}
+
+func grpcSM2Verify(msg []byte) ([]byte, error) {
+	var pub, message, sig []byte
+	var uid, format string
+	if err := parseMessage(msg, map[int]interface{}{1: &pub, 2: &message, 3: &sig, 4: &uid, 5: &format}); err != nil {
+		return nil, err
+	}
+	in := map[string]interface{}{"message": string(message), "signature": hex.EncodeToString(sig)}
+	setHex(in, "public_key", pub)
+	setString(in, "user_id", uid)
+	setString(in, "signature_format", format)
+	res, err := grpcDispatch("sm2", "verify", in)
+	if err != nil {
+		return nil, err
+	}
+	return pb.AppendBool(nil, 1, res.Valid != nil && *res.Valid), nil
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/grpc"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pb"
+)
+
+func TestGRPC(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewH2CServer(newHTTPHandler(nil))
+	go srv.Serve(ln)
+	defer srv.Close()
+	client := grpc.NewH2CClient()
+	base := "http://" + ln.Addr().String() + "/" + grpcService
+
+	// call invokes method and returns the single response's fields.
+	call := func(method string, requests ...[]byte) ([]pb.Field, error) {
+		t.Helper()
+		out, err := grpc.Call(client, base, method, requests...)
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != 1 {
+			t.Fatalf("%s: %d responses", method, len(out))
+		}
+		return pb.Parse(out[0])
+	}
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+
+	f, err := call("Sm3Hash", pb.AppendString(nil, 1, "abc"))
+	if err != nil || hex.EncodeToString(f[0].Bytes) != abc {
+		t.Fatalf("Sm3Hash: %v %v", f, err)
+	}
+	f, err = call("Sm3HashStream", pb.AppendString(nil, 1, "a"), nil, pb.AppendString(nil, 1, "bc"))
+	if err != nil || hex.EncodeToString(f[0].Bytes) != abc {
+		t.Fatalf("Sm3HashStream: %v %v", f, err)
+	}
+
+	f, err = call("Invoke", pb.AppendString(pb.AppendString(pb.AppendString(nil, 1, "sm3"), 2, "hash"), 3, `{"data": "abc"}`))
+	var res Result
+	if err != nil || json.Unmarshal(f[0].Bytes, &res) != nil || res.Output != abc {
+		t.Fatalf("Invoke: %v %v", f, err)
+	}
+	var st *grpc.Error
+	if _, err := call("Invoke", pb.AppendString(nil, 1, "sm3")); !errors.As(err, &st) || st.Code != grpc.InvalidArgument {
+		t.Errorf("Invoke without an operation: %v", err)
+	}
+
+	// SM4 unary and streaming agree.
+	key, _ := hex.DecodeString(testSM4Key)
+	iv := bytes.Repeat([]byte{1}, 16)
+	plaintext := []byte(strings.Repeat("0123456789", 10))
+	sm4Req := func(data []byte) []byte {
+		return pb.AppendBytes(pb.AppendBytes(pb.AppendString(pb.AppendBytes(nil, 1, key), 2, "CBC"), 3, iv), 4, data)
+	}
+	f, err = call("Sm4Encrypt", sm4Req(plaintext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := f[0].Bytes
+	out, err := grpc.Call(client, base, "Sm4EncryptStream",
+		pb.AppendBytes(pb.AppendString(pb.AppendBytes(nil, 1, key), 2, "CBC"), 3, iv),
+		pb.AppendBytes(nil, 5, plaintext[:7]), pb.AppendBytes(nil, 5, plaintext[7:]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []byte
+	for _, msg := range out {
+		fields, _ := pb.Parse(msg)
+		for _, f := range fields {
+			if f.Num == 1 {
+				streamed = append(streamed, f.Bytes...)
+			}
+		}
+	}
+	if !bytes.Equal(streamed, ct) {
+		t.Fatalf("streamed ciphertext %x, unary %x", streamed, ct)
+	}
+	f, err = call("Sm4Decrypt", sm4Req(ct))
+	if err != nil || !bytes.Equal(f[0].Bytes, plaintext) {
+		t.Fatalf("Sm4Decrypt: %v", err)
+	}
+	if _, err := call("Sm4Encrypt", pb.AppendBytes(nil, 1, []byte{1})); !errors.As(err, &st) || st.Code != grpc.InvalidArgument {
+		t.Errorf("short key: %v", err)
+	}
+
+	kp := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	priv, _ := hex.DecodeString(kp.PrivateKey)
+	pub, _ := hex.DecodeString(kp.PublicKey)
+	msg := []byte{0xff, 0x00, 'm'}
+	f, err = call("Sm2Sign", pb.AppendBytes(pb.AppendBytes(nil, 1, priv), 2, msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	verify := func(m []byte) bool {
+		f, err := call("Sm2Verify", pb.AppendBytes(pb.AppendBytes(pb.AppendBytes(nil, 1, pub), 2, m), 3, f[0].Bytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return len(f) == 1 && f[0].Varint == 1
+	}
+	if !verify(msg) || verify([]byte("other")) {
+		t.Error("Sm2Verify does not check the signature")
+	}
+}
+
+func TestWebSocket(t *testing.T) {
+	srv := httptest.NewServer(newHTTPHandler(nil))
+	defer srv.Close()
+	nc, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer nc.Close()
+	io.WriteString(nc, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake: %v %v", resp, err)
+	}
+	// call sends a text message with an all-zero mask and returns the
+	// text of the reply.
+	call := func(msg string) string {
+		t.Helper()
+		frame := []byte{0x81, 0x80 | 126}
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(msg)))
+		nc.Write(append(append(frame, 0, 0, 0, 0), msg...))
+		var hdr [4]byte
+		if _, err := io.ReadFull(br, hdr[:2]); err != nil {
+			t.Fatal(err)
+		}
+		n := int(hdr[1])
+		if n == 126 {
+			io.ReadFull(br, hdr[2:])
+			n = int(binary.BigEndian.Uint16(hdr[2:]))
+		}
+		reply := make([]byte, n)
+		io.ReadFull(br, reply)
+		return string(reply)
+	}
+	var res struct {
+		ID     int     `json:"id"`
+		Result *Result `json:"result"`
+		Error  *struct {
+			Code int     `json:"code"`
+			Data *Result `json:"data"`
+		} `json:"error"`
+	}
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	reply := call(`{"jsonrpc": "2.0", "id": 7, "method": "sm3.hash", "params": {"data": "abc"}}`)
+	if json.Unmarshal([]byte(reply), &res) != nil || res.ID != 7 || res.Result == nil || res.Result.Output != abc {
+		t.Fatalf("sm3.hash: %s", reply)
+	}
+	reply = call(`{"jsonrpc": "2.0", "id": 8, "method": "sm3.nope"}`)
+	if json.Unmarshal([]byte(reply), &res) != nil || res.Error == nil || res.Error.Code != rpcMethodNotFound {
+		t.Fatalf("unknown method: %s", reply)
+	}
+
+	// Peers cannot reach the files of the wrapper.
+	for _, params := range []string{`{"key": "` + testSM4Key + `", "iv": "` + testSM4Key + `", "input_file": "/etc/hostname"}`, `{"data": "abc", "output_file": "x"}`} {
+		res.Error = nil
+		reply = call(`{"jsonrpc": "2.0", "id": 9, "method": "zuc.encrypt", "params": ` + params + `}`)
+		if json.Unmarshal([]byte(reply), &res) != nil || res.Error == nil || res.Error.Data == nil || res.Error.Data.ErrorCode != codeInvalidField {
+			t.Errorf("file access: %s", reply)
+		}
+	}
+
+	// Web pages of other origins cannot connect.
+	for _, origin := range []string{"https://evil.example", "null"} {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Origin", origin)
+		if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Origin %s: %v %v", origin, resp, err)
+		}
+	}
+
+	// gRPC over HTTP/1.1 is refused.
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/"+grpcService+"/Sm3Hash", nil)
+	req.Header.Set("Content-Type", "application/grpc")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusHTTPVersionNotSupported {
+		t.Errorf("gRPC over HTTP/1.1: %v %v", resp, err)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// handler runs one operation on the decoded --input object. Handlers read
+// it into a typed struct with decodeRequest, or field by field with the
+// helpers below where fields come in several forms (bytesField) or depend
+// on each other.
+type handler func(in map[string]interface{}) (*Result, error)
+
+var handlers = map[string]map[string]handler{
+	"sm2": {
+		"sign":    sm2Sign,
+		"verify":  sm2Verify,
+		"encrypt": sm2Encrypt,
+		"decrypt": sm2Decrypt,
+		"keygen":  sm2Keygen,
+		"digest":  sm2Digest,
+
+		"verify-batch":      sm2VerifyBatch,
+		"recover-pub":       sm2RecoverPub,
+		"envelope-encrypt":  sm2EnvelopeEncrypt,
+		"envelope-decrypt":  sm2EnvelopeDecrypt,
+		"cms-encrypt":       sm2CMSEncrypt,
+		"cms-decrypt":       sm2EnvelopeDecrypt,
+		"key-split":         sm2KeySplit,
+		"key-combine":       sm2KeyCombine,
+		"p7-sign":           sm2P7Sign,
+		"p7-verify":         sm2P7Verify,
+		"cert-selfsign":     sm2CertSelfSign,
+		"cert-issue":        sm2CertIssue,
+		"cert-verify-chain": sm2CertVerifyChain,
+		"cert-parse":        sm2CertParse,
+		"csr-create":        sm2CSRCreate,
+		"csr-verify":        sm2CSRVerify,
+		"p12-create":        sm2P12Create,
+		"p12-parse":         sm2P12Parse,
+		"crl-create":        sm2CRLCreate,
+		"crl-check":         sm2CRLCheck,
+		"ocsp-request":      sm2OCSPRequest,
+		"ocsp-respond":      sm2OCSPRespond,
+		"ocsp-verify":       sm2OCSPVerify,
+		"tsa-request":       sm2TSARequest,
+		"tsa-sign":          sm2TSASign,
+		"tsa-verify":        sm2TSAVerify,
+		"jws-sign":          sm2JWSSign,
+		"jws-verify":        sm2JWSVerify,
+		"jwt-sign":          sm2JWTSign,
+		"jwt-verify":        sm2JWTVerify,
+		"jwe-encrypt":       sm2JWEEncrypt,
+		"jwe-decrypt":       sm2JWEDecrypt,
+		"cose-sign":         sm2COSESign,
+		"cose-verify":       sm2COSEVerify,
+		"convert-signature": sm2ConvertSignature,
+		"derive-pub":        sm2DerivePub,
+		"validate-key":      sm2ValidateKey,
+
+		"keyexchange-init":    sm2KeyExchangeInit,
+		"keyexchange-respond": sm2KeyExchangeRespond,
+		"keyexchange-confirm": sm2KeyExchangeConfirm,
+
+		"encapsulate": sm2Encapsulate,
+		"decapsulate": sm2Decapsulate,
+		"ecdh":        sm2ECDH,
+
+		"cosign-keygen-client": sm2CoSignKeygenClient,
+		"cosign-keygen-server": sm2CoSignKeygenServer,
+		"cosign-sign-client":   sm2CoSignSignClient,
+		"cosign-sign-server":   sm2CoSignSignServer,
+		"cosign-sign-finish":   sm2CoSignSignFinish,
+	},
+	"sm3": {
+		"hash":   sm3Hash,
+		"hmac":   sm3HMAC,
+		"hkdf":   sm3HKDF,
+		"kdf":    sm3KDF,
+		"pbkdf2": sm3PBKDF2,
+		"mgf1":   sm3MGF1,
+		"init":   sm3Init,
+		"update": sm3Update,
+		"final":  sm3Final,
+	},
+	"keystore": {
+		"create": keystoreCreate,
+		"list":   keystoreList,
+		"get":    keystoreGet,
+		"put":    keystorePut,
+		"delete": keystoreDelete,
+	},
+	"sm9": {
+		"master-keygen": sm9MasterKeygen,
+		"user-keygen":   sm9UserKeygen,
+		"sign":          sm9Sign,
+		"verify":        sm9Verify,
+		"encrypt":       sm9Encrypt,
+		"decrypt":       sm9Decrypt,
+		"encapsulate":   sm9Encapsulate,
+		"decapsulate":   sm9Decapsulate,
+	},
+	"sm4": checkSM4Keys(map[string]handler{
+		"encrypt": sm4Encrypt,
+		"decrypt": sm4Decrypt,
+		"wrap":    sm4Wrap,
+		"unwrap":  sm4Unwrap,
+		"cmac":    sm4CMAC,
+		"cbcmac":  sm4CBCMAC,
+
+		"cose-encrypt": sm4COSEEncrypt,
+		"cose-decrypt": sm4COSEDecrypt,
+
+		"encrypt-init":   sm4IncrementalInit("encrypt"),
+		"encrypt-update": sm4IncrementalUpdate("encrypt"),
+		"encrypt-final":  sm4IncrementalFinal("encrypt"),
+		"decrypt-init":   sm4IncrementalInit("decrypt"),
+		"decrypt-update": sm4IncrementalUpdate("decrypt"),
+		"decrypt-final":  sm4IncrementalFinal("decrypt"),
+	}),
+	"zuc": {
+		"encrypt":   zucEncrypt,
+		"decrypt":   zucDecrypt,
+		"mac":       zucMAC,
+		"keystream": zucKeystream,
+	},
+	"tlcp": {
+		"client": tlcpClient,
+		"server": tlcpServer,
+	},
+	"tls13": {
+		"client": tls13Client,
+		"server": tls13Server,
+	},
+	"wrapper": {
+		"version":      wrapperVersion,
+		"capabilities": wrapperCapabilities,
+	},
+}
+
+func lookup(algorithm, operation string) (handler, error) {
+	ops, ok := handlers[algorithm]
+	if !ok {
+		return nil, &codedError{codeUnsupportedOperation, fmt.Errorf("unsupported algorithm %q (supported: %s)", algorithm, strings.Join(sortedKeys(handlers), ", "))}
+	}
+	h, ok := ops[operation]
+	if !ok {
+		return nil, &codedError{codeUnsupportedOperation, fmt.Errorf("unsupported operation %q for %s (supported: %s)", operation, algorithm, strings.Join(sortedKeys(ops), ", "))}
+	}
+	return h, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// stringField returns the string field name and whether it was present.
+func stringField(in map[string]interface{}, name string) (string, bool, error) {
+	v, ok := in[name]
+	if !ok || v == nil {
+		return "", false, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", false, &codedError{codeInvalidField, fmt.Errorf("field %q must be a string", name)}
+	}
+	return s, true, nil
+}
+
+func requireString(in map[string]interface{}, name string) (string, error) {
+	s, ok, err := stringField(in, name)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return s, nil
+}
+
+// stringListField returns the array of strings field name and whether it
+// was present.
+func stringListField(in map[string]interface{}, name string) ([]string, bool, error) {
+	v, ok := in[name]
+	if !ok || v == nil {
+		return nil, false, nil
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, false, &codedError{codeInvalidField, fmt.Errorf("field %q must be an array of strings", name)}
+	}
+	out := make([]string, len(list))
+	for i, item := range list {
+		if out[i], ok = item.(string); !ok {
+			return nil, false, &codedError{codeInvalidField, fmt.Errorf("%s[%d] must be a string", name, i)}
+		}
+	}
+	return out, true, nil
+}
+
+// hexField decodes the hex string field name and reports whether it was present.
+// A CBOR byte string is taken as is.
+func hexField(in map[string]interface{}, name string) ([]byte, bool, error) {
+	if b, ok := in[name].([]byte); ok {
+		return b, true, nil
+	}
+	s, ok, err := stringField(in, name)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid hex: %v", name, err)}
+	}
+	return b, true, nil
+}
+
+func requireHex(in map[string]interface{}, name string) ([]byte, error) {
+	b, ok, err := hexField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return b, nil
+}
+
+// intField returns the integer field name and whether it was present.
+func intField(in map[string]interface{}, name string) (int, bool, error) {
+	v, ok := in[name]
+	if !ok || v == nil {
+		return 0, false, nil
+	}
+	var n int64
+	var err error
+	switch v := v.(type) {
+	case json.Number:
+		n, err = v.Int64()
+	case float64:
+		n = int64(v)
+		if float64(n) != v {
+			err = errors.New("not an integer")
+		}
+	default:
+		err = errors.New("not a number")
+	}
+	if err != nil {
+		return 0, false, &codedError{codeInvalidField, fmt.Errorf("field %q must be an integer", name)}
+	}
+	return int(n), true, nil
+}
+
+// boolField reads an optional boolean flag; an absent flag is false.
+func boolField(in map[string]interface{}, name string) (bool, error) {
+	v, ok := in[name]
+	if !ok || v == nil {
+		return false, nil
+	}
+	b, ok := v.(bool)
+	if !ok {
+		return false, &codedError{codeInvalidField, fmt.Errorf("field %q must be a boolean", name)}
+	}
+	return b, nil
+}
+
+func boolPtr(b bool) *bool { return &b }
@@ -0,0 +1,283 @@
+// Package cbor encodes and decodes the subset of CBOR (RFC 8949) that COSE
+// messages use: integers, byte and text strings, arrays, maps, tags, and
+// the simple values false, true and null.
+//
+// Marshal writes the deterministic encoding of RFC 8949 section 4.2.1:
+// arguments in their shortest form, definite lengths only, and map keys
+// sorted by the bytewise order of their encodings. Unmarshal accepts any
+// definite-length encoding of the subset, and a Decoder reads a sequence of
+// data items from a stream.
+package cbor
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Major types.
+const (
+	majorUint   = 0
+	majorNegInt = 1
+	majorBytes  = 2
+	majorText   = 3
+	majorArray  = 4
+	majorMap    = 5
+	majorTag    = 6
+	majorSimple = 7
+)
+
+// Tag is a tagged data item.
+type Tag struct {
+	Number  uint64
+	Content interface{}
+}
+
+// Map is a CBOR map. Unmarshal gives integer keys as int64 and text keys
+// as string.
+type Map map[interface{}]interface{}
+
+// maxDepth bounds the nesting Unmarshal follows.
+const maxDepth = 32
+
+// Marshal returns the deterministic encoding of v, which is built from
+// int, int64, uint64, []byte, string, []interface{}, Map, Tag, bool and
+// nil.
+func Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := encode(&buf, v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeHead(buf *bytes.Buffer, major byte, arg uint64) {
+	m := major << 5
+	switch {
+	case arg < 24:
+		buf.WriteByte(m | byte(arg))
+	case arg <= math.MaxUint8:
+		buf.Write([]byte{m | 24, byte(arg)})
+	case arg <= math.MaxUint16:
+		buf.WriteByte(m | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(arg)))
+	case arg <= math.MaxUint32:
+		buf.WriteByte(m | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(arg)))
+	default:
+		buf.WriteByte(m | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, arg))
+	}
+}
+
+func encode(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case int:
+		return encode(buf, int64(v))
+	case int64:
+		if v < 0 {
+			writeHead(buf, majorNegInt, uint64(-(v + 1)))
+		} else {
+			writeHead(buf, majorUint, uint64(v))
+		}
+	case uint64:
+		writeHead(buf, majorUint, v)
+	case []byte:
+		writeHead(buf, majorBytes, uint64(len(v)))
+		buf.Write(v)
+	case string:
+		writeHead(buf, majorText, uint64(len(v)))
+		buf.WriteString(v)
+	case []interface{}:
+		writeHead(buf, majorArray, uint64(len(v)))
+		for _, e := range v {
+			if err := encode(buf, e); err != nil {
+				return err
+			}
+		}
+	case Map:
+		type entry struct{ key, value []byte }
+		entries := make([]entry, 0, len(v))
+		for k, e := range v {
+			key, err := Marshal(k)
+			if err != nil {
+				return err
+			}
+			value, err := Marshal(e)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, entry{key, value})
+		}
+		sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+		writeHead(buf, majorMap, uint64(len(entries)))
+		for i, e := range entries {
+			if i > 0 && bytes.Equal(e.key, entries[i-1].key) {
+				return errors.New("cbor: duplicate map key")
+			}
+			buf.Write(e.key)
+			buf.Write(e.value)
+		}
+	case Tag:
+		writeHead(buf, majorTag, v.Number)
+		return encode(buf, v.Content)
+	case bool:
+		if v {
+			buf.WriteByte(0xf5)
+		} else {
+			buf.WriteByte(0xf4)
+		}
+	case nil:
+		buf.WriteByte(0xf6)
+	default:
+		return fmt.Errorf("cbor: cannot encode %T", v)
+	}
+	return nil
+}
+
+// Unmarshal decodes a single data item filling all of data.
+func Unmarshal(data []byte) (interface{}, error) {
+	d := &decoder{data: data}
+	v, err := d.item(0)
+	if err != nil {
+		return nil, err
+	}
+	if d.off != len(data) {
+		return nil, errors.New("cbor: trailing data")
+	}
+	return v, nil
+}
+
+var errTruncated = errors.New("cbor: truncated data")
+
+type decoder struct {
+	data []byte
+	off  int
+}
+
+func (d *decoder) head() (major byte, arg uint64, err error) {
+	if d.off >= len(d.data) {
+		return 0, 0, errTruncated
+	}
+	b := d.data[d.off]
+	d.off++
+	major, info := b>>5, b&0x1f
+	var n int
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		n = 1
+	case info == 25:
+		n = 2
+	case info == 26:
+		n = 4
+	case info == 27:
+		n = 8
+	default:
+		return 0, 0, fmt.Errorf("cbor: unsupported additional information %d", info)
+	}
+	if len(d.data)-d.off < n {
+		return 0, 0, errTruncated
+	}
+	for _, c := range d.data[d.off : d.off+n] {
+		arg = arg<<8 | uint64(c)
+	}
+	d.off += n
+	return major, arg, nil
+}
+
+func (d *decoder) bytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.off) {
+		return nil, errTruncated
+	}
+	b := d.data[d.off : d.off+int(n)]
+	d.off += int(n)
+	return b, nil
+}
+
+func (d *decoder) item(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, errors.New("cbor: nesting too deep")
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case majorUint:
+		if arg > math.MaxInt64 {
+			return arg, nil
+		}
+		return int64(arg), nil
+	case majorNegInt:
+		if arg > math.MaxInt64 {
+			return nil, errors.New("cbor: negative integer out of range")
+		}
+		return -1 - int64(arg), nil
+	case majorBytes:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case majorText:
+		b, err := d.bytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		if arg > uint64(len(d.data)-d.off) {
+			return nil, errTruncated
+		}
+		a := make([]interface{}, arg)
+		for i := range a {
+			if a[i], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	case majorMap:
+		if arg > uint64(len(d.data)-d.off)/2 {
+			return nil, errTruncated
+		}
+		m := Map{}
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.item(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, fmt.Errorf("cbor: unsupported map key type %T", k)
+			}
+			if _, ok := m[k]; ok {
+				return nil, errors.New("cbor: duplicate map key")
+			}
+			if m[k], err = d.item(depth + 1); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	case majorTag:
+		content, err := d.item(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Tag{Number: arg, Content: content}, nil
+	}
+	switch arg {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("cbor: unsupported simple value or float (argument %d)", arg)
+}
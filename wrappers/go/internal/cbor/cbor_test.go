@@ -0,0 +1,122 @@
+package cbor
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// Examples from RFC 8949 appendix A, in their deterministic encodings.
+var examples = []struct {
+	value interface{}
+	hex   string
+}{
+	{int64(0), "00"},
+	{int64(23), "17"},
+	{int64(24), "1818"},
+	{int64(1000), "1903e8"},
+	{int64(1000000), "1a000f4240"},
+	{int64(1000000000000), "1b000000e8d4a51000"},
+	{uint64(18446744073709551615), "1bffffffffffffffff"},
+	{int64(-1), "20"},
+	{int64(-1000), "3903e7"},
+	{false, "f4"},
+	{true, "f5"},
+	{nil, "f6"},
+	{[]byte{}, "40"},
+	{[]byte{1, 2, 3, 4}, "4401020304"},
+	{"", "60"},
+	{"IETF", "6449455446"},
+	{"ü", "62c3bc"},
+	{[]interface{}{}, "80"},
+	{[]interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}, "8301820203820405"},
+	{Map{}, "a0"},
+	{Map{int64(1): int64(2), int64(3): int64(4)}, "a201020304"},
+	{Map{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}, "a26161016162820203"},
+	{Tag{Number: 1, Content: int64(1363896240)}, "c11a514b67b0"},
+}
+
+func TestExamples(t *testing.T) {
+	for _, ex := range examples {
+		want, _ := hex.DecodeString(ex.hex)
+		got, err := Marshal(ex.value)
+		if err != nil || !bytes.Equal(got, want) {
+			t.Errorf("Marshal(%#v) = %x, %v; want %s", ex.value, got, err, ex.hex)
+		}
+		v, err := Unmarshal(want)
+		if err != nil || !reflect.DeepEqual(v, ex.value) {
+			t.Errorf("Unmarshal(%s) = %#v, %v", ex.hex, v, err)
+		}
+	}
+}
+
+func TestDeterministicMapOrder(t *testing.T) {
+	// Keys sort by their encodings: 0a (10), 1864 (100), 20 (-1), 617a
+	// ("z"), 626161 ("aa").
+	m := Map{"aa": int64(5), int64(-1): int64(2), int64(10): int64(1), "z": int64(4), int64(100): int64(3)}
+	got, _ := Marshal(m)
+	if want := "a50a011864032002617a0462616105"; hex.EncodeToString(got) != want {
+		t.Errorf("Marshal = %x, want %s", got, want)
+	}
+	if _, err := Marshal(Map{int64(1): 1, 1: 2}); err == nil {
+		t.Error("duplicate key encoded")
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	for _, bad := range []string{
+		"",
+		"18",                 // truncated argument
+		"4401",               // truncated bytes
+		"8201",               // truncated array
+		"a101",               // map without a value
+		"a2010201",           // duplicate key
+		"a1f401",             // unsupported key type
+		"5f4101ff",           // indefinite length
+		"f93c00",             // float
+		"3bffffffffffffffff", // negative integer out of range
+		"0000",               // trailing data
+		"9b7fffffffffffffff", // huge array length
+	} {
+		b, _ := hex.DecodeString(bad)
+		if v, err := Unmarshal(b); err == nil {
+			t.Errorf("Unmarshal(%s) = %#v", bad, v)
+		}
+	}
+	deep := bytes.Repeat([]byte{0x81}, maxDepth+2)
+	if _, err := Unmarshal(append(deep, 0)); err == nil {
+		t.Error("deep nesting accepted")
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	var seq []byte
+	for _, ex := range examples {
+		b, _ := hex.DecodeString(ex.hex)
+		seq = append(seq, b...)
+	}
+	d := NewDecoder(bytes.NewReader(seq), 1<<10)
+	for _, ex := range examples {
+		v, err := d.Decode()
+		if err != nil || !reflect.DeepEqual(v, ex.value) {
+			t.Fatalf("Decode = %#v, %v; want %#v", v, err, ex.value)
+		}
+	}
+	if v, err := d.Decode(); err != io.EOF {
+		t.Errorf("Decode at the end = %#v, %v; want io.EOF", v, err)
+	}
+
+	for _, bad := range []string{
+		"8201",               // truncated array
+		"5a00010000",         // longer than the limit
+		"9b7fffffffffffffff", // huge array length
+		"f93c00",             // float
+	} {
+		b, _ := hex.DecodeString(bad)
+		if v, err := NewDecoder(bytes.NewReader(b), 1<<10).Decode(); err == nil || err == io.EOF {
+			t.Errorf("Decode(%s) = %#v, %v", bad, v, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package cbor
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder reads a CBOR sequence (RFC 8742): data items one after another,
+// without separators.
+type Decoder struct {
+	r   *bufio.Reader
+	max int64
+	buf bytes.Buffer
+}
+
+// NewDecoder returns a Decoder reading from r that rejects data items
+// longer than max bytes.
+func NewDecoder(r io.Reader, max int64) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), max: max}
+}
+
+// Decode reads and decodes the next data item, as Unmarshal would. It
+// returns io.EOF at the end of the input, and only there. After any other
+// error the sequence cannot be resynchronized.
+func (d *Decoder) Decode() (interface{}, error) {
+	d.buf.Reset()
+	if _, err := d.r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+	if err := d.copyItem(0); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			err = errTruncated
+		}
+		return nil, err
+	}
+	return Unmarshal(d.buf.Bytes())
+}
+
+// copyItem copies the encoding of one data item to d.buf, following the
+// structure only as far as needed to find its end.
+func (d *Decoder) copyItem(depth int) error {
+	if depth > maxDepth {
+		return errors.New("cbor: nesting too deep")
+	}
+	if int64(d.buf.Len()) >= d.max {
+		return errTooLarge
+	}
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return err
+	}
+	d.buf.WriteByte(b)
+	major, info := b>>5, b&0x1f
+	arg := uint64(info)
+	if info >= 24 {
+		if info > 27 {
+			return fmt.Errorf("cbor: unsupported additional information %d", info)
+		}
+		n := 1 << (info - 24)
+		if err := d.copyN(int64(n)); err != nil {
+			return err
+		}
+		arg = 0
+		for _, c := range d.buf.Bytes()[d.buf.Len()-n:] {
+			arg = arg<<8 | uint64(c)
+		}
+	}
+	switch major {
+	case majorBytes, majorText:
+		if arg > uint64(d.max) {
+			return errTooLarge
+		}
+		return d.copyN(int64(arg))
+	case majorArray, majorMap:
+		if major == majorMap {
+			if arg > 1<<62 {
+				return errTooLarge
+			}
+			arg *= 2
+		}
+		for i := uint64(0); i < arg; i++ {
+			if err := d.copyItem(depth + 1); err != nil {
+				return err
+			}
+		}
+	case majorTag:
+		return d.copyItem(depth + 1)
+	}
+	return nil
+}
+
+var errTooLarge = errors.New("cbor: data item too large")
+
+func (d *Decoder) copyN(n int64) error {
+	if int64(d.buf.Len())+n > d.max {
+		return errTooLarge
+	}
+	_, err := io.CopyN(&d.buf, d.r, n)
+	return err
+}
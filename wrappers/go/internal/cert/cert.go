@@ -0,0 +1,330 @@
+// Package cert builds and parses X.509 certificates whose keys are SM2 and
+// whose signatures are SM2-with-SM3 (GM/T 0015), which crypto/x509 does
+// not support.
+package cert
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// OIDSignatureSM2WithSM3 identifies SM2 signatures over SM3 (GM/T 0006).
+var OIDSignatureSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+
+var (
+	oidPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+	oidExtSubjectKeyID     = asn1.ObjectIdentifier{2, 5, 29, 14}
+	oidExtKeyUsage         = asn1.ObjectIdentifier{2, 5, 29, 15}
+	oidExtSubjectAltName   = asn1.ObjectIdentifier{2, 5, 29, 17}
+	oidExtBasicConstraints = asn1.ObjectIdentifier{2, 5, 29, 19}
+	oidExtAuthorityKeyID   = asn1.ObjectIdentifier{2, 5, 29, 35}
+	oidExtExtendedKeyUsage = asn1.ObjectIdentifier{2, 5, 29, 37}
+)
+
+// handledExtensions are the extensions ExtensionFields decodes.
+var handledExtensions = []asn1.ObjectIdentifier{
+	oidExtSubjectKeyID, oidExtKeyUsage, oidExtSubjectAltName,
+	oidExtBasicConstraints, oidExtAuthorityKeyID, oidExtExtendedKeyUsage,
+}
+
+// KeyUsage is the bit set of the X.509 key usage extension; bit 0 is
+// digitalSignature.
+type KeyUsage int
+
+const (
+	KeyUsageDigitalSignature KeyUsage = 1 << iota
+	KeyUsageContentCommitment
+	KeyUsageKeyEncipherment
+	KeyUsageDataEncipherment
+	KeyUsageKeyAgreement
+	KeyUsageCertSign
+	KeyUsageCRLSign
+	KeyUsageEncipherOnly
+	KeyUsageDecipherOnly
+)
+
+// Extended key usage purposes of RFC 5280.
+var (
+	OIDExtKeyUsageServerAuth      = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 1}
+	OIDExtKeyUsageClientAuth      = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 2}
+	OIDExtKeyUsageCodeSigning     = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 3}
+	OIDExtKeyUsageEmailProtection = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 4}
+	OIDExtKeyUsageTimeStamping    = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 8}
+	OIDExtKeyUsageOCSPSigning     = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 3, 9}
+)
+
+// Certificate is a parsed X.509 certificate. The extensions the package
+// knows are decoded into fields; all of them stay in Extensions.
+type Certificate struct {
+	Raw                     []byte
+	RawTBSCertificate       []byte
+	RawSubject              []byte
+	RawIssuer               []byte
+	RawSubjectPublicKeyInfo []byte
+
+	Version            int // 1, 2 or 3
+	SerialNumber       *big.Int
+	Issuer             pkix.Name
+	Subject            pkix.Name
+	NotBefore          time.Time
+	NotAfter           time.Time
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Signature          []byte
+
+	// PublicKeyAlgorithm is the SubjectPublicKeyInfo algorithm and, for
+	// EC keys, PublicKeyCurve its named curve. PublicKey is set only for
+	// SM2 keys.
+	PublicKeyAlgorithm asn1.ObjectIdentifier
+	PublicKeyCurve     asn1.ObjectIdentifier
+	PublicKey          *sm2.PublicKey
+
+	Extensions []pkix.Extension
+	ExtensionFields
+	// UnhandledCriticalExtensions are the critical extensions that
+	// ExtensionFields does not decode. Verify rejects a certificate with
+	// any, as RFC 5280 section 4.2 requires.
+	UnhandledCriticalExtensions []asn1.ObjectIdentifier
+}
+
+// ExtensionFields holds the decoded values of the extensions the package
+// knows, in certificates and in the extension request of a PKCS #10
+// request.
+type ExtensionFields struct {
+	SubjectKeyID          []byte
+	AuthorityKeyID        []byte
+	KeyUsage              KeyUsage // zero when the extension is absent
+	ExtKeyUsage           []asn1.ObjectIdentifier
+	BasicConstraintsValid bool
+	IsCA                  bool
+	MaxPathLen            int // -1 when unlimited
+	DNSNames              []string
+	EmailAddresses        []string
+	IPAddresses           []net.IP
+}
+
+type certificate struct {
+	TBS                asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type tbsCertificate struct {
+	Raw                asn1.RawContent
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           validity
+	Subject            asn1.RawValue
+	PublicKey          publicKeyInfo
+	IssuerUniqueID     asn1.BitString   `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString   `asn1:"optional,tag:2"`
+	Extensions         []pkix.Extension `asn1:"omitempty,optional,explicit,tag:3"`
+}
+
+type validity struct {
+	NotBefore, NotAfter time.Time
+}
+
+type publicKeyInfo struct {
+	Raw       asn1.RawContent
+	Algorithm pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type basicConstraints struct {
+	IsCA       bool `asn1:"optional"`
+	MaxPathLen int  `asn1:"optional,default:-1"`
+}
+
+type authorityKeyID struct {
+	ID []byte `asn1:"optional,tag:0"`
+}
+
+// GeneralName tags of subjectAltName.
+const (
+	nameTagEmail = 1
+	nameTagDNS   = 2
+	nameTagIP    = 7
+)
+
+// Parse parses a DER certificate. Keys other than SM2 are described but
+// not decoded.
+func Parse(der []byte) (*Certificate, error) {
+	var outer certificate
+	rest, err := asn1.Unmarshal(der, &outer)
+	if err != nil {
+		return nil, fmt.Errorf("cert: malformed certificate: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cert: trailing data after certificate")
+	}
+	var tbs tbsCertificate
+	if _, err := asn1.Unmarshal(outer.TBS.FullBytes, &tbs); err != nil {
+		return nil, fmt.Errorf("cert: malformed tbsCertificate: %v", err)
+	}
+	if !tbs.SignatureAlgorithm.Algorithm.Equal(outer.SignatureAlgorithm.Algorithm) {
+		return nil, errors.New("cert: signature algorithm differs from the one in tbsCertificate")
+	}
+	c := &Certificate{
+		Raw:                     der,
+		RawTBSCertificate:       outer.TBS.FullBytes,
+		RawSubject:              tbs.Subject.FullBytes,
+		RawIssuer:               tbs.Issuer.FullBytes,
+		RawSubjectPublicKeyInfo: tbs.PublicKey.Raw,
+		Version:                 tbs.Version + 1,
+		SerialNumber:            tbs.SerialNumber,
+		NotBefore:               tbs.Validity.NotBefore,
+		NotAfter:                tbs.Validity.NotAfter,
+		SignatureAlgorithm:      outer.SignatureAlgorithm.Algorithm,
+		Signature:               outer.Signature.RightAlign(),
+		PublicKeyAlgorithm:      tbs.PublicKey.Algorithm.Algorithm,
+		Extensions:              tbs.Extensions,
+	}
+	if c.Issuer, err = parseName(c.RawIssuer); err != nil {
+		return nil, err
+	}
+	if c.Subject, err = parseName(c.RawSubject); err != nil {
+		return nil, err
+	}
+	if c.PublicKeyAlgorithm.Equal(oidPublicKeyEC) {
+		var curve asn1.ObjectIdentifier
+		if _, err := asn1.Unmarshal(tbs.PublicKey.Algorithm.Parameters.FullBytes, &curve); err == nil {
+			c.PublicKeyCurve = curve
+		}
+		if point, err := sm2.UnmarshalPKIXPublicKey(c.RawSubjectPublicKeyInfo); err == nil {
+			if c.PublicKey, err = sm2.ParsePublicKey(point); err != nil {
+				return nil, fmt.Errorf("cert: %v", err)
+			}
+		}
+	}
+	if err := c.ExtensionFields.parse(c.Extensions); err != nil {
+		return nil, err
+	}
+	for _, ext := range c.Extensions {
+		if ext.Critical && !slices.ContainsFunc(handledExtensions, ext.Id.Equal) {
+			c.UnhandledCriticalExtensions = append(c.UnhandledCriticalExtensions, ext.Id)
+		}
+	}
+	return c, nil
+}
+
+func parseName(der []byte) (pkix.Name, error) {
+	var rdns pkix.RDNSequence
+	var name pkix.Name
+	if _, err := asn1.Unmarshal(der, &rdns); err != nil {
+		return name, fmt.Errorf("cert: malformed name: %v", err)
+	}
+	name.FillFromRDNSequence(&rdns)
+	return name, nil
+}
+
+func (f *ExtensionFields) parse(exts []pkix.Extension) error {
+	f.MaxPathLen = -1
+	for _, ext := range exts {
+		if err := f.parseExtension(ext); err != nil {
+			return fmt.Errorf("cert: malformed extension %v: %v", ext.Id, err)
+		}
+	}
+	return nil
+}
+
+func (f *ExtensionFields) parseExtension(ext pkix.Extension) error {
+	var err error
+	switch {
+	case ext.Id.Equal(oidExtSubjectKeyID):
+		_, err = asn1.Unmarshal(ext.Value, &f.SubjectKeyID)
+	case ext.Id.Equal(oidExtAuthorityKeyID):
+		var aki authorityKeyID
+		_, err = asn1.Unmarshal(ext.Value, &aki)
+		f.AuthorityKeyID = aki.ID
+	case ext.Id.Equal(oidExtKeyUsage):
+		var bits asn1.BitString
+		_, err = asn1.Unmarshal(ext.Value, &bits)
+		for i := 0; i < 9; i++ {
+			if bits.At(i) != 0 {
+				f.KeyUsage |= 1 << i
+			}
+		}
+	case ext.Id.Equal(oidExtExtendedKeyUsage):
+		_, err = asn1.Unmarshal(ext.Value, &f.ExtKeyUsage)
+	case ext.Id.Equal(oidExtBasicConstraints):
+		var bc basicConstraints
+		_, err = asn1.Unmarshal(ext.Value, &bc)
+		f.BasicConstraintsValid, f.IsCA, f.MaxPathLen = true, bc.IsCA, bc.MaxPathLen
+	case ext.Id.Equal(oidExtSubjectAltName):
+		err = f.parseSubjectAltName(ext.Value)
+	}
+	return err
+}
+
+func (f *ExtensionFields) parseSubjectAltName(der []byte) error {
+	var names []asn1.RawValue
+	if _, err := asn1.Unmarshal(der, &names); err != nil {
+		return err
+	}
+	for _, n := range names {
+		if n.Class != asn1.ClassContextSpecific {
+			continue
+		}
+		switch n.Tag {
+		case nameTagEmail:
+			f.EmailAddresses = append(f.EmailAddresses, string(n.Bytes))
+		case nameTagDNS:
+			f.DNSNames = append(f.DNSNames, string(n.Bytes))
+		case nameTagIP:
+			if len(n.Bytes) != net.IPv4len && len(n.Bytes) != net.IPv6len {
+				return errors.New("bad IP address length")
+			}
+			f.IPAddresses = append(f.IPAddresses, net.IP(n.Bytes))
+		}
+	}
+	return nil
+}
+
+// CheckSignature reports whether the certificate is signed by pub with
+// SM2-with-SM3 under the signer identity uid. A nil uid selects
+// sm2.DefaultUID, which GM/T 0015 and Bouncy Castle use; OpenSSL 3.0
+// signs certificates with an empty identity instead.
+func (c *Certificate) CheckSignature(pub *sm2.PublicKey, uid []byte) error {
+	return checkSignature(c.SignatureAlgorithm, c.RawTBSCertificate, c.Signature, pub, uid)
+}
+
+func checkSignature(alg asn1.ObjectIdentifier, signed, sig []byte, pub *sm2.PublicKey, uid []byte) error {
+	if !alg.Equal(OIDSignatureSM2WithSM3) {
+		return fmt.Errorf("cert: unsupported signature algorithm %v", alg)
+	}
+	r, s, err := sm2.UnmarshalSignature(sig)
+	if err != nil {
+		return fmt.Errorf("cert: malformed signature: %v", err)
+	}
+	if !sm2.Verify(pub, signed, signerUID(uid), r, s) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// ErrSignature is returned when a signature does not verify.
+var ErrSignature = errors.New("cert: signature verification failed")
+
+// IsSelfIssued reports whether the subject and issuer names are equal.
+func (c *Certificate) IsSelfIssued() bool {
+	return bytes.Equal(c.RawSubject, c.RawIssuer)
+}
+
+func signerUID(uid []byte) []byte {
+	if uid == nil {
+		return []byte(sm2.DefaultUID)
+	}
+	return uid
+}
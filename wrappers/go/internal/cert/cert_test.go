@@ -0,0 +1,137 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// A self-signed SM2-with-SM3 CA certificate (serial 0x1234) written by
+// OpenSSL 3.0.
+const opensslCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBnTCCAUOgAwIBAgICEjQwCgYIKoEcz1UBg3UwLDEVMBMGA1UEAwwMcGtjczcg
+c2lnbmVyMRMwEQYDVQQKDApzbS1iYy10ZXN0MCAXDTI2MTAxNjAwMTU1N1oYDzIx
+MjYwOTIyMDAxNTU3WjAsMRUwEwYDVQQDDAxwa2NzNyBzaWduZXIxEzARBgNVBAoM
+CnNtLWJjLXRlc3QwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAARVzuzz4q8nZjhj
+W4VdCYJTeyqbENWOJKn2uhb00fE9qqNiLjEVq2auXh6lH9JukRlDefWxp0Hxrp7E
+S04SBcIfo1MwUTAdBgNVHQ4EFgQUalWAbpKNXCl+OxidSKEPSS+irUkwHwYDVR0j
+BBgwFoAUalWAbpKNXCl+OxidSKEPSS+irUkwDwYDVR0TAQH/BAUwAwEB/zAKBggq
+gRzPVQGDdQNIADBFAiBRyw6JSr05cOKHyWCbP9iXCJ2tvr5DuJsHqrkNu0LKUgIh
+ALQcZgUPrrId1aXODh0OaX0rPBEl/5VDHcpRbiYJKz3n
+-----END CERTIFICATE-----
+`
+
+func TestParseOpenSSL(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslCertPEM))
+	c, err := Parse(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Version != 3 || c.SerialNumber.Int64() != 0x1234 || c.Subject.CommonName != "pkcs7 signer" ||
+		c.Issuer.String() != c.Subject.String() || c.NotAfter.Year() != 2126 {
+		t.Errorf("fields: v%d serial %v subject %q issuer %q not after %v", c.Version, c.SerialNumber, c.Subject, c.Issuer, c.NotAfter)
+	}
+	if !c.BasicConstraintsValid || !c.IsCA || c.MaxPathLen != -1 {
+		t.Errorf("basic constraints %v %v %d", c.BasicConstraintsValid, c.IsCA, c.MaxPathLen)
+	}
+	ski := "6a55806e928d5c297e3b189d48a10f492fa2ad49"
+	if hex.EncodeToString(c.SubjectKeyID) != ski || hex.EncodeToString(c.AuthorityKeyID) != ski {
+		t.Errorf("key IDs %x %x", c.SubjectKeyID, c.AuthorityKeyID)
+	}
+	if c.PublicKey == nil {
+		t.Fatal("no SM2 public key")
+	}
+	// OpenSSL signs with an empty identity rather than the default one.
+	if err := c.CheckSignature(c.PublicKey, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckSignature(c.PublicKey, nil); err != ErrSignature {
+		t.Errorf("default identity: %v", err)
+	}
+	// OpenSSL computes the key identifier the same way as Create.
+	if id := sha1.Sum(c.PublicKey.Bytes()); !bytes.Equal(id[:], c.SubjectKeyID) {
+		t.Errorf("SHA-1 of the key = %x", id)
+	}
+	other, _ := sm2.GenerateKey(rand.Reader)
+	if err := c.CheckSignature(&other.PublicKey, []byte{}); err != ErrSignature {
+		t.Errorf("other key: %v", err)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	caKey, _ := sm2.GenerateKey(rand.Reader)
+	now := time.Now()
+	caTmpl := &Template{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "Test CA", Organization: []string{"sm-bc-test"}},
+		NotBefore:    now,
+		NotAfter:     now.Add(24 * time.Hour),
+		KeyUsage:     KeyUsageCertSign | KeyUsageCRLSign,
+		IsCA:         true,
+		MaxPathLen:   0,
+	}
+	caDER, err := Create(rand.Reader, caTmpl, &caKey.PublicKey, nil, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := Parse(caDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ca.CheckSignature(&caKey.PublicKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if !ca.IsCA || ca.MaxPathLen != 0 || ca.KeyUsage != KeyUsageCertSign|KeyUsageCRLSign || ca.AuthorityKeyID != nil {
+		t.Errorf("CA: %v %d %b %x", ca.IsCA, ca.MaxPathLen, ca.KeyUsage, ca.AuthorityKeyID)
+	}
+
+	leafKey, _ := sm2.GenerateKey(rand.Reader)
+	leafTmpl := &Template{
+		Subject:        pkix.Name{CommonName: "server"},
+		NotBefore:      now,
+		NotAfter:       now.Add(time.Hour),
+		KeyUsage:       KeyUsageDigitalSignature | KeyUsageKeyEncipherment,
+		ExtKeyUsage:    []asn1.ObjectIdentifier{OIDExtKeyUsageServerAuth},
+		DNSNames:       []string{"localhost", "example.test"},
+		EmailAddresses: []string{"ops@example.test"},
+		IPAddresses:    []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")},
+	}
+	leafDER, err := Create(rand.Reader, leafTmpl, &leafKey.PublicKey, ca, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := Parse(leafDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.CheckSignature(&caKey.PublicKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if leaf.Issuer.CommonName != "Test CA" || !bytes.Equal(leaf.AuthorityKeyID, ca.SubjectKeyID) || leaf.BasicConstraintsValid {
+		t.Errorf("leaf issuer %q, AKI %x", leaf.Issuer, leaf.AuthorityKeyID)
+	}
+	if len(leaf.DNSNames) != 2 || leaf.EmailAddresses[0] != "ops@example.test" || !leaf.IPAddresses[1].Equal(net.IPv6loopback) ||
+		len(leaf.ExtKeyUsage) != 1 || !leaf.ExtKeyUsage[0].Equal(OIDExtKeyUsageServerAuth) || leaf.SerialNumber.Sign() <= 0 {
+		t.Errorf("leaf: %v %v %v %v %v", leaf.DNSNames, leaf.EmailAddresses, leaf.IPAddresses, leaf.ExtKeyUsage, leaf.SerialNumber)
+	}
+	if !leaf.NotBefore.Equal(now.UTC().Truncate(time.Second)) {
+		t.Errorf("not before %v", leaf.NotBefore)
+	}
+
+	if _, err := Create(rand.Reader, leafTmpl, &leafKey.PublicKey, ca, leafKey); err == nil {
+		t.Error("issued with a key that does not match the issuer")
+	}
+	if _, err := Create(rand.Reader, leafTmpl, &leafKey.PublicKey, nil, caKey); err == nil {
+		t.Error("self-signed with another key")
+	}
+}
@@ -0,0 +1,211 @@
+package cert
+
+import (
+	"crypto/sha1"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// Template holds the fields of a certificate to be created.
+type Template struct {
+	// SerialNumber is random when nil.
+	SerialNumber *big.Int
+	Subject      pkix.Name
+	NotBefore    time.Time
+	NotAfter     time.Time
+
+	KeyUsage    KeyUsage
+	ExtKeyUsage []asn1.ObjectIdentifier
+	// IsCA adds critical basic constraints with cA set; MaxPathLen is
+	// then written unless it is negative.
+	IsCA       bool
+	MaxPathLen int
+
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+
+	// ExtraExtensions are added after the extensions of the fields above.
+	ExtraExtensions []pkix.Extension
+
+	// SignerUID is the issuer identity in the SM2 signature; nil selects
+	// sm2.DefaultUID.
+	SignerUID []byte
+}
+
+// Create returns a DER certificate for pub signed by priv. With a nil
+// parent it is self-signed and priv must belong to pub; otherwise parent is
+// the issuer's certificate and priv its key.
+//
+// The certificate carries a subject key identifier (the SHA-1 of the
+// public key, RFC 5280 method 1) and, when the issuer has one, an
+// authority key identifier.
+func Create(rand io.Reader, tmpl *Template, pub *sm2.PublicKey, parent *Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	serial := tmpl.SerialNumber
+	if serial == nil {
+		b := make([]byte, 16)
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		b[0] &= 0x7f
+		b[0] |= 0x40
+		serial = new(big.Int).SetBytes(b)
+	}
+	if serial.Sign() <= 0 {
+		return nil, errors.New("cert: serial number must be positive")
+	}
+	if !tmpl.NotAfter.After(tmpl.NotBefore) {
+		return nil, errors.New("cert: NotAfter must be later than NotBefore")
+	}
+
+	subject, err := asn1.Marshal(tmpl.Subject.ToRDNSequence())
+	if err != nil {
+		return nil, err
+	}
+	issuer, authorityKey := subject, []byte(nil)
+	if parent == nil {
+		if !samePublicKey(pub, &priv.PublicKey) {
+			return nil, errors.New("cert: private key does not match the public key of a self-signed certificate")
+		}
+	} else {
+		if parent.PublicKey == nil || !samePublicKey(parent.PublicKey, &priv.PublicKey) {
+			return nil, errors.New("cert: private key does not match the issuer certificate")
+		}
+		issuer, authorityKey = parent.RawSubject, parent.SubjectKeyID
+	}
+
+	spki, err := sm2.MarshalPKIXPublicKey(pub.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	subjectKey := sha1.Sum(pub.Bytes())
+	exts, err := extensions(tmpl, subjectKey[:], authorityKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3}
+	tbs, err := asn1.Marshal(tbsCertificate{
+		Version:            2,
+		SerialNumber:       serial,
+		SignatureAlgorithm: sigAlg,
+		Issuer:             asn1.RawValue{FullBytes: issuer},
+		Validity:           validity{tmpl.NotBefore.UTC().Truncate(time.Second), tmpl.NotAfter.UTC().Truncate(time.Second)},
+		Subject:            asn1.RawValue{FullBytes: subject},
+		PublicKey:          publicKeyInfo{Raw: spki},
+		Extensions:         exts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sign(rand, tbs, sigAlg, priv, tmpl.SignerUID)
+}
+
+// sign wraps tbs in the SEQUENCE { tbs, signatureAlgorithm, signature }
+// shared by certificates, requests and CRLs.
+func sign(rand io.Reader, tbs []byte, sigAlg pkix.AlgorithmIdentifier, priv *sm2.PrivateKey, uid []byte) ([]byte, error) {
+	r, s, err := sm2.Sign(rand, priv, tbs, signerUID(uid))
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sm2.MarshalSignature(r, s)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(certificate{
+		TBS:                asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: sigAlg,
+		Signature:          asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	})
+}
+
+func samePublicKey(a, b *sm2.PublicKey) bool {
+	return a.X.Cmp(b.X) == 0 && a.Y.Cmp(b.Y) == 0
+}
+
+func extensions(tmpl *Template, subjectKey, authorityKey []byte) ([]pkix.Extension, error) {
+	var exts []pkix.Extension
+	add := func(id asn1.ObjectIdentifier, critical bool, v interface{}) error {
+		der, err := asn1.Marshal(v)
+		if err == nil {
+			exts = append(exts, pkix.Extension{Id: id, Critical: critical, Value: der})
+		}
+		return err
+	}
+
+	if subjectKey != nil {
+		if err := add(oidExtSubjectKeyID, false, subjectKey); err != nil {
+			return nil, err
+		}
+	}
+	if authorityKey != nil {
+		if err := add(oidExtAuthorityKeyID, false, authorityKeyID{ID: authorityKey}); err != nil {
+			return nil, err
+		}
+	}
+	if tmpl.IsCA {
+		bc := basicConstraints{IsCA: true, MaxPathLen: tmpl.MaxPathLen}
+		if bc.MaxPathLen < 0 {
+			bc.MaxPathLen = -1
+		}
+		if err := add(oidExtBasicConstraints, true, bc); err != nil {
+			return nil, err
+		}
+	}
+	if tmpl.KeyUsage != 0 {
+		if err := add(oidExtKeyUsage, true, keyUsageBits(tmpl.KeyUsage)); err != nil {
+			return nil, err
+		}
+	}
+	if len(tmpl.ExtKeyUsage) > 0 {
+		// RFC 3161 requires a TSA certificate to carry timeStamping as its
+		// only purpose, in a critical extension.
+		critical := len(tmpl.ExtKeyUsage) == 1 && tmpl.ExtKeyUsage[0].Equal(OIDExtKeyUsageTimeStamping)
+		if err := add(oidExtExtendedKeyUsage, critical, tmpl.ExtKeyUsage); err != nil {
+			return nil, err
+		}
+	}
+	if san := subjectAltName(tmpl); len(san) > 0 {
+		if err := add(oidExtSubjectAltName, false, san); err != nil {
+			return nil, err
+		}
+	}
+	return append(exts, tmpl.ExtraExtensions...), nil
+}
+
+// keyUsageBits encodes ku as a DER BIT STRING without trailing zero bits.
+func keyUsageBits(ku KeyUsage) asn1.BitString {
+	var b [2]byte
+	n := 0
+	for i := 0; i < 9; i++ {
+		if ku&(1<<i) != 0 {
+			b[i/8] |= 0x80 >> (i % 8)
+			n = i + 1
+		}
+	}
+	return asn1.BitString{Bytes: b[:(n+7)/8], BitLength: n}
+}
+
+func subjectAltName(tmpl *Template) []asn1.RawValue {
+	var names []asn1.RawValue
+	for _, n := range tmpl.DNSNames {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagDNS, Bytes: []byte(n)})
+	}
+	for _, n := range tmpl.EmailAddresses {
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagEmail, Bytes: []byte(n)})
+	}
+	for _, ip := range tmpl.IPAddresses {
+		if v4 := ip.To4(); v4 != nil {
+			ip = v4
+		}
+		names = append(names, asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: nameTagIP, Bytes: ip})
+	}
+	return names
+}
@@ -0,0 +1,254 @@
+package cert
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+var (
+	oidExtCRLNumber  = asn1.ObjectIdentifier{2, 5, 29, 20}
+	oidExtReasonCode = asn1.ObjectIdentifier{2, 5, 29, 21}
+)
+
+// RevokedCertificate is an entry of a CRL.
+type RevokedCertificate struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+	// ReasonCode is the CRLReason of RFC 5280. Zero, unspecified, is
+	// written by leaving the reasonCode extension out.
+	ReasonCode int
+}
+
+// CRLTemplate holds the fields of a CRL to be created.
+type CRLTemplate struct {
+	// Number is the CRL number, which must not be negative.
+	Number     *big.Int
+	ThisUpdate time.Time
+	NextUpdate time.Time
+	Revoked    []RevokedCertificate
+
+	// SignerUID is the issuer identity in the SM2 signature; nil selects
+	// sm2.DefaultUID.
+	SignerUID []byte
+}
+
+// CRL is a parsed X.509 v2 certificate revocation list.
+type CRL struct {
+	Raw            []byte
+	RawTBSCertList []byte
+	RawIssuer      []byte
+
+	Version            int // 1 or 2
+	Issuer             pkix.Name
+	ThisUpdate         time.Time
+	NextUpdate         time.Time // zero when absent
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Signature          []byte
+	Revoked            []RevokedCertificate
+
+	Number         *big.Int // nil when absent
+	AuthorityKeyID []byte
+	Extensions     []pkix.Extension
+}
+
+type tbsCertList struct {
+	Raw                 asn1.RawContent
+	Version             int `asn1:"optional,default:0"`
+	SignatureAlgorithm  pkix.AlgorithmIdentifier
+	Issuer              asn1.RawValue
+	ThisUpdate          time.Time
+	NextUpdate          time.Time            `asn1:"optional"`
+	RevokedCertificates []revokedCertificate `asn1:"optional,omitempty"`
+	Extensions          []pkix.Extension     `asn1:"optional,omitempty,explicit,tag:0"`
+}
+
+type revokedCertificate struct {
+	SerialNumber   *big.Int
+	RevocationTime time.Time
+	Extensions     []pkix.Extension `asn1:"optional,omitempty"`
+}
+
+// CreateCRL returns a DER v2 CRL signed by priv as the CA of issuer. It
+// carries the CRL number and, when the issuer has a subject key
+// identifier, an authority key identifier.
+func CreateCRL(rand io.Reader, tmpl *CRLTemplate, issuer *Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	if issuer.PublicKey == nil || !samePublicKey(issuer.PublicKey, &priv.PublicKey) {
+		return nil, errors.New("cert: private key does not match the issuer certificate")
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&KeyUsageCRLSign == 0 {
+		return nil, errors.New("cert: issuer key usage does not allow CRL signing")
+	}
+	if tmpl.Number == nil || tmpl.Number.Sign() < 0 {
+		return nil, errors.New("cert: CRL number must not be negative")
+	}
+	if !tmpl.NextUpdate.After(tmpl.ThisUpdate) {
+		return nil, errors.New("cert: NextUpdate must be later than ThisUpdate")
+	}
+
+	var revoked []revokedCertificate
+	for _, r := range tmpl.Revoked {
+		if r.SerialNumber == nil || r.SerialNumber.Sign() <= 0 {
+			return nil, errors.New("cert: revoked serial number must be positive")
+		}
+		entry := revokedCertificate{SerialNumber: r.SerialNumber, RevocationTime: r.RevocationTime.UTC().Truncate(time.Second)}
+		if r.ReasonCode != 0 {
+			if r.ReasonCode < 0 || r.ReasonCode > 10 || r.ReasonCode == 7 {
+				return nil, fmt.Errorf("cert: invalid CRL reason %d", r.ReasonCode)
+			}
+			reason, err := asn1.Marshal(asn1.Enumerated(r.ReasonCode))
+			if err != nil {
+				return nil, err
+			}
+			entry.Extensions = []pkix.Extension{{Id: oidExtReasonCode, Value: reason}}
+		}
+		revoked = append(revoked, entry)
+	}
+
+	var exts []pkix.Extension
+	if issuer.SubjectKeyID != nil {
+		aki, err := asn1.Marshal(authorityKeyID{ID: issuer.SubjectKeyID})
+		if err != nil {
+			return nil, err
+		}
+		exts = append(exts, pkix.Extension{Id: oidExtAuthorityKeyID, Value: aki})
+	}
+	number, err := asn1.Marshal(tmpl.Number)
+	if err != nil {
+		return nil, err
+	}
+	exts = append(exts, pkix.Extension{Id: oidExtCRLNumber, Value: number})
+
+	sigAlg := pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3}
+	tbs, err := asn1.Marshal(tbsCertList{
+		Version:             1,
+		SignatureAlgorithm:  sigAlg,
+		Issuer:              asn1.RawValue{FullBytes: issuer.RawSubject},
+		ThisUpdate:          tmpl.ThisUpdate.UTC().Truncate(time.Second),
+		NextUpdate:          tmpl.NextUpdate.UTC().Truncate(time.Second),
+		RevokedCertificates: revoked,
+		Extensions:          exts,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sign(rand, tbs, sigAlg, priv, tmpl.SignerUID)
+}
+
+// ParseCRL parses a DER CRL. It does not check the signature.
+func ParseCRL(der []byte) (*CRL, error) {
+	var outer certificate
+	rest, err := asn1.Unmarshal(der, &outer)
+	if err != nil {
+		return nil, fmt.Errorf("cert: malformed CRL: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cert: trailing data after CRL")
+	}
+	var tbs tbsCertList
+	if _, err := asn1.Unmarshal(outer.TBS.FullBytes, &tbs); err != nil {
+		return nil, fmt.Errorf("cert: malformed tbsCertList: %v", err)
+	}
+	if !tbs.SignatureAlgorithm.Algorithm.Equal(outer.SignatureAlgorithm.Algorithm) {
+		return nil, errors.New("cert: signature algorithm differs from the one in tbsCertList")
+	}
+	crl := &CRL{
+		Raw:                der,
+		RawTBSCertList:     outer.TBS.FullBytes,
+		RawIssuer:          tbs.Issuer.FullBytes,
+		Version:            tbs.Version + 1,
+		ThisUpdate:         tbs.ThisUpdate,
+		NextUpdate:         tbs.NextUpdate,
+		SignatureAlgorithm: outer.SignatureAlgorithm.Algorithm,
+		Signature:          outer.Signature.RightAlign(),
+		Extensions:         tbs.Extensions,
+	}
+	if crl.Issuer, err = parseName(crl.RawIssuer); err != nil {
+		return nil, err
+	}
+	for _, ext := range tbs.Extensions {
+		switch {
+		case ext.Id.Equal(oidExtCRLNumber):
+			if _, err = asn1.Unmarshal(ext.Value, &crl.Number); err != nil {
+				return nil, fmt.Errorf("cert: malformed CRL number: %v", err)
+			}
+		case ext.Id.Equal(oidExtAuthorityKeyID):
+			var aki authorityKeyID
+			if _, err = asn1.Unmarshal(ext.Value, &aki); err != nil {
+				return nil, fmt.Errorf("cert: malformed extension %v: %v", ext.Id, err)
+			}
+			crl.AuthorityKeyID = aki.ID
+		}
+	}
+	for _, r := range tbs.RevokedCertificates {
+		entry := RevokedCertificate{SerialNumber: r.SerialNumber, RevocationTime: r.RevocationTime}
+		for _, ext := range r.Extensions {
+			if !ext.Id.Equal(oidExtReasonCode) {
+				continue
+			}
+			var reason asn1.Enumerated
+			if _, err := asn1.Unmarshal(ext.Value, &reason); err != nil {
+				return nil, fmt.Errorf("cert: malformed CRL reason: %v", err)
+			}
+			entry.ReasonCode = int(reason)
+		}
+		crl.Revoked = append(crl.Revoked, entry)
+	}
+	return crl, nil
+}
+
+// CheckSignature reports whether the CRL is signed by pub, as in
+// Certificate.CheckSignature.
+func (crl *CRL) CheckSignature(pub *sm2.PublicKey, uid []byte) error {
+	return checkSignature(crl.SignatureAlgorithm, crl.RawTBSCertList, crl.Signature, pub, uid)
+}
+
+// Errors of CRL.Check, which say why the CRL cannot be relied on.
+var (
+	ErrCRLIssuer      = errors.New("cert: CRL and certificate are not both issued by the issuer certificate")
+	ErrCRLKeyUsage    = errors.New("cert: issuer key usage does not allow CRL signing")
+	ErrCRLExpired     = errors.New("cert: CRL is past its next update")
+	ErrCRLNotYetValid = errors.New("cert: CRL is not yet valid")
+)
+
+// Check looks c up in the CRL after making sure that the CRL applies:
+// issuer must have issued both c and the CRL, and at time now the CRL must
+// be current. It returns the CRL entry of c, or nil when c is not
+// revoked. Problems with the CRL are returned as ErrSignature or one of
+// the ErrCRL errors.
+func (crl *CRL) Check(c, issuer *Certificate, now time.Time, uid []byte) (*RevokedCertificate, error) {
+	if !bytes.Equal(c.RawIssuer, issuer.RawSubject) || !bytes.Equal(crl.RawIssuer, issuer.RawSubject) {
+		return nil, ErrCRLIssuer
+	}
+	if len(crl.AuthorityKeyID) > 0 && len(issuer.SubjectKeyID) > 0 && !bytes.Equal(crl.AuthorityKeyID, issuer.SubjectKeyID) {
+		return nil, ErrCRLIssuer
+	}
+	if issuer.PublicKey == nil {
+		return nil, ErrSignature
+	}
+	if err := crl.CheckSignature(issuer.PublicKey, uid); err != nil {
+		return nil, err
+	}
+	if issuer.KeyUsage != 0 && issuer.KeyUsage&KeyUsageCRLSign == 0 {
+		return nil, ErrCRLKeyUsage
+	}
+	if now.Before(crl.ThisUpdate) {
+		return nil, ErrCRLNotYetValid
+	}
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		return nil, ErrCRLExpired
+	}
+	for i, r := range crl.Revoked {
+		if r.SerialNumber.Cmp(c.SerialNumber) == 0 {
+			return &crl.Revoked[i], nil
+		}
+	}
+	return nil, nil
+}
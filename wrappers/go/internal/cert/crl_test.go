@@ -0,0 +1,107 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// A CRL for opensslCertPEM written by "openssl ca -gencrl" (CRL number 5)
+// revoking serial 0abc for keyCompromise and 0abd without a reason.
+const opensslCRLPEM = `
+-----BEGIN X509 CRL-----
+MIIBIjCByAIBATAKBggqgRzPVQGDdTAsMRUwEwYDVQQDDAxwa2NzNyBzaWduZXIx
+EzARBgNVBAoMCnNtLWJjLXRlc3QXDTI2MTAxNjAwMzQ1M1oYDzIxMjYwOTIyMDAz
+NDUzWjA4MCECAgq8Fw0yNjAxMDEwMDAwMDBaMAwwCgYDVR0VBAMKAQEwEwICCr0X
+DTI2MDIwMTAwMDAwMFqgLzAtMB8GA1UdIwQYMBaAFGpVgG6SjVwpfjsYnUihD0kv
+oq1JMAoGA1UdFAQDAgEFMAoGCCqBHM9VAYN1A0kAMEYCIQCOoUUu5kmOBdpvApJq
+FebVvuY6cJS+LsfRw4/BsyJbpwIhANi7EQy8rQQOc728mtGsNzxBnbC7qZpZNz8+
+S73nkdBK
+-----END X509 CRL-----
+`
+
+func TestParseCRLOpenSSL(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslCRLPEM))
+	crl, err := ParseCRL(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(opensslCertPEM))
+	ca, _ := Parse(block.Bytes)
+	if crl.Version != 2 || crl.Number.Int64() != 5 || crl.Issuer.CommonName != "pkcs7 signer" || crl.NextUpdate.Year() != 2126 {
+		t.Errorf("fields: v%d number %v issuer %q next update %v", crl.Version, crl.Number, crl.Issuer, crl.NextUpdate)
+	}
+	if string(crl.AuthorityKeyID) != string(ca.SubjectKeyID) || len(crl.Revoked) != 2 {
+		t.Fatalf("AKI %x, %d entries", crl.AuthorityKeyID, len(crl.Revoked))
+	}
+	if r := crl.Revoked[0]; r.SerialNumber.Int64() != 0xabc || r.ReasonCode != 1 || !r.RevocationTime.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("entry 0: %+v", r)
+	}
+	if r := crl.Revoked[1]; r.SerialNumber.Int64() != 0xabd || r.ReasonCode != 0 {
+		t.Errorf("entry 1: %+v", r)
+	}
+	if err := crl.CheckSignature(ca.PublicKey, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := crl.CheckSignature(ca.PublicKey, nil); err != ErrSignature {
+		t.Errorf("default identity: %v", err)
+	}
+}
+
+func TestCRL(t *testing.T) {
+	root := issue(t, &Template{Subject: pkix.Name{CommonName: "CRL root"}, IsCA: true, KeyUsage: KeyUsageCertSign | KeyUsageCRLSign}, nil)
+	good := issue(t, &Template{Subject: pkix.Name{CommonName: "good"}}, root)
+	bad := issue(t, &Template{Subject: pkix.Name{CommonName: "bad"}}, root)
+	tmpl := &CRLTemplate{
+		Number:     big.NewInt(7),
+		ThisUpdate: testNow.Add(-time.Minute),
+		NextUpdate: testNow.Add(24 * time.Hour),
+		Revoked:    []RevokedCertificate{{SerialNumber: bad.cert.SerialNumber, RevocationTime: testNow.Add(-time.Hour), ReasonCode: 4}},
+	}
+	der, err := CreateCRL(rand.Reader, tmpl, root.cert, root.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := ParseCRL(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crl.Number.Int64() != 7 || string(crl.AuthorityKeyID) != string(root.cert.SubjectKeyID) {
+		t.Errorf("number %v, AKI %x", crl.Number, crl.AuthorityKeyID)
+	}
+
+	if r, err := crl.Check(good.cert, root.cert, testNow, nil); r != nil || err != nil {
+		t.Errorf("good: %+v, %v", r, err)
+	}
+	if r, err := crl.Check(bad.cert, root.cert, testNow, nil); err != nil || r == nil || r.ReasonCode != 4 || !r.RevocationTime.Equal(testNow.Add(-time.Hour)) {
+		t.Errorf("bad: %+v, %v", r, err)
+	}
+	other := issue(t, &Template{Subject: pkix.Name{CommonName: "other"}, IsCA: true}, nil)
+	for _, tc := range []struct {
+		issuer *Certificate
+		now    time.Time
+		uid    []byte
+		want   error
+	}{
+		{other.cert, testNow, nil, ErrCRLIssuer},
+		{root.cert, testNow, []byte{}, ErrSignature},
+		{root.cert, testNow.Add(48 * time.Hour), nil, ErrCRLExpired},
+		{root.cert, testNow.Add(-time.Hour), nil, ErrCRLNotYetValid},
+	} {
+		if _, err := crl.Check(good.cert, tc.issuer, tc.now, tc.uid); !errors.Is(err, tc.want) {
+			t.Errorf("want %v, got %v", tc.want, err)
+		}
+	}
+
+	signOnly := issue(t, &Template{Subject: pkix.Name{CommonName: "sign only"}, IsCA: true, KeyUsage: KeyUsageCertSign}, nil)
+	if _, err := CreateCRL(rand.Reader, tmpl, signOnly.cert, signOnly.key); err == nil {
+		t.Error("CRL signed without cRLSign")
+	}
+	if _, err := CreateCRL(rand.Reader, tmpl, root.cert, good.key); err == nil {
+		t.Error("CRL signed with a key that does not match the issuer")
+	}
+}
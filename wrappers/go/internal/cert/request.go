@@ -0,0 +1,133 @@
+package cert
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// oidExtensionRequest is the PKCS #9 attribute carrying the extensions a
+// PKCS #10 request asks for.
+var oidExtensionRequest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 14}
+
+// Request is a parsed PKCS #10 certification request for an SM2 key.
+// ExtensionFields decodes its extension request.
+type Request struct {
+	Raw                []byte
+	RawTBSRequest      []byte
+	RawSubject         []byte
+	Subject            pkix.Name
+	PublicKey          *sm2.PublicKey
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Signature          []byte
+
+	Extensions []pkix.Extension
+	ExtensionFields
+}
+
+type tbsRequest struct {
+	Version    int
+	Subject    asn1.RawValue
+	PublicKey  publicKeyInfo
+	Attributes []attribute `asn1:"tag:0,set"`
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// CreateRequest returns a DER PKCS #10 request for priv, signed with
+// SM2-with-SM3 under tmpl.SignerUID. The subject and extension fields of
+// tmpl are requested; serial number and validity are left to the CA.
+func CreateRequest(rand io.Reader, tmpl *Template, priv *sm2.PrivateKey) ([]byte, error) {
+	subject, err := asn1.Marshal(tmpl.Subject.ToRDNSequence())
+	if err != nil {
+		return nil, err
+	}
+	spki, err := sm2.MarshalPKIXPublicKey(priv.PublicKey.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	exts, err := extensions(tmpl, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute{}
+	if len(exts) > 0 {
+		der, err := asn1.Marshal(exts)
+		if err != nil {
+			return nil, err
+		}
+		attrs = append(attrs, attribute{Type: oidExtensionRequest, Values: []asn1.RawValue{{FullBytes: der}}})
+	}
+	tbs, err := asn1.Marshal(tbsRequest{
+		Subject:    asn1.RawValue{FullBytes: subject},
+		PublicKey:  publicKeyInfo{Raw: spki},
+		Attributes: attrs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sign(rand, tbs, pkix.AlgorithmIdentifier{Algorithm: OIDSignatureSM2WithSM3}, priv, tmpl.SignerUID)
+}
+
+// ParseRequest parses a DER PKCS #10 request. It does not check the
+// signature.
+func ParseRequest(der []byte) (*Request, error) {
+	var outer certificate
+	rest, err := asn1.Unmarshal(der, &outer)
+	if err != nil {
+		return nil, fmt.Errorf("cert: malformed request: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("cert: trailing data after request")
+	}
+	var tbs tbsRequest
+	if _, err := asn1.Unmarshal(outer.TBS.FullBytes, &tbs); err != nil {
+		return nil, fmt.Errorf("cert: malformed certificationRequestInfo: %v", err)
+	}
+	if tbs.Version != 0 {
+		return nil, fmt.Errorf("cert: unsupported request version %d", tbs.Version)
+	}
+	req := &Request{
+		Raw:                der,
+		RawTBSRequest:      outer.TBS.FullBytes,
+		RawSubject:         tbs.Subject.FullBytes,
+		SignatureAlgorithm: outer.SignatureAlgorithm.Algorithm,
+		Signature:          outer.Signature.RightAlign(),
+	}
+	if req.Subject, err = parseName(req.RawSubject); err != nil {
+		return nil, err
+	}
+	point, err := sm2.UnmarshalPKIXPublicKey(tbs.PublicKey.Raw)
+	if err != nil {
+		return nil, fmt.Errorf("cert: request key: %v", err)
+	}
+	if req.PublicKey, err = sm2.ParsePublicKey(point); err != nil {
+		return nil, fmt.Errorf("cert: request key: %v", err)
+	}
+	for _, attr := range tbs.Attributes {
+		if !attr.Type.Equal(oidExtensionRequest) || len(attr.Values) != 1 {
+			continue
+		}
+		if _, err := asn1.Unmarshal(attr.Values[0].FullBytes, &req.Extensions); err != nil {
+			return nil, fmt.Errorf("cert: malformed extension request: %v", err)
+		}
+	}
+	if err := req.ExtensionFields.parse(req.Extensions); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// CheckSignature reports whether the request is signed by its own key
+// under the signer identity uid, which defaults to sm2.DefaultUID as in
+// Certificate.CheckSignature.
+func (r *Request) CheckSignature(uid []byte) error {
+	return checkSignature(r.SignatureAlgorithm, r.RawTBSRequest, r.Signature, r.PublicKey, uid)
+}
@@ -0,0 +1,80 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"net"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// A request for the key of opensslCertPEM with a subject alternative name
+// and key usage, written by OpenSSL 3.0.
+const opensslRequestPEM = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBITCByQIBADArMRQwEgYDVQQDDAtjc3Igc3ViamVjdDETMBEGA1UECgwKc20t
+YmMtdGVzdDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABFXO7PPirydmOGNbhV0J
+glN7KpsQ1Y4kqfa6FvTR8T2qo2IuMRWrZq5eHqUf0m6RGUN59bGnQfGunsRLThIF
+wh+gPDA6BgkqhkiG9w0BCQ4xLTArMBkGA1UdEQQSMBCCCGNzci50ZXN0hwQKAQID
+MA4GA1UdDwEB/wQEAwIHgDAKBggqgRzPVQGDdQNHADBEAiAMREBMXazYN4gN47lr
+bk6OepTA0J//toKuB696PzPjdgIgJOAie2mSqNc7NFRY0sfKWNqyQ5kWe4Z2O8ew
+HrIpqLs=
+-----END CERTIFICATE REQUEST-----
+`
+
+func TestParseOpenSSLRequest(t *testing.T) {
+	block, _ := pem.Decode([]byte(opensslRequestPEM))
+	req, err := ParseRequest(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Subject.String() != "CN=csr subject,O=sm-bc-test" || len(req.DNSNames) != 1 || req.DNSNames[0] != "csr.test" ||
+		!req.IPAddresses[0].Equal(net.IPv4(10, 1, 2, 3)) || req.KeyUsage != KeyUsageDigitalSignature {
+		t.Errorf("request: %q %v %v %b", req.Subject, req.DNSNames, req.IPAddresses, req.KeyUsage)
+	}
+	if err := req.CheckSignature([]byte{}); err != nil {
+		t.Fatal(err)
+	}
+	block, _ = pem.Decode([]byte(opensslCertPEM))
+	c, _ := Parse(block.Bytes)
+	if !samePublicKey(req.PublicKey, c.PublicKey) {
+		t.Error("request key differs from the certificate key")
+	}
+}
+
+func TestCreateRequest(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	for _, tmpl := range []*Template{
+		{Subject: pkix.Name{CommonName: "bare"}},
+		{
+			Subject:     pkix.Name{CommonName: "server", Country: []string{"CN"}},
+			DNSNames:    []string{"server.test"},
+			KeyUsage:    KeyUsageDigitalSignature | KeyUsageKeyAgreement,
+			ExtKeyUsage: []asn1.ObjectIdentifier{OIDExtKeyUsageServerAuth},
+		},
+	} {
+		der, err := CreateRequest(rand.Reader, tmpl, priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req, err := ParseRequest(der)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := req.CheckSignature(nil); err != nil {
+			t.Fatal(err)
+		}
+		if req.Subject.CommonName != tmpl.Subject.CommonName || !samePublicKey(req.PublicKey, &priv.PublicKey) ||
+			len(req.DNSNames) != len(tmpl.DNSNames) || req.KeyUsage != tmpl.KeyUsage || len(req.ExtKeyUsage) != len(tmpl.ExtKeyUsage) {
+			t.Errorf("%s: %+v", tmpl.Subject.CommonName, req)
+		}
+		// The signature covers the request; changing a byte of the
+		// subject breaks it.
+		der[20] ^= 1
+		if req, err := ParseRequest(der); err == nil && req.CheckSignature(nil) == nil {
+			t.Errorf("%s: tampered request verified", tmpl.Subject.CommonName)
+		}
+	}
+}
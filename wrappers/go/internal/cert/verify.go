@@ -0,0 +1,201 @@
+package cert
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"fmt"
+	"time"
+)
+
+// oidExtKeyUsageAny is anyExtendedKeyUsage, which satisfies every purpose.
+var oidExtKeyUsageAny = asn1.ObjectIdentifier{2, 5, 29, 37, 0}
+
+// InvalidReason says why Verify rejected a certificate.
+type InvalidReason int
+
+const (
+	// UnknownIssuer: no candidate issuer leads to a root.
+	UnknownIssuer InvalidReason = iota
+	// BadSignature: the certificate does not verify under its issuer's key.
+	BadSignature
+	// Expired: the time is outside the certificate's validity period.
+	Expired
+	// NotYetValid: the time is before the certificate's validity period.
+	NotYetValid
+	// NotCA: an issuer lacks basic constraints with cA set.
+	NotCA
+	// KeyUsageMismatch: an issuer's key usage lacks keyCertSign.
+	KeyUsageMismatch
+	// TooManyIntermediates: an issuer's path length constraint is exceeded.
+	TooManyIntermediates
+	// ExtKeyUsageMismatch: the leaf lacks a required extended key usage.
+	ExtKeyUsageMismatch
+	// UnhandledCriticalExtension: a certificate has a critical extension
+	// the package does not process.
+	UnhandledCriticalExtension
+)
+
+var reasonText = map[InvalidReason]string{
+	UnknownIssuer:              "no chain to a trusted root",
+	BadSignature:               "signature does not verify under the issuer's key",
+	Expired:                    "certificate has expired",
+	NotYetValid:                "certificate is not yet valid",
+	NotCA:                      "issuer is not a CA",
+	KeyUsageMismatch:           "issuer key usage does not allow certificate signing",
+	TooManyIntermediates:       "issuer path length constraint exceeded",
+	ExtKeyUsageMismatch:        "certificate is not valid for the requested purpose",
+	UnhandledCriticalExtension: "certificate has an unhandled critical extension",
+}
+
+// VerifyError is returned by Verify. Cert is the certificate that failed
+// the check.
+type VerifyError struct {
+	Reason InvalidReason
+	Cert   *Certificate
+}
+
+func (e *VerifyError) Error() string {
+	return fmt.Sprintf("cert: %q: %s", e.Cert.Subject.String(), reasonText[e.Reason])
+}
+
+// VerifyOptions are the trust anchors and checks of Verify.
+type VerifyOptions struct {
+	Roots         []*Certificate
+	Intermediates []*Certificate
+	// CurrentTime is the time of validity checks; zero means now.
+	CurrentTime time.Time
+	// ExtKeyUsage lists purposes the leaf must allow. A leaf without the
+	// extension allows every purpose.
+	ExtKeyUsage []asn1.ObjectIdentifier
+	// SignerUID is the SM2 signer identity of every signature in the
+	// chain, as in CheckSignature.
+	SignerUID []byte
+}
+
+// Verify builds a chain from c through Intermediates to one of Roots and
+// returns it, leaf first. Every certificate must be within its validity
+// period and have no unhandled critical extensions, and every issuer must
+// be a CA whose key usage (when present) includes keyCertSign and whose
+// path length constraint holds. The first problem found is returned as a
+// *VerifyError.
+func (c *Certificate) Verify(opts VerifyOptions) ([]*Certificate, error) {
+	if opts.CurrentTime.IsZero() {
+		opts.CurrentTime = time.Now()
+	}
+	if err := checkCertificate(c, opts.CurrentTime); err != nil {
+		return nil, err
+	}
+	if !allowsPurposes(c, opts.ExtKeyUsage) {
+		return nil, &VerifyError{ExtKeyUsageMismatch, c}
+	}
+	for _, root := range opts.Roots {
+		if bytes.Equal(root.Raw, c.Raw) {
+			return []*Certificate{c}, nil
+		}
+	}
+	return buildChain([]*Certificate{c}, &opts)
+}
+
+// checkCertificate checks what every certificate of a chain must meet on
+// its own: the validity period and the critical extensions.
+func checkCertificate(c *Certificate, now time.Time) error {
+	switch {
+	case now.Before(c.NotBefore):
+		return &VerifyError{NotYetValid, c}
+	case now.After(c.NotAfter):
+		return &VerifyError{Expired, c}
+	case len(c.UnhandledCriticalExtensions) > 0:
+		return &VerifyError{UnhandledCriticalExtension, c}
+	}
+	return nil
+}
+
+func allowsPurposes(c *Certificate, purposes []asn1.ObjectIdentifier) bool {
+	if len(c.ExtKeyUsage) == 0 {
+		return true
+	}
+	for _, want := range purposes {
+		found := false
+		for _, have := range c.ExtKeyUsage {
+			if have.Equal(want) || have.Equal(oidExtKeyUsageAny) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// buildChain extends chain, whose last element still needs an issuer,
+// depth first: roots before intermediates.
+func buildChain(chain []*Certificate, opts *VerifyOptions) ([]*Certificate, error) {
+	child := chain[len(chain)-1]
+	var firstErr error
+	try := func(parent *Certificate, isRoot bool) []*Certificate {
+		if !bytes.Equal(parent.RawSubject, child.RawIssuer) {
+			return nil
+		}
+		if len(child.AuthorityKeyID) > 0 && len(parent.SubjectKeyID) > 0 && !bytes.Equal(child.AuthorityKeyID, parent.SubjectKeyID) {
+			return nil
+		}
+		for _, c := range chain {
+			if bytes.Equal(c.Raw, parent.Raw) {
+				return nil
+			}
+		}
+		// chain holds the leaf and the intermediates below parent.
+		err := checkIssuer(parent, child, len(chain)-1, opts)
+		if err == nil {
+			next := append(chain[:len(chain):len(chain)], parent)
+			if isRoot {
+				return next
+			}
+			var path []*Certificate
+			if path, err = buildChain(next, opts); err == nil {
+				return path
+			}
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		return nil
+	}
+	for _, root := range opts.Roots {
+		if path := try(root, true); path != nil {
+			return path, nil
+		}
+	}
+	for _, ca := range opts.Intermediates {
+		if path := try(ca, false); path != nil {
+			return path, nil
+		}
+	}
+	if firstErr == nil {
+		firstErr = &VerifyError{UnknownIssuer, child}
+	}
+	return nil, firstErr
+}
+
+// checkIssuer checks that parent may issue child with below intermediate
+// CAs under it.
+func checkIssuer(parent, child *Certificate, below int, opts *VerifyOptions) error {
+	if parent.PublicKey == nil || child.CheckSignature(parent.PublicKey, opts.SignerUID) != nil {
+		return &VerifyError{BadSignature, child}
+	}
+	if err := checkCertificate(parent, opts.CurrentTime); err != nil {
+		return err
+	}
+	if !parent.BasicConstraintsValid || !parent.IsCA {
+		return &VerifyError{NotCA, parent}
+	}
+	if parent.KeyUsage != 0 && parent.KeyUsage&KeyUsageCertSign == 0 {
+		return &VerifyError{KeyUsageMismatch, parent}
+	}
+	if parent.MaxPathLen >= 0 && below > parent.MaxPathLen {
+		return &VerifyError{TooManyIntermediates, parent}
+	}
+	return nil
+}
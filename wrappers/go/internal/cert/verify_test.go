@@ -0,0 +1,141 @@
+package cert
+
+import (
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+type testCA struct {
+	cert *Certificate
+	key  *sm2.PrivateKey
+}
+
+func issue(t *testing.T, tmpl *Template, parent *testCA) *testCA {
+	t.Helper()
+	key, _ := sm2.GenerateKey(rand.Reader)
+	signer, parentCert := key, (*Certificate)(nil)
+	if parent != nil {
+		signer, parentCert = parent.key, parent.cert
+	}
+	if tmpl.NotAfter.IsZero() {
+		tmpl.NotBefore, tmpl.NotAfter = testNow.Add(-time.Hour), testNow.Add(time.Hour)
+	}
+	der, err := Create(rand.Reader, tmpl, &key.PublicKey, parentCert, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{c, key}
+}
+
+var testNow = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func caTemplate(cn string, pathLen int) *Template {
+	return &Template{Subject: pkix.Name{CommonName: cn}, IsCA: true, MaxPathLen: pathLen, KeyUsage: KeyUsageCertSign}
+}
+
+func reasonOf(err error) (InvalidReason, bool) {
+	var ve *VerifyError
+	if errors.As(err, &ve) {
+		return ve.Reason, true
+	}
+	return 0, false
+}
+
+func TestVerifyChain(t *testing.T) {
+	root := issue(t, caTemplate("root", 1), nil)
+	inter := issue(t, caTemplate("inter", 0), root)
+	leaf := issue(t, &Template{
+		Subject:     pkix.Name{CommonName: "leaf"},
+		KeyUsage:    KeyUsageDigitalSignature,
+		ExtKeyUsage: []asn1.ObjectIdentifier{OIDExtKeyUsageServerAuth},
+	}, inter)
+	opts := VerifyOptions{Roots: []*Certificate{root.cert}, Intermediates: []*Certificate{inter.cert}, CurrentTime: testNow}
+
+	chain, err := leaf.cert.Verify(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(chain) != 3 || chain[0] != leaf.cert || chain[1] != inter.cert || chain[2] != root.cert {
+		t.Fatalf("chain of %d", len(chain))
+	}
+	if chain, err := root.cert.Verify(opts); err != nil || len(chain) != 1 {
+		t.Errorf("root alone: %d, %v", len(chain), err)
+	}
+	opts.ExtKeyUsage = []asn1.ObjectIdentifier{OIDExtKeyUsageServerAuth}
+	if _, err := leaf.cert.Verify(opts); err != nil {
+		t.Errorf("serverAuth: %v", err)
+	}
+
+	rogue := issue(t, caTemplate("inter", -1), nil) // same name, other key
+	notCA := issue(t, &Template{Subject: pkix.Name{CommonName: "not a CA"}}, root)
+	notCALeaf := issue(t, &Template{Subject: pkix.Name{CommonName: "leaf"}}, notCA)
+	noSign := issue(t, &Template{Subject: pkix.Name{CommonName: "no sign"}, IsCA: true, MaxPathLen: -1, KeyUsage: KeyUsageCRLSign}, root)
+	noSignLeaf := issue(t, &Template{Subject: pkix.Name{CommonName: "leaf"}}, noSign)
+	tampered := *leaf.cert
+	tampered.Signature = append([]byte{}, leaf.cert.Signature...)
+	tampered.Signature[len(tampered.Signature)-1] ^= 1
+	inter2 := issue(t, caTemplate("inter 2", -1), inter)
+	deep := issue(t, &Template{Subject: pkix.Name{CommonName: "too deep"}}, inter2)
+	// A critical policy constraints extension, which Verify does not
+	// process.
+	policy := []pkix.Extension{{Id: asn1.ObjectIdentifier{2, 5, 29, 36}, Critical: true, Value: []byte{0x30, 0x03, 0x80, 0x01, 0x00}}}
+	critLeaf := issue(t, &Template{Subject: pkix.Name{CommonName: "leaf"}, ExtraExtensions: policy}, inter)
+	critInterTmpl := caTemplate("critical inter", -1)
+	critInterTmpl.ExtraExtensions = policy
+	critInter := issue(t, critInterTmpl, root)
+	critInterLeaf := issue(t, &Template{Subject: pkix.Name{CommonName: "leaf"}}, critInter)
+	if len(critLeaf.cert.UnhandledCriticalExtensions) != 1 || len(leaf.cert.UnhandledCriticalExtensions) != 0 {
+		t.Errorf("unhandled critical extensions: %v and %v", critLeaf.cert.UnhandledCriticalExtensions, leaf.cert.UnhandledCriticalExtensions)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		leaf   *Certificate
+		opts   VerifyOptions
+		reason InvalidReason
+	}{
+		{"expired", leaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: opts.Intermediates, CurrentTime: testNow.Add(2 * time.Hour)}, Expired},
+		{"not yet valid", leaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: opts.Intermediates, CurrentTime: testNow.Add(-2 * time.Hour)}, NotYetValid},
+		{"no intermediate", leaf.cert, VerifyOptions{Roots: opts.Roots, CurrentTime: testNow}, UnknownIssuer},
+		// Same name, other key: the key identifiers rule it out.
+		{"rogue intermediate", leaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: []*Certificate{rogue.cert}, CurrentTime: testNow}, UnknownIssuer},
+		{"signature", &tampered, opts, BadSignature},
+		{"purpose", leaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: opts.Intermediates, CurrentTime: testNow,
+			ExtKeyUsage: []asn1.ObjectIdentifier{OIDExtKeyUsageCodeSigning}}, ExtKeyUsageMismatch},
+		{"not a CA", notCALeaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: []*Certificate{notCA.cert}, CurrentTime: testNow}, NotCA},
+		{"key usage", noSignLeaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: []*Certificate{noSign.cert}, CurrentTime: testNow}, KeyUsageMismatch},
+		{"path length", deep.cert, VerifyOptions{Roots: opts.Roots, Intermediates: []*Certificate{inter.cert, inter2.cert}, CurrentTime: testNow}, TooManyIntermediates},
+		{"critical extension", critLeaf.cert, opts, UnhandledCriticalExtension},
+		{"critical extension in an issuer", critInterLeaf.cert, VerifyOptions{Roots: opts.Roots, Intermediates: []*Certificate{critInter.cert}, CurrentTime: testNow}, UnhandledCriticalExtension},
+	} {
+		_, err := tc.leaf.Verify(tc.opts)
+		if reason, ok := reasonOf(err); !ok || reason != tc.reason {
+			t.Errorf("%s: %v, want reason %d", tc.name, err, tc.reason)
+		}
+	}
+}
+
+func TestVerifySignerUID(t *testing.T) {
+	root := issue(t, caTemplate("root", -1), nil)
+	tmpl := &Template{Subject: pkix.Name{CommonName: "leaf"}, SignerUID: []byte{}}
+	leaf := issue(t, tmpl, root)
+	opts := VerifyOptions{Roots: []*Certificate{root.cert}, CurrentTime: testNow}
+	_, err := leaf.cert.Verify(opts)
+	if reason, ok := reasonOf(err); !ok || reason != BadSignature {
+		t.Errorf("default identity: reason %d", reason)
+	}
+	opts.SignerUID = []byte{}
+	if _, err := leaf.cert.Verify(opts); err != nil {
+		t.Error(err)
+	}
+}
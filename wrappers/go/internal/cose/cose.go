@@ -0,0 +1,347 @@
+// Package cose builds and checks COSE_Sign1 and COSE_Encrypt0 messages
+// (RFC 9052) with SM2-with-SM3 signatures and SM4-GCM encryption.
+//
+// No COSE algorithm identifiers are registered for the SM algorithms, so
+// the alg header carries the text values "SM2-SM3" and "SM4-GCM", which
+// RFC 9052 allows, matching the SM-JOSE names. SM2 signatures are the
+// 64-byte r || s over the Sig_structure under a signer identity; SM4-GCM
+// uses a 12-byte IV and a 16-byte tag like A128GCM (RFC 9053 section 4.1).
+// Messages are written in the deterministic CBOR encoding, so that with a
+// deterministic signature or a fixed IV they are byte-exact.
+package cose
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cbor"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// Algorithm values of the alg header.
+const (
+	AlgSM2SM3 = "SM2-SM3"
+	AlgSM4GCM = "SM4-GCM"
+)
+
+// CBOR tags of the message types.
+const (
+	TagSign1    = 18
+	TagEncrypt0 = 16
+)
+
+// Header labels.
+const (
+	labelAlg  = 1
+	labelCrit = 2
+	labelKID  = 4
+	labelIV   = 5
+)
+
+// Errors of verification and decryption.
+var (
+	ErrAlgorithm  = errors.New("cose: unsupported algorithm")
+	ErrCritical   = errors.New("cose: unsupported critical header parameter")
+	ErrSignature  = errors.New("cose: signature does not verify")
+	ErrDecryption = errors.New("cose: decryption failed")
+)
+
+const (
+	ivSize  = 12
+	tagSize = 16
+)
+
+// Options are the optional parts of a message.
+type Options struct {
+	// KID is placed in the unprotected header.
+	KID []byte
+	// ExternalAAD is authenticated with the message but not carried in
+	// it.
+	ExternalAAD []byte
+	// Untagged leaves out the CBOR tag of the message type.
+	Untagged bool
+}
+
+func protectedHeader(alg string) ([]byte, error) {
+	return cbor.Marshal(cbor.Map{int64(labelAlg): alg})
+}
+
+func unprotectedHeader(opts Options) cbor.Map {
+	h := cbor.Map{}
+	if opts.KID != nil {
+		h[int64(labelKID)] = opts.KID
+	}
+	return h
+}
+
+func wrap(tag uint64, msg []interface{}, opts Options) ([]byte, error) {
+	if opts.Untagged {
+		return cbor.Marshal(msg)
+	}
+	return cbor.Marshal(cbor.Tag{Number: tag, Content: msg})
+}
+
+// Message is a parsed COSE_Sign1 or COSE_Encrypt0.
+type Message struct {
+	Protected   cbor.Map
+	Unprotected cbor.Map
+	// Payload is the signed payload of a COSE_Sign1.
+	Payload []byte
+
+	rawProtected []byte
+	// last is the signature of a COSE_Sign1 and the ciphertext of a
+	// COSE_Encrypt0.
+	last []byte
+}
+
+// KID returns the key ID of m, from either header.
+func (m *Message) KID() []byte {
+	for _, h := range []cbor.Map{m.Protected, m.Unprotected} {
+		if kid, ok := h[int64(labelKID)].([]byte); ok {
+			return kid
+		}
+	}
+	return nil
+}
+
+// parse reads a message of n elements, tagged with tag or untagged.
+func parse(data []byte, tag uint64, n int) (*Message, []interface{}, error) {
+	v, err := cbor.Unmarshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t, ok := v.(cbor.Tag); ok {
+		if t.Number != tag {
+			return nil, nil, fmt.Errorf("cose: unexpected tag %d, want %d", t.Number, tag)
+		}
+		v = t.Content
+	}
+	a, ok := v.([]interface{})
+	if !ok || len(a) != n {
+		return nil, nil, fmt.Errorf("cose: message is not an array of %d elements", n)
+	}
+	m := &Message{}
+	if m.rawProtected, ok = a[0].([]byte); !ok {
+		return nil, nil, errors.New("cose: protected header is not a byte string")
+	}
+	m.Protected = cbor.Map{}
+	if len(m.rawProtected) > 0 {
+		p, err := cbor.Unmarshal(m.rawProtected)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cose: protected header: %v", err)
+		}
+		if m.Protected, ok = p.(cbor.Map); !ok {
+			return nil, nil, errors.New("cose: protected header is not a map")
+		}
+	}
+	if m.Unprotected, ok = a[1].(cbor.Map); !ok {
+		return nil, nil, errors.New("cose: unprotected header is not a map")
+	}
+	for k := range m.Unprotected {
+		if _, ok := m.Protected[k]; ok {
+			return nil, nil, fmt.Errorf("cose: header label %v is both protected and unprotected", k)
+		}
+	}
+	if m.last, ok = a[n-1].([]byte); !ok {
+		return nil, nil, errors.New("cose: last element is not a byte string")
+	}
+	return m, a, nil
+}
+
+// check returns ErrAlgorithm unless the protected alg is alg, and
+// ErrCritical if critical parameters are named.
+func (m *Message) check(alg string) error {
+	if m.Protected[int64(labelAlg)] != alg {
+		return ErrAlgorithm
+	}
+	if _, ok := m.Protected[int64(labelCrit)]; ok {
+		return ErrCritical
+	}
+	return nil
+}
+
+// SignOptions are the options of Sign1.
+type SignOptions struct {
+	Options
+	// Detached leaves the payload out of the message (nil in its place).
+	Detached bool
+	// Deterministic derives the signature nonce as RFC 6979 does, with
+	// HMAC-SM3, so that the message is reproducible.
+	Deterministic bool
+}
+
+// sigStructure returns the Sig_structure of a COSE_Sign1.
+func sigStructure(protected, externalAAD, payload []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	return cbor.Marshal([]interface{}{"Signature1", protected, externalAAD, payload})
+}
+
+// Sign1 returns a COSE_Sign1 of payload signed by priv under the signer
+// identity uid.
+func Sign1(rand io.Reader, payload []byte, priv *sm2.PrivateKey, uid []byte, opts SignOptions) ([]byte, error) {
+	protected, err := protectedHeader(AlgSM2SM3)
+	if err != nil {
+		return nil, err
+	}
+	tbs, err := sigStructure(protected, opts.ExternalAAD, payload)
+	if err != nil {
+		return nil, err
+	}
+	var r, s *big.Int
+	if opts.Deterministic {
+		r, s, err = sm2.SignDeterministic(priv, tbs, uid)
+	} else {
+		r, s, err = sm2.Sign(rand, priv, tbs, uid)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	var body interface{} = payload
+	if opts.Detached {
+		body = nil
+	}
+	return wrap(TagSign1, []interface{}{protected, unprotectedHeader(opts.Options), body, sig}, opts.Options)
+}
+
+// ParseSign1 parses a COSE_Sign1. detached is the payload of a message
+// that leaves it out, and must be nil for one that carries it.
+func ParseSign1(data, detached []byte) (*Message, error) {
+	m, a, err := parse(data, TagSign1, 4)
+	if err != nil {
+		return nil, err
+	}
+	switch p := a[2].(type) {
+	case []byte:
+		if detached != nil {
+			return nil, errors.New("cose: message carries a payload but a detached one was given")
+		}
+		m.Payload = p
+	case nil:
+		if detached == nil {
+			return nil, errors.New("cose: payload is detached and was not given")
+		}
+		m.Payload = detached
+	default:
+		return nil, errors.New("cose: payload is not a byte string or nil")
+	}
+	return m, nil
+}
+
+// Verify checks the signature of a COSE_Sign1 under pub, the signer
+// identity uid and externalAAD.
+func (m *Message) Verify(pub *sm2.PublicKey, uid, externalAAD []byte) error {
+	if err := m.check(AlgSM2SM3); err != nil {
+		return err
+	}
+	if len(m.last) != 64 {
+		return ErrSignature
+	}
+	tbs, err := sigStructure(m.rawProtected, externalAAD, m.Payload)
+	if err != nil {
+		return err
+	}
+	r := new(big.Int).SetBytes(m.last[:32])
+	s := new(big.Int).SetBytes(m.last[32:])
+	if !sm2.Verify(pub, tbs, uid, r, s) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// EncryptOptions are the options of Encrypt0.
+type EncryptOptions struct {
+	Options
+	// IV is the 12-byte GCM nonce, random when nil. An IV must never be
+	// used twice with one key.
+	IV []byte
+}
+
+// encStructure returns the Enc_structure of a COSE_Encrypt0, the
+// additional data of SM4-GCM.
+func encStructure(protected, externalAAD []byte) ([]byte, error) {
+	if externalAAD == nil {
+		externalAAD = []byte{}
+	}
+	return cbor.Marshal([]interface{}{"Encrypt0", protected, externalAAD})
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt0 returns a COSE_Encrypt0 of plaintext under the 16-byte SM4
+// key, with the IV in the unprotected header.
+func Encrypt0(rand io.Reader, plaintext, key []byte, opts EncryptOptions) ([]byte, error) {
+	iv := opts.IV
+	if iv == nil {
+		iv = make([]byte, ivSize)
+		if _, err := io.ReadFull(rand, iv); err != nil {
+			return nil, err
+		}
+	} else if len(iv) != ivSize {
+		return nil, fmt.Errorf("cose: IV must be %d bytes, got %d", ivSize, len(iv))
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	protected, err := protectedHeader(AlgSM4GCM)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := encStructure(protected, opts.ExternalAAD)
+	if err != nil {
+		return nil, err
+	}
+	unprotected := unprotectedHeader(opts.Options)
+	unprotected[int64(labelIV)] = iv
+	ciphertext := aead.Seal(nil, iv, plaintext, aad)
+	return wrap(TagEncrypt0, []interface{}{protected, unprotected, ciphertext}, opts.Options)
+}
+
+// ParseEncrypt0 parses a COSE_Encrypt0.
+func ParseEncrypt0(data []byte) (*Message, error) {
+	m, _, err := parse(data, TagEncrypt0, 3)
+	return m, err
+}
+
+// Decrypt returns the plaintext of a COSE_Encrypt0 under key and
+// externalAAD.
+func (m *Message) Decrypt(key, externalAAD []byte) ([]byte, error) {
+	if err := m.check(AlgSM4GCM); err != nil {
+		return nil, err
+	}
+	iv, ok := m.Unprotected[int64(labelIV)].([]byte)
+	if !ok {
+		iv, ok = m.Protected[int64(labelIV)].([]byte)
+	}
+	if !ok || len(iv) != ivSize || len(m.last) < tagSize {
+		return nil, ErrDecryption
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	aad, err := encStructure(m.rawProtected, externalAAD)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, iv, m.last, aad)
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,142 @@
+package cose
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cbor"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func TestSign1(t *testing.T) {
+	priv, _ := sm2.NewPrivateKey(bytes.Repeat([]byte{0x11}, 32))
+	other, _ := sm2.GenerateKey(rand.Reader)
+	uid := []byte(sm2.DefaultUID)
+	payload := []byte("sensor 42: 21.5C")
+
+	opts := SignOptions{Options: Options{KID: []byte("k1")}, Deterministic: true}
+	msg, err := Sign1(rand.Reader, payload, priv, uid, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tag 18, four elements, << {1: "SM2-SM3"} >>, {4: h'6b31'}.
+	if h := hex.EncodeToString(msg); !strings.HasPrefix(h, "d2844aa10167534d322d534d33a104426b3150") {
+		t.Errorf("Sign1 = %s", h)
+	}
+	again, _ := Sign1(rand.Reader, payload, priv, uid, opts)
+	if !bytes.Equal(msg, again) {
+		t.Error("deterministic messages differ")
+	}
+	m, err := ParseSign1(msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(m.Payload, payload) || string(m.KID()) != "k1" {
+		t.Errorf("ParseSign1 = %+v", m)
+	}
+	if err := m.Verify(&priv.PublicKey, uid, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Verify(&other.PublicKey, uid, nil); err != ErrSignature {
+		t.Errorf("other key: %v", err)
+	}
+	if err := m.Verify(&priv.PublicKey, uid, []byte("aad")); err != ErrSignature {
+		t.Errorf("other external AAD: %v", err)
+	}
+
+	opts = SignOptions{Options: Options{ExternalAAD: []byte("aad"), Untagged: true}, Detached: true}
+	msg, err = Sign1(rand.Reader, payload, priv, uid, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg[0] != 0x84 {
+		t.Errorf("untagged message starts %x", msg[0])
+	}
+	if _, err := ParseSign1(msg, nil); err == nil {
+		t.Error("detached message parsed without its payload")
+	}
+	if m, err := ParseSign1(msg, payload); err != nil || m.Verify(&priv.PublicKey, uid, []byte("aad")) != nil {
+		t.Errorf("detached: %v", err)
+	}
+	if m, _ := ParseSign1(msg, []byte("other")); m.Verify(&priv.PublicKey, uid, []byte("aad")) != ErrSignature {
+		t.Error("detached message verified with another payload")
+	}
+
+	// A message whose alg is ES256 (-7) is refused before any signature
+	// check.
+	es256, _ := cbor.Marshal(cbor.Map{int64(1): int64(-7)})
+	forged, _ := cbor.Marshal(cbor.Tag{Number: TagSign1, Content: []interface{}{es256, cbor.Map{}, payload, make([]byte, 64)}})
+	if m, err := ParseSign1(forged, nil); err != nil || m.Verify(&priv.PublicKey, uid, nil) != ErrAlgorithm {
+		t.Errorf("ES256: %v", err)
+	}
+	crit, _ := cbor.Marshal(cbor.Map{int64(1): AlgSM2SM3, int64(2): []interface{}{int64(99)}})
+	forged, _ = cbor.Marshal([]interface{}{crit, cbor.Map{}, payload, make([]byte, 64)})
+	if m, err := ParseSign1(forged, nil); err != nil || m.Verify(&priv.PublicKey, uid, nil) != ErrCritical {
+		t.Errorf("crit: %v", err)
+	}
+	for _, bad := range []interface{}{
+		cbor.Tag{Number: TagEncrypt0, Content: []interface{}{[]byte{}, cbor.Map{}, payload, []byte{}}},
+		[]interface{}{[]byte{}, cbor.Map{}, payload},
+		[]interface{}{cbor.Map{}, cbor.Map{}, payload, []byte{}},
+		[]interface{}{[]byte{0xa1}, cbor.Map{}, payload, []byte{}},
+		[]interface{}{es256, cbor.Map{int64(1): int64(-7)}, payload, []byte{}},
+		[]interface{}{[]byte{}, cbor.Map{}, "text", []byte{}},
+	} {
+		data, _ := cbor.Marshal(bad)
+		if _, err := ParseSign1(data, nil); err == nil {
+			t.Errorf("ParseSign1(%x) succeeded", data)
+		}
+	}
+}
+
+func TestEncrypt0(t *testing.T) {
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	iv, _ := hex.DecodeString("000102030405060708090a0b")
+	plaintext := []byte("open valve 3")
+
+	msg, err := Encrypt0(rand.Reader, plaintext, key, EncryptOptions{IV: iv})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Tag 16, three elements, << {1: "SM4-GCM"} >>, {5: iv}, then the
+	// ciphertext and tag.
+	prefix := "d0834aa10167534d342d47434da1054c000102030405060708090a0b581c"
+	if h := hex.EncodeToString(msg); !strings.HasPrefix(h, prefix) || len(msg) != len(prefix)/2+len(plaintext)+16 {
+		t.Errorf("Encrypt0 = %s", h)
+	}
+	m, err := ParseEncrypt0(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.Decrypt(key, nil)
+	if err != nil || !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := m.Decrypt(key, []byte("aad")); err != ErrDecryption {
+		t.Errorf("other external AAD: %v", err)
+	}
+	otherKey := append([]byte{}, key...)
+	otherKey[0] ^= 1
+	if _, err := m.Decrypt(otherKey, nil); err != ErrDecryption {
+		t.Errorf("other key: %v", err)
+	}
+
+	msg, err = Encrypt0(rand.Reader, plaintext, key, EncryptOptions{Options: Options{KID: []byte("k"), ExternalAAD: []byte("aad")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, err := ParseEncrypt0(msg); err != nil || string(m.KID()) != "k" {
+		t.Fatalf("KID: %v", err)
+	} else if got, err := m.Decrypt(key, []byte("aad")); err != nil || !bytes.Equal(got, plaintext) {
+		t.Errorf("random IV: %q, %v", got, err)
+	}
+	if _, err := Encrypt0(rand.Reader, plaintext, key, EncryptOptions{IV: iv[:8]}); err == nil {
+		t.Error("short IV accepted")
+	}
+	if _, err := ParseEncrypt0(append([]byte{0xd2}, msg[1:]...)); err == nil {
+		t.Error("COSE_Sign1 tag accepted")
+	}
+}
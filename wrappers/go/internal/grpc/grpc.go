@@ -0,0 +1,217 @@
+// Package grpc serves and calls gRPC methods over cleartext HTTP/2 with
+// net/http alone. Messages are opaque byte strings, encoded by the caller;
+// compression, deadlines and metadata beyond the status are not
+// supported.
+package grpc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// MaxMessageSize bounds a single message; large payloads are streamed.
+const MaxMessageSize = 16 << 20
+
+// Code is a gRPC status code.
+type Code int
+
+// Status codes used by this package and its callers.
+const (
+	OK              Code = 0
+	Unknown         Code = 2
+	InvalidArgument Code = 3
+	NotFound        Code = 5
+	Unimplemented   Code = 12
+	Internal        Code = 13
+)
+
+// Error is a non-OK status.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("grpc: status %d: %s", e.Code, e.Message)
+}
+
+// Errorf returns an *Error with code and a formatted message.
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// Stream is the server side of a call. Unary methods Recv one message and
+// Send one.
+type Stream struct {
+	body io.Reader
+	w    http.ResponseWriter
+	rc   *http.ResponseController
+}
+
+// Recv returns the next request message, or io.EOF after the last.
+func (s *Stream) Recv() ([]byte, error) {
+	return readMessage(s.body)
+}
+
+// Send writes a response message and flushes it to the client.
+func (s *Stream) Send(msg []byte) error {
+	if err := writeMessage(s.w, msg); err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+// Handler runs one call. A returned *Error is sent as the status; other
+// errors become Unknown.
+type Handler func(s *Stream) error
+
+// Server dispatches the methods of one service by path
+// "/<Service>/<method>".
+type Server struct {
+	Service string
+	Methods map[string]Handler
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	if r.ProtoMajor != 2 {
+		http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+	rc := http.NewResponseController(w)
+	// Streaming methods read requests while writing responses.
+	rc.EnableFullDuplex()
+	w.WriteHeader(http.StatusOK)
+
+	err := Errorf(Unimplemented, "unknown method %s", r.URL.Path)
+	service, method, ok := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if h := srv.Methods[method]; ok && service == srv.Service && h != nil {
+		err = h(&Stream{body: r.Body, w: w, rc: rc})
+	}
+	st := &Error{Code: OK}
+	if err != nil && !errors.As(err, &st) {
+		st = &Error{Code: Unknown, Message: err.Error()}
+	}
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(st.Code)))
+	if st.Message != "" {
+		w.Header().Set("Grpc-Message", encodeMessage(st.Message))
+	}
+}
+
+// encodeMessage percent-encodes a status message as gRPC requires.
+func encodeMessage(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 0x20 && c < 0x7f && c != '%' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// readMessage reads one length-prefixed message. io.EOF means there is
+// none; a message cut short is io.ErrUnexpectedEOF.
+func readMessage(r io.Reader) ([]byte, error) {
+	var hdr [5]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if hdr[0] != 0 {
+		return nil, Errorf(Unimplemented, "compressed messages are not supported")
+	}
+	n := binary.BigEndian.Uint32(hdr[1:])
+	if n > MaxMessageSize {
+		return nil, Errorf(InvalidArgument, "message of %d bytes exceeds the limit of %d", n, MaxMessageSize)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+func writeMessage(w io.Writer, msg []byte) error {
+	hdr := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(hdr[1:], uint32(len(msg)))
+	_, err := w.Write(append(hdr, msg...))
+	return err
+}
+
+// Call invokes method on the service at baseURL (such as
+// "http://127.0.0.1:9000/pkg.Service") with the given request messages
+// and returns the response messages. A non-OK status is an *Error. The
+// client must speak HTTP/2 without TLS.
+func Call(client *http.Client, baseURL, method string, requests ...[]byte) ([][]byte, error) {
+	var body bytes.Buffer
+	for _, msg := range requests {
+		writeMessage(&body, msg)
+	}
+	req, err := http.NewRequest(http.MethodPost, baseURL+"/"+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("grpc: HTTP status %s", resp.Status)
+	}
+	var out [][]byte
+	for {
+		msg, err := readMessage(resp.Body)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, msg)
+	}
+	code, err := strconv.Atoi(resp.Trailer.Get("Grpc-Status"))
+	if err != nil {
+		return nil, errors.New("grpc: response without a status")
+	}
+	if code != int(OK) {
+		msg, _ := url.PathUnescape(resp.Trailer.Get("Grpc-Message"))
+		return out, &Error{Code: Code(code), Message: msg}
+	}
+	return out, nil
+}
+
+// NewH2CClient returns a client that speaks HTTP/2 without TLS, as Call
+// needs.
+func NewH2CClient() *http.Client {
+	var p http.Protocols
+	p.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &p}}
+}
+
+// NewH2CServer returns a server for handler that accepts HTTP/2 without
+// TLS, as gRPC clients connect, besides HTTP/1.1 for other handlers.
+func NewH2CServer(handler http.Handler) *http.Server {
+	var p http.Protocols
+	p.SetHTTP1(true)
+	p.SetUnencryptedHTTP2(true)
+	return &http.Server{Handler: handler, Protocols: &p}
+}
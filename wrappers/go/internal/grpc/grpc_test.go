@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestCall(t *testing.T) {
+	srv := &Server{Service: "test.Echo", Methods: map[string]Handler{
+		// Unary: echoes the request reversed.
+		"Reverse": func(s *Stream) error {
+			msg, err := s.Recv()
+			if err != nil {
+				return err
+			}
+			for i, j := 0, len(msg)-1; i < j; i, j = i+1, j-1 {
+				msg[i], msg[j] = msg[j], msg[i]
+			}
+			return s.Send(msg)
+		},
+		// Bidirectional: echoes every message, then fails.
+		"Echo": func(s *Stream) error {
+			for {
+				msg, err := s.Recv()
+				if err == io.EOF {
+					return Errorf(InvalidArgument, "done after 100%% – bye")
+				}
+				if err != nil {
+					return err
+				}
+				if err := s.Send(msg); err != nil {
+					return err
+				}
+			}
+		},
+	}}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := NewH2CServer(srv)
+	go hs.Serve(ln)
+	defer hs.Close()
+	client := NewH2CClient()
+	base := "http://" + ln.Addr().String() + "/test.Echo"
+
+	out, err := Call(client, base, "Reverse", []byte("abc"))
+	if err != nil || len(out) != 1 || string(out[0]) != "cba" {
+		t.Fatalf("Reverse: %q, %v", out, err)
+	}
+	out, err = Call(client, base, "Echo", []byte("1"), nil, bytes.Repeat([]byte{7}, 1<<20))
+	var st *Error
+	if !errors.As(err, &st) || st.Code != InvalidArgument || st.Message != "done after 100% – bye" {
+		t.Fatalf("Echo status: %v", err)
+	}
+	if len(out) != 3 || string(out[0]) != "1" || len(out[1]) != 0 || len(out[2]) != 1<<20 {
+		t.Fatalf("Echo returned %d messages", len(out))
+	}
+	if _, err := Call(client, base, "Missing"); !errors.As(err, &st) || st.Code != Unimplemented {
+		t.Errorf("unknown method: %v", err)
+	}
+	if _, err := Call(client, "http://"+ln.Addr().String()+"/other.Service", "Reverse", nil); !errors.As(err, &st) || st.Code != Unimplemented {
+		t.Errorf("unknown service: %v", err)
+	}
+}
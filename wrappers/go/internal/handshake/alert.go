@@ -0,0 +1,71 @@
+package handshake
+
+import "strconv"
+
+// Alert is the description of an alert record.
+type Alert uint8
+
+const (
+	AlertCloseNotify            Alert = 0
+	AlertUnexpectedMessage      Alert = 10
+	AlertBadRecordMAC           Alert = 20
+	AlertRecordOverflow         Alert = 22
+	AlertHandshakeFailure       Alert = 40
+	AlertBadCertificate         Alert = 42
+	AlertUnsupportedCertificate Alert = 43
+	AlertCertificateExpired     Alert = 45
+	AlertCertificateUnknown     Alert = 46
+	AlertIllegalParameter       Alert = 47
+	AlertUnknownCA              Alert = 48
+	AlertDecodeError            Alert = 50
+	AlertDecryptError           Alert = 51
+	AlertProtocolVersion        Alert = 70
+	AlertInternalError          Alert = 80
+	AlertUserCanceled           Alert = 90
+	AlertMissingExtension       Alert = 109
+	AlertCertificateRequired    Alert = 116
+)
+
+const (
+	alertLevelWarning = 1
+	alertLevelError   = 2
+)
+
+var alertText = map[Alert]string{
+	AlertCloseNotify:            "close notify",
+	AlertUnexpectedMessage:      "unexpected message",
+	AlertBadRecordMAC:           "bad record MAC",
+	AlertRecordOverflow:         "record overflow",
+	AlertHandshakeFailure:       "handshake failure",
+	AlertBadCertificate:         "bad certificate",
+	AlertUnsupportedCertificate: "unsupported certificate",
+	AlertCertificateExpired:     "certificate expired",
+	AlertCertificateUnknown:     "certificate unknown",
+	AlertIllegalParameter:       "illegal parameter",
+	AlertUnknownCA:              "unknown certificate authority",
+	AlertDecodeError:            "error decoding message",
+	AlertDecryptError:           "error decrypting message",
+	AlertProtocolVersion:        "protocol version not supported",
+	AlertInternalError:          "internal error",
+	AlertUserCanceled:           "user canceled",
+	AlertMissingExtension:       "missing extension",
+	AlertCertificateRequired:    "certificate required",
+}
+
+func (a Alert) String() string {
+	if s, ok := alertText[a]; ok {
+		return s
+	}
+	return "alert(" + strconv.Itoa(int(a)) + ")"
+}
+
+// AlertError is returned when the peer sends a fatal alert.
+type AlertError struct {
+	// Protocol is the Name of the Protocol the alert was received on.
+	Protocol string
+	Alert    Alert
+}
+
+func (e AlertError) Error() string {
+	return e.Protocol + ": peer sent alert: " + e.Alert.String()
+}
@@ -0,0 +1,99 @@
+package handshake
+
+import (
+	cryptorand "crypto/rand"
+	"encoding/asn1"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// Config holds the settings TLCP and TLS 1.3 endpoints have in common;
+// tlcp.Config and tls13.Config embed it.
+type Config struct {
+	// Rand is the source of randomness; nil means crypto/rand.
+	Rand io.Reader
+	// Time returns the current time; nil means time.Now.
+	Time func() time.Time
+
+	// RootCAs are the trust anchors for the peer's certificates, which
+	// InsecureSkipVerify accepts unchecked.
+	RootCAs            []*cert.Certificate
+	InsecureSkipVerify bool
+	// CertificateUID is the SM2 signer identity of the certificate
+	// signatures in the peer's chain, as in cert.VerifyOptions.
+	CertificateUID []byte
+
+	// CipherSuites lists the enabled suites, most preferred first; nil
+	// enables every suite the protocol implements.
+	CipherSuites []uint16
+
+	// ClientAuth makes a server request and require client certificates.
+	ClientAuth bool
+}
+
+// Certificate is a certificate chain, leaf first, and the private key of
+// its leaf.
+type Certificate struct {
+	Chain      [][]byte
+	PrivateKey *sm2.PrivateKey
+}
+
+// Random returns Rand or crypto/rand.
+func (c *Config) Random() io.Reader {
+	if c.Rand != nil {
+		return c.Rand
+	}
+	return cryptorand.Reader
+}
+
+// Now returns the time from Time or time.Now.
+func (c *Config) Now() time.Time {
+	if c.Time != nil {
+		return c.Time()
+	}
+	return time.Now()
+}
+
+// ParseCertificates parses the certificate list the peer sent, failing
+// the connection with bad_certificate if an entry does not parse.
+func (c *Conn) ParseCertificates(list [][]byte) ([]*cert.Certificate, error) {
+	certs := make([]*cert.Certificate, len(list))
+	for i, der := range list {
+		var err error
+		if certs[i], err = cert.Parse(der); err != nil {
+			return nil, c.Fail(AlertBadCertificate, "peer certificate %d: %v", i, err)
+		}
+	}
+	return certs, nil
+}
+
+// VerifyCertificate verifies the peer certificate leaf, named name in
+// errors, against the RootCAs of the Config for purpose. A failure sends
+// the alert that matches its reason.
+func (c *Conn) VerifyCertificate(name string, leaf *cert.Certificate, intermediates []*cert.Certificate, purpose asn1.ObjectIdentifier) error {
+	_, err := leaf.Verify(cert.VerifyOptions{
+		Roots:         c.config.RootCAs,
+		Intermediates: intermediates,
+		CurrentTime:   c.config.Now(),
+		ExtKeyUsage:   []asn1.ObjectIdentifier{purpose},
+		SignerUID:     c.config.CertificateUID,
+	})
+	if err == nil {
+		return nil
+	}
+	a := AlertBadCertificate
+	var verr *cert.VerifyError
+	if errors.As(err, &verr) {
+		switch verr.Reason {
+		case cert.UnknownIssuer:
+			a = AlertUnknownCA
+		case cert.Expired, cert.NotYetValid:
+			a = AlertCertificateExpired
+		}
+	}
+	return c.Fail(a, "%s: %v", name, err)
+}
@@ -0,0 +1,334 @@
+package handshake
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+)
+
+// RecordType is the content type of a record.
+type RecordType uint8
+
+const (
+	RecordTypeChangeCipherSpec RecordType = 20
+	RecordTypeAlert            RecordType = 21
+	RecordTypeHandshake        RecordType = 22
+	RecordTypeApplicationData  RecordType = 23
+)
+
+const (
+	// RecordHeaderLen is the length of the type, version and length
+	// header of a record.
+	RecordHeaderLen = 5
+	// MaxPlaintext bounds the content of a record.
+	MaxPlaintext = 16384
+	// MaxHandshake bounds the body of a handshake message.
+	MaxHandshake = 1 << 16
+)
+
+// ErrBadRecord is returned by Protection.Open for a record that does not
+// authenticate. Padding and MAC errors alike are ErrBadRecord.
+var ErrBadRecord = errors.New("bad record")
+
+// Protection is the record protection of one direction of a Conn.
+type Protection interface {
+	// Seal returns the record of type typ carrying payload, header
+	// included.
+	Seal(typ RecordType, payload []byte) ([]byte, error)
+	// Open removes the protection of a record with header hdr, returning
+	// its content type and content. A record that does not authenticate
+	// is ErrBadRecord and is answered with bad_record_mac; any other
+	// error reports a record the connection does not expect and is
+	// answered with unexpected_message.
+	Open(hdr, fragment []byte) (RecordType, []byte, error)
+}
+
+// Protocol is what a Conn needs to know about the protocol it carries.
+type Protocol struct {
+	// Name prefixes the errors of the Conn.
+	Name string
+	// Version reports whether a record version is acceptable.
+	Version func(v uint16) bool
+	// MaxCiphertext bounds the fragment of a received record.
+	MaxCiphertext int
+	// MiddleboxCCS drops the unprotected ChangeCipherSpec records a TLS
+	// 1.3 peer sends during the handshake (RFC 8446 appendix D.4).
+	MiddleboxCCS bool
+	// IgnoreAlertLevel makes user_canceled the only warning and every
+	// other alert but close_notify fatal, as in TLS 1.3; otherwise the
+	// level of an alert decides.
+	IgnoreAlertLevel bool
+
+	// Handshake runs the client or server handshake.
+	Handshake func() error
+	// PostHandshake processes the handshake messages received after the
+	// handshake, which are buffered for NextHandshake. Without it they
+	// are refused as renegotiation.
+	PostHandshake func() error
+}
+
+// Conn is the record layer of a TLCP or TLS 1.3 connection over an
+// underlying net.Conn and the application data on top of it. The
+// protocol packages embed it and supply the handshake through Protocol
+// and the record protection through In and Out. A Conn is not safe for
+// concurrent use.
+type Conn struct {
+	conn   net.Conn
+	proto  *Protocol
+	config *Config
+
+	handshakeDone bool
+	handshakeErr  error
+
+	// In and Out protect the records read and written.
+	In, Out Protection
+	// hand holds handshake bytes not yet consumed as messages; input
+	// holds application data not yet returned by Read.
+	hand, input []byte
+	readErr     error
+	closed      bool
+}
+
+// NewConn returns a Conn over conn carrying proto with the settings of
+// config, whose records start out protected by in and out.
+func NewConn(conn net.Conn, proto *Protocol, config *Config, in, out Protection) Conn {
+	return Conn{conn: conn, proto: proto, config: config, In: in, Out: out}
+}
+
+// NetConn returns the underlying connection.
+func (c *Conn) NetConn() net.Conn { return c.conn }
+
+// Handshake runs the handshake if it has not run yet.
+func (c *Conn) Handshake() error {
+	if c.handshakeDone || c.handshakeErr != nil {
+		return c.handshakeErr
+	}
+	c.handshakeErr = c.proto.Handshake()
+	c.handshakeDone = c.handshakeErr == nil
+	return c.handshakeErr
+}
+
+// Read reads application data, running the handshake first if needed. It
+// returns io.EOF once the peer has sent close_notify.
+func (c *Conn) Read(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	for len(c.input) == 0 {
+		if c.readErr != nil {
+			return 0, c.readErr
+		}
+		typ, data, err := c.ReadRecord()
+		if err != nil {
+			return 0, err
+		}
+		switch typ {
+		case RecordTypeApplicationData:
+			c.input = data
+		case RecordTypeHandshake:
+			if c.proto.PostHandshake == nil {
+				c.readErr = c.Fail(AlertUnexpectedMessage, "renegotiation is not supported")
+				break
+			}
+			c.hand = append(c.hand, data...)
+			if err := c.proto.PostHandshake(); err != nil {
+				c.readErr = err
+			}
+		default:
+			c.readErr = c.Fail(AlertUnexpectedMessage, "unexpected record type %d", typ)
+		}
+	}
+	n := copy(b, c.input)
+	c.input = c.input[n:]
+	return n, nil
+}
+
+// Write writes application data, running the handshake first if needed.
+func (c *Conn) Write(b []byte) (int, error) {
+	if err := c.Handshake(); err != nil {
+		return 0, err
+	}
+	if err := c.WriteRecord(RecordTypeApplicationData, b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// Close sends close_notify after a completed handshake and closes the
+// underlying connection.
+func (c *Conn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.handshakeDone {
+		c.SendAlert(AlertCloseNotify)
+	}
+	return c.conn.Close()
+}
+
+// CloseWrite sends close_notify without closing the connection, so the
+// peer's reply can still be read.
+func (c *Conn) CloseWrite() error {
+	if !c.handshakeDone {
+		return errors.New(c.proto.Name + ": CloseWrite before the handshake")
+	}
+	return c.SendAlert(AlertCloseNotify)
+}
+
+func (c *Conn) LocalAddr() net.Addr                { return c.conn.LocalAddr() }
+func (c *Conn) RemoteAddr() net.Addr               { return c.conn.RemoteAddr() }
+func (c *Conn) SetDeadline(t time.Time) error      { return c.conn.SetDeadline(t) }
+func (c *Conn) SetReadDeadline(t time.Time) error  { return c.conn.SetReadDeadline(t) }
+func (c *Conn) SetWriteDeadline(t time.Time) error { return c.conn.SetWriteDeadline(t) }
+
+// ReadRecord reads one record and removes its protection. Alerts are
+// handled here: close_notify becomes io.EOF and fatal alerts an
+// AlertError. An error sticks: every later read returns it.
+func (c *Conn) ReadRecord() (RecordType, []byte, error) {
+	if c.readErr != nil {
+		return 0, nil, c.readErr
+	}
+	typ, data, err := c.readRecordOrAlert()
+	if err != nil {
+		c.readErr = err
+	}
+	return typ, data, err
+}
+
+func (c *Conn) readRecordOrAlert() (RecordType, []byte, error) {
+	hdr := make([]byte, RecordHeaderLen)
+	if _, err := io.ReadFull(c.conn, hdr); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	typ := RecordType(hdr[0])
+	if v := binary.BigEndian.Uint16(hdr[1:]); !c.proto.Version(v) {
+		return 0, nil, c.Fail(AlertProtocolVersion, "record version %#04x", v)
+	}
+	n := int(binary.BigEndian.Uint16(hdr[3:]))
+	if n > c.proto.MaxCiphertext {
+		return 0, nil, c.Fail(AlertRecordOverflow, "%d-byte record", n)
+	}
+	fragment := make([]byte, n)
+	if _, err := io.ReadFull(c.conn, fragment); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, err
+	}
+	if typ == RecordTypeChangeCipherSpec && c.proto.MiddleboxCCS {
+		if c.handshakeDone || n != 1 || fragment[0] != 1 {
+			return 0, nil, c.Fail(AlertUnexpectedMessage, "unexpected ChangeCipherSpec")
+		}
+		return c.readRecordOrAlert()
+	}
+	typ, data, err := c.In.Open(hdr, fragment)
+	switch {
+	case err == ErrBadRecord:
+		return 0, nil, c.Fail(AlertBadRecordMAC, "record authentication failed")
+	case err != nil:
+		return 0, nil, c.Fail(AlertUnexpectedMessage, "%v", err)
+	}
+	if len(data) > MaxPlaintext {
+		return 0, nil, c.Fail(AlertRecordOverflow, "%d-byte plaintext", len(data))
+	}
+	if typ != RecordTypeAlert {
+		return typ, data, nil
+	}
+	if len(data) != 2 {
+		return 0, nil, c.Fail(AlertDecodeError, "malformed alert")
+	}
+	a := Alert(data[1])
+	warning := data[0] == alertLevelWarning
+	if c.proto.IgnoreAlertLevel {
+		warning = a == AlertUserCanceled
+	}
+	switch {
+	case a == AlertCloseNotify:
+		return 0, nil, io.EOF
+	case warning:
+		// Warnings are ignored.
+		return c.readRecordOrAlert()
+	default:
+		return 0, nil, AlertError{c.proto.Name, a}
+	}
+}
+
+// WriteRecord writes data as records of type typ, fragmenting it as
+// needed.
+func (c *Conn) WriteRecord(typ RecordType, data []byte) error {
+	for first := true; first || len(data) > 0; first = false {
+		n := min(len(data), MaxPlaintext)
+		rec, err := c.Out.Seal(typ, data[:n])
+		if err != nil {
+			return err
+		}
+		if _, err := c.conn.Write(rec); err != nil {
+			return err
+		}
+		data = data[n:]
+	}
+	return nil
+}
+
+// SendAlert sends a, as a warning if it is close_notify or user_canceled.
+func (c *Conn) SendAlert(a Alert) error {
+	level := byte(alertLevelError)
+	if a == AlertCloseNotify || a == AlertUserCanceled {
+		level = alertLevelWarning
+	}
+	return c.WriteRecord(RecordTypeAlert, []byte{level, byte(a)})
+}
+
+// Fail sends the fatal alert a and returns the error it reports.
+func (c *Conn) Fail(a Alert, format string, args ...interface{}) error {
+	c.SendAlert(a)
+	return fmt.Errorf(c.proto.Name+": "+format, args...)
+}
+
+// HandshakeBuffered reports whether handshake bytes have been received
+// that were not consumed as messages yet. Handshake messages must not
+// span a change of keys.
+func (c *Conn) HandshakeBuffered() bool { return len(c.hand) != 0 }
+
+// NextHandshake removes and returns the first complete handshake message
+// buffered, header included, or nil if there is none yet.
+func (c *Conn) NextHandshake() ([]byte, error) {
+	if len(c.hand) < 4 {
+		return nil, nil
+	}
+	n := int(c.hand[1])<<16 | int(c.hand[2])<<8 | int(c.hand[3])
+	if n > MaxHandshake {
+		return nil, c.Fail(AlertIllegalParameter, "%d-byte handshake message", n)
+	}
+	if len(c.hand) < 4+n {
+		return nil, nil
+	}
+	msg := c.hand[: 4+n : 4+n]
+	c.hand = c.hand[4+n:]
+	return msg, nil
+}
+
+// ReadHandshake reads records until a complete handshake message is
+// buffered and returns it, header included.
+func (c *Conn) ReadHandshake() ([]byte, error) {
+	for {
+		if msg, err := c.NextHandshake(); msg != nil || err != nil {
+			return msg, err
+		}
+		typ, data, err := c.ReadRecord()
+		if err != nil {
+			return nil, err
+		}
+		if typ != RecordTypeHandshake {
+			return nil, c.Fail(AlertUnexpectedMessage, "record type %d during the handshake", typ)
+		}
+		c.hand = append(c.hand, data...)
+	}
+}
@@ -0,0 +1,62 @@
+package handshake
+
+import (
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// plaintext is a Protection that leaves records unprotected.
+type plaintext struct{}
+
+func (plaintext) Seal(typ RecordType, payload []byte) ([]byte, error) {
+	b := Builder{byte(typ), 3, 3}
+	b.Vec16(payload)
+	return b, nil
+}
+
+func (plaintext) Open(hdr, fragment []byte) (RecordType, []byte, error) {
+	return RecordType(hdr[0]), fragment, nil
+}
+
+// TestAlerts checks how a Conn reads alerts: a warning is skipped unless
+// the protocol ignores alert levels, and close_notify ends the stream.
+func TestAlerts(t *testing.T) {
+	for _, c := range []struct {
+		ignoreLevel bool
+		level       byte
+		alert       Alert
+		want        error
+	}{
+		{false, alertLevelWarning, AlertCloseNotify, io.EOF},
+		{false, alertLevelWarning, AlertUnexpectedMessage, nil},
+		{true, alertLevelWarning, AlertUnexpectedMessage, AlertError{"test", AlertUnexpectedMessage}},
+		{true, alertLevelError, AlertUserCanceled, nil},
+		{false, alertLevelError, AlertDecodeError, AlertError{"test", AlertDecodeError}},
+	} {
+		client, server := net.Pipe()
+		proto := &Protocol{
+			Name:             "test",
+			Version:          func(v uint16) bool { return v == 0x0303 },
+			MaxCiphertext:    MaxPlaintext,
+			IgnoreAlertLevel: c.ignoreLevel,
+		}
+		conn := NewConn(client, proto, &Config{}, plaintext{}, plaintext{})
+		go func() {
+			peer := NewConn(server, proto, &Config{}, plaintext{}, plaintext{})
+			peer.WriteRecord(RecordTypeAlert, []byte{c.level, byte(c.alert)})
+			peer.WriteRecord(RecordTypeApplicationData, []byte("data"))
+			server.Close()
+		}()
+		typ, data, err := conn.ReadRecord()
+		if c.want == nil {
+			if err != nil || typ != RecordTypeApplicationData || string(data) != "data" {
+				t.Errorf("%v at level %d: %v, %q, %v", c.alert, c.level, typ, data, err)
+			}
+		} else if !errors.Is(err, c.want) {
+			t.Errorf("%v at level %d: %v, want %v", c.alert, c.level, err, c.want)
+		}
+		client.Close()
+	}
+}
@@ -0,0 +1,136 @@
+// Package handshake holds what TLCP (GB/T 38636) and TLS 1.3 (RFC 8446)
+// share: the codec of their handshake messages, big-endian integers and
+// length-prefixed vectors in a four-byte type and length header; the
+// record layer, alerts and application data of a Conn; the Transcript of
+// a handshake; and the common Config. The tlcp and tls13 packages add
+// their handshakes, key schedules and record protection.
+package handshake
+
+// Builder appends the big-endian integers and length-prefixed vectors of
+// handshake messages.
+type Builder []byte
+
+func (b *Builder) U8(v uint8)   { *b = append(*b, v) }
+func (b *Builder) U16(v uint16) { *b = append(*b, byte(v>>8), byte(v)) }
+func (b *Builder) U24(v int)    { *b = append(*b, byte(v>>16), byte(v>>8), byte(v)) }
+
+func (b *Builder) Vec8(v []byte) {
+	b.U8(uint8(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *Builder) Vec16(v []byte) {
+	b.U16(uint16(len(v)))
+	*b = append(*b, v...)
+}
+
+func (b *Builder) Vec24(v []byte) {
+	b.U24(len(v))
+	*b = append(*b, v...)
+}
+
+// Extension appends an extension of type typ with body data.
+func (b *Builder) Extension(typ uint16, data []byte) {
+	b.U16(typ)
+	b.Vec16(data)
+}
+
+// U16s returns the concatenated big-endian values of vs.
+func U16s(vs []uint16) []byte {
+	var b Builder
+	for _, v := range vs {
+		b.U16(v)
+	}
+	return b
+}
+
+// Message frames body as a handshake message of type typ.
+func Message(typ uint8, body []byte) []byte {
+	b := Builder{typ}
+	b.Vec24(body)
+	return b
+}
+
+// Parser reads what Builder writes. A read past the end marks the parser
+// bad and returns zero values, so a message is checked once at the end.
+type Parser struct {
+	b   []byte
+	bad bool
+}
+
+// NewParser returns a Parser reading b.
+func NewParser(b []byte) *Parser { return &Parser{b: b} }
+
+// Bytes reads n bytes.
+func (p *Parser) Bytes(n int) []byte {
+	if p.bad || n > len(p.b) {
+		p.bad = true
+		return nil
+	}
+	v := p.b[:n:n]
+	p.b = p.b[n:]
+	return v
+}
+
+func (p *Parser) U8() uint8 {
+	if v := p.Bytes(1); v != nil {
+		return v[0]
+	}
+	return 0
+}
+
+func (p *Parser) U16() uint16 {
+	if v := p.Bytes(2); v != nil {
+		return uint16(v[0])<<8 | uint16(v[1])
+	}
+	return 0
+}
+
+func (p *Parser) U24() int {
+	if v := p.Bytes(3); v != nil {
+		return int(v[0])<<16 | int(v[1])<<8 | int(v[2])
+	}
+	return 0
+}
+
+func (p *Parser) Vec8() []byte  { return p.Bytes(int(p.U8())) }
+func (p *Parser) Vec16() []byte { return p.Bytes(int(p.U16())) }
+func (p *Parser) Vec24() []byte { return p.Bytes(p.U24()) }
+
+// Empty reports whether all input has been read.
+func (p *Parser) Empty() bool { return len(p.b) == 0 }
+
+// More reports whether input remains and every read so far succeeded,
+// the condition for reading the next element of a list.
+func (p *Parser) More() bool { return len(p.b) > 0 && !p.bad }
+
+// OK reports whether every read so far succeeded.
+func (p *Parser) OK() bool { return !p.bad }
+
+// Done reports whether the message parsed and was fully consumed.
+func (p *Parser) Done() bool { return !p.bad && len(p.b) == 0 }
+
+// U16List parses a whole vector body as big-endian 16-bit values.
+func U16List(data []byte) ([]uint16, bool) {
+	p := NewParser(data)
+	var vs []uint16
+	for p.More() {
+		vs = append(vs, p.U16())
+	}
+	return vs, p.OK()
+}
+
+// Extensions parses an extensions block into a map from type to body. A
+// repeated extension fails the parse.
+func Extensions(data []byte) (map[uint16][]byte, bool) {
+	p := NewParser(data)
+	exts := make(map[uint16][]byte)
+	for p.More() {
+		typ, body := p.U16(), p.Vec16()
+		if _, dup := exts[typ]; dup {
+			return nil, false
+		}
+		exts[typ] = body
+	}
+	return exts, p.OK()
+}
@@ -0,0 +1,57 @@
+package handshake
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	var b Builder
+	b.U8(1)
+	b.U16(0x0203)
+	b.U24(0x040506)
+	b.Vec8([]byte("a"))
+	b.Vec16([]byte("bc"))
+	b.Vec24([]byte("def"))
+	b.Extension(0x0a0b, U16s([]uint16{7, 8}))
+
+	p := NewParser(b)
+	if p.U8() != 1 || p.U16() != 0x0203 || p.U24() != 0x040506 {
+		t.Fatal("integers do not round-trip")
+	}
+	if string(p.Vec8()) != "a" || string(p.Vec16()) != "bc" || string(p.Vec24()) != "def" {
+		t.Fatal("vectors do not round-trip")
+	}
+	exts, ok := Extensions(p.Bytes(len(b) - 18))
+	if !ok || !p.Done() {
+		t.Fatalf("extensions %v, %v, done %v", exts, ok, p.Done())
+	}
+	if vs, ok := U16List(exts[0x0a0b]); !ok || !slices.Equal(vs, []uint16{7, 8}) {
+		t.Errorf("U16List = %v, %v", vs, ok)
+	}
+
+	msg := Message(20, []byte{9, 9})
+	if !bytes.Equal(msg, []byte{20, 0, 0, 2, 9, 9}) {
+		t.Errorf("Message = %x", msg)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	p := NewParser([]byte{0, 5, 1})
+	if v := p.Vec16(); v != nil || p.OK() || p.Done() || p.More() {
+		t.Errorf("overlong vector: %x, ok %v", v, p.OK())
+	}
+	if p.U8() != 0 {
+		t.Error("read after a failed read")
+	}
+	if _, ok := U16List([]byte{1, 2, 3}); ok {
+		t.Error("odd-length list accepted")
+	}
+	var b Builder
+	b.Extension(1, nil)
+	b.Extension(1, nil)
+	if _, ok := Extensions(b); ok {
+		t.Error("repeated extension accepted")
+	}
+}
@@ -0,0 +1,63 @@
+// Package handshaketest runs TLCP and TLS 1.3 connections over loopback
+// for the tests of the protocol packages.
+package handshaketest
+
+import (
+	"io"
+	"net"
+	"testing"
+)
+
+// Conn is a connection whose completed handshake is described by S.
+type Conn[S any] interface {
+	net.Conn
+	ConnectionState() S
+}
+
+// Echo accepts one connection on a loopback listener, wraps it with
+// server and echoes the first len(msg) bytes back, while a connection
+// wrapped with client sends msg and reads the reply. It returns the
+// client's state, reply and error, then the server's state and error.
+func Echo[S any, C Conn[S]](t *testing.T, client, server func(net.Conn) C, msg []byte) (S, []byte, error, S, error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	type result struct {
+		state S
+		err   error
+	}
+	done := make(chan result, 1)
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			done <- result{err: err}
+			return
+		}
+		c := server(nc)
+		defer c.Close()
+		buf := make([]byte, len(msg))
+		if _, err := io.ReadFull(c, buf); err != nil {
+			done <- result{c.ConnectionState(), err}
+			return
+		}
+		_, err = c.Write(buf)
+		done <- result{c.ConnectionState(), err}
+	}()
+
+	nc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := client(nc)
+	defer c.Close()
+	var reply []byte
+	if _, err = c.Write(msg); err == nil {
+		reply = make([]byte, len(msg))
+		_, err = io.ReadFull(c, reply)
+	}
+	res := <-done
+	return c.ConnectionState(), reply, err, res.state, res.err
+}
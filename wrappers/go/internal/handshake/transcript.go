@@ -0,0 +1,42 @@
+package handshake
+
+import "bytes"
+
+// Transcript accumulates the handshake messages sent and received on a
+// Conn, which the Finished messages and signatures cover.
+type Transcript struct {
+	c   *Conn
+	buf bytes.Buffer
+}
+
+// NewTranscript returns an empty transcript of the messages on c.
+func NewTranscript(c *Conn) *Transcript { return &Transcript{c: c} }
+
+// ReadMessage returns the next handshake message, which must be of type
+// want, and its body. The message is added to the transcript.
+func (t *Transcript) ReadMessage(want uint8) ([]byte, error) {
+	msg, err := t.c.ReadHandshake()
+	if err != nil {
+		return nil, err
+	}
+	if msg[0] != want {
+		return nil, t.c.Fail(AlertUnexpectedMessage, "handshake message type %d, want %d", msg[0], want)
+	}
+	t.buf.Write(msg)
+	return msg[4:], nil
+}
+
+// WriteMessage sends a handshake message and adds it to the transcript.
+func (t *Transcript) WriteMessage(msg []byte) error {
+	t.buf.Write(msg)
+	return t.c.WriteRecord(RecordTypeHandshake, msg)
+}
+
+// Add adds a message read with Conn.ReadHandshake.
+func (t *Transcript) Add(msg []byte) { t.buf.Write(msg) }
+
+// Bytes returns the messages so far.
+func (t *Transcript) Bytes() []byte { return t.buf.Bytes() }
+
+// Reset empties the transcript.
+func (t *Transcript) Reset() { t.buf.Reset() }
@@ -0,0 +1,155 @@
+package jose
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// The JWE algorithms of SM-JOSE: the content encryption key is encrypted
+// to the recipient with SM2 (GB/T 32918.4, C1 || C3 || C2 with C1
+// uncompressed) and the content with SM4-GCM under a 96-bit IV and a
+// 128-bit tag, as A128GCM does with AES (RFC 7518 section 5.3).
+const (
+	AlgSM2    = "SM2"
+	EncSM4GCM = "SM4-GCM"
+)
+
+// ErrDecryption is returned for JWEs whose key or content does not
+// decrypt. The two cases are not told apart.
+var ErrDecryption = errors.New("jose: decryption failed")
+
+const (
+	gcmIVSize  = 12
+	gcmTagSize = 16
+)
+
+// Encrypt returns the compact JWE of plaintext for pub, with alg AlgSM2
+// and enc EncSM4GCM. header holds any parameters besides those two; "alg"
+// and "enc" in header must match them.
+func Encrypt(rand io.Reader, header Header, plaintext []byte, pub *sm2.PublicKey) (string, error) {
+	h := Header{}
+	for k, v := range header {
+		h[k] = v
+	}
+	for k, want := range map[string]string{"alg": AlgSM2, "enc": EncSM4GCM} {
+		if v, ok := h[k]; ok && v != want {
+			return "", fmt.Errorf("jose: header %s %v does not match %s", k, v, want)
+		}
+		h[k] = want
+	}
+	if _, ok := h["zip"]; ok {
+		return "", errors.New("jose: compression is not supported")
+	}
+	hj, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	protected := b64.EncodeToString(hj)
+
+	cek := make([]byte, sm4.KeySize)
+	iv := make([]byte, gcmIVSize)
+	if _, err := io.ReadFull(rand, cek); err != nil {
+		return "", err
+	}
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return "", err
+	}
+	encryptedKey, err := sm2.Encrypt(rand, pub, cek)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newGCM(cek)
+	if err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(plaintext)], sealed[len(plaintext):]
+	return strings.Join([]string{
+		protected,
+		b64.EncodeToString(encryptedKey),
+		b64.EncodeToString(iv),
+		b64.EncodeToString(ciphertext),
+		b64.EncodeToString(tag),
+	}, "."), nil
+}
+
+// JWE is a parsed compact JWE.
+type JWE struct {
+	Header Header
+
+	protected    string
+	encryptedKey []byte
+	iv           []byte
+	ciphertext   []byte
+	tag          []byte
+}
+
+// ParseJWE parses a compact JWE.
+func ParseJWE(token string) (*JWE, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("jose: token has %d parts, want 5", len(parts))
+	}
+	hj, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("jose: header: %v", err)
+	}
+	j := &JWE{protected: parts[0]}
+	d := json.NewDecoder(bytes.NewReader(hj))
+	d.UseNumber()
+	if err := d.Decode(&j.Header); err != nil || j.Header == nil || d.More() {
+		return nil, errors.New("jose: header is not a JSON object")
+	}
+	for i, f := range []*[]byte{&j.encryptedKey, &j.iv, &j.ciphertext, &j.tag} {
+		if *f, err = b64.DecodeString(parts[i+1]); err != nil {
+			return nil, fmt.Errorf("jose: part %d: %v", i+2, err)
+		}
+	}
+	return j, nil
+}
+
+// Decrypt returns the plaintext of j, decrypting its key with priv. A JWE
+// with another alg or enc gives ErrAlgorithm, one naming critical
+// extensions or compression ErrCritical, and one that does not decrypt
+// ErrDecryption.
+func (j *JWE) Decrypt(priv *sm2.PrivateKey) ([]byte, error) {
+	if j.Header["alg"] != AlgSM2 || j.Header["enc"] != EncSM4GCM {
+		return nil, ErrAlgorithm
+	}
+	if _, ok := j.Header["crit"]; ok {
+		return nil, ErrCritical
+	}
+	if _, ok := j.Header["zip"]; ok {
+		return nil, ErrCritical
+	}
+	cek, err := sm2.Decrypt(priv, j.encryptedKey)
+	if err != nil || len(cek) != sm4.KeySize || len(j.iv) != gcmIVSize || len(j.tag) != gcmTagSize {
+		return nil, ErrDecryption
+	}
+	aead, err := newGCM(cek)
+	if err != nil {
+		return nil, err
+	}
+	sealed := append(append([]byte{}, j.ciphertext...), j.tag...)
+	plaintext, err := aead.Open(nil, j.iv, sealed, []byte(j.protected))
+	if err != nil {
+		return nil, ErrDecryption
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
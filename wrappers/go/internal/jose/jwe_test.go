@@ -0,0 +1,79 @@
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func TestJWE(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	other, _ := sm2.GenerateKey(rand.Reader)
+	plaintext := []byte("card number 6222 0000 0000 0000")
+
+	token, err := Encrypt(rand.Reader, Header{"kid": "r1"}, plaintext, &priv.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// {"alg":"SM2","enc":"SM4-GCM","kid":"r1"}
+	if !strings.HasPrefix(token, "eyJhbGciOiJTTTIiLCJlbmMiOiJTTTQtR0NNIiwia2lkIjoicjEifQ.") {
+		t.Errorf("header of %s", token)
+	}
+	j, err := ParseJWE(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(j.encryptedKey) != 65+32+16 || len(j.iv) != 12 || len(j.ciphertext) != len(plaintext) || len(j.tag) != 16 {
+		t.Errorf("part sizes %d %d %d %d", len(j.encryptedKey), len(j.iv), len(j.ciphertext), len(j.tag))
+	}
+	got, err := j.Decrypt(priv)
+	if err != nil || string(got) != string(plaintext) {
+		t.Fatalf("Decrypt = %q, %v", got, err)
+	}
+	if _, err := j.Decrypt(other); err != ErrDecryption {
+		t.Errorf("other key: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	tamper := func(i int, s string) string {
+		p := append([]string{}, parts...)
+		p[i] = s
+		return strings.Join(p, ".")
+	}
+	enc := base64.RawURLEncoding.EncodeToString
+	for _, tc := range []struct {
+		token string
+		want  error
+	}{
+		// The protected header is the additional data of SM4-GCM.
+		{tamper(0, enc([]byte(`{"alg":"SM2","enc":"SM4-GCM","kid":"r2"}`))), ErrDecryption},
+		{tamper(0, enc([]byte(`{"alg":"RSA-OAEP","enc":"SM4-GCM"}`))), ErrAlgorithm},
+		{tamper(0, enc([]byte(`{"alg":"SM2","enc":"A128GCM"}`))), ErrAlgorithm},
+		{tamper(0, enc([]byte(`{"alg":"SM2","enc":"SM4-GCM","zip":"DEF"}`))), ErrCritical},
+		{tamper(0, enc([]byte(`{"alg":"SM2","enc":"SM4-GCM","crit":["x"],"x":1}`))), ErrCritical},
+		{tamper(3, enc(append([]byte{'C'}, plaintext[1:]...))), ErrDecryption},
+		{tamper(4, enc(make([]byte, 16))), ErrDecryption},
+		{tamper(4, enc(make([]byte, 12))), ErrDecryption},
+		{tamper(1, enc(make([]byte, 113))), ErrDecryption},
+	} {
+		j, err := ParseJWE(tc.token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := j.Decrypt(priv); err != tc.want {
+			t.Errorf("%s: %v, want %v", tc.token[:20], err, tc.want)
+		}
+	}
+
+	if _, err := Encrypt(rand.Reader, Header{"enc": "A256GCM"}, plaintext, &priv.PublicKey); err == nil {
+		t.Error("encrypted with a conflicting enc")
+	}
+	for _, bad := range []string{"a.b.c.d", strings.Join(parts[:4], "."), tamper(2, "!!")} {
+		if _, err := ParseJWE(bad); err == nil {
+			t.Errorf("ParseJWE(%q) succeeded", bad)
+		}
+	}
+}
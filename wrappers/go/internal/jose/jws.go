@@ -0,0 +1,133 @@
+// Package jose implements the compact serializations of JSON Web Signature
+// (RFC 7515) with SM2 signatures over SM3 and of JSON Web Encryption (RFC
+// 7516) with SM2 and SM4-GCM, and JSON Web Tokens (RFC 7519) on top of
+// the signatures.
+//
+// No JOSE algorithm is registered for SM2, so the package uses the name
+// "SM2-SM3" that SM-JOSE deployments have settled on: the signature is
+// SM2 (GB/T 32918.2) over the JWS signing input under a signer identity,
+// written as the 64-byte r || s like the ES* algorithms of RFC 7518.
+package jose
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// AlgSM2SM3 is the "alg" of SM2-with-SM3 signatures.
+const AlgSM2SM3 = "SM2-SM3"
+
+// Header is a JOSE header.
+type Header map[string]interface{}
+
+// Errors of JWS verification.
+var (
+	ErrAlgorithm = errors.New("jose: unsupported algorithm")
+	ErrCritical  = errors.New("jose: unsupported critical header parameter")
+	ErrSignature = errors.New("jose: signature does not verify")
+)
+
+var b64 = base64.RawURLEncoding.Strict()
+
+// Sign returns the compact JWS of payload signed by priv under the signer
+// identity uid. header holds any parameters besides "alg", which Sign
+// sets; an "alg" in header must be AlgSM2SM3. With detached set the
+// payload is left out of the token (RFC 7515 appendix F) and must be
+// given to ParseJWS.
+func Sign(rand io.Reader, header Header, payload []byte, priv *sm2.PrivateKey, uid []byte, detached bool) (string, error) {
+	h := Header{}
+	for k, v := range header {
+		h[k] = v
+	}
+	if alg, ok := h["alg"]; ok && alg != AlgSM2SM3 {
+		return "", fmt.Errorf("jose: header alg %v does not match %s", alg, AlgSM2SM3)
+	}
+	h["alg"] = AlgSM2SM3
+	hj, err := json.Marshal(h)
+	if err != nil {
+		return "", err
+	}
+	input := b64.EncodeToString(hj) + "." + b64.EncodeToString(payload)
+	r, s, err := sm2.Sign(rand, priv, []byte(input), uid)
+	if err != nil {
+		return "", err
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	if detached {
+		input = input[:strings.IndexByte(input, '.')+1]
+	}
+	return input + "." + b64.EncodeToString(sig), nil
+}
+
+// JWS is a parsed compact JWS.
+type JWS struct {
+	Header  Header
+	Payload []byte
+
+	signingInput string
+	signature    []byte
+}
+
+// ParseJWS parses a compact JWS. detached is the payload of a token that
+// leaves it out, and must be nil for one that carries it.
+func ParseJWS(token string, detached []byte) (*JWS, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("jose: token has %d parts, want 3", len(parts))
+	}
+	hj, err := b64.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("jose: header: %v", err)
+	}
+	j := &JWS{}
+	d := json.NewDecoder(bytes.NewReader(hj))
+	d.UseNumber()
+	if err := d.Decode(&j.Header); err != nil || j.Header == nil || d.More() {
+		return nil, errors.New("jose: header is not a JSON object")
+	}
+	if detached != nil {
+		if parts[1] != "" {
+			return nil, errors.New("jose: token carries a payload but a detached one was given")
+		}
+		j.Payload = detached
+		parts[1] = b64.EncodeToString(detached)
+	} else if j.Payload, err = b64.DecodeString(parts[1]); err != nil {
+		return nil, fmt.Errorf("jose: payload: %v", err)
+	}
+	if j.signature, err = b64.DecodeString(parts[2]); err != nil {
+		return nil, fmt.Errorf("jose: signature: %v", err)
+	}
+	j.signingInput = parts[0] + "." + parts[1]
+	return j, nil
+}
+
+// Verify checks the signature of j under pub and the signer identity uid.
+// Tokens whose alg is not AlgSM2SM3 give ErrAlgorithm, and tokens naming
+// critical extensions give ErrCritical, since none are understood.
+func (j *JWS) Verify(pub *sm2.PublicKey, uid []byte) error {
+	if j.Header["alg"] != AlgSM2SM3 {
+		return ErrAlgorithm
+	}
+	if _, ok := j.Header["crit"]; ok {
+		return ErrCritical
+	}
+	if len(j.signature) != 64 {
+		return ErrSignature
+	}
+	r := new(big.Int).SetBytes(j.signature[:32])
+	s := new(big.Int).SetBytes(j.signature[32:])
+	if !sm2.Verify(pub, []byte(j.signingInput), uid, r, s) {
+		return ErrSignature
+	}
+	return nil
+}
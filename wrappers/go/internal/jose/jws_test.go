@@ -0,0 +1,83 @@
+package jose
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func TestJWS(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	other, _ := sm2.GenerateKey(rand.Reader)
+	uid := []byte(sm2.DefaultUID)
+	payload := []byte(`{"sub":"alice"}`)
+
+	token, err := Sign(rand.Reader, Header{"typ": "JWT", "kid": "k1"}, payload, priv, uid, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "eyJhbGciOiJTTTItU00zIiwia2lkIjoiazEiLCJ0eXAiOiJKV1QifQ.") {
+		t.Errorf("header of %s", token)
+	}
+	j, err := ParseJWS(token, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(j.Payload) != string(payload) || j.Header["kid"] != "k1" {
+		t.Errorf("ParseJWS = %+v", j)
+	}
+	if err := j.Verify(&priv.PublicKey, uid); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.Verify(&other.PublicKey, uid); err != ErrSignature {
+		t.Errorf("other key: %v", err)
+	}
+	if err := j.Verify(&priv.PublicKey, []byte("other")); err != ErrSignature {
+		t.Errorf("other identity: %v", err)
+	}
+
+	parts := strings.Split(token, ".")
+	forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory"}`)) + "." + parts[2]
+	if j, err := ParseJWS(forged, nil); err != nil || j.Verify(&priv.PublicKey, uid) != ErrSignature {
+		t.Errorf("forged payload: %v", err)
+	}
+	for _, h := range []string{`{"alg":"none"}`, `{"alg":"ES256"}`} {
+		tok := base64.RawURLEncoding.EncodeToString([]byte(h)) + "." + parts[1] + "." + parts[2]
+		if j, err := ParseJWS(tok, nil); err != nil || j.Verify(&priv.PublicKey, uid) != ErrAlgorithm {
+			t.Errorf("%s: %v", h, err)
+		}
+	}
+	crit, _ := Sign(rand.Reader, Header{"crit": []string{"exp"}, "exp": 1}, payload, priv, uid, false)
+	if j, err := ParseJWS(crit, nil); err != nil || j.Verify(&priv.PublicKey, uid) != ErrCritical {
+		t.Errorf("crit: %v", err)
+	}
+	if _, err := Sign(rand.Reader, Header{"alg": "ES256"}, payload, priv, uid, false); err == nil {
+		t.Error("signed with a conflicting alg")
+	}
+
+	detached, err := Sign(rand.Reader, nil, payload, priv, uid, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(detached, ".") != 2 || strings.Split(detached, ".")[1] != "" {
+		t.Fatalf("detached token %s", detached)
+	}
+	if j, err := ParseJWS(detached, payload); err != nil || j.Verify(&priv.PublicKey, uid) != nil {
+		t.Errorf("detached: %v", err)
+	}
+	if j, err := ParseJWS(detached, []byte("other")); err != nil || j.Verify(&priv.PublicKey, uid) != ErrSignature {
+		t.Errorf("detached, other payload: %v", err)
+	}
+	if _, err := ParseJWS(token, payload); err == nil {
+		t.Error("detached payload accepted for an attached token")
+	}
+
+	for _, bad := range []string{"a.b", "a.b.c.d", "!!.e30.AA", "W10.e30.AA", parts[0] + "." + parts[1] + "=.AA"} {
+		if _, err := ParseJWS(bad, nil); err == nil {
+			t.Errorf("ParseJWS(%q) succeeded", bad)
+		}
+	}
+}
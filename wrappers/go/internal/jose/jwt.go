@@ -0,0 +1,167 @@
+package jose
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// Claims is the claims set of a JSON Web Token (RFC 7519).
+type Claims map[string]interface{}
+
+// Errors of claims validation.
+var (
+	ErrClaims      = errors.New("jose: malformed claims")
+	ErrExpired     = errors.New("jose: token has expired")
+	ErrNotYetValid = errors.New("jose: token is not yet valid")
+	ErrIssuer      = errors.New("jose: unexpected issuer")
+	ErrAudience    = errors.New("jose: token is not for this audience")
+)
+
+// SignJWT returns claims as a JWT signed like Sign. The header gets "typ"
+// JWT unless header sets it.
+func SignJWT(rand io.Reader, header Header, claims Claims, priv *sm2.PrivateKey, uid []byte) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	h := Header{"typ": "JWT"}
+	for k, v := range header {
+		h[k] = v
+	}
+	return Sign(rand, h, payload, priv, uid, false)
+}
+
+// ParseJWT parses a JWT and its claims set. The signature is checked by
+// the Verify method of the returned JWS.
+func ParseJWT(token string) (*JWS, Claims, error) {
+	j, err := ParseJWS(token, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	var c Claims
+	d := json.NewDecoder(bytes.NewReader(j.Payload))
+	d.UseNumber()
+	if err := d.Decode(&c); err != nil || c == nil || d.More() {
+		return nil, nil, ErrClaims
+	}
+	return j, c, nil
+}
+
+// Validation is what Validate checks the registered claims against.
+type Validation struct {
+	// Time is the current time, and Leeway the clock skew allowed for
+	// "exp" and "nbf".
+	Time   time.Time
+	Leeway time.Duration
+	// Issuer and Audience, when not empty, must match "iss" and one of
+	// the values of "aud".
+	Issuer   string
+	Audience string
+	// RequireExp rejects tokens without "exp".
+	RequireExp bool
+}
+
+// Validate checks the "exp", "nbf", "iss" and "aud" claims. Claims of the
+// wrong type give ErrClaims.
+func (c Claims) Validate(v Validation) error {
+	exp, hasExp, err := c.date("exp")
+	if err != nil {
+		return err
+	}
+	nbf, hasNbf, err := c.date("nbf")
+	if err != nil {
+		return err
+	}
+	if _, _, err := c.date("iat"); err != nil {
+		return err
+	}
+	if hasExp && !v.Time.Before(exp.Add(v.Leeway)) || !hasExp && v.RequireExp {
+		return ErrExpired
+	}
+	if hasNbf && v.Time.Before(nbf.Add(-v.Leeway)) {
+		return ErrNotYetValid
+	}
+	iss, hasIss := c["iss"]
+	if hasIss {
+		if _, ok := iss.(string); !ok {
+			return ErrClaims
+		}
+	}
+	if v.Issuer != "" && iss != v.Issuer {
+		return ErrIssuer
+	}
+	aud, err := c.audience()
+	if err != nil {
+		return err
+	}
+	if v.Audience != "" {
+		for _, a := range aud {
+			if a == v.Audience {
+				return nil
+			}
+		}
+		return ErrAudience
+	}
+	return nil
+}
+
+// date reads a NumericDate claim, which may have a fractional part.
+func (c Claims) date(name string) (time.Time, bool, error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	var f float64
+	switch n := v.(type) {
+	case json.Number:
+		var err error
+		if f, err = n.Float64(); err != nil {
+			return time.Time{}, false, ErrClaims
+		}
+	case float64:
+		f = n
+	case int:
+		f = float64(n)
+	case int64:
+		f = float64(n)
+	default:
+		return time.Time{}, false, ErrClaims
+	}
+	if math.IsNaN(f) || math.Abs(f) > 1<<53 {
+		return time.Time{}, false, ErrClaims
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)), true, nil
+}
+
+// audience reads "aud", a string or an array of strings.
+func (c Claims) audience() ([]string, error) {
+	switch a := c["aud"].(type) {
+	case nil:
+		if _, ok := c["aud"]; ok {
+			return nil, ErrClaims
+		}
+		return nil, nil
+	case string:
+		return []string{a}, nil
+	case []interface{}:
+		out := make([]string, len(a))
+		for i, v := range a {
+			s, ok := v.(string)
+			if !ok {
+				return nil, ErrClaims
+			}
+			out[i] = s
+		}
+		return out, nil
+	case []string:
+		return a, nil
+	}
+	return nil, ErrClaims
+}
@@ -0,0 +1,85 @@
+package jose
+
+import (
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func TestJWT(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	uid := []byte(sm2.DefaultUID)
+	now := time.Unix(1780000000, 0)
+	token, err := SignJWT(rand.Reader, nil, Claims{
+		"iss": "https://idp.example", "aud": []string{"api", "web"},
+		"iat": now.Unix(), "nbf": now.Unix(), "exp": now.Unix() + 60,
+	}, priv, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	j, c, err := ParseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j.Header["typ"] != "JWT" || j.Verify(&priv.PublicKey, uid) != nil {
+		t.Fatalf("header %v", j.Header)
+	}
+	v := Validation{Time: now, Issuer: "https://idp.example", Audience: "api"}
+	if err := c.Validate(v); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		change func(*Validation)
+		want   error
+	}{
+		{func(v *Validation) { v.Time = now.Add(60 * time.Second) }, ErrExpired},
+		{func(v *Validation) { v.Time, v.Leeway = now.Add(60*time.Second), time.Second }, nil},
+		{func(v *Validation) { v.Time = now.Add(-time.Second) }, ErrNotYetValid},
+		{func(v *Validation) { v.Time, v.Leeway = now.Add(-time.Second), time.Second }, nil},
+		{func(v *Validation) { v.Issuer = "other" }, ErrIssuer},
+		{func(v *Validation) { v.Audience = "admin" }, ErrAudience},
+		{func(v *Validation) { v.Audience = "web" }, nil},
+	} {
+		v := v
+		tc.change(&v)
+		if err := c.Validate(v); err != tc.want {
+			t.Errorf("%+v: %v, want %v", v, err, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		claims Claims
+		want   error
+	}{
+		{Claims{"exp": 1780000000.5}, nil},
+		{Claims{}, nil},
+		{Claims{"aud": "api"}, nil},
+		{Claims{"exp": "tomorrow"}, ErrClaims},
+		{Claims{"iat": true}, ErrClaims},
+		{Claims{"iss": 7}, ErrClaims},
+		{Claims{"aud": []interface{}{"api", 1}}, ErrClaims},
+	} {
+		tok, _ := SignJWT(rand.Reader, nil, tc.claims, priv, uid)
+		_, c, err := ParseJWT(tok)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := c.Validate(Validation{Time: now}); err != tc.want {
+			t.Errorf("%v: %v, want %v", tc.claims, err, tc.want)
+		}
+	}
+	if _, c, _ := ParseJWT(token); c.Validate(Validation{Time: now.Add(time.Hour), RequireExp: true}) != ErrExpired {
+		t.Error("expired token with RequireExp")
+	}
+	noExp, _ := SignJWT(rand.Reader, nil, Claims{}, priv, uid)
+	if _, c, _ := ParseJWT(noExp); c.Validate(Validation{Time: now, RequireExp: true}) != ErrExpired {
+		t.Error("token without exp accepted")
+	}
+
+	array, _ := Sign(rand.Reader, nil, []byte(`["not","an","object"]`), priv, uid, false)
+	if _, _, err := ParseJWT(array); err != ErrClaims {
+		t.Errorf("array claims: %v", err)
+	}
+}
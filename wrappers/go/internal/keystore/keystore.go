@@ -0,0 +1,335 @@
+// Package keystore reads and writes a password protected file of named
+// SM2 and SM4 keys.
+//
+// SM2 keys are held as the ECCPRIVATEKEYBLOB and ECCPUBLICKEYBLOB
+// structures of GM/T 0016, the smart card (SKF) interface, so that they
+// can be handed to SKF devices unchanged; SM4 keys are held as their 16
+// key bytes. GM/T 0016 defines the blobs but no file, so the container
+// is this package's own:
+//
+//	magic "SMKS" | version 1 | PBKDF2 iterations (uint32) | salt (16) |
+//	nonce (12) | SM4-GCM ciphertext of the entry table
+//
+// The key is derived from the password with PBKDF2-HMAC-SM3, and the
+// header is authenticated as additional data. Integers are big-endian
+// except inside the GM/T 0016 blobs, which are little-endian C structures.
+package keystore
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+const (
+	magic   = "SMKS"
+	version = 1
+
+	// Iterations is the PBKDF2 iteration count of files written by Write.
+	Iterations = 10000
+
+	saltSize   = 16
+	nonceSize  = 12
+	headerSize = len(magic) + 1 + 4 + saltSize + nonceSize
+
+	// eccBlobLen is the coordinate size of the GM/T 0016 blobs,
+	// ECC_MAX_MODULUS_BITS_LEN / 8; 256-bit values are right-aligned.
+	eccBlobLen = 64
+	eccBitLen  = 256
+)
+
+// KeyType is the kind of key in an entry.
+type KeyType byte
+
+const (
+	SM2 KeyType = 1
+	SM4 KeyType = 2
+)
+
+func (t KeyType) String() string {
+	switch t {
+	case SM2:
+		return "sm2"
+	case SM4:
+		return "sm4"
+	}
+	return fmt.Sprintf("KeyType(%d)", byte(t))
+}
+
+// Entry is a named key. Exactly one of SM2 and SM4 is set, as Type says.
+type Entry struct {
+	Name    string
+	Type    KeyType
+	Created time.Time
+	SM2     *sm2.PrivateKey
+	SM4     []byte
+}
+
+// Keystore is the decrypted content of a keystore file.
+type Keystore struct {
+	entries map[string]Entry
+}
+
+// Errors of the keystore operations.
+var (
+	ErrPassword = errors.New("keystore: wrong password or corrupted file")
+	ErrNotFound = errors.New("keystore: no such entry")
+	ErrExists   = errors.New("keystore: entry already exists")
+)
+
+// New returns an empty keystore.
+func New() *Keystore {
+	return &Keystore{entries: map[string]Entry{}}
+}
+
+// Names returns the entry names in sorted order.
+func (ks *Keystore) Names() []string {
+	names := make([]string, 0, len(ks.entries))
+	for name := range ks.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Get returns the entry called name.
+func (ks *Keystore) Get(name string) (Entry, error) {
+	e, ok := ks.entries[name]
+	if !ok {
+		return Entry{}, ErrNotFound
+	}
+	return e, nil
+}
+
+// Put adds e, replacing an entry of the same name only when replace is
+// set.
+func (ks *Keystore) Put(e Entry, replace bool) error {
+	if e.Name == "" || len(e.Name) > 0xffff {
+		return errors.New("keystore: entry name must be 1 to 65535 bytes")
+	}
+	switch {
+	case e.Type == SM2 && e.SM2 != nil && e.SM4 == nil:
+	case e.Type == SM4 && e.SM2 == nil && len(e.SM4) == sm4.KeySize:
+	default:
+		return errors.New("keystore: entry must hold one SM2 private key or one 16-byte SM4 key")
+	}
+	if _, ok := ks.entries[e.Name]; ok && !replace {
+		return ErrExists
+	}
+	ks.entries[e.Name] = e
+	return nil
+}
+
+// Delete removes the entry called name.
+func (ks *Keystore) Delete(name string) error {
+	if _, ok := ks.entries[name]; !ok {
+		return ErrNotFound
+	}
+	delete(ks.entries, name)
+	return nil
+}
+
+// Read opens the keystore file at path with password.
+func Read(path, password string) (*Keystore, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data, password)
+}
+
+// Write encrypts the keystore under password, with a fresh salt and
+// nonce from rand, and replaces the file at path. The file is written
+// beside path and renamed, so a failed write leaves the old file intact.
+func (ks *Keystore) Write(path, password string, rand io.Reader) error {
+	data, err := ks.Encode(password, rand)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Encode returns the keystore file for ks under password.
+func (ks *Keystore) Encode(password string, rand io.Reader) ([]byte, error) {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	header[len(magic)] = version
+	binary.BigEndian.PutUint32(header[len(magic)+1:], Iterations)
+	if _, err := io.ReadFull(rand, header[len(magic)+5:]); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(password, header)
+	if err != nil {
+		return nil, err
+	}
+	var table bytes.Buffer
+	binary.Write(&table, binary.BigEndian, uint32(len(ks.entries)))
+	for _, name := range ks.Names() {
+		e := ks.entries[name]
+		key := e.SM4
+		if e.Type == SM2 {
+			key = sm2Blobs(e.SM2)
+		}
+		binary.Write(&table, binary.BigEndian, uint16(len(e.Name)))
+		table.WriteString(e.Name)
+		table.WriteByte(byte(e.Type))
+		binary.Write(&table, binary.BigEndian, e.Created.Unix())
+		binary.Write(&table, binary.BigEndian, uint16(len(key)))
+		table.Write(key)
+	}
+	return aead.Seal(header, header[headerSize-nonceSize:], table.Bytes(), header), nil
+}
+
+// Decode opens a keystore file with password.
+func Decode(data []byte, password string) (*Keystore, error) {
+	if len(data) < headerSize || string(data[:len(magic)]) != magic {
+		return nil, errors.New("keystore: not a keystore file")
+	}
+	if v := data[len(magic)]; v != version {
+		return nil, fmt.Errorf("keystore: unsupported version %d", v)
+	}
+	header := data[:headerSize]
+	aead, err := newAEAD(password, header)
+	if err != nil {
+		return nil, err
+	}
+	table, err := aead.Open(nil, header[headerSize-nonceSize:], data[headerSize:], header)
+	if err != nil {
+		return nil, ErrPassword
+	}
+
+	ks := New()
+	r := bytes.NewReader(table)
+	var count uint32
+	if err := binary.Read(r, binary.BigEndian, &count); err != nil {
+		return nil, errMalformed
+	}
+	for i := uint32(0); i < count; i++ {
+		var e Entry
+		name, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		e.Name = string(name)
+		t, err := r.ReadByte()
+		if err != nil {
+			return nil, errMalformed
+		}
+		e.Type = KeyType(t)
+		var created int64
+		if err := binary.Read(r, binary.BigEndian, &created); err != nil {
+			return nil, errMalformed
+		}
+		e.Created = time.Unix(created, 0).UTC()
+		key, err := readField(r)
+		if err != nil {
+			return nil, err
+		}
+		switch e.Type {
+		case SM2:
+			if e.SM2, err = parseSM2Blobs(key); err != nil {
+				return nil, err
+			}
+		case SM4:
+			e.SM4 = key
+		default:
+			return nil, fmt.Errorf("keystore: entry %q has unknown key type %d", e.Name, t)
+		}
+		if err := ks.Put(e, false); err != nil {
+			return nil, fmt.Errorf("keystore: entry %q: %v", e.Name, err)
+		}
+	}
+	if r.Len() != 0 {
+		return nil, errMalformed
+	}
+	return ks, nil
+}
+
+var errMalformed = errors.New("keystore: malformed entry table")
+
+// readField reads a uint16 length and that many bytes.
+func readField(r *bytes.Reader) ([]byte, error) {
+	var n uint16
+	if err := binary.Read(r, binary.BigEndian, &n); err != nil || int(n) > r.Len() {
+		return nil, errMalformed
+	}
+	b := make([]byte, n)
+	r.Read(b)
+	return b, nil
+}
+
+func newAEAD(password string, header []byte) (cipher.AEAD, error) {
+	iterations := int(binary.BigEndian.Uint32(header[len(magic)+1:]))
+	if iterations < 1 || iterations > pbes2.MaxIterations {
+		return nil, fmt.Errorf("keystore: %w: %d (maximum %d)", pbes2.ErrIterations, iterations, pbes2.MaxIterations)
+	}
+	salt := header[len(magic)+5 : len(magic)+5+saltSize]
+	key, err := pbkdf2.Key(sm3.New, password, salt, iterations, sm4.KeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sm2Blobs returns the ECCPRIVATEKEYBLOB of priv followed by the
+// ECCPUBLICKEYBLOB of its public key.
+func sm2Blobs(priv *sm2.PrivateKey) []byte {
+	out := make([]byte, 4+eccBlobLen+4+2*eccBlobLen)
+	binary.LittleEndian.PutUint32(out, eccBitLen)
+	priv.D.FillBytes(out[4 : 4+eccBlobLen])
+	pub := out[4+eccBlobLen:]
+	binary.LittleEndian.PutUint32(pub, eccBitLen)
+	priv.X.FillBytes(pub[4 : 4+eccBlobLen])
+	priv.Y.FillBytes(pub[4+eccBlobLen:])
+	return out
+}
+
+// parseSM2Blobs reverses sm2Blobs and checks that the public key belongs
+// to the private key.
+func parseSM2Blobs(b []byte) (*sm2.PrivateKey, error) {
+	if len(b) != 4+eccBlobLen+4+2*eccBlobLen ||
+		binary.LittleEndian.Uint32(b) != eccBitLen || binary.LittleEndian.Uint32(b[4+eccBlobLen:]) != eccBitLen {
+		return nil, errors.New("keystore: malformed GM/T 0016 ECC key blob")
+	}
+	priv, err := sm2.NewPrivateKey(b[4 : 4+eccBlobLen])
+	if err != nil {
+		return nil, err
+	}
+	pub := b[4+eccBlobLen+4:]
+	x := new(big.Int).SetBytes(pub[:eccBlobLen])
+	y := new(big.Int).SetBytes(pub[eccBlobLen:])
+	if x.Cmp(priv.X) != 0 || y.Cmp(priv.Y) != 0 {
+		return nil, errors.New("keystore: ECC public key blob does not match the private key")
+	}
+	return priv, nil
+}
@@ -0,0 +1,106 @@
+package keystore
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func TestRoundTrip(t *testing.T) {
+	priv, _ := sm2.GenerateKey(rand.Reader)
+	sm4Key := bytes.Repeat([]byte{0x5a}, 16)
+	created := time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	ks := New()
+	if err := ks.Put(Entry{Name: "signing", Type: SM2, Created: created, SM2: priv}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Put(Entry{Name: "data", Type: SM4, Created: created, SM4: sm4Key}, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := ks.Put(Entry{Name: "data", Type: SM4, SM4: sm4Key}, false); err != ErrExists {
+		t.Errorf("duplicate name: %v", err)
+	}
+	if err := ks.Put(Entry{Name: "short", Type: SM4, SM4: sm4Key[:8]}, false); err == nil {
+		t.Error("accepted an 8-byte SM4 key")
+	}
+
+	path := filepath.Join(t.TempDir(), "keys.smks")
+	if err := ks.Write(path, "secret", rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	got, err := Read(path, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := got.Names(); len(names) != 2 || names[0] != "data" || names[1] != "signing" {
+		t.Errorf("Names = %v", names)
+	}
+	e, err := got.Get("signing")
+	if err != nil || e.Type != SM2 || e.SM2.D.Cmp(priv.D) != 0 || !e.Created.Equal(created) {
+		t.Errorf("Get(signing) = %+v, %v", e, err)
+	}
+	e, err = got.Get("data")
+	if err != nil || e.Type != SM4 || !bytes.Equal(e.SM4, sm4Key) {
+		t.Errorf("Get(data) = %+v, %v", e, err)
+	}
+	if err := got.Delete("data"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := got.Get("data"); err != ErrNotFound {
+		t.Errorf("deleted entry: %v", err)
+	}
+	if err := got.Delete("data"); err != ErrNotFound {
+		t.Errorf("second delete: %v", err)
+	}
+
+	if _, err := Read(path, "wrong"); err != ErrPassword {
+		t.Errorf("wrong password: %v", err)
+	}
+	data, _ := ks.Encode("secret", rand.Reader)
+	data[len(magic)+5] ^= 1 // the salt is authenticated
+	if _, err := Decode(data, "secret"); err != ErrPassword {
+		t.Errorf("tampered header: %v", err)
+	}
+	data, _ = ks.Encode("secret", rand.Reader)
+	binary.BigEndian.PutUint32(data[len(magic)+1:], pbes2.MaxIterations+1)
+	if _, err := Decode(data, "secret"); !errors.Is(err, pbes2.ErrIterations) {
+		t.Errorf("excessive iteration count: %v", err)
+	}
+	if _, err := Decode([]byte("not a keystore at all, really not"), "secret"); err == nil {
+		t.Error("decoded garbage")
+	}
+}
+
+func TestSM2Blobs(t *testing.T) {
+	d, _ := hex.DecodeString("3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8")
+	priv, err := sm2.NewPrivateKey(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := sm2Blobs(priv)
+	// BitLen is the little-endian ULONG 256, and values are right-aligned
+	// in 64-byte fields.
+	if !bytes.Equal(b[:4], []byte{0, 1, 0, 0}) || !bytes.Equal(b[4:36], make([]byte, 32)) || !bytes.Equal(b[36:68], d) {
+		t.Errorf("ECCPRIVATEKEYBLOB = %x", b[:68])
+	}
+	if !bytes.Equal(b[68:72], []byte{0, 1, 0, 0}) || !bytes.Equal(b[104:136], priv.X.FillBytes(make([]byte, 32))) {
+		t.Errorf("ECCPUBLICKEYBLOB = %x", b[68:])
+	}
+	got, err := parseSM2Blobs(b)
+	if err != nil || got.D.Cmp(priv.D) != 0 {
+		t.Fatalf("parseSM2Blobs = %v, %v", got, err)
+	}
+	b[len(b)-1] ^= 1
+	if _, err := parseSM2Blobs(b); err == nil {
+		t.Error("accepted a public key blob of another key")
+	}
+}
@@ -0,0 +1,34 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+)
+
+// CBCMAC computes the raw CBC-MAC (ISO/IEC 9797-1 MAC algorithm 1) of msg
+// with a zero IV. Without lengthPrefix the message is zero padded to a
+// whole number of blocks (padding method 1; an empty message becomes one
+// zero block), which is only secure for fixed-length messages. With
+// lengthPrefix a block holding the bit length of msg is prepended before
+// zero padding (padding method 3), which prevents extension forgeries.
+func CBCMAC(b cipher.Block, msg []byte, lengthPrefix bool) []byte {
+	bs := b.BlockSize()
+	y := make([]byte, bs)
+	if lengthPrefix {
+		binary.BigEndian.PutUint64(y[bs-8:], uint64(len(msg))*8)
+		b.Encrypt(y, y)
+	}
+	for len(msg) > bs {
+		subtle.XORBytes(y, y, msg[:bs])
+		b.Encrypt(y, y)
+		msg = msg[bs:]
+	}
+	if len(msg) > 0 || !lengthPrefix {
+		last := make([]byte, bs)
+		copy(last, msg)
+		subtle.XORBytes(y, y, last)
+		b.Encrypt(y, y)
+	}
+	return y
+}
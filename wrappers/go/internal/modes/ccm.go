@@ -0,0 +1,162 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+type ccm struct {
+	b         cipher.Block
+	nonceSize int
+	tagSize   int
+}
+
+// NewCCM returns the CCM AEAD of NIST SP 800-38C (RFC 3610) for a 128-bit
+// block cipher. nonceSize must be 7 to 13 bytes and tagSize an even number
+// from 4 to 16.
+func NewCCM(b cipher.Block, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("modes: CCM requires a 128-bit block cipher")
+	}
+	if nonceSize < 7 || nonceSize > 13 {
+		return nil, fmt.Errorf("%w: CCM nonce must be 7 to 13 bytes, got %d", ErrNonceSize, nonceSize)
+	}
+	if tagSize < 4 || tagSize > 16 || tagSize%2 != 0 {
+		return nil, fmt.Errorf("%w: CCM tag must be an even length from 4 to 16 bytes, got %d", ErrTagSize, tagSize)
+	}
+	return &ccm{b: b, nonceSize: nonceSize, tagSize: tagSize}, nil
+}
+
+func (c *ccm) NonceSize() int { return c.nonceSize }
+
+func (c *ccm) Overhead() int { return c.tagSize }
+
+// maxLen is the longest payload the length field of B0 can describe.
+func (c *ccm) maxLen() uint64 {
+	q := 15 - c.nonceSize
+	if q >= 8 {
+		return 1<<63 - 1
+	}
+	return 1<<(8*q) - 1
+}
+
+func (c *ccm) Seal(dst, nonce, plaintext, aad []byte) []byte {
+	if len(nonce) != c.nonceSize {
+		panic("modes: incorrect CCM nonce length")
+	}
+	if uint64(len(plaintext)) > c.maxLen() {
+		panic("modes: CCM plaintext too long")
+	}
+	tag := c.mac(nonce, plaintext, aad)
+	ret, out := sliceForAppend(dst, len(plaintext)+c.tagSize)
+	c.ctr(nonce, out, plaintext, tag)
+	copy(out[len(plaintext):], tag)
+	return ret
+}
+
+func (c *ccm) Open(dst, nonce, ciphertext, aad []byte) ([]byte, error) {
+	if len(nonce) != c.nonceSize {
+		panic("modes: incorrect CCM nonce length")
+	}
+	if len(ciphertext) < c.tagSize || uint64(len(ciphertext)-c.tagSize) > c.maxLen() {
+		return nil, ErrAuthentication
+	}
+	n := len(ciphertext) - c.tagSize
+	tag := append([]byte(nil), ciphertext[n:]...)
+	ret, out := sliceForAppend(dst, n)
+	c.ctr(nonce, out, ciphertext[:n], tag)
+	if subtle.ConstantTimeCompare(c.mac(nonce, out, aad), tag) != 1 {
+		clear(out)
+		return nil, ErrAuthentication
+	}
+	return ret, nil
+}
+
+// counter returns the counter block Ctr_i.
+func (c *ccm) counter(nonce []byte, i uint64) []byte {
+	blk := make([]byte, 16)
+	q := 15 - c.nonceSize
+	blk[0] = byte(q - 1)
+	copy(blk[1:], nonce)
+	var ctr [8]byte
+	binary.BigEndian.PutUint64(ctr[:], i)
+	copy(blk[16-q:], ctr[8-min(q, 8):])
+	return blk
+}
+
+// ctr encrypts src into dst with counters starting at 1 and masks tag with
+// the encryption of Ctr_0 in place.
+func (c *ccm) ctr(nonce, dst, src, tag []byte) {
+	ks := make([]byte, 16)
+	c.b.Encrypt(ks, c.counter(nonce, 0))
+	subtle.XORBytes(tag, tag, ks[:c.tagSize])
+	for i := uint64(1); len(src) > 0; i++ {
+		c.b.Encrypt(ks, c.counter(nonce, i))
+		n := subtle.XORBytes(dst, src, ks)
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// mac computes the unmasked CBC-MAC tag T.
+func (c *ccm) mac(nonce, plaintext, aad []byte) []byte {
+	q := 15 - c.nonceSize
+	b0 := make([]byte, 16)
+	b0[0] = byte(8*((c.tagSize-2)/2) + (q - 1))
+	if len(aad) > 0 {
+		b0[0] |= 0x40
+	}
+	copy(b0[1:], nonce)
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(plaintext)))
+	copy(b0[16-q:], l[8-min(q, 8):])
+
+	y := make([]byte, 16)
+	c.b.Encrypt(y, b0)
+	absorb := func(data []byte) {
+		for len(data) > 0 {
+			n := subtle.XORBytes(y, y, data)
+			c.b.Encrypt(y, y)
+			data = data[n:]
+		}
+	}
+	if len(aad) > 0 {
+		var hdr []byte
+		switch n := uint64(len(aad)); {
+		case n < 1<<16-1<<8:
+			hdr = binary.BigEndian.AppendUint16(nil, uint16(n))
+		case n <= 1<<32-1:
+			hdr = binary.BigEndian.AppendUint32([]byte{0xff, 0xfe}, uint32(n))
+		default:
+			hdr = binary.BigEndian.AppendUint64([]byte{0xff, 0xff}, n)
+		}
+		absorb(zeroPad(append(hdr, aad...)))
+	}
+	absorb(zeroPad(plaintext))
+	return y[:c.tagSize]
+}
+
+// zeroPad returns data extended with zeros to a multiple of 16 bytes.
+func zeroPad(data []byte) []byte {
+	if len(data)%16 == 0 {
+		return data
+	}
+	out := make([]byte, len(data)+16-len(data)%16)
+	copy(out, data)
+	return out
+}
+
+// sliceForAppend extends in by n bytes, returning the whole slice and the
+// new tail, as the standard library's AEADs do.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,73 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"fmt"
+)
+
+type cfb struct {
+	b       cipher.Block
+	reg     []byte // shift register, one block
+	out     []byte // keystream for the current segment
+	seg     []byte // ciphertext bytes of the current segment
+	segSize int
+	used    int
+	decrypt bool
+}
+
+// NewCFBEncrypter returns a CFB stream with the given segment size in bytes
+// (1 for CFB8, the block size for full-block CFB).
+func NewCFBEncrypter(b cipher.Block, iv []byte, segmentSize int) (cipher.Stream, error) {
+	return newCFB(b, iv, segmentSize, false)
+}
+
+// NewCFBDecrypter is the decrypting counterpart of NewCFBEncrypter.
+func NewCFBDecrypter(b cipher.Block, iv []byte, segmentSize int) (cipher.Stream, error) {
+	return newCFB(b, iv, segmentSize, true)
+}
+
+func newCFB(b cipher.Block, iv []byte, segmentSize int, decrypt bool) (cipher.Stream, error) {
+	bs := b.BlockSize()
+	if len(iv) != bs {
+		return nil, fmt.Errorf("modes: CFB IV must be %d bytes", bs)
+	}
+	if segmentSize < 1 || segmentSize > bs {
+		return nil, fmt.Errorf("modes: CFB segment size must be between 1 and %d bytes", bs)
+	}
+	c := &cfb{
+		b:       b,
+		reg:     append([]byte(nil), iv...),
+		out:     make([]byte, bs),
+		seg:     make([]byte, 0, segmentSize),
+		segSize: segmentSize,
+		used:    segmentSize,
+		decrypt: decrypt,
+	}
+	return c, nil
+}
+
+func (c *cfb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("modes: output smaller than input")
+	}
+	for i, v := range src {
+		if c.used == c.segSize {
+			c.b.Encrypt(c.out, c.reg)
+			c.used = 0
+		}
+		o := v ^ c.out[c.used]
+		ct := o
+		if c.decrypt {
+			ct = v
+		}
+		dst[i] = o
+		c.seg = append(c.seg, ct)
+		c.used++
+		if c.used == c.segSize {
+			// Shift the completed ciphertext segment into the register.
+			n := copy(c.reg, c.reg[c.segSize:])
+			copy(c.reg[n:], c.seg)
+			c.seg = c.seg[:0]
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+)
+
+// CMAC computes the CMAC of NIST SP 800-38B (RFC 4493) over msg with a
+// 128-bit block cipher.
+func CMAC(b cipher.Block, msg []byte) []byte {
+	k1, k2 := cmacSubkeys(b)
+	y := make([]byte, 16)
+	for len(msg) > 16 {
+		subtle.XORBytes(y, y, msg[:16])
+		b.Encrypt(y, y)
+		msg = msg[16:]
+	}
+	last := make([]byte, 16)
+	copy(last, msg)
+	if len(msg) == 16 {
+		subtle.XORBytes(last, last, k1)
+	} else {
+		last[len(msg)] = 0x80
+		subtle.XORBytes(last, last, k2)
+	}
+	subtle.XORBytes(y, y, last)
+	b.Encrypt(y, y)
+	return y
+}
+
+func cmacSubkeys(b cipher.Block) (k1, k2 []byte) {
+	l := make([]byte, 16)
+	b.Encrypt(l, l)
+	k1 = dbl(l)
+	k2 = dbl(k1)
+	return k1, k2
+}
+
+// dbl doubles a value in GF(2^128), big-endian byte order.
+func dbl(in []byte) []byte {
+	out := make([]byte, len(in))
+	var carry byte
+	for i := len(in) - 1; i >= 0; i-- {
+		out[i] = in[i]<<1 | carry
+		carry = in[i] >> 7
+	}
+	if carry != 0 {
+		out[len(out)-1] ^= 0x87
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package modes
+
+import "crypto/cipher"
+
+type ecb struct {
+	b       cipher.Block
+	decrypt bool
+}
+
+// NewECBEncrypter returns a cipher.BlockMode that encrypts each block
+// independently.
+func NewECBEncrypter(b cipher.Block) cipher.BlockMode { return &ecb{b: b} }
+
+// NewECBDecrypter returns a cipher.BlockMode that decrypts each block
+// independently.
+func NewECBDecrypter(b cipher.Block) cipher.BlockMode { return &ecb{b: b, decrypt: true} }
+
+func (e *ecb) BlockSize() int { return e.b.BlockSize() }
+
+func (e *ecb) CryptBlocks(dst, src []byte) {
+	bs := e.b.BlockSize()
+	if len(src)%bs != 0 {
+		panic("modes: input not full blocks")
+	}
+	if len(dst) < len(src) {
+		panic("modes: output smaller than input")
+	}
+	for ; len(src) > 0; src, dst = src[bs:], dst[bs:] {
+		if e.decrypt {
+			e.b.Decrypt(dst, src)
+		} else {
+			e.b.Encrypt(dst, src)
+		}
+	}
+}
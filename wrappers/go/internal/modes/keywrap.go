@@ -0,0 +1,67 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+)
+
+// defaultKeyWrapIV is the initial value A6A6A6A6A6A6A6A6 of RFC 3394.
+var defaultKeyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// WrapKey wraps key, a multiple of 8 bytes and at least 16 bytes long,
+// with the RFC 3394 key wrap algorithm.
+func WrapKey(b cipher.Block, key []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("modes: key wrap requires a 128-bit block cipher")
+	}
+	if len(key) < 16 || len(key)%8 != 0 {
+		return nil, errors.New("modes: key to wrap must be a multiple of 8 bytes and at least 16 bytes")
+	}
+	n := len(key) / 8
+	out := make([]byte, 8+len(key))
+	copy(out, defaultKeyWrapIV)
+	copy(out[8:], key)
+
+	var buf [16]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf[:8], out[:8])
+			copy(buf[8:], out[8*i:])
+			b.Encrypt(buf[:], buf[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(buf[:8])^t)
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	return out, nil
+}
+
+// UnwrapKey reverses WrapKey and checks the integrity value.
+func UnwrapKey(b cipher.Block, wrapped []byte) ([]byte, error) {
+	if b.BlockSize() != 16 {
+		return nil, errors.New("modes: key wrap requires a 128-bit block cipher")
+	}
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, errors.New("modes: wrapped key must be a multiple of 8 bytes and at least 24 bytes")
+	}
+	n := len(wrapped)/8 - 1
+	out := append([]byte(nil), wrapped...)
+
+	var buf [16]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(out[:8])^t)
+			copy(buf[8:], out[8*i:])
+			b.Decrypt(buf[:], buf[:])
+			copy(out[:8], buf[:8])
+			copy(out[8*i:], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(out[:8], defaultKeyWrapIV) != 1 {
+		return nil, ErrAuthentication
+	}
+	return out[8:], nil
+}
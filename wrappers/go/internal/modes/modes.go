@@ -0,0 +1,17 @@
+// Package modes implements block cipher modes of operation that the
+// standard library's crypto/cipher does not provide, or provides only in a
+// single configuration. Every mode works with any 128-bit cipher.Block.
+package modes
+
+import "errors"
+
+// ErrAuthentication is returned by CCM and key unwrapping for input whose
+// integrity check fails.
+var ErrAuthentication = errors.New("modes: message authentication failed")
+
+// ErrNonceSize and ErrTagSize are returned by NewCCM for sizes the mode
+// does not define.
+var (
+	ErrNonceSize = errors.New("modes: invalid nonce size")
+	ErrTagSize   = errors.New("modes: invalid tag size")
+)
@@ -0,0 +1,273 @@
+package modes
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// The NIST SP 800-38A vectors use AES-128, which exercises the same
+// generic code paths as SM4.
+var (
+	nistKey = mustHex("2b7e151628aed2a6abf7158809cf4f3c")
+	nistIV  = mustHex("000102030405060708090a0b0c0d0e0f")
+)
+
+func TestCFB(t *testing.T) {
+	cases := []struct {
+		name    string
+		segment int
+		pt, ct  string
+	}{
+		// F.3.7 CFB8-AES128.Encrypt
+		{"CFB8", 1, "6bc1bee22e409f96e93d7e117393172aae2d", "3b79424c9c0dd436bace9e0ed4586a4f32b9"},
+		// F.3.13 CFB128-AES128.Encrypt, first two blocks
+		{"CFB128", 16, "6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51",
+			"3b3fd92eb72dad20333449f8e83cfb4ac8a64537a0b3a93fcde3cdad9f1ce58b"},
+	}
+	for _, c := range cases {
+		b, _ := aes.NewCipher(nistKey)
+		enc, err := NewCFBEncrypter(b, nistIV, c.segment)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pt := mustHex(c.pt)
+		got := make([]byte, len(pt))
+		// Feed unaligned pieces to exercise the segment bookkeeping.
+		enc.XORKeyStream(got[:5], pt[:5])
+		enc.XORKeyStream(got[5:], pt[5:])
+		if hex.EncodeToString(got) != c.ct {
+			t.Errorf("%s encrypt = %x, want %s", c.name, got, c.ct)
+		}
+		dec, _ := NewCFBDecrypter(b, nistIV, c.segment)
+		dec.XORKeyStream(got, got)
+		if !bytes.Equal(got, pt) {
+			t.Errorf("%s decrypt = %x", c.name, got)
+		}
+	}
+}
+
+func TestOFB(t *testing.T) {
+	// F.4.1 OFB-AES128.Encrypt, first two blocks plus a partial block.
+	pt := mustHex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c8")
+	want := "3b3fd92eb72dad20333449f8e83cfb4a7789508d16918f03f53c52dac54ed8259740"
+	b, _ := aes.NewCipher(nistKey)
+	s, err := NewOFB(b, nistIV)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, len(pt))
+	s.XORKeyStream(got, pt)
+	if hex.EncodeToString(got) != want {
+		t.Fatalf("OFB = %x, want %s", got, want)
+	}
+}
+
+func TestCCM(t *testing.T) {
+	// RFC 3610 packet vector #1 (AES-128, 8-byte tag, 13-byte nonce).
+	key := mustHex("c0c1c2c3c4c5c6c7c8c9cacbcccdcecf")
+	nonce := mustHex("00000003020100a0a1a2a3a4a5")
+	aad := mustHex("0001020304050607")
+	pt := mustHex("08090a0b0c0d0e0f101112131415161718191a1b1c1d1e")
+	want := "588c979a61c663d2f066d0c2c0f989806d5f6b61dac38417e8d12cfdf926e0"
+
+	b, _ := aes.NewCipher(key)
+	aead, err := NewCCM(b, len(nonce), 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct := aead.Seal(nil, nonce, pt, aad)
+	if hex.EncodeToString(ct) != want {
+		t.Fatalf("Seal = %x, want %s", ct, want)
+	}
+	got, err := aead.Open(nil, nonce, ct, aad)
+	if err != nil || !bytes.Equal(got, pt) {
+		t.Fatalf("Open = %x, %v", got, err)
+	}
+	ct[0] ^= 1
+	if _, err := aead.Open(nil, nonce, ct, aad); err == nil {
+		t.Fatal("Open accepted a tampered ciphertext")
+	}
+	if _, err := NewCCM(b, 6, 8); !errors.Is(err, ErrNonceSize) {
+		t.Fatalf("NewCCM with a 6-byte nonce: %v", err)
+	}
+	if _, err := NewCCM(b, 12, 5); !errors.Is(err, ErrTagSize) {
+		t.Fatalf("NewCCM with an odd tag length: %v", err)
+	}
+}
+
+// SM4-CCM example from RFC 8998 Appendix A.2.
+func TestSM4CCMVector(t *testing.T) {
+	key := mustHex("0123456789abcdeffedcba9876543210")
+	nonce := mustHex("00001234567800000000abcd")
+	aad := mustHex("feedfacedeadbeeffeedfacedeadbeefabaddad2")
+	pt := mustHex("aaaaaaaaaaaaaaaabbbbbbbbbbbbbbbbccccccccccccccccdddddddddddddddd" +
+		"eeeeeeeeeeeeeeeeffffffffffffffffeeeeeeeeeeeeeeeeaaaaaaaaaaaaaaaa")
+	want := "48af93501fa62adbcd414cce6034d895dda1bf8f132f042098661572e7483094" +
+		"fd12e518ce062c98acee28d95df4416bed31a2f04476c18bb40c84a74b97dc5b" +
+		"16842d4fa186f56ab33256971fa110f4"
+
+	b, _ := sm4.NewCipher(key)
+	aead, _ := NewCCM(b, len(nonce), 16)
+	if got := aead.Seal(nil, nonce, pt, aad); hex.EncodeToString(got) != want {
+		t.Fatalf("Seal = %x, want %s", got, want)
+	}
+}
+
+func TestXTS(t *testing.T) {
+	// IEEE 1619-2007 Annex B vector 1 (XTS-AES-128).
+	k1, _ := aes.NewCipher(make([]byte, 16))
+	k2, _ := aes.NewCipher(make([]byte, 16))
+	x, err := NewXTS(k1, k2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, 32)
+	if err := x.Encrypt(got, make([]byte, 32), make([]byte, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if want := "917cf69ebd68b2ec9b9fe9a3eadda692cd43d2f59598ed858c02c2652fbf922e"; hex.EncodeToString(got) != want {
+		t.Fatalf("Encrypt = %x, want %s", got, want)
+	}
+
+	// Vector 15 exercises ciphertext stealing with a 17-byte data unit.
+	k1, _ = aes.NewCipher(mustHex("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0"))
+	k2, _ = aes.NewCipher(mustHex("bfbebdbcbbbab9b8b7b6b5b4b3b2b1b0"))
+	x, _ = NewXTS(k1, k2)
+	tweak := mustHex("9a785634120000000000000000000000")
+	pt := mustHex("000102030405060708090a0b0c0d0e0f10")
+	got = make([]byte, len(pt))
+	x.Encrypt(got, pt, tweak)
+	if want := "6c1625db4671522d3d7599601de7ca09ed"; hex.EncodeToString(got) != want {
+		t.Fatalf("Encrypt = %x, want %s", got, want)
+	}
+	x.Decrypt(got, got, tweak)
+	if !bytes.Equal(got, pt) {
+		t.Fatalf("Decrypt = %x", got)
+	}
+}
+
+func TestXTSCiphertextStealing(t *testing.T) {
+	k1, _ := sm4.NewCipher(mustHex("0123456789abcdeffedcba9876543210"))
+	k2, _ := sm4.NewCipher(mustHex("fedcba98765432100123456789abcdef"))
+	x, _ := NewXTS(k1, k2)
+	tweak := mustHex("000102030405060708090a0b0c0d0e0f")
+
+	aligned := make([]byte, 48)
+	for i := range aligned {
+		aligned[i] = byte(i)
+	}
+	alignedCT := make([]byte, 48)
+	x.Encrypt(alignedCT, aligned, tweak)
+
+	for n := 16; n <= 48; n++ {
+		pt := aligned[:n]
+		ct := make([]byte, n)
+		if err := x.Encrypt(ct, pt, tweak); err != nil {
+			t.Fatal(err)
+		}
+		// Blocks before the stolen pair are unaffected by the tail.
+		if lead := (n/16 - 1) * 16; n%16 != 0 && !bytes.Equal(ct[:lead], alignedCT[:lead]) {
+			t.Fatalf("n=%d: leading blocks differ", n)
+		}
+		back := make([]byte, n)
+		if err := x.Decrypt(back, ct, tweak); err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(back, pt) {
+			t.Fatalf("n=%d: round trip = %x", n, back)
+		}
+	}
+	if err := x.Encrypt(make([]byte, 15), make([]byte, 15), tweak); err == nil {
+		t.Fatal("Encrypt accepted a 15-byte data unit")
+	}
+}
+
+func TestKeyWrap(t *testing.T) {
+	// RFC 3394 section 4.1 and 4.3.
+	kek := mustHex("000102030405060708090a0b0c0d0e0f1011121314151617")
+	cases := []struct{ kek, key, want string }{
+		{"000102030405060708090a0b0c0d0e0f", "00112233445566778899aabbccddeeff",
+			"1fa68b0a8112b447aef34bd8fb5a7b829d3e862371d2cfe5"},
+		{hex.EncodeToString(kek), "00112233445566778899aabbccddeeff0001020304050607",
+			"031d33264e15d33268f24ec260743edce1c6c7ddee725a936ba814915c6762d2"},
+	}
+	for _, c := range cases {
+		b, _ := aes.NewCipher(mustHex(c.kek))
+		got, err := WrapKey(b, mustHex(c.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(got) != c.want {
+			t.Errorf("WrapKey = %x, want %s", got, c.want)
+		}
+		back, err := UnwrapKey(b, got)
+		if err != nil || hex.EncodeToString(back) != c.key {
+			t.Errorf("UnwrapKey = %x, %v", back, err)
+		}
+		got[3] ^= 1
+		if _, err := UnwrapKey(b, got); err == nil {
+			t.Error("UnwrapKey accepted a corrupted key")
+		}
+	}
+}
+
+func TestCMAC(t *testing.T) {
+	// RFC 4493 section 4.
+	b, _ := aes.NewCipher(nistKey)
+	msg := mustHex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e5130c81c46a35ce411")
+	cases := []struct {
+		n    int
+		want string
+	}{
+		{0, "bb1d6929e95937287fa37d129b756746"},
+		{16, "070a16b46b4d4144f79bdd9dd04a287c"},
+		{40, "dfa66747de9ae63030ca32611497c827"},
+	}
+	for _, c := range cases {
+		if got := CMAC(b, msg[:c.n]); hex.EncodeToString(got) != c.want {
+			t.Errorf("CMAC(%d bytes) = %x, want %s", c.n, got, c.want)
+		}
+	}
+}
+
+func TestCBCMAC(t *testing.T) {
+	b, _ := aes.NewCipher(nistKey)
+	msg := mustHex("6bc1bee22e409f96e93d7e117393172aae2d8a571e03ac9c9eb76fac45af8e51" +
+		"30c81c46a35ce411e5fbc1191a0a52eff69f2445df4f9b17ad2b417be66c3710")
+
+	// The MAC of aligned data is the last block of CBC encryption under a
+	// zero IV.
+	zero := make([]byte, 16)
+	for _, n := range []int{16, 64} {
+		ct := make([]byte, n)
+		cipher.NewCBCEncrypter(b, zero).CryptBlocks(ct, msg[:n])
+		if got := CBCMAC(b, msg[:n], false); !bytes.Equal(got, ct[n-16:]) {
+			t.Errorf("CBCMAC(%d bytes) = %x, want %x", n, got, ct[n-16:])
+		}
+	}
+
+	// Partial blocks are zero padded, and the prefixed variant is CBC-MAC
+	// over the length block followed by the padded message.
+	padded := append(append([]byte(nil), msg[:20]...), make([]byte, 12)...)
+	if !bytes.Equal(CBCMAC(b, msg[:20], false), CBCMAC(b, padded, false)) {
+		t.Error("CBCMAC does not zero pad partial blocks")
+	}
+	prefixed := append(mustHex("000000000000000000000000000000a0"), padded...)
+	if !bytes.Equal(CBCMAC(b, msg[:20], true), CBCMAC(b, prefixed, false)) {
+		t.Error("length-prefixed CBCMAC does not match manual prefixing")
+	}
+}
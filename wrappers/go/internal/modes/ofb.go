@@ -0,0 +1,37 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"fmt"
+)
+
+type ofb struct {
+	b    cipher.Block
+	out  []byte
+	used int
+}
+
+// NewOFB returns an OFB keystream generator. Encryption and decryption are
+// the same operation.
+func NewOFB(b cipher.Block, iv []byte) (cipher.Stream, error) {
+	if len(iv) != b.BlockSize() {
+		return nil, fmt.Errorf("modes: OFB IV must be %d bytes", b.BlockSize())
+	}
+	o := &ofb{b: b, out: append([]byte(nil), iv...)}
+	o.used = len(o.out)
+	return o, nil
+}
+
+func (o *ofb) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("modes: output smaller than input")
+	}
+	for i, v := range src {
+		if o.used == len(o.out) {
+			o.b.Encrypt(o.out, o.out)
+			o.used = 0
+		}
+		dst[i] = v ^ o.out[o.used]
+		o.used++
+	}
+}
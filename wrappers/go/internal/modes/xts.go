@@ -0,0 +1,104 @@
+package modes
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+)
+
+// XTS implements the XTS mode of IEEE Std 1619-2007 with ciphertext
+// stealing, keyed by two independent 128-bit block ciphers.
+type XTS struct {
+	k1, k2 cipher.Block
+}
+
+// NewXTS returns XTS using k1 for data and k2 for the tweak.
+func NewXTS(k1, k2 cipher.Block) (*XTS, error) {
+	if k1.BlockSize() != 16 || k2.BlockSize() != 16 {
+		return nil, errors.New("modes: XTS requires a 128-bit block cipher")
+	}
+	return &XTS{k1: k1, k2: k2}, nil
+}
+
+// Encrypt encrypts one data unit of at least 16 bytes under the 16-byte tweak.
+func (x *XTS) Encrypt(dst, src, tweak []byte) error {
+	return x.crypt(dst, src, tweak, false)
+}
+
+// Decrypt decrypts one data unit of at least 16 bytes under the 16-byte tweak.
+func (x *XTS) Decrypt(dst, src, tweak []byte) error {
+	return x.crypt(dst, src, tweak, true)
+}
+
+func (x *XTS) crypt(dst, src, tweak []byte, decrypt bool) error {
+	if len(tweak) != 16 {
+		return errors.New("modes: XTS tweak must be 16 bytes")
+	}
+	if len(src) < 16 {
+		return errors.New("modes: XTS data unit must be at least 16 bytes")
+	}
+	if len(dst) < len(src) {
+		return errors.New("modes: output smaller than input")
+	}
+	var t [16]byte
+	x.k2.Encrypt(t[:], tweak)
+
+	full := len(src) / 16
+	rem := len(src) % 16
+	if rem != 0 {
+		// The last full block takes part in ciphertext stealing.
+		full--
+	}
+	for i := 0; i < full; i++ {
+		x.block(dst[i*16:], src[i*16:], &t, decrypt)
+		mulAlpha(&t)
+	}
+	if rem == 0 {
+		return nil
+	}
+
+	off := full * 16
+	var cc, pp [16]byte
+	if !decrypt {
+		x.block(cc[:], src[off:], &t, false)
+		copy(pp[:], src[off+16:])
+		copy(pp[rem:], cc[rem:])
+		mulAlpha(&t)
+		x.block(dst[off:], pp[:], &t, false)
+		copy(dst[off+16:], cc[:rem])
+		return nil
+	}
+	// Decryption consumes the two final tweaks in reverse order.
+	next := t
+	mulAlpha(&next)
+	x.block(pp[:], src[off:], &next, true)
+	copy(cc[:], src[off+16:])
+	copy(cc[rem:], pp[rem:])
+	x.block(dst[off:], cc[:], &t, true)
+	copy(dst[off+16:], pp[:rem])
+	return nil
+}
+
+func (x *XTS) block(dst, src []byte, t *[16]byte, decrypt bool) {
+	var buf [16]byte
+	subtle.XORBytes(buf[:], src[:16], t[:])
+	if decrypt {
+		x.k1.Decrypt(buf[:], buf[:])
+	} else {
+		x.k1.Encrypt(buf[:], buf[:])
+	}
+	subtle.XORBytes(dst[:16], buf[:], t[:])
+}
+
+// mulAlpha multiplies the tweak by α in GF(2^128), little-endian byte order.
+func mulAlpha(t *[16]byte) {
+	var carry byte
+	for i := range t {
+		next := t[i] >> 7
+		t[i] = t[i]<<1 | carry
+		carry = next
+	}
+	if carry != 0 {
+		t[0] ^= 0x87
+	}
+}
@@ -0,0 +1,554 @@
+// Package ocsp builds OCSP requests and builds and verifies OCSP responses
+// (RFC 6960) for SM2 certificates, with SM2-with-SM3 response signatures.
+// It handles one certificate per request and response.
+package ocsp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+var (
+	oidBasicResponse = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 1}
+	oidNonce         = asn1.ObjectIdentifier{1, 3, 6, 1, 5, 5, 7, 48, 1, 2}
+)
+
+// HashAlgorithm is the hash of the issuer name and key in a CertID.
+type HashAlgorithm int
+
+const (
+	SM3 HashAlgorithm = iota
+	SHA1
+	SHA256
+)
+
+var hashes = []struct {
+	alg HashAlgorithm
+	oid asn1.ObjectIdentifier
+	h   func() hash.Hash
+}{
+	{SM3, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}, sm3.New},
+	{SHA1, asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}, sha1.New},
+	{SHA256, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, sha256.New},
+}
+
+// ResponseStatus is the outcome of an OCSP request as a whole.
+type ResponseStatus int
+
+const (
+	Successful       ResponseStatus = 0
+	MalformedRequest ResponseStatus = 1
+	InternalError    ResponseStatus = 2
+	TryLater         ResponseStatus = 3
+	SigRequired      ResponseStatus = 5
+	Unauthorized     ResponseStatus = 6
+)
+
+// CertStatus is the status of the certificate in a response.
+type CertStatus int
+
+const (
+	Good CertStatus = iota
+	Revoked
+	Unknown
+)
+
+type certID struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	NameHash      []byte
+	IssuerKeyHash []byte
+	SerialNumber  *big.Int
+}
+
+type ocspRequest struct {
+	TBSRequest tbsRequest
+}
+
+type tbsRequest struct {
+	Version           int           `asn1:"explicit,tag:0,default:0,optional"`
+	RequestorName     asn1.RawValue `asn1:"explicit,tag:1,optional"`
+	RequestList       []request
+	RequestExtensions []pkix.Extension `asn1:"explicit,tag:2,optional"`
+}
+
+type request struct {
+	Cert       certID
+	Extensions []pkix.Extension `asn1:"explicit,tag:0,optional"`
+}
+
+type ocspResponse struct {
+	Status   asn1.Enumerated
+	Response responseBytes `asn1:"explicit,tag:0,optional"`
+}
+
+type responseBytes struct {
+	ResponseType asn1.ObjectIdentifier
+	Response     []byte
+}
+
+type basicResponse struct {
+	TBSResponseData    asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          asn1.BitString
+	Certificates       []asn1.RawValue `asn1:"explicit,tag:0,optional"`
+}
+
+type responseData struct {
+	Version     int `asn1:"optional,default:0,explicit,tag:0"`
+	ResponderID asn1.RawValue
+	ProducedAt  time.Time `asn1:"generalized"`
+	Responses   []singleResponse
+	Extensions  []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type singleResponse struct {
+	CertID     certID
+	Good       asn1.Flag        `asn1:"tag:0,optional"`
+	Revoked    revokedInfo      `asn1:"tag:1,optional"`
+	Unknown    asn1.Flag        `asn1:"tag:2,optional"`
+	ThisUpdate time.Time        `asn1:"generalized"`
+	NextUpdate time.Time        `asn1:"generalized,explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"explicit,tag:1,optional"`
+}
+
+type revokedInfo struct {
+	RevocationTime time.Time       `asn1:"generalized"`
+	Reason         asn1.Enumerated `asn1:"explicit,tag:0,optional"`
+}
+
+// ResponderID tags.
+const (
+	responderByName = 1
+	responderByKey  = 2
+)
+
+// Request is a parsed OCSP request for one certificate.
+type Request struct {
+	HashAlgorithm  HashAlgorithm
+	IssuerNameHash []byte
+	IssuerKeyHash  []byte
+	SerialNumber   *big.Int
+	Nonce          []byte // nil when absent
+}
+
+// CreateRequest returns a DER OCSP request for c, issued by issuer, with
+// the CertID hashed by h and the nonce extension when nonce is not nil.
+func CreateRequest(c, issuer *cert.Certificate, h HashAlgorithm, nonce []byte) ([]byte, error) {
+	id, err := newCertID(c.SerialNumber, issuer, h)
+	if err != nil {
+		return nil, err
+	}
+	tbs := tbsRequest{RequestList: []request{{Cert: id}}}
+	if nonce != nil {
+		ext, err := nonceExtension(nonce)
+		if err != nil {
+			return nil, err
+		}
+		tbs.RequestExtensions = []pkix.Extension{ext}
+	}
+	return asn1.Marshal(ocspRequest{tbs})
+}
+
+// ParseRequest parses a DER OCSP request for a single certificate. A
+// signature on the request is ignored.
+func ParseRequest(der []byte) (*Request, error) {
+	var req ocspRequest
+	if _, err := asn1.Unmarshal(der, &req); err != nil {
+		return nil, fmt.Errorf("ocsp: malformed request: %v", err)
+	}
+	if len(req.TBSRequest.RequestList) != 1 {
+		return nil, fmt.Errorf("ocsp: request for %d certificates, want 1", len(req.TBSRequest.RequestList))
+	}
+	id := req.TBSRequest.RequestList[0].Cert
+	h, err := hashAlgorithm(id.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	r := &Request{HashAlgorithm: h, IssuerNameHash: id.NameHash, IssuerKeyHash: id.IssuerKeyHash, SerialNumber: id.SerialNumber}
+	if r.Nonce, err = findNonce(req.TBSRequest.RequestExtensions); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func hashAlgorithm(oid asn1.ObjectIdentifier) (HashAlgorithm, error) {
+	for _, h := range hashes {
+		if h.oid.Equal(oid) {
+			return h.alg, nil
+		}
+	}
+	return 0, fmt.Errorf("ocsp: unsupported CertID hash %v", oid)
+}
+
+// newCertID identifies serial under issuer: the hashes of the issuer's
+// DER name and of the public key bits of its SubjectPublicKeyInfo.
+func newCertID(serial *big.Int, issuer *cert.Certificate, alg HashAlgorithm) (certID, error) {
+	var spki struct {
+		Algorithm pkix.AlgorithmIdentifier
+		PublicKey asn1.BitString
+	}
+	if _, err := asn1.Unmarshal(issuer.RawSubjectPublicKeyInfo, &spki); err != nil {
+		return certID{}, fmt.Errorf("ocsp: malformed issuer key: %v", err)
+	}
+	for _, h := range hashes {
+		if h.alg != alg {
+			continue
+		}
+		name, key := h.h(), h.h()
+		name.Write(issuer.RawSubject)
+		key.Write(spki.PublicKey.RightAlign())
+		return certID{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: h.oid, Parameters: asn1.NullRawValue},
+			NameHash:      name.Sum(nil),
+			IssuerKeyHash: key.Sum(nil),
+			SerialNumber:  serial,
+		}, nil
+	}
+	return certID{}, fmt.Errorf("ocsp: unsupported hash algorithm %d", alg)
+}
+
+// matches reports whether id names serial under issuer, whatever hash it
+// uses.
+func (id certID) matches(serial *big.Int, issuer *cert.Certificate) bool {
+	alg, err := hashAlgorithm(id.HashAlgorithm.Algorithm)
+	if err != nil || id.SerialNumber == nil || id.SerialNumber.Cmp(serial) != 0 {
+		return false
+	}
+	want, err := newCertID(serial, issuer, alg)
+	return err == nil && bytes.Equal(id.NameHash, want.NameHash) && bytes.Equal(id.IssuerKeyHash, want.IssuerKeyHash)
+}
+
+// nonceExtension encodes nonce as an OCTET STRING inside the extension
+// value, as RFC 8954 and OpenSSL do.
+func nonceExtension(nonce []byte) (pkix.Extension, error) {
+	v, err := asn1.Marshal(nonce)
+	return pkix.Extension{Id: oidNonce, Value: v}, err
+}
+
+func findNonce(exts []pkix.Extension) ([]byte, error) {
+	for _, ext := range exts {
+		if !ext.Id.Equal(oidNonce) {
+			continue
+		}
+		var nonce []byte
+		if rest, err := asn1.Unmarshal(ext.Value, &nonce); err != nil || len(rest) != 0 {
+			return nil, errors.New("ocsp: malformed nonce")
+		}
+		return nonce, nil
+	}
+	return nil, nil
+}
+
+// Response is the parsed status of one certificate in an OCSP response.
+type Response struct {
+	Status           CertStatus
+	SerialNumber     *big.Int
+	ProducedAt       time.Time
+	ThisUpdate       time.Time
+	NextUpdate       time.Time // zero when absent
+	RevokedAt        time.Time
+	RevocationReason int // a CRLReason code; zero when absent
+	Nonce            []byte
+
+	// Certificates are those included in the response, typically the
+	// delegated responder's.
+	Certificates []*cert.Certificate
+
+	RawResponseData    []byte
+	SignatureAlgorithm asn1.ObjectIdentifier
+	Signature          []byte
+	// ResponderName is the DER name of a responder identified by name;
+	// ResponderKeyHash the SHA-1 of the key of one identified by key.
+	ResponderName    []byte
+	ResponderKeyHash []byte
+}
+
+// ResponseError is returned by ParseResponse for responses whose status
+// is not Successful.
+type ResponseError struct {
+	Status ResponseStatus
+}
+
+var statusText = map[ResponseStatus]string{
+	MalformedRequest: "malformed request",
+	InternalError:    "internal error",
+	TryLater:         "try later",
+	SigRequired:      "signature required",
+	Unauthorized:     "unauthorized",
+}
+
+func (e *ResponseError) Error() string {
+	if text, ok := statusText[e.Status]; ok {
+		return "ocsp: responder reports " + text
+	}
+	return fmt.Sprintf("ocsp: responder reports status %d", e.Status)
+}
+
+// ErrNoStatus is returned by ParseResponse when the response does not
+// cover the certificate.
+var ErrNoStatus = errors.New("ocsp: response does not cover the certificate")
+
+// ParseResponse parses a DER OCSP response and returns the status of c,
+// issued by issuer. It does not check the signature.
+func ParseResponse(der []byte, c, issuer *cert.Certificate) (*Response, error) {
+	var resp ocspResponse
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("ocsp: malformed response: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("ocsp: trailing data after response")
+	}
+	if status := ResponseStatus(resp.Status); status != Successful {
+		return nil, &ResponseError{status}
+	}
+	if !resp.Response.ResponseType.Equal(oidBasicResponse) {
+		return nil, fmt.Errorf("ocsp: unsupported response type %v", resp.Response.ResponseType)
+	}
+	var basic basicResponse
+	if _, err := asn1.Unmarshal(resp.Response.Response, &basic); err != nil {
+		return nil, fmt.Errorf("ocsp: malformed BasicOCSPResponse: %v", err)
+	}
+	var data responseData
+	if _, err := asn1.Unmarshal(basic.TBSResponseData.FullBytes, &data); err != nil {
+		return nil, fmt.Errorf("ocsp: malformed ResponseData: %v", err)
+	}
+
+	r := &Response{
+		ProducedAt:         data.ProducedAt,
+		RawResponseData:    basic.TBSResponseData.FullBytes,
+		SignatureAlgorithm: basic.SignatureAlgorithm.Algorithm,
+		Signature:          basic.Signature.RightAlign(),
+	}
+	switch id := data.ResponderID; {
+	case id.Class == asn1.ClassContextSpecific && id.Tag == responderByName:
+		r.ResponderName = id.Bytes
+	case id.Class == asn1.ClassContextSpecific && id.Tag == responderByKey:
+		if _, err := asn1.Unmarshal(id.Bytes, &r.ResponderKeyHash); err != nil {
+			return nil, errors.New("ocsp: malformed responder key hash")
+		}
+	default:
+		return nil, errors.New("ocsp: malformed ResponderID")
+	}
+	for _, raw := range basic.Certificates {
+		rc, err := cert.Parse(raw.FullBytes)
+		if err != nil {
+			return nil, err
+		}
+		r.Certificates = append(r.Certificates, rc)
+	}
+	if r.Nonce, err = findNonce(data.Extensions); err != nil {
+		return nil, err
+	}
+
+	for _, single := range data.Responses {
+		if !single.CertID.matches(c.SerialNumber, issuer) {
+			continue
+		}
+		r.SerialNumber = single.CertID.SerialNumber
+		r.ThisUpdate, r.NextUpdate = single.ThisUpdate, single.NextUpdate
+		switch {
+		case bool(single.Good):
+			r.Status = Good
+		case bool(single.Unknown):
+			r.Status = Unknown
+		default:
+			r.Status = Revoked
+			r.RevokedAt = single.Revoked.RevocationTime
+			r.RevocationReason = int(single.Revoked.Reason)
+		}
+		return r, nil
+	}
+	return nil, ErrNoStatus
+}
+
+// Errors of Response.Verify.
+var (
+	ErrUnauthorizedResponder = errors.New("ocsp: response is not signed by the issuer or a responder it authorized")
+	ErrExpired               = errors.New("ocsp: response is past its next update")
+	ErrNotYetValid           = errors.New("ocsp: response is not yet valid")
+)
+
+// Verify checks that the response is signed under uid either by issuer or
+// by a delegated responder, included in the response, that issuer
+// certified for OCSPSigning, and that it is current at now. A bad
+// signature is reported as cert.ErrSignature.
+func (r *Response) Verify(issuer *cert.Certificate, now time.Time, uid []byte) error {
+	signer := issuer
+	if !r.identifies(issuer) {
+		signer = nil
+		for _, c := range r.Certificates {
+			if r.identifies(c) {
+				signer = c
+				break
+			}
+		}
+		if signer == nil || !bytes.Equal(signer.RawIssuer, issuer.RawSubject) || issuer.PublicKey == nil ||
+			signer.CheckSignature(issuer.PublicKey, uid) != nil || !allowsOCSPSigning(signer) {
+			return ErrUnauthorizedResponder
+		}
+	}
+	if signer.PublicKey == nil {
+		return ErrUnauthorizedResponder
+	}
+	if !r.SignatureAlgorithm.Equal(cert.OIDSignatureSM2WithSM3) {
+		return fmt.Errorf("ocsp: unsupported signature algorithm %v", r.SignatureAlgorithm)
+	}
+	rs, ss, err := sm2.UnmarshalSignature(r.Signature)
+	if err != nil {
+		return fmt.Errorf("ocsp: malformed signature: %v", err)
+	}
+	if uid == nil {
+		uid = []byte(sm2.DefaultUID)
+	}
+	if !sm2.Verify(signer.PublicKey, r.RawResponseData, uid, rs, ss) {
+		return cert.ErrSignature
+	}
+	if now.Before(r.ThisUpdate) {
+		return ErrNotYetValid
+	}
+	if !r.NextUpdate.IsZero() && now.After(r.NextUpdate) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// identifies reports whether c matches the ResponderID.
+func (r *Response) identifies(c *cert.Certificate) bool {
+	if r.ResponderName != nil {
+		return bytes.Equal(r.ResponderName, c.RawSubject)
+	}
+	if c.PublicKey == nil {
+		return false
+	}
+	sum := sha1.Sum(c.PublicKey.Bytes())
+	return bytes.Equal(r.ResponderKeyHash, sum[:])
+}
+
+func allowsOCSPSigning(c *cert.Certificate) bool {
+	for _, oid := range c.ExtKeyUsage {
+		if oid.Equal(cert.OIDExtKeyUsageOCSPSigning) {
+			return true
+		}
+	}
+	return false
+}
+
+// ResponseTemplate holds the status to be signed into a response.
+type ResponseTemplate struct {
+	Status           CertStatus
+	RevokedAt        time.Time
+	RevocationReason int
+	ThisUpdate       time.Time
+	NextUpdate       time.Time // omitted when zero
+	ProducedAt       time.Time
+
+	// SignerUID is the responder identity in the SM2 signature; nil
+	// selects sm2.DefaultUID.
+	SignerUID []byte
+}
+
+// CreateResponse answers req with a successful response signed by priv.
+// With a nil responder, priv is the key of issuer; otherwise responder is
+// a delegated responder certificate, which is included in the response.
+// The responder is identified by key and the request's nonce is echoed.
+func CreateResponse(rand io.Reader, req *Request, tmpl *ResponseTemplate, issuer, responder *cert.Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	signer := issuer
+	if responder != nil {
+		signer = responder
+	}
+	if signer.PublicKey == nil || signer.PublicKey.X.Cmp(priv.X) != 0 || signer.PublicKey.Y.Cmp(priv.Y) != 0 {
+		return nil, errors.New("ocsp: private key does not match the responder certificate")
+	}
+	id, err := newCertID(req.SerialNumber, issuer, req.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(id.NameHash, req.IssuerNameHash) || !bytes.Equal(id.IssuerKeyHash, req.IssuerKeyHash) {
+		return nil, errors.New("ocsp: request is for a certificate of another issuer")
+	}
+
+	single := singleResponse{
+		CertID:     id,
+		ThisUpdate: tmpl.ThisUpdate.UTC().Truncate(time.Second),
+		NextUpdate: tmpl.NextUpdate.UTC().Truncate(time.Second),
+	}
+	switch tmpl.Status {
+	case Good:
+		single.Good = true
+	case Unknown:
+		single.Unknown = true
+	case Revoked:
+		single.Revoked = revokedInfo{RevocationTime: tmpl.RevokedAt.UTC().Truncate(time.Second), Reason: asn1.Enumerated(tmpl.RevocationReason)}
+	default:
+		return nil, fmt.Errorf("ocsp: invalid certificate status %d", tmpl.Status)
+	}
+
+	keyHash := sha1.Sum(signer.PublicKey.Bytes())
+	keyHashDER, err := asn1.Marshal(keyHash[:])
+	if err != nil {
+		return nil, err
+	}
+	data := responseData{
+		ResponderID: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: responderByKey, IsCompound: true, Bytes: keyHashDER},
+		ProducedAt:  tmpl.ProducedAt.UTC().Truncate(time.Second),
+		Responses:   []singleResponse{single},
+	}
+	if req.Nonce != nil {
+		ext, err := nonceExtension(req.Nonce)
+		if err != nil {
+			return nil, err
+		}
+		data.Extensions = []pkix.Extension{ext}
+	}
+	tbs, err := asn1.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	uid := tmpl.SignerUID
+	if uid == nil {
+		uid = []byte(sm2.DefaultUID)
+	}
+	r, s, err := sm2.Sign(rand, priv, tbs, uid)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sm2.MarshalSignature(r, s)
+	if err != nil {
+		return nil, err
+	}
+	basic := basicResponse{
+		TBSResponseData:    asn1.RawValue{FullBytes: tbs},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: cert.OIDSignatureSM2WithSM3},
+		Signature:          asn1.BitString{Bytes: sig, BitLength: 8 * len(sig)},
+	}
+	if responder != nil {
+		basic.Certificates = []asn1.RawValue{{FullBytes: responder.Raw}}
+	}
+	basicDER, err := asn1.Marshal(basic)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(ocspResponse{Status: asn1.Enumerated(Successful), Response: responseBytes{oidBasicResponse, basicDER}})
+}
+
+// CreateErrorResponse returns an unsuccessful response with status.
+func CreateErrorResponse(status ResponseStatus) ([]byte, error) {
+	if status == Successful {
+		return nil, errors.New("ocsp: an error response needs an unsuccessful status")
+	}
+	return asn1.Marshal(ocspResponse{Status: asn1.Enumerated(status)})
+}
@@ -0,0 +1,196 @@
+package ocsp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// A self-signed SM2 CA certificate (serial 0x1234) written by OpenSSL 3.0.
+const opensslCertPEM = `-----BEGIN CERTIFICATE-----
+MIIBnTCCAUOgAwIBAgICEjQwCgYIKoEcz1UBg3UwLDEVMBMGA1UEAwwMcGtjczcg
+c2lnbmVyMRMwEQYDVQQKDApzbS1iYy10ZXN0MCAXDTI2MTAxNjAwMTU1N1oYDzIx
+MjYwOTIyMDAxNTU3WjAsMRUwEwYDVQQDDAxwa2NzNyBzaWduZXIxEzARBgNVBAoM
+CnNtLWJjLXRlc3QwWTATBgcqhkjOPQIBBggqgRzPVQGCLQNCAARVzuzz4q8nZjhj
+W4VdCYJTeyqbENWOJKn2uhb00fE9qqNiLjEVq2auXh6lH9JukRlDefWxp0Hxrp7E
+S04SBcIfo1MwUTAdBgNVHQ4EFgQUalWAbpKNXCl+OxidSKEPSS+irUkwHwYDVR0j
+BBgwFoAUalWAbpKNXCl+OxidSKEPSS+irUkwDwYDVR0TAQH/BAUwAwEB/zAKBggq
+gRzPVQGDdQNIADBFAiBRyw6JSr05cOKHyWCbP9iXCJ2tvr5DuJsHqrkNu0LKUgIh
+ALQcZgUPrrId1aXODh0OaX0rPBEl/5VDHcpRbiYJKz3n
+-----END CERTIFICATE-----
+`
+
+// The request "openssl ocsp -sm3 -issuer ca.pem -cert ca.pem" wrote for
+// the certificate above, with its nonce.
+const (
+	opensslRequestHex = "308184308181305a30583056300c06082a811ccf5501831105000420e61577d70774289b3b62b408a74897f7df51b67429ed70dd73e27665dd51f9c1042011b1c151eee39baaa5a3876f988d26908054a8d6d305ef73a2e5070c1c39f24602021234a2233021301f06092b060105050730010204120410c89ec0218b1d7fb5cd582fb0d2bfb81f"
+	opensslNonceHex   = "c89ec0218b1d7fb5cd582fb0d2bfb81f"
+)
+
+// The response of "openssl ocsp -index ... -rsigner ca.pem -rmd sm3" to
+// that request: revoked on 2026-01-01 for keyCompromise, responder by
+// name, with the responder certificate included.
+const opensslResponse = `
+MIIDIQoBAKCCAxowggMWBgkrBgEFBQcwAQEEggMHMIIDAzCCAQChLjAsMRUwEwYDVQQDDAxwa2Nz
+NyBzaWduZXIxEzARBgNVBAoMCnNtLWJjLXRlc3QYDzIwMjYxMDE2MDAzODA4WjCBlzCBlDBWMAwG
+CCqBHM9VAYMRBQAEIOYVd9cHdCibO2K0CKdIl/ffUbZ0Ke1w3XPidmXdUfnBBCARscFR7uObqqWj
+h2+YjSaQgFSo1tMF73Oi5QcMHDnyRgICEjShFhgPMjAyNjAxMDEwMDAwMDBaoAMKAQEYDzIwMjYx
+MDE2MDAzODA4WqARGA8yMTI2MDkyMjAwMzgwOFqhIzAhMB8GCSsGAQUFBzABAgQSBBDInsAhix1/
+tc1YL7DSv7gfMAoGCCqBHM9VAYN1A0gAMEUCIBqPOavvhV7n95bH/kPgE7o2CArznjSHJo7VSHTi
+3RYiAiEAkj4LUu0S45nVyI1tagyP/R1K4w2zf6slsLpElxrtxcSgggGlMIIBoTCCAZ0wggFDoAMC
+AQICAhI0MAoGCCqBHM9VAYN1MCwxFTATBgNVBAMMDHBrY3M3IHNpZ25lcjETMBEGA1UECgwKc20t
+YmMtdGVzdDAgFw0yNjEwMTYwMDE1NTdaGA8yMTI2MDkyMjAwMTU1N1owLDEVMBMGA1UEAwwMcGtj
+czcgc2lnbmVyMRMwEQYDVQQKDApzbS1iYy10ZXN0MFkwEwYHKoZIzj0CAQYIKoEcz1UBgi0DQgAE
+Vc7s8+KvJ2Y4Y1uFXQmCU3sqmxDVjiSp9roW9NHxPaqjYi4xFatmrl4epR/SbpEZQ3n1sadB8a6e
+xEtOEgXCH6NTMFEwHQYDVR0OBBYEFGpVgG6SjVwpfjsYnUihD0kvoq1JMB8GA1UdIwQYMBaAFGpV
+gG6SjVwpfjsYnUihD0kvoq1JMA8GA1UdEwEB/wQFMAMBAf8wCgYIKoEcz1UBg3UDSAAwRQIgUcsO
+iUq9OXDih8lgmz/Ylwidrb6+Q7ibB6q5DbtCylICIQC0HGYFD66yHdWlzg4dDml9KzwRJf+VQx3K
+UW4mCSs95w==
+`
+
+func opensslCert(t *testing.T) *cert.Certificate {
+	t.Helper()
+	block, _ := pem.Decode([]byte(opensslCertPEM))
+	c, err := cert.Parse(block.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestRequestOpenSSL(t *testing.T) {
+	ca := opensslCert(t)
+	nonce, _ := hex.DecodeString(opensslNonceHex)
+	der, err := CreateRequest(ca, ca, SM3, nonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(der) != opensslRequestHex {
+		t.Errorf("CreateRequest = %x", der)
+	}
+	want, _ := hex.DecodeString(opensslRequestHex)
+	req, err := ParseRequest(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.HashAlgorithm != SM3 || req.SerialNumber.Int64() != 0x1234 || !bytes.Equal(req.Nonce, nonce) {
+		t.Errorf("ParseRequest = %+v", req)
+	}
+}
+
+func TestResponseOpenSSL(t *testing.T) {
+	ca := opensslCert(t)
+	der, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(opensslResponse, "\n", ""))
+	resp, err := ParseResponse(der, ca, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, _ := hex.DecodeString(opensslNonceHex)
+	if resp.Status != Revoked || resp.RevocationReason != 1 || !resp.RevokedAt.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		!bytes.Equal(resp.Nonce, nonce) || len(resp.Certificates) != 1 || resp.ResponderName == nil {
+		t.Errorf("ParseResponse = %+v", resp)
+	}
+	now := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)
+	// OpenSSL signs with an empty identity.
+	if err := resp.Verify(ca, now, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := resp.Verify(ca, now, nil); err != cert.ErrSignature {
+		t.Errorf("default identity: %v", err)
+	}
+	if err := resp.Verify(ca, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), []byte{}); err != ErrNotYetValid {
+		t.Errorf("before thisUpdate: %v", err)
+	}
+}
+
+func TestCreateResponse(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	newCert := func(tmpl *cert.Template, parent *cert.Certificate, parentKey *sm2.PrivateKey) (*cert.Certificate, *sm2.PrivateKey) {
+		key, _ := sm2.GenerateKey(rand.Reader)
+		if parentKey == nil {
+			parentKey = key
+		}
+		tmpl.NotBefore, tmpl.NotAfter = now.Add(-time.Hour), now.Add(time.Hour)
+		der, err := cert.Create(rand.Reader, tmpl, &key.PublicKey, parent, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, _ := cert.Parse(der)
+		return c, key
+	}
+	ca, caKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "ocsp ca"}, IsCA: true, MaxPathLen: -1}, nil, nil)
+	leaf, _ := newCert(&cert.Template{Subject: pkix.Name{CommonName: "leaf"}, SerialNumber: big.NewInt(42)}, ca, caKey)
+	responder, responderKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "responder"}, ExtKeyUsage: []asn1.ObjectIdentifier{cert.OIDExtKeyUsageOCSPSigning}}, ca, caKey)
+	rogue, rogueKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "rogue"}}, ca, caKey)
+
+	reqDER, err := CreateRequest(leaf, ca, SHA1, []byte("nonce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := ParseRequest(reqDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &ResponseTemplate{Status: Revoked, RevokedAt: now.Add(-time.Minute), RevocationReason: 4, ThisUpdate: now, NextUpdate: now.Add(time.Hour), ProducedAt: now}
+	for _, tc := range []struct {
+		name      string
+		responder *cert.Certificate
+		key       *sm2.PrivateKey
+		want      error
+	}{
+		{"issuer", nil, caKey, nil},
+		{"delegated", responder, responderKey, nil},
+		{"no OCSPSigning", rogue, rogueKey, ErrUnauthorizedResponder},
+	} {
+		der, err := CreateResponse(rand.Reader, req, tmpl, ca, tc.responder, tc.key)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		resp, err := ParseResponse(der, leaf, ca)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if resp.Status != Revoked || resp.RevocationReason != 4 || string(resp.Nonce) != "nonce" || resp.SerialNumber.Int64() != 42 {
+			t.Errorf("%s: %+v", tc.name, resp)
+		}
+		if err := resp.Verify(ca, now, nil); err != tc.want {
+			t.Errorf("%s: Verify = %v, want %v", tc.name, err, tc.want)
+		}
+	}
+
+	der, _ := CreateResponse(rand.Reader, req, &ResponseTemplate{Status: Good, ThisUpdate: now, NextUpdate: now.Add(time.Hour), ProducedAt: now}, ca, nil, caKey)
+	resp, err := ParseResponse(der, leaf, ca)
+	if err != nil || resp.Status != Good {
+		t.Fatalf("good: %+v, %v", resp, err)
+	}
+	if err := resp.Verify(ca, now.Add(2*time.Hour), nil); err != ErrExpired {
+		t.Errorf("after nextUpdate: %v", err)
+	}
+	if _, err := ParseResponse(der, responder, ca); err != ErrNoStatus {
+		t.Errorf("other certificate: %v", err)
+	}
+	if _, err := CreateResponse(rand.Reader, req, tmpl, ca, nil, responderKey); err == nil {
+		t.Error("signed with a key that does not match the issuer")
+	}
+
+	der, err = CreateErrorResponse(TryLater)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var re *ResponseError
+	if _, err := ParseResponse(der, leaf, ca); !errors.As(err, &re) || re.Status != TryLater {
+		t.Errorf("error response: %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+// Package pb encodes and decodes the protocol buffers wire format for the
+// hand-written messages of the gRPC mode: varint and length-delimited
+// fields are produced, and every wire type is parsed so that unknown
+// fields can be skipped.
+//
+// The Append functions follow proto3 and leave out fields that hold their
+// default value.
+package pb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types.
+const (
+	TypeVarint  = 0
+	TypeFixed64 = 1
+	TypeBytes   = 2
+	TypeFixed32 = 5
+)
+
+func appendTag(b []byte, num, typ int) []byte {
+	return binary.AppendUvarint(b, uint64(num)<<3|uint64(typ))
+}
+
+// AppendVarint appends field num as a varint unless v is zero.
+func AppendVarint(b []byte, num int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	return binary.AppendUvarint(appendTag(b, num, TypeVarint), v)
+}
+
+// AppendBool appends field num when v is true.
+func AppendBool(b []byte, num int, v bool) []byte {
+	if !v {
+		return b
+	}
+	return AppendVarint(b, num, 1)
+}
+
+// AppendBytes appends field num unless v is empty.
+func AppendBytes(b []byte, num int, v []byte) []byte {
+	if len(v) == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(appendTag(b, num, TypeBytes), uint64(len(v)))
+	return append(b, v...)
+}
+
+// AppendString appends field num unless s is empty.
+func AppendString(b []byte, num int, s string) []byte {
+	return AppendBytes(b, num, []byte(s))
+}
+
+// Field is one field of a parsed message. Varint holds the value of
+// varint and fixed-size fields, Bytes that of length-delimited ones.
+type Field struct {
+	Num    int
+	Type   int
+	Varint uint64
+	Bytes  []byte
+}
+
+var errTruncated = errors.New("pb: truncated message")
+
+// Parse splits a message into its fields, in wire order. Bytes alias b.
+func Parse(b []byte) ([]Field, error) {
+	var fields []Field
+	for len(b) > 0 {
+		tag, n := binary.Uvarint(b)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		b = b[n:]
+		f := Field{Num: int(tag >> 3), Type: int(tag & 7)}
+		if f.Num == 0 || tag>>3 > 1<<29-1 {
+			return nil, fmt.Errorf("pb: invalid field number %d", tag>>3)
+		}
+		switch f.Type {
+		case TypeVarint:
+			if f.Varint, n = binary.Uvarint(b); n <= 0 {
+				return nil, errTruncated
+			}
+			b = b[n:]
+		case TypeFixed64:
+			if len(b) < 8 {
+				return nil, errTruncated
+			}
+			f.Varint, b = binary.LittleEndian.Uint64(b), b[8:]
+		case TypeFixed32:
+			if len(b) < 4 {
+				return nil, errTruncated
+			}
+			f.Varint, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		case TypeBytes:
+			l, n := binary.Uvarint(b)
+			if n <= 0 || l > uint64(len(b)-n) {
+				return nil, errTruncated
+			}
+			f.Bytes, b = b[n:n+int(l)], b[n+int(l):]
+		default:
+			return nil, fmt.Errorf("pb: unsupported wire type %d", f.Type)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Check reports an error for a field whose wire type is not typ. Fields
+// of the wrong type are malformed rather than unknown.
+func (f Field) Check(typ int) error {
+	if f.Type != typ {
+		return fmt.Errorf("pb: field %d has wire type %d, want %d", f.Num, f.Type, typ)
+	}
+	return nil
+}
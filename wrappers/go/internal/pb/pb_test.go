@@ -0,0 +1,45 @@
+package pb
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+func TestEncoding(t *testing.T) {
+	// The examples of the protocol buffers encoding guide.
+	b := AppendVarint(nil, 1, 150)
+	if got := hex.EncodeToString(b); got != "089601" {
+		t.Errorf("varint 150 = %s", got)
+	}
+	b = AppendString(nil, 2, "testing")
+	if got := hex.EncodeToString(b); got != "120774657374696e67" {
+		t.Errorf("string = %s", got)
+	}
+	// Defaults are left out.
+	if b := AppendBool(AppendBytes(AppendVarint(nil, 1, 0), 2, nil), 3, false); len(b) != 0 {
+		t.Errorf("defaults encoded as %x", b)
+	}
+
+	msg := AppendBool(AppendBytes(AppendVarint(nil, 1, 1<<40), 2, []byte{0, 1}), 300, true)
+	// An unknown fixed32 field and an unknown fixed64 field.
+	msg = append(msg, 0x25, 1, 2, 3, 4, 0x29, 1, 2, 3, 4, 5, 6, 7, 8)
+	fields, err := Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 5 || fields[0].Varint != 1<<40 || !bytes.Equal(fields[1].Bytes, []byte{0, 1}) ||
+		fields[2].Num != 300 || fields[2].Varint != 1 || fields[3].Varint != 0x04030201 || fields[4].Type != TypeFixed64 {
+		t.Fatalf("parsed %+v", fields)
+	}
+	if fields[1].Check(TypeBytes) != nil || fields[1].Check(TypeVarint) == nil {
+		t.Error("Check does not compare wire types")
+	}
+
+	for _, bad := range []string{"08", "0a05ab", "0b", "00", "25aabb", "8080808080808080808001"} {
+		b, _ := hex.DecodeString(bad)
+		if _, err := Parse(b); err == nil {
+			t.Errorf("Parse(%s) succeeded", bad)
+		}
+	}
+}
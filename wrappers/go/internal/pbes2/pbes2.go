@@ -0,0 +1,251 @@
+// Package pbes2 encrypts and decrypts PKCS #8 EncryptedPrivateKeyInfo
+// structures, and the encrypted content of other containers, with the
+// PBES2 scheme of PKCS #5 (RFC 8018): PBKDF2 key derivation followed by a
+// CBC block cipher.
+//
+// The Chinese profile used by GmSSL pairs PBKDF2 with HMAC-SM3 and
+// SM4-CBC; AES-CBC with HMAC-SHA1 or HMAC-SHA256, as written by OpenSSL,
+// is accepted for decryption as well.
+package pbes2
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+var (
+	oidPBES2  = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 13}
+	oidPBKDF2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 5, 12}
+
+	oidHMACWithSHA1   = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 7}
+	oidHMACWithSHA256 = asn1.ObjectIdentifier{1, 2, 840, 113549, 2, 9}
+	oidHMACWithSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401, 2}
+
+	oidSM4CBC    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+	oidAES128CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 2}
+	oidAES192CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 22}
+	oidAES256CBC = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 1, 42}
+)
+
+// Scheme names a cipher and PRF combination for Encrypt.
+type Scheme int
+
+const (
+	SM4CBCWithHMACSM3       Scheme = iota // GmSSL's default
+	AES256CBCWithHMACSHA256               // OpenSSL's default
+)
+
+// Iterations is the PBKDF2 iteration count used by Encrypt.
+const Iterations = 10000
+
+// MaxIterations bounds the iteration count read from encrypted data, which
+// would otherwise let one crafted key keep the CPU busy indefinitely.
+const MaxIterations = 10_000_000
+
+// ErrIterations is an iteration count below 1 or above MaxIterations.
+var ErrIterations = errors.New("pbes2: iteration count out of range")
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type encryptedPrivateKeyInfo struct {
+	Algorithm     asn1.RawValue
+	EncryptedData []byte
+}
+
+type pbes2Params struct {
+	KeyDerivationFunc algorithmIdentifier
+	EncryptionScheme  algorithmIdentifier
+}
+
+type pbkdf2Params struct {
+	Salt           []byte
+	IterationCount int
+	KeyLength      int                 `asn1:"optional"`
+	PRF            algorithmIdentifier `asn1:"optional"`
+}
+
+type cipherSpec struct {
+	oid     asn1.ObjectIdentifier
+	keySize int
+	newFunc func([]byte) (cipher.Block, error)
+}
+
+var ciphers = []cipherSpec{
+	{oidSM4CBC, 16, sm4.NewCipher},
+	{oidAES128CBC, 16, aes.NewCipher},
+	{oidAES192CBC, 24, aes.NewCipher},
+	{oidAES256CBC, 32, aes.NewCipher},
+}
+
+var prfs = []struct {
+	oid asn1.ObjectIdentifier
+	h   func() hash.Hash
+}{
+	{oidHMACWithSM3, sm3.New},
+	{oidHMACWithSHA256, sha256.New},
+	{oidHMACWithSHA1, sha1.New},
+}
+
+// Encrypt wraps the DER PrivateKeyInfo plaintext in an
+// EncryptedPrivateKeyInfo under passphrase, with salt and IV from rand.
+func Encrypt(rand io.Reader, plaintext []byte, passphrase string, scheme Scheme) ([]byte, error) {
+	alg, data, err := EncryptData(rand, plaintext, passphrase, scheme)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(encryptedPrivateKeyInfo{Algorithm: asn1.RawValue{FullBytes: alg}, EncryptedData: data})
+}
+
+// Decrypt returns the DER PrivateKeyInfo inside an EncryptedPrivateKeyInfo.
+func Decrypt(der []byte, passphrase string) ([]byte, error) {
+	var info encryptedPrivateKeyInfo
+	if rest, err := asn1.Unmarshal(der, &info); err != nil || len(rest) != 0 {
+		return nil, errors.New("pbes2: malformed EncryptedPrivateKeyInfo")
+	}
+	return DecryptData(info.Algorithm.FullBytes, info.EncryptedData, passphrase)
+}
+
+// EncryptData encrypts plaintext under passphrase and returns the DER
+// AlgorithmIdentifier of the scheme and the ciphertext, for containers
+// such as PKCS #12 that hold them apart.
+func EncryptData(rand io.Reader, plaintext []byte, passphrase string, scheme Scheme) (algorithm, ciphertext []byte, err error) {
+	c, prfOID := ciphers[0], oidHMACWithSM3
+	if scheme == AES256CBCWithHMACSHA256 {
+		c, prfOID = ciphers[3], oidHMACWithSHA256
+	}
+	salt := make([]byte, 16)
+	iv := make([]byte, 16)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, nil, err
+	}
+	key, err := pbkdf2.Key(prfHash(prfOID), passphrase, salt, Iterations, c.keySize)
+	if err != nil {
+		return nil, nil, err
+	}
+	block, err := c.newFunc(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	pad := block.BlockSize() - len(plaintext)%block.BlockSize()
+	data := append(append([]byte(nil), plaintext...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+
+	kdfParams, err := asn1.Marshal(pbkdf2Params{
+		Salt:           salt,
+		IterationCount: Iterations,
+		PRF:            algorithmIdentifier{Algorithm: prfOID, Parameters: asn1.NullRawValue},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	ivParam, err := asn1.Marshal(iv)
+	if err != nil {
+		return nil, nil, err
+	}
+	params, err := asn1.Marshal(pbes2Params{
+		KeyDerivationFunc: algorithmIdentifier{Algorithm: oidPBKDF2, Parameters: asn1.RawValue{FullBytes: kdfParams}},
+		EncryptionScheme:  algorithmIdentifier{Algorithm: c.oid, Parameters: asn1.RawValue{FullBytes: ivParam}},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	algorithm, err = asn1.Marshal(algorithmIdentifier{Algorithm: oidPBES2, Parameters: asn1.RawValue{FullBytes: params}})
+	return algorithm, data, err
+}
+
+// DecryptData decrypts ciphertext under passphrase with the PBES2 scheme
+// described by the DER AlgorithmIdentifier algorithm.
+func DecryptData(algorithm, ciphertext []byte, passphrase string) ([]byte, error) {
+	var alg algorithmIdentifier
+	if rest, err := asn1.Unmarshal(algorithm, &alg); err != nil || len(rest) != 0 {
+		return nil, errors.New("pbes2: malformed AlgorithmIdentifier")
+	}
+	if !alg.Algorithm.Equal(oidPBES2) {
+		return nil, fmt.Errorf("pbes2: unsupported encryption algorithm %v", alg.Algorithm)
+	}
+	var params pbes2Params
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+		return nil, errors.New("pbes2: malformed PBES2 parameters")
+	}
+	if !params.KeyDerivationFunc.Algorithm.Equal(oidPBKDF2) {
+		return nil, fmt.Errorf("pbes2: unsupported key derivation function %v", params.KeyDerivationFunc.Algorithm)
+	}
+	var kdf pbkdf2Params
+	if _, err := asn1.Unmarshal(params.KeyDerivationFunc.Parameters.FullBytes, &kdf); err != nil {
+		return nil, errors.New("pbes2: malformed PBKDF2 parameters")
+	}
+	prfOID := kdf.PRF.Algorithm
+	if prfOID == nil {
+		prfOID = oidHMACWithSHA1 // the DEFAULT of PBKDF2-params
+	}
+	h := prfHash(prfOID)
+	if h == nil {
+		return nil, fmt.Errorf("pbes2: unsupported PRF %v", prfOID)
+	}
+	var c *cipherSpec
+	for i := range ciphers {
+		if ciphers[i].oid.Equal(params.EncryptionScheme.Algorithm) {
+			c = &ciphers[i]
+		}
+	}
+	if c == nil {
+		return nil, fmt.Errorf("pbes2: unsupported cipher %v", params.EncryptionScheme.Algorithm)
+	}
+	if kdf.KeyLength != 0 && kdf.KeyLength != c.keySize {
+		return nil, errors.New("pbes2: key length does not match the cipher")
+	}
+	var iv []byte
+	if _, err := asn1.Unmarshal(params.EncryptionScheme.Parameters.FullBytes, &iv); err != nil || len(iv) != 16 {
+		return nil, errors.New("pbes2: malformed IV")
+	}
+	if kdf.IterationCount < 1 || kdf.IterationCount > MaxIterations {
+		return nil, fmt.Errorf("%w: %d (maximum %d)", ErrIterations, kdf.IterationCount, MaxIterations)
+	}
+
+	key, err := pbkdf2.Key(h, passphrase, kdf.Salt, kdf.IterationCount, c.keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := c.newFunc(key)
+	if err != nil {
+		return nil, err
+	}
+	data := ciphertext
+	if len(data) == 0 || len(data)%block.BlockSize() != 0 {
+		return nil, errors.New("pbes2: encrypted data is not block aligned")
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, data)
+	pad := int(out[len(out)-1])
+	if pad < 1 || pad > block.BlockSize() || !bytes.Equal(out[len(out)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errors.New("pbes2: decryption failed (wrong passphrase?)")
+	}
+	return out[:len(out)-pad], nil
+}
+
+func prfHash(oid asn1.ObjectIdentifier) func() hash.Hash {
+	for _, p := range prfs {
+		if p.oid.Equal(oid) {
+			return p.h
+		}
+	}
+	return nil
+}
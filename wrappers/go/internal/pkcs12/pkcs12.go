@@ -0,0 +1,468 @@
+// Package pkcs12 writes and reads PKCS #12 (RFC 7292) files holding one
+// SM2 private key and its certificate chain.
+//
+// Files are written the way GmSSL and recent Bouncy Castle releases do:
+// the certificates in an encryptedData and the key in a
+// pkcs8ShroudedKeyBag, both under PBES2 with PBKDF2-HMAC-SM3 and SM4-CBC,
+// and an HMAC-SM3 integrity MAC keyed with the PKCS #12 KDF. The AES and
+// SHA-256 equivalents written by OpenSSL 3 are supported as well.
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"unicode/utf16"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+var (
+	oidData          = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidEncryptedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 6}
+
+	oidKeyBag              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 1}
+	oidPKCS8ShroudedKeyBag = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 2}
+	oidCertBag             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 12, 10, 1, 3}
+	oidX509Certificate     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 22, 1}
+
+	oidFriendlyName = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 20}
+	oidLocalKeyID   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 21}
+
+	oidSM3    = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+	oidSHA256 = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSHA1   = asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}
+)
+
+// macDigests are the MAC hashes accepted by Decode, with their PKCS #12
+// KDF block size v in bytes.
+var macDigests = []struct {
+	oid asn1.ObjectIdentifier
+	h   func() hash.Hash
+	v   int
+}{
+	{oidSM3, sm3.New, 64},
+	{oidSHA256, sha256.New, 64},
+	{oidSHA1, sha1.New, 64},
+}
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type pfxPDU struct {
+	Version  int
+	AuthSafe contentInfo
+	MacData  macData `asn1:"optional"`
+}
+
+// contentInfo holds the [0] EXPLICIT content as a raw value, since
+// encoding/asn1 does not add the explicit tag around a RawValue.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+type macData struct {
+	Mac        digestInfo
+	MacSalt    []byte
+	Iterations int `asn1:"optional,default:1"`
+}
+
+type digestInfo struct {
+	Algorithm algorithmIdentifier
+	Digest    []byte
+}
+
+type encryptedData struct {
+	Version              int
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm asn1.RawValue
+	EncryptedContent           asn1.RawValue `asn1:"optional"`
+}
+
+type safeBag struct {
+	ID         asn1.ObjectIdentifier
+	Value      asn1.RawValue
+	Attributes []attribute `asn1:"set,optional"`
+}
+
+type attribute struct {
+	ID     asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+type certBag struct {
+	ID   asn1.ObjectIdentifier
+	Data asn1.RawValue
+}
+
+func explicit(der []byte) asn1.RawValue {
+	return asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: der}
+}
+
+// unexplicit returns the content of a [0] EXPLICIT value.
+func unexplicit(v asn1.RawValue, what string) ([]byte, error) {
+	if v.Class != asn1.ClassContextSpecific || v.Tag != 0 || !v.IsCompound {
+		return nil, fmt.Errorf("pkcs12: malformed %s", what)
+	}
+	return v.Bytes, nil
+}
+
+// Encode returns a PFX holding key and certs, the end-entity certificate
+// first, protected by password. friendlyName labels the key and the
+// end-entity certificate when it is not empty.
+func Encode(rand io.Reader, key *sm2.PrivateKey, certs [][]byte, password string, scheme pbes2.Scheme, friendlyName string) ([]byte, error) {
+	if len(certs) == 0 {
+		return nil, errors.New("pkcs12: no certificate")
+	}
+	localKeyID := sha1.Sum(certs[0])
+	attrs, err := bagAttributes(localKeyID[:], friendlyName)
+	if err != nil {
+		return nil, err
+	}
+
+	var certBags []safeBag
+	for i, c := range certs {
+		cb, err := asn1.Marshal(certBag{ID: oidX509Certificate, Data: explicit(mustOctets(c))})
+		if err != nil {
+			return nil, err
+		}
+		bag := safeBag{ID: oidCertBag, Value: explicit(cb)}
+		if i == 0 {
+			bag.Attributes = attrs
+		}
+		certBags = append(certBags, bag)
+	}
+	certContents, err := asn1.Marshal(certBags)
+	if err != nil {
+		return nil, err
+	}
+	alg, ct, err := pbes2.EncryptData(rand, certContents, password, scheme)
+	if err != nil {
+		return nil, err
+	}
+	ed, err := asn1.Marshal(encryptedData{EncryptedContentInfo: encryptedContentInfo{
+		ContentType:                oidData,
+		ContentEncryptionAlgorithm: asn1.RawValue{FullBytes: alg},
+		EncryptedContent:           asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: ct},
+	}})
+	if err != nil {
+		return nil, err
+	}
+
+	pkcs8, err := sm2.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, err
+	}
+	shrouded, err := pbes2.Encrypt(rand, pkcs8, password, scheme)
+	if err != nil {
+		return nil, err
+	}
+	keyContents, err := asn1.Marshal([]safeBag{{ID: oidPKCS8ShroudedKeyBag, Value: explicit(shrouded), Attributes: attrs}})
+	if err != nil {
+		return nil, err
+	}
+
+	authSafe, err := asn1.Marshal([]contentInfo{
+		{ContentType: oidEncryptedData, Content: explicit(ed)},
+		{ContentType: oidData, Content: explicit(mustOctets(keyContents))},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	digest, h := oidSM3, sm3.New
+	if scheme == pbes2.AES256CBCWithHMACSHA256 {
+		digest, h = oidSHA256, sha256.New
+	}
+	salt := make([]byte, 16)
+	if _, err := io.ReadFull(rand, salt); err != nil {
+		return nil, err
+	}
+	mac := computeMAC(h, 64, authSafe, salt, pbes2.Iterations, password)
+	return asn1.Marshal(pfxPDU{
+		Version:  3,
+		AuthSafe: contentInfo{ContentType: oidData, Content: explicit(mustOctets(authSafe))},
+		MacData: macData{
+			Mac:        digestInfo{Algorithm: algorithmIdentifier{Algorithm: digest, Parameters: asn1.NullRawValue}, Digest: mac},
+			MacSalt:    salt,
+			Iterations: pbes2.Iterations,
+		},
+	})
+}
+
+// Decode returns the private key, the certificates, the end-entity
+// certificate first, and the friendly name of the key in a PFX.
+func Decode(pfx []byte, password string) (key *sm2.PrivateKey, certs [][]byte, friendlyName string, err error) {
+	var p pfxPDU
+	if rest, err := asn1.Unmarshal(pfx, &p); err != nil || len(rest) != 0 {
+		return nil, nil, "", errors.New("pkcs12: malformed PFX")
+	}
+	if p.Version != 3 {
+		return nil, nil, "", fmt.Errorf("pkcs12: unsupported version %d", p.Version)
+	}
+	if !p.AuthSafe.ContentType.Equal(oidData) {
+		return nil, nil, "", errors.New("pkcs12: only password integrity mode is supported")
+	}
+	authSafe, err := octets(p.AuthSafe.Content, "authSafe")
+	if err != nil {
+		return nil, nil, "", err
+	}
+	if err := checkMAC(p.MacData, authSafe, password); err != nil {
+		return nil, nil, "", err
+	}
+
+	var contents []contentInfo
+	if rest, err := asn1.Unmarshal(authSafe, &contents); err != nil || len(rest) != 0 {
+		return nil, nil, "", errors.New("pkcs12: malformed AuthenticatedSafe")
+	}
+	var bags []safeBag
+	for _, ci := range contents {
+		var safeContents []byte
+		switch {
+		case ci.ContentType.Equal(oidData):
+			if safeContents, err = octets(ci.Content, "data content"); err != nil {
+				return nil, nil, "", err
+			}
+		case ci.ContentType.Equal(oidEncryptedData):
+			if safeContents, err = decryptContent(ci.Content, password); err != nil {
+				return nil, nil, "", err
+			}
+		default:
+			return nil, nil, "", fmt.Errorf("pkcs12: unsupported content type %v", ci.ContentType)
+		}
+		var sc []safeBag
+		if rest, err := asn1.Unmarshal(safeContents, &sc); err != nil || len(rest) != 0 {
+			return nil, nil, "", errors.New("pkcs12: malformed SafeContents")
+		}
+		bags = append(bags, sc...)
+	}
+
+	var keyID []byte
+	for _, bag := range bags {
+		value, err := unexplicit(bag.Value, "SafeBag")
+		if err != nil {
+			return nil, nil, "", err
+		}
+		switch {
+		case bag.ID.Equal(oidKeyBag), bag.ID.Equal(oidPKCS8ShroudedKeyBag):
+			if key != nil {
+				return nil, nil, "", errors.New("pkcs12: more than one private key")
+			}
+			if bag.ID.Equal(oidPKCS8ShroudedKeyBag) {
+				if value, err = pbes2.Decrypt(value, password); err != nil {
+					return nil, nil, "", err
+				}
+			}
+			d, err := sm2.UnmarshalPKCS8PrivateKey(value)
+			if err != nil {
+				return nil, nil, "", err
+			}
+			if key, err = sm2.NewPrivateKey(d); err != nil {
+				return nil, nil, "", err
+			}
+			keyID, friendlyName, err = parseAttributes(bag.Attributes)
+			if err != nil {
+				return nil, nil, "", err
+			}
+		case bag.ID.Equal(oidCertBag):
+			var cb certBag
+			if rest, err := asn1.Unmarshal(value, &cb); err != nil || len(rest) != 0 {
+				return nil, nil, "", errors.New("pkcs12: malformed CertBag")
+			}
+			if !cb.ID.Equal(oidX509Certificate) {
+				continue
+			}
+			der, err := octets(cb.Data, "CertBag")
+			if err != nil {
+				return nil, nil, "", err
+			}
+			certs = append(certs, der)
+		}
+	}
+	if key == nil {
+		return nil, nil, "", errors.New("pkcs12: no private key")
+	}
+	return key, leafFirst(certs, keyID), friendlyName, nil
+}
+
+// leafFirst moves the certificate whose SHA-1 is the key's localKeyId to
+// the front; files written by other tools need not order their bags.
+func leafFirst(certs [][]byte, keyID []byte) [][]byte {
+	for i, c := range certs {
+		if sum := sha1.Sum(c); keyID != nil && bytes.Equal(sum[:], keyID) {
+			return append([][]byte{c}, append(certs[:i:i], certs[i+1:]...)...)
+		}
+	}
+	return certs
+}
+
+func decryptContent(v asn1.RawValue, password string) ([]byte, error) {
+	der, err := unexplicit(v, "encryptedData")
+	if err != nil {
+		return nil, err
+	}
+	var ed encryptedData
+	if rest, err := asn1.Unmarshal(der, &ed); err != nil || len(rest) != 0 {
+		return nil, errors.New("pkcs12: malformed EncryptedData")
+	}
+	ct := ed.EncryptedContentInfo.EncryptedContent
+	if ct.Class != asn1.ClassContextSpecific || ct.Tag != 0 || ct.IsCompound {
+		return nil, errors.New("pkcs12: EncryptedData has no primitive encrypted content")
+	}
+	return pbes2.DecryptData(ed.EncryptedContentInfo.ContentEncryptionAlgorithm.FullBytes, ct.Bytes, password)
+}
+
+func checkMAC(m macData, authSafe []byte, password string) error {
+	if m.Mac.Algorithm.Algorithm == nil {
+		return errors.New("pkcs12: missing MAC")
+	}
+	for _, d := range macDigests {
+		if d.oid.Equal(m.Mac.Algorithm.Algorithm) {
+			if m.Iterations < 1 || m.Iterations > pbes2.MaxIterations {
+				return fmt.Errorf("pkcs12: MAC %w: %d (maximum %d)", pbes2.ErrIterations, m.Iterations, pbes2.MaxIterations)
+			}
+			mac := computeMAC(d.h, d.v, authSafe, m.MacSalt, m.Iterations, password)
+			if !hmac.Equal(mac, m.Mac.Digest) {
+				return errors.New("pkcs12: MAC verification failed (wrong password?)")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("pkcs12: unsupported MAC algorithm %v", m.Mac.Algorithm.Algorithm)
+}
+
+func computeMAC(h func() hash.Hash, v int, message, salt []byte, iterations int, password string) []byte {
+	key := deriveKey(h, v, 3, salt, bmpPassword(password), iterations, h().Size())
+	mac := hmac.New(h, key)
+	mac.Write(message)
+	return mac.Sum(nil)
+}
+
+// deriveKey is the PKCS #12 key derivation function of RFC 7292
+// Appendix B.2, with ID the purpose byte (3 for MAC keys) and v the
+// block size of h in bytes.
+func deriveKey(h func() hash.Hash, v int, id byte, salt, password []byte, iterations, n int) []byte {
+	d := bytes.Repeat([]byte{id}, v)
+	fill := func(b []byte) []byte {
+		if len(b) == 0 {
+			return nil
+		}
+		out := make([]byte, v*((len(b)+v-1)/v))
+		for i := range out {
+			out[i] = b[i%len(b)]
+		}
+		return out
+	}
+	i := append(fill(salt), fill(password)...)
+
+	var out []byte
+	for len(out) < n {
+		a := h()
+		a.Write(d)
+		a.Write(i)
+		sum := a.Sum(nil)
+		for r := 1; r < iterations; r++ {
+			a.Reset()
+			a.Write(sum)
+			sum = a.Sum(nil)
+		}
+		out = append(out, sum...)
+
+		// I_j = (I_j + B + 1) mod 2^(8v) for each v-byte block of I.
+		b := fill(sum)
+		for j := 0; j < len(i); j += v {
+			carry := 1
+			for k := v - 1; k >= 0; k-- {
+				carry += int(i[j+k]) + int(b[k])
+				i[j+k] = byte(carry)
+				carry >>= 8
+			}
+		}
+	}
+	return out[:n]
+}
+
+// bmpPassword is the password as a null-terminated BMPString.
+func bmpPassword(password string) []byte {
+	return append(bmpString(password), 0, 0)
+}
+
+func bmpString(s string) []byte {
+	var out []byte
+	for _, r := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(r>>8), byte(r))
+	}
+	return out
+}
+
+func bagAttributes(localKeyID []byte, friendlyName string) ([]attribute, error) {
+	id, err := asn1.Marshal(localKeyID)
+	if err != nil {
+		return nil, err
+	}
+	attrs := []attribute{{ID: oidLocalKeyID, Values: []asn1.RawValue{{FullBytes: id}}}}
+	if friendlyName != "" {
+		name := asn1.RawValue{Tag: asn1.TagBMPString, Bytes: bmpString(friendlyName)}
+		attrs = append(attrs, attribute{ID: oidFriendlyName, Values: []asn1.RawValue{name}})
+	}
+	return attrs, nil
+}
+
+func parseAttributes(attrs []attribute) (localKeyID []byte, friendlyName string, err error) {
+	for _, a := range attrs {
+		if len(a.Values) != 1 {
+			continue
+		}
+		switch v := a.Values[0]; {
+		case a.ID.Equal(oidLocalKeyID):
+			if v.Tag != asn1.TagOctetString || v.Class != asn1.ClassUniversal {
+				return nil, "", errors.New("pkcs12: malformed localKeyId")
+			}
+			localKeyID = v.Bytes
+		case a.ID.Equal(oidFriendlyName):
+			if v.Tag != asn1.TagBMPString || v.Class != asn1.ClassUniversal || len(v.Bytes)%2 != 0 {
+				return nil, "", errors.New("pkcs12: malformed friendlyName")
+			}
+			u := make([]uint16, len(v.Bytes)/2)
+			for i := range u {
+				u[i] = uint16(v.Bytes[2*i])<<8 | uint16(v.Bytes[2*i+1])
+			}
+			friendlyName = string(utf16.Decode(u))
+		}
+	}
+	return localKeyID, friendlyName, nil
+}
+
+// mustOctets returns the DER OCTET STRING holding b.
+func mustOctets(b []byte) []byte {
+	der, _ := asn1.Marshal(b)
+	return der
+}
+
+// octets returns the bytes of the OCTET STRING inside a [0] EXPLICIT value.
+func octets(v asn1.RawValue, what string) ([]byte, error) {
+	der, err := unexplicit(v, what)
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	if rest, err := asn1.Unmarshal(der, &b); err != nil || len(rest) != 0 {
+		return nil, fmt.Errorf("pkcs12: malformed %s", what)
+	}
+	return b, nil
+}
@@ -0,0 +1,137 @@
+package pkcs12
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/asn1"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// opensslPFX was written by OpenSSL 3.0 with
+//
+//	openssl pkcs12 -export -name "pkcs12 test" -keypbe SM4-CBC -certpbe SM4-CBC -macalg sm3 -passout pass:secret
+//
+// for a self-signed SM2 certificate and its key.
+const opensslPFX = `
+MIIEgAIBAzCCBDcGCSqGSIb3DQEHAaCCBCgEggQkMIIEIDCCArEGCSqGSIb3DQEHBqCCAqIwggKe
+AgEAMIIClwYJKoZIhvcNAQcBMFYGCSqGSIb3DQEFDTBJMCkGCSqGSIb3DQEFDDAcBAj5A7sxrB1v
+yAICCAAwDAYIKoZIhvcNAgkFADAcBggqgRzPVQFoAgQQtTjdCI2TNXcFQN4fAbX2C4CCAjDBNr50
+H7rCtCvC1dlJ+sU4YD7YIGPrSH/X5JNx+y2XojdUHicaUSUW7O7GFzwhtYlswHBeXLf5S6NbSgL+
+rWR66HKDQjKKU4ZloWZjjUqGlHaNVvDvqRQx+Ode9BKp14RH6ePLdBlWSQ+Ox1sp8a+PQLxqnB/O
+1NiJ4/g+ijzaQAkIBva1fAB5V7FQKqbSlGFm8gvQMqfF7E7oeI44rYJv5AVvCdkhJmJPLeopm6bb
+xcb0yHoSFI5m0Jg1LVP5pNFZqixh+8jLVuMzF7pbflfqi6aGVI7/mXbaizXRHrxxmF7zM1+WnFfI
+hXZAL7D3XOH9nGHE9a788Uwo8rTyQkDiVsVga3zSPkfmv+L1eAttmPVzODry84NYrMHUQ3uffUYh
+N6uSfReSEtNA8+ajfG47p4YALAI0HHsdstEF4pxSJaZCcnSrMaZqTlcK78D4yehG6OWT91Ha3dSO
+NfUXYejUct9g09cB2/Ss6/f3EJSKAIC/M8sPj/Jop71rGUP0Fp6Ji1oMZ8TMpBrQWtTYGLtk7tOH
+y3GOpQtvRe47yZydd9P4Tsn1ZauUg9oDxwGdvlBjLHAyI9hnrVvR+TzFsobm9fU4WCaqm9cEvwsA
+JnJGdNCPeQJLi4NYbv6gYkbdXJ82CWmFOeY/nhMuSmxRBqUqusUVA1hr/g5SM3ceJftN1ACcu2+I
+vK8JFstAUetQbF6axbV6lsgVx1XMgfxgdlcz23uf6I+mp7XHfigrL5lUkTCCAWcGCSqGSIb3DQEH
+AaCCAVgEggFUMIIBUDCCAUwGCyqGSIb3DQEMCgECoIHuMIHrMFYGCSqGSIb3DQEFDTBJMCkGCSqG
+SIb3DQEFDDAcBAhUSnK/8oxxfwICCAAwDAYIKoZIhvcNAgkFADAcBggqgRzPVQFoAgQQTaE5ckB6
+4ph3E540ToQL7QSBkCu/dInAaIj3vrr8a4VU6fMttFvUhDHY9jjVq3WMEkUuUZZLh1OBJCr/QfVi
+LIbWLWtpbf8V08s1t8CGrEV4vTquT27Xb/ZhU3t7EMTpuAmRDgC4OPSBVwY8lIK9HwTdGyQUarig
+1M1RuuXe4OFAO8LaYsRqEd76k/35x2pkOgGc4vAC70O/8yXu+7/53N3LiTFMMCMGCSqGSIb3DQEJ
+FTEWBBSluiVEYH+97yqP1mDOBZymcDexVzAlBgkqhkiG9w0BCRQxGB4WAHAAawBjAHMAMQAyACAA
+dABlAHMAdDBAMDAwDAYIKoEcz1UBgxEFAAQgEHKCGJdW/IofMHeXhkp3nmqNkQCvkHqNs4d4vAAV
+8gwECA4fbbi9TJKPAgIIAA==
+`
+
+func TestDecodeOpenSSL(t *testing.T) {
+	der, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(opensslPFX, "\n", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, certs, name, err := Decode(der, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "pkcs12 test" || len(certs) != 1 {
+		t.Fatalf("friendlyName %q, %d certificates", name, len(certs))
+	}
+	c, err := cert.Parse(certs[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(c.PublicKey.Bytes(), key.PublicKey.Bytes()) {
+		t.Error("key does not match the certificate")
+	}
+	if _, _, _, err := Decode(der, "wrong"); err == nil {
+		t.Error("decoded with the wrong password")
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	ca, _ := sm2.GenerateKey(rand.Reader)
+	leaf, _ := sm2.GenerateKey(rand.Reader)
+	now := time.Now()
+	tmpl := &cert.Template{NotBefore: now, NotAfter: now.Add(time.Hour), IsCA: true}
+	tmpl.Subject.CommonName = "pkcs12 ca"
+	caDER, err := cert.Create(rand.Reader, tmpl, &ca.PublicKey, nil, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caCert, _ := cert.Parse(caDER)
+	tmpl = &cert.Template{NotBefore: now, NotAfter: now.Add(time.Hour)}
+	tmpl.Subject.CommonName = "pkcs12 leaf"
+	leafDER, err := cert.Create(rand.Reader, tmpl, &leaf.PublicKey, caCert, ca)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, scheme := range []pbes2.Scheme{pbes2.SM4CBCWithHMACSM3, pbes2.AES256CBCWithHMACSHA256} {
+		pfx, err := Encode(rand.Reader, leaf, [][]byte{leafDER, caDER}, "p@ss", scheme, "名称")
+		if err != nil {
+			t.Fatal(err)
+		}
+		key, certs, name, err := Decode(pfx, "p@ss")
+		if err != nil {
+			t.Fatalf("scheme %d: %v", scheme, err)
+		}
+		if !bytes.Equal(key.Bytes(), leaf.Bytes()) || name != "名称" {
+			t.Errorf("scheme %d: key or friendlyName %q changed", scheme, name)
+		}
+		if len(certs) != 2 || !bytes.Equal(certs[0], leafDER) || !bytes.Equal(certs[1], caDER) {
+			t.Errorf("scheme %d: certificates not returned leaf first", scheme)
+		}
+		pfx[len(pfx)-40] ^= 1
+		if _, _, _, err := Decode(pfx, "p@ss"); err == nil {
+			t.Errorf("scheme %d: decoded a tampered PFX", scheme)
+		}
+	}
+}
+
+func TestIterationLimit(t *testing.T) {
+	key, _ := sm2.GenerateKey(rand.Reader)
+	now := time.Now()
+	tmpl := &cert.Template{NotBefore: now, NotAfter: now.Add(time.Hour)}
+	der, err := cert.Create(rand.Reader, tmpl, &key.PublicKey, nil, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pfx, err := Encode(rand.Reader, key, [][]byte{der}, "p@ss", pbes2.SM4CBCWithHMACSM3, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p pfxPDU
+	if _, err := asn1.Unmarshal(pfx, &p); err != nil {
+		t.Fatal(err)
+	}
+	for _, n := range []int{0, pbes2.MaxIterations + 1, 1<<31 - 1} {
+		p.MacData.Iterations = n
+		der, err := asn1.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, _, _, err := Decode(der, "p@ss"); !errors.Is(err, pbes2.ErrIterations) {
+			t.Errorf("MAC iteration count %d: err = %v, want pbes2.ErrIterations", n, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package pkcs7
+
+import (
+	"encoding/asn1"
+	"fmt"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// certificate holds the fields of an X.509 certificate that SignedData
+// needs: the issuer and serial number naming the signer, and its key.
+type certificate struct {
+	TBS                tbsCertificate
+	SignatureAlgorithm algorithmIdentifier
+	Signature          asn1.BitString
+}
+
+type tbsCertificate struct {
+	Version            int `asn1:"optional,explicit,default:0,tag:0"`
+	SerialNumber       *big.Int
+	SignatureAlgorithm algorithmIdentifier
+	Issuer             asn1.RawValue
+	Validity           asn1.RawValue
+	Subject            asn1.RawValue
+	PublicKey          asn1.RawValue
+	IssuerUniqueID     asn1.BitString `asn1:"optional,tag:1"`
+	SubjectUniqueID    asn1.BitString `asn1:"optional,tag:2"`
+	Extensions         asn1.RawValue  `asn1:"optional,explicit,tag:3"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+func parseCertificate(der []byte) (*certificate, error) {
+	var c certificate
+	rest, err := asn1.Unmarshal(der, &c)
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7: malformed certificate: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("pkcs7: trailing data after certificate")
+	}
+	return &c, nil
+}
+
+func (c *certificate) publicKey() (*sm2.PublicKey, error) {
+	point, err := sm2.UnmarshalPKIXPublicKey(c.TBS.PublicKey.FullBytes)
+	if err != nil {
+		return nil, err
+	}
+	return sm2.ParsePublicKey(point)
+}
+
+func (c *certificate) issuerAndSerial() issuerAndSerialNumber {
+	return issuerAndSerialNumber{Issuer: c.TBS.Issuer, SerialNumber: c.TBS.SerialNumber}
+}
+
+// CertificatePublicKey returns the SM2 public key of a DER certificate.
+func CertificatePublicKey(der []byte) (*sm2.PublicKey, error) {
+	c, err := parseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return c.publicKey()
+}
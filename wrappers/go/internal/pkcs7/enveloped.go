@@ -0,0 +1,325 @@
+package pkcs7
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// ContentCipher selects the content encryption algorithm of Encrypt.
+type ContentCipher int
+
+const (
+	SM4GCM ContentCipher = iota // 12-byte nonce, 16-byte tag
+	SM4CBC                      // 16-byte IV, PKCS #7 padding
+)
+
+// Profile selects the object identifiers and containers of Encrypt and
+// Sign.
+type Profile int
+
+const (
+	// ProfileGM is GM/T 0010: EnvelopedData under the GM content types,
+	// with the GCM tag appended to the encrypted content, and SignedData
+	// signed with sm2Sign.
+	ProfileGM Profile = iota
+	// ProfileCMS is RFC 5652 EnvelopedData for CBC and RFC 5083
+	// AuthEnvelopedData for GCM, under the PKCS #7 content types, and
+	// SignedData signed with SM2-with-SM3.
+	ProfileCMS
+)
+
+const (
+	gcmNonceSize = 12
+	gcmTagSize   = 16
+)
+
+type envelopedData struct {
+	Version              int
+	RecipientInfos       []keyTransRecipientInfo `asn1:"set"`
+	EncryptedContentInfo encryptedContentInfo
+}
+
+type authEnvelopedData struct {
+	Version                  int
+	RecipientInfos           []keyTransRecipientInfo `asn1:"set"`
+	AuthEncryptedContentInfo encryptedContentInfo
+	MAC                      []byte
+}
+
+// keyTransRecipientInfo names the recipient either by
+// IssuerAndSerialNumber (version 0) or by [0] SubjectKeyIdentifier
+// (version 2).
+type keyTransRecipientInfo struct {
+	Version                int
+	RID                    asn1.RawValue
+	KeyEncryptionAlgorithm algorithmIdentifier
+	EncryptedKey           []byte
+}
+
+type encryptedContentInfo struct {
+	ContentType                asn1.ObjectIdentifier
+	ContentEncryptionAlgorithm algorithmIdentifier
+	EncryptedContent           []byte `asn1:"tag:0,optional"`
+}
+
+// gcmParameters follows RFC 5084.
+type gcmParameters struct {
+	Nonce  []byte
+	ICVLen int `asn1:"default:12"`
+}
+
+// SubjectKeyID identifies an SM2 recipient without a certificate: the SM3
+// digest of its uncompressed public key.
+func SubjectKeyID(pub *sm2.PublicKey) []byte {
+	id := sm3.Sum(pub.Bytes())
+	return id[:]
+}
+
+// Recipient is the holder of the SM2 key that the content key is
+// encrypted to. With Certificate, its DER X.509 certificate, the
+// recipient is named by issuer and serial number; otherwise by
+// SubjectKeyID.
+type Recipient struct {
+	Key         *sm2.PublicKey
+	Certificate []byte
+}
+
+func (r Recipient) info(encryptedKey []byte) (keyTransRecipientInfo, error) {
+	ri := keyTransRecipientInfo{
+		Version:                2,
+		RID:                    asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: SubjectKeyID(r.Key)},
+		KeyEncryptionAlgorithm: algorithmIdentifier{Algorithm: oidSM2Encrypt},
+		EncryptedKey:           encryptedKey,
+	}
+	if r.Certificate == nil {
+		return ri, nil
+	}
+	cert, err := parseCertificate(r.Certificate)
+	if err != nil {
+		return ri, err
+	}
+	pub, err := cert.publicKey()
+	if err != nil {
+		return ri, err
+	}
+	if pub.X.Cmp(r.Key.X) != 0 || pub.Y.Cmp(r.Key.Y) != 0 {
+		return ri, errors.New("pkcs7: certificate does not belong to the recipient key")
+	}
+	ias, err := asn1.Marshal(cert.issuerAndSerial())
+	if err != nil {
+		return ri, err
+	}
+	ri.Version = 0
+	ri.RID = asn1.RawValue{FullBytes: ias}
+	return ri, nil
+}
+
+// Encrypt returns a ContentInfo holding the content encrypted for the
+// recipient. The SM4 session key is drawn from rand and encrypted with SM2
+// as a GM/T 0009 SM2Cipher.
+func Encrypt(rand io.Reader, content []byte, recipient Recipient, cc ContentCipher, profile Profile) ([]byte, error) {
+	key := make([]byte, sm4.KeySize)
+	if _, err := io.ReadFull(rand, key); err != nil {
+		return nil, err
+	}
+	ct, err := sm2.Encrypt(rand, recipient.Key, key)
+	if err != nil {
+		return nil, err
+	}
+	encryptedKey, err := sm2.MarshalCiphertext(ct)
+	if err != nil {
+		return nil, err
+	}
+	ri, err := recipient.info(encryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	block, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	dataType := OIDData
+	if profile == ProfileCMS {
+		dataType = oidCMSData
+	}
+
+	var alg algorithmIdentifier
+	var encrypted, tag []byte
+	switch cc {
+	case SM4GCM:
+		nonce := make([]byte, gcmNonceSize)
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		params, err := asn1.Marshal(gcmParameters{Nonce: nonce, ICVLen: gcmTagSize})
+		if err != nil {
+			return nil, err
+		}
+		alg = algorithmIdentifier{Algorithm: oidSM4GCM, Parameters: asn1.RawValue{FullBytes: params}}
+		encrypted = aead.Seal(nil, nonce, content, nil)
+		if profile == ProfileCMS {
+			encrypted, tag = encrypted[:len(content)], encrypted[len(content):]
+		}
+	case SM4CBC:
+		iv := make([]byte, sm4.BlockSize)
+		if _, err := io.ReadFull(rand, iv); err != nil {
+			return nil, err
+		}
+		params, err := asn1.Marshal(iv)
+		if err != nil {
+			return nil, err
+		}
+		alg = algorithmIdentifier{Algorithm: oidSM4CBC, Parameters: asn1.RawValue{FullBytes: params}}
+		pad := sm4.BlockSize - len(content)%sm4.BlockSize
+		encrypted = append(append([]byte{}, content...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(encrypted, encrypted)
+	default:
+		return nil, fmt.Errorf("pkcs7: unknown content cipher %d", cc)
+	}
+	eci := encryptedContentInfo{ContentType: dataType, ContentEncryptionAlgorithm: alg, EncryptedContent: encrypted}
+
+	if tag != nil {
+		der, err := asn1.Marshal(authEnvelopedData{
+			RecipientInfos:           []keyTransRecipientInfo{ri},
+			AuthEncryptedContentInfo: eci,
+			MAC:                      tag,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return wrapContent(oidCMSAuthEnvelopedData, der)
+	}
+	// CMS sets the version to 2 when a recipient is named by subject key
+	// identifier, and to 0 otherwise.
+	der, err := asn1.Marshal(envelopedData{
+		Version:              ri.Version,
+		RecipientInfos:       []keyTransRecipientInfo{ri},
+		EncryptedContentInfo: eci,
+	})
+	if err != nil {
+		return nil, err
+	}
+	contentType := OIDEnvelopedData
+	if profile == ProfileCMS {
+		contentType = oidCMSEnvelopedData
+	}
+	return wrapContent(contentType, der)
+}
+
+// Decrypt opens GM/T 0010 or CMS EnvelopedData, or CMS AuthEnvelopedData,
+// as produced by Encrypt or another implementation. A recipient named by
+// subject key identifier must match priv; one named by issuer and serial
+// number is tried, since the SM2 check value rejects the wrong key.
+func Decrypt(der []byte, priv *sm2.PrivateKey) ([]byte, error) {
+	contentType, inner, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	var recipients []keyTransRecipientInfo
+	var eci encryptedContentInfo
+	var tag []byte
+	switch {
+	case contentType.Equal(OIDEnvelopedData), contentType.Equal(oidCMSEnvelopedData):
+		var env envelopedData
+		if _, err := asn1.Unmarshal(inner, &env); err != nil {
+			return nil, fmt.Errorf("pkcs7: malformed EnvelopedData: %v", err)
+		}
+		recipients, eci = env.RecipientInfos, env.EncryptedContentInfo
+	case contentType.Equal(oidCMSAuthEnvelopedData):
+		var env authEnvelopedData
+		if _, err := asn1.Unmarshal(inner, &env); err != nil {
+			return nil, fmt.Errorf("pkcs7: malformed AuthEnvelopedData: %v", err)
+		}
+		recipients, eci, tag = env.RecipientInfos, env.AuthEncryptedContentInfo, env.MAC
+	default:
+		return nil, fmt.Errorf("pkcs7: content type %v is not enveloped data", contentType)
+	}
+
+	block, err := contentKey(recipients, priv)
+	if err != nil {
+		return nil, err
+	}
+	alg := eci.ContentEncryptionAlgorithm
+	switch {
+	case alg.Algorithm.Equal(oidSM4GCM):
+		var params gcmParameters
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &params); err != nil {
+			return nil, fmt.Errorf("pkcs7: malformed GCM parameters: %v", err)
+		}
+		var aead cipher.AEAD
+		switch {
+		case len(params.Nonce) == gcmNonceSize:
+			aead, err = cipher.NewGCMWithTagSize(block, params.ICVLen)
+		case params.ICVLen == gcmTagSize:
+			aead, err = cipher.NewGCMWithNonceSize(block, len(params.Nonce))
+		default:
+			err = errors.New("non-standard nonce and tag sizes")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("pkcs7: unsupported GCM parameters: %v", err)
+		}
+		sealed := append(append([]byte{}, eci.EncryptedContent...), tag...)
+		pt, err := aead.Open(nil, params.Nonce, sealed, nil)
+		if err != nil {
+			return nil, errors.New("pkcs7: content authentication failed")
+		}
+		return pt, nil
+	case alg.Algorithm.Equal(oidSM4CBC) && tag == nil:
+		var iv []byte
+		if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &iv); err != nil || len(iv) != sm4.BlockSize {
+			return nil, errors.New("pkcs7: malformed SM4-CBC IV")
+		}
+		ct := eci.EncryptedContent
+		if len(ct) == 0 || len(ct)%sm4.BlockSize != 0 {
+			return nil, errors.New("pkcs7: encrypted content is not block aligned")
+		}
+		pt := make([]byte, len(ct))
+		cipher.NewCBCDecrypter(block, iv).CryptBlocks(pt, ct)
+		pad := int(pt[len(pt)-1])
+		if pad == 0 || pad > sm4.BlockSize || !bytes.Equal(pt[len(pt)-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+			return nil, errors.New("pkcs7: bad content padding")
+		}
+		return pt[:len(pt)-pad], nil
+	}
+	return nil, fmt.Errorf("pkcs7: unsupported content encryption algorithm %v", alg.Algorithm)
+}
+
+// contentKey decrypts the SM4 content key from the recipient matching
+// priv.
+func contentKey(recipients []keyTransRecipientInfo, priv *sm2.PrivateKey) (cipher.Block, error) {
+	ski := SubjectKeyID(&priv.PublicKey)
+	for _, ri := range recipients {
+		if !ri.KeyEncryptionAlgorithm.Algorithm.Equal(oidSM2Encrypt) {
+			continue
+		}
+		if ri.RID.Class == asn1.ClassContextSpecific && !bytes.Equal(ri.RID.Bytes, ski) {
+			continue
+		}
+		ct, err := sm2.UnmarshalCiphertext(ri.EncryptedKey)
+		if err != nil {
+			continue
+		}
+		key, err := sm2.Decrypt(priv, ct)
+		if err != nil {
+			continue
+		}
+		block, err := sm4.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("pkcs7: content key: %v", err)
+		}
+		return block, nil
+	}
+	return nil, errors.New("pkcs7: no recipient matches the private key")
+}
@@ -0,0 +1,85 @@
+// Package pkcs7 builds and parses the SM2 cryptographic message syntax of
+// GM/T 0010, the Chinese profile of PKCS #7: SM2 for signatures and key
+// transport, SM3 for digests and SM4 for content encryption, identified by
+// the GM object identifiers. Signed and enveloped data can also be written
+// under the standard CMS content types.
+package pkcs7
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+// Content types of GM/T 0010 (1.2.156.10197.6.1.4.2.*).
+var (
+	OIDData          = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 1}
+	OIDSignedData    = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 2}
+	OIDEnvelopedData = asn1.ObjectIdentifier{1, 2, 156, 10197, 6, 1, 4, 2, 3}
+)
+
+// Content types of RFC 5652 and RFC 5083.
+var (
+	oidCMSData              = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidCMSSignedData        = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidCMSEnvelopedData     = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 3}
+	oidCMSAuthEnvelopedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 23}
+)
+
+// Algorithm identifiers.
+var (
+	oidSM2Encrypt = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 3}
+	oidSM4CBC     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 2}
+	oidSM4GCM     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 104, 8}
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+// contentInfo holds the [0] EXPLICIT content as a raw value, since
+// encoding/asn1 does not add the explicit tag around a RawValue.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"optional"`
+}
+
+// wrapContent returns ContentInfo { contentType, [0] EXPLICIT content }
+// for the DER encoded content.
+func wrapContent(contentType asn1.ObjectIdentifier, content []byte) ([]byte, error) {
+	return asn1.Marshal(contentInfo{
+		ContentType: contentType,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: content},
+	})
+}
+
+// parseContentInfo returns the content type and DER encoded content of a
+// ContentInfo.
+func parseContentInfo(der []byte) (asn1.ObjectIdentifier, []byte, error) {
+	var ci contentInfo
+	rest, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, nil, fmt.Errorf("pkcs7: malformed ContentInfo: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("pkcs7: trailing data after ContentInfo")
+	}
+	if ci.Content.Class != asn1.ClassContextSpecific || ci.Content.Tag != 0 || len(ci.Content.Bytes) == 0 {
+		return nil, nil, errors.New("pkcs7: ContentInfo has no content")
+	}
+	return ci.ContentType, ci.Content.Bytes, nil
+}
+
+// unwrapContent parses a ContentInfo of the expected type and returns the
+// DER encoded content.
+func unwrapContent(der []byte, contentType asn1.ObjectIdentifier) ([]byte, error) {
+	ct, content, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !ct.Equal(contentType) {
+		return nil, fmt.Errorf("pkcs7: content type %v, want %v", ct, contentType)
+	}
+	return content, nil
+}
@@ -0,0 +1,423 @@
+package pkcs7
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+var (
+	oidSM3     = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}
+	oidSM2Sign = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 1}
+	// oidSM2WithSM3 is the signature algorithm of ProfileCMS; both are
+	// accepted on input.
+	oidSM2WithSM3 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 501}
+)
+
+// Attribute types of RFC 5652, section 11.
+var (
+	OIDAttributeContentType   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	OIDAttributeMessageDigest = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+)
+
+// ErrSignature reports a SignedData whose signature or message digest
+// does not match, as opposed to one that cannot be parsed.
+var ErrSignature = errors.New("pkcs7: signature does not verify")
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []algorithmIdentifier `asn1:"set"`
+	ContentInfo      contentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+// signerInfo names the signer by IssuerAndSerialNumber (version 1) or by
+// [0] SubjectKeyIdentifier (version 3).
+type signerInfo struct {
+	Version                   int
+	SID                       asn1.RawValue
+	DigestAlgorithm           algorithmIdentifier
+	AuthenticatedAttributes   asn1.RawValue `asn1:"optional,tag:0"`
+	DigestEncryptionAlgorithm algorithmIdentifier
+	EncryptedDigest           []byte
+	UnauthenticatedAttributes asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+// Attribute is a CMS attribute: a type and its SET OF values.
+type Attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values []asn1.RawValue `asn1:"set"`
+}
+
+// Signer describes the key that signs. Certificate, the signer's DER
+// X.509 certificate, is optional: with it the certificate is embedded and
+// the signer named by issuer and serial number as GM/T 0010 and GmSSL do;
+// without it the signer is named by SubjectKeyID.
+type Signer struct {
+	Key         *sm2.PrivateKey
+	UID         []byte
+	Certificate []byte
+}
+
+// SignOptions select the SignedData variant.
+type SignOptions struct {
+	// Detached leaves the content out of the SignedData.
+	Detached bool
+	// NoAttributes signs the content itself instead of the
+	// contentType and messageDigest authenticated attributes.
+	NoAttributes bool
+	// Profile selects the GM content types and sm2Sign (the default) or
+	// the RFC 5652 content types and SM2-with-SM3.
+	Profile Profile
+	// ContentType is the type of content when it is not data, such as
+	// the TSTInfo of an RFC 3161 time-stamp token.
+	ContentType asn1.ObjectIdentifier
+	// Attributes are signed after contentType and messageDigest.
+	Attributes []Attribute
+	// NoCertificate leaves Signer.Certificate out of the SignedData while
+	// still naming the signer by its issuer and serial number.
+	NoCertificate bool
+}
+
+// Sign returns a ContentInfo holding SignedData over content.
+func Sign(rand io.Reader, content []byte, signer Signer, opts SignOptions) ([]byte, error) {
+	outerType, contentType, sigAlg := OIDSignedData, OIDData, oidSM2Sign
+	if opts.Profile == ProfileCMS {
+		outerType, contentType, sigAlg = oidCMSSignedData, oidCMSData, oidSM2WithSM3
+	}
+	if opts.ContentType != nil {
+		contentType = opts.ContentType
+	}
+	if opts.NoAttributes && (opts.ContentType != nil || opts.Attributes != nil) {
+		return nil, errors.New("pkcs7: content other than data and extra attributes need signed attributes")
+	}
+	sd := signedData{
+		DigestAlgorithms: []algorithmIdentifier{{Algorithm: oidSM3, Parameters: asn1.NullRawValue}},
+		ContentInfo:      contentInfo{ContentType: contentType},
+	}
+	if !opts.Detached {
+		octets, err := asn1.Marshal(content)
+		if err != nil {
+			return nil, err
+		}
+		sd.ContentInfo.Content = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: octets}
+	}
+
+	si := signerInfo{
+		Version:                   3,
+		SID:                       asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, Bytes: SubjectKeyID(&signer.Key.PublicKey)},
+		DigestAlgorithm:           algorithmIdentifier{Algorithm: oidSM3, Parameters: asn1.NullRawValue},
+		DigestEncryptionAlgorithm: algorithmIdentifier{Algorithm: sigAlg},
+	}
+	if signer.Certificate != nil {
+		cert, err := parseCertificate(signer.Certificate)
+		if err != nil {
+			return nil, err
+		}
+		pub, err := cert.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		if pub.X.Cmp(signer.Key.X) != 0 || pub.Y.Cmp(signer.Key.Y) != 0 {
+			return nil, errors.New("pkcs7: certificate does not belong to the signing key")
+		}
+		ias, err := asn1.Marshal(cert.issuerAndSerial())
+		if err != nil {
+			return nil, err
+		}
+		si.Version = 1
+		si.SID = asn1.RawValue{FullBytes: ias}
+		if !opts.NoCertificate {
+			sd.Certificates = asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: signer.Certificate}
+		}
+	}
+
+	signed := content
+	if !opts.NoAttributes {
+		attrs, err := signedAttributes(contentType, content, opts.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		// The signature covers the attributes as a SET OF; the SignerInfo
+		// carries them under [0] IMPLICIT.
+		signed = attrs
+		si.AuthenticatedAttributes = asn1.RawValue{FullBytes: retag(attrs, 0xa0)}
+	}
+	r, s, err := sm2.Sign(rand, signer.Key, signed, signer.UID)
+	if err != nil {
+		return nil, err
+	}
+	if si.EncryptedDigest, err = sm2.MarshalSignature(r, s); err != nil {
+		return nil, err
+	}
+	sd.SignerInfos = []signerInfo{si}
+	sd.Version = signedDataVersion(contentType, sd.SignerInfos)
+
+	der, err := asn1.Marshal(sd)
+	if err != nil {
+		return nil, err
+	}
+	return wrapContent(outerType, der)
+}
+
+// signedDataVersion is the CMSVersion of a SignedData without attribute
+// certificates or other certificate and CRL formats (RFC 5652, section
+// 5.1): 3 if the content is not data or a SignerInfo is version 3, that
+// is names its signer by subject key identifier, else 1.
+func signedDataVersion(contentType asn1.ObjectIdentifier, sis []signerInfo) int {
+	if !isData(contentType) {
+		return 3
+	}
+	for _, si := range sis {
+		if si.Version == 3 {
+			return 3
+		}
+	}
+	return 1
+}
+
+// isData reports whether contentType is data under either profile.
+func isData(contentType asn1.ObjectIdentifier) bool {
+	return contentType.Equal(OIDData) || contentType.Equal(oidCMSData)
+}
+
+// signedAttributes returns the DER SET OF the contentType and
+// messageDigest attributes for content, followed by extra.
+func signedAttributes(contentType asn1.ObjectIdentifier, content []byte, extra []Attribute) ([]byte, error) {
+	ct, err := asn1.Marshal(contentType)
+	if err != nil {
+		return nil, err
+	}
+	sum := sm3.Sum(content)
+	md, err := asn1.Marshal(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	attrs := []Attribute{
+		{Type: OIDAttributeContentType, Values: []asn1.RawValue{{FullBytes: ct}}},
+		{Type: OIDAttributeMessageDigest, Values: []asn1.RawValue{{FullBytes: md}}},
+	}
+	return asn1.MarshalWithParams(append(attrs, extra...), "set")
+}
+
+// retag replaces the identifier octet of a DER element.
+func retag(der []byte, tag byte) []byte {
+	out := append([]byte{}, der...)
+	out[0] = tag
+	return out
+}
+
+// SignedData is a parsed SignedData, for callers such as time-stamp
+// tokens that check the signer and its attributes themselves.
+type SignedData struct {
+	ContentType asn1.ObjectIdentifier
+	// Content is nil when the SignedData is detached.
+	Content      []byte
+	Certificates [][]byte
+	SignerInfos  []SignerInfo
+}
+
+// SignerInfo is a parsed SignerInfo.
+type SignerInfo struct {
+	// SID is an IssuerAndSerialNumber or a [0] subject key identifier.
+	SID                asn1.RawValue
+	DigestAlgorithm    asn1.ObjectIdentifier
+	SignatureAlgorithm asn1.ObjectIdentifier
+	// SignedAttributes is the DER SET OF the signed attributes, which the
+	// signature covers instead of the content, or nil.
+	SignedAttributes []byte
+	Signature        []byte
+}
+
+// Names reports whether the SignerInfo names the certificate with the DER
+// issuer Name and serial number, or the subject key identifier keyID.
+func (si *SignerInfo) Names(issuer []byte, serial *big.Int, keyID []byte) bool {
+	if si.SID.Class == asn1.ClassContextSpecific && si.SID.Tag == 0 {
+		return keyID != nil && bytes.Equal(si.SID.Bytes, keyID)
+	}
+	var ias issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(si.SID.FullBytes, &ias); err != nil {
+		return false
+	}
+	return bytes.Equal(ias.Issuer.FullBytes, issuer) && ias.SerialNumber.Cmp(serial) == 0
+}
+
+// ParseSignedData parses a ContentInfo holding SignedData under either
+// profile. It does not check the signatures.
+func ParseSignedData(der []byte) (*SignedData, error) {
+	ct, inner, err := parseContentInfo(der)
+	if err != nil {
+		return nil, err
+	}
+	if !ct.Equal(OIDSignedData) && !ct.Equal(oidCMSSignedData) {
+		return nil, fmt.Errorf("pkcs7: content type %v, want SignedData", ct)
+	}
+	var sd signedData
+	if _, err := asn1.Unmarshal(inner, &sd); err != nil {
+		return nil, fmt.Errorf("pkcs7: malformed SignedData: %v", err)
+	}
+	out := &SignedData{ContentType: sd.ContentInfo.ContentType}
+	if c := sd.ContentInfo.Content; len(c.Bytes) > 0 {
+		if _, err := asn1.Unmarshal(c.Bytes, &out.Content); err != nil {
+			return nil, fmt.Errorf("pkcs7: malformed content: %v", err)
+		}
+		if out.Content == nil {
+			out.Content = []byte{}
+		}
+	}
+	for b := sd.Certificates.Bytes; len(b) > 0; {
+		var raw asn1.RawValue
+		if b, err = asn1.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("pkcs7: malformed certificates: %v", err)
+		}
+		out.Certificates = append(out.Certificates, raw.FullBytes)
+	}
+	for _, si := range sd.SignerInfos {
+		p := SignerInfo{
+			SID:                si.SID,
+			DigestAlgorithm:    si.DigestAlgorithm.Algorithm,
+			SignatureAlgorithm: si.DigestEncryptionAlgorithm.Algorithm,
+			Signature:          si.EncryptedDigest,
+		}
+		if attrs := si.AuthenticatedAttributes.FullBytes; len(attrs) > 0 {
+			p.SignedAttributes = retag(attrs, 0x31)
+		}
+		out.SignerInfos = append(out.SignerInfos, p)
+	}
+	return out, nil
+}
+
+// ParseAttributes parses a DER SET OF attributes such as
+// SignerInfo.SignedAttributes.
+func ParseAttributes(set []byte) ([]Attribute, error) {
+	var attrs []Attribute
+	rest, err := asn1.UnmarshalWithParams(set, &attrs, "set")
+	if err != nil {
+		return nil, fmt.Errorf("pkcs7: malformed signed attributes: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("pkcs7: trailing data after signed attributes")
+	}
+	return attrs, nil
+}
+
+// Verify checks the SignedData in der and returns the signed content. For
+// detached signatures the content is passed in; otherwise content must be
+// nil. The signer's key is pub when it is not nil, or else the embedded
+// certificate named by the SignerInfo. Every SignerInfo must verify.
+func Verify(der, content []byte, pub *sm2.PublicKey, uid []byte) ([]byte, error) {
+	sd, err := ParseSignedData(der)
+	if err != nil {
+		return nil, err
+	}
+	if !isData(sd.ContentType) {
+		return nil, fmt.Errorf("pkcs7: unsupported signed content type %v", sd.ContentType)
+	}
+	if sd.Content != nil {
+		if content != nil {
+			return nil, errors.New("pkcs7: content given for a SignedData that is not detached")
+		}
+		content = sd.Content
+	} else if content == nil {
+		return nil, errors.New("pkcs7: detached SignedData needs the content")
+	}
+	certs, err := parseCertificates(sd.Certificates)
+	if err != nil {
+		return nil, err
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, errors.New("pkcs7: no SignerInfo")
+	}
+	for _, si := range sd.SignerInfos {
+		if err := verifySigner(&si, content, pub, uid, certs); err != nil {
+			return nil, err
+		}
+	}
+	return content, nil
+}
+
+func parseCertificates(ders [][]byte) ([]*certificate, error) {
+	var certs []*certificate
+	for _, der := range ders {
+		c, err := parseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, c)
+	}
+	return certs, nil
+}
+
+func verifySigner(si *SignerInfo, content []byte, pub *sm2.PublicKey, uid []byte, certs []*certificate) error {
+	if !si.DigestAlgorithm.Equal(oidSM3) {
+		return fmt.Errorf("pkcs7: unsupported digest algorithm %v", si.DigestAlgorithm)
+	}
+	if alg := si.SignatureAlgorithm; !alg.Equal(oidSM2Sign) && !alg.Equal(oidSM2WithSM3) {
+		return fmt.Errorf("pkcs7: unsupported signature algorithm %v", alg)
+	}
+	if pub == nil {
+		var err error
+		if pub, err = signerKey(si, certs); err != nil {
+			return err
+		}
+	}
+
+	signed := content
+	if si.SignedAttributes != nil {
+		if err := checkMessageDigest(si.SignedAttributes, content); err != nil {
+			return err
+		}
+		signed = si.SignedAttributes
+	}
+	r, s, err := sm2.UnmarshalSignature(si.Signature)
+	if err != nil || !sm2.Verify(pub, signed, uid, r, s) {
+		return ErrSignature
+	}
+	return nil
+}
+
+// signerKey finds the embedded certificate named by si.
+func signerKey(si *SignerInfo, certs []*certificate) (*sm2.PublicKey, error) {
+	for _, c := range certs {
+		pub, err := c.publicKey()
+		if err != nil {
+			continue
+		}
+		if si.Names(c.TBS.Issuer.FullBytes, c.TBS.SerialNumber, SubjectKeyID(pub)) {
+			return pub, nil
+		}
+	}
+	return nil, errors.New("pkcs7: signer's certificate is not embedded; supply the public key")
+}
+
+// checkMessageDigest finds the messageDigest attribute in the attribute
+// SET and compares it with SM3(content).
+func checkMessageDigest(set, content []byte) error {
+	attrs, err := ParseAttributes(set)
+	if err != nil {
+		return err
+	}
+	for _, a := range attrs {
+		if !a.Type.Equal(OIDAttributeMessageDigest) || len(a.Values) != 1 {
+			continue
+		}
+		var md []byte
+		if _, err := asn1.Unmarshal(a.Values[0].FullBytes, &md); err != nil {
+			return fmt.Errorf("pkcs7: malformed messageDigest: %v", err)
+		}
+		sum := sm3.Sum(content)
+		if !bytes.Equal(md, sum[:]) {
+			return ErrSignature
+		}
+		return nil
+	}
+	return errors.New("pkcs7: authenticated attributes lack messageDigest")
+}
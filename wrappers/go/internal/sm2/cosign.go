@@ -0,0 +1,121 @@
+package sm2
+
+import (
+	"errors"
+	"io"
+	"math/big"
+)
+
+// Two-party SM2 signing, as used by mobile "shield" products. The client
+// holds d1 and the server d2 with d1·d2 = (1+d)⁻¹, so neither share
+// reveals the private key d and a signature needs both parties:
+//
+//	client: P1 = d1⁻¹·G                      → server
+//	server: P = d2⁻¹·P1 - G                  (the joint public key)
+//
+//	client: Q1 = k1·G, e                     → server
+//	server: (x1, y1) = k3·Q1 + k2·G, r = e + x1,
+//	        s2 = d2·k3, s3 = d2·(r + k2)     → client
+//	client: s = d1·k1·s2 + d1·s3 - r
+//
+// The result is an ordinary SM2 signature with nonce k = k1·k3 + k2.
+
+// ParseScalar decodes a big-endian integer in [1, n-1], such as a key
+// share or an ephemeral secret.
+func ParseScalar(b []byte) (*big.Int, error) {
+	k := new(big.Int).SetBytes(b)
+	if k.Sign() == 0 || k.Cmp(params.N) >= 0 {
+		return nil, errors.New("sm2: scalar out of range [1, n-1]")
+	}
+	return k, nil
+}
+
+func randNonzero(rand io.Reader) (*big.Int, error) {
+	return randScalar(rand, new(big.Int).Sub(params.N, one))
+}
+
+// CoSignClientShare draws the client share d1 and returns it with
+// P1 = d1⁻¹·G for the server.
+func CoSignClientShare(rand io.Reader) (d1 *big.Int, p1 *PublicKey, err error) {
+	if d1, err = randNonzero(rand); err != nil {
+		return nil, nil, err
+	}
+	inv := new(big.Int).ModInverse(d1, params.N)
+	x, y := ScalarBaseMult(inv.Bytes())
+	return d1, &PublicKey{X: x, Y: y}, nil
+}
+
+// CoSignServerShare draws the server share d2 for the client's P1 and
+// returns it with the joint public key P = d2⁻¹·P1 - G.
+func CoSignServerShare(rand io.Reader, p1 *PublicKey) (d2 *big.Int, pub *PublicKey, err error) {
+	for {
+		if d2, err = randNonzero(rand); err != nil {
+			return nil, nil, err
+		}
+		inv := new(big.Int).ModInverse(d2, params.N)
+		x, y := ScalarMult(p1.X, p1.Y, inv.Bytes())
+		x, y = Add(x, y, params.Gx, new(big.Int).Sub(params.P, params.Gy))
+		// d = (d1·d2)⁻¹ - 1 must not be 0 (P at infinity).
+		if x.Sign() != 0 || y.Sign() != 0 {
+			return d2, &PublicKey{X: x, Y: y}, nil
+		}
+	}
+}
+
+// CoSignClientStart draws the client's nonce share k1 and returns it with
+// Q1 = k1·G.
+func CoSignClientStart(rand io.Reader) (k1 *big.Int, q1 *PublicKey, err error) {
+	if k1, err = randNonzero(rand); err != nil {
+		return nil, nil, err
+	}
+	x, y := ScalarBaseMult(k1.Bytes())
+	return k1, &PublicKey{X: x, Y: y}, nil
+}
+
+// CoSignServer computes the server's part of a signature of the digest e:
+// r and the partial values s2 and s3.
+func CoSignServer(rand io.Reader, d2 *big.Int, digest []byte, q1 *PublicKey) (r, s2, s3 *big.Int, err error) {
+	n := params.N
+	e := new(big.Int).SetBytes(digest)
+	for {
+		k2, err := randNonzero(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		k3, err := randNonzero(rand)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		x2, y2 := ScalarBaseMult(k2.Bytes())
+		x, y := ScalarMult(q1.X, q1.Y, k3.Bytes())
+		x, _ = Add(x, y, x2, y2)
+		r = new(big.Int).Add(e, x)
+		r.Mod(r, n)
+		if r.Sign() == 0 {
+			continue
+		}
+		s2 = new(big.Int).Mul(d2, k3)
+		s2.Mod(s2, n)
+		s3 = new(big.Int).Add(r, k2)
+		s3.Mul(s3, d2)
+		s3.Mod(s3, n)
+		return r, s2, s3, nil
+	}
+}
+
+// CoSignClientFinish combines the server's values into the signature
+// (r, s). It fails when s is 0 or r + s = n, in which case the protocol
+// must be run again.
+func CoSignClientFinish(d1, k1, r, s2, s3 *big.Int) (s *big.Int, err error) {
+	n := params.N
+	s = new(big.Int).Mul(d1, k1)
+	s.Mul(s, s2)
+	t := new(big.Int).Mul(d1, s3)
+	s.Add(s, t)
+	s.Sub(s, r)
+	s.Mod(s, n)
+	if s.Sign() == 0 || new(big.Int).Add(r, s).Cmp(n) == 0 {
+		return nil, errors.New("sm2: degenerate co-signature, run the protocol again")
+	}
+	return s, nil
+}
@@ -0,0 +1,252 @@
+package sm2
+
+import "math/big"
+
+// CurveParams holds the domain parameters of the SM2 recommended curve
+// y² = x³ + ax + b over GF(p) with base point (Gx, Gy) of order N.
+type CurveParams struct {
+	P, A, B, N, Gx, Gy *big.Int
+	BitSize            int
+}
+
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("sm2: bad curve constant " + s)
+	}
+	return n
+}
+
+var params = &CurveParams{
+	P:       fromHex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFF"),
+	A:       fromHex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00000000FFFFFFFFFFFFFFFC"),
+	B:       fromHex("28E9FA9E9D9F5E344D5A9E4BCF6509A7F39789F515AB8F92DDBCBD414D940E93"),
+	N:       fromHex("FFFFFFFEFFFFFFFFFFFFFFFFFFFFFFFF7203DF6B21C6052B53BBF40939D54123"),
+	Gx:      fromHex("32C4AE2C1F1981195F9904466A39C9948FE30BBFF2660BE1715A4589334C74C7"),
+	Gy:      fromHex("BC3736A2F4F6779C59BDCEE36B692153D0A9877CC62A474002DF32E52139F0A0"),
+	BitSize: 256,
+}
+
+// Params returns the parameters of the SM2 curve. Callers must not modify
+// the returned values.
+func Params() *CurveParams { return params }
+
+// IsOnCurve reports whether (x, y) is an affine point on the curve.
+// The point at infinity, represented as (0, 0), is not on the curve.
+func IsOnCurve(x, y *big.Int) bool {
+	p := params.P
+	if x.Sign() < 0 || x.Cmp(p) >= 0 || y.Sign() < 0 || y.Cmp(p) >= 0 {
+		return false
+	}
+	y2 := new(big.Int).Mul(y, y)
+	y2.Mod(y2, p)
+	return polynomial(x).Cmp(y2) == 0
+}
+
+// polynomial returns x³ + ax + b mod p.
+func polynomial(x *big.Int) *big.Int {
+	p := params.P
+	x3 := new(big.Int).Mul(x, x)
+	x3.Mul(x3, x)
+	ax := new(big.Int).Mul(params.A, x)
+	x3.Add(x3, ax)
+	x3.Add(x3, params.B)
+	return x3.Mod(x3, p)
+}
+
+// Add returns the sum of two affine points. (0, 0) is the identity.
+func Add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	z1 := zForAffine(x1, y1)
+	z2 := zForAffine(x2, y2)
+	return affineFromJacobian(addJacobian(x1, y1, z1, x2, y2, z2))
+}
+
+// Double returns 2·(x, y).
+func Double(x, y *big.Int) (*big.Int, *big.Int) {
+	return affineFromJacobian(doubleJacobian(x, y, zForAffine(x, y)))
+}
+
+// ScalarMult returns k·(x, y) where k is a big-endian integer.
+func ScalarMult(x, y *big.Int, k []byte) (*big.Int, *big.Int) {
+	z := zForAffine(x, y)
+	rx, ry, rz := new(big.Int), new(big.Int), new(big.Int)
+	for _, b := range k {
+		for bit := 0; bit < 8; bit++ {
+			rx, ry, rz = doubleJacobian(rx, ry, rz)
+			if b&0x80 == 0x80 {
+				rx, ry, rz = addJacobian(x, y, z, rx, ry, rz)
+			}
+			b <<= 1
+		}
+	}
+	return affineFromJacobian(rx, ry, rz)
+}
+
+// ScalarBaseMult returns k·G where k is a big-endian integer.
+func ScalarBaseMult(k []byte) (*big.Int, *big.Int) {
+	return ScalarMult(params.Gx, params.Gy, k)
+}
+
+func zForAffine(x, y *big.Int) *big.Int {
+	z := new(big.Int)
+	if x.Sign() != 0 || y.Sign() != 0 {
+		z.SetInt64(1)
+	}
+	return z
+}
+
+func affineFromJacobian(x, y, z *big.Int) (*big.Int, *big.Int) {
+	if z.Sign() == 0 {
+		return new(big.Int), new(big.Int)
+	}
+	p := params.P
+	zinv := new(big.Int).ModInverse(z, p)
+	zinvsq := new(big.Int).Mul(zinv, zinv)
+
+	xOut := new(big.Int).Mul(x, zinvsq)
+	xOut.Mod(xOut, p)
+	zinvsq.Mul(zinvsq, zinv)
+	yOut := new(big.Int).Mul(y, zinvsq)
+	yOut.Mod(yOut, p)
+	return xOut, yOut
+}
+
+// addJacobian uses the add-2007-bl formulas.
+func addJacobian(x1, y1, z1, x2, y2, z2 *big.Int) (*big.Int, *big.Int, *big.Int) {
+	p := params.P
+	x3, y3, z3 := new(big.Int), new(big.Int), new(big.Int)
+	if z1.Sign() == 0 {
+		x3.Set(x2)
+		y3.Set(y2)
+		z3.Set(z2)
+		return x3, y3, z3
+	}
+	if z2.Sign() == 0 {
+		x3.Set(x1)
+		y3.Set(y1)
+		z3.Set(z1)
+		return x3, y3, z3
+	}
+
+	z1z1 := new(big.Int).Mul(z1, z1)
+	z1z1.Mod(z1z1, p)
+	z2z2 := new(big.Int).Mul(z2, z2)
+	z2z2.Mod(z2z2, p)
+
+	u1 := new(big.Int).Mul(x1, z2z2)
+	u1.Mod(u1, p)
+	u2 := new(big.Int).Mul(x2, z1z1)
+	u2.Mod(u2, p)
+	h := new(big.Int).Sub(u2, u1)
+	xEqual := h.Sign() == 0
+	if h.Sign() == -1 {
+		h.Add(h, p)
+	}
+	i := new(big.Int).Lsh(h, 1)
+	i.Mul(i, i)
+	j := new(big.Int).Mul(h, i)
+
+	s1 := new(big.Int).Mul(y1, z2)
+	s1.Mul(s1, z2z2)
+	s1.Mod(s1, p)
+	s2 := new(big.Int).Mul(y2, z1)
+	s2.Mul(s2, z1z1)
+	s2.Mod(s2, p)
+	r := new(big.Int).Sub(s2, s1)
+	if r.Sign() == -1 {
+		r.Add(r, p)
+	}
+	yEqual := r.Sign() == 0
+	if xEqual && yEqual {
+		return doubleJacobian(x1, y1, z1)
+	}
+	r.Lsh(r, 1)
+	v := new(big.Int).Mul(u1, i)
+
+	x3.Set(r)
+	x3.Mul(x3, x3)
+	x3.Sub(x3, j)
+	x3.Sub(x3, v)
+	x3.Sub(x3, v)
+	x3.Mod(x3, p)
+
+	y3.Set(r)
+	v.Sub(v, x3)
+	y3.Mul(y3, v)
+	s1.Mul(s1, j)
+	s1.Lsh(s1, 1)
+	y3.Sub(y3, s1)
+	y3.Mod(y3, p)
+
+	z3.Add(z1, z2)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, z1z1)
+	z3.Sub(z3, z2z2)
+	z3.Mul(z3, h)
+	z3.Mod(z3, p)
+
+	return x3, y3, z3
+}
+
+// doubleJacobian uses the dbl-2001-b formulas, which rely on a = -3.
+func doubleJacobian(x, y, z *big.Int) (*big.Int, *big.Int, *big.Int) {
+	p := params.P
+	if z.Sign() == 0 {
+		return new(big.Int), new(big.Int), new(big.Int)
+	}
+	delta := new(big.Int).Mul(z, z)
+	delta.Mod(delta, p)
+	gamma := new(big.Int).Mul(y, y)
+	gamma.Mod(gamma, p)
+	alpha := new(big.Int).Sub(x, delta)
+	if alpha.Sign() == -1 {
+		alpha.Add(alpha, p)
+	}
+	alpha2 := new(big.Int).Add(x, delta)
+	alpha.Mul(alpha, alpha2)
+	alpha2.Set(alpha)
+	alpha.Lsh(alpha, 1)
+	alpha.Add(alpha, alpha2)
+
+	beta := alpha2.Mul(x, gamma)
+
+	x3 := new(big.Int).Mul(alpha, alpha)
+	beta8 := new(big.Int).Lsh(beta, 3)
+	beta8.Mod(beta8, p)
+	x3.Sub(x3, beta8)
+	if x3.Sign() == -1 {
+		x3.Add(x3, p)
+	}
+	x3.Mod(x3, p)
+
+	z3 := new(big.Int).Add(y, z)
+	z3.Mul(z3, z3)
+	z3.Sub(z3, gamma)
+	if z3.Sign() == -1 {
+		z3.Add(z3, p)
+	}
+	z3.Sub(z3, delta)
+	if z3.Sign() == -1 {
+		z3.Add(z3, p)
+	}
+	z3.Mod(z3, p)
+
+	beta.Lsh(beta, 2)
+	beta.Sub(beta, x3)
+	if beta.Sign() == -1 {
+		beta.Add(beta, p)
+	}
+	y3 := alpha.Mul(alpha, beta)
+
+	gamma.Mul(gamma, gamma)
+	gamma.Lsh(gamma, 3)
+	gamma.Mod(gamma, p)
+
+	y3.Sub(y3, gamma)
+	if y3.Sign() == -1 {
+		y3.Add(y3, p)
+	}
+	y3.Mod(y3, p)
+
+	return x3, y3, z3
+}
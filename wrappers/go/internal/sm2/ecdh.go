@@ -0,0 +1,13 @@
+package sm2
+
+import "errors"
+
+// ECDH returns the 32-byte x-coordinate of [d]P for the private key d and
+// the peer's public key P.
+func ECDH(priv *PrivateKey, peer *PublicKey) ([]byte, error) {
+	x, y := ScalarMult(peer.X, peer.Y, priv.D.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, errors.New("sm2: shared point is at infinity")
+	}
+	return x.FillBytes(make([]byte, 32)), nil
+}
@@ -0,0 +1,55 @@
+package sm2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Encapsulate returns klen bytes of key shared with pub and the 65-byte
+// point C = [k]G that carries it. The key is KDF(x2 || y2, klen) with
+// (x2, y2) = [k]PB, the key stream of SM2 encryption.
+func Encapsulate(rand io.Reader, pub *PublicKey, klen int) (key, c []byte, err error) {
+	if klen <= 0 {
+		return nil, nil, errors.New("sm2: key length must be positive")
+	}
+	nMinus1 := new(big.Int).Sub(params.N, one)
+	for {
+		k, err := randScalar(rand, nMinus1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if key, c = encapsulateWithK(pub, k, klen); !allZero(key) {
+			return key, c, nil
+		}
+	}
+}
+
+func encapsulateWithK(pub *PublicKey, k *big.Int, klen int) (key, c []byte) {
+	x1, y1 := ScalarBaseMult(k.Bytes())
+	x2, y2 := ScalarMult(pub.X, pub.Y, k.Bytes())
+	return kemKDF(x2, y2, klen), (&PublicKey{X: x1, Y: y1}).Bytes()
+}
+
+// Decapsulate recovers the klen-byte key carried by the point c.
+func Decapsulate(priv *PrivateKey, c []byte, klen int) ([]byte, error) {
+	if klen <= 0 {
+		return nil, errors.New("sm2: key length must be positive")
+	}
+	p, err := ParsePublicKey(c)
+	if err != nil {
+		return nil, fmt.Errorf("sm2: invalid encapsulation: %w", err)
+	}
+	x2, y2 := ScalarMult(p.X, p.Y, priv.D.Bytes())
+	key := kemKDF(x2, y2, klen)
+	if allZero(key) {
+		return nil, errors.New("sm2: decapsulation failed")
+	}
+	return key, nil
+}
+
+func kemKDF(x2, y2 *big.Int, klen int) []byte {
+	z := append(x2.FillBytes(make([]byte, 32)), y2.FillBytes(make([]byte, 32))...)
+	return KDF(z, klen)
+}
@@ -0,0 +1,96 @@
+package sm2
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// KeyExchange is one party's view of the SM2 key agreement protocol of
+// GB/T 32918.3. The initiator is user A and the responder user B; ZA is
+// always the initiator's identity hash.
+type KeyExchange struct {
+	Initiator bool
+	// Static and Ephemeral are this party's key pairs (d, P) and (r, R).
+	Static    *PrivateKey
+	Ephemeral *PrivateKey
+	UID       []byte
+
+	PeerStatic    *PublicKey
+	PeerEphemeral *PublicKey
+	PeerUID       []byte
+}
+
+// xBar returns 2^w + (x mod 2^w) with w = 127 for the 256-bit curve.
+func xBar(x *big.Int) *big.Int {
+	w := uint((params.N.BitLen()+1)/2 - 1)
+	mask := new(big.Int).Lsh(one, w)
+	xb := new(big.Int).Sub(mask, one)
+	xb.And(xb, x)
+	return xb.Add(xb, mask)
+}
+
+// Agree derives the shared key of klen bytes. It also returns the
+// optional confirmation hash this party sends (SA for the initiator, SB for
+// the responder) and the one it expects from its peer.
+func (kx *KeyExchange) Agree(klen int) (key, send, expect []byte, err error) {
+	if klen <= 0 {
+		return nil, nil, nil, errors.New("sm2: key length must be positive")
+	}
+	za, err := ZA(&kx.Static.PublicKey, kx.UID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	zb, err := ZA(kx.PeerStatic, kx.PeerUID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	own, peer := &kx.Ephemeral.PublicKey, kx.PeerEphemeral
+	if !kx.Initiator {
+		za, zb = zb, za
+	}
+
+	// t = (d + x̄·r) mod n; the shared point is t·(P' + x̄'·R').
+	t := new(big.Int).Mul(xBar(own.X), kx.Ephemeral.D)
+	t.Add(t, kx.Static.D)
+	t.Mod(t, params.N)
+	x, y := ScalarMult(peer.X, peer.Y, xBar(peer.X).Bytes())
+	x, y = Add(kx.PeerStatic.X, kx.PeerStatic.Y, x, y)
+	x, y = ScalarMult(x, y, t.Bytes())
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, nil, nil, errors.New("sm2: key exchange produced the point at infinity")
+	}
+	xb := x.FillBytes(make([]byte, 32))
+	yb := y.FillBytes(make([]byte, 32))
+
+	z := append(append(append(append([]byte{}, xb...), yb...), za...), zb...)
+	key = KDF(z, klen)
+
+	// RA and RB in protocol order.
+	ra, rb := own, peer
+	if !kx.Initiator {
+		ra, rb = rb, ra
+	}
+	h := sm3.New()
+	h.Write(xb)
+	h.Write(za)
+	h.Write(zb)
+	for _, v := range []*big.Int{ra.X, ra.Y, rb.X, rb.Y} {
+		h.Write(v.FillBytes(make([]byte, 32)))
+	}
+	inner := h.Sum(nil)
+	confirm := func(tag byte) []byte {
+		h := sm3.New()
+		h.Write([]byte{tag})
+		h.Write(yb)
+		h.Write(inner)
+		return h.Sum(nil)
+	}
+	// SB (and S1) use 0x02, SA (and S2) use 0x03.
+	sb, sa := confirm(2), confirm(3)
+	if kx.Initiator {
+		return key, sa, sb, nil
+	}
+	return key, sb, sa, nil
+}
@@ -0,0 +1,139 @@
+package sm2
+
+import (
+	"encoding/asn1"
+	"errors"
+	"fmt"
+)
+
+var (
+	// OIDPublicKeyEC is id-ecPublicKey from RFC 5480.
+	OIDPublicKeyEC = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+	// OIDSM2 is sm2p256v1 (GM/T 0006), used both as the named curve and,
+	// by some older producers, as the key algorithm itself.
+	OIDSM2 = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301}
+)
+
+type algorithmIdentifier struct {
+	Algorithm  asn1.ObjectIdentifier
+	Parameters asn1.RawValue `asn1:"optional"`
+}
+
+type subjectPublicKeyInfo struct {
+	Algorithm algorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+type pkcs8 struct {
+	Version    int
+	Algorithm  algorithmIdentifier
+	PrivateKey []byte
+}
+
+// ecPrivateKey is the SEC 1 / RFC 5915 ECPrivateKey structure.
+type ecPrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+func sm2Algorithm() algorithmIdentifier {
+	params, _ := asn1.Marshal(OIDSM2)
+	return algorithmIdentifier{Algorithm: OIDPublicKeyEC, Parameters: asn1.RawValue{FullBytes: params}}
+}
+
+// checkAlgorithm accepts id-ecPublicKey with the SM2 curve, or the SM2 OID
+// as the algorithm.
+func checkAlgorithm(alg algorithmIdentifier) error {
+	if alg.Algorithm.Equal(OIDSM2) {
+		return nil
+	}
+	if !alg.Algorithm.Equal(OIDPublicKeyEC) {
+		return fmt.Errorf("sm2: unsupported key algorithm %v", alg.Algorithm)
+	}
+	var curve asn1.ObjectIdentifier
+	if _, err := asn1.Unmarshal(alg.Parameters.FullBytes, &curve); err != nil || !curve.Equal(OIDSM2) {
+		return errors.New("sm2: key is not on the SM2 curve")
+	}
+	return nil
+}
+
+// MarshalPKIXPublicKey wraps an encoded point (compressed or not) in a DER
+// SubjectPublicKeyInfo.
+func MarshalPKIXPublicKey(point []byte) ([]byte, error) {
+	return asn1.Marshal(subjectPublicKeyInfo{
+		Algorithm: sm2Algorithm(),
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+// UnmarshalPKIXPublicKey returns the encoded point of a DER
+// SubjectPublicKeyInfo. The point itself is not validated.
+func UnmarshalPKIXPublicKey(der []byte) ([]byte, error) {
+	var spki subjectPublicKeyInfo
+	rest, err := asn1.Unmarshal(der, &spki)
+	if err != nil {
+		return nil, fmt.Errorf("sm2: malformed SubjectPublicKeyInfo: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after SubjectPublicKeyInfo")
+	}
+	if err := checkAlgorithm(spki.Algorithm); err != nil {
+		return nil, err
+	}
+	return spki.PublicKey.RightAlign(), nil
+}
+
+// MarshalPKCS8PrivateKey encodes priv as a DER PKCS #8 PrivateKeyInfo
+// holding an ECPrivateKey with the uncompressed public key, as OpenSSL and
+// Bouncy Castle write it.
+func MarshalPKCS8PrivateKey(priv *PrivateKey) ([]byte, error) {
+	pub := priv.PublicKey.Bytes()
+	inner, err := asn1.Marshal(ecPrivateKey{
+		Version:    1,
+		PrivateKey: priv.Bytes(),
+		PublicKey:  asn1.BitString{Bytes: pub, BitLength: 8 * len(pub)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8{Algorithm: sm2Algorithm(), PrivateKey: inner})
+}
+
+// UnmarshalPKCS8PrivateKey returns the private scalar of a DER PKCS #8
+// PrivateKeyInfo. The scalar is not range checked.
+func UnmarshalPKCS8PrivateKey(der []byte) ([]byte, error) {
+	var p pkcs8
+	rest, err := asn1.Unmarshal(der, &p)
+	if err != nil {
+		return nil, fmt.Errorf("sm2: malformed PKCS #8 key: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after PKCS #8 key")
+	}
+	if err := checkAlgorithm(p.Algorithm); err != nil {
+		return nil, err
+	}
+	return UnmarshalECPrivateKey(p.PrivateKey)
+}
+
+// UnmarshalECPrivateKey returns the private scalar of a DER SEC 1
+// ECPrivateKey.
+func UnmarshalECPrivateKey(der []byte) ([]byte, error) {
+	var k ecPrivateKey
+	rest, err := asn1.Unmarshal(der, &k)
+	if err != nil {
+		return nil, fmt.Errorf("sm2: malformed ECPrivateKey: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after ECPrivateKey")
+	}
+	if k.Version != 1 {
+		return nil, fmt.Errorf("sm2: unsupported ECPrivateKey version %d", k.Version)
+	}
+	if k.NamedCurveOID != nil && !k.NamedCurveOID.Equal(OIDSM2) {
+		return nil, errors.New("sm2: key is not on the SM2 curve")
+	}
+	return k.PrivateKey, nil
+}
@@ -0,0 +1,54 @@
+package sm2
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// RecoverPublicKeys returns the public keys for which (r, s) is a valid
+// signature of the digest e. Since kG = sG + (r+s)P, every curve point R
+// with x(R) ≡ r - e (mod n) gives the candidate P = (r+s)⁻¹(R - sG): up
+// to two abscissae, each with two ordinates.
+//
+// The digest is SM3(ZA || M) and ZA depends on the public key, so recovery
+// needs e itself; a message alone is not enough.
+func RecoverPublicKeys(digest []byte, r, s *big.Int) ([]*PublicKey, error) {
+	if len(digest) != sm3.Size {
+		return nil, fmt.Errorf("sm2: digest must be %d bytes, got %d", sm3.Size, len(digest))
+	}
+	n, p := params.N, params.P
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return nil, errors.New("sm2: signature values out of range")
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return nil, errors.New("sm2: r + s is zero")
+	}
+	tInv := new(big.Int).ModInverse(t, n)
+	sx, sy := ScalarBaseMult(s.Bytes())
+	negSY := new(big.Int).Sub(p, sy)
+
+	x1 := new(big.Int).SetBytes(digest)
+	x1.Sub(r, x1)
+	x1.Mod(x1, n)
+	var keys []*PublicKey
+	for x := x1; x.Cmp(p) < 0; x = new(big.Int).Add(x, n) {
+		for _, odd := range []bool{false, true} {
+			y := decompressY(x, odd)
+			if y == nil {
+				break
+			}
+			qx, qy := Add(x, y, sx, negSY)
+			qx, qy = ScalarMult(qx, qy, tInv.Bytes())
+			if qx.Sign() == 0 && qy.Sign() == 0 {
+				continue
+			}
+			keys = append(keys, &PublicKey{X: qx, Y: qy})
+		}
+	}
+	return keys, nil
+}
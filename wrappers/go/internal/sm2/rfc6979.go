@@ -0,0 +1,103 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"fmt"
+	"hash"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// nonceGenerator derives signing nonces deterministically from the
+// private key and the message digest with the HMAC_DRBG construction of
+// RFC 6979, section 3.2.
+type nonceGenerator struct {
+	newHash func() hash.Hash
+	q       *big.Int
+	k, v    []byte
+	started bool
+}
+
+// newNonceGenerator seeds the generator for private key x and message
+// digest h1 in a group of order q.
+func newNonceGenerator(newHash func() hash.Hash, q, x *big.Int, h1 []byte) *nonceGenerator {
+	g := &nonceGenerator{newHash: newHash, q: q}
+	hlen := newHash().Size()
+	rlen := (q.BitLen() + 7) / 8
+	g.v = bytes.Repeat([]byte{1}, hlen)
+	g.k = make([]byte, hlen)
+
+	xb := x.FillBytes(make([]byte, rlen))
+	hb := g.bits2int(h1)
+	hb.Mod(hb, q)
+	hbb := hb.FillBytes(make([]byte, rlen))
+	g.k = g.mac(g.v, []byte{0}, xb, hbb)
+	g.v = g.mac(g.v)
+	g.k = g.mac(g.v, []byte{1}, xb, hbb)
+	g.v = g.mac(g.v)
+	return g
+}
+
+func (g *nonceGenerator) mac(parts ...[]byte) []byte {
+	m := hmac.New(g.newHash, g.k)
+	for _, p := range parts {
+		m.Write(p)
+	}
+	return m.Sum(nil)
+}
+
+// bits2int keeps the leftmost qlen bits of b.
+func (g *nonceGenerator) bits2int(b []byte) *big.Int {
+	i := new(big.Int).SetBytes(b)
+	if excess := len(b)*8 - g.q.BitLen(); excess > 0 {
+		i.Rsh(i, uint(excess))
+	}
+	return i
+}
+
+// next returns the next candidate nonce in [1, q-1]. Calling it again
+// after a candidate was rejected reseeds as the RFC prescribes.
+func (g *nonceGenerator) next() *big.Int {
+	for {
+		if g.started {
+			g.k = g.mac(g.v, []byte{0})
+			g.v = g.mac(g.v)
+		}
+		g.started = true
+		var t []byte
+		for len(t)*8 < g.q.BitLen() {
+			g.v = g.mac(g.v)
+			t = append(t, g.v...)
+		}
+		if k := g.bits2int(t); k.Sign() > 0 && k.Cmp(g.q) < 0 {
+			return k
+		}
+	}
+}
+
+// SignDeterministic is Sign with the nonce derived from the private key
+// and e = SM3(ZA || msg) by RFC 6979 using HMAC-SM3, so the same input
+// always gives the same signature.
+func SignDeterministic(priv *PrivateKey, msg, uid []byte) (r, s *big.Int, err error) {
+	e, err := Digest(&priv.PublicKey, msg, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return SignDigestDeterministic(priv, e)
+}
+
+// SignDigestDeterministic is SignDigest with an RFC 6979 nonce.
+func SignDigestDeterministic(priv *PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	if len(digest) != sm3.Size {
+		return nil, nil, fmt.Errorf("sm2: digest must be %d bytes, got %d", sm3.Size, len(digest))
+	}
+	e := new(big.Int).SetBytes(digest)
+	g := newNonceGenerator(sm3.New, params.N, priv.D, digest)
+	for {
+		if r, s, ok := signWithK(priv, e, g.next()); ok {
+			return r, s, nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package sm2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+)
+
+// Share is one point (X, Y) of a Shamir sharing of a private key over the
+// group order n. X is never 0; the secret is the polynomial's value there.
+type Share struct {
+	X byte
+	Y *big.Int
+}
+
+// Bytes encodes the share as X followed by 32-byte Y.
+func (s Share) Bytes() []byte {
+	out := make([]byte, 33)
+	out[0] = s.X
+	s.Y.FillBytes(out[1:])
+	return out
+}
+
+// ParseShare decodes the 33-byte encoding of Share.Bytes.
+func ParseShare(b []byte) (Share, error) {
+	if len(b) != 33 || b[0] == 0 {
+		return Share{}, errors.New("sm2: a share is 33 bytes: a nonzero index and a 32-byte value")
+	}
+	y := new(big.Int).SetBytes(b[1:])
+	if y.Cmp(params.N) >= 0 {
+		return Share{}, errors.New("sm2: share value out of range")
+	}
+	return Share{X: b[0], Y: y}, nil
+}
+
+// SplitKey splits d into count shares, any threshold of which recover it,
+// with a random polynomial of degree threshold-1 over GF(n).
+func SplitKey(rand io.Reader, d *big.Int, count, threshold int) ([]Share, error) {
+	if threshold < 2 || threshold > count || count > 255 {
+		return nil, fmt.Errorf("sm2: need 2 <= threshold <= count <= 255, got threshold %d and count %d", threshold, count)
+	}
+	coeffs := []*big.Int{d}
+	for i := 1; i < threshold; i++ {
+		c, err := randScalar(rand, new(big.Int).Sub(params.N, one))
+		if err != nil {
+			return nil, err
+		}
+		coeffs = append(coeffs, c)
+	}
+	shares := make([]Share, count)
+	for i := range shares {
+		x := big.NewInt(int64(i + 1))
+		// Horner's rule from the highest coefficient down.
+		y := new(big.Int)
+		for j := len(coeffs) - 1; j >= 0; j-- {
+			y.Mul(y, x)
+			y.Add(y, coeffs[j])
+			y.Mod(y, params.N)
+		}
+		shares[i] = Share{X: byte(i + 1), Y: y}
+	}
+	return shares, nil
+}
+
+// CombineKey interpolates the shares at 0. With fewer shares than the
+// threshold the result is an unrelated value, which callers detect by
+// comparing public keys.
+func CombineKey(shares []Share) (*big.Int, error) {
+	if len(shares) < 2 {
+		return nil, errors.New("sm2: at least two shares are needed")
+	}
+	n := params.N
+	d := new(big.Int)
+	for i, si := range shares {
+		num, den := big.NewInt(1), big.NewInt(1)
+		for j, sj := range shares {
+			if i == j {
+				continue
+			}
+			if si.X == sj.X {
+				return nil, fmt.Errorf("sm2: duplicate share index %d", si.X)
+			}
+			// Lagrange basis at 0: Π xj / (xj - xi).
+			num.Mul(num, big.NewInt(int64(sj.X)))
+			num.Mod(num, n)
+			den.Mul(den, big.NewInt(int64(sj.X)-int64(si.X)))
+			den.Mod(den, n)
+		}
+		term := new(big.Int).ModInverse(den, n)
+		term.Mul(term, num)
+		term.Mul(term, si.Y)
+		d.Add(d, term)
+		d.Mod(d, n)
+	}
+	return d, nil
+}
@@ -0,0 +1,426 @@
+// Package sm2 implements the SM2 public key algorithms (GB/T 32918-2016)
+// over the recommended 256-bit curve: digital signatures, key exchange and
+// public key encryption.
+//
+// The arithmetic is built on math/big and is not constant time. It exists
+// to produce and check interoperability vectors, not to protect secrets.
+package sm2
+
+import (
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// DefaultUID is the user identifier used in ZA when none is supplied.
+const DefaultUID = "1234567812345678"
+
+var one = big.NewInt(1)
+
+// PublicKey is an SM2 public key.
+type PublicKey struct {
+	X, Y *big.Int
+}
+
+// PrivateKey is an SM2 private key.
+type PrivateKey struct {
+	PublicKey
+	D *big.Int
+}
+
+// GenerateKey returns a fresh key pair using randomness from rand.
+func GenerateKey(rand io.Reader) (*PrivateKey, error) {
+	// The private key must lie in [1, n-2] so that 1+d is invertible.
+	nMinus2 := new(big.Int).Sub(params.N, big.NewInt(2))
+	d, err := randScalar(rand, nMinus2)
+	if err != nil {
+		return nil, err
+	}
+	return newPrivateKey(d), nil
+}
+
+func newPrivateKey(d *big.Int) *PrivateKey {
+	priv := &PrivateKey{D: d}
+	priv.X, priv.Y = ScalarBaseMult(d.Bytes())
+	return priv
+}
+
+// NewPrivateKey builds a key pair from a big-endian private scalar.
+func NewPrivateKey(d []byte) (*PrivateKey, error) {
+	k := new(big.Int).SetBytes(d)
+	nMinus1 := new(big.Int).Sub(params.N, one)
+	if k.Sign() == 0 || k.Cmp(nMinus1) >= 0 {
+		return nil, errors.New("sm2: private key out of range [1, n-2]")
+	}
+	return newPrivateKey(k), nil
+}
+
+// Errors of public key parsing and decryption.
+var (
+	ErrNotOnCurve = errors.New("sm2: public key is not on the curve")
+	// ErrDecryption is returned for every ciphertext that does not
+	// decrypt, without saying which check failed.
+	ErrDecryption = errors.New("sm2: decryption failed")
+)
+
+// ParsePublicKey decodes an uncompressed point 04 || X || Y or a
+// compressed point 02 || X or 03 || X, where the prefix carries the parity
+// of Y.
+func ParsePublicKey(b []byte) (*PublicKey, error) {
+	switch {
+	case len(b) == 65 && b[0] == 4:
+		x := new(big.Int).SetBytes(b[1:33])
+		y := new(big.Int).SetBytes(b[33:])
+		if !IsOnCurve(x, y) {
+			return nil, ErrNotOnCurve
+		}
+		return &PublicKey{X: x, Y: y}, nil
+	case len(b) == 33 && (b[0] == 2 || b[0] == 3):
+		x := new(big.Int).SetBytes(b[1:])
+		y := decompressY(x, b[0] == 3)
+		if y == nil {
+			return nil, ErrNotOnCurve
+		}
+		return &PublicKey{X: x, Y: y}, nil
+	}
+	return nil, errors.New("sm2: public key must be 65 bytes starting with 04 or 33 bytes starting with 02 or 03")
+}
+
+// decompressY solves y² = x³ + ax + b for the root with the given parity,
+// or returns nil when x is not the abscissa of a curve point.
+func decompressY(x *big.Int, odd bool) *big.Int {
+	p := params.P
+	if x.Cmp(p) >= 0 {
+		return nil
+	}
+	y := new(big.Int).ModSqrt(polynomial(x), p)
+	if y == nil {
+		return nil
+	}
+	if y.Bit(0) == 1 != odd {
+		y.Sub(p, y)
+	}
+	return y
+}
+
+// Bytes returns the uncompressed encoding 04 || X || Y.
+func (pub *PublicKey) Bytes() []byte {
+	out := make([]byte, 65)
+	out[0] = 4
+	pub.X.FillBytes(out[1:33])
+	pub.Y.FillBytes(out[33:])
+	return out
+}
+
+// CompressedBytes returns the compressed encoding 02/03 || X.
+func (pub *PublicKey) CompressedBytes() []byte {
+	out := make([]byte, 33)
+	out[0] = 2 + byte(pub.Y.Bit(0))
+	pub.X.FillBytes(out[1:])
+	return out
+}
+
+// Bytes returns the 32-byte big-endian private scalar.
+func (priv *PrivateKey) Bytes() []byte {
+	return priv.D.FillBytes(make([]byte, 32))
+}
+
+// randScalar returns a uniform integer in [1, max].
+func randScalar(rand io.Reader, max *big.Int) (*big.Int, error) {
+	b := make([]byte, params.BitSize/8+8)
+	if _, err := io.ReadFull(rand, b); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(b)
+	k.Mod(k, max)
+	return k.Add(k, one), nil
+}
+
+// ZA computes SM3(ENTLA || IDA || a || b || xG || yG || xA || yA).
+func ZA(pub *PublicKey, uid []byte) ([]byte, error) {
+	if len(uid) >= 8192 {
+		return nil, errors.New("sm2: user ID longer than 8191 bytes")
+	}
+	h := sm3.New()
+	var entla [2]byte
+	binary.BigEndian.PutUint16(entla[:], uint16(len(uid)*8))
+	h.Write(entla[:])
+	h.Write(uid)
+	for _, v := range []*big.Int{params.A, params.B, params.Gx, params.Gy, pub.X, pub.Y} {
+		h.Write(v.FillBytes(make([]byte, 32)))
+	}
+	return h.Sum(nil), nil
+}
+
+// messageDigest returns e = SM3(ZA || msg) as an integer.
+func messageDigest(pub *PublicKey, msg, uid []byte) (*big.Int, error) {
+	za, err := ZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+	h := sm3.New()
+	h.Write(za)
+	h.Write(msg)
+	return new(big.Int).SetBytes(h.Sum(nil)), nil
+}
+
+// Sign signs msg under the identity uid and returns the signature (r, s).
+func Sign(rand io.Reader, priv *PrivateKey, msg, uid []byte) (r, s *big.Int, err error) {
+	e, err := messageDigest(&priv.PublicKey, msg, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	return signDigest(rand, priv, e)
+}
+
+// SignDigest signs a precomputed e = SM3(ZA || M) of Size bytes, for
+// callers that hash apart from signing.
+func SignDigest(rand io.Reader, priv *PrivateKey, digest []byte) (r, s *big.Int, err error) {
+	if len(digest) != sm3.Size {
+		return nil, nil, fmt.Errorf("sm2: digest must be %d bytes, got %d", sm3.Size, len(digest))
+	}
+	return signDigest(rand, priv, new(big.Int).SetBytes(digest))
+}
+
+func signDigest(rand io.Reader, priv *PrivateKey, e *big.Int) (r, s *big.Int, err error) {
+	nMinus1 := new(big.Int).Sub(params.N, one)
+	for {
+		k, err := randScalar(rand, nMinus1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if r, s, ok := signWithK(priv, e, k); ok {
+			return r, s, nil
+		}
+	}
+}
+
+// Digest returns e = SM3(ZA || msg), the value SignDigest and VerifyDigest
+// take.
+func Digest(pub *PublicKey, msg, uid []byte) ([]byte, error) {
+	e, err := messageDigest(pub, msg, uid)
+	if err != nil {
+		return nil, err
+	}
+	return e.FillBytes(make([]byte, sm3.Size)), nil
+}
+
+// signWithK runs the signing equations for a fixed nonce. It reports false
+// when k must be rejected and another one drawn.
+func signWithK(priv *PrivateKey, e, k *big.Int) (r, s *big.Int, ok bool) {
+	n := params.N
+	x1, _ := ScalarBaseMult(k.Bytes())
+	r = new(big.Int).Add(e, x1)
+	r.Mod(r, n)
+	if rk := new(big.Int).Add(r, k); r.Sign() == 0 || rk.Cmp(n) == 0 {
+		return nil, nil, false
+	}
+	dInv := new(big.Int).Add(priv.D, one)
+	dInv.ModInverse(dInv, n)
+	s = new(big.Int).Mul(r, priv.D)
+	s.Sub(k, s)
+	s.Mul(s, dInv)
+	s.Mod(s, n)
+	if s.Sign() == 0 {
+		return nil, nil, false
+	}
+	return r, s, true
+}
+
+// Verify reports whether (r, s) is a valid signature of msg under uid.
+func Verify(pub *PublicKey, msg, uid []byte, r, s *big.Int) bool {
+	e, err := messageDigest(pub, msg, uid)
+	if err != nil {
+		return false
+	}
+	return verifyDigest(pub, e, r, s)
+}
+
+// VerifyDigest reports whether (r, s) is a valid signature of the
+// precomputed digest e.
+func VerifyDigest(pub *PublicKey, digest []byte, r, s *big.Int) bool {
+	if len(digest) != sm3.Size {
+		return false
+	}
+	return verifyDigest(pub, new(big.Int).SetBytes(digest), r, s)
+}
+
+func verifyDigest(pub *PublicKey, e, r, s *big.Int) bool {
+	n := params.N
+	if r.Sign() <= 0 || r.Cmp(n) >= 0 || s.Sign() <= 0 || s.Cmp(n) >= 0 {
+		return false
+	}
+	t := new(big.Int).Add(r, s)
+	t.Mod(t, n)
+	if t.Sign() == 0 {
+		return false
+	}
+	x1, y1 := ScalarBaseMult(s.Bytes())
+	x2, y2 := ScalarMult(pub.X, pub.Y, t.Bytes())
+	x, _ := Add(x1, y1, x2, y2)
+	x.Add(x, e)
+	x.Mod(x, n)
+	return x.Cmp(r) == 0
+}
+
+type signature struct {
+	R, S *big.Int
+}
+
+// MarshalSignature encodes (r, s) as an ASN.1 DER SEQUENCE.
+func MarshalSignature(r, s *big.Int) ([]byte, error) {
+	return asn1.Marshal(signature{r, s})
+}
+
+// UnmarshalSignature decodes an ASN.1 DER signature.
+func UnmarshalSignature(der []byte) (r, s *big.Int, err error) {
+	var sig signature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, nil, fmt.Errorf("sm2: malformed signature: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, nil, errors.New("sm2: trailing data after signature")
+	}
+	return sig.R, sig.S, nil
+}
+
+// KDF is the SM3-based key derivation function from GB/T 32918.4.
+func KDF(z []byte, klen int) []byte {
+	out := make([]byte, 0, klen+sm3.Size)
+	var ct [4]byte
+	for i := uint32(1); len(out) < klen; i++ {
+		binary.BigEndian.PutUint32(ct[:], i)
+		h := sm3.New()
+		h.Write(z)
+		h.Write(ct[:])
+		out = h.Sum(out)
+	}
+	return out[:klen]
+}
+
+// Encrypt encrypts msg for pub and returns C1 || C3 || C2, with C1 an
+// uncompressed point.
+func Encrypt(rand io.Reader, pub *PublicKey, msg []byte) ([]byte, error) {
+	if len(msg) == 0 {
+		return nil, errors.New("sm2: plaintext is empty")
+	}
+	nMinus1 := new(big.Int).Sub(params.N, one)
+	for {
+		k, err := randScalar(rand, nMinus1)
+		if err != nil {
+			return nil, err
+		}
+		if ct, ok := encryptWithK(pub, msg, k); ok {
+			return ct, nil
+		}
+	}
+}
+
+func encryptWithK(pub *PublicKey, msg []byte, k *big.Int) ([]byte, bool) {
+	x1, y1 := ScalarBaseMult(k.Bytes())
+	x2, y2 := ScalarMult(pub.X, pub.Y, k.Bytes())
+	x2b := x2.FillBytes(make([]byte, 32))
+	y2b := y2.FillBytes(make([]byte, 32))
+
+	t := KDF(append(append([]byte{}, x2b...), y2b...), len(msg))
+	if allZero(t) {
+		return nil, false
+	}
+	c2 := make([]byte, len(msg))
+	subtle.XORBytes(c2, msg, t)
+
+	h := sm3.New()
+	h.Write(x2b)
+	h.Write(msg)
+	h.Write(y2b)
+
+	c1 := (&PublicKey{X: x1, Y: y1}).Bytes()
+	out := append(c1, h.Sum(nil)...)
+	return append(out, c2...), true
+}
+
+// Decrypt decrypts a C1 || C3 || C2 ciphertext.
+func Decrypt(priv *PrivateKey, ct []byte) ([]byte, error) {
+	if len(ct) <= 65+sm3.Size {
+		return nil, errors.New("sm2: ciphertext too short")
+	}
+	c1, err := ParsePublicKey(ct[:65])
+	if err != nil {
+		return nil, fmt.Errorf("sm2: invalid C1: %w", err)
+	}
+	c3 := ct[65 : 65+sm3.Size]
+	c2 := ct[65+sm3.Size:]
+
+	x2, y2 := ScalarMult(c1.X, c1.Y, priv.D.Bytes())
+	x2b := x2.FillBytes(make([]byte, 32))
+	y2b := y2.FillBytes(make([]byte, 32))
+
+	t := KDF(append(append([]byte{}, x2b...), y2b...), len(c2))
+	if allZero(t) {
+		return nil, ErrDecryption
+	}
+	msg := make([]byte, len(c2))
+	subtle.XORBytes(msg, c2, t)
+
+	h := sm3.New()
+	h.Write(x2b)
+	h.Write(msg)
+	h.Write(y2b)
+	if subtle.ConstantTimeCompare(h.Sum(nil), c3) != 1 {
+		return nil, ErrDecryption
+	}
+	return msg, nil
+}
+
+// cipherASN1 is the SM2Cipher structure of GM/T 0009.
+type cipherASN1 struct {
+	X, Y       *big.Int
+	Hash       []byte
+	CipherText []byte
+}
+
+// MarshalCiphertext converts a C1 || C3 || C2 ciphertext to the DER
+// encoded SM2Cipher SEQUENCE of GM/T 0009.
+func MarshalCiphertext(ct []byte) ([]byte, error) {
+	if len(ct) <= 65+sm3.Size || ct[0] != 4 {
+		return nil, errors.New("sm2: malformed ciphertext")
+	}
+	return asn1.Marshal(cipherASN1{
+		X:          new(big.Int).SetBytes(ct[1:33]),
+		Y:          new(big.Int).SetBytes(ct[33:65]),
+		Hash:       ct[65 : 65+sm3.Size],
+		CipherText: ct[65+sm3.Size:],
+	})
+}
+
+// UnmarshalCiphertext converts a DER SM2Cipher to C1 || C3 || C2.
+func UnmarshalCiphertext(der []byte) ([]byte, error) {
+	var c cipherASN1
+	rest, err := asn1.Unmarshal(der, &c)
+	if err != nil {
+		return nil, fmt.Errorf("sm2: malformed ciphertext: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("sm2: trailing data after ciphertext")
+	}
+	if c.X.Sign() < 0 || c.Y.Sign() < 0 || c.X.BitLen() > 256 || c.Y.BitLen() > 256 || len(c.Hash) != sm3.Size {
+		return nil, errors.New("sm2: malformed ciphertext")
+	}
+	c1 := (&PublicKey{X: c.X, Y: c.Y}).Bytes()
+	return append(append(c1, c.Hash...), c.CipherText...), nil
+}
+
+func allZero(b []byte) bool {
+	var acc byte
+	for _, v := range b {
+		acc |= v
+	}
+	return acc == 0
+}
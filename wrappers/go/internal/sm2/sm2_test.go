@@ -0,0 +1,442 @@
+package sm2
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(strings.ReplaceAll(s, " ", ""))
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+func TestCurve(t *testing.T) {
+	if !IsOnCurve(params.Gx, params.Gy) {
+		t.Fatal("base point is not on the curve")
+	}
+	x, y := ScalarBaseMult(params.N.Bytes())
+	if x.Sign() != 0 || y.Sign() != 0 {
+		t.Fatal("n·G is not the point at infinity")
+	}
+	// 3G computed by addition must equal 3G computed by scalar multiplication.
+	x2, y2 := Double(params.Gx, params.Gy)
+	x3, y3 := Add(x2, y2, params.Gx, params.Gy)
+	sx, sy := ScalarBaseMult([]byte{3})
+	if x3.Cmp(sx) != 0 || y3.Cmp(sy) != 0 {
+		t.Fatal("G+2G != 3G")
+	}
+}
+
+// Example from GM/T 0003.5-2012 using the recommended curve.
+var (
+	exampleD   = mustHex("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8")
+	examplePub = mustHex("04 09F9DF311E5421A150DD7D161E4BC5C672179FAD1833FC076BB08FF356F35020" +
+		"CCEA490CE26775A52DC6EA718CC1AA600AED05FBF35E084A6632F6072DA9AD13")
+	exampleK = mustHex("59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21")
+)
+
+func TestStandardSignature(t *testing.T) {
+	priv, err := NewPrivateKey(exampleD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(priv.PublicKey.Bytes(), examplePub) {
+		t.Fatalf("public key = %x", priv.PublicKey.Bytes())
+	}
+	e, err := messageDigest(&priv.PublicKey, []byte("message digest"), []byte(DefaultUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, ok := signWithK(priv, e, new(big.Int).SetBytes(exampleK))
+	if !ok {
+		t.Fatal("nonce rejected")
+	}
+	wantR := "f5a03b0648d2c4630eeac513e1bb81a15944da3827d5b74143ac7eaceee720b3"
+	wantS := "b1b6aa29df212fd8763182bc0d421ca1bb9038fd1f7f42d4840b69c485bbc1aa"
+	if hex.EncodeToString(r.Bytes()) != wantR || hex.EncodeToString(s.Bytes()) != wantS {
+		t.Fatalf("signature = (%x, %x)", r, s)
+	}
+	if !Verify(&priv.PublicKey, []byte("message digest"), []byte(DefaultUID), r, s) {
+		t.Fatal("standard signature does not verify")
+	}
+}
+
+func TestStandardEncryption(t *testing.T) {
+	priv, _ := NewPrivateKey(exampleD)
+	ct, ok := encryptWithK(&priv.PublicKey, []byte("encryption standard"), new(big.Int).SetBytes(exampleK))
+	if !ok {
+		t.Fatal("nonce rejected")
+	}
+	want := mustHex("04 04EBFC718E8D1798620432268E77FEB6415E2EDE0E073C0F4F640ECD2E149A73" +
+		"E858F9D81E5430A57B36DAAB8F950A3C64E6EE6A63094D99283AFF767E124DF0" +
+		"59983C18F809E262923C53AEC295D30383B54E39D609D160AFCB1908D0BD8766" +
+		"21886CA989CA9C7D58087307CA93092D651EFA")
+	if !bytes.Equal(ct, want) {
+		t.Fatalf("ciphertext = %x", ct)
+	}
+	pt, err := Decrypt(priv, ct)
+	if err != nil || string(pt) != "encryption standard" {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+// The KEM key is the key stream of the standard's encryption example.
+func TestEncapsulate(t *testing.T) {
+	priv, _ := NewPrivateKey(exampleD)
+	msg := []byte("encryption standard")
+	key, c := encapsulateWithK(&priv.PublicKey, new(big.Int).SetBytes(exampleK), len(msg))
+	ct, _ := encryptWithK(&priv.PublicKey, msg, new(big.Int).SetBytes(exampleK))
+	if !bytes.Equal(c, ct[:65]) {
+		t.Errorf("C = %x, want C1 %x", c, ct[:65])
+	}
+	for i := range msg {
+		if key[i]^msg[i] != ct[65+32+i] {
+			t.Fatalf("key = %x is not the encryption key stream", key)
+		}
+	}
+	got, err := Decapsulate(priv, c, len(msg))
+	if err != nil || !bytes.Equal(got, key) {
+		t.Fatalf("Decapsulate = %x, %v", got, err)
+	}
+
+	key, c, err = Encapsulate(rand.Reader, &priv.PublicKey, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := Decapsulate(priv, c, 16); !bytes.Equal(got, key) {
+		t.Error("random encapsulation does not round-trip")
+	}
+	c[64] ^= 1
+	if _, err := Decapsulate(priv, c, 16); err == nil {
+		t.Error("point off the curve accepted")
+	}
+}
+
+func TestECDH(t *testing.T) {
+	a, _ := GenerateKey(rand.Reader)
+	b, _ := GenerateKey(rand.Reader)
+	ab, err := ECDH(a, &b.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba, _ := ECDH(b, &a.PublicKey)
+	if !bytes.Equal(ab, ba) || len(ab) != 32 {
+		t.Errorf("shared secrets differ: %x, %x", ab, ba)
+	}
+	if _, err := ECDH(a, &PublicKey{X: new(big.Int), Y: new(big.Int)}); err == nil {
+		t.Error("point at infinity accepted")
+	}
+}
+
+func TestSignVerifyRoundTrip(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("interop")
+	r, s, err := Sign(rand.Reader, priv, msg, []byte(DefaultUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := MarshalSignature(r, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, s2, err := UnmarshalSignature(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(&priv.PublicKey, msg, []byte(DefaultUID), r2, s2) {
+		t.Fatal("signature does not verify")
+	}
+	if Verify(&priv.PublicKey, []byte("tampered"), []byte(DefaultUID), r2, s2) {
+		t.Fatal("signature verifies for a different message")
+	}
+}
+
+func TestDecryptRejectsTampering(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	ct, err := Encrypt(rand.Reader, &priv.PublicKey, []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct[len(ct)-1] ^= 1
+	if _, err := Decrypt(priv, ct); err == nil {
+		t.Fatal("tampered ciphertext decrypted")
+	}
+}
+
+func TestCiphertextASN1(t *testing.T) {
+	priv, _ := NewPrivateKey(exampleD)
+	ct, _ := encryptWithK(&priv.PublicKey, []byte("encryption standard"), new(big.Int).SetBytes(exampleK))
+	der, err := MarshalCiphertext(ct)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// x has its high bit clear; y's first byte is 0xe8 and needs a zero
+	// prefix.
+	want := "307c" + "0220" + "04ebfc718e8d1798620432268e77feb6415e2ede0e073c0f4f640ecd2e149a73" +
+		"022100" + "e858f9d81e5430a57b36daab8f950a3c64e6ee6a63094d99283aff767e124df0" +
+		"0420" + "59983c18f809e262923c53aec295d30383b54e39d609d160afcb1908d0bd8766" +
+		"0413" + "21886ca989ca9c7d58087307ca93092d651efa"
+	if hex.EncodeToString(der) != want {
+		t.Fatalf("DER = %x", der)
+	}
+	back, err := UnmarshalCiphertext(der)
+	if err != nil || !bytes.Equal(back, ct) {
+		t.Fatalf("UnmarshalCiphertext = %x, %v", back, err)
+	}
+	if _, err := UnmarshalCiphertext(append(der, 0)); err == nil {
+		t.Error("trailing data accepted")
+	}
+}
+
+func TestCompressedPublicKey(t *testing.T) {
+	for i := 0; i < 8; i++ {
+		priv, _ := GenerateKey(rand.Reader)
+		c := priv.PublicKey.CompressedBytes()
+		pub, err := ParsePublicKey(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(pub.Bytes(), priv.PublicKey.Bytes()) {
+			t.Fatalf("decompressed %x to %x", c, pub.Bytes())
+		}
+	}
+	// Gy ends in 0xa0, so G compresses with the even prefix.
+	g := (&PublicKey{X: params.Gx, Y: params.Gy}).CompressedBytes()
+	if want := "0232c4ae2c1f1981195f9904466a39c9948fe30bbff2660be1715a4589334c74c7"; hex.EncodeToString(g) != want {
+		t.Errorf("G compresses to %x", g)
+	}
+	bad := make([]byte, 33)
+	bad[0] = 2
+	for x := byte(0); ; x++ {
+		bad[32] = x
+		if decompressY(new(big.Int).SetBytes(bad[1:]), false) == nil {
+			break
+		}
+	}
+	if _, err := ParsePublicKey(bad); err == nil {
+		t.Error("x without a curve point accepted")
+	}
+}
+
+func TestPKCS8AndPKIX(t *testing.T) {
+	priv, _ := NewPrivateKey(exampleD)
+	der, err := MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Algorithm identifier: id-ecPublicKey with the sm2p256v1 curve.
+	if !bytes.Contains(der, mustHex("301306072a8648ce3d020106082a811ccf5501822d")) {
+		t.Fatalf("PKCS #8 = %x", der)
+	}
+	d, err := UnmarshalPKCS8PrivateKey(der)
+	if err != nil || !bytes.Equal(d, exampleD) {
+		t.Fatalf("UnmarshalPKCS8PrivateKey = %x, %v", d, err)
+	}
+
+	spki, err := MarshalPKIXPublicKey(examplePub)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "3059301306072a8648ce3d020106082a811ccf5501822d034200" + hex.EncodeToString(examplePub)
+	if hex.EncodeToString(spki) != want {
+		t.Fatalf("SPKI = %x", spki)
+	}
+	point, err := UnmarshalPKIXPublicKey(spki)
+	if err != nil || !bytes.Equal(point, examplePub) {
+		t.Fatalf("UnmarshalPKIXPublicKey = %x, %v", point, err)
+	}
+
+	// A P-256 key must be rejected.
+	p256 := mustHex("3059301306072a8648ce3d020106082a8648ce3d030107034200")
+	if _, err := UnmarshalPKIXPublicKey(append(p256, examplePub...)); err == nil {
+		t.Error("P-256 SubjectPublicKeyInfo accepted")
+	}
+}
+
+func TestKeyExchange(t *testing.T) {
+	// GB/T 32918.5 example: users A and B with the default identity agree
+	// on a 128-bit key.
+	key := func(d string) *PrivateKey {
+		k, err := NewPrivateKey(mustHex(d))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return k
+	}
+	a := key("81EB26E941BB5AF16DF116495F90695272AE2CD63D6C4AE1678418BE48230029")
+	b := key("785129917D45A9EA5437A59356B82338EAADDA6CEB199088F14AE10DEFA229B5")
+	ra := key("D4DE15474DB74D06491C440D305E012400990F3E390C7E87153C12DB2EA60BB3")
+	rb := key("7E07124814B309489125EAED101113164EBF0F3458C5BD88335C1F9D596243D6")
+	uid := []byte(DefaultUID)
+
+	ka, sa, wantSB, err := (&KeyExchange{
+		Initiator: true, Static: a, Ephemeral: ra, UID: uid,
+		PeerStatic: &b.PublicKey, PeerEphemeral: &rb.PublicKey, PeerUID: uid,
+	}).Agree(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, sb, wantSA, err := (&KeyExchange{
+		Static: b, Ephemeral: rb, UID: uid,
+		PeerStatic: &a.PublicKey, PeerEphemeral: &ra.PublicKey, PeerUID: uid,
+	}).Agree(16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := mustHex("6C89347354DE2484C60B4AB1FDE4C6E5"); !bytes.Equal(ka, want) || !bytes.Equal(kb, want) {
+		t.Errorf("KA = %x, KB = %x, want %x", ka, kb, want)
+	}
+	if want := mustHex("18C7894B3816DF16CF07B05C5EC0BEF5D655D58F779CC1B400A4F3884644DB88"); !bytes.Equal(sa, want) || !bytes.Equal(wantSA, want) {
+		t.Errorf("SA = %x, expected by B %x, want %x", sa, wantSA, want)
+	}
+	if want := mustHex("D3A0FE15DEE185CEAE907A6B595CC32A266ED7B3367E9983A896DC32FA20F8EB"); !bytes.Equal(sb, want) || !bytes.Equal(wantSB, want) {
+		t.Errorf("SB = %x, expected by A %x, want %x", sb, wantSB, want)
+	}
+}
+
+func TestNonceGeneratorRFC6979(t *testing.T) {
+	// RFC 6979 A.2.5: P-256, SHA-256, message "sample".
+	q, _ := new(big.Int).SetString("FFFFFFFF00000000FFFFFFFFFFFFFFFFBCE6FAADA7179E84F3B9CAC2FC632551", 16)
+	x, _ := new(big.Int).SetString("C9AFA9D845BA75166B5C215767B1D6934E50C3DB36E89B127B8A622B120F6721", 16)
+	h1 := sha256.Sum256([]byte("sample"))
+	k := newNonceGenerator(sha256.New, q, x, h1[:]).next()
+	if want := "a6e3c57dd01abe90086538398355dd4c3b17aa873382b0f24d6129493d8aad60"; k.Text(16) != want {
+		t.Errorf("k = %x, want %s", k, want)
+	}
+}
+
+func TestSignDeterministic(t *testing.T) {
+	priv, err := NewPrivateKey(mustHex("3945208F7B2144B13F36E38AC6D39F95889393692860B51A42FB81EF4DF7C5B8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, uid := []byte("message digest"), []byte(DefaultUID)
+	r1, s1, err := SignDeterministic(priv, msg, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, s2, _ := SignDeterministic(priv, msg, uid)
+	if r1.Cmp(r2) != 0 || s1.Cmp(s2) != 0 {
+		t.Fatal("signatures differ")
+	}
+	if !Verify(&priv.PublicKey, msg, uid, r1, s1) {
+		t.Fatal("signature does not verify")
+	}
+	if r3, _, _ := SignDeterministic(priv, []byte("other"), uid); r3.Cmp(r1) == 0 {
+		t.Fatal("different messages share a nonce")
+	}
+	// Golden values; a change here breaks vectors published by the harness.
+	if r1.Text(16) != "24858ee71d63e687feefe41f5af80a59f0791eb1dabc2bbe71daf0e57f06c367" ||
+		s1.Text(16) != "3d15550de52785a435004c937256ac715c0e04176ac57062c6722fa692f7a491" {
+		t.Errorf("r = %x, s = %x", r1, s1)
+	}
+}
+
+func TestRecoverPublicKeys(t *testing.T) {
+	priv, err := GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e, err := Digest(&priv.PublicKey, []byte("recover"), []byte(DefaultUID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s, err := SignDigest(rand.Reader, priv, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := RecoverPublicKeys(e, r, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, k := range keys {
+		if !VerifyDigest(k, e, r, s) {
+			t.Errorf("candidate %x does not verify", k.Bytes())
+		}
+		found = found || k.X.Cmp(priv.X) == 0 && k.Y.Cmp(priv.Y) == 0
+	}
+	if !found || len(keys) < 2 {
+		t.Fatalf("signer's key not among %d candidates", len(keys))
+	}
+	if _, err := RecoverPublicKeys(e, r, new(big.Int).Sub(params.N, r)); err == nil {
+		t.Error("r + s = n accepted")
+	}
+}
+
+func TestCoSign(t *testing.T) {
+	d1, p1, err := CoSignClientShare(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, pub, err := CoSignServerShare(rand.Reader, p1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The joint key is (d1·d2)⁻¹ - 1.
+	d := new(big.Int).Mul(d1, d2)
+	d.ModInverse(d.Mod(d, params.N), params.N)
+	d.Sub(d, one)
+	if x, y := ScalarBaseMult(d.Bytes()); x.Cmp(pub.X) != 0 || y.Cmp(pub.Y) != 0 {
+		t.Fatal("joint public key does not match the shares")
+	}
+
+	e, _ := Digest(pub, []byte("co-signed"), []byte(DefaultUID))
+	k1, q1, err := CoSignClientStart(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, s2, s3, err := CoSignServer(rand.Reader, d2, e, q1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := CoSignClientFinish(d1, k1, r, s2, s3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(pub, []byte("co-signed"), []byte(DefaultUID), r, s) {
+		t.Fatal("co-signature does not verify")
+	}
+}
+
+func TestShamir(t *testing.T) {
+	priv, _ := GenerateKey(rand.Reader)
+	shares, err := SplitKey(rand.Reader, priv.D, 5, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, subset := range [][]int{{0, 1, 2}, {4, 2, 0}, {1, 3, 4}, {0, 1, 2, 3, 4}} {
+		var pick []Share
+		for _, i := range subset {
+			s, err := ParseShare(shares[i].Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			pick = append(pick, s)
+		}
+		d, err := CombineKey(pick)
+		if err != nil || d.Cmp(priv.D) != 0 {
+			t.Fatalf("shares %v: d = %x, %v", subset, d, err)
+		}
+	}
+	if d, _ := CombineKey(shares[:2]); d.Cmp(priv.D) == 0 {
+		t.Fatal("two of three shares recovered the key")
+	}
+	if _, err := CombineKey([]Share{shares[0], shares[0]}); err == nil {
+		t.Fatal("duplicate shares accepted")
+	}
+	if _, err := SplitKey(rand.Reader, priv.D, 3, 4); err == nil {
+		t.Fatal("threshold above count accepted")
+	}
+}
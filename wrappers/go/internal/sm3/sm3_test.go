@@ -0,0 +1,80 @@
+package sm3
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Example vectors from GB/T 32905-2016 Appendix A.
+var vectors = []struct {
+	in, out string
+}{
+	{"abc", "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	{strings.Repeat("abcd", 16), "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+}
+
+func TestSum(t *testing.T) {
+	for _, v := range vectors {
+		got := Sum([]byte(v.in))
+		if hex.EncodeToString(got[:]) != v.out {
+			t.Errorf("Sum(%q) = %x, want %s", v.in, got, v.out)
+		}
+	}
+}
+
+func TestIncremental(t *testing.T) {
+	for _, v := range vectors {
+		h := New()
+		for i := 0; i < len(v.in); i++ {
+			h.Write([]byte{v.in[i]})
+		}
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.out {
+			t.Errorf("incremental %q = %s, want %s", v.in, got, v.out)
+		}
+		// Sum must not disturb the running state.
+		if got := hex.EncodeToString(h.Sum(nil)); got != v.out {
+			t.Errorf("second Sum %q = %s, want %s", v.in, got, v.out)
+		}
+	}
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	msg := []byte(strings.Repeat("abcd", 40) + "xyz")
+	want := Sum(msg)
+	for _, cut := range []int{0, 3, 4, 7, 63, 64, 65, 130} {
+		h := New()
+		h.Write(msg[:cut])
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if words := cut % BlockSize / 4; len(state) != 53+4*words {
+			t.Errorf("cut %d: state is %d bytes", cut, len(state))
+		}
+		for _, s := range [][]byte{state, state[:len(state)-1]} {
+			r := New()
+			if err := r.(encoding.BinaryUnmarshaler).UnmarshalBinary(s); err != nil {
+				t.Fatalf("cut %d: %v", cut, err)
+			}
+			r.Write(msg[cut:])
+			if got := r.Sum(nil); !bytes.Equal(got, want[:]) {
+				t.Errorf("cut %d: resumed digest = %x", cut, got)
+			}
+		}
+	}
+
+	h := New()
+	h.Write([]byte("abcdef"))
+	state, _ := h.(encoding.BinaryMarshaler).MarshalBinary()
+	// "ef" is the partial word; "abcd" the only complete one.
+	if got := hex.EncodeToString(state[:16]); got != "65660000000000020000000000000006" {
+		t.Errorf("header = %s", got)
+	}
+	state[15]++ // byte count no longer matches the buffer
+	if err := New().(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err == nil {
+		t.Error("inconsistent state accepted")
+	}
+}
@@ -0,0 +1,65 @@
+package sm3
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// purposeAny is the ordinal of CryptoServicePurpose.ANY, which Bouncy
+// Castle appends to encoded digest states.
+const purposeAny = 9
+
+const stateHeaderSize = 52
+
+// MarshalBinary encodes the running state in the layout of Bouncy Castle's
+// EncodableDigest.getEncodedState for GeneralDigest subclasses:
+//
+//	xBuf[4] | xBufOff uint32 | byteCount uint64 | V[8] uint32 |
+//	xOff uint32 | inwords[xOff] uint32 | purpose byte
+//
+// All integers are big-endian. xBuf holds the bytes of a partial word and
+// inwords the complete words of a partial block.
+func (d *digest) MarshalBinary() ([]byte, error) {
+	words := d.nx / 4
+	b := make([]byte, stateHeaderSize+4*words+1)
+	copy(b, d.x[words*4:d.nx])
+	binary.BigEndian.PutUint32(b[4:], uint32(d.nx%4))
+	binary.BigEndian.PutUint64(b[8:], d.len)
+	for i, v := range d.h {
+		binary.BigEndian.PutUint32(b[16+4*i:], v)
+	}
+	binary.BigEndian.PutUint32(b[48:], uint32(words))
+	copy(b[stateHeaderSize:], d.x[:words*4])
+	b[len(b)-1] = purposeAny
+	return b, nil
+}
+
+// UnmarshalBinary restores a state written by MarshalBinary. The trailing
+// purpose byte is optional, as older Bouncy Castle releases omit it.
+func (d *digest) UnmarshalBinary(b []byte) error {
+	if len(b) < stateHeaderSize {
+		return errors.New("sm3: encoded state is too short")
+	}
+	partial := binary.BigEndian.Uint32(b[4:])
+	words := binary.BigEndian.Uint32(b[48:])
+	if partial > 3 || words > 15 {
+		return errors.New("sm3: invalid encoded state")
+	}
+	if n := stateHeaderSize + 4*int(words); len(b) != n && len(b) != n+1 {
+		return errors.New("sm3: encoded state has the wrong length")
+	}
+	count := binary.BigEndian.Uint64(b[8:])
+	nx := int(words*4 + partial)
+	if count%BlockSize != uint64(nx) {
+		return errors.New("sm3: encoded state byte count does not match its buffer")
+	}
+
+	d.len = count
+	for i := range d.h {
+		d.h[i] = binary.BigEndian.Uint32(b[16+4*i:])
+	}
+	copy(d.x[:], b[stateHeaderSize:stateHeaderSize+4*words])
+	copy(d.x[words*4:], b[:partial])
+	d.nx = nx
+	return nil
+}
@@ -0,0 +1,81 @@
+package sm4
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+)
+
+func mustHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// Example 1 from GB/T 32907-2016 Appendix A.
+func TestStandardVector(t *testing.T) {
+	key := mustHex("0123456789abcdeffedcba9876543210")
+	want := mustHex("681edf34d206965e86b3e94f536e4246")
+
+	c, err := NewCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]byte, BlockSize)
+	c.Encrypt(got, key)
+	if !bytes.Equal(got, want) {
+		t.Fatalf("Encrypt = %x, want %x", got, want)
+	}
+	c.Decrypt(got, got)
+	if !bytes.Equal(got, key) {
+		t.Fatalf("Decrypt = %x, want %x", got, key)
+	}
+}
+
+// Example 2 from GB/T 32907-2016 Appendix A: one million encryptions.
+func TestStandardVectorIterated(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping 1e6 iterations in short mode")
+	}
+	key := mustHex("0123456789abcdeffedcba9876543210")
+	want := mustHex("595298c7c6fd271f0402f804c33d3f66")
+
+	c, _ := NewCipher(key)
+	buf := append([]byte(nil), key...)
+	for i := 0; i < 1000000; i++ {
+		c.Encrypt(buf, buf)
+	}
+	if !bytes.Equal(buf, want) {
+		t.Fatalf("got %x, want %x", buf, want)
+	}
+}
+
+func TestKeySize(t *testing.T) {
+	if _, err := NewCipher(make([]byte, 15)); err == nil {
+		t.Fatal("expected error for 15-byte key")
+	}
+}
+
+// SM4-GCM example from RFC 8998 Appendix A.1.
+func TestGCMVector(t *testing.T) {
+	key := mustHex("0123456789abcdeffedcba9876543210")
+	nonce := mustHex("00001234567800000000abcd")
+	aad := mustHex("feedfacedeadbeeffeedfacedeadbeefabaddad2")
+	pt := mustHex("aaaaaaaaaaaaaaaabbbbbbbbbbbbbbbbccccccccccccccccdddddddddddddddd" +
+		"eeeeeeeeeeeeeeeeffffffffffffffffeeeeeeeeeeeeeeeeaaaaaaaaaaaaaaaa")
+	want := mustHex("17f399f08c67d5ee19d0dc9969c4bb7d5fd46fd3756489069157b282bb200735" +
+		"d82710ca5c22f0ccfa7cbf93d496ac15a56834cbcf98c397b4024a2691233b8d" +
+		"83de3541e4c2b58177e065a9bf7b62ec")
+
+	c, _ := NewCipher(key)
+	aead, err := cipher.NewGCM(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := aead.Seal(nil, nonce, pt, aad); !bytes.Equal(got, want) {
+		t.Fatalf("Seal = %x, want %x", got, want)
+	}
+}
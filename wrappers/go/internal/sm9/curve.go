@@ -0,0 +1,183 @@
+package sm9
+
+import (
+	"math/big"
+)
+
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("sm9: bad constant " + s)
+	}
+	return n
+}
+
+// The BN curve of GB/T 38635.1: E(Fq): y² = x³ + 5, with the sextic twist
+// E'(Fq²): y² = x³ + 5u carrying G2.
+var (
+	q = fromHex("B640000002A3A6F1D603AB4FF58EC74521F2934B1A7AEEDBE56F9B27E351457D")
+	// N is the order of G1, G2 and GT.
+	N = fromHex("B640000002A3A6F1D603AB4FF58EC74449F2934B18EA8BEEE56EE19CD69ECF25")
+	// ateLoop is 6t + 2 for the curve parameter t = 0x600000000058F98A.
+	ateLoop = fromHex("2400000000215D93E")
+
+	b  = big.NewInt(5)
+	b2 = fp2{new(big.Int), big.NewInt(5)}
+
+	g1Gen = &g1Point{
+		x: fromHex("93DE051D62BF718FF5ED0704487D01D6E1E4086909DC3280E8C4E4817C66DDDD"),
+		y: fromHex("21FE8DDA4F21E607631065125C395BBC1C1C00CBFA6024350C464CD70A3EA616"),
+	}
+	g2Gen = &g2Point{
+		x: fp2{
+			fromHex("3722755292130B08D2AAB97FD34EC120EE265948D19C17ABF9B7213BAF82D65B"),
+			fromHex("85AEF3D078640C98597B6027B441A01FF1DD2C190F5E93C454806C11D8806141"),
+		},
+		y: fp2{
+			fromHex("A7CF28D519BE3DA65F3170153D278FF247EFBA98A71A08116215BBA5C999A7C7"),
+			fromHex("17509B092E845C1266BA0D262CBEE6ED0736A96FA347C8BD856DC76B84EBEB96"),
+		},
+	}
+)
+
+func mod(x *big.Int) *big.Int { return x.Mod(x, q) }
+
+func inverse(x *big.Int) *big.Int { return new(big.Int).ModInverse(x, q) }
+
+// fp2 is a0 + a1·u in Fq² = Fq[u]/(u² + 2).
+type fp2 struct{ a0, a1 *big.Int }
+
+func (a fp2) isZero() bool { return a.a0.Sign() == 0 && a.a1.Sign() == 0 }
+
+func (a fp2) equal(b fp2) bool { return a.a0.Cmp(b.a0) == 0 && a.a1.Cmp(b.a1) == 0 }
+
+func fp2Add(a, b fp2) fp2 {
+	return fp2{mod(new(big.Int).Add(a.a0, b.a0)), mod(new(big.Int).Add(a.a1, b.a1))}
+}
+
+func fp2Sub(a, b fp2) fp2 {
+	return fp2{mod(new(big.Int).Sub(a.a0, b.a0)), mod(new(big.Int).Sub(a.a1, b.a1))}
+}
+
+func fp2Neg(a fp2) fp2 { return fp2Sub(fp2{new(big.Int), new(big.Int)}, a) }
+
+func fp2Mul(a, b fp2) fp2 {
+	t := new(big.Int).Mul(a.a1, b.a1)
+	c0 := new(big.Int).Mul(a.a0, b.a0)
+	c0.Sub(c0, t.Lsh(t, 1))
+	c1 := new(big.Int).Mul(a.a0, b.a1)
+	c1.Add(c1, new(big.Int).Mul(a.a1, b.a0))
+	return fp2{mod(c0), mod(c1)}
+}
+
+func fp2Scale(a fp2, k int64) fp2 {
+	kk := big.NewInt(k)
+	return fp2{mod(new(big.Int).Mul(a.a0, kk)), mod(new(big.Int).Mul(a.a1, kk))}
+}
+
+// fp2Inv returns 1/a, using (a0 + a1·u)(a0 - a1·u) = a0² + 2·a1².
+func fp2Inv(a fp2) fp2 {
+	n := new(big.Int).Mul(a.a1, a.a1)
+	n.Lsh(n, 1).Add(n, new(big.Int).Mul(a.a0, a.a0))
+	n = inverse(mod(n))
+	return fp2{mod(new(big.Int).Mul(a.a0, n)), mod(new(big.Int).Mul(new(big.Int).Neg(a.a1), n))}
+}
+
+// g1Point is an affine point of E(Fq); nil is the point at infinity.
+type g1Point struct{ x, y *big.Int }
+
+func (p *g1Point) onCurve() bool {
+	y2 := mod(new(big.Int).Mul(p.y, p.y))
+	x3 := new(big.Int).Mul(p.x, p.x)
+	x3.Mul(x3, p.x).Add(x3, b)
+	return y2.Cmp(mod(x3)) == 0
+}
+
+func g1Add(p1, p2 *g1Point) *g1Point {
+	if p1 == nil {
+		return p2
+	}
+	if p2 == nil {
+		return p1
+	}
+	var l *big.Int
+	if p1.x.Cmp(p2.x) == 0 {
+		if mod(new(big.Int).Add(p1.y, p2.y)).Sign() == 0 {
+			return nil
+		}
+		l = new(big.Int).Mul(p1.x, p1.x)
+		l.Mul(l, big.NewInt(3))
+		l.Mul(l, inverse(mod(new(big.Int).Lsh(p1.y, 1))))
+	} else {
+		l = new(big.Int).Sub(p2.y, p1.y)
+		l.Mul(l, inverse(mod(new(big.Int).Sub(p2.x, p1.x))))
+	}
+	mod(l)
+	x := new(big.Int).Mul(l, l)
+	x.Sub(x, p1.x).Sub(x, p2.x)
+	mod(x)
+	y := new(big.Int).Sub(p1.x, x)
+	y.Mul(y, l).Sub(y, p1.y)
+	return &g1Point{x, mod(y)}
+}
+
+func g1Mul(k *big.Int, p *g1Point) *g1Point {
+	var r *g1Point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = g1Add(r, r)
+		if k.Bit(i) == 1 {
+			r = g1Add(r, p)
+		}
+	}
+	return r
+}
+
+// g2Point is an affine point of the twist E'(Fq²); nil is the point at
+// infinity.
+type g2Point struct{ x, y fp2 }
+
+func (p *g2Point) onCurve() bool {
+	x3 := fp2Mul(fp2Mul(p.x, p.x), p.x)
+	return fp2Mul(p.y, p.y).equal(fp2Add(x3, b2))
+}
+
+func (p *g2Point) neg() *g2Point { return &g2Point{p.x, fp2Neg(p.y)} }
+
+// g2Slope returns the slope of the line through p1 and p2, the tangent
+// when they are equal, or false for a vertical line.
+func g2Slope(p1, p2 *g2Point) (fp2, bool) {
+	if p1.x.equal(p2.x) {
+		if fp2Add(p1.y, p2.y).isZero() {
+			return fp2{}, false
+		}
+		return fp2Mul(fp2Scale(fp2Mul(p1.x, p1.x), 3), fp2Inv(fp2Scale(p1.y, 2))), true
+	}
+	return fp2Mul(fp2Sub(p2.y, p1.y), fp2Inv(fp2Sub(p2.x, p1.x))), true
+}
+
+func g2Add(p1, p2 *g2Point) *g2Point {
+	if p1 == nil {
+		return p2
+	}
+	if p2 == nil {
+		return p1
+	}
+	l, ok := g2Slope(p1, p2)
+	if !ok {
+		return nil
+	}
+	x := fp2Sub(fp2Sub(fp2Mul(l, l), p1.x), p2.x)
+	y := fp2Sub(fp2Mul(l, fp2Sub(p1.x, x)), p1.y)
+	return &g2Point{x, y}
+}
+
+func g2Mul(k *big.Int, p *g2Point) *g2Point {
+	var r *g2Point
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = g2Add(r, r)
+		if k.Bit(i) == 1 {
+			r = g2Add(r, p)
+		}
+	}
+	return r
+}
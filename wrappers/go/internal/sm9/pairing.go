@@ -0,0 +1,215 @@
+package sm9
+
+import (
+	"math/big"
+)
+
+// fp12 is Σ c[i]·w^i in Fq¹² = Fq[w]/(w¹² + 2). The tower of GB/T 38635.1,
+// Fq⁴ = Fq²[v]/(v² - u) and Fq¹² = Fq⁴[w]/(w³ - v), is the same field
+// with v = w³ and u = w⁶; the flat form keeps the arithmetic short.
+type fp12 [12]*big.Int
+
+func fp12One() *fp12 {
+	var r fp12
+	for i := range r {
+		r[i] = new(big.Int)
+	}
+	r[0].SetInt64(1)
+	return &r
+}
+
+// fp12Mono returns c·w^k.
+func fp12Mono(k int, c *big.Int) *fp12 {
+	r := fp12One()
+	r[0].SetInt64(0)
+	r[k] = mod(new(big.Int).Set(c))
+	return r
+}
+
+// fp12FromFp2 embeds a0 + a1·u as a0 + a1·w⁶.
+func fp12FromFp2(a fp2) *fp12 {
+	r := fp12Mono(0, a.a0)
+	r[6] = new(big.Int).Set(a.a1)
+	return r
+}
+
+func (a *fp12) equal(b *fp12) bool {
+	for i := range a {
+		if a[i].Cmp(b[i]) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *fp12) isOne() bool { return a.equal(fp12One()) }
+
+func fp12Sub(a, b *fp12) *fp12 {
+	var r fp12
+	for i := range r {
+		r[i] = mod(new(big.Int).Sub(a[i], b[i]))
+	}
+	return &r
+}
+
+func fp12Mul(a, b *fp12) *fp12 {
+	var t [23]*big.Int
+	for i := range t {
+		t[i] = new(big.Int)
+	}
+	m := new(big.Int)
+	for i := range a {
+		if a[i].Sign() == 0 {
+			continue
+		}
+		for j := range b {
+			t[i+j].Add(t[i+j], m.Mul(a[i], b[j]))
+		}
+	}
+	var r fp12
+	for i := range r {
+		r[i] = new(big.Int).Set(t[i])
+		if i+12 < len(t) {
+			r[i].Sub(r[i], m.Lsh(t[i+12], 1))
+		}
+		mod(r[i])
+	}
+	return &r
+}
+
+func fp12Exp(a *fp12, k *big.Int) *fp12 {
+	r := fp12One()
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		r = fp12Mul(r, r)
+		if k.Bit(i) == 1 {
+			r = fp12Mul(r, a)
+		}
+	}
+	return r
+}
+
+// frobGamma[i] is γ^i for γ = (-2)^((q-1)/12), so that w^q = γ·w.
+var frobGamma = func() [12]*big.Int {
+	gamma := new(big.Int).Exp(new(big.Int).Sub(q, big.NewInt(2)), new(big.Int).Div(new(big.Int).Sub(q, big.NewInt(1)), big.NewInt(12)), q)
+	var g [12]*big.Int
+	g[0] = big.NewInt(1)
+	for i := 1; i < 12; i++ {
+		g[i] = mod(new(big.Int).Mul(g[i-1], gamma))
+	}
+	return g
+}()
+
+// frobenius returns a^q.
+func frobenius(a *fp12) *fp12 {
+	var r fp12
+	for i := range r {
+		r[i] = mod(new(big.Int).Mul(a[i], frobGamma[i]))
+	}
+	return &r
+}
+
+// fp12Inv returns 1/a as a^(q + q² + … + q¹¹) divided by the norm
+// a^(1 + q + … + q¹¹), which lies in Fq.
+func fp12Inv(a *fp12) *fp12 {
+	c := frobenius(a)
+	prod := c
+	for i := 0; i < 10; i++ {
+		c = frobenius(c)
+		prod = fp12Mul(prod, c)
+	}
+	n := inverse(fp12Mul(a, prod)[0])
+	var r fp12
+	for i := range r {
+		r[i] = mod(new(big.Int).Mul(prod[i], n))
+	}
+	return &r
+}
+
+// The untwisting map ψ(x, y) = (x·w⁻², y·w⁻³) takes E'(Fq²) to E(Fq¹²).
+var (
+	wInv  = fp12Mono(11, new(big.Int).Neg(inverse(big.NewInt(2)))) // w⁻¹ = -w¹¹/2
+	wInv2 = fp12Mul(wInv, wInv)
+	wInv3 = fp12Mul(wInv2, wInv)
+)
+
+func untwist(p *g2Point) (x, y *fp12) {
+	return fp12Mul(fp12FromFp2(p.x), wInv2), fp12Mul(fp12FromFp2(p.y), wInv3)
+}
+
+// g2Frobenius returns π_q(p), the image of ψ(p) under the q-power
+// Frobenius map, on the twist.
+func g2Frobenius(p *g2Point) *g2Point {
+	x, y := untwist(p)
+	x = fp12Mul(frobenius(x), fp12Mono(2, big.NewInt(1)))
+	y = fp12Mul(frobenius(y), fp12Mono(3, big.NewInt(1)))
+	return &g2Point{fp2{x[0], x[6]}, fp2{y[0], y[6]}}
+}
+
+// line evaluates at p the line through ψ(t) and ψ(s), the tangent when
+// t = s.
+func line(t, s *g2Point, p *g1Point) *fp12 {
+	l, _ := g2Slope(t, s)
+	// The slope on E is the slope on E' times w⁻³/w⁻² = w⁻¹.
+	lambda := fp12Mul(fp12FromFp2(l), wInv)
+	tx, ty := untwist(t)
+	r := fp12Sub(fp12Mono(0, p.y), ty)
+	return fp12Sub(r, fp12Mul(lambda, fp12Sub(fp12Mono(0, p.x), tx)))
+}
+
+// pair computes the R-ate pairing e(p, q) of GB/T 38635.1:
+//
+//	f = f_{a,Q}(P) · l_{aQ,π(Q)}(P) · l_{aQ+π(Q),-π²(Q)}(P)
+//
+// with a = 6t + 2, raised to (q¹² - 1)/N.
+func pair(p *g1Point, qq *g2Point) *fp12 {
+	t := qq
+	f := fp12One()
+	for i := ateLoop.BitLen() - 2; i >= 0; i-- {
+		f = fp12Mul(fp12Mul(f, f), line(t, t, p))
+		t = g2Add(t, t)
+		if ateLoop.Bit(i) == 1 {
+			f = fp12Mul(f, line(t, qq, p))
+			t = g2Add(t, qq)
+		}
+	}
+	q1 := g2Frobenius(qq)
+	q2 := g2Frobenius(q1).neg()
+	f = fp12Mul(f, line(t, q1, p))
+	t = g2Add(t, q1)
+	f = fp12Mul(f, line(t, q2, p))
+	return finalExp(f)
+}
+
+// hardExp is (q⁴ - q² + 1)/N.
+var hardExp = func() *big.Int {
+	q2 := new(big.Int).Mul(q, q)
+	e := new(big.Int).Mul(q2, q2)
+	e.Sub(e, q2).Add(e, big.NewInt(1))
+	return e.Div(e, N)
+}()
+
+// finalExp raises f to (q¹² - 1)/N = (q⁶ - 1)(q² + 1)(q⁴ - q² + 1)/N.
+func finalExp(f *fp12) *fp12 {
+	f6 := f
+	for i := 0; i < 6; i++ {
+		f6 = frobenius(f6)
+	}
+	f = fp12Mul(f6, fp12Inv(f))
+	f = fp12Mul(frobenius(frobenius(f)), f)
+	return fp12Exp(f, hardExp)
+}
+
+// gtOrder lists the exponents of w in the order GB/T 38635.1 writes the
+// coefficients of an Fq¹² element: highest tower component first, i.e.
+// the Fq⁴ coefficients of w², w, 1, each as the Fq² coefficients of v
+// and 1, each as those of u and 1.
+var gtOrder = [12]int{11, 5, 8, 2, 10, 4, 7, 1, 9, 3, 6, 0}
+
+// gtBytes returns the 384-byte encoding of a.
+func gtBytes(a *fp12) []byte {
+	out := make([]byte, 0, 12*32)
+	for _, i := range gtOrder {
+		out = append(out, a[i].FillBytes(make([]byte, 32))...)
+	}
+	return out
+}
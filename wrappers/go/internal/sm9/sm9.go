@@ -0,0 +1,506 @@
+// Package sm9 implements the SM9 identity-based cryptographic algorithms
+// (GB/T 38635-2020) over the 256-bit BN curve of the standard: digital
+// signatures, public key encryption and key encapsulation.
+//
+// Like package sm2, the arithmetic is built on math/big, is not constant
+// time, and exists to produce and check interoperability vectors.
+//
+// Points are written uncompressed with a 04 prefix; G2 coordinates, in
+// Fq², are written as in the standard with the coefficient of u first.
+package sm9
+
+import (
+	"crypto/subtle"
+	"encoding/asn1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// Function identifiers (hid) of the user keys, from GB/T 38635.1.
+const (
+	HIDSign        = 0x01
+	HIDKeyExchange = 0x02
+	HIDEncrypt     = 0x03
+)
+
+// Sizes of the encodings.
+const (
+	G1Size        = 65
+	G2Size        = 129
+	SignatureSize = 32 + G1Size
+	// C3Size is the length of the check value of a ciphertext.
+	C3Size = sm3.Size
+)
+
+// ErrDecryption is returned for every ciphertext that does not decrypt,
+// without saying which check failed.
+var ErrDecryption = errors.New("sm9: decryption failed")
+
+var one = big.NewInt(1)
+
+// MasterPrivateKey is the master secret of a key generation centre, ks
+// for signatures or ke for encryption.
+type MasterPrivateKey struct {
+	D *big.Int
+}
+
+// GenerateMasterKey returns a random master secret in [1, N-1].
+func GenerateMasterKey(rand io.Reader) (*MasterPrivateKey, error) {
+	d, err := randScalar(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &MasterPrivateKey{D: d}, nil
+}
+
+// NewMasterKey returns the master secret d, which must lie in [1, N-1].
+func NewMasterKey(d []byte) (*MasterPrivateKey, error) {
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() == 0 || k.Cmp(N) >= 0 {
+		return nil, errors.New("sm9: master key out of range")
+	}
+	return &MasterPrivateKey{D: k}, nil
+}
+
+// Bytes returns the 32-byte master secret.
+func (m *MasterPrivateKey) Bytes() []byte { return m.D.FillBytes(make([]byte, 32)) }
+
+// randScalar returns a random integer in [1, N-1].
+func randScalar(rand io.Reader) (*big.Int, error) {
+	nMinus1 := new(big.Int).Sub(N, one)
+	buf := make([]byte, 40)
+	if _, err := io.ReadFull(rand, buf); err != nil {
+		return nil, err
+	}
+	k := new(big.Int).SetBytes(buf)
+	return k.Mod(k, nMinus1).Add(k, one), nil
+}
+
+// userScalar returns ks / (H1(ID || hid) + ks) mod N, the scalar of the
+// user key for id and hid.
+func (m *MasterPrivateKey) userScalar(id []byte, hid byte) (*big.Int, error) {
+	t1 := h1(id, hid)
+	t1.Add(t1, m.D).Mod(t1, N)
+	if t1.Sign() == 0 {
+		return nil, errors.New("sm9: identity collides with the master key; generate a new master key")
+	}
+	t2 := new(big.Int).ModInverse(t1, N)
+	return t2.Mul(t2, m.D).Mod(t2, N), nil
+}
+
+// SignMasterPublicKey is Ppub-s = [ks]P2.
+type SignMasterPublicKey struct {
+	p *g2Point
+}
+
+// SignPublicKey returns the signature master public key of m.
+func (m *MasterPrivateKey) SignPublicKey() *SignMasterPublicKey {
+	return &SignMasterPublicKey{g2Mul(m.D, g2Gen)}
+}
+
+// Bytes returns the 129-byte encoding of the key.
+func (k *SignMasterPublicKey) Bytes() []byte { return marshalG2(k.p) }
+
+// ParseSignMasterPublicKey parses the encoding written by Bytes.
+func ParseSignMasterPublicKey(b []byte) (*SignMasterPublicKey, error) {
+	p, err := unmarshalG2(b)
+	if err != nil {
+		return nil, err
+	}
+	return &SignMasterPublicKey{p}, nil
+}
+
+// SignPrivateKey is a user's signing key dsA = [t2]P1.
+type SignPrivateKey struct {
+	p *g1Point
+}
+
+// SignUserKey derives the signing key of id with function identifier hid,
+// normally HIDSign.
+func (m *MasterPrivateKey) SignUserKey(id []byte, hid byte) (*SignPrivateKey, error) {
+	t2, err := m.userScalar(id, hid)
+	if err != nil {
+		return nil, err
+	}
+	return &SignPrivateKey{g1Mul(t2, g1Gen)}, nil
+}
+
+// Bytes returns the 65-byte encoding of the key.
+func (k *SignPrivateKey) Bytes() []byte { return marshalG1(k.p) }
+
+// ParseSignPrivateKey parses the encoding written by Bytes.
+func ParseSignPrivateKey(b []byte) (*SignPrivateKey, error) {
+	p, err := unmarshalG1(b)
+	if err != nil {
+		return nil, err
+	}
+	return &SignPrivateKey{p}, nil
+}
+
+// EncryptMasterPublicKey is Ppub-e = [ke]P1.
+type EncryptMasterPublicKey struct {
+	p *g1Point
+}
+
+// EncryptPublicKey returns the encryption master public key of m.
+func (m *MasterPrivateKey) EncryptPublicKey() *EncryptMasterPublicKey {
+	return &EncryptMasterPublicKey{g1Mul(m.D, g1Gen)}
+}
+
+// Bytes returns the 65-byte encoding of the key.
+func (k *EncryptMasterPublicKey) Bytes() []byte { return marshalG1(k.p) }
+
+// ParseEncryptMasterPublicKey parses the encoding written by Bytes.
+func ParseEncryptMasterPublicKey(b []byte) (*EncryptMasterPublicKey, error) {
+	p, err := unmarshalG1(b)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptMasterPublicKey{p}, nil
+}
+
+// EncryptPrivateKey is a user's decryption key deB = [t2]P2.
+type EncryptPrivateKey struct {
+	p *g2Point
+}
+
+// EncryptUserKey derives the decryption key of id with function
+// identifier hid, normally HIDEncrypt.
+func (m *MasterPrivateKey) EncryptUserKey(id []byte, hid byte) (*EncryptPrivateKey, error) {
+	t2, err := m.userScalar(id, hid)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptPrivateKey{g2Mul(t2, g2Gen)}, nil
+}
+
+// Bytes returns the 129-byte encoding of the key.
+func (k *EncryptPrivateKey) Bytes() []byte { return marshalG2(k.p) }
+
+// ParseEncryptPrivateKey parses the encoding written by Bytes.
+func ParseEncryptPrivateKey(b []byte) (*EncryptPrivateKey, error) {
+	p, err := unmarshalG2(b)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptPrivateKey{p}, nil
+}
+
+// Encrypt encrypts msg to id, with function identifier hid, under the
+// master public key, using the KDF-based stream cipher of GB/T 38635.2,
+// and returns C1 || C3 || C2 with C1 the 65-byte encoding of the point.
+func Encrypt(rand io.Reader, mpk *EncryptMasterPublicKey, id []byte, hid byte, msg []byte) ([]byte, error) {
+	for {
+		r, err := randScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		if ct, ok := encryptWithR(mpk, id, hid, msg, r); ok {
+			return ct, nil
+		}
+	}
+}
+
+// encryptWithR is steps A2 to A8 of the encryption algorithm with the
+// random r.
+func encryptWithR(mpk *EncryptMasterPublicKey, id []byte, hid byte, msg []byte, r *big.Int) ([]byte, bool) {
+	c1, k := encapsulate(mpk, id, hid, r, len(msg)+C3Size)
+	if len(msg) > 0 && allZero(k[:len(msg)]) {
+		return nil, false
+	}
+	c2 := make([]byte, len(msg))
+	for i := range msg {
+		c2[i] = msg[i] ^ k[i]
+	}
+	ct := append(c1, mac(k[len(msg):], c2)...)
+	return append(ct, c2...), true
+}
+
+// Decrypt decrypts the C1 || C3 || C2 ciphertext ct for id with the
+// user's key.
+func Decrypt(priv *EncryptPrivateKey, id []byte, ct []byte) ([]byte, error) {
+	if len(ct) < G1Size+C3Size {
+		return nil, ErrDecryption
+	}
+	c1, c3, c2 := ct[:G1Size], ct[G1Size:G1Size+C3Size], ct[G1Size+C3Size:]
+	k, err := decapsulate(priv, id, c1, len(c2)+C3Size)
+	if err != nil || len(c2) > 0 && allZero(k[:len(c2)]) {
+		return nil, ErrDecryption
+	}
+	if subtle.ConstantTimeCompare(mac(k[len(c2):], c2), c3) != 1 {
+		return nil, ErrDecryption
+	}
+	msg := make([]byte, len(c2))
+	for i := range c2 {
+		msg[i] = c2[i] ^ k[i]
+	}
+	return msg, nil
+}
+
+// Encapsulate returns a klen-byte key for id, with function identifier
+// hid, under the master public key and its 65-byte encapsulation C.
+func Encapsulate(rand io.Reader, mpk *EncryptMasterPublicKey, id []byte, hid byte, klen int) (key, c []byte, err error) {
+	if klen <= 0 {
+		return nil, nil, errors.New("sm9: key length must be positive")
+	}
+	for {
+		r, err := randScalar(rand)
+		if err != nil {
+			return nil, nil, err
+		}
+		if c, key = encapsulate(mpk, id, hid, r, klen); !allZero(key) {
+			return key, c, nil
+		}
+	}
+}
+
+// Decapsulate recovers the klen-byte key of the encapsulation c for id
+// with the user's key.
+func Decapsulate(priv *EncryptPrivateKey, id, c []byte, klen int) ([]byte, error) {
+	if klen <= 0 {
+		return nil, errors.New("sm9: key length must be positive")
+	}
+	key, err := decapsulate(priv, id, c, klen)
+	if err != nil || allZero(key) {
+		return nil, ErrDecryption
+	}
+	return key, nil
+}
+
+type ciphertextASN1 struct {
+	EnType     int
+	C1         asn1.BitString
+	C3         []byte
+	CipherText []byte
+}
+
+// MarshalCiphertext converts C1 || C3 || C2 to the DER SM9Cipher of
+// GM/T 0044, with EnType 0 for the KDF stream cipher.
+func MarshalCiphertext(ct []byte) ([]byte, error) {
+	if len(ct) < G1Size+C3Size {
+		return nil, errors.New("sm9: ciphertext too short")
+	}
+	return asn1.Marshal(ciphertextASN1{
+		C1:         asn1.BitString{Bytes: ct[:G1Size], BitLength: 8 * G1Size},
+		C3:         ct[G1Size : G1Size+C3Size],
+		CipherText: ct[G1Size+C3Size:],
+	})
+}
+
+// UnmarshalCiphertext converts the DER SM9Cipher to C1 || C3 || C2.
+func UnmarshalCiphertext(der []byte) ([]byte, error) {
+	var v ciphertextASN1
+	rest, err := asn1.Unmarshal(der, &v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 || len(v.C1.Bytes) != G1Size || v.C1.BitLength != 8*G1Size || len(v.C3) != C3Size {
+		return nil, errors.New("sm9: malformed ciphertext")
+	}
+	if v.EnType != 0 {
+		return nil, fmt.Errorf("sm9: unsupported ciphertext EnType %d (supported: 0, the KDF stream cipher)", v.EnType)
+	}
+	return append(append(v.C1.Bytes, v.C3...), v.CipherText...), nil
+}
+
+// encapsulate returns C = [r]QB for QB = [H1(ID || hid)]P1 + Ppub-e and
+// klen bytes of KDF(C || w || ID) for w = e(Ppub-e, P2)^r.
+func encapsulate(mpk *EncryptMasterPublicKey, id []byte, hid byte, r *big.Int, klen int) (c, k []byte) {
+	qb := g1Add(g1Mul(h1(id, hid), g1Gen), mpk.p)
+	c = marshalG1(g1Mul(r, qb))
+	w := fp12Exp(pair(mpk.p, g2Gen), r)
+	return c, kdf(c, w, id, klen)
+}
+
+// decapsulate recomputes the key of encapsulate from C with w = e(C, de).
+func decapsulate(priv *EncryptPrivateKey, id, c []byte, klen int) ([]byte, error) {
+	p, err := unmarshalG1(c)
+	if err != nil {
+		return nil, err
+	}
+	return kdf(c, pair(p, priv.p), id, klen), nil
+}
+
+// kdf is KDF(C || w || ID, klen), with C written without its 04 prefix.
+func kdf(c []byte, w *fp12, id []byte, klen int) []byte {
+	z := append(append(append([]byte{}, c[1:]...), gtBytes(w)...), id...)
+	return sm2.KDF(z, klen)
+}
+
+// mac is MAC(K2, Z) = SM3(Z || K2).
+func mac(k2, z []byte) []byte {
+	h := sm3.New()
+	h.Write(z)
+	h.Write(k2)
+	return h.Sum(nil)
+}
+
+func allZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Signature is an SM9 signature (h, S).
+type Signature struct {
+	H *big.Int
+	S *g1Point
+}
+
+// Sign signs msg with the user key priv under the master public key.
+func Sign(rand io.Reader, mpk *SignMasterPublicKey, priv *SignPrivateKey, msg []byte) (*Signature, error) {
+	g := pair(g1Gen, mpk.p)
+	for {
+		r, err := randScalar(rand)
+		if err != nil {
+			return nil, err
+		}
+		if sig, ok := signWithR(g, priv, msg, r); ok {
+			return sig, nil
+		}
+	}
+}
+
+// signWithR is steps A2 to A6 of the signature algorithm with g =
+// e(P1, Ppub-s) and the random r.
+func signWithR(g *fp12, priv *SignPrivateKey, msg []byte, r *big.Int) (*Signature, bool) {
+	w := fp12Exp(g, r)
+	h := h2(msg, gtBytes(w))
+	l := new(big.Int).Sub(r, h)
+	if l.Mod(l, N).Sign() == 0 {
+		return nil, false
+	}
+	return &Signature{H: h, S: g1Mul(l, priv.p)}, true
+}
+
+// Verify reports whether sig is a valid signature of msg by id, with
+// function identifier hid, under the master public key.
+func Verify(mpk *SignMasterPublicKey, id []byte, hid byte, msg []byte, sig *Signature) bool {
+	if sig.H.Sign() <= 0 || sig.H.Cmp(N) >= 0 || sig.S == nil {
+		return false
+	}
+	g := pair(g1Gen, mpk.p)
+	t := fp12Exp(g, sig.H)
+	p := g2Add(g2Mul(h1(id, hid), g2Gen), mpk.p)
+	u := pair(sig.S, p)
+	w := fp12Mul(u, t)
+	return h2(msg, gtBytes(w)).Cmp(sig.H) == 0
+}
+
+// Bytes returns h (32 bytes) followed by the encoding of S.
+func (sig *Signature) Bytes() []byte {
+	return append(sig.H.FillBytes(make([]byte, 32)), marshalG1(sig.S)...)
+}
+
+type signatureASN1 struct {
+	H []byte
+	S asn1.BitString
+}
+
+// MarshalASN1 returns the DER SEQUENCE { h OCTET STRING, S BIT STRING }
+// of GM/T 0044.
+func (sig *Signature) MarshalASN1() ([]byte, error) {
+	s := marshalG1(sig.S)
+	return asn1.Marshal(signatureASN1{sig.H.FillBytes(make([]byte, 32)), asn1.BitString{Bytes: s, BitLength: 8 * len(s)}})
+}
+
+// ParseSignature parses the encoding written by Bytes.
+func ParseSignature(b []byte) (*Signature, error) {
+	if len(b) != SignatureSize {
+		return nil, fmt.Errorf("sm9: signature must be %d bytes, got %d", SignatureSize, len(b))
+	}
+	s, err := unmarshalG1(b[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &Signature{H: new(big.Int).SetBytes(b[:32]), S: s}, nil
+}
+
+// UnmarshalSignatureASN1 parses the encoding written by MarshalASN1.
+func UnmarshalSignatureASN1(der []byte) (*Signature, error) {
+	var v signatureASN1
+	rest, err := asn1.Unmarshal(der, &v)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 || len(v.H) != 32 || v.S.BitLength != 8*len(v.S.Bytes) {
+		return nil, errors.New("sm9: malformed signature")
+	}
+	return ParseSignature(append(v.H, v.S.Bytes...))
+}
+
+// hashToRange is Hv of GB/T 38635.2 with SM3: SM3(prefix || z || ct) for
+// ct = 1, 2, truncated to 320 bits, reduced to [1, N-1].
+func hashToRange(prefix byte, parts ...[]byte) *big.Int {
+	var ha []byte
+	for ct := uint32(1); ct <= 2; ct++ {
+		h := sm3.New()
+		h.Write([]byte{prefix})
+		for _, p := range parts {
+			h.Write(p)
+		}
+		h.Write(binary.BigEndian.AppendUint32(nil, ct))
+		ha = h.Sum(ha)
+	}
+	k := new(big.Int).SetBytes(ha[:40])
+	return k.Mod(k, new(big.Int).Sub(N, one)).Add(k, one)
+}
+
+func h1(id []byte, hid byte) *big.Int { return hashToRange(0x01, id, []byte{hid}) }
+
+func h2(msg, w []byte) *big.Int { return hashToRange(0x02, msg, w) }
+
+func marshalG1(p *g1Point) []byte {
+	out := make([]byte, G1Size)
+	out[0] = 4
+	p.x.FillBytes(out[1:33])
+	p.y.FillBytes(out[33:])
+	return out
+}
+
+func unmarshalG1(b []byte) (*g1Point, error) {
+	if len(b) != G1Size || b[0] != 4 {
+		return nil, fmt.Errorf("sm9: G1 point must be %d bytes starting 04", G1Size)
+	}
+	p := &g1Point{new(big.Int).SetBytes(b[1:33]), new(big.Int).SetBytes(b[33:])}
+	if p.x.Cmp(q) >= 0 || p.y.Cmp(q) >= 0 || !p.onCurve() {
+		return nil, errors.New("sm9: G1 point is not on the curve")
+	}
+	return p, nil
+}
+
+func marshalG2(p *g2Point) []byte {
+	out := make([]byte, G2Size)
+	out[0] = 4
+	for i, c := range []*big.Int{p.x.a1, p.x.a0, p.y.a1, p.y.a0} {
+		c.FillBytes(out[1+32*i : 33+32*i])
+	}
+	return out
+}
+
+func unmarshalG2(b []byte) (*g2Point, error) {
+	if len(b) != G2Size || b[0] != 4 {
+		return nil, fmt.Errorf("sm9: G2 point must be %d bytes starting 04", G2Size)
+	}
+	var c [4]*big.Int
+	for i := range c {
+		c[i] = new(big.Int).SetBytes(b[1+32*i : 33+32*i])
+		if c[i].Cmp(q) >= 0 {
+			return nil, errors.New("sm9: G2 coordinate out of range")
+		}
+	}
+	p := &g2Point{fp2{c[1], c[0]}, fp2{c[3], c[2]}}
+	if !p.onCurve() || g2Mul(N, p) != nil {
+		return nil, errors.New("sm9: point is not in G2")
+	}
+	return p, nil
+}
@@ -0,0 +1,207 @@
+package sm9
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"math/big"
+	"testing"
+)
+
+func TestPairing(t *testing.T) {
+	g := pair(g1Gen, g2Gen)
+	if g.isOne() || !fp12Exp(g, N).isOne() {
+		t.Fatal("e(P1, P2) is not of order N")
+	}
+	a, b := big.NewInt(3), big.NewInt(5)
+	if !pair(g1Mul(a, g1Gen), g2Mul(b, g2Gen)).equal(fp12Exp(g, big.NewInt(15))) {
+		t.Error("pairing is not bilinear")
+	}
+}
+
+// The signature example of GB/T 38635.2 annex A.
+func TestSignVector(t *testing.T) {
+	ks, _ := hex.DecodeString("000130E78459D78545CB54C587E02CF480CE0B66340F319F348A1D5B1F2DC5F4")
+	master, err := NewMasterKey(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpk := master.SignPublicKey()
+	if got := hex.EncodeToString(mpk.Bytes()); got != "04"+
+		"9f64080b3084f733e48aff4b41b565011ce0711c5e392cfb0ab1b6791b94c408"+
+		"29dba116152d1f786ce843ed24a3b573414d2177386a92dd8f14d65696ea5e32"+
+		"69850938abea0112b57329f447e3a0cbad3e2fdb1a77f335e89e1408d0ef1c25"+
+		"41e00a53dda532da1a7ce027b7a46f741006e85f5cdff0730e75c05fb4e3216d" {
+		t.Errorf("Ppub-s = %s", got)
+	}
+	priv, err := master.SignUserKey([]byte("Alice"), HIDSign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(priv.Bytes()); got != "04"+
+		"a5702f05cf1315305e2d6eb64b0deb923db1a0bcf0caff90523ac8754aa69820"+
+		"78559a844411f9825c109f5ee3f52d720dd01785392a727bb1556952b2b013d3" {
+		t.Errorf("dsA = %s", got)
+	}
+	msg := []byte("Chinese IBS standard")
+	r := fromHex("033C8616B06704813203DFD00965022ED15975C662337AED648835DC4B1CBE")
+	sig, ok := signWithR(pair(g1Gen, mpk.p), priv, msg, r)
+	if !ok {
+		t.Fatal("signWithR failed")
+	}
+	want := "823c4b21e4bd2dfe1ed92c606653e996668563152fc33f55d7bfbb9bd9705adb" + "04" +
+		"73bf96923ce58b6ad0e13e9643a406d8eb98417c50ef1b29cef9adb48b6d598c" +
+		"856712f1c2e0968ab7769f42a99586aed139d5b8b3e15891827cc2aced9baa05"
+	if got := hex.EncodeToString(sig.Bytes()); got != want {
+		t.Errorf("signature = %s", got)
+	}
+	if !Verify(mpk, []byte("Alice"), HIDSign, msg, sig) {
+		t.Error("vector signature does not verify")
+	}
+}
+
+func TestSignRoundTrip(t *testing.T) {
+	master, _ := GenerateMasterKey(rand.Reader)
+	mpk := master.SignPublicKey()
+	priv, _ := master.SignUserKey([]byte("bob@example.com"), HIDSign)
+	msg := []byte("message")
+	sig, err := Sign(rand.Reader, mpk, priv, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := sig.MarshalASN1()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := UnmarshalSignatureASN1(der)
+	if err != nil || !Verify(mpk, []byte("bob@example.com"), HIDSign, msg, sig2) {
+		t.Fatalf("DER round trip: %v", err)
+	}
+	if Verify(mpk, []byte("alice@example.com"), HIDSign, msg, sig) {
+		t.Error("verified under another identity")
+	}
+	if Verify(mpk, []byte("bob@example.com"), HIDEncrypt, msg, sig) {
+		t.Error("verified under another hid")
+	}
+	if Verify(mpk, []byte("bob@example.com"), HIDSign, []byte("other"), sig) {
+		t.Error("verified another message")
+	}
+	other, _ := GenerateMasterKey(rand.Reader)
+	if Verify(other.SignPublicKey(), []byte("bob@example.com"), HIDSign, msg, sig) {
+		t.Error("verified under another master key")
+	}
+
+	mpk2, err := ParseSignMasterPublicKey(mpk.Bytes())
+	if err != nil || !Verify(mpk2, []byte("bob@example.com"), HIDSign, msg, sig) {
+		t.Errorf("master public key round trip: %v", err)
+	}
+	if _, err := ParseSignPrivateKey(priv.Bytes()); err != nil {
+		t.Error(err)
+	}
+	bad := mpk.Bytes()
+	bad[100] ^= 1
+	if _, err := ParseSignMasterPublicKey(bad); err == nil {
+		t.Error("point off the twist accepted")
+	}
+	if _, err := ParseSignature(sig.Bytes()[:96]); err == nil {
+		t.Error("short signature accepted")
+	}
+	if _, err := NewMasterKey(N.Bytes()); err == nil {
+		t.Error("master key N accepted")
+	}
+}
+
+// The encryption example of GB/T 38635.2 annex C.
+func TestEncryptVector(t *testing.T) {
+	ke, _ := hex.DecodeString("0001edee3778f441f8dea3d9fa0acc4e07ee36c93f9a08618af4ad85cede1c22")
+	master, err := NewMasterKey(ke)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpk := master.EncryptPublicKey()
+	if got := hex.EncodeToString(mpk.Bytes()); got != "04"+
+		"787ed7b8a51f3ab84e0a66003f32da5c720b17eca7137d39abc66e3c80a892ff"+
+		"769de61791e5adc4b9ff85a31354900b202871279a8c49dc3f220f644c57a7b1" {
+		t.Errorf("Ppub-e = %s", got)
+	}
+	priv, err := master.EncryptUserKey([]byte("Bob"), HIDEncrypt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := []byte("Chinese IBE standard")
+	r := fromHex("AAC0541779C8FC45E3E2CB25C12B5D2576B2129AE8BB5EE2CBE5EC9E785C")
+	ct, ok := encryptWithR(mpk, []byte("Bob"), HIDEncrypt, msg, r)
+	if !ok {
+		t.Fatal("encryptWithR failed")
+	}
+	want := "04" +
+		"2445471164490618e1ee20528ff1d545b0f14c8bcaa44544f03dab5dac07d8ff" +
+		"42ffca97d57cddc05ea405f2e586feb3a6930715532b8000759f13059ed59ac0" +
+		"ba672387bcd6de5016a158a52bb2e7fc429197bcab70b25afee37a2b9db9f367" +
+		"1b5f5b0e951489682f3e64e1378cdd5da9513b1c"
+	if got := hex.EncodeToString(ct); got != want {
+		t.Errorf("ciphertext = %s", got)
+	}
+	pt, err := Decrypt(priv, []byte("Bob"), ct)
+	if err != nil || string(pt) != string(msg) {
+		t.Fatalf("Decrypt = %q, %v", pt, err)
+	}
+}
+
+func TestEncryptRoundTrip(t *testing.T) {
+	master, _ := GenerateMasterKey(rand.Reader)
+	mpk := master.EncryptPublicKey()
+	priv, _ := master.EncryptUserKey([]byte("bob@example.com"), HIDEncrypt)
+	for _, msg := range []string{"", "message"} {
+		ct, err := Encrypt(rand.Reader, mpk, []byte("bob@example.com"), HIDEncrypt, []byte(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		der, err := MarshalCiphertext(ct)
+		if err != nil {
+			t.Fatal(err)
+		}
+		back, err := UnmarshalCiphertext(der)
+		if err != nil || string(back) != string(ct) {
+			t.Fatalf("DER round trip: %v", err)
+		}
+		if pt, err := Decrypt(priv, []byte("bob@example.com"), ct); err != nil || string(pt) != msg {
+			t.Errorf("Decrypt = %q, %v", pt, err)
+		}
+		if _, err := Decrypt(priv, []byte("alice@example.com"), ct); err != ErrDecryption {
+			t.Errorf("other identity: %v", err)
+		}
+		ct[len(ct)-1-len(msg)] ^= 1
+		if _, err := Decrypt(priv, []byte("bob@example.com"), ct); err != ErrDecryption {
+			t.Errorf("tampered C3: %v", err)
+		}
+	}
+	if _, err := Decrypt(priv, []byte("bob@example.com"), make([]byte, G1Size)); err != ErrDecryption {
+		t.Errorf("short ciphertext: %v", err)
+	}
+}
+
+// The key encapsulation example of GB/T 38635.2 annex C.
+func TestEncapsulateVector(t *testing.T) {
+	ke, _ := hex.DecodeString("0001edee3778f441f8dea3d9fa0acc4e07ee36c93f9a08618af4ad85cede1c22")
+	master, _ := NewMasterKey(ke)
+	priv, _ := master.EncryptUserKey([]byte("Bob"), HIDEncrypt)
+	r := fromHex("74015F8489C01EF4270456F9E6475BFB602BDE7F33FD482AB4E3684A6722")
+	c, key := encapsulate(master.EncryptPublicKey(), []byte("Bob"), HIDEncrypt, r, 32)
+	wantC := "04" +
+		"1edee2c3f465914491de44cefb2cb434ab02c308d9dc5e2067b4fed5aaac8a0f" +
+		"1c9b4c435eca35ab83bb734174c0f78fde81a53374aff3b3602bbc5e37be9a4c"
+	const wantKey = "4ff5cf86d2ad40c8f4bac98d76abdbde0c0e2f0a829d3f911ef5b2bce0695480"
+	if got := hex.EncodeToString(c); got != wantC {
+		t.Errorf("C = %s", got)
+	}
+	if got := hex.EncodeToString(key); got != wantKey {
+		t.Errorf("K = %s", got)
+	}
+	got, err := Decapsulate(priv, []byte("Bob"), c, 32)
+	if err != nil || hex.EncodeToString(got) != wantKey {
+		t.Errorf("Decapsulate = %x, %v", got, err)
+	}
+	if _, err := Decapsulate(priv, []byte("Bob"), c[:64], 32); err != ErrDecryption {
+		t.Errorf("short encapsulation: %v", err)
+	}
+}
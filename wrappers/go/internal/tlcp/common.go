@@ -0,0 +1,123 @@
+// Package tlcp implements TLCP 1.1, the transport layer cryptography
+// protocol of GM/T 0024-2014 and GB/T 38636-2020. TLCP follows TLS 1.1 but
+// authenticates the server with an SM2 signing certificate and transports
+// the premaster secret under a second SM2 encryption certificate.
+//
+// Only the ECC key exchange is implemented, with the cipher suites
+// ECC_SM4_CBC_SM3 and ECC_SM4_GCM_SM3; the ECDHE, IBC and RSA suites,
+// session resumption and renegotiation are not. Like the rest of the module
+// it exists to test interoperability, not to protect traffic.
+package tlcp
+
+import (
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+)
+
+// VersionTLCP is the protocol version of TLCP 1.1.
+const VersionTLCP = 0x0101
+
+// Cipher suites of GB/T 38636-2020 that the package implements.
+const (
+	ECC_SM4_CBC_SM3 uint16 = 0xe013
+	ECC_SM4_GCM_SM3 uint16 = 0xe053
+)
+
+type cipherSuite struct {
+	id   uint16
+	name string
+	// aead selects SM4-GCM records; otherwise records are SM4-CBC with
+	// an HMAC-SM3 MAC.
+	aead bool
+}
+
+// cipherSuites lists the implemented suites, GCM preferred.
+var cipherSuites = []*cipherSuite{
+	{ECC_SM4_GCM_SM3, "ECC_SM4_GCM_SM3", true},
+	{ECC_SM4_CBC_SM3, "ECC_SM4_CBC_SM3", false},
+}
+
+func suiteByID(id uint16) *cipherSuite {
+	for _, s := range cipherSuites {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// CipherSuites returns the IDs of the GB/T 38636 suites the package
+// implements.
+func CipherSuites() []uint16 {
+	ids := make([]uint16, len(cipherSuites))
+	for i, s := range cipherSuites {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// CipherSuiteName returns the GB/T 38636 name of a cipher suite, or its
+// ID in hex when the package does not implement it.
+func CipherSuiteName(id uint16) string {
+	if s := suiteByID(id); s != nil {
+		return s.name
+	}
+	return fmt.Sprintf("0x%04X", id)
+}
+
+// Certificate is a signing or encryption certificate chain with the key
+// of its leaf.
+type Certificate = handshake.Certificate
+
+// Config configures a TLCP client or server.
+type Config struct {
+	handshake.Config
+
+	// SignCertificate and EncCertificate are the endpoint's signing and
+	// encryption certificates. A server needs both; a client sends them
+	// when the server requests client authentication.
+	SignCertificate *Certificate
+	EncCertificate  *Certificate
+}
+
+func (c *Config) suites() []uint16 {
+	if c.CipherSuites != nil {
+		return c.CipherSuites
+	}
+	return CipherSuites()
+}
+
+// ConnectionState describes a completed TLCP handshake.
+type ConnectionState struct {
+	Version     uint16
+	CipherSuite uint16
+	// PeerCertificates are the certificates the peer sent: its signing
+	// certificate, its encryption certificate and any intermediates. A
+	// server without ClientAuth has none.
+	PeerCertificates []*cert.Certificate
+}
+
+const (
+	typeClientHello        uint8 = 1
+	typeServerHello        uint8 = 2
+	typeCertificate        uint8 = 11
+	typeServerKeyExchange  uint8 = 12
+	typeCertificateRequest uint8 = 13
+	typeServerHelloDone    uint8 = 14
+	typeCertificateVerify  uint8 = 15
+	typeClientKeyExchange  uint8 = 16
+	typeFinished           uint8 = 20
+)
+
+// certTypeECDSASign is the only client certificate type a server requests.
+const certTypeECDSASign = 64
+
+// maxCiphertext bounds a protected record: the plaintext, its MAC and
+// up to 2048 bytes of IV and padding.
+const maxCiphertext = handshake.MaxPlaintext + 2048
+
+// AlertError is returned when the peer sends a fatal alert; its Protocol
+// is "tlcp".
+type AlertError = handshake.AlertError
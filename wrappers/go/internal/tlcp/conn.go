@@ -0,0 +1,192 @@
+package tlcp
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+	"io"
+	"net"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// Conn is a TLCP connection. Reading, writing and closing are those of
+// the embedded handshake.Conn; the handshake runs on the first Read or
+// Write, or on Handshake.
+type Conn struct {
+	handshake.Conn
+	config *Config
+	state  ConnectionState
+}
+
+// Client returns a client-side TLCP connection over conn.
+func Client(conn net.Conn, config *Config) *Conn {
+	c := &Conn{config: config}
+	c.init(conn, c.clientHandshake)
+	return c
+}
+
+// Server returns a server-side TLCP connection over conn.
+func Server(conn net.Conn, config *Config) *Conn {
+	c := &Conn{config: config}
+	c.init(conn, c.serverHandshake)
+	return c
+}
+
+func (c *Conn) init(conn net.Conn, run func() error) {
+	proto := &handshake.Protocol{
+		Name:          "tlcp",
+		Version:       func(v uint16) bool { return v == VersionTLCP },
+		MaxCiphertext: maxCiphertext,
+		Handshake:     run,
+	}
+	c.Conn = handshake.NewConn(conn, proto, &c.config.Config, &halfConn{}, &halfConn{})
+}
+
+// ConnectionState returns the version, suite and peer certificates
+// negotiated by the handshake.
+func (c *Conn) ConnectionState() ConnectionState { return c.state }
+
+// halfConn is the record protection of one direction. Before the first
+// ChangeCipherSpec both cbc and aead are nil and records are plaintext.
+type halfConn struct {
+	rand  io.Reader // explicit IVs of CBC records
+	cbc   cipher.Block
+	mac   hash.Hash
+	aead  cipher.AEAD
+	fixed []byte // implicit nonce of GCM records
+	seq   uint64
+}
+
+func newHalfConn(suite *cipherSuite, keys trafficKeys, rand io.Reader) (*halfConn, error) {
+	block, err := sm4.NewCipher(keys.key)
+	if err != nil {
+		return nil, err
+	}
+	if !suite.aead {
+		return &halfConn{rand: rand, cbc: block, mac: hmac.New(sm3.New, keys.mac)}, nil
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &halfConn{aead: aead, fixed: keys.iv}, nil
+}
+
+// additionalData is the seq_num || type || version || length prefix of
+// the MAC and the GCM additional data.
+func (hc *halfConn) additionalData(typ handshake.RecordType, n int) []byte {
+	ad := make([]byte, 13)
+	binary.BigEndian.PutUint64(ad, hc.seq)
+	ad[8] = byte(typ)
+	binary.BigEndian.PutUint16(ad[9:], VersionTLCP)
+	binary.BigEndian.PutUint16(ad[11:], uint16(n))
+	return ad
+}
+
+// Seal protects a record payload. CBC records are IV || E(payload || MAC
+// || padding) with an explicit random IV; GCM records carry the sequence
+// number as the explicit part of the nonce.
+func (hc *halfConn) Seal(typ handshake.RecordType, payload []byte) ([]byte, error) {
+	defer func() { hc.seq++ }()
+	rec := make([]byte, handshake.RecordHeaderLen)
+	rec[0] = byte(typ)
+	binary.BigEndian.PutUint16(rec[1:], VersionTLCP)
+	switch {
+	case hc.aead != nil:
+		explicit := make([]byte, 8)
+		binary.BigEndian.PutUint64(explicit, hc.seq)
+		nonce := append(append([]byte{}, hc.fixed...), explicit...)
+		rec = hc.aead.Seal(append(rec, explicit...), nonce, payload, hc.additionalData(typ, len(payload)))
+	case hc.cbc != nil:
+		hc.mac.Reset()
+		hc.mac.Write(hc.additionalData(typ, len(payload)))
+		hc.mac.Write(payload)
+		data := hc.mac.Sum(append([]byte{}, payload...))
+		pad := sm4.BlockSize - len(data)%sm4.BlockSize
+		for i := 0; i < pad; i++ {
+			data = append(data, byte(pad-1))
+		}
+		iv := make([]byte, sm4.BlockSize)
+		if _, err := io.ReadFull(hc.rand, iv); err != nil {
+			return nil, err
+		}
+		cipher.NewCBCEncrypter(hc.cbc, iv).CryptBlocks(data, data)
+		rec = append(append(rec, iv...), data...)
+	default:
+		rec = append(rec, payload...)
+	}
+	binary.BigEndian.PutUint16(rec[3:], uint16(len(rec)-handshake.RecordHeaderLen))
+	return rec, nil
+}
+
+// Open reverses Seal. Every failure is handshake.ErrBadRecord so that
+// padding and MAC errors look alike.
+func (hc *halfConn) Open(hdr, fragment []byte) (handshake.RecordType, []byte, error) {
+	defer func() { hc.seq++ }()
+	typ := handshake.RecordType(hdr[0])
+	switch {
+	case hc.aead == nil && hc.cbc == nil:
+		return typ, fragment, nil
+	case hc.aead != nil:
+		if len(fragment) < 8+hc.aead.Overhead() {
+			return 0, nil, handshake.ErrBadRecord
+		}
+		nonce := append(append([]byte{}, hc.fixed...), fragment[:8]...)
+		ct := fragment[8:]
+		out, err := hc.aead.Open(nil, nonce, ct, hc.additionalData(typ, len(ct)-hc.aead.Overhead()))
+		if err != nil {
+			return 0, nil, handshake.ErrBadRecord
+		}
+		return typ, out, nil
+	}
+	macLen := hc.mac.Size()
+	if len(fragment)%sm4.BlockSize != 0 || len(fragment) < sm4.BlockSize+macLen+1 {
+		return 0, nil, handshake.ErrBadRecord
+	}
+	data := make([]byte, len(fragment)-sm4.BlockSize)
+	cipher.NewCBCDecrypter(hc.cbc, fragment[:sm4.BlockSize]).CryptBlocks(data, fragment[sm4.BlockSize:])
+	pad := int(data[len(data)-1]) + 1
+	good := 1
+	if pad > len(data)-macLen {
+		pad, good = 0, 0
+	}
+	for _, b := range data[len(data)-pad:] {
+		good &= subtle.ConstantTimeByteEq(b, byte(pad-1))
+	}
+	data = data[:len(data)-pad]
+	payload, mac := data[:len(data)-macLen], data[len(data)-macLen:]
+	hc.mac.Reset()
+	hc.mac.Write(hc.additionalData(typ, len(payload)))
+	hc.mac.Write(payload)
+	if good&subtle.ConstantTimeCompare(hc.mac.Sum(nil), mac) != 1 {
+		return 0, nil, handshake.ErrBadRecord
+	}
+	return typ, payload, nil
+}
+
+// readChangeCipherSpec reads the ChangeCipherSpec record and installs in.
+func (c *Conn) readChangeCipherSpec(in *halfConn) error {
+	typ, data, err := c.ReadRecord()
+	if err != nil {
+		return err
+	}
+	if typ != handshake.RecordTypeChangeCipherSpec || len(data) != 1 || data[0] != 1 || c.HandshakeBuffered() {
+		return c.Fail(handshake.AlertUnexpectedMessage, "expected ChangeCipherSpec")
+	}
+	c.In = in
+	return nil
+}
+
+// writeChangeCipherSpec sends ChangeCipherSpec and installs out.
+func (c *Conn) writeChangeCipherSpec(out *halfConn) error {
+	if err := c.WriteRecord(handshake.RecordTypeChangeCipherSpec, []byte{1}); err != nil {
+		return err
+	}
+	c.Out = out
+	return nil
+}
@@ -0,0 +1,95 @@
+package tlcp
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// helloRandom fills the 32-byte random of a hello message: the time in
+// seconds followed by 28 random bytes.
+func helloRandom(cfg *Config, random []byte) error {
+	binary.BigEndian.PutUint32(random, uint32(cfg.Now().Unix()))
+	_, err := io.ReadFull(cfg.Random(), random[4:])
+	return err
+}
+
+// certificateList is the certificate list of a Certificate message: the
+// signing certificate, the encryption certificate, then the intermediates
+// of both chains.
+func certificateList(signCert, encCert *Certificate) [][]byte {
+	list := [][]byte{signCert.Chain[0], encCert.Chain[0]}
+	for _, c := range append(append([][]byte{}, signCert.Chain[1:]...), encCert.Chain[1:]...) {
+		dup := false
+		for _, have := range list {
+			dup = dup || bytes.Equal(have, c)
+		}
+		if !dup {
+			list = append(list, c)
+		}
+	}
+	return list
+}
+
+// verifyPeer parses the peer's certificate list and, unless
+// InsecureSkipVerify is set, verifies its signing and encryption
+// certificates against RootCAs for purpose. A client's list may lack the
+// encryption certificate.
+func (c *Conn) verifyPeer(list [][]byte, purpose asn1.ObjectIdentifier) ([]*cert.Certificate, error) {
+	certs, err := c.ParseCertificates(list)
+	if err != nil {
+		return nil, err
+	}
+	leaves := certs[:min(len(certs), 2)]
+	for i, leaf := range leaves {
+		if leaf.PublicKey == nil {
+			return nil, c.Fail(handshake.AlertUnsupportedCertificate, "peer certificate %d does not hold an SM2 key", i)
+		}
+	}
+	if c.config.InsecureSkipVerify {
+		return certs, nil
+	}
+	for i, leaf := range leaves {
+		if err := c.VerifyCertificate(fmt.Sprintf("peer certificate %d", i), leaf, certs[len(leaves):], purpose); err != nil {
+			return nil, err
+		}
+	}
+	if ku := certs[0].KeyUsage; ku != 0 && ku&cert.KeyUsageDigitalSignature == 0 {
+		return nil, c.Fail(handshake.AlertBadCertificate, "peer signing certificate does not allow digital signatures")
+	}
+	const encUsage = cert.KeyUsageKeyEncipherment | cert.KeyUsageDataEncipherment | cert.KeyUsageKeyAgreement
+	if len(certs) > 1 {
+		if ku := certs[1].KeyUsage; ku != 0 && ku&encUsage == 0 {
+			return nil, c.Fail(handshake.AlertBadCertificate, "peer encryption certificate does not allow encipherment")
+		}
+	}
+	return certs, nil
+}
+
+// serverKeyExchangeData is what the server signs in ServerKeyExchange: the
+// two hello randoms and its length-prefixed encryption certificate.
+func serverKeyExchangeData(clientRandom, serverRandom, encCert []byte) []byte {
+	b := handshake.Builder(append(append([]byte{}, clientRandom...), serverRandom...))
+	b.Vec24(encCert)
+	return b
+}
+
+// sign returns the DER SM2 signature of msg with the default identity.
+func sign(cfg *Config, priv *sm2.PrivateKey, msg []byte) ([]byte, error) {
+	r, s, err := sm2.Sign(cfg.Random(), priv, msg, []byte(sm2.DefaultUID))
+	if err != nil {
+		return nil, err
+	}
+	return sm2.MarshalSignature(r, s)
+}
+
+func verifySignature(pub *sm2.PublicKey, msg, sig []byte) bool {
+	r, s, err := sm2.UnmarshalSignature(sig)
+	return err == nil && sm2.Verify(pub, msg, []byte(sm2.DefaultUID), r, s)
+}
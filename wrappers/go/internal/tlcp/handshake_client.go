@@ -0,0 +1,166 @@
+package tlcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"slices"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func (c *Conn) clientHandshake() error {
+	cfg := c.config
+	hs := handshake.NewTranscript(&c.Conn)
+
+	hello := &clientHelloMsg{
+		version:      VersionTLCP,
+		random:       make([]byte, 32),
+		cipherSuites: cfg.suites(),
+		compressions: []uint8{0},
+	}
+	if err := helloRandom(cfg, hello.random); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(hello.marshal()); err != nil {
+		return err
+	}
+
+	body, err := hs.ReadMessage(typeServerHello)
+	if err != nil {
+		return err
+	}
+	var sh serverHelloMsg
+	if !sh.unmarshal(body) {
+		return c.Fail(handshake.AlertDecodeError, "malformed ServerHello")
+	}
+	if sh.version != VersionTLCP {
+		return c.Fail(handshake.AlertProtocolVersion, "server chose version %#04x", sh.version)
+	}
+	suite := suiteByID(sh.cipherSuite)
+	if suite == nil || !slices.Contains(hello.cipherSuites, sh.cipherSuite) {
+		return c.Fail(handshake.AlertIllegalParameter, "server chose cipher suite %s, which was not offered", CipherSuiteName(sh.cipherSuite))
+	}
+	if sh.compression != 0 {
+		return c.Fail(handshake.AlertIllegalParameter, "server chose compression method %d", sh.compression)
+	}
+	c.state = ConnectionState{Version: sh.version, CipherSuite: suite.id}
+
+	if body, err = hs.ReadMessage(typeCertificate); err != nil {
+		return err
+	}
+	var cm certificateMsg
+	if !cm.unmarshal(body) {
+		return c.Fail(handshake.AlertDecodeError, "malformed Certificate")
+	}
+	if len(cm.certificates) < 2 {
+		return c.Fail(handshake.AlertBadCertificate, "server sent %d certificates, want signing and encryption certificates", len(cm.certificates))
+	}
+	peer, err := c.verifyPeer(cm.certificates, cert.OIDExtKeyUsageServerAuth)
+	if err != nil {
+		return err
+	}
+	c.state.PeerCertificates = peer
+
+	if body, err = hs.ReadMessage(typeServerKeyExchange); err != nil {
+		return err
+	}
+	sig, ok := parseOpaque16(body)
+	if !ok {
+		return c.Fail(handshake.AlertDecodeError, "malformed ServerKeyExchange")
+	}
+	if !verifySignature(peer[0].PublicKey, serverKeyExchangeData(hello.random, sh.random, cm.certificates[1]), sig) {
+		return c.Fail(handshake.AlertDecryptError, "ServerKeyExchange signature does not verify")
+	}
+
+	msg, err := c.ReadHandshake()
+	if err != nil {
+		return err
+	}
+	var certRequested bool
+	if msg[0] == typeCertificateRequest {
+		var cr certificateRequestMsg
+		if !cr.unmarshal(msg[4:]) {
+			return c.Fail(handshake.AlertDecodeError, "malformed CertificateRequest")
+		}
+		hs.Add(msg)
+		certRequested = true
+		if msg, err = c.ReadHandshake(); err != nil {
+			return err
+		}
+	}
+	if msg[0] != typeServerHelloDone || len(msg) != 4 {
+		return c.Fail(handshake.AlertUnexpectedMessage, "expected ServerHelloDone")
+	}
+	hs.Add(msg)
+
+	var signKey *sm2.PrivateKey
+	if certRequested {
+		var chain [][]byte
+		if cfg.SignCertificate != nil && cfg.EncCertificate != nil {
+			chain = certificateList(cfg.SignCertificate, cfg.EncCertificate)
+			signKey = cfg.SignCertificate.PrivateKey
+		}
+		if err := hs.WriteMessage((&certificateMsg{chain}).marshal()); err != nil {
+			return err
+		}
+	}
+
+	premaster := make([]byte, 48)
+	binary.BigEndian.PutUint16(premaster, VersionTLCP)
+	if _, err := io.ReadFull(cfg.Random(), premaster[2:]); err != nil {
+		return err
+	}
+	ct, err := sm2.Encrypt(cfg.Random(), peer[1].PublicKey, premaster)
+	if err != nil {
+		return err
+	}
+	if ct, err = sm2.MarshalCiphertext(ct); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(opaque16(typeClientKeyExchange, ct)); err != nil {
+		return err
+	}
+
+	if signKey != nil {
+		sig, err := sign(cfg, signKey, hs.Bytes())
+		if err != nil {
+			return err
+		}
+		if err := hs.WriteMessage(opaque16(typeCertificateVerify, sig)); err != nil {
+			return err
+		}
+	}
+
+	master := masterFromPremaster(premaster, hello.random, sh.random)
+	clientKeys, serverKeys := keysFromMaster(suite, master, hello.random, sh.random)
+	out, err := newHalfConn(suite, clientKeys, cfg.Random())
+	if err != nil {
+		return err
+	}
+	in, err := newHalfConn(suite, serverKeys, cfg.Random())
+	if err != nil {
+		return err
+	}
+	if err := c.writeChangeCipherSpec(out); err != nil {
+		return err
+	}
+	verify := finishedSum(master, "client finished", hs.Bytes())
+	if err := hs.WriteMessage(handshake.Message(typeFinished, verify)); err != nil {
+		return err
+	}
+
+	if err := c.readChangeCipherSpec(in); err != nil {
+		return err
+	}
+	want := finishedSum(master, "server finished", hs.Bytes())
+	if body, err = hs.ReadMessage(typeFinished); err != nil {
+		return err
+	}
+	if !bytes.Equal(body, want) {
+		return c.Fail(handshake.AlertDecryptError, "server Finished does not verify")
+	}
+	return nil
+}
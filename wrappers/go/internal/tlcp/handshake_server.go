@@ -0,0 +1,164 @@
+package tlcp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func (c *Conn) serverHandshake() error {
+	cfg := c.config
+	if cfg.SignCertificate == nil || cfg.EncCertificate == nil {
+		return errors.New("tlcp: a server needs signing and encryption certificates")
+	}
+	hs := handshake.NewTranscript(&c.Conn)
+
+	body, err := hs.ReadMessage(typeClientHello)
+	if err != nil {
+		return err
+	}
+	var ch clientHelloMsg
+	if !ch.unmarshal(body) {
+		return c.Fail(handshake.AlertDecodeError, "malformed ClientHello")
+	}
+	if ch.version < VersionTLCP {
+		return c.Fail(handshake.AlertProtocolVersion, "client offered version %#04x", ch.version)
+	}
+	if bytes.IndexByte(ch.compressions, 0) < 0 {
+		return c.Fail(handshake.AlertHandshakeFailure, "client does not offer null compression")
+	}
+	var suite *cipherSuite
+	for _, id := range cfg.suites() {
+		if s := suiteByID(id); s != nil && slices.Contains(ch.cipherSuites, id) {
+			suite = s
+			break
+		}
+	}
+	if suite == nil {
+		return c.Fail(handshake.AlertHandshakeFailure, "no cipher suite in common")
+	}
+	c.state = ConnectionState{Version: VersionTLCP, CipherSuite: suite.id}
+
+	sh := &serverHelloMsg{
+		version:     VersionTLCP,
+		random:      make([]byte, 32),
+		sessionID:   make([]byte, 32),
+		cipherSuite: suite.id,
+	}
+	if err := helloRandom(cfg, sh.random); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(cfg.Random(), sh.sessionID); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(sh.marshal()); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage((&certificateMsg{certificateList(cfg.SignCertificate, cfg.EncCertificate)}).marshal()); err != nil {
+		return err
+	}
+	sig, err := sign(cfg, cfg.SignCertificate.PrivateKey, serverKeyExchangeData(ch.random, sh.random, cfg.EncCertificate.Chain[0]))
+	if err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(opaque16(typeServerKeyExchange, sig)); err != nil {
+		return err
+	}
+	if cfg.ClientAuth {
+		cr := &certificateRequestMsg{certificateTypes: []byte{certTypeECDSASign}}
+		for _, root := range cfg.RootCAs {
+			cr.authorities = append(cr.authorities, root.RawSubject)
+		}
+		if err := hs.WriteMessage(cr.marshal()); err != nil {
+			return err
+		}
+	}
+	if err := hs.WriteMessage(handshake.Message(typeServerHelloDone, nil)); err != nil {
+		return err
+	}
+
+	var clientSignKey *sm2.PublicKey
+	if cfg.ClientAuth {
+		if body, err = hs.ReadMessage(typeCertificate); err != nil {
+			return err
+		}
+		var cm certificateMsg
+		if !cm.unmarshal(body) {
+			return c.Fail(handshake.AlertDecodeError, "malformed Certificate")
+		}
+		if len(cm.certificates) == 0 {
+			return c.Fail(handshake.AlertHandshakeFailure, "client sent no certificate")
+		}
+		peer, err := c.verifyPeer(cm.certificates, cert.OIDExtKeyUsageClientAuth)
+		if err != nil {
+			return err
+		}
+		c.state.PeerCertificates = peer
+		clientSignKey = peer[0].PublicKey
+	}
+
+	if body, err = hs.ReadMessage(typeClientKeyExchange); err != nil {
+		return err
+	}
+	ct, ok := parseOpaque16(body)
+	if !ok {
+		return c.Fail(handshake.AlertDecodeError, "malformed ClientKeyExchange")
+	}
+	if ct, err = sm2.UnmarshalCiphertext(ct); err != nil {
+		return c.Fail(handshake.AlertDecodeError, "ClientKeyExchange: %v", err)
+	}
+	premaster, err := sm2.Decrypt(cfg.EncCertificate.PrivateKey, ct)
+	if err != nil {
+		return c.Fail(handshake.AlertDecryptError, "premaster secret does not decrypt")
+	}
+	if len(premaster) != 48 || binary.BigEndian.Uint16(premaster) != ch.version {
+		return c.Fail(handshake.AlertIllegalParameter, "malformed premaster secret")
+	}
+
+	if clientSignKey != nil {
+		signed := append([]byte{}, hs.Bytes()...)
+		if body, err = hs.ReadMessage(typeCertificateVerify); err != nil {
+			return err
+		}
+		sig, ok := parseOpaque16(body)
+		if !ok {
+			return c.Fail(handshake.AlertDecodeError, "malformed CertificateVerify")
+		}
+		if !verifySignature(clientSignKey, signed, sig) {
+			return c.Fail(handshake.AlertDecryptError, "CertificateVerify signature does not verify")
+		}
+	}
+
+	master := masterFromPremaster(premaster, ch.random, sh.random)
+	clientKeys, serverKeys := keysFromMaster(suite, master, ch.random, sh.random)
+	in, err := newHalfConn(suite, clientKeys, cfg.Random())
+	if err != nil {
+		return err
+	}
+	out, err := newHalfConn(suite, serverKeys, cfg.Random())
+	if err != nil {
+		return err
+	}
+	if err := c.readChangeCipherSpec(in); err != nil {
+		return err
+	}
+	want := finishedSum(master, "client finished", hs.Bytes())
+	if body, err = hs.ReadMessage(typeFinished); err != nil {
+		return err
+	}
+	if !bytes.Equal(body, want) {
+		return c.Fail(handshake.AlertDecryptError, "client Finished does not verify")
+	}
+
+	if err := c.writeChangeCipherSpec(out); err != nil {
+		return err
+	}
+	verify := finishedSum(master, "server finished", hs.Bytes())
+	return hs.WriteMessage(handshake.Message(typeFinished, verify))
+}
@@ -0,0 +1,143 @@
+package tlcp
+
+import "github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+
+// skipExtensions consumes the optional extensions block that ends hello
+// messages. TLCP defines no extensions, so their contents are ignored.
+func skipExtensions(p *handshake.Parser) {
+	if !p.Empty() {
+		p.Vec16()
+	}
+}
+
+type clientHelloMsg struct {
+	version      uint16
+	random       []byte
+	sessionID    []byte
+	cipherSuites []uint16
+	compressions []uint8
+}
+
+func (m *clientHelloMsg) marshal() []byte {
+	var b handshake.Builder
+	b.U16(m.version)
+	b = append(b, m.random...)
+	b.Vec8(m.sessionID)
+	var suites handshake.Builder
+	for _, s := range m.cipherSuites {
+		suites.U16(s)
+	}
+	b.Vec16(suites)
+	b.Vec8(m.compressions)
+	return handshake.Message(typeClientHello, b)
+}
+
+func (m *clientHelloMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.version = p.U16()
+	m.random = p.Bytes(32)
+	m.sessionID = p.Vec8()
+	suites := handshake.NewParser(p.Vec16())
+	for suites.More() {
+		m.cipherSuites = append(m.cipherSuites, suites.U16())
+	}
+	m.compressions = p.Vec8()
+	skipExtensions(p)
+	return p.Done() && suites.OK() && len(m.sessionID) <= 32
+}
+
+type serverHelloMsg struct {
+	version     uint16
+	random      []byte
+	sessionID   []byte
+	cipherSuite uint16
+	compression uint8
+}
+
+func (m *serverHelloMsg) marshal() []byte {
+	var b handshake.Builder
+	b.U16(m.version)
+	b = append(b, m.random...)
+	b.Vec8(m.sessionID)
+	b.U16(m.cipherSuite)
+	b.U8(m.compression)
+	return handshake.Message(typeServerHello, b)
+}
+
+func (m *serverHelloMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.version = p.U16()
+	m.random = p.Bytes(32)
+	m.sessionID = p.Vec8()
+	m.cipherSuite = p.U16()
+	m.compression = p.U8()
+	skipExtensions(p)
+	return p.Done() && len(m.sessionID) <= 32
+}
+
+// certificateMsg carries DER certificates. A TLCP server sends its signing
+// certificate, then its encryption certificate, then any intermediates.
+type certificateMsg struct {
+	certificates [][]byte
+}
+
+func (m *certificateMsg) marshal() []byte {
+	var list handshake.Builder
+	for _, c := range m.certificates {
+		list.Vec24(c)
+	}
+	var b handshake.Builder
+	b.Vec24(list)
+	return handshake.Message(typeCertificate, b)
+}
+
+func (m *certificateMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	list := handshake.NewParser(p.Vec24())
+	for list.More() {
+		m.certificates = append(m.certificates, list.Vec24())
+	}
+	return p.Done() && list.OK()
+}
+
+// certificateRequestMsg is the TLS 1.1 form, without signature algorithms.
+type certificateRequestMsg struct {
+	certificateTypes []byte
+	authorities      [][]byte
+}
+
+func (m *certificateRequestMsg) marshal() []byte {
+	var b handshake.Builder
+	b.Vec8(m.certificateTypes)
+	var names handshake.Builder
+	for _, n := range m.authorities {
+		names.Vec16(n)
+	}
+	b.Vec16(names)
+	return handshake.Message(typeCertificateRequest, b)
+}
+
+func (m *certificateRequestMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.certificateTypes = p.Vec8()
+	names := handshake.NewParser(p.Vec16())
+	for names.More() {
+		m.authorities = append(m.authorities, names.Vec16())
+	}
+	return p.Done() && names.OK() && len(m.certificateTypes) > 0
+}
+
+// opaque16 is the body of the messages that hold one opaque<0..2^16-1>:
+// the signature of ServerKeyExchange and CertificateVerify and the
+// encrypted premaster secret of ClientKeyExchange.
+func opaque16(typ uint8, v []byte) []byte {
+	var b handshake.Builder
+	b.Vec16(v)
+	return handshake.Message(typ, b)
+}
+
+func parseOpaque16(body []byte) ([]byte, bool) {
+	p := handshake.NewParser(body)
+	v := p.Vec16()
+	return v, p.Done()
+}
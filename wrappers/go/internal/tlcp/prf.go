@@ -0,0 +1,72 @@
+package tlcp
+
+import (
+	"crypto/hmac"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+const (
+	masterSecretLength = 48
+	finishedLength     = 12
+	macKeyLength       = sm3.Size
+	keyLength          = 16
+	cbcIVLength        = 16
+	gcmFixedIVLength   = 4
+)
+
+// prf is the TLS 1.2 pseudorandom function P_SM3 that GM/T 0024 uses.
+func prf(secret []byte, label string, seed []byte, n int) []byte {
+	labelSeed := append([]byte(label), seed...)
+	h := hmac.New(sm3.New, secret)
+	out := make([]byte, 0, n+sm3.Size)
+	a := labelSeed
+	for len(out) < n {
+		h.Reset()
+		h.Write(a)
+		a = h.Sum(nil)
+		h.Reset()
+		h.Write(a)
+		h.Write(labelSeed)
+		out = h.Sum(out)
+	}
+	return out[:n]
+}
+
+func masterFromPremaster(premaster, clientRandom, serverRandom []byte) []byte {
+	seed := append(append([]byte{}, clientRandom...), serverRandom...)
+	return prf(premaster, "master secret", seed, masterSecretLength)
+}
+
+// trafficKeys are the keys of one direction of a connection.
+type trafficKeys struct {
+	mac, key, iv []byte
+}
+
+// keysFromMaster expands the master secret into the client and server
+// write keys of suite. GCM suites have no MAC keys and a 4-byte implicit
+// nonce as IV.
+func keysFromMaster(suite *cipherSuite, master, clientRandom, serverRandom []byte) (client, server trafficKeys) {
+	macLen, ivLen := macKeyLength, cbcIVLength
+	if suite.aead {
+		macLen, ivLen = 0, gcmFixedIVLength
+	}
+	seed := append(append([]byte{}, serverRandom...), clientRandom...)
+	b := prf(master, "key expansion", seed, 2*(macLen+keyLength+ivLen))
+	next := func(n int) []byte {
+		v := b[:n:n]
+		b = b[n:]
+		return v
+	}
+	client.mac, server.mac = next(macLen), next(macLen)
+	client.key, server.key = next(keyLength), next(keyLength)
+	client.iv, server.iv = next(ivLen), next(ivLen)
+	return client, server
+}
+
+// finishedSum is the verify_data of a Finished message over the handshake
+// messages in transcript.
+func finishedSum(master []byte, label string, transcript []byte) []byte {
+	h := sm3.Sum(transcript)
+	return prf(master, label, h[:], finishedLength)
+}
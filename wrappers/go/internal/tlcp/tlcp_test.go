@@ -0,0 +1,187 @@
+package tlcp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake/handshaketest"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+type testPKI struct {
+	root       *cert.Certificate
+	rootKey    *sm2.PrivateKey
+	serverSign *Certificate
+	serverEnc  *Certificate
+	clientSign *Certificate
+	clientEnc  *Certificate
+}
+
+func issue(t *testing.T, name string, ku cert.KeyUsage, parent *cert.Certificate, parentKey *sm2.PrivateKey) *Certificate {
+	t.Helper()
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := cert.Create(rand.Reader, &cert.Template{
+		Subject:   pkix.Name{CommonName: name},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+		KeyUsage:  ku,
+	}, &key.PublicKey, parent, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Certificate{Chain: [][]byte{der}, PrivateKey: key}
+}
+
+func newPKI(t *testing.T) *testPKI {
+	rootKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := cert.Create(rand.Reader, &cert.Template{
+		Subject:   pkix.Name{CommonName: "TLCP test CA"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+		KeyUsage:  cert.KeyUsageCertSign,
+		IsCA:      true,
+	}, &rootKey.PublicKey, nil, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := cert.Parse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const enc = cert.KeyUsageKeyEncipherment | cert.KeyUsageDataEncipherment
+	return &testPKI{
+		root:       root,
+		rootKey:    rootKey,
+		serverSign: issue(t, "server sign", cert.KeyUsageDigitalSignature, root, rootKey),
+		serverEnc:  issue(t, "server enc", enc, root, rootKey),
+		clientSign: issue(t, "client sign", cert.KeyUsageDigitalSignature, root, rootKey),
+		clientEnc:  issue(t, "client enc", enc, root, rootKey),
+	}
+}
+
+// echo runs a TLCP client against a server that echoes msg back.
+func echo(t *testing.T, client, server *Config, msg []byte) (ConnectionState, []byte, error, ConnectionState, error) {
+	t.Helper()
+	return handshaketest.Echo[ConnectionState](t,
+		func(nc net.Conn) *Conn { return Client(nc, client) },
+		func(nc net.Conn) *Conn { return Server(nc, server) },
+		msg)
+}
+
+func TestHandshake(t *testing.T) {
+	pki := newPKI(t)
+	server := &Config{SignCertificate: pki.serverSign, EncCertificate: pki.serverEnc}
+	msg := bytes.Repeat([]byte("probe "), 5000) // spans several records
+	for _, suite := range CipherSuites() {
+		client := &Config{Config: handshake.Config{RootCAs: []*cert.Certificate{pki.root}, CipherSuites: []uint16{suite}}}
+		cs, reply, err, ss, serr := echo(t, client, server, msg)
+		if err != nil || serr != nil {
+			t.Fatalf("%s: client %v, server %v", CipherSuiteName(suite), err, serr)
+		}
+		if !bytes.Equal(reply, msg) {
+			t.Errorf("%s: echo differs", CipherSuiteName(suite))
+		}
+		if cs.CipherSuite != suite || ss.CipherSuite != suite || cs.Version != VersionTLCP {
+			t.Errorf("%s: negotiated %#04x/%#04x", CipherSuiteName(suite), cs.CipherSuite, ss.CipherSuite)
+		}
+		if len(cs.PeerCertificates) != 2 || cs.PeerCertificates[1].Subject.CommonName != "server enc" {
+			t.Errorf("%s: peer certificates %v", CipherSuiteName(suite), cs.PeerCertificates)
+		}
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	pki := newPKI(t)
+	roots := []*cert.Certificate{pki.root}
+	server := &Config{Config: handshake.Config{RootCAs: roots, ClientAuth: true}, SignCertificate: pki.serverSign, EncCertificate: pki.serverEnc}
+	client := &Config{Config: handshake.Config{RootCAs: roots}, SignCertificate: pki.clientSign, EncCertificate: pki.clientEnc}
+	_, _, err, ss, serr := echo(t, client, server, []byte("hello"))
+	if err != nil || serr != nil {
+		t.Fatalf("client %v, server %v", err, serr)
+	}
+	if len(ss.PeerCertificates) != 2 || ss.PeerCertificates[0].Subject.CommonName != "client sign" {
+		t.Errorf("client certificates %v", ss.PeerCertificates)
+	}
+
+	// A client without certificates is refused.
+	_, _, err, _, serr = echo(t, &Config{Config: handshake.Config{RootCAs: roots}}, server, []byte("hello"))
+	if err == nil || serr == nil || !strings.Contains(serr.Error(), "no certificate") {
+		t.Errorf("anonymous client: client %v, server %v", err, serr)
+	}
+}
+
+func TestHandshakeFailures(t *testing.T) {
+	pki := newPKI(t)
+	server := &Config{SignCertificate: pki.serverSign, EncCertificate: pki.serverEnc}
+
+	other := newPKI(t)
+	_, _, err, _, serr := echo(t, &Config{Config: handshake.Config{RootCAs: []*cert.Certificate{other.root}}}, server, []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "no chain to a trusted root") {
+		t.Errorf("untrusted server: %v", err)
+	}
+	var ae AlertError
+	if !errors.As(serr, &ae) || ae.Alert != handshake.AlertUnknownCA {
+		t.Errorf("server saw %v, want an unknown CA alert", serr)
+	}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{InsecureSkipVerify: true}}, server, []byte("x")); err != nil {
+		t.Errorf("InsecureSkipVerify: %v", err)
+	}
+
+	// A server whose encryption key does not match its certificate cannot
+	// decrypt the premaster secret.
+	bad := &Config{SignCertificate: pki.serverSign, EncCertificate: &Certificate{Chain: pki.serverEnc.Chain, PrivateKey: pki.clientEnc.PrivateKey}}
+	if _, _, err, _, serr = echo(t, &Config{Config: handshake.Config{InsecureSkipVerify: true}}, bad, []byte("x")); err == nil || serr == nil {
+		t.Errorf("mismatched encryption key: client %v, server %v", err, serr)
+	}
+
+	// A signing certificate that is not the one that signs
+	// ServerKeyExchange is detected by the client.
+	swapped := &Config{SignCertificate: &Certificate{Chain: pki.serverSign.Chain, PrivateKey: pki.serverEnc.PrivateKey}, EncCertificate: pki.serverEnc}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{InsecureSkipVerify: true}}, swapped, []byte("x")); err == nil || !strings.Contains(err.Error(), "ServerKeyExchange") {
+		t.Errorf("wrong signing key: %v", err)
+	}
+
+	cbcOnly := &Config{Config: handshake.Config{CipherSuites: []uint16{ECC_SM4_CBC_SM3}}, SignCertificate: pki.serverSign, EncCertificate: pki.serverEnc}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{InsecureSkipVerify: true, CipherSuites: []uint16{ECC_SM4_GCM_SM3}}}, cbcOnly, []byte("x")); err == nil {
+		t.Error("handshake without a common suite succeeded")
+	}
+}
+
+func TestRecordProtection(t *testing.T) {
+	for _, suite := range cipherSuites {
+		keys, _ := keysFromMaster(suite, make([]byte, 48), make([]byte, 32), make([]byte, 32))
+		out, _ := newHalfConn(suite, keys, rand.Reader)
+		in, _ := newHalfConn(suite, keys, rand.Reader)
+		for _, n := range []int{0, 1, 15, 16, 100} {
+			payload := bytes.Repeat([]byte{byte(n)}, n)
+			rec, err := out.Seal(handshake.RecordTypeApplicationData, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hdr, fragment := rec[:handshake.RecordHeaderLen], rec[handshake.RecordHeaderLen:]
+			typ, got, err := in.Open(hdr, fragment)
+			if err != nil || typ != handshake.RecordTypeApplicationData || !bytes.Equal(got, payload) {
+				t.Fatalf("%s: %d bytes: %v", suite.name, n, err)
+			}
+			rec[len(rec)-1] ^= 1
+			in.seq--
+			if _, _, err := in.Open(hdr, fragment); err != handshake.ErrBadRecord {
+				t.Errorf("%s: tampered record opened", suite.name)
+			}
+		}
+	}
+}
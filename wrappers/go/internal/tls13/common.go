@@ -0,0 +1,189 @@
+// Package tls13 implements TLS 1.3 (RFC 8446) with the ShangMi cipher
+// suites of RFC 8998: TLS_SM4_GCM_SM3 and TLS_SM4_CCM_SM3, ECDHE over
+// curveSM2 and sm2sig_sm3 signatures with SM2 certificates.
+//
+// Only what RFC 8998 needs is implemented: full handshakes with optional
+// client authentication and HelloRetryRequest, KeyUpdate, and ignoring
+// session tickets. There is no PSK resumption, early data or other
+// group or signature scheme. Like the rest of the module it exists to
+// test interoperability, not to protect traffic.
+package tls13
+
+import (
+	"crypto/cipher"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// VersionTLS13 is the protocol version of TLS 1.3.
+const VersionTLS13 = 0x0304
+
+// Cipher suites of RFC 8998.
+const (
+	TLS_SM4_GCM_SM3 uint16 = 0x00c6
+	TLS_SM4_CCM_SM3 uint16 = 0x00c7
+)
+
+// The named group and signature scheme of RFC 8998.
+const (
+	curveSM2           uint16 = 0x0029
+	signatureSM2SigSM3 uint16 = 0x0708
+)
+
+// SignatureUID is the SM2 identity RFC 8998 prescribes for
+// CertificateVerify signatures.
+const SignatureUID = "TLSv1.3+GM+Cipher+Suite"
+
+type cipherSuite struct {
+	id   uint16
+	name string
+	aead func(key []byte) (cipher.AEAD, error)
+}
+
+const (
+	keyLength = sm4.KeySize
+	ivLength  = 12
+)
+
+func aeadGCM(key []byte) (cipher.AEAD, error) {
+	b, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(b)
+}
+
+func aeadCCM(key []byte) (cipher.AEAD, error) {
+	b, err := sm4.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return modes.NewCCM(b, ivLength, 16)
+}
+
+// cipherSuites lists both RFC 8998 suites, GCM preferred over CCM.
+var cipherSuites = []*cipherSuite{
+	{TLS_SM4_GCM_SM3, "TLS_SM4_GCM_SM3", aeadGCM},
+	{TLS_SM4_CCM_SM3, "TLS_SM4_CCM_SM3", aeadCCM},
+}
+
+func suiteByID(id uint16) *cipherSuite {
+	for _, s := range cipherSuites {
+		if s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+// CipherSuites returns the IDs of the RFC 8998 suites, GCM first.
+func CipherSuites() []uint16 {
+	ids := make([]uint16, len(cipherSuites))
+	for i, s := range cipherSuites {
+		ids[i] = s.id
+	}
+	return ids
+}
+
+// CipherSuiteName returns the RFC 8998 name of a cipher suite, or its ID
+// in hex when the package does not implement it.
+func CipherSuiteName(id uint16) string {
+	if s := suiteByID(id); s != nil {
+		return s.name
+	}
+	return fmt.Sprintf("0x%04X", id)
+}
+
+// Certificate is the endpoint's chain with the key of its leaf.
+type Certificate = handshake.Certificate
+
+// Config configures a TLS 1.3 client or server.
+type Config struct {
+	handshake.Config
+
+	// Certificate is the endpoint's SM2 certificate. A server needs one;
+	// a client sends it when the server requests client authentication.
+	Certificate *Certificate
+
+	// SignatureUID is the SM2 identity of CertificateVerify signatures;
+	// nil selects the SignatureUID constant.
+	SignatureUID []byte
+
+	// ServerName is sent by a client in the server_name extension and,
+	// unless InsecureSkipVerify is set, must match the server's
+	// certificate.
+	ServerName string
+
+	// omitKeyShare makes a client offer curveSM2 without a key share, so
+	// that tests can provoke a HelloRetryRequest.
+	omitKeyShare bool
+}
+
+func (c *Config) suites() []uint16 {
+	if c.CipherSuites != nil {
+		return c.CipherSuites
+	}
+	return CipherSuites()
+}
+
+func (c *Config) signatureUID() []byte {
+	if c.SignatureUID != nil {
+		return c.SignatureUID
+	}
+	return []byte(SignatureUID)
+}
+
+// ConnectionState describes a completed TLS 1.3 handshake.
+type ConnectionState struct {
+	Version     uint16
+	CipherSuite uint16
+	// ServerName is the server_name a client sent.
+	ServerName string
+	// HelloRetry reports whether the server sent a HelloRetryRequest.
+	HelloRetry bool
+	// PeerCertificates is the peer's chain, leaf first. A server without
+	// ClientAuth has none.
+	PeerCertificates []*cert.Certificate
+}
+
+const (
+	typeClientHello         uint8 = 1
+	typeServerHello         uint8 = 2
+	typeNewSessionTicket    uint8 = 4
+	typeEncryptedExtensions uint8 = 8
+	typeCertificate         uint8 = 11
+	typeCertificateRequest  uint8 = 13
+	typeCertificateVerify   uint8 = 15
+	typeFinished            uint8 = 20
+	typeKeyUpdate           uint8 = 24
+	typeMessageHash         uint8 = 254
+)
+
+const (
+	extensionServerName          uint16 = 0
+	extensionSupportedGroups     uint16 = 10
+	extensionSignatureAlgorithms uint16 = 13
+	extensionSupportedVersions   uint16 = 43
+	extensionCookie              uint16 = 44
+	extensionKeyShare            uint16 = 51
+)
+
+// helloRetryRequestRandom is the ServerHello random that marks a
+// HelloRetryRequest.
+var helloRetryRequestRandom = []byte{
+	0xcf, 0x21, 0xad, 0x74, 0xe5, 0x9a, 0x61, 0x11, 0xbe, 0x1d, 0x8c, 0x02, 0x1e, 0x65, 0xb8, 0x91,
+	0xc2, 0xa2, 0x11, 0x16, 0x7a, 0xbb, 0x8c, 0x5e, 0x07, 0x9e, 0x09, 0xe2, 0xc8, 0xa8, 0x33, 0x9c,
+}
+
+// maxCiphertext bounds a protected record: the inner plaintext with its
+// content type and padding, and the AEAD tag.
+const maxCiphertext = handshake.MaxPlaintext + 256
+
+// AlertError is returned when the peer sends an error alert; its Protocol
+// is "tls13". TLS 1.3 treats every alert but close_notify and
+// user_canceled as fatal.
+type AlertError = handshake.AlertError
@@ -0,0 +1,194 @@
+package tls13
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// Conn is a TLS 1.3 connection. Reading, writing and closing are those
+// of the embedded handshake.Conn, which also skips NewSessionTicket
+// messages and honours KeyUpdate once the handshake, run on the first
+// Read or Write or on Handshake, is done.
+type Conn struct {
+	handshake.Conn
+	config   *Config
+	isClient bool
+	state    ConnectionState
+	suite    *cipherSuite
+}
+
+// Client returns a client-side TLS 1.3 connection over conn.
+func Client(conn net.Conn, config *Config) *Conn {
+	c := &Conn{config: config, isClient: true}
+	c.init(conn, c.clientHandshake)
+	return c
+}
+
+// Server returns a server-side TLS 1.3 connection over conn.
+func Server(conn net.Conn, config *Config) *Conn {
+	c := &Conn{config: config}
+	c.init(conn, c.serverHandshake)
+	return c
+}
+
+func (c *Conn) init(conn net.Conn, run func() error) {
+	proto := &handshake.Protocol{
+		Name: "tls13",
+		// The legacy record version is 0x0301 or 0x0303.
+		Version:          func(v uint16) bool { return v>>8 == 3 },
+		MaxCiphertext:    maxCiphertext,
+		MiddleboxCCS:     true,
+		IgnoreAlertLevel: true,
+		Handshake:        run,
+		PostHandshake:    c.handlePostHandshake,
+	}
+	c.Conn = handshake.NewConn(conn, proto, &c.config.Config, &halfConn{}, &halfConn{})
+}
+
+// ConnectionState returns what the handshake negotiated, including the
+// server name and whether a HelloRetryRequest was needed.
+func (c *Conn) ConnectionState() ConnectionState { return c.state }
+
+// halfConn is the record protection of one direction under one traffic
+// secret. Until the first secret is installed aead is nil and records are
+// plaintext.
+type halfConn struct {
+	aead   cipher.AEAD
+	iv     []byte
+	secret []byte
+	seq    uint64
+}
+
+func newHalfConn(suite *cipherSuite, secret []byte) (*halfConn, error) {
+	key, iv := trafficKey(sm3.New, secret)
+	aead, err := suite.aead(key)
+	if err != nil {
+		return nil, err
+	}
+	return &halfConn{aead: aead, iv: iv, secret: secret}, nil
+}
+
+// nonce is the IV XORed with the sequence number.
+func (hc *halfConn) nonce() []byte {
+	nonce := append([]byte{}, hc.iv...)
+	for i := 0; i < 8; i++ {
+		nonce[len(nonce)-1-i] ^= byte(hc.seq >> (8 * i))
+	}
+	return nonce
+}
+
+// Seal returns the record of type typ holding payload. Protected records
+// are application_data records whose TLSInnerPlaintext carries the real
+// type; the record header is the additional data.
+func (hc *halfConn) Seal(typ handshake.RecordType, payload []byte) ([]byte, error) {
+	hdr := make([]byte, handshake.RecordHeaderLen)
+	binary.BigEndian.PutUint16(hdr[1:], 0x0303)
+	if hc.aead == nil {
+		hdr[0] = byte(typ)
+		binary.BigEndian.PutUint16(hdr[3:], uint16(len(payload)))
+		return append(hdr, payload...), nil
+	}
+	defer func() { hc.seq++ }()
+	inner := append(append([]byte{}, payload...), byte(typ))
+	hdr[0] = byte(handshake.RecordTypeApplicationData)
+	binary.BigEndian.PutUint16(hdr[3:], uint16(len(inner)+hc.aead.Overhead()))
+	return hc.aead.Seal(hdr, hc.nonce(), inner, hdr), nil
+}
+
+// Open reverses Seal, returning the inner type and content of a
+// protected record. Once keys are installed every record must be
+// protected, and before that none may carry application data. An inner
+// plaintext of only padding is handshake.ErrBadRecord as well.
+func (hc *halfConn) Open(hdr, fragment []byte) (handshake.RecordType, []byte, error) {
+	typ := handshake.RecordType(hdr[0])
+	if hc.aead == nil {
+		if typ == handshake.RecordTypeApplicationData {
+			return 0, nil, errors.New("application data before the handshake")
+		}
+		return typ, fragment, nil
+	}
+	if typ != handshake.RecordTypeApplicationData {
+		return 0, nil, fmt.Errorf("unprotected record of type %d", typ)
+	}
+	defer func() { hc.seq++ }()
+	inner, err := hc.aead.Open(nil, hc.nonce(), fragment, hdr)
+	if err != nil {
+		return 0, nil, handshake.ErrBadRecord
+	}
+	i := len(inner) - 1
+	for i >= 0 && inner[i] == 0 {
+		i--
+	}
+	if i < 0 {
+		return 0, nil, handshake.ErrBadRecord
+	}
+	return handshake.RecordType(inner[i]), inner[:i], nil
+}
+
+// writeChangeCipherSpec sends the unprotected ChangeCipherSpec of
+// middlebox compatibility mode (RFC 8446 appendix D.4).
+func (c *Conn) writeChangeCipherSpec() error {
+	_, err := c.NetConn().Write([]byte{byte(handshake.RecordTypeChangeCipherSpec), 3, 3, 0, 1, 1})
+	return err
+}
+
+// handlePostHandshake processes the complete handshake messages received
+// after the handshake.
+func (c *Conn) handlePostHandshake() error {
+	for {
+		msg, err := c.NextHandshake()
+		if msg == nil || err != nil {
+			return err
+		}
+		switch msg[0] {
+		case typeNewSessionTicket:
+			if !c.isClient {
+				return c.Fail(handshake.AlertUnexpectedMessage, "NewSessionTicket from a client")
+			}
+			// Resumption is not supported.
+		case typeKeyUpdate:
+			if len(msg) != 5 || msg[4] > 1 {
+				return c.Fail(handshake.AlertDecodeError, "malformed KeyUpdate")
+			}
+			if c.HandshakeBuffered() {
+				return c.Fail(handshake.AlertUnexpectedMessage, "KeyUpdate not at a record boundary")
+			}
+			in, err := newHalfConn(c.suite, nextTrafficSecret(sm3.New, c.In.(*halfConn).secret))
+			if err != nil {
+				return err
+			}
+			c.In = in
+			if msg[4] == 1 {
+				if err := c.updateKeys(false); err != nil {
+					return err
+				}
+			}
+		default:
+			return c.Fail(handshake.AlertUnexpectedMessage, "handshake message type %d after the handshake", msg[0])
+		}
+	}
+}
+
+// updateKeys sends KeyUpdate, asking the peer to update its keys too if
+// requestPeer is set, and moves to the next sending traffic secret.
+func (c *Conn) updateKeys(requestPeer bool) error {
+	var request byte
+	if requestPeer {
+		request = 1
+	}
+	if err := c.WriteRecord(handshake.RecordTypeHandshake, handshake.Message(typeKeyUpdate, []byte{request})); err != nil {
+		return err
+	}
+	out, err := newHalfConn(c.suite, nextTrafficSecret(sm3.New, c.Out.(*halfConn).secret))
+	if err != nil {
+		return err
+	}
+	c.Out = out
+	return nil
+}
@@ -0,0 +1,219 @@
+package tls13
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"net"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// handshakeState is the transcript of a handshake in progress, which
+// the key schedule, Finished and CertificateVerify steps below hash.
+type handshakeState struct {
+	*handshake.Transcript
+	c *Conn
+}
+
+func newHandshakeState(c *Conn) *handshakeState {
+	return &handshakeState{handshake.NewTranscript(&c.Conn), c}
+}
+
+// hash returns the transcript hash of the messages so far.
+func (hs *handshakeState) hash() []byte {
+	h := sm3.New()
+	h.Write(hs.Bytes())
+	return h.Sum(nil)
+}
+
+// restartTranscript replaces the first ClientHello by a message_hash
+// message once a HelloRetryRequest is sent or received.
+func (hs *handshakeState) restartTranscript() {
+	msg := handshake.Message(typeMessageHash, hs.hash())
+	hs.Reset()
+	hs.Add(msg)
+}
+
+// secrets are the traffic secrets of one stage of the key schedule.
+type secrets struct {
+	client, server []byte
+}
+
+// installHandshakeKeys derives the handshake traffic secrets from the
+// (EC)DHE shared secret and the transcript through ServerHello, installs
+// them on c and returns them with the handshake secret.
+func (hs *handshakeState) installHandshakeKeys(shared []byte) (secrets, []byte, error) {
+	handshakeSecret := nextSecret(sm3.New, earlySecret(sm3.New), shared)
+	th := hs.hash()
+	s := secrets{
+		client: deriveSecret(sm3.New, handshakeSecret, "c hs traffic", th),
+		server: deriveSecret(sm3.New, handshakeSecret, "s hs traffic", th),
+	}
+	return s, handshakeSecret, hs.c.install(s)
+}
+
+// applicationSecrets derives the application traffic secrets from the
+// transcript through the server Finished.
+func (hs *handshakeState) applicationSecrets(handshakeSecret []byte) secrets {
+	master := nextSecret(sm3.New, handshakeSecret, nil)
+	th := hs.hash()
+	return secrets{
+		client: deriveSecret(sm3.New, master, "c ap traffic", th),
+		server: deriveSecret(sm3.New, master, "s ap traffic", th),
+	}
+}
+
+// install sets up both directions of c from s.
+func (c *Conn) install(s secrets) error {
+	send, recv := s.server, s.client
+	if c.isClient {
+		send, recv = s.client, s.server
+	}
+	out, err := newHalfConn(c.suite, send)
+	if err != nil {
+		return err
+	}
+	c.Out = out
+	return c.installIn(recv)
+}
+
+// installIn sets up the receiving direction of c from secret. Handshake
+// messages must not span the key change.
+func (c *Conn) installIn(secret []byte) error {
+	if c.HandshakeBuffered() {
+		return c.Fail(handshake.AlertUnexpectedMessage, "handshake message spans a key change")
+	}
+	in, err := newHalfConn(c.suite, secret)
+	if err != nil {
+		return err
+	}
+	c.In = in
+	return nil
+}
+
+// finished returns the Finished message sent under the handshake traffic
+// secret base.
+func (hs *handshakeState) finished(base []byte) []byte {
+	return handshake.Message(typeFinished, finishedData(sm3.New, base, hs.hash()))
+}
+
+// readFinished reads the peer's Finished and checks it against base.
+func (hs *handshakeState) readFinished(base []byte) error {
+	want := finishedData(sm3.New, base, hs.hash())
+	body, err := hs.ReadMessage(typeFinished)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(body, want) {
+		return hs.c.Fail(handshake.AlertDecryptError, "peer Finished does not verify")
+	}
+	return nil
+}
+
+// Context strings of CertificateVerify signatures.
+const (
+	serverSignatureContext = "TLS 1.3, server CertificateVerify"
+	clientSignatureContext = "TLS 1.3, client CertificateVerify"
+)
+
+// signedContent is what CertificateVerify signs: 64 spaces, the context
+// string, a zero byte and the transcript hash.
+func signedContent(context string, transcriptHash []byte) []byte {
+	b := bytes.Repeat([]byte{0x20}, 64)
+	b = append(b, context...)
+	b = append(b, 0)
+	return append(b, transcriptHash...)
+}
+
+// writeCertificateVerify signs the transcript with priv as sm2sig_sm3.
+func (hs *handshakeState) writeCertificateVerify(priv *sm2.PrivateKey, context string) error {
+	cfg := hs.c.config
+	r, s, err := sm2.Sign(cfg.Random(), priv, signedContent(context, hs.hash()), cfg.signatureUID())
+	if err != nil {
+		return err
+	}
+	sig, err := sm2.MarshalSignature(r, s)
+	if err != nil {
+		return err
+	}
+	return hs.WriteMessage((&certificateVerifyMsg{signatureSM2SigSM3, sig}).marshal())
+}
+
+// readCertificateVerify reads the peer's CertificateVerify and checks it
+// under pub.
+func (hs *handshakeState) readCertificateVerify(pub *sm2.PublicKey, context string) error {
+	content := signedContent(context, hs.hash())
+	body, err := hs.ReadMessage(typeCertificateVerify)
+	if err != nil {
+		return err
+	}
+	var cv certificateVerifyMsg
+	if !cv.unmarshal(body) {
+		return hs.c.Fail(handshake.AlertDecodeError, "malformed CertificateVerify")
+	}
+	if cv.scheme != signatureSM2SigSM3 {
+		return hs.c.Fail(handshake.AlertIllegalParameter, "CertificateVerify signature scheme %#04x", cv.scheme)
+	}
+	r, s, err := sm2.UnmarshalSignature(cv.signature)
+	if err != nil || !sm2.Verify(pub, content, hs.c.config.signatureUID(), r, s) {
+		return hs.c.Fail(handshake.AlertDecryptError, "CertificateVerify signature does not verify")
+	}
+	return nil
+}
+
+// verifyPeer parses the peer's chain and, unless InsecureSkipVerify is
+// set, verifies it against RootCAs for purpose and checks the leaf
+// against serverName when that is not empty.
+func (c *Conn) verifyPeer(list [][]byte, purpose asn1.ObjectIdentifier, serverName string) ([]*cert.Certificate, error) {
+	certs, err := c.ParseCertificates(list)
+	if err != nil {
+		return nil, err
+	}
+	if certs[0].PublicKey == nil {
+		return nil, c.Fail(handshake.AlertUnsupportedCertificate, "peer certificate does not hold an SM2 key")
+	}
+	if c.config.InsecureSkipVerify {
+		return certs, nil
+	}
+	if err := c.VerifyCertificate("peer certificate", certs[0], certs[1:], purpose); err != nil {
+		return nil, err
+	}
+	if ku := certs[0].KeyUsage; ku != 0 && ku&cert.KeyUsageDigitalSignature == 0 {
+		return nil, c.Fail(handshake.AlertBadCertificate, "peer certificate does not allow digital signatures")
+	}
+	if serverName != "" && !matchesHostname(certs[0], serverName) {
+		return nil, c.Fail(handshake.AlertBadCertificate, "peer certificate is not valid for %q", serverName)
+	}
+	return certs, nil
+}
+
+// matchesHostname reports whether the subject alternative names of c
+// cover host: an IP address must be listed, and a DNS name must match a
+// name exactly or a wildcard in its leftmost label.
+func matchesHostname(c *cert.Certificate, host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		for _, have := range c.IPAddresses {
+			if have.Equal(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, name := range c.DNSNames {
+		name = strings.ToLower(strings.TrimSuffix(name, "."))
+		if name == host {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(name, "*."); ok {
+			if i := strings.IndexByte(host, '.'); i > 0 && host[i+1:] == rest {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package tls13
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"slices"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func (c *Conn) clientHandshake() error {
+	cfg := c.config
+	hs := newHandshakeState(c)
+
+	key, err := sm2.GenerateKey(cfg.Random())
+	if err != nil {
+		return err
+	}
+	share := keyShare{curveSM2, key.PublicKey.Bytes()}
+	hello := &clientHelloMsg{
+		random:            make([]byte, 32),
+		sessionID:         make([]byte, 32), // middlebox compatibility mode
+		cipherSuites:      cfg.suites(),
+		compressions:      []uint8{0},
+		supportedVersions: []uint16{VersionTLS13},
+		supportedGroups:   []uint16{curveSM2},
+		signatureSchemes:  []uint16{signatureSM2SigSM3},
+	}
+	if !cfg.omitKeyShare {
+		hello.keyShares = []keyShare{share}
+	}
+	// RFC 6066 does not allow IP addresses in server_name.
+	if net.ParseIP(cfg.ServerName) == nil {
+		hello.serverName = cfg.ServerName
+	}
+	if _, err := io.ReadFull(cfg.Random(), hello.random); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(cfg.Random(), hello.sessionID); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(hello.marshal()); err != nil {
+		return err
+	}
+
+	sh, err := c.readServerHello(hs)
+	if err != nil {
+		return err
+	}
+	sentCCS := false
+	if sh.isHelloRetryRequest() {
+		if sh.selectedGroup != curveSM2 || len(hello.keyShares) != 0 {
+			return c.Fail(handshake.AlertIllegalParameter, "HelloRetryRequest would not change the ClientHello")
+		}
+		if err := c.checkServerHello(hello, sh); err != nil {
+			return err
+		}
+		retrySuite := sh.cipherSuite
+		c.state.HelloRetry = true
+		if err := c.writeChangeCipherSpec(); err != nil {
+			return err
+		}
+		sentCCS = true
+		hello.keyShares = []keyShare{share}
+		hello.cookie = sh.cookie
+		if err := hs.WriteMessage(hello.marshal()); err != nil {
+			return err
+		}
+		if sh, err = c.readServerHello(hs); err != nil {
+			return err
+		}
+		if sh.isHelloRetryRequest() {
+			return c.Fail(handshake.AlertUnexpectedMessage, "second HelloRetryRequest")
+		}
+		if sh.cipherSuite != retrySuite {
+			return c.Fail(handshake.AlertIllegalParameter, "server changed the cipher suite after HelloRetryRequest")
+		}
+	}
+	if err := c.checkServerHello(hello, sh); err != nil {
+		return err
+	}
+	if sh.keyShare.group != curveSM2 {
+		return c.Fail(handshake.AlertIllegalParameter, "server chose group %#04x, which was not offered", sh.keyShare.group)
+	}
+	shared, err := sharedSecret(key, sh.keyShare.data)
+	if err != nil {
+		return c.Fail(handshake.AlertIllegalParameter, "server key share: %v", err)
+	}
+	c.suite = suiteByID(sh.cipherSuite)
+	c.state.Version = VersionTLS13
+	c.state.CipherSuite = sh.cipherSuite
+	c.state.ServerName = cfg.ServerName
+	hsSecrets, hsSecret, err := hs.installHandshakeKeys(shared)
+	if err != nil {
+		return err
+	}
+
+	body, err := hs.ReadMessage(typeEncryptedExtensions)
+	if err != nil {
+		return err
+	}
+	if !parseEncryptedExtensions(body) {
+		return c.Fail(handshake.AlertDecodeError, "malformed EncryptedExtensions")
+	}
+
+	msg, err := c.ReadHandshake()
+	if err != nil {
+		return err
+	}
+	var certReq *certificateRequestMsg
+	if msg[0] == typeCertificateRequest {
+		certReq = new(certificateRequestMsg)
+		if !certReq.unmarshal(msg[4:]) {
+			return c.Fail(handshake.AlertDecodeError, "malformed CertificateRequest")
+		}
+		hs.Add(msg)
+		if msg, err = c.ReadHandshake(); err != nil {
+			return err
+		}
+	}
+	if msg[0] != typeCertificate {
+		return c.Fail(handshake.AlertUnexpectedMessage, "handshake message type %d, want Certificate", msg[0])
+	}
+	hs.Add(msg)
+	var cm certificateMsg
+	if !cm.unmarshal(msg[4:]) || len(cm.context) != 0 {
+		return c.Fail(handshake.AlertDecodeError, "malformed Certificate")
+	}
+	if len(cm.certificates) == 0 {
+		return c.Fail(handshake.AlertDecodeError, "server sent no certificate")
+	}
+	peer, err := c.verifyPeer(cm.certificates, cert.OIDExtKeyUsageServerAuth, cfg.ServerName)
+	if err != nil {
+		return err
+	}
+	c.state.PeerCertificates = peer
+	if err := hs.readCertificateVerify(peer[0].PublicKey, serverSignatureContext); err != nil {
+		return err
+	}
+	if err := hs.readFinished(hsSecrets.server); err != nil {
+		return err
+	}
+	app := hs.applicationSecrets(hsSecret)
+
+	if !sentCCS {
+		if err := c.writeChangeCipherSpec(); err != nil {
+			return err
+		}
+	}
+	if certReq != nil {
+		var chain [][]byte
+		if cfg.Certificate != nil && slices.Contains(certReq.signatureSchemes, signatureSM2SigSM3) {
+			chain = cfg.Certificate.Chain
+		}
+		if err := hs.WriteMessage((&certificateMsg{certReq.context, chain}).marshal()); err != nil {
+			return err
+		}
+		if chain != nil {
+			if err := hs.writeCertificateVerify(cfg.Certificate.PrivateKey, clientSignatureContext); err != nil {
+				return err
+			}
+		}
+	}
+	if err := hs.WriteMessage(hs.finished(hsSecrets.client)); err != nil {
+		return err
+	}
+	return c.install(app)
+}
+
+// readServerHello reads a ServerHello or HelloRetryRequest. A
+// HelloRetryRequest restarts the transcript before it is added.
+func (c *Conn) readServerHello(hs *handshakeState) (*serverHelloMsg, error) {
+	msg, err := c.ReadHandshake()
+	if err != nil {
+		return nil, err
+	}
+	if msg[0] != typeServerHello {
+		return nil, c.Fail(handshake.AlertUnexpectedMessage, "handshake message type %d, want ServerHello", msg[0])
+	}
+	sh := new(serverHelloMsg)
+	if !sh.unmarshal(msg[4:]) {
+		return nil, c.Fail(handshake.AlertDecodeError, "malformed ServerHello")
+	}
+	if sh.isHelloRetryRequest() {
+		hs.restartTranscript()
+	}
+	hs.Add(msg)
+	return sh, nil
+}
+
+// checkServerHello checks the fields a ServerHello and a
+// HelloRetryRequest share against the ClientHello.
+func (c *Conn) checkServerHello(hello *clientHelloMsg, sh *serverHelloMsg) error {
+	if sh.supportedVersion != VersionTLS13 {
+		return c.Fail(handshake.AlertProtocolVersion, "server did not select TLS 1.3")
+	}
+	if suiteByID(sh.cipherSuite) == nil || !slices.Contains(hello.cipherSuites, sh.cipherSuite) {
+		return c.Fail(handshake.AlertIllegalParameter, "server chose cipher suite %s, which was not offered", CipherSuiteName(sh.cipherSuite))
+	}
+	if sh.compression != 0 {
+		return c.Fail(handshake.AlertIllegalParameter, "server chose compression method %d", sh.compression)
+	}
+	if !bytes.Equal(sh.sessionID, hello.sessionID) {
+		return c.Fail(handshake.AlertIllegalParameter, "server did not echo the legacy session ID")
+	}
+	return nil
+}
+
+var errBadKeyShare = errors.New("key share is not an uncompressed curveSM2 point")
+
+// sharedSecret is the curveSM2 ECDHE secret of key and the peer's
+// uncompressed point.
+func sharedSecret(key *sm2.PrivateKey, peer []byte) ([]byte, error) {
+	if len(peer) != 65 || peer[0] != 4 {
+		return nil, errBadKeyShare
+	}
+	pub, err := sm2.ParsePublicKey(peer)
+	if err != nil {
+		return nil, err
+	}
+	return sm2.ECDH(key, pub)
+}
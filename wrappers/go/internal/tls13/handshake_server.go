@@ -0,0 +1,188 @@
+package tls13
+
+import (
+	"errors"
+	"io"
+	"slices"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func (c *Conn) serverHandshake() error {
+	cfg := c.config
+	if cfg.Certificate == nil {
+		return errors.New("tls13: the server needs a certificate")
+	}
+	hs := newHandshakeState(c)
+
+	ch, err := c.readClientHello(hs)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(ch.supportedVersions, VersionTLS13) {
+		return c.Fail(handshake.AlertProtocolVersion, "client does not offer TLS 1.3")
+	}
+	if len(ch.compressions) != 1 || ch.compressions[0] != 0 {
+		return c.Fail(handshake.AlertIllegalParameter, "client offers compression")
+	}
+	var suite *cipherSuite
+	for _, id := range cfg.suites() {
+		if s := suiteByID(id); s != nil && slices.Contains(ch.cipherSuites, id) {
+			suite = s
+			break
+		}
+	}
+	if suite == nil {
+		return c.Fail(handshake.AlertHandshakeFailure, "no cipher suite in common")
+	}
+	if !slices.Contains(ch.signatureSchemes, signatureSM2SigSM3) {
+		return c.Fail(handshake.AlertHandshakeFailure, "client does not offer sm2sig_sm3")
+	}
+	if !slices.Contains(ch.supportedGroups, curveSM2) {
+		return c.Fail(handshake.AlertHandshakeFailure, "client does not offer curveSM2")
+	}
+	c.suite = suite
+	c.state = ConnectionState{Version: VersionTLS13, CipherSuite: suite.id, ServerName: ch.serverName}
+
+	sentCCS := false
+	share := findKeyShare(ch.keyShares)
+	if share == nil {
+		hs.restartTranscript()
+		hrr := &serverHelloMsg{
+			random:           helloRetryRequestRandom,
+			sessionID:        ch.sessionID,
+			cipherSuite:      suite.id,
+			supportedVersion: VersionTLS13,
+			selectedGroup:    curveSM2,
+		}
+		if err := hs.WriteMessage(hrr.marshal()); err != nil {
+			return err
+		}
+		if len(ch.sessionID) > 0 {
+			if err := c.writeChangeCipherSpec(); err != nil {
+				return err
+			}
+			sentCCS = true
+		}
+		c.state.HelloRetry = true
+		if ch, err = c.readClientHello(hs); err != nil {
+			return err
+		}
+		if share = findKeyShare(ch.keyShares); share == nil || len(ch.keyShares) != 1 {
+			return c.Fail(handshake.AlertIllegalParameter, "second ClientHello does not hold just a curveSM2 key share")
+		}
+		if !slices.Contains(ch.cipherSuites, suite.id) {
+			return c.Fail(handshake.AlertIllegalParameter, "second ClientHello drops the selected cipher suite")
+		}
+	}
+
+	key, err := sm2.GenerateKey(cfg.Random())
+	if err != nil {
+		return err
+	}
+	shared, err := sharedSecret(key, share.data)
+	if err != nil {
+		return c.Fail(handshake.AlertIllegalParameter, "client key share: %v", err)
+	}
+	sh := &serverHelloMsg{
+		random:           make([]byte, 32),
+		sessionID:        ch.sessionID,
+		cipherSuite:      suite.id,
+		supportedVersion: VersionTLS13,
+		keyShare:         keyShare{curveSM2, key.PublicKey.Bytes()},
+	}
+	if _, err := io.ReadFull(cfg.Random(), sh.random); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(sh.marshal()); err != nil {
+		return err
+	}
+	if len(ch.sessionID) > 0 && !sentCCS {
+		if err := c.writeChangeCipherSpec(); err != nil {
+			return err
+		}
+	}
+	hsSecrets, hsSecret, err := hs.installHandshakeKeys(shared)
+	if err != nil {
+		return err
+	}
+
+	if err := hs.WriteMessage(handshake.Message(typeEncryptedExtensions, []byte{0, 0})); err != nil {
+		return err
+	}
+	if cfg.ClientAuth {
+		req := &certificateRequestMsg{signatureSchemes: []uint16{signatureSM2SigSM3}}
+		if err := hs.WriteMessage(req.marshal()); err != nil {
+			return err
+		}
+	}
+	if err := hs.WriteMessage((&certificateMsg{certificates: cfg.Certificate.Chain}).marshal()); err != nil {
+		return err
+	}
+	if err := hs.writeCertificateVerify(cfg.Certificate.PrivateKey, serverSignatureContext); err != nil {
+		return err
+	}
+	if err := hs.WriteMessage(hs.finished(hsSecrets.server)); err != nil {
+		return err
+	}
+	app := hs.applicationSecrets(hsSecret)
+	// The server sends under the application secret from here on, while
+	// the client's flight is still under its handshake secret.
+	out, err := newHalfConn(suite, app.server)
+	if err != nil {
+		return err
+	}
+	c.Out = out
+
+	if cfg.ClientAuth {
+		body, err := hs.ReadMessage(typeCertificate)
+		if err != nil {
+			return err
+		}
+		var cm certificateMsg
+		if !cm.unmarshal(body) || len(cm.context) != 0 {
+			return c.Fail(handshake.AlertDecodeError, "malformed Certificate")
+		}
+		if len(cm.certificates) == 0 {
+			return c.Fail(handshake.AlertCertificateRequired, "client sent no certificate")
+		}
+		peer, err := c.verifyPeer(cm.certificates, cert.OIDExtKeyUsageClientAuth, "")
+		if err != nil {
+			return err
+		}
+		c.state.PeerCertificates = peer
+		if err := hs.readCertificateVerify(peer[0].PublicKey, clientSignatureContext); err != nil {
+			return err
+		}
+	}
+	if err := hs.readFinished(hsSecrets.client); err != nil {
+		return err
+	}
+	return c.installIn(app.client)
+}
+
+// readClientHello reads and parses a ClientHello and adds it to the
+// transcript.
+func (c *Conn) readClientHello(hs *handshakeState) (*clientHelloMsg, error) {
+	body, err := hs.ReadMessage(typeClientHello)
+	if err != nil {
+		return nil, err
+	}
+	ch := new(clientHelloMsg)
+	if !ch.unmarshal(body) {
+		return nil, c.Fail(handshake.AlertDecodeError, "malformed ClientHello")
+	}
+	return ch, nil
+}
+
+// findKeyShare returns the client's curveSM2 key share, if any.
+func findKeyShare(shares []keyShare) *keyShare {
+	for i := range shares {
+		if shares[i].group == curveSM2 {
+			return &shares[i]
+		}
+	}
+	return nil
+}
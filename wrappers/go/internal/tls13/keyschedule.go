@@ -0,0 +1,91 @@
+package tls13
+
+import (
+	"crypto/hmac"
+	"hash"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+)
+
+// The key schedule of RFC 8446 section 7.1, parameterised by its hash so
+// that it can be checked against the SHA-256 traces of RFC 8448. The TLS
+// 1.3 suites of RFC 8998 both use SM3.
+
+// hkdfExtract is HKDF-Extract of RFC 5869; a nil salt or ikm stands for
+// a string of hash-length zeros.
+func hkdfExtract(newHash func() hash.Hash, salt, ikm []byte) []byte {
+	size := newHash().Size()
+	if salt == nil {
+		salt = make([]byte, size)
+	}
+	if ikm == nil {
+		ikm = make([]byte, size)
+	}
+	mac := hmac.New(newHash, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+// expandLabel is HKDF-Expand-Label: HKDF-Expand of secret with the
+// HkdfLabel of "tls13 "+label and context.
+func expandLabel(newHash func() hash.Hash, secret []byte, label string, context []byte, n int) []byte {
+	var info handshake.Builder
+	info.U16(uint16(n))
+	info.Vec8([]byte("tls13 " + label))
+	info.Vec8(context)
+
+	mac := hmac.New(newHash, secret)
+	var out, t []byte
+	for i := byte(1); len(out) < n; i++ {
+		mac.Reset()
+		mac.Write(t)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		t = mac.Sum(nil)
+		out = append(out, t...)
+	}
+	return out[:n]
+}
+
+// deriveSecret is Derive-Secret with the hash of the transcript already
+// computed.
+func deriveSecret(newHash func() hash.Hash, secret []byte, label string, transcriptHash []byte) []byte {
+	return expandLabel(newHash, secret, label, transcriptHash, newHash().Size())
+}
+
+// emptyHash is the hash of the empty string, the context of the
+// "derived" secrets.
+func emptyHash(newHash func() hash.Hash) []byte {
+	return newHash().Sum(nil)
+}
+
+// earlySecret is the early secret without a PSK.
+func earlySecret(newHash func() hash.Hash) []byte {
+	return hkdfExtract(newHash, nil, nil)
+}
+
+// nextSecret extracts the secret that follows secret in the schedule,
+// with ikm the (EC)DHE shared secret or nil.
+func nextSecret(newHash func() hash.Hash, secret, ikm []byte) []byte {
+	salt := deriveSecret(newHash, secret, "derived", emptyHash(newHash))
+	return hkdfExtract(newHash, salt, ikm)
+}
+
+// trafficKey derives the write key and IV of a traffic secret.
+func trafficKey(newHash func() hash.Hash, secret []byte) (key, iv []byte) {
+	return expandLabel(newHash, secret, "key", nil, keyLength), expandLabel(newHash, secret, "iv", nil, ivLength)
+}
+
+// finishedData is the verify_data of a Finished message sent under the
+// handshake traffic secret base.
+func finishedData(newHash func() hash.Hash, base, transcriptHash []byte) []byte {
+	key := expandLabel(newHash, base, "finished", nil, newHash().Size())
+	mac := hmac.New(newHash, key)
+	mac.Write(transcriptHash)
+	return mac.Sum(nil)
+}
+
+// nextTrafficSecret is the application traffic secret after a KeyUpdate.
+func nextTrafficSecret(newHash func() hash.Hash, secret []byte) []byte {
+	return expandLabel(newHash, secret, "traffic upd", nil, newHash().Size())
+}
@@ -0,0 +1,303 @@
+package tls13
+
+import (
+	"bytes"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+)
+
+type keyShare struct {
+	group uint16
+	data  []byte
+}
+
+type clientHelloMsg struct {
+	random            []byte
+	sessionID         []byte
+	cipherSuites      []uint16
+	compressions      []uint8
+	serverName        string
+	supportedVersions []uint16
+	supportedGroups   []uint16
+	signatureSchemes  []uint16
+	keyShares         []keyShare
+	cookie            []byte
+}
+
+func (m *clientHelloMsg) marshal() []byte {
+	var b handshake.Builder
+	b.U16(0x0303) // legacy_version
+	b = append(b, m.random...)
+	b.Vec8(m.sessionID)
+	b.Vec16(handshake.U16s(m.cipherSuites))
+	b.Vec8(m.compressions)
+
+	var exts handshake.Builder
+	if m.serverName != "" {
+		var name handshake.Builder
+		name.U8(0) // host_name
+		name.Vec16([]byte(m.serverName))
+		var list handshake.Builder
+		list.Vec16(name)
+		exts.Extension(extensionServerName, list)
+	}
+	var versions handshake.Builder
+	versions.Vec8(handshake.U16s(m.supportedVersions))
+	exts.Extension(extensionSupportedVersions, versions)
+	var groups handshake.Builder
+	groups.Vec16(handshake.U16s(m.supportedGroups))
+	exts.Extension(extensionSupportedGroups, groups)
+	var schemes handshake.Builder
+	schemes.Vec16(handshake.U16s(m.signatureSchemes))
+	exts.Extension(extensionSignatureAlgorithms, schemes)
+	var shares handshake.Builder
+	for _, ks := range m.keyShares {
+		shares.U16(ks.group)
+		shares.Vec16(ks.data)
+	}
+	var keyShares handshake.Builder
+	keyShares.Vec16(shares)
+	exts.Extension(extensionKeyShare, keyShares)
+	if m.cookie != nil {
+		var cookie handshake.Builder
+		cookie.Vec16(m.cookie)
+		exts.Extension(extensionCookie, cookie)
+	}
+	b.Vec16(exts)
+	return handshake.Message(typeClientHello, b)
+}
+
+func (m *clientHelloMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	p.U16() // legacy_version
+	m.random = p.Bytes(32)
+	m.sessionID = p.Vec8()
+	suites, ok := handshake.U16List(p.Vec16())
+	m.cipherSuites = suites
+	m.compressions = p.Vec8()
+	var extData []byte
+	if !p.Empty() {
+		extData = p.Vec16()
+	}
+	if !p.Done() || !ok || len(m.sessionID) > 32 {
+		return false
+	}
+	exts, ok := handshake.Extensions(extData)
+	if !ok {
+		return false
+	}
+	for typ, data := range exts {
+		e := handshake.NewParser(data)
+		switch typ {
+		case extensionServerName:
+			list := handshake.NewParser(e.Vec16())
+			for list.More() {
+				if kind, name := list.U8(), list.Vec16(); kind == 0 {
+					m.serverName = string(name)
+				}
+			}
+			ok = list.OK()
+		case extensionSupportedVersions:
+			m.supportedVersions, ok = handshake.U16List(e.Vec8())
+		case extensionSupportedGroups:
+			m.supportedGroups, ok = handshake.U16List(e.Vec16())
+		case extensionSignatureAlgorithms:
+			m.signatureSchemes, ok = handshake.U16List(e.Vec16())
+		case extensionKeyShare:
+			shares := handshake.NewParser(e.Vec16())
+			for shares.More() {
+				m.keyShares = append(m.keyShares, keyShare{shares.U16(), shares.Vec16()})
+			}
+			ok = shares.OK()
+		case extensionCookie:
+			m.cookie = e.Vec16()
+		default:
+			continue
+		}
+		if !ok || !e.Done() {
+			return false
+		}
+	}
+	return true
+}
+
+// serverHelloMsg is a ServerHello or, with helloRetryRequestRandom as its
+// random, a HelloRetryRequest, whose key_share holds only selectedGroup.
+type serverHelloMsg struct {
+	random           []byte
+	sessionID        []byte
+	cipherSuite      uint16
+	compression      uint8
+	supportedVersion uint16
+	keyShare         keyShare
+	selectedGroup    uint16
+	cookie           []byte
+}
+
+func (m *serverHelloMsg) isHelloRetryRequest() bool {
+	return bytes.Equal(m.random, helloRetryRequestRandom)
+}
+
+func (m *serverHelloMsg) marshal() []byte {
+	var b handshake.Builder
+	b.U16(0x0303) // legacy_version
+	b = append(b, m.random...)
+	b.Vec8(m.sessionID)
+	b.U16(m.cipherSuite)
+	b.U8(m.compression)
+
+	var exts handshake.Builder
+	var version handshake.Builder
+	version.U16(m.supportedVersion)
+	exts.Extension(extensionSupportedVersions, version)
+	var share handshake.Builder
+	if m.isHelloRetryRequest() {
+		share.U16(m.selectedGroup)
+	} else {
+		share.U16(m.keyShare.group)
+		share.Vec16(m.keyShare.data)
+	}
+	exts.Extension(extensionKeyShare, share)
+	if m.cookie != nil {
+		var cookie handshake.Builder
+		cookie.Vec16(m.cookie)
+		exts.Extension(extensionCookie, cookie)
+	}
+	b.Vec16(exts)
+	return handshake.Message(typeServerHello, b)
+}
+
+func (m *serverHelloMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	p.U16() // legacy_version
+	m.random = p.Bytes(32)
+	m.sessionID = p.Vec8()
+	m.cipherSuite = p.U16()
+	m.compression = p.U8()
+	var extData []byte
+	if !p.Empty() {
+		extData = p.Vec16()
+	}
+	if !p.Done() || len(m.sessionID) > 32 {
+		return false
+	}
+	exts, ok := handshake.Extensions(extData)
+	if !ok {
+		return false
+	}
+	for typ, data := range exts {
+		e := handshake.NewParser(data)
+		switch typ {
+		case extensionSupportedVersions:
+			m.supportedVersion = e.U16()
+		case extensionKeyShare:
+			if m.isHelloRetryRequest() {
+				m.selectedGroup = e.U16()
+			} else {
+				m.keyShare = keyShare{e.U16(), e.Vec16()}
+			}
+		case extensionCookie:
+			m.cookie = e.Vec16()
+		default:
+			continue
+		}
+		if !e.Done() {
+			return false
+		}
+	}
+	return true
+}
+
+// parseEncryptedExtensions checks the framing of EncryptedExtensions. The
+// package sends none and ignores what it receives.
+func parseEncryptedExtensions(body []byte) bool {
+	p := handshake.NewParser(body)
+	data := p.Vec16()
+	_, ok := handshake.Extensions(data)
+	return p.Done() && ok
+}
+
+type certificateRequestMsg struct {
+	context          []byte
+	signatureSchemes []uint16
+}
+
+func (m *certificateRequestMsg) marshal() []byte {
+	var b handshake.Builder
+	b.Vec8(m.context)
+	var schemes handshake.Builder
+	schemes.Vec16(handshake.U16s(m.signatureSchemes))
+	var exts handshake.Builder
+	exts.Extension(extensionSignatureAlgorithms, schemes)
+	b.Vec16(exts)
+	return handshake.Message(typeCertificateRequest, b)
+}
+
+func (m *certificateRequestMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.context = p.Vec8()
+	extData := p.Vec16()
+	if !p.Done() {
+		return false
+	}
+	exts, ok := handshake.Extensions(extData)
+	if !ok {
+		return false
+	}
+	data, ok := exts[extensionSignatureAlgorithms]
+	if !ok {
+		return false
+	}
+	e := handshake.NewParser(data)
+	m.signatureSchemes, ok = handshake.U16List(e.Vec16())
+	return ok && e.Done()
+}
+
+// certificateMsg carries a DER certificate chain, leaf first. The
+// per-certificate extensions are neither sent nor interpreted.
+type certificateMsg struct {
+	context      []byte
+	certificates [][]byte
+}
+
+func (m *certificateMsg) marshal() []byte {
+	var list handshake.Builder
+	for _, c := range m.certificates {
+		list.Vec24(c)
+		list.Vec16(nil)
+	}
+	var b handshake.Builder
+	b.Vec8(m.context)
+	b.Vec24(list)
+	return handshake.Message(typeCertificate, b)
+}
+
+func (m *certificateMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.context = p.Vec8()
+	list := handshake.NewParser(p.Vec24())
+	for list.More() {
+		m.certificates = append(m.certificates, list.Vec24())
+		list.Vec16()
+	}
+	return p.Done() && list.OK()
+}
+
+type certificateVerifyMsg struct {
+	scheme    uint16
+	signature []byte
+}
+
+func (m *certificateVerifyMsg) marshal() []byte {
+	var b handshake.Builder
+	b.U16(m.scheme)
+	b.Vec16(m.signature)
+	return handshake.Message(typeCertificateVerify, b)
+}
+
+func (m *certificateVerifyMsg) unmarshal(body []byte) bool {
+	p := handshake.NewParser(body)
+	m.scheme = p.U16()
+	m.signature = p.Vec16()
+	return p.Done()
+}
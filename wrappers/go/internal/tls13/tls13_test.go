@@ -0,0 +1,295 @@
+package tls13
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/handshake/handshaketest"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+func fromHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// TestKeySchedule checks the key schedule against the SHA-256 trace of
+// the simple 1-RTT handshake of RFC 8448 section 3.
+func TestKeySchedule(t *testing.T) {
+	early := earlySecret(sha256.New)
+	if want := fromHex("33ad0a1c607ec03b09e6cd9893680ce210adf300aa1f2660e1b22e10f170f92a"); !bytes.Equal(early, want) {
+		t.Errorf("early secret %x", early)
+	}
+	derived := deriveSecret(sha256.New, early, "derived", emptyHash(sha256.New))
+	if want := fromHex("6f2615a108c702c5678f54fc9dbab69716c076189c48250cebeac3576c3611ba"); !bytes.Equal(derived, want) {
+		t.Errorf("derived secret %x", derived)
+	}
+	handshakeSecret := nextSecret(sha256.New, early, fromHex("8bd4054fb55b9d63fdfbacf9f04b9f0d35e6d63f537563efd46272900f89492d"))
+	if want := fromHex("1dc826e93606aa6fdc0aadc12f741b01046aa6b99f691ed221a9f0ca043fbeac"); !bytes.Equal(handshakeSecret, want) {
+		t.Errorf("handshake secret %x", handshakeSecret)
+	}
+	master := nextSecret(sha256.New, handshakeSecret, nil)
+	if want := fromHex("18df06843d13a08bf2a449844c5f8a478001bc4d4c627984d5a41da8d0402919"); !bytes.Equal(master, want) {
+		t.Errorf("master secret %x", master)
+	}
+	for _, tc := range []struct{ secret, key, iv string }{
+		{"b67b7d690cc16c4e75e54213cb2d37b4e9c912bcded9105d42befd59d391ad38", "3fce516009c21727d0f2e4e86ee403bc", "5d313eb2671276ee13000b30"},
+		{"9e40646ce79a7f9dc05af8889bce6552875afa0b06df0087f792ebb7c17504a5", "17422dda596ed5d9acd890e3c63f5051", "5b78923dee08579033e523d9"},
+	} {
+		key, iv := trafficKey(sha256.New, fromHex(tc.secret))
+		if hex.EncodeToString(key) != tc.key || hex.EncodeToString(iv) != tc.iv {
+			t.Errorf("traffic key of %s: %x %x", tc.secret, key, iv)
+		}
+	}
+}
+
+type testPKI struct {
+	root    *cert.Certificate
+	rootKey *sm2.PrivateKey
+	server  *Certificate
+	client  *Certificate
+}
+
+func issue(t *testing.T, tmpl *cert.Template, parent *cert.Certificate, parentKey *sm2.PrivateKey) *Certificate {
+	t.Helper()
+	key, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl.NotBefore = time.Now().Add(-time.Hour)
+	tmpl.NotAfter = time.Now().Add(time.Hour)
+	der, err := cert.Create(rand.Reader, tmpl, &key.PublicKey, parent, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Certificate{Chain: [][]byte{der}, PrivateKey: key}
+}
+
+func newPKI(t *testing.T) *testPKI {
+	rootKey, err := sm2.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := cert.Create(rand.Reader, &cert.Template{
+		Subject:   pkix.Name{CommonName: "TLS 1.3 test CA"},
+		NotBefore: time.Now().Add(-time.Hour),
+		NotAfter:  time.Now().Add(time.Hour),
+		KeyUsage:  cert.KeyUsageCertSign,
+		IsCA:      true,
+	}, &rootKey.PublicKey, nil, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := cert.Parse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testPKI{
+		root:    root,
+		rootKey: rootKey,
+		server: issue(t, &cert.Template{
+			Subject:  pkix.Name{CommonName: "server"},
+			KeyUsage: cert.KeyUsageDigitalSignature,
+			DNSNames: []string{"*.example.com"},
+		}, root, rootKey),
+		client: issue(t, &cert.Template{
+			Subject:  pkix.Name{CommonName: "client"},
+			KeyUsage: cert.KeyUsageDigitalSignature,
+		}, root, rootKey),
+	}
+}
+
+// echo runs a TLS 1.3 client against a server that echoes msg back.
+func echo(t *testing.T, client, server *Config, msg []byte) (ConnectionState, []byte, error, ConnectionState, error) {
+	t.Helper()
+	return handshaketest.Echo[ConnectionState](t,
+		func(nc net.Conn) *Conn { return Client(nc, client) },
+		func(nc net.Conn) *Conn { return Server(nc, server) },
+		msg)
+}
+
+func TestHandshake(t *testing.T) {
+	pki := newPKI(t)
+	server := &Config{Certificate: pki.server}
+	msg := bytes.Repeat([]byte("probe "), 5000) // spans several records
+	for _, suite := range CipherSuites() {
+		for _, retry := range []bool{false, true} {
+			client := &Config{Config: handshake.Config{RootCAs: []*cert.Certificate{pki.root}, CipherSuites: []uint16{suite}}, ServerName: "www.example.com", omitKeyShare: retry}
+			cs, reply, err, ss, serr := echo(t, client, server, msg)
+			if err != nil || serr != nil {
+				t.Fatalf("%s: client %v, server %v", CipherSuiteName(suite), err, serr)
+			}
+			if !bytes.Equal(reply, msg) {
+				t.Errorf("%s: echo differs", CipherSuiteName(suite))
+			}
+			if cs.CipherSuite != suite || ss.CipherSuite != suite || cs.Version != VersionTLS13 {
+				t.Errorf("%s: negotiated %#04x/%#04x", CipherSuiteName(suite), cs.CipherSuite, ss.CipherSuite)
+			}
+			if cs.HelloRetry != retry || ss.HelloRetry != retry || ss.ServerName != "www.example.com" {
+				t.Errorf("%s: client %+v, server %+v", CipherSuiteName(suite), cs, ss)
+			}
+			if len(cs.PeerCertificates) != 1 || cs.PeerCertificates[0].Subject.CommonName != "server" {
+				t.Errorf("%s: peer certificates %v", CipherSuiteName(suite), cs.PeerCertificates)
+			}
+		}
+	}
+}
+
+func TestClientAuth(t *testing.T) {
+	pki := newPKI(t)
+	roots := []*cert.Certificate{pki.root}
+	server := &Config{Config: handshake.Config{RootCAs: roots, ClientAuth: true}, Certificate: pki.server}
+	client := &Config{Config: handshake.Config{RootCAs: roots}, Certificate: pki.client}
+	_, _, err, ss, serr := echo(t, client, server, []byte("hello"))
+	if err != nil || serr != nil {
+		t.Fatalf("client %v, server %v", err, serr)
+	}
+	if len(ss.PeerCertificates) != 1 || ss.PeerCertificates[0].Subject.CommonName != "client" {
+		t.Errorf("client certificates %v", ss.PeerCertificates)
+	}
+
+	// A client without a certificate is refused.
+	_, _, err, _, serr = echo(t, &Config{Config: handshake.Config{RootCAs: roots}}, server, []byte("hello"))
+	var ae AlertError
+	if !errors.As(err, &ae) || ae.Alert != handshake.AlertCertificateRequired || serr == nil {
+		t.Errorf("anonymous client: client %v, server %v", err, serr)
+	}
+}
+
+func TestHandshakeFailures(t *testing.T) {
+	pki := newPKI(t)
+	roots := []*cert.Certificate{pki.root}
+	server := &Config{Certificate: pki.server}
+
+	other := newPKI(t)
+	_, _, err, _, serr := echo(t, &Config{Config: handshake.Config{RootCAs: []*cert.Certificate{other.root}}}, server, []byte("x"))
+	if err == nil || !strings.Contains(err.Error(), "no chain to a trusted root") {
+		t.Errorf("untrusted server: %v", err)
+	}
+	var ae AlertError
+	if !errors.As(serr, &ae) || ae.Alert != handshake.AlertUnknownCA {
+		t.Errorf("server saw %v, want an unknown CA alert", serr)
+	}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{InsecureSkipVerify: true}, ServerName: "other.test"}, server, []byte("x")); err != nil {
+		t.Errorf("InsecureSkipVerify: %v", err)
+	}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{RootCAs: roots}, ServerName: "other.test"}, server, []byte("x")); err == nil || !strings.Contains(err.Error(), "not valid for") {
+		t.Errorf("wrong server name: %v", err)
+	}
+
+	// A key that does not match the certificate fails CertificateVerify,
+	// as does a different signer identity.
+	bad := &Config{Certificate: &Certificate{Chain: pki.server.Chain, PrivateKey: pki.client.PrivateKey}}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{RootCAs: roots}}, bad, []byte("x")); err == nil || !strings.Contains(err.Error(), "CertificateVerify") {
+		t.Errorf("mismatched key: %v", err)
+	}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{RootCAs: roots}, SignatureUID: []byte("1234567812345678")}, server, []byte("x")); err == nil {
+		t.Error("handshake with different signature identities succeeded")
+	}
+
+	ccmOnly := &Config{Config: handshake.Config{CipherSuites: []uint16{TLS_SM4_CCM_SM3}}, Certificate: pki.server}
+	if _, _, err, _, _ = echo(t, &Config{Config: handshake.Config{RootCAs: roots, CipherSuites: []uint16{TLS_SM4_GCM_SM3}}}, ccmOnly, []byte("x")); err == nil {
+		t.Error("handshake without a common suite succeeded")
+	}
+}
+
+func TestKeyUpdate(t *testing.T) {
+	pki := newPKI(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	done := make(chan error, 1)
+	var sc *Conn
+	go func() {
+		nc, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		sc = Server(nc, &Config{Certificate: pki.server})
+		defer sc.Close()
+		buf := make([]byte, 64)
+		for i := 0; i < 2; i++ {
+			n, err := sc.Read(buf)
+			if err == nil {
+				_, err = sc.Write(buf[:n])
+			}
+			if err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	nc, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cc := Client(nc, &Config{Config: handshake.Config{RootCAs: []*cert.Certificate{pki.root}}})
+	defer cc.Close()
+	buf := make([]byte, 64)
+	for i, msg := range []string{"before", "after"} {
+		if i == 1 {
+			// Ask the server to update its keys too.
+			if err := cc.updateKeys(true); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := cc.Write([]byte(msg)); err != nil {
+			t.Fatal(err)
+		}
+		n, err := cc.Read(buf)
+		if err != nil || string(buf[:n]) != msg {
+			t.Fatalf("%s: %q %v", msg, buf[:n], err)
+		}
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// Both directions restarted their sequence numbers: the client read
+	// one record under the server's new keys, the server one under the
+	// client's.
+	if cs, ss := cc.In.(*halfConn).seq, sc.In.(*halfConn).seq; cs != 1 || ss != 1 {
+		t.Errorf("sequence numbers %d, %d after KeyUpdate", cs, ss)
+	}
+}
+
+func TestRecordProtection(t *testing.T) {
+	for _, suite := range cipherSuites {
+		secret := make([]byte, 32)
+		out, _ := newHalfConn(suite, secret)
+		in, _ := newHalfConn(suite, secret)
+		for _, n := range []int{0, 1, 15, 16, 100} {
+			payload := bytes.Repeat([]byte{byte(n)}, n)
+			rec, _ := out.Seal(handshake.RecordTypeHandshake, payload)
+			if rec[0] != byte(handshake.RecordTypeApplicationData) {
+				t.Fatalf("%s: outer type %d", suite.name, rec[0])
+			}
+			hdr, fragment := rec[:handshake.RecordHeaderLen], rec[handshake.RecordHeaderLen:]
+			typ, got, err := in.Open(hdr, fragment)
+			if err != nil || typ != handshake.RecordTypeHandshake || !bytes.Equal(got, payload) {
+				t.Fatalf("%s: %d bytes: %v", suite.name, n, err)
+			}
+			rec[len(rec)-1] ^= 1
+			in.seq--
+			if _, _, err := in.Open(hdr, fragment); err != handshake.ErrBadRecord {
+				t.Errorf("%s: tampered record opened", suite.name)
+			}
+		}
+	}
+}
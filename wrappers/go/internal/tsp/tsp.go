@@ -0,0 +1,632 @@
+// Package tsp builds time-stamp requests and builds and verifies
+// time-stamp responses (RFC 3161) with SM3 message imprints and
+// SM2-with-SM3 signed tokens. A token is CMS SignedData (RFC 5652) over a
+// TSTInfo; its signer is named by issuer and serial number and bound to
+// the TSA certificate by an ESS signingCertificateV2 attribute (RFC 5816).
+package tsp
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pkcs7"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+var (
+	oidTSTInfo = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 1, 4}
+
+	oidAttributeSigningCertificate   = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 12}
+	oidAttributeSigningCertificateV2 = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 16, 2, 47}
+
+	oidSM2Sign = asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 301, 1}
+)
+
+// HashAlgorithm is the hash of a message imprint.
+type HashAlgorithm int
+
+const (
+	SM3 HashAlgorithm = iota
+	SHA256
+	SHA1
+)
+
+var hashes = []struct {
+	alg HashAlgorithm
+	oid asn1.ObjectIdentifier
+	h   func() hash.Hash
+}{
+	{SM3, asn1.ObjectIdentifier{1, 2, 156, 10197, 1, 401}, sm3.New},
+	{SHA256, asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}, sha256.New},
+	{SHA1, asn1.ObjectIdentifier{1, 3, 14, 3, 2, 26}, sha1.New},
+}
+
+// Sum returns the hash of data under h.
+func (h HashAlgorithm) Sum(data []byte) []byte {
+	for _, e := range hashes {
+		if e.alg == h {
+			d := e.h()
+			d.Write(data)
+			return d.Sum(nil)
+		}
+	}
+	return nil
+}
+
+func hashAlgorithm(oid asn1.ObjectIdentifier) (HashAlgorithm, error) {
+	for _, h := range hashes {
+		if h.oid.Equal(oid) {
+			return h.alg, nil
+		}
+	}
+	return 0, fmt.Errorf("tsp: unsupported imprint hash %v", oid)
+}
+
+func hashOID(alg HashAlgorithm) asn1.ObjectIdentifier {
+	for _, h := range hashes {
+		if h.alg == alg {
+			return h.oid
+		}
+	}
+	return nil
+}
+
+// Status is the PKIStatus of a time-stamp response.
+type Status int
+
+const (
+	Granted                Status = 0
+	GrantedWithMods        Status = 1
+	Rejection              Status = 2
+	Waiting                Status = 3
+	RevocationWarning      Status = 4
+	RevocationNotification Status = 5
+)
+
+// FailureInfo is a bit of the PKIFailureInfo of a rejected request.
+type FailureInfo int
+
+const (
+	BadAlg              FailureInfo = 0
+	BadRequest          FailureInfo = 2
+	BadDataFormat       FailureInfo = 5
+	TimeNotAvailable    FailureInfo = 14
+	UnacceptedPolicy    FailureInfo = 15
+	UnacceptedExtension FailureInfo = 16
+	AddInfoNotAvailable FailureInfo = 17
+	SystemFailure       FailureInfo = 25
+)
+
+type messageImprint struct {
+	HashAlgorithm pkix.AlgorithmIdentifier
+	HashedMessage []byte
+}
+
+type timeStampReq struct {
+	Version        int
+	MessageImprint messageImprint
+	ReqPolicy      asn1.ObjectIdentifier `asn1:"optional"`
+	Nonce          *big.Int              `asn1:"optional"`
+	CertReq        bool                  `asn1:"optional"`
+	Extensions     []pkix.Extension      `asn1:"tag:0,optional"`
+}
+
+type timeStampResp struct {
+	Status         pkiStatusInfo
+	TimeStampToken asn1.RawValue `asn1:"optional"`
+}
+
+type pkiStatusInfo struct {
+	Status       int
+	StatusString []asn1.RawValue `asn1:"optional"`
+	FailInfo     asn1.BitString  `asn1:"optional"`
+}
+
+type tstInfo struct {
+	Version        int
+	Policy         asn1.ObjectIdentifier
+	MessageImprint messageImprint
+	SerialNumber   *big.Int
+	// GenTime is kept raw: encoding/asn1 rejects the fractional seconds
+	// that TSAs writing an accuracy below a second put in it.
+	GenTime    asn1.RawValue
+	Accuracy   accuracy         `asn1:"optional"`
+	Ordering   bool             `asn1:"optional"`
+	Nonce      *big.Int         `asn1:"optional"`
+	TSA        asn1.RawValue    `asn1:"explicit,tag:0,optional"`
+	Extensions []pkix.Extension `asn1:"tag:1,optional"`
+}
+
+type accuracy struct {
+	Seconds int `asn1:"optional"`
+	Millis  int `asn1:"tag:0,optional"`
+	Micros  int `asn1:"tag:1,optional"`
+}
+
+// signingCertificate is the ESS SigningCertificate of RFC 2634 (SHA-1
+// hashes) or SigningCertificateV2 of RFC 5816; only the first ESSCertID,
+// which names the signer, is used.
+type signingCertificate struct {
+	Certs    []asn1.RawValue
+	Policies asn1.RawValue `asn1:"optional"`
+}
+
+type essCertIDv2 struct {
+	HashAlgorithm pkix.AlgorithmIdentifier `asn1:"optional"`
+	CertHash      []byte
+	IssuerSerial  issuerSerial `asn1:"optional"`
+}
+
+type essCertID struct {
+	CertHash     []byte
+	IssuerSerial issuerSerial `asn1:"optional"`
+}
+
+type issuerSerial struct {
+	Issuer       []asn1.RawValue
+	SerialNumber *big.Int
+}
+
+// Request is a time-stamp request.
+type Request struct {
+	HashAlgorithm HashAlgorithm
+	HashedMessage []byte
+	Policy        asn1.ObjectIdentifier // nil when absent
+	Nonce         *big.Int              // nil when absent
+	// CertReq asks the TSA to include its certificate in the token.
+	CertReq bool
+}
+
+// CreateRequest returns the DER TimeStampReq for req.
+func CreateRequest(req *Request) ([]byte, error) {
+	oid := hashOID(req.HashAlgorithm)
+	if oid == nil {
+		return nil, fmt.Errorf("tsp: unsupported hash algorithm %d", req.HashAlgorithm)
+	}
+	if len(req.HashedMessage) != len(req.HashAlgorithm.Sum(nil)) {
+		return nil, errors.New("tsp: hashed message length does not match the hash algorithm")
+	}
+	if req.Nonce != nil && req.Nonce.Sign() < 0 {
+		return nil, errors.New("tsp: nonce must not be negative")
+	}
+	return asn1.Marshal(timeStampReq{
+		Version: 1,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oid, Parameters: asn1.NullRawValue},
+			HashedMessage: req.HashedMessage,
+		},
+		ReqPolicy: req.Policy,
+		Nonce:     req.Nonce,
+		CertReq:   req.CertReq,
+	})
+}
+
+// ParseRequest parses a DER TimeStampReq. Requests with extensions are
+// refused, since none are understood.
+func ParseRequest(der []byte) (*Request, error) {
+	var req timeStampReq
+	rest, err := asn1.Unmarshal(der, &req)
+	if err != nil {
+		return nil, fmt.Errorf("tsp: malformed request: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("tsp: trailing data after request")
+	}
+	if req.Version != 1 {
+		return nil, fmt.Errorf("tsp: unsupported request version %d", req.Version)
+	}
+	if len(req.Extensions) != 0 {
+		return nil, errors.New("tsp: request extensions are not supported")
+	}
+	h, err := hashAlgorithm(req.MessageImprint.HashAlgorithm.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.MessageImprint.HashedMessage) != len(h.Sum(nil)) {
+		return nil, errors.New("tsp: hashed message length does not match the hash algorithm")
+	}
+	return &Request{
+		HashAlgorithm: h,
+		HashedMessage: req.MessageImprint.HashedMessage,
+		Policy:        req.ReqPolicy,
+		Nonce:         req.Nonce,
+		CertReq:       req.CertReq,
+	}, nil
+}
+
+// TokenTemplate holds the fields of a token that the TSA chooses.
+type TokenTemplate struct {
+	Policy asn1.ObjectIdentifier
+	// SerialNumber is random when nil.
+	SerialNumber *big.Int
+	GenTime      time.Time
+	Accuracy     time.Duration // omitted when zero
+
+	// SignerUID is the TSA identity in the SM2 signature; nil selects
+	// sm2.DefaultUID.
+	SignerUID []byte
+}
+
+// CreateResponse grants req with a token signed by priv, the key of the
+// TSA certificate tsa, which must be certified for timeStamping only. The
+// token echoes the request's nonce and includes tsa when the request
+// asks for it. A request for another policy than tmpl.Policy is rejected
+// with UnacceptedPolicy.
+func CreateResponse(rand io.Reader, req *Request, tmpl *TokenTemplate, tsa *cert.Certificate, priv *sm2.PrivateKey) ([]byte, error) {
+	if tsa.PublicKey == nil || tsa.PublicKey.X.Cmp(priv.X) != 0 || tsa.PublicKey.Y.Cmp(priv.Y) != 0 {
+		return nil, errors.New("tsp: private key does not match the TSA certificate")
+	}
+	if !timeStampingOnly(tsa) {
+		return nil, errors.New("tsp: TSA certificate is not certified for timeStamping only")
+	}
+	if len(tmpl.Policy) == 0 {
+		return nil, errors.New("tsp: token needs a policy")
+	}
+	if req.Policy != nil && !req.Policy.Equal(tmpl.Policy) {
+		return CreateErrorResponse(UnacceptedPolicy, "requested policy is not supported")
+	}
+	serial := tmpl.SerialNumber
+	if serial == nil {
+		b := make([]byte, 16)
+		if _, err := io.ReadFull(rand, b); err != nil {
+			return nil, err
+		}
+		b[0] &= 0x7f
+		b[0] |= 0x40
+		serial = new(big.Int).SetBytes(b)
+	}
+	if serial.Sign() <= 0 {
+		return nil, errors.New("tsp: serial number must be positive")
+	}
+	if tmpl.Accuracy < 0 {
+		return nil, errors.New("tsp: accuracy must not be negative")
+	}
+
+	info := tstInfo{
+		Version: 1,
+		Policy:  tmpl.Policy,
+		MessageImprint: messageImprint{
+			HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashOID(req.HashAlgorithm), Parameters: asn1.NullRawValue},
+			HashedMessage: req.HashedMessage,
+		},
+		SerialNumber: serial,
+		GenTime:      asn1.RawValue{Tag: asn1.TagGeneralizedTime, Bytes: []byte(tmpl.GenTime.UTC().Format("20060102150405Z"))},
+		Accuracy: accuracy{
+			Seconds: int(tmpl.Accuracy / time.Second),
+			Millis:  int(tmpl.Accuracy % time.Second / time.Millisecond),
+			Micros:  int(tmpl.Accuracy % time.Millisecond / time.Microsecond),
+		},
+		Nonce: req.Nonce,
+	}
+	content, err := asn1.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	token, err := sign(rand, content, tsa, priv, tmpl.SignerUID, req.CertReq)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(timeStampResp{Status: pkiStatusInfo{Status: int(Granted)}, TimeStampToken: asn1.RawValue{FullBytes: token}})
+}
+
+// CreateErrorResponse returns a response rejecting a request for reason
+// info, with the optional explanation text.
+func CreateErrorResponse(info FailureInfo, text string) ([]byte, error) {
+	status := pkiStatusInfo{Status: int(Rejection)}
+	if text != "" {
+		status.StatusString = []asn1.RawValue{{Tag: asn1.TagUTF8String, Bytes: []byte(text)}}
+	}
+	n := int(info)
+	if n < 0 || n > 25 {
+		return nil, fmt.Errorf("tsp: invalid failure info %d", info)
+	}
+	status.FailInfo = asn1.BitString{Bytes: make([]byte, n/8+1), BitLength: n + 1}
+	status.FailInfo.Bytes[n/8] |= 0x80 >> uint(n%8)
+	return asn1.Marshal(timeStampResp{Status: status})
+}
+
+// sign wraps the DER TSTInfo content in a ContentInfo holding CMS
+// SignedData signed by priv for tsa, with the contentType, messageDigest
+// and signingCertificateV2 signed attributes.
+func sign(rand io.Reader, content []byte, tsa *cert.Certificate, priv *sm2.PrivateKey, uid []byte, includeCert bool) ([]byte, error) {
+	sc, err := signingCertificateV2(tsa)
+	if err != nil {
+		return nil, err
+	}
+	if uid == nil {
+		uid = []byte(sm2.DefaultUID)
+	}
+	return pkcs7.Sign(rand, content, pkcs7.Signer{Key: priv, UID: uid, Certificate: tsa.Raw}, pkcs7.SignOptions{
+		Profile:       pkcs7.ProfileCMS,
+		ContentType:   oidTSTInfo,
+		Attributes:    []pkcs7.Attribute{sc},
+		NoCertificate: !includeCert,
+	})
+}
+
+// signingCertificateV2 returns the ESS signingCertificateV2 attribute
+// naming tsa.
+func signingCertificateV2(tsa *cert.Certificate) (pkcs7.Attribute, error) {
+	dirName, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 4, IsCompound: true, Bytes: tsa.RawIssuer})
+	if err != nil {
+		return pkcs7.Attribute{}, err
+	}
+	id, err := asn1.Marshal(essCertIDv2{
+		HashAlgorithm: pkix.AlgorithmIdentifier{Algorithm: hashOID(SM3), Parameters: asn1.NullRawValue},
+		CertHash:      SM3.Sum(tsa.Raw),
+		IssuerSerial:  issuerSerial{Issuer: []asn1.RawValue{{FullBytes: dirName}}, SerialNumber: tsa.SerialNumber},
+	})
+	if err != nil {
+		return pkcs7.Attribute{}, err
+	}
+	sc, err := asn1.Marshal(signingCertificate{Certs: []asn1.RawValue{{FullBytes: id}}})
+	if err != nil {
+		return pkcs7.Attribute{}, err
+	}
+	return pkcs7.Attribute{Type: oidAttributeSigningCertificateV2, Values: []asn1.RawValue{{FullBytes: sc}}}, nil
+}
+
+// timeStampingOnly reports whether c is certified for timeStamping as its
+// only extended key usage.
+func timeStampingOnly(c *cert.Certificate) bool {
+	return len(c.ExtKeyUsage) == 1 && c.ExtKeyUsage[0].Equal(cert.OIDExtKeyUsageTimeStamping)
+}
+
+// Token is a parsed time-stamp token.
+type Token struct {
+	Raw           []byte
+	Policy        asn1.ObjectIdentifier
+	HashAlgorithm HashAlgorithm
+	HashedMessage []byte
+	SerialNumber  *big.Int
+	GenTime       time.Time
+	Accuracy      time.Duration // zero when absent
+	Nonce         *big.Int      // nil when absent
+
+	// Certificates are those included in the token, typically the TSA's.
+	Certificates []*cert.Certificate
+
+	content []byte
+	signer  pkcs7.SignerInfo
+}
+
+// ResponseError is returned by ParseResponse for responses that do not
+// grant the request.
+type ResponseError struct {
+	Status      Status
+	FailureInfo []FailureInfo
+	Text        string
+}
+
+func (e *ResponseError) Error() string {
+	msg := fmt.Sprintf("tsp: TSA reports status %d", e.Status)
+	if e.Text != "" {
+		msg += ": " + e.Text
+	}
+	return msg
+}
+
+// ParseResponse parses a DER TimeStampResp and returns its token.
+func ParseResponse(der []byte) (*Token, error) {
+	var resp timeStampResp
+	rest, err := asn1.Unmarshal(der, &resp)
+	if err != nil {
+		return nil, fmt.Errorf("tsp: malformed response: %v", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("tsp: trailing data after response")
+	}
+	if s := Status(resp.Status.Status); s != Granted && s != GrantedWithMods {
+		e := &ResponseError{Status: s}
+		for i := 0; i < resp.Status.FailInfo.BitLength; i++ {
+			if resp.Status.FailInfo.At(i) == 1 {
+				e.FailureInfo = append(e.FailureInfo, FailureInfo(i))
+			}
+		}
+		for _, text := range resp.Status.StatusString {
+			if e.Text != "" {
+				e.Text += "; "
+			}
+			e.Text += string(text.Bytes)
+		}
+		return nil, e
+	}
+	if len(resp.TimeStampToken.FullBytes) == 0 {
+		return nil, errors.New("tsp: granted response has no token")
+	}
+	return ParseToken(resp.TimeStampToken.FullBytes)
+}
+
+// ParseToken parses a DER time-stamp token. It does not check the
+// signature.
+func ParseToken(der []byte) (*Token, error) {
+	sd, err := pkcs7.ParseSignedData(der)
+	if err != nil {
+		return nil, fmt.Errorf("tsp: malformed token: %v", err)
+	}
+	if !sd.ContentType.Equal(oidTSTInfo) {
+		return nil, fmt.Errorf("tsp: signed content type %v, want TSTInfo", sd.ContentType)
+	}
+	if sd.Content == nil {
+		return nil, errors.New("tsp: token has no TSTInfo content")
+	}
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("tsp: token has %d signers, want 1", len(sd.SignerInfos))
+	}
+	t := &Token{Raw: der, content: sd.Content, signer: sd.SignerInfos[0]}
+	var info tstInfo
+	if _, err := asn1.Unmarshal(t.content, &info); err != nil {
+		return nil, fmt.Errorf("tsp: malformed TSTInfo: %v", err)
+	}
+	if info.Version != 1 {
+		return nil, fmt.Errorf("tsp: unsupported TSTInfo version %d", info.Version)
+	}
+	if t.HashAlgorithm, err = hashAlgorithm(info.MessageImprint.HashAlgorithm.Algorithm); err != nil {
+		return nil, err
+	}
+	if info.GenTime.Tag != asn1.TagGeneralizedTime {
+		return nil, errors.New("tsp: malformed genTime")
+	}
+	// time.Parse accepts the fractional seconds the layout leaves out.
+	if t.GenTime, err = time.Parse("20060102150405Z0700", string(info.GenTime.Bytes)); err != nil {
+		return nil, fmt.Errorf("tsp: malformed genTime: %v", err)
+	}
+	t.Policy = info.Policy
+	t.HashedMessage = info.MessageImprint.HashedMessage
+	t.SerialNumber = info.SerialNumber
+	t.Nonce = info.Nonce
+	t.Accuracy = time.Duration(info.Accuracy.Seconds)*time.Second +
+		time.Duration(info.Accuracy.Millis)*time.Millisecond +
+		time.Duration(info.Accuracy.Micros)*time.Microsecond
+
+	for _, raw := range sd.Certificates {
+		c, err := cert.Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		t.Certificates = append(t.Certificates, c)
+	}
+	return t, nil
+}
+
+// Errors of Token.Verify.
+var (
+	ErrWrongSigner        = errors.New("tsp: token is not signed by the TSA certificate")
+	ErrExtKeyUsage        = errors.New("tsp: TSA certificate is not certified for timeStamping")
+	ErrMessageDigest      = errors.New("tsp: message digest attribute does not match the TSTInfo")
+	ErrSigningCertificate = errors.New("tsp: signing certificate attribute does not match the TSA certificate")
+)
+
+// Verify checks that the token is signed under uid by the key of tsa:
+// the SignerInfo and the ESS signing certificate attribute must name tsa,
+// which must be certified for timeStamping. A bad signature is reported as
+// cert.ErrSignature.
+func (t *Token) Verify(tsa *cert.Certificate, uid []byte) error {
+	si := &t.signer
+	if !si.Names(tsa.RawIssuer, tsa.SerialNumber, tsa.SubjectKeyID) {
+		return ErrWrongSigner
+	}
+	if tsa.PublicKey == nil {
+		return ErrWrongSigner
+	}
+	if !si.DigestAlgorithm.Equal(hashOID(SM3)) {
+		return fmt.Errorf("tsp: unsupported digest algorithm %v", si.DigestAlgorithm)
+	}
+	if alg := si.SignatureAlgorithm; !alg.Equal(cert.OIDSignatureSM2WithSM3) && !alg.Equal(oidSM2Sign) {
+		return fmt.Errorf("tsp: unsupported signature algorithm %v", alg)
+	}
+	if si.SignedAttributes == nil {
+		return errors.New("tsp: token has no signed attributes")
+	}
+	if err := checkAttributes(si.SignedAttributes, t.content, tsa); err != nil {
+		return err
+	}
+	r, s, err := sm2.UnmarshalSignature(si.Signature)
+	if err != nil {
+		return fmt.Errorf("tsp: malformed signature: %v", err)
+	}
+	if uid == nil {
+		uid = []byte(sm2.DefaultUID)
+	}
+	if !sm2.Verify(tsa.PublicKey, si.SignedAttributes, uid, r, s) {
+		return cert.ErrSignature
+	}
+	if !timeStampingOnly(tsa) {
+		return ErrExtKeyUsage
+	}
+	return nil
+}
+
+// checkAttributes checks the contentType, messageDigest and signing
+// certificate attributes in the signed attribute SET.
+func checkAttributes(set, content []byte, tsa *cert.Certificate) error {
+	attrs, err := pkcs7.ParseAttributes(set)
+	if err != nil {
+		return err
+	}
+	var contentType, digest, signingCert bool
+	for _, a := range attrs {
+		if len(a.Values) != 1 {
+			continue
+		}
+		v := a.Values[0].FullBytes
+		switch {
+		case a.Type.Equal(pkcs7.OIDAttributeContentType):
+			var ct asn1.ObjectIdentifier
+			if _, err := asn1.Unmarshal(v, &ct); err != nil || !ct.Equal(oidTSTInfo) {
+				return errors.New("tsp: content type attribute is not TSTInfo")
+			}
+			contentType = true
+		case a.Type.Equal(pkcs7.OIDAttributeMessageDigest):
+			var md []byte
+			if _, err := asn1.Unmarshal(v, &md); err != nil {
+				return fmt.Errorf("tsp: malformed messageDigest: %v", err)
+			}
+			if !bytes.Equal(md, SM3.Sum(content)) {
+				return ErrMessageDigest
+			}
+			digest = true
+		case a.Type.Equal(oidAttributeSigningCertificate), a.Type.Equal(oidAttributeSigningCertificateV2):
+			if err := checkSigningCertificate(a.Type, v, tsa); err != nil {
+				return err
+			}
+			signingCert = true
+		}
+	}
+	switch {
+	case !contentType:
+		return errors.New("tsp: signed attributes lack contentType")
+	case !digest:
+		return errors.New("tsp: signed attributes lack messageDigest")
+	case !signingCert:
+		return errors.New("tsp: signed attributes lack the signing certificate")
+	}
+	return nil
+}
+
+// checkSigningCertificate compares the first ESSCertID of an ESS signing
+// certificate attribute value with tsa.
+func checkSigningCertificate(typ asn1.ObjectIdentifier, der []byte, tsa *cert.Certificate) error {
+	var sc signingCertificate
+	if _, err := asn1.Unmarshal(der, &sc); err != nil || len(sc.Certs) == 0 {
+		return errors.New("tsp: malformed signing certificate attribute")
+	}
+	var hash, want []byte
+	var serial *big.Int
+	if typ.Equal(oidAttributeSigningCertificate) {
+		var id essCertID
+		if _, err := asn1.Unmarshal(sc.Certs[0].FullBytes, &id); err != nil {
+			return errors.New("tsp: malformed ESSCertID")
+		}
+		sum := sha1.Sum(tsa.Raw)
+		hash, want, serial = id.CertHash, sum[:], id.IssuerSerial.SerialNumber
+	} else {
+		var id essCertIDv2
+		if _, err := asn1.Unmarshal(sc.Certs[0].FullBytes, &id); err != nil {
+			return errors.New("tsp: malformed ESSCertIDv2")
+		}
+		h := SHA256 // the DEFAULT of ESSCertIDv2
+		if id.HashAlgorithm.Algorithm != nil {
+			var err error
+			if h, err = hashAlgorithm(id.HashAlgorithm.Algorithm); err != nil {
+				return err
+			}
+		}
+		hash, want, serial = id.CertHash, h.Sum(tsa.Raw), id.IssuerSerial.SerialNumber
+	}
+	if !bytes.Equal(hash, want) || (serial != nil && serial.Cmp(tsa.SerialNumber) != 0) {
+		return ErrSigningCertificate
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package tsp
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// The request "openssl ts -query -data data.txt -sm3 -cert" wrote for the
+// message "hello tsa", with nonce 0xec32eb9273d8d8e3.
+const opensslRequestHex = "30430201013030300c06082a811ccf550183110500042013705c55ef9def8d60e3d802d4ccba3d9748d7b7a60cc0b69e4c2a3c435da16c020900ec32eb9273d8d8e30101ff"
+
+// The rejection OpenSSL 3.0 answers that request with when asked to sign
+// with an SM2 key, which its PKCS #7 code does not support.
+const opensslRejectionHex = "302b302902010230240c224572726f7220647572696e67207369676e61747572652067656e65726174696f6e2e"
+
+func TestRequestOpenSSL(t *testing.T) {
+	want, _ := hex.DecodeString(opensslRequestHex)
+	nonce, _ := new(big.Int).SetString("ec32eb9273d8d8e3", 16)
+	der, err := CreateRequest(&Request{HashAlgorithm: SM3, HashedMessage: SM3.Sum([]byte("hello tsa")), Nonce: nonce, CertReq: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(der, want) {
+		t.Errorf("CreateRequest = %x", der)
+	}
+	req, err := ParseRequest(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.HashAlgorithm != SM3 || !bytes.Equal(req.HashedMessage, SM3.Sum([]byte("hello tsa"))) || req.Nonce.Cmp(nonce) != 0 || !req.CertReq || req.Policy != nil {
+		t.Errorf("ParseRequest = %+v", req)
+	}
+}
+
+func TestRejectionOpenSSL(t *testing.T) {
+	der, _ := hex.DecodeString(opensslRejectionHex)
+	var re *ResponseError
+	if _, err := ParseResponse(der); !errors.As(err, &re) || re.Status != Rejection || re.Text != "Error during signature generation." {
+		t.Errorf("ParseResponse = %v", err)
+	}
+}
+
+func TestCreateResponse(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	newCert := func(tmpl *cert.Template, parent *cert.Certificate, parentKey *sm2.PrivateKey) (*cert.Certificate, *sm2.PrivateKey) {
+		key, _ := sm2.GenerateKey(rand.Reader)
+		if parentKey == nil {
+			parentKey = key
+		}
+		tmpl.NotBefore, tmpl.NotAfter = now.Add(-time.Hour), now.Add(time.Hour)
+		der, err := cert.Create(rand.Reader, tmpl, &key.PublicKey, parent, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c, _ := cert.Parse(der)
+		return c, key
+	}
+	ca, caKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "tsa ca"}, IsCA: true, MaxPathLen: -1}, nil, nil)
+	tsa, tsaKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "tsa"}, ExtKeyUsage: []asn1.ObjectIdentifier{cert.OIDExtKeyUsageTimeStamping}}, ca, caKey)
+	other, _ := newCert(&cert.Template{Subject: pkix.Name{CommonName: "other tsa"}, ExtKeyUsage: []asn1.ObjectIdentifier{cert.OIDExtKeyUsageTimeStamping}}, ca, caKey)
+	plain, plainKey := newCert(&cert.Template{Subject: pkix.Name{CommonName: "plain"}}, ca, caKey)
+
+	policy := asn1.ObjectIdentifier{1, 2, 3, 4, 1}
+	message := []byte("document")
+	req := &Request{HashAlgorithm: SM3, HashedMessage: SM3.Sum(message), Nonce: big.NewInt(7), CertReq: true}
+	tmpl := &TokenTemplate{Policy: policy, SerialNumber: big.NewInt(99), GenTime: now, Accuracy: 1500 * time.Millisecond}
+	der, err := CreateResponse(rand.Reader, req, tmpl, tsa, tsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := ParseResponse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !tok.Policy.Equal(policy) || tok.HashAlgorithm != SM3 || !bytes.Equal(tok.HashedMessage, req.HashedMessage) ||
+		tok.SerialNumber.Int64() != 99 || !tok.GenTime.Equal(now) || tok.Accuracy != 1500*time.Millisecond ||
+		tok.Nonce.Int64() != 7 || len(tok.Certificates) != 1 {
+		t.Errorf("ParseResponse = %+v", tok)
+	}
+	if err := tok.Verify(tsa, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := tok.Verify(tsa, []byte{}); err != cert.ErrSignature {
+		t.Errorf("other identity: %v", err)
+	}
+	if err := tok.Verify(other, nil); err != ErrWrongSigner {
+		t.Errorf("other TSA: %v", err)
+	}
+
+	// A token whose TSTInfo was swapped for another no longer matches its
+	// messageDigest attribute.
+	der2, _ := CreateResponse(rand.Reader, &Request{HashAlgorithm: SHA256, HashedMessage: SHA256.Sum(message)}, tmpl, tsa, tsaKey)
+	tok2, err := ParseResponse(der2)
+	if err != nil || len(tok2.Certificates) != 0 || tok2.Nonce != nil || tok2.HashAlgorithm != SHA256 {
+		t.Fatalf("second token: %+v, %v", tok2, err)
+	}
+	tok2.content = tok.content
+	if err := tok2.Verify(tsa, nil); err != ErrMessageDigest {
+		t.Errorf("swapped TSTInfo: %v", err)
+	}
+
+	// A certificate with the same issuer and serial number but another
+	// body fails the ESS check.
+	twin, _ := newCert(&cert.Template{Subject: pkix.Name{CommonName: "twin"}, SerialNumber: tsa.SerialNumber, ExtKeyUsage: []asn1.ObjectIdentifier{cert.OIDExtKeyUsageTimeStamping}}, ca, caKey)
+	if err := tok.Verify(twin, nil); err != ErrSigningCertificate {
+		t.Errorf("twin certificate: %v", err)
+	}
+
+	if _, err := CreateResponse(rand.Reader, req, tmpl, plain, plainKey); err == nil {
+		t.Error("signed with a certificate lacking timeStamping")
+	}
+	if _, err := CreateResponse(rand.Reader, req, tmpl, other, tsaKey); err == nil {
+		t.Error("signed with a key that does not match the certificate")
+	}
+
+	der, err = CreateResponse(rand.Reader, &Request{HashAlgorithm: SM3, HashedMessage: req.HashedMessage, Policy: asn1.ObjectIdentifier{1, 2, 3}}, tmpl, tsa, tsaKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var re *ResponseError
+	if _, err := ParseResponse(der); !errors.As(err, &re) || re.Status != Rejection || len(re.FailureInfo) != 1 || re.FailureInfo[0] != UnacceptedPolicy {
+		t.Errorf("other policy: %v", err)
+	}
+}
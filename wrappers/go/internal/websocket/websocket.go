@@ -0,0 +1,270 @@
+// Package websocket implements the server side of the WebSocket protocol
+// (RFC 6455) on net/http: the opening handshake, fragmented and control
+// frames, and the closing handshake. Extensions and subprotocols are not
+// negotiated. Cross-origin handshakes are refused unless allowed.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Opcodes of data messages.
+const (
+	OpText   = 1
+	OpBinary = 2
+)
+
+const (
+	opContinuation = 0
+	opClose        = 8
+	opPing         = 9
+	opPong         = 10
+)
+
+// Close status codes.
+const (
+	CloseNormal          = 1000
+	CloseProtocolError   = 1002
+	CloseInvalidData     = 1007
+	CloseMessageTooLarge = 1009
+)
+
+// MaxMessageSize bounds a reassembled message.
+const MaxMessageSize = 16 << 20
+
+// acceptGUID is the key suffix of RFC 6455 section 1.3.
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Conn is a server-side WebSocket connection. ReadMessage must be called
+// from one goroutine; writes may come from any.
+type Conn struct {
+	nc net.Conn
+	br *bufio.Reader
+	mu sync.Mutex // serializes frame writes
+}
+
+// AcceptKey computes Sec-WebSocket-Accept for a Sec-WebSocket-Key.
+func AcceptKey(key string) string {
+	h := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SameOrigin reports whether r may open a connection: it has no Origin
+// header, so it does not come from a browser, or its Origin is one of
+// allowed or names the host r was sent to. Browsers let any web page open
+// a WebSocket to any server and only report the page in Origin, so a
+// server must check it (RFC 6455 section 10.2).
+func SameOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	for _, o := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// Upgrade completes the opening handshake of an HTTP/1.1 request whose
+// Origin passes SameOrigin with allowedOrigins. On failure it has already
+// answered the request with an HTTP error.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if r.Method != http.MethodGet || !headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") || key == "" {
+		http.Error(w, "WebSocket upgrade required", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "unsupported WebSocket version", http.StatusUpgradeRequired)
+		return nil, errors.New("websocket: unsupported version")
+	}
+	if !SameOrigin(r, allowedOrigins) {
+		http.Error(w, "Origin not allowed", http.StatusForbidden)
+		return nil, fmt.Errorf("websocket: origin %q not allowed", r.Header.Get("Origin"))
+	}
+	nc, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		http.Error(w, "WebSocket needs HTTP/1.1", http.StatusHTTPVersionNotSupported)
+		return nil, err
+	}
+	resp := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + AcceptKey(key) + "\r\n\r\n"
+	if _, err := nc.Write([]byte(resp)); err != nil {
+		nc.Close()
+		return nil, err
+	}
+	return &Conn{nc: nc, br: brw.Reader}, nil
+}
+
+// ProtocolError is a violation by the peer; the connection has been
+// closed with its Code.
+type ProtocolError struct {
+	Code   int
+	Reason string
+}
+
+func (e *ProtocolError) Error() string { return "websocket: " + e.Reason }
+
+// ReadMessage returns the next data message and its opcode. Pings are
+// answered and pongs ignored. When the peer closes the connection, the
+// close is echoed and io.EOF returned.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var op int
+	var msg []byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case opClose:
+			code := []byte{}
+			if len(payload) >= 2 {
+				code = payload[:2]
+			}
+			c.writeFrame(opClose, code)
+			c.nc.Close()
+			return 0, nil, io.EOF
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opContinuation:
+			if msg == nil {
+				return 0, nil, c.fail(CloseProtocolError, "continuation without a message")
+			}
+		case OpText, OpBinary:
+			if msg != nil {
+				return 0, nil, c.fail(CloseProtocolError, "new message inside a fragmented one")
+			}
+			op, msg = opcode, []byte{}
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+		if len(msg)+len(payload) > MaxMessageSize {
+			return 0, nil, c.fail(CloseMessageTooLarge, "message too large")
+		}
+		msg = append(msg, payload...)
+		if !fin {
+			continue
+		}
+		if op == OpText && !utf8.Valid(msg) {
+			return 0, nil, c.fail(CloseInvalidData, "text message is not UTF-8")
+		}
+		return op, msg, nil
+	}
+}
+
+// readFrame reads one frame and unmasks its payload.
+func (c *Conn) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = hdr[0]&0x80 != 0, int(hdr[0]&0x0f)
+	if hdr[0]&0x70 != 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "reserved bits set")
+	}
+	if hdr[1]&0x80 == 0 {
+		return false, 0, nil, c.fail(CloseProtocolError, "unmasked client frame")
+	}
+	n := uint64(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		if _, err := io.ReadFull(c.br, b[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(b[:])
+	}
+	if opcode >= opClose && (n > 125 || !fin) {
+		return false, 0, nil, c.fail(CloseProtocolError, "malformed control frame")
+	}
+	if n > MaxMessageSize {
+		return false, 0, nil, c.fail(CloseMessageTooLarge, "message too large")
+	}
+	var mask [4]byte
+	if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+		return false, 0, nil, err
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		return false, 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// fail closes the connection with code and returns the matching error.
+func (c *Conn) fail(code int, reason string) error {
+	c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, uint16(code)))
+	c.nc.Close()
+	return &ProtocolError{Code: code, Reason: reason}
+}
+
+// WriteMessage sends data as one unfragmented message.
+func (c *Conn) WriteMessage(opcode int, data []byte) error {
+	return c.writeFrame(opcode, data)
+}
+
+func (c *Conn) writeFrame(opcode int, payload []byte) error {
+	frame := []byte{0x80 | byte(opcode)}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, 126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, 127), uint64(n))
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.nc.Write(append(frame, payload...))
+	return err
+}
+
+// Close sends a normal close frame and closes the connection without
+// waiting for the peer's answer.
+func (c *Conn) Close() error {
+	c.writeFrame(opClose, binary.BigEndian.AppendUint16(nil, CloseNormal))
+	return c.nc.Close()
+}
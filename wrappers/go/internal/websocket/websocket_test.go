@@ -0,0 +1,193 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// RFC 6455 section 1.3.
+	if got := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("AcceptKey = %s", got)
+	}
+}
+
+func TestSameOrigin(t *testing.T) {
+	allowed := []string{"https://tests.example"}
+	for _, c := range []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://localhost:9000", true},
+		{"https://LOCALHOST:9000", true},
+		{"https://tests.example", true},
+		{"https://evil.example", false},
+		{"http://localhost:9001", false},
+		{"null", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:9000/ws", nil)
+		if c.origin != "" {
+			r.Header.Set("Origin", c.origin)
+		}
+		if got := SameOrigin(r, allowed); got != c.want {
+			t.Errorf("SameOrigin(%q) = %v", c.origin, got)
+		}
+	}
+}
+
+// client is a minimal WebSocket client for the tests.
+type client struct {
+	nc net.Conn
+	br *bufio.Reader
+}
+
+func dial(t *testing.T, url string) *client {
+	t.Helper()
+	nc, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	io.WriteString(nc, "GET /ws HTTP/1.1\r\nHost: x\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n"+
+		"Sec-WebSocket-Key: "+key+"\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	br := bufio.NewReader(nc)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		t.Fatalf("handshake: %s %v", resp.Status, resp.Header)
+	}
+	return &client{nc: nc, br: br}
+}
+
+// send writes a frame, masked unless unmasked is set.
+func (c *client) send(fin bool, opcode int, payload []byte, unmasked bool) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	mask := []byte{1, 2, 3, 4}
+	m := byte(0x80)
+	if unmasked {
+		m = 0
+	}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, m|byte(n))
+	case n <= 0xffff:
+		frame = binary.BigEndian.AppendUint16(append(frame, m|126), uint16(n))
+	default:
+		frame = binary.BigEndian.AppendUint64(append(frame, m|127), uint64(n))
+	}
+	if !unmasked {
+		frame = append(frame, mask...)
+		for i, b := range payload {
+			frame = append(frame, b^mask[i%4])
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+	c.nc.Write(frame)
+}
+
+func (c *client) recv(t *testing.T) (int, []byte) {
+	t.Helper()
+	var hdr [2]byte
+	if _, err := io.ReadFull(c.br, hdr[:]); err != nil {
+		t.Fatal(err)
+	}
+	n := int(hdr[1] & 0x7f)
+	switch n {
+	case 126:
+		var b [2]byte
+		io.ReadFull(c.br, b[:])
+		n = int(binary.BigEndian.Uint16(b[:]))
+	case 127:
+		var b [8]byte
+		io.ReadFull(c.br, b[:])
+		n = int(binary.BigEndian.Uint64(b[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return int(hdr[0] & 0x0f), payload
+}
+
+func TestEcho(t *testing.T) {
+	errs := make(chan error, 4)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c, err := Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		for {
+			op, msg, err := c.ReadMessage()
+			if err != nil {
+				errs <- err
+				return
+			}
+			c.WriteMessage(op, msg)
+		}
+	}))
+	defer srv.Close()
+
+	c := dial(t, srv.URL)
+	c.send(true, OpText, []byte("hello"), false)
+	if op, msg := c.recv(t); op != OpText || string(msg) != "hello" {
+		t.Fatalf("echo: %d %q", op, msg)
+	}
+	// A fragmented binary message with a ping in between.
+	big := bytes.Repeat([]byte{0xfe}, 70000)
+	c.send(false, OpBinary, big[:100], false)
+	c.send(true, opPing, []byte("p"), false)
+	c.send(true, opContinuation, big[100:], false)
+	if op, msg := c.recv(t); op != opPong || string(msg) != "p" {
+		t.Fatalf("pong: %d %q", op, msg)
+	}
+	if op, msg := c.recv(t); op != OpBinary || !bytes.Equal(msg, big) {
+		t.Fatalf("fragmented message: %d, %d bytes", op, len(msg))
+	}
+	c.send(true, opClose, []byte{0x03, 0xe8}, false)
+	if op, msg := c.recv(t); op != opClose || !bytes.Equal(msg, []byte{0x03, 0xe8}) {
+		t.Fatalf("close: %d %x", op, msg)
+	}
+	if err := <-errs; err != io.EOF {
+		t.Errorf("after close: %v", err)
+	}
+
+	for _, bad := range []func(c *client){
+		func(c *client) { c.send(true, OpText, []byte("x"), true) },
+		func(c *client) { c.send(true, opContinuation, []byte("x"), false) },
+		func(c *client) { c.send(true, OpText, []byte{0xff}, false) },
+		func(c *client) { c.send(false, opPing, nil, false) },
+	} {
+		c := dial(t, srv.URL)
+		bad(c)
+		if op, _ := c.recv(t); op != opClose {
+			t.Errorf("bad frame answered with opcode %d", op)
+		}
+		if _, ok := (<-errs).(*ProtocolError); !ok {
+			t.Error("bad frame not reported as a ProtocolError")
+		}
+	}
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Errorf("plain GET: %s", resp.Status)
+	}
+}
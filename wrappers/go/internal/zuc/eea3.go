@@ -0,0 +1,49 @@
+package zuc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// checkParams validates the parameters shared by EEA3 and EIA3.
+func checkParams(bearer, direction uint8, data []byte, length int) error {
+	if bearer > 0x1f {
+		return fmt.Errorf("zuc: bearer must be 5 bits, got %d", bearer)
+	}
+	if direction > 1 {
+		return fmt.Errorf("zuc: direction must be 0 or 1, got %d", direction)
+	}
+	if length < 0 || length > 8*len(data) {
+		return errors.New("zuc: length exceeds the data")
+	}
+	return nil
+}
+
+// EEA3 encrypts or decrypts the first length bits of data with 128-EEA3
+// under the 16-byte key, COUNT, BEARER (5 bits) and DIRECTION (1 bit). The
+// result is (length+7)/8 bytes with the bits past length cleared.
+func EEA3(key []byte, count uint32, bearer, direction uint8, data []byte, length int) ([]byte, error) {
+	if err := checkParams(bearer, direction, data, length); err != nil {
+		return nil, err
+	}
+	var iv [IVSize]byte
+	iv[0], iv[1], iv[2], iv[3] = byte(count>>24), byte(count>>16), byte(count>>8), byte(count)
+	iv[4] = bearer<<3 | direction<<2
+	copy(iv[8:], iv[:8])
+	c, err := NewCipher(key, iv[:])
+	if err != nil {
+		return nil, err
+	}
+	return xorBits(c, data, length), nil
+}
+
+// xorBits XORs the first length bits of data with the keystream of c and
+// clears the bits past length in the last byte.
+func xorBits(c *Cipher, data []byte, length int) []byte {
+	out := make([]byte, (length+7)/8)
+	c.XORKeyStream(out, data[:len(out)])
+	if r := length % 8; r != 0 {
+		out[len(out)-1] &= 0xff << (8 - r)
+	}
+	return out
+}
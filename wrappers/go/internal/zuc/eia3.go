@@ -0,0 +1,43 @@
+package zuc
+
+// MACSize is the size in bytes of a 128-EIA3 MAC.
+const MACSize = 4
+
+// EIA3 returns the 128-EIA3 MAC of the first length bits of data under
+// the 16-byte key, COUNT, BEARER (5 bits) and DIRECTION (1 bit).
+func EIA3(key []byte, count uint32, bearer, direction uint8, data []byte, length int) ([]byte, error) {
+	if err := checkParams(bearer, direction, data, length); err != nil {
+		return nil, err
+	}
+	var iv [IVSize]byte
+	iv[0], iv[1], iv[2], iv[3] = byte(count>>24), byte(count>>16), byte(count>>8), byte(count)
+	iv[4] = bearer << 3
+	copy(iv[8:], iv[:8])
+	iv[8] ^= direction << 7
+	iv[14] ^= direction << 7
+	c, err := NewCipher(key, iv[:])
+	if err != nil {
+		return nil, err
+	}
+	z := make([]uint32, (length+31)/32+2)
+	for i := range z {
+		z[i] = c.Word()
+	}
+	var t uint32
+	for i := 0; i < length; i++ {
+		if data[i/8]>>(7-i%8)&1 == 1 {
+			t ^= wordAt(z, i)
+		}
+	}
+	t ^= wordAt(z, length) ^ z[len(z)-1]
+	return []byte{byte(t >> 24), byte(t >> 16), byte(t >> 8), byte(t)}, nil
+}
+
+// wordAt returns the 32 keystream bits starting at bit i.
+func wordAt(z []uint32, i int) uint32 {
+	j, r := i/32, i%32
+	if r == 0 {
+		return z[j]
+	}
+	return z[j]<<r | z[j+1]>>(32-r)
+}
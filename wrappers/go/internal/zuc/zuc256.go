@@ -0,0 +1,145 @@
+package zuc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ZUC-256 key and IV sizes in bytes. The IV is 17 bytes followed by eight
+// 6-bit values, packed into 6 bytes (IV256Size) or one per byte
+// (IV256UnpackedSize).
+const (
+	Key256Size        = 32
+	IV256Size         = 23
+	IV256UnpackedSize = 25
+)
+
+// The 7-bit constants d_i of ZUC-256 for the keystream and for each MAC
+// size; d5 to d12 are 0x40 in all of them.
+var (
+	d256    = [16]byte{0x22, 0x2f, 0x24, 0x2a, 0x6d, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x52, 0x10, 0x30}
+	d256Mac = map[int][16]byte{
+		4:  {0x22, 0x2f, 0x25, 0x2a, 0x6d, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x52, 0x10, 0x30},
+		8:  {0x23, 0x2f, 0x24, 0x2a, 0x6d, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x52, 0x10, 0x30},
+		16: {0x23, 0x2f, 0x25, 0x2a, 0x6d, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x40, 0x52, 0x10, 0x30},
+	}
+)
+
+// NewCipher256 returns a ZUC-256 generator for the 32-byte key and the
+// 23- or 25-byte iv.
+func NewCipher256(key, iv []byte) (*Cipher, error) {
+	return newCipher256(key, iv, &d256)
+}
+
+func newCipher256(key, iv []byte, d *[16]byte) (*Cipher, error) {
+	if len(key) != Key256Size {
+		return nil, fmt.Errorf("zuc: invalid ZUC-256 key size %d, want %d", len(key), Key256Size)
+	}
+	iv, err := unpackIV256(iv)
+	if err != nil {
+		return nil, err
+	}
+	k := key
+	cell := func(a, b, c, e byte) uint32 {
+		return uint32(a)<<23 | uint32(b)<<16 | uint32(c)<<8 | uint32(e)
+	}
+	c := &Cipher{}
+	c.s = [16]uint32{
+		cell(k[0], d[0], k[21], k[16]),
+		cell(k[1], d[1], k[22], k[17]),
+		cell(k[2], d[2], k[23], k[18]),
+		cell(k[3], d[3], k[24], k[19]),
+		cell(k[4], d[4], k[25], k[20]),
+		cell(iv[0], d[5]|iv[17], k[5], k[26]),
+		cell(iv[1], d[6]|iv[18], k[6], k[27]),
+		cell(iv[10], d[7]|iv[19], k[7], iv[2]),
+		cell(k[8], d[8]|iv[20], iv[3], iv[11]),
+		cell(k[9], d[9]|iv[21], iv[12], iv[4]),
+		cell(iv[5], d[10]|iv[22], k[10], k[28]),
+		cell(k[11], d[11]|iv[23], iv[6], iv[13]),
+		cell(k[12], d[12]|iv[24], iv[7], iv[14]),
+		cell(k[13], d[13], iv[15], iv[8]),
+		cell(k[14], d[14]|k[31]>>4, iv[16], iv[9]),
+		cell(k[15], d[15]|k[31]&0x0f, k[30], k[29]),
+	}
+	c.init()
+	return c, nil
+}
+
+// unpackIV256 returns the 25-byte form of a ZUC-256 IV.
+func unpackIV256(iv []byte) ([]byte, error) {
+	switch len(iv) {
+	case IV256UnpackedSize:
+		for _, b := range iv[17:] {
+			if b > 0x3f {
+				return nil, fmt.Errorf("zuc: IV bytes 17 to 24 must be 6-bit values, got %#02x", b)
+			}
+		}
+		return iv, nil
+	case IV256Size:
+		out := make([]byte, IV256UnpackedSize)
+		copy(out, iv[:17])
+		var acc uint64
+		for _, b := range iv[17:] {
+			acc = acc<<8 | uint64(b)
+		}
+		for i := 0; i < 8; i++ {
+			out[17+i] = byte(acc>>(42-6*i)) & 0x3f
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("zuc: invalid ZUC-256 IV size %d, want %d or %d", len(iv), IV256Size, IV256UnpackedSize)
+}
+
+// Crypt256 encrypts or decrypts the first length bits of data with the
+// ZUC-256 keystream for the 32-byte key and the 23- or 25-byte iv, like
+// EEA3.
+func Crypt256(key, iv, data []byte, length int) ([]byte, error) {
+	if length < 0 || length > 8*len(data) {
+		return nil, errors.New("zuc: length exceeds the data")
+	}
+	c, err := NewCipher256(key, iv)
+	if err != nil {
+		return nil, err
+	}
+	return xorBits(c, data, length), nil
+}
+
+// MAC256 returns the ZUC-256 MAC of size 4, 8 or 16 bytes of the first
+// length bits of data under the 32-byte key and the 23- or 25-byte iv.
+func MAC256(key, iv, data []byte, length, size int) ([]byte, error) {
+	d, ok := d256Mac[size]
+	if !ok {
+		return nil, fmt.Errorf("zuc: invalid ZUC-256 MAC size %d (supported: 4, 8, 16)", size)
+	}
+	if length < 0 || length > 8*len(data) {
+		return nil, errors.New("zuc: length exceeds the data")
+	}
+	c, err := newCipher256(key, iv, &d)
+	if err != nil {
+		return nil, err
+	}
+	n := size / 4
+	z := make([]uint32, 2*n+(length+31)/32+1)
+	for i := range z {
+		z[i] = c.Word()
+	}
+	t := make([]uint32, n)
+	copy(t, z)
+	xorAt := func(bit int) {
+		for j := range t {
+			t[j] ^= wordAt(z, bit+32*j)
+		}
+	}
+	for i := 0; i < length; i++ {
+		if data[i/8]>>(7-i%8)&1 == 1 {
+			xorAt(32*n + i)
+		}
+	}
+	xorAt(32*n + length)
+	out := make([]byte, 0, size)
+	for _, w := range t {
+		out = append(out, byte(w>>24), byte(w>>16), byte(w>>8), byte(w))
+	}
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package zuc
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestSBoxesArePermutations(t *testing.T) {
+	for name, s := range map[string]*[256]byte{"S0": &s0, "S1": &s1} {
+		var seen [256]bool
+		for _, v := range s {
+			if seen[v] {
+				t.Errorf("%s repeats %#02x", name, v)
+			}
+			seen[v] = true
+		}
+	}
+}
+
+// The keystream test vectors of GB/T 33133.1 annex A.
+func TestKeystream(t *testing.T) {
+	for _, tc := range []struct{ key, iv, z1, z2 string }{
+		{"00000000000000000000000000000000", "00000000000000000000000000000000", "27bede74", "018082da"},
+		{"ffffffffffffffffffffffffffffffff", "ffffffffffffffffffffffffffffffff", "0657cfa0", "7096398b"},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		iv, _ := hex.DecodeString(tc.iv)
+		c, err := NewCipher(key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var z [8]byte
+		c.XORKeyStream(z[:], z[:])
+		if got := hex.EncodeToString(z[:]); got != tc.z1+tc.z2 {
+			t.Errorf("key %s: keystream %s, want %s%s", tc.key, got, tc.z1, tc.z2)
+		}
+	}
+}
+
+// Test sets 1 and 2 of the 3GPP EEA3 and EIA3 implementers' test data.
+func TestEEA3(t *testing.T) {
+	for _, tc := range []struct {
+		key               string
+		count             uint32
+		bearer, direction uint8
+		length            int
+		plaintext, want   string
+	}{
+		{
+			"173d14ba5003731d7a60049470f00a29", 0x66035492, 0xf, 0, 193,
+			"6cf65340735552ab0c9752fa6f9025fe0bd675d9005875b200",
+			"a6c85fc66afb8533aafc2518dfe784940ee1e4b030238cc800",
+		},
+		{
+			"e5bd3ea0eb55ade866c6ac58bd54302a", 0x56823, 0x18, 1, 800,
+			"14a8ef693d678507bbe7270a7f67ff5006c3525b9807e467c4e56000ba338f5d42955903675182224" +
+				"6c80d3b38f07f4be2d8ff5805f5132229bde93bbbdcaf382bf1ee972fbf9977bada8945847a2a6c" +
+				"9ad34a667554e04d1f7fa2c33241bd8f01ba220d",
+			"131d43e0dea1be5c5a1bfd971d852cbf712d7b4f57961fea3208afa8bca433f456ad09c7417e58bc6" +
+				"9cf8866d1353f74865e80781d202dfb3ecff7fcbc3b190fe82a204ed0e350fc0f6f2613b2f2bca6" +
+				"df5a473a57a4a00d985ebad880d6f23864a07b01",
+		},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		pt, _ := hex.DecodeString(tc.plaintext)
+		ct, err := EEA3(key, tc.count, tc.bearer, tc.direction, pt, tc.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(ct); got != tc.want {
+			t.Errorf("count %#x: ciphertext %s, want %s", tc.count, got, tc.want)
+		}
+		back, _ := EEA3(key, tc.count, tc.bearer, tc.direction, ct, tc.length)
+		if hex.EncodeToString(back) != tc.plaintext {
+			t.Errorf("count %#x: decryption does not round-trip", tc.count)
+		}
+	}
+	if _, err := EEA3(make([]byte, 16), 0, 32, 0, nil, 0); err == nil {
+		t.Error("6-bit bearer accepted")
+	}
+	if _, err := EEA3(make([]byte, 16), 0, 0, 0, []byte{0}, 9); err == nil {
+		t.Error("length beyond the data accepted")
+	}
+}
+
+func TestEIA3(t *testing.T) {
+	for _, tc := range []struct {
+		key               string
+		count             uint32
+		bearer, direction uint8
+		length            int
+		message, want     string
+	}{
+		{"00000000000000000000000000000000", 0, 0, 0, 1, "00000000", "c8a9595e"},
+		{"47054125561eb2dda94059da05097850", 0x561eb2dd, 0x14, 0, 90, "000000000000000000000000", "6719a088"},
+		{
+			"c9e6cec4607c72db000aefa88385ab0a", 0xa94059da, 0xa, 1, 577,
+			"983b41d47d780c9e1ad11d7eb70391b1de0b35da2dc62f83e7b78d6306ca0ea07e941b7be91348f9" +
+				"fcb170e2217fecd97f9f68adb16e5d7d21e569d280ed775cebde3f4093c5388100000000",
+			"fae8ff0b",
+		},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		msg, _ := hex.DecodeString(tc.message)
+		mac, err := EIA3(key, tc.count, tc.bearer, tc.direction, msg, tc.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := hex.EncodeToString(mac); got != tc.want {
+			t.Errorf("count %#x: MAC %s, want %s", tc.count, got, tc.want)
+		}
+	}
+}
+
+// The test vectors of "The ZUC-256 Stream Cipher" (2018).
+func TestZUC256Keystream(t *testing.T) {
+	for _, tc := range []struct{ key, iv, want string }{
+		{
+			strings.Repeat("00", 32), strings.Repeat("00", 25),
+			"58d03ad62e032ce2dafc683a39bdcb0352a2bc67f1b7de74163ce3a101ef55589639d75b95fa681b" +
+				"7f090df756391ccc903b7612744d544c17bc3fad8b163b0821787c0b97775bb84943c6bbe8ad8afd",
+		},
+		{
+			strings.Repeat("ff", 32), strings.Repeat("ff", 17) + strings.Repeat("3f", 8),
+			"3356cbaed1a1c18b6baa4ffe343f777c9e15128f251ab65b949f7b26ef7157f296dd2fa9df95e3ee" +
+				"7a5be02ec32ba585505af316c2f9ded27cdbd935e441ce1115fd0a80bb7aef6768989416b8fac8c2",
+		},
+	} {
+		key, _ := hex.DecodeString(tc.key)
+		iv, _ := hex.DecodeString(tc.iv)
+		c, err := NewCipher256(key, iv)
+		if err != nil {
+			t.Fatal(err)
+		}
+		z := make([]byte, 80)
+		c.XORKeyStream(z, z)
+		if got := hex.EncodeToString(z); got != tc.want {
+			t.Errorf("key %s: keystream %s", tc.key[:8], got)
+		}
+	}
+	packed, _ := unpackIV256(bytesOf(0xff, IV256Size))
+	if hex.EncodeToString(packed) != strings.Repeat("ff", 17)+strings.Repeat("3f", 8) {
+		t.Errorf("unpacked IV %x", packed)
+	}
+}
+
+func TestMAC256(t *testing.T) {
+	for _, tc := range []struct {
+		fill, msg byte
+		length    int
+		want      map[int]string
+	}{
+		{0x00, 0x00, 400, map[int]string{4: "9b972a74", 8: "673e54990034d38c", 16: "d85e54bbcb9600967084c952a1654b26"}},
+		{0x00, 0x11, 4000, map[int]string{4: "8754f5cf", 8: "130dc225e72240cc", 16: "df1e8307b31cc62beca1ac6f8190c22f"}},
+		{0xff, 0x00, 400, map[int]string{4: "1f3079b4", 8: "8c71394d39957725", 16: "a35bb274b567c48b28319f111af34fbd"}},
+		{0xff, 0x11, 4000, map[int]string{4: "5c7c8b88", 8: "ea1dee544bb6223b", 16: "3a83b554be408ca5494124ed9d473205"}},
+	} {
+		key := bytesOf(tc.fill, Key256Size)
+		iv := bytesOf(tc.fill, IV256Size)
+		msg := bytesOf(tc.msg, tc.length/8)
+		for size, want := range tc.want {
+			mac, err := MAC256(key, iv, msg, tc.length, size)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := hex.EncodeToString(mac); got != want {
+				t.Errorf("%#02x/%d bits: %d-byte MAC %s, want %s", tc.fill, tc.length, size, got, want)
+			}
+		}
+	}
+	if _, err := MAC256(make([]byte, 32), make([]byte, 23), nil, 0, 12); err == nil {
+		t.Error("12-byte MAC accepted")
+	}
+}
+
+func bytesOf(b byte, n int) []byte {
+	return []byte(strings.Repeat(string([]byte{b}), n))
+}
+
+func TestCrypt256(t *testing.T) {
+	key, iv := bytesOf(0x01, Key256Size), bytesOf(0x02, IV256Size)
+	ct, err := Crypt256(key, iv, []byte("message"), 53)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt, _ := Crypt256(key, iv, ct, 53)
+	if string(pt) != "messag`" {
+		t.Errorf("round trip: %q", pt)
+	}
+	if _, err := NewCipher256(key, bytesOf(0x40, IV256UnpackedSize)); err == nil {
+		t.Error("7-bit IV value accepted")
+	}
+}
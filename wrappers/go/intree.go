@@ -0,0 +1,37 @@
+//go:build !smgobc
+
+package main
+
+import (
+	"crypto/cipher"
+	"hash"
+	"io"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// Without the smgobc build tag the primitives that smgobc.go takes from
+// sm-go-bc come from internal/, so the wrapper builds without the library.
+
+func newSM4(key []byte) (cipher.Block, error) { return sm4.NewCipher(key) }
+
+func newSM3() hash.Hash { return sm3.New() }
+
+func sm2SignDigest(rand io.Reader, priv *sm2.PrivateKey, e []byte) (r, s *big.Int, err error) {
+	return sm2.SignDigest(rand, priv, e)
+}
+
+func sm2VerifyDigest(pub *sm2.PublicKey, e []byte, r, s *big.Int) bool {
+	return sm2.VerifyDigest(pub, e, r, s)
+}
+
+func sm2EncryptC1C3C2(rand io.Reader, pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	return sm2.Encrypt(rand, pub, msg)
+}
+
+func sm2DecryptC1C3C2(priv *sm2.PrivateKey, ct []byte) ([]byte, error) {
+	return sm2.Decrypt(priv, ct)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/keystore"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// openKeystore reads "keystore_file" with "password" and returns the
+// keystore, its path and the password. Operations that change the
+// keystore rewrite the whole file under a fresh salt and nonce.
+func openKeystore(in map[string]interface{}) (*keystore.Keystore, string, string, error) {
+	path, err := requireString(in, "keystore_file")
+	if err != nil {
+		return nil, "", "", err
+	}
+	password, err := requireString(in, "password")
+	if err != nil {
+		return nil, "", "", err
+	}
+	ks, err := keystore.Read(path, password)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return ks, path, password, nil
+}
+
+// keystoreCreate writes an empty keystore to "keystore_file" under
+// "password". An existing file is replaced only when "overwrite" is set.
+func keystoreCreate(in map[string]interface{}) (*Result, error) {
+	path, err := requireString(in, "keystore_file")
+	if err != nil {
+		return nil, err
+	}
+	password, err := requireString(in, "password")
+	if err != nil {
+		return nil, err
+	}
+	if password == "" {
+		return nil, &codedError{codeInvalidField, errors.New("field \"password\" must not be empty")}
+	}
+	overwrite, err := boolField(in, "overwrite")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(path); err == nil && !overwrite {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("%s already exists; set \"overwrite\" to replace it", path)}
+	}
+	if err := keystore.New().Write(path, password, rand); err != nil {
+		return nil, err
+	}
+	return &Result{Output: path}, nil
+}
+
+// keystoreList returns the entry names, sorted, in Outputs.
+func keystoreList(in map[string]interface{}) (*Result, error) {
+	ks, _, _, err := openKeystore(in)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Outputs: ks.Names()}, nil
+}
+
+// keystoreGet returns the entry "name": the key pair of an SM2 entry, in
+// the "key_format" of keygen, or the hex key of an SM4 entry in Output.
+func keystoreGet(in map[string]interface{}) (*Result, error) {
+	ks, _, _, err := openKeystore(in)
+	if err != nil {
+		return nil, err
+	}
+	name, err := requireString(in, "name")
+	if err != nil {
+		return nil, err
+	}
+	e, err := ks.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return entryResult(in, e, true)
+}
+
+// entryResult describes e, with its secret key when withSecret is set.
+func entryResult(in map[string]interface{}, e keystore.Entry, withSecret bool) (*Result, error) {
+	res := &Result{}
+	if e.Type == keystore.SM2 {
+		var priv *sm2.PrivateKey
+		if withSecret {
+			priv = e.SM2
+		}
+		var err error
+		if res, err = keyPairResult(in, &e.SM2.PublicKey, priv); err != nil {
+			return nil, err
+		}
+	} else if withSecret {
+		res.Output = hex.EncodeToString(e.SM4)
+	}
+	res.KeyType = e.Type.String()
+	return res, nil
+}
+
+// keystorePut stores a key as "name". "type" is sm2 (the default) with
+// "private_key", or sm4 with the hex "key"; without a key one is
+// generated. An entry of the same name is replaced only when "overwrite"
+// is set. The result describes the entry without its secret key, which
+// keystore-get returns.
+func keystorePut(in map[string]interface{}) (*Result, error) {
+	ks, path, password, err := openKeystore(in)
+	if err != nil {
+		return nil, err
+	}
+	name, err := requireString(in, "name")
+	if err != nil {
+		return nil, err
+	}
+	overwrite, err := boolField(in, "overwrite")
+	if err != nil {
+		return nil, err
+	}
+	typ, _, err := stringField(in, "type")
+	if err != nil {
+		return nil, err
+	}
+
+	e := keystore.Entry{Name: name, Created: time.Now()}
+	switch strings.ToLower(typ) {
+	case "", "sm2":
+		e.Type = keystore.SM2
+		if in["key"] != nil {
+			return nil, &codedError{codeInvalidField, errors.New("field \"key\" is for sm4 entries; use \"private_key\"")}
+		}
+		if e.SM2, _, err = sm2PrivateKey(in); err != nil {
+			return nil, err
+		}
+	case "sm4":
+		e.Type = keystore.SM4
+		if in["private_key"] != nil {
+			return nil, &codedError{codeInvalidField, errors.New("field \"private_key\" is for sm2 entries; use \"key\"")}
+		}
+		if in["key"] != nil {
+			if e.SM4, err = sm4KeyField(in, sm4.KeySize); err != nil {
+				return nil, err
+			}
+		} else {
+			e.SM4 = make([]byte, sm4.KeySize)
+			if _, err := io.ReadFull(rand, e.SM4); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported type %q (supported: sm2, sm4)", typ)}
+	}
+	if err := ks.Put(e, overwrite); err != nil {
+		if err == keystore.ErrExists {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("entry %q already exists; set \"overwrite\" to replace it", name)}
+		}
+		return nil, err
+	}
+	if err := ks.Write(path, password, rand); err != nil {
+		return nil, err
+	}
+	return entryResult(in, e, false)
+}
+
+// keystoreDelete removes the entry "name".
+func keystoreDelete(in map[string]interface{}) (*Result, error) {
+	ks, path, password, err := openKeystore(in)
+	if err != nil {
+		return nil, err
+	}
+	name, err := requireString(in, "name")
+	if err != nil {
+		return nil, err
+	}
+	if err := ks.Delete(name); err != nil {
+		return nil, err
+	}
+	if err := ks.Write(path, password, rand); err != nil {
+		return nil, err
+	}
+	return &Result{Output: name}, nil
+}
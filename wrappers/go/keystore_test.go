@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestKeystore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.smks")
+	with := func(extra map[string]interface{}) map[string]interface{} {
+		in := map[string]interface{}{"keystore_file": path, "password": "secret"}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return in
+	}
+	mustCall(t, "keystore", "create", with(nil))
+	mustFail(t, "keystore", "create", with(nil))
+	mustFail(t, "keystore", "create", with(map[string]interface{}{"password": "", "overwrite": true}))
+
+	signer := mustCall(t, "keystore", "put", with(map[string]interface{}{"name": "signer"}))
+	if signer.KeyType != "sm2" || signer.PublicKey == "" || signer.PrivateKey != "" {
+		t.Errorf("put sm2: %+v", signer)
+	}
+	const sm4Key = "0123456789abcdeffedcba9876543210"
+	if res := mustCall(t, "keystore", "put", with(map[string]interface{}{"name": "data", "type": "sm4", "key": sm4Key})); res.KeyType != "sm4" || res.Output != "" {
+		t.Errorf("put sm4: %+v", res)
+	}
+	mustFail(t, "keystore", "put", with(map[string]interface{}{"name": "data", "type": "sm4"}))
+	mustFail(t, "keystore", "put", with(map[string]interface{}{"name": "bad", "type": "sm4", "private_key": signer.PublicKey}))
+	mustFail(t, "keystore", "put", with(map[string]interface{}{"name": "bad", "type": "rsa"}))
+
+	if res := mustCall(t, "keystore", "list", with(nil)); !reflect.DeepEqual(res.Outputs, []string{"data", "signer"}) {
+		t.Errorf("list: %v", res.Outputs)
+	}
+	got := mustCall(t, "keystore", "get", with(map[string]interface{}{"name": "signer"}))
+	if got.KeyType != "sm2" || got.PublicKey != signer.PublicKey || got.PrivateKey == "" {
+		t.Errorf("get sm2: %+v", got)
+	}
+	// The stored key signs for the public key put returned.
+	sig := mustCall(t, "sm2", "sign", map[string]interface{}{"message": "msg", "private_key": got.PrivateKey}).Output
+	if res := mustCall(t, "sm2", "verify", map[string]interface{}{"message": "msg", "signature": sig, "public_key": signer.PublicKey}); !*res.Valid {
+		t.Error("stored key does not match its public key")
+	}
+	if res := mustCall(t, "keystore", "get", with(map[string]interface{}{"name": "data"})); res.Output != sm4Key {
+		t.Errorf("get sm4: %+v", res)
+	}
+
+	mustFail(t, "keystore", "list", with(map[string]interface{}{"password": "wrong"}))
+	mustCall(t, "keystore", "delete", with(map[string]interface{}{"name": "data"}))
+	mustFail(t, "keystore", "get", with(map[string]interface{}{"name": "data"}))
+	mustFail(t, "keystore", "delete", with(map[string]interface{}{"name": "data"}))
+	if res := mustCall(t, "keystore", "list", with(nil)); !reflect.DeepEqual(res.Outputs, []string{"signer"}) {
+		t.Errorf("list after delete: %v", res.Outputs)
+	}
+}
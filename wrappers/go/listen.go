@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+)
+
+// maxFrameSize bounds one request of the socket mode.
+const maxFrameSize = 64 << 20
+
+// listenSocket listens on spec, "unix:<path>". A socket left behind by an
+// earlier run is replaced; anything else at path is an error.
+func listenSocket(spec string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(spec, "unix:")
+	if !ok || path == "" {
+		return nil, fmt.Errorf("unsupported --listen address %q (want unix:<path>)", spec)
+	}
+	if fi, err := os.Lstat(path); err == nil && fi.Mode()&os.ModeSocket != 0 {
+		os.Remove(path)
+	}
+	return net.Listen("unix", path)
+}
+
+// serveSocket answers requests in format on the Unix socket of spec until
+// the process is interrupted or terminated, which closes the listener and
+// removes the socket file.
+func serveSocket(spec, format string) int {
+	ln, err := listenSocket(spec)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	log := json.NewEncoder(stderr)
+	log.Encode(&serverEvent{Event: "listening", Time: now(), Address: ln.Addr().String(), Protocol: "unix"})
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sig
+		ln.Close()
+	}()
+	if err := serveFrames(ln, format); !errors.Is(err, net.ErrClosed) {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// serveFrames serves the connections of ln concurrently until Accept
+// fails. Each request is a JSON object (or a CBOR map, with format
+// "cbor") as in batch mode, naming its "algorithm" and "operation",
+// preceded by its length as a 4-byte big-endian integer; each gets its
+// Result framed the same way, in order.
+func serveFrames(ln net.Listener, format string) error {
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = errReader{errNoStdin}
+	for {
+		c, err := ln.Accept()
+		if err != nil {
+			return err
+		}
+		go serveFrameConn(c, format)
+	}
+}
+
+func serveFrameConn(c net.Conn, format string) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	for {
+		req, err := readFrame(r)
+		if err == io.EOF {
+			return
+		}
+		var reply []byte
+		if err != nil {
+			reply = encodeReply(format, errorResult(err))
+		} else {
+			reply = frameAnswer(format, req)
+		}
+		if werr := writeFrame(c, reply); werr != nil || err != nil {
+			// After a bad frame the stream cannot be resynchronized.
+			return
+		}
+	}
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = &codedError{codeInvalidRequest, errors.New("truncated frame header")}
+		}
+		return nil, err
+	}
+	n := binary.BigEndian.Uint32(hdr[:])
+	if n > maxFrameSize {
+		return nil, &codedError{codeInvalidRequest, fmt.Errorf("frame of %d bytes exceeds the limit of %d", n, maxFrameSize)}
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, &codedError{codeInvalidRequest, errors.New("truncated frame")}
+	}
+	return b, nil
+}
+
+func writeFrame(w io.Writer, b []byte) error {
+	frame := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(b)), uint32(len(b)))
+	_, err := w.Write(append(frame, b...))
+	return err
+}
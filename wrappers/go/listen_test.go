@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSocketMode(t *testing.T) {
+	// Socket paths are short; t.TempDir may exceed the limit.
+	dir, err := os.MkdirTemp("", "wrapper")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "s")
+	// A stale socket is replaced.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	ln, err := listenSocket("unix:" + path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- serveFrames(ln, formatJSON) }()
+
+	// Two clients at once, each with several requests.
+	c1, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	c2, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	roundTrip := func(c net.Conn, req string) *Result {
+		t.Helper()
+		if err := writeFrame(c, []byte(req)); err != nil {
+			t.Fatal(err)
+		}
+		b, err := readFrame(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var res Result
+		if err := json.Unmarshal(b, &res); err != nil {
+			t.Fatalf("response %q", b)
+		}
+		return &res
+	}
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	if res := roundTrip(c2, `{"algorithm": "sm3", "operation": "hash", "data": "abc"}`); res.Output != abc {
+		t.Errorf("hash: %+v", res)
+	}
+	if res := roundTrip(c1, `{"algorithm": "sm2", "operation": "keygen"}`); res.Status != statusSuccess {
+		t.Errorf("keygen: %+v", res)
+	}
+	for _, req := range []string{`{"algorithm": "sm3"}`, `not json`, `{"algorithm": "sm3", "operation": "hash", "stdin": true}`} {
+		if res := roundTrip(c1, req); res.Status != statusError {
+			t.Errorf("%s: %+v", req, res)
+		}
+	}
+	if res := roundTrip(c1, `{"algorithm": "sm3", "operation": "hash", "data": "abc"}`); res.Output != abc {
+		t.Errorf("hash after errors: %+v", res)
+	}
+	for _, req := range []string{
+		`{"algorithm": "sm3", "operation": "hash", "stdin": true}`,
+		`{"algorithm": "sm3", "operation": "hash", "input_file": "/etc/hostname"}`,
+		`{"algorithm": "sm3", "operation": "hash", "data": "abc", "output_file": "x"}`,
+		`{"algorithm": "keystore", "operation": "create", "keystore_file": "x", "password": "p"}`,
+	} {
+		if res := roundTrip(c1, req); res.ErrorCode != codeInvalidField {
+			t.Errorf("%s: %+v", req, res)
+		}
+	}
+
+	// An oversized frame is answered with an error and the connection
+	// closed.
+	c1.Write(binary.BigEndian.AppendUint32(nil, maxFrameSize+1))
+	if b, err := readFrame(c1); err != nil || json.Unmarshal(b, new(Result)) != nil {
+		t.Fatalf("oversized frame: %q, %v", b, err)
+	}
+	if _, err := readFrame(c1); err == nil {
+		t.Error("connection left open after a bad frame")
+	}
+
+	ln.Close()
+	<-done
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("socket file left behind")
+	}
+	if _, err := listenSocket("tcp:127.0.0.1:0"); err == nil {
+		t.Error("a TCP address was accepted")
+	}
+}
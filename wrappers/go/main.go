@@ -0,0 +1,503 @@
+// Command wrapper exposes SM2, SM3 and SM4 through the JSON command line
+// interface shared by every language wrapper in the cross-language suite:
+//
+//	wrapper <algorithm> <operation> --input '<json>'
+//	wrapper <algorithm> <operation> --<field> <value>...
+//	wrapper <algorithm> <operation> [--input -] < request.json
+//	wrapper <algorithm> <operation> --input-format yaml|toml < request.yaml
+//	wrapper [<algorithm> <operation>] --batch [--format cbor|protobuf] < requests
+//	wrapper --serve-stdio
+//	wrapper --grpc <address> [--allow-origin <origin>...]
+//	wrapper --listen unix:<path> [--format cbor]
+//	wrapper --serve-stdio | --listen unix:<path> --metrics <address>
+//	wrapper help [<algorithm> [<operation>]]
+//	wrapper version | --version
+//	wrapper --selftest [<mode or request>]
+//	wrapper gen-vectors [--algorithm <algorithm>] [--count N] [--seed N]
+//	wrapper verify-vectors <file>
+//	wrapper difftest --peer "<command>"
+//	wrapper roundtrip [--iterations N]
+//	wrapper fuzz-corpus export [--dir <dir>]
+//	wrapper report [--format json|html] <results.ndjson>...
+//	wrapper bench [--algorithm <algorithm>] [--duration D]
+//
+// Exactly one JSON object is written to stdout, either
+// {"status": "success", "output": ...} or {"status": "error", "message": ...};
+// in batch mode, one such line per request line. Field flags build the
+// request on the command line (see fieldFlags), and help describes the
+// operations and their fields. --serve-stdio speaks
+// JSON-RPC 2.0 instead (see serveStdio), and --grpc serves the service of
+// proto/wrapper.proto. --listen answers length-prefixed requests on a Unix
+// socket (see serveFrames). The server modes expose Prometheus metrics at
+// /metrics: --grpc on its own address, the others on --metrics.
+// --format cbor exchanges CBOR instead of JSON in --batch and --listen
+// (see formatCBOR), and --format protobuf length-delimited messages in
+// --batch (see runProtobufBatch). --input-format reads a single request
+// as YAML or TOML (see decodeInput). Subcommands such as gen-vectors are
+// listed in commands.
+// SM_WRAPPER_CONFIG and SM_WRAPPER_* variables set default request fields
+// (see loadDefaults). --selftest runs the known answer tests of wrapper
+// selftest before anything else, and stops with its Result if one fails.
+// --seed N, for tests only, derives every key, IV and nonce from N (see
+// useSeed), so that a failing case can be replayed bit for bit.
+package main
+
+import (
+	"bufio"
+	cryptorand "crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const usage = "usage: wrapper <algorithm> <operation> [--<field> <value>...] [--input '<json>' | --input -] [--input-format json|yaml|toml] | wrapper [<algorithm> <operation>] --batch [--format json|cbor|protobuf] | wrapper --serve-stdio | wrapper --grpc <address> [--allow-origin <origin>...] | wrapper --listen unix:<path> [--format json|cbor] [--metrics <address>] | wrapper help [<algorithm> [<operation>]] | wrapper --version | wrapper --selftest | wrapper <command> [--help]"
+
+// rand is the randomness source for keys, nonces and IVs.
+var rand io.Reader = cryptorand.Reader
+
+// stdin is read by operations that take "stdin": true. When the request
+// itself comes from standard input, they read what follows it.
+var stdin io.Reader = os.Stdin
+
+// stderr receives the progress log of the server modes.
+var stderr io.Writer = os.Stderr
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout))
+}
+
+// run executes the request, or in batch mode every request on standard
+// input, and writes the Results to stdout. It returns the process exit
+// code: see exitCode for a single request.
+func run(args []string, stdout io.Writer) int {
+	if len(args) > 0 {
+		if c, ok := commands[args[0]]; ok {
+			return c.run(args[1:], stdout)
+		}
+	}
+	defaults = &config{}
+	inv, err := parseArgs(args)
+	if err == nil && inv.help {
+		if err = writeHelp(stdout, inv.algorithm, inv.operation); err == nil {
+			return exitSuccess
+		}
+	}
+	// The operations of the wrapper itself, such as version, take no
+	// defaults, so that a broken config file cannot break them.
+	if err == nil && inv.algorithm != "wrapper" {
+		var cfg *config
+		if cfg, err = loadDefaults(); err == nil {
+			defaults = cfg
+		}
+	}
+	if err == nil && inv.seed != nil {
+		defer useSeed(*inv.seed)()
+	}
+	if err == nil && inv.selfTest && (inv.algorithm != "wrapper" || inv.operation != "selftest") {
+		// A failed self-test stops every mode before it starts.
+		if res := dispatch("wrapper", "selftest", map[string]interface{}{}); exitCode(res) != exitSuccess {
+			enc := json.NewEncoder(stdout)
+			enc.SetEscapeHTML(false)
+			enc.Encode(res)
+			return exitCode(res)
+		}
+	}
+	if err == nil && inv.batch && inv.format == formatCBOR {
+		return runCBORBatch(inv, stdout)
+	}
+	if err == nil && inv.batch && inv.format == formatProtobuf {
+		return runProtobufBatch(inv, stdout)
+	}
+	if err == nil && inv.batch {
+		return runBatch(inv, stdout)
+	}
+	if err == nil && inv.metrics != "" {
+		if err := serveMetrics(inv.metrics); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return exitFailure
+		}
+	}
+	if err == nil && inv.serve {
+		return serveStdio(stdout)
+	}
+	if err == nil && inv.grpc != "" {
+		return serveGRPC(inv.grpc, inv.origins)
+	}
+	if err == nil && inv.listen != "" {
+		return serveSocket(inv.listen, inv.format)
+	}
+	var res *Result
+	var ce *codedError
+	if err != nil && !errors.As(err, &ce) {
+		err = &codedError{codeUsage, err}
+	}
+	if err != nil {
+		res = errorResult(err)
+	} else {
+		res = execute(inv)
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(res); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	return exitCode(res)
+}
+
+// Exit codes of a single invocation, by the class of its outcome, so that
+// scripts can branch without parsing the result.
+const (
+	exitSuccess = 0
+	// exitFailure covers what the other classes do not, such as I/O errors.
+	exitFailure = 1
+	exitUsage   = 2
+	// exitBadInput is a request with a missing, malformed or unsupported
+	// field.
+	exitBadInput = 3
+	// exitCryptoFailure is input the cryptographic operation rejected.
+	exitCryptoFailure = 4
+	// exitInvalid is a verification that completed with "valid": false.
+	exitInvalid = 5
+)
+
+// exitCodes maps the error codes to the exit codes of their class.
+var exitCodes = map[string]int{
+	codeUsage:                exitUsage,
+	codeUnsupportedOperation: exitUsage,
+	codeInvalidRequest:       exitBadInput,
+	codeMissingField:         exitBadInput,
+	codeInvalidField:         exitBadInput,
+	codeInvalidEncoding:      exitBadInput,
+	codeUnsupportedOption:    exitBadInput,
+	codeInvalidKeyLength:     exitBadInput,
+	codeInvalidIVLength:      exitBadInput,
+	codeInvalidDataLength:    exitBadInput,
+	codeWeakKey:              exitCryptoFailure,
+	codeBadPadding:           exitCryptoFailure,
+	codePointNotOnCurve:      exitCryptoFailure,
+	codeDecryptionFailed:     exitCryptoFailure,
+	codeAuthenticationFailed: exitCryptoFailure,
+	codeOperationFailed:      exitCryptoFailure,
+	codeIO:                   exitFailure,
+}
+
+func exitCode(res *Result) int {
+	switch {
+	case res.Status != statusSuccess:
+		if code, ok := exitCodes[res.ErrorCode]; ok {
+			return code
+		}
+		return exitFailure
+	case res.Valid != nil && !*res.Valid:
+		return exitInvalid
+	}
+	return exitSuccess
+}
+
+// invocation is the parsed command line.
+type invocation struct {
+	// algorithm and operation are empty in batch mode when every request
+	// names its own.
+	algorithm, operation string
+	input                string
+	inputFormat          string // json, yaml or toml
+	batch                bool
+	format               string // wire format of --batch and --listen
+	serve                bool
+	grpc                 string   // address of the gRPC mode
+	origins              []string // web origins allowed on /ws besides the server's
+	listen               string   // unix:<path> of the socket mode
+	remote               bool     // requests come from a peer of a server mode
+	metrics              string   // address of /metrics for --serve-stdio and --listen
+	selfTest             bool     // run wrapper selftest before the mode
+	seed                 *int64   // seed of the insecure test randomness
+	// fields are set by field flags and override those of the input.
+	fields map[string]interface{}
+	help   bool
+}
+
+func parseArgs(args []string) (*invocation, error) {
+	inv := &invocation{}
+	var names []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		names = append(names, strings.ToLower(args[0]))
+		args = args[1:]
+	}
+	if len(names) > 0 && names[0] == "help" {
+		inv.help, names = true, names[1:]
+	}
+	if len(names) > 2 {
+		return nil, errors.New(usage)
+	}
+	showVersion := len(names) == 1 && names[0] == "version"
+	if showVersion {
+		names = nil
+	}
+	if len(names) > 0 {
+		inv.algorithm = names[0]
+	}
+	if len(names) > 1 {
+		inv.operation = names[1]
+		var err error
+		if inv.fields, args, err = fieldFlags(inv.algorithm, inv.operation, args); err != nil {
+			return nil, err
+		}
+	}
+	fs := flag.NewFlagSet("wrapper", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&inv.input, "input", "-", "JSON request object, or - for standard input")
+	fs.StringVar(&inv.inputFormat, "input-format", "json", "format of the request: json, yaml or toml")
+	fs.BoolVar(&inv.batch, "batch", false, "answer one JSON request per line of standard input")
+	fs.StringVar(&inv.format, "format", formatJSON, "wire format of --batch and --listen: json, cbor or protobuf")
+	fs.BoolVar(&inv.serve, "serve-stdio", false, "serve JSON-RPC 2.0 on standard input and output")
+	fs.StringVar(&inv.grpc, "grpc", "", "serve gRPC on this address")
+	fs.Func("allow-origin", "let web pages of this origin use /ws of --grpc (repeatable)", func(s string) error {
+		inv.origins = append(inv.origins, s)
+		return nil
+	})
+	fs.StringVar(&inv.listen, "listen", "", "serve framed JSON requests on unix:<path>")
+	fs.StringVar(&inv.metrics, "metrics", "", "serve Prometheus metrics on this address")
+	fs.BoolVar(&inv.help, "help", inv.help, "describe the wrapper, an algorithm or an operation")
+	fs.BoolVar(&inv.help, "h", inv.help, "same as --help")
+	fs.BoolVar(&showVersion, "version", showVersion, "same as wrapper version")
+	fs.BoolVar(&inv.selfTest, "selftest", false, "run the known answer tests first, and stop if one fails")
+	fs.Func("seed", "INSECURE, for tests: derive keys, IVs and nonces from this seed", func(s string) error {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return errors.New("not an integer")
+		}
+		inv.seed = &n
+		return nil
+	})
+	if err := fs.Parse(args); err != nil {
+		return nil, fmt.Errorf("%v; %s", err, usage)
+	}
+	if showVersion && !inv.help {
+		if inv.algorithm != "" || fs.NArg() > 0 {
+			return nil, errors.New(usage)
+		}
+		// The version takes no request, so standard input is not read.
+		inv.algorithm, inv.operation, inv.input = "wrapper", "version", "{}"
+	}
+	if inv.help {
+		return inv, nil
+	}
+	if inv.selfTest && inv.algorithm == "" && !inv.batch && !inv.serve && inv.grpc == "" && inv.listen == "" && fs.NArg() == 0 {
+		// On its own, --selftest is the request.
+		inv.algorithm, inv.operation, inv.input = "wrapper", "selftest", "{}"
+	}
+	if inv.algorithm != "" && inv.operation == "" {
+		return nil, errors.New(usage)
+	}
+	servers := 0
+	for _, on := range []bool{inv.serve, inv.grpc != "", inv.listen != ""} {
+		if on {
+			servers++
+		}
+	}
+	server := servers > 0
+	if fs.NArg() > 0 || (inv.algorithm == "" && !inv.batch && !server) {
+		return nil, errors.New(usage)
+	}
+	if (inv.batch || server) && inv.input != "-" {
+		return nil, errors.New("--batch and the server modes read their own requests; --input does not apply")
+	}
+	if _, ok := inputFormats[inv.inputFormat]; !ok && inv.inputFormat != "json" {
+		return nil, fmt.Errorf("unknown --input-format %q: want json, yaml or toml", inv.inputFormat)
+	}
+	if (inv.batch || server) && inv.inputFormat != "json" {
+		return nil, errors.New("--batch and the server modes read JSON requests; --input-format does not apply")
+	}
+	switch inv.format {
+	case formatJSON, formatCBOR, formatProtobuf:
+	default:
+		return nil, fmt.Errorf("unknown --format %q: want json, cbor or protobuf", inv.format)
+	}
+	if inv.format != formatJSON && !inv.batch && (inv.listen == "" || inv.format == formatProtobuf) {
+		return nil, errors.New("--format applies to --batch, and cbor to --listen too; JSON-RPC and gRPC fix their own encodings")
+	}
+	if server && (inv.batch || inv.algorithm != "" || servers > 1) {
+		return nil, errors.New("--serve-stdio, --grpc and --listen take the operation from each call and exclude other modes")
+	}
+	if len(inv.origins) > 0 && inv.grpc == "" {
+		return nil, errors.New("--allow-origin applies to --grpc")
+	}
+	if inv.metrics != "" && !inv.serve && inv.listen == "" {
+		return nil, errors.New("--metrics applies to --serve-stdio and --listen; --grpc serves /metrics itself")
+	}
+	if len(inv.fields) > 0 {
+		if inv.batch {
+			return nil, errors.New("field flags apply to a single request, not to --batch")
+		}
+		// The flags are the request unless --input is given too.
+		explicit := false
+		fs.Visit(func(f *flag.Flag) { explicit = explicit || f.Name == "input" })
+		if !explicit {
+			inv.input, inv.inputFormat = "{}", "json"
+		}
+	}
+	return inv, nil
+}
+
+func execute(inv *invocation) *Result {
+	var in map[string]interface{}
+	var err error
+	switch {
+	case inv.inputFormat != "json":
+		in, err = decodeInput(inv)
+	case inv.input != "-":
+		in, _, err = readInput(strings.NewReader(inv.input), false)
+	case isTerminal(stdin):
+		// Without --input nobody is going to type a request.
+		in = map[string]interface{}{}
+	default:
+		var rest io.Reader
+		in, rest, err = readInput(stdin, true)
+		saved := stdin
+		defer func() { stdin = saved }()
+		stdin = rest
+	}
+	if err != nil {
+		return errorResult(err)
+	}
+	for k, v := range inv.fields {
+		in[k] = v
+	}
+	return dispatch(inv.algorithm, inv.operation, in)
+}
+
+// dispatch runs one decoded request, with the defaults of its algorithm,
+// and records it in metrics. Requests for unknown operations are counted
+// under "unknown" to bound the number of series.
+func dispatch(algorithm, operation string, in map[string]interface{}) *Result {
+	return dispatchFrom(algorithm, operation, in, false)
+}
+
+// serverDispatch is dispatch for the requests of the server modes, whose
+// peers must not reach the files or standard input of the wrapper: a
+// request with one of localFields fails with ERR_INVALID_FIELD.
+func serverDispatch(algorithm, operation string, in map[string]interface{}) *Result {
+	return dispatchFrom(algorithm, operation, in, true)
+}
+
+func dispatchFrom(algorithm, operation string, in map[string]interface{}, remote bool) *Result {
+	start := time.Now()
+	h, err := lookup(algorithm, operation)
+	if err != nil {
+		res := errorResult(err)
+		metrics.observe("unknown", "unknown", res, time.Since(start))
+		return res
+	}
+	var res *Result
+	if remote {
+		if err := checkRemote(in); err != nil {
+			res = errorResult(err)
+		}
+	}
+	if res == nil {
+		if in != nil {
+			defaults.apply(algorithm, in)
+		}
+		res = runHandler(algorithm, operation, h, in)
+	}
+	metrics.observe(algorithm, operation, res, time.Since(start))
+	return res
+}
+
+// runHandler validates in against the schema of the operation and runs
+// its handler h, without defaults or metrics.
+func runHandler(algorithm, operation string, h handler, in map[string]interface{}) *Result {
+	res, err := (*Result)(nil), schemas()[[2]string{algorithm, operation}].validate(in)
+	if err == nil {
+		res, err = withOutputEncoding(algorithm, operation, withFiles(algorithm, operation, h))(in)
+	}
+	if err != nil {
+		return errorResult(err)
+	}
+	res.Status = statusSuccess
+	return res
+}
+
+// readInput decodes the request object at the start of r; from standard
+// input, empty input stands for {}. It returns a reader of what follows
+// the object, less one newline: the payload of operations that take
+// "stdin": true. Nothing beyond the object is read until then.
+func readInput(r io.Reader, allowEmpty bool) (map[string]interface{}, io.Reader, error) {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var in map[string]interface{}
+	if err := dec.Decode(&in); err == io.EOF && allowEmpty {
+		return map[string]interface{}{}, strings.NewReader(""), nil
+	} else if err != nil {
+		return nil, nil, &codedError{codeInvalidRequest, fmt.Errorf("invalid --input JSON: %v", err)}
+	}
+	if in == nil {
+		return nil, nil, &codedError{codeInvalidRequest, errors.New("invalid --input JSON: expected an object")}
+	}
+	return in, &payloadReader{r: bufio.NewReader(io.MultiReader(dec.Buffered(), r))}, nil
+}
+
+// inputFormats are the parsers of --input-format other than JSON.
+var inputFormats = map[string]func(src, name string) (map[string]interface{}, error){
+	"yaml": parseYAML,
+	"toml": parseTOML,
+}
+
+// decodeInput reads the whole request of inv in the format of
+// --input-format. Unlike a JSON request, it leaves nothing on standard
+// input for "stdin": true to read, so that field is rejected.
+func decodeInput(inv *invocation) (map[string]interface{}, error) {
+	src, name := inv.input, "--input"
+	if inv.input == "-" {
+		name = "standard input"
+		if isTerminal(stdin) {
+			return map[string]interface{}{}, nil
+		}
+		b, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, &codedError{codeIO, fmt.Errorf("reading standard input: %v", err)}
+		}
+		src = string(b)
+	}
+	in, err := inputFormats[inv.inputFormat](src, name)
+	if err != nil {
+		return nil, &codedError{codeInvalidRequest, fmt.Errorf("invalid %s request: %v", strings.ToUpper(inv.inputFormat), err)}
+	}
+	if v, ok := in["stdin"].(bool); ok && v {
+		return nil, &codedError{codeInvalidRequest, errors.New(`"stdin": true needs a JSON request, which can be followed by the payload`)}
+	}
+	return in, nil
+}
+
+// payloadReader skips one newline before the payload on its first Read.
+type payloadReader struct {
+	r       *bufio.Reader
+	started bool
+}
+
+func (p *payloadReader) Read(b []byte) (int, error) {
+	if !p.started {
+		p.started = true
+		if c, err := p.r.ReadByte(); err == nil && c != '\n' {
+			p.r.UnreadByte()
+		}
+	}
+	return p.r.Read(b)
+}
+
+// isTerminal reports whether r is a character device such as a terminal.
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
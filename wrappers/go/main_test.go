@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// call runs the CLI with in as --input and decodes the Result.
+func call(t *testing.T, algorithm, operation string, in map[string]interface{}) (*Result, int) {
+	t.Helper()
+	input, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	code := run([]string{algorithm, operation, "--input", string(input)}, &out)
+	var res Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatalf("output is not JSON: %q", out.String())
+	}
+	return &res, code
+}
+
+// mustCall is call for requests that are expected to succeed. Failed
+// verifications succeed too, with exit code 5.
+func mustCall(t *testing.T, algorithm, operation string, in map[string]interface{}) *Result {
+	t.Helper()
+	res, code := call(t, algorithm, operation, in)
+	want := exitSuccess
+	if res.Valid != nil && !*res.Valid {
+		want = exitInvalid
+	}
+	if code != want || res.Status != statusSuccess {
+		t.Fatalf("%s %s failed (exit %d): %s", algorithm, operation, code, res.Message)
+	}
+	return res
+}
+
+// mustFail is call for requests that are expected to be rejected.
+func mustFail(t *testing.T, algorithm, operation string, in map[string]interface{}) *Result {
+	t.Helper()
+	res, code := call(t, algorithm, operation, in)
+	if code == 0 || res.Status != statusError || res.Message == "" {
+		t.Fatalf("%s %s succeeded unexpectedly: %+v", algorithm, operation, res)
+	}
+	return res
+}
+
+func TestUsageErrors(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"sm3"},
+		{"sm3", "hash", "--input", "not json"},
+		{"sm3", "hash", "--input", "[]"},
+		{"sm3", "hash", "--bogus"},
+		{"sm5", "hash", "--input", "{}"},
+		{"sm3", "digest", "--input", "{}"},
+		{"sm3", "hash", "--metrics", "127.0.0.1:0"},
+		{"--grpc", "127.0.0.1:0", "--metrics", "127.0.0.1:0"},
+		{"--batch", "--format", "xml"},
+		{"--serve-stdio", "--format", "cbor"},
+		{"--listen", "unix:/nonexistent/s", "--format", "protobuf"},
+		{"sm3", "hash", "--format", "cbor", "--input", "{}"},
+	} {
+		var out bytes.Buffer
+		if code := run(args, &out); code == 0 {
+			t.Errorf("run(%q) exited 0", args)
+		}
+		var res Result
+		if err := json.Unmarshal(out.Bytes(), &res); err != nil || res.Status != statusError {
+			t.Errorf("run(%q) = %q, want an error result", args, out.String())
+		}
+	}
+}
+
+func TestInputFromStdin(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+	}{
+		{[]string{"sm3", "hash"}, `{"data": "abc"}`},
+		{[]string{"sm3", "hash", "--input", "-"}, "{\"data\": \"abc\"}\n"},
+		// The payload of "stdin": true follows the request.
+		{[]string{"sm3", "hash"}, "{\"stdin\": true}\nabc"},
+		{[]string{"sm3", "hash", "--input", "-"}, `{"stdin": true}abc`},
+	} {
+		stdin = strings.NewReader(tc.stdin)
+		var out bytes.Buffer
+		var res Result
+		if code := run(tc.args, &out); code != 0 || json.Unmarshal(out.Bytes(), &res) != nil || res.Output != abc {
+			t.Errorf("%q with %q: %s", tc.args, tc.stdin, out.String())
+		}
+	}
+
+	// Empty input is an empty request.
+	stdin = strings.NewReader("")
+	var out bytes.Buffer
+	if code := run([]string{"sm2", "keygen"}, &out); code != 0 {
+		t.Errorf("keygen without input: %s", out.String())
+	}
+	stdin = strings.NewReader("[1]")
+	if code := run([]string{"sm3", "hash", "--input", "-"}, &out); code == 0 {
+		t.Error("a JSON array on stdin was accepted")
+	}
+}
+
+func TestInputFormat(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+	}{
+		{[]string{"sm3", "hash", "--input-format", "yaml"}, "---\ndata: abc # comment\n"},
+		{[]string{"sm3", "hash", "--input-format=toml"}, "data = 'abc'\n"},
+		{[]string{"sm3", "hash", "--input-format", "yaml", "--input", "data: |-\n  abc"}, ""},
+		{[]string{"sm3", "hash", "--input-format", "json"}, `{"data": "abc"}`},
+	} {
+		stdin = strings.NewReader(tc.stdin)
+		var out bytes.Buffer
+		var res Result
+		if code := run(tc.args, &out); code != 0 || json.Unmarshal(out.Bytes(), &res) != nil || res.Output != abc {
+			t.Errorf("%q with %q: %s", tc.args, tc.stdin, out.String())
+		}
+	}
+
+	for _, tc := range []struct {
+		args  []string
+		stdin string
+		code  string
+	}{
+		{[]string{"sm3", "hash", "--input-format", "xml"}, "", codeUsage},
+		{[]string{"--batch", "--input-format", "yaml"}, "", codeUsage},
+		{[]string{"sm3", "hash", "--input-format", "yaml"}, "data: [abc\n", codeInvalidRequest},
+		{[]string{"sm3", "hash", "--input-format", "toml"}, "stdin = true\n", codeInvalidRequest},
+	} {
+		stdin = strings.NewReader(tc.stdin)
+		var out bytes.Buffer
+		var res Result
+		if code := run(tc.args, &out); code == 0 || json.Unmarshal(out.Bytes(), &res) != nil || res.ErrorCode != tc.code {
+			t.Errorf("%q with %q: %s, want %s", tc.args, tc.stdin, out.String(), tc.code)
+		}
+	}
+}
+
+func TestSM3Hash(t *testing.T) {
+	res := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc"})
+	if want := "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"; res.Output != want {
+		t.Fatalf("output = %s, want %s", res.Output, want)
+	}
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": 42})
+}
+
+func TestExitCodes(t *testing.T) {
+	keys := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	sig := mustCall(t, "sm2", "sign", map[string]interface{}{"private_key": keys.PrivateKey, "message": "abc"}).Output
+	for _, c := range []struct {
+		args []string
+		want int
+	}{
+		{[]string{"sm3", "hash", "--input", `{"data": "abc"}`}, exitSuccess},
+		{[]string{"sm3", "hash", "--bogus"}, exitUsage},
+		{[]string{"sm5", "hash", "--input", "{}"}, exitUsage},
+		{[]string{"sm3", "hash", "--input", "[]"}, exitBadInput},
+		{[]string{"sm4", "encrypt", "--input", `{"key": "zz", "plaintext": "x"}`}, exitBadInput},
+		{[]string{"sm4", "decrypt", "--input", `{"key": "` + testSM4Key + `", "mode": "CBC", "iv": "` + strings.Repeat("00", 16) + `", "ciphertext": "` + strings.Repeat("00", 32) + `"}`}, exitCryptoFailure},
+		{[]string{"sm2", "verify", "--input", `{"public_key": "` + keys.PublicKey + `", "message": "abc", "signature": "` + sig + `"}`}, exitSuccess},
+		{[]string{"sm2", "verify", "--input", `{"public_key": "` + keys.PublicKey + `", "message": "abd", "signature": "` + sig + `"}`}, exitInvalid},
+		{[]string{"sm3", "hash", "--input", `{"input_file": "` + filepath.Join(t.TempDir(), "missing") + `"}`}, exitFailure},
+		// Malformed requests that pass the schema are bad input too.
+		{[]string{"sm2", "validate-key", "--input", "{}"}, exitBadInput},
+		{[]string{"sm3", "hash", "--input", `{"data_list": ["a"], "per_item": true, "expected": "00"}`}, exitBadInput},
+		{[]string{"sm4", "encrypt-init", "--input", `{"key": "` + testSM4Key + `", "mode": "GCM"}`}, exitBadInput},
+		{[]string{"sm2", "jwt-sign", "--input", `{"claims": "x"}`}, exitBadInput},
+		{[]string{"sm2", "keygen", "--input", `{"input_file": "x"}`}, exitBadInput},
+	} {
+		var out bytes.Buffer
+		if code := run(c.args, &out); code != c.want {
+			t.Errorf("run(%q) exited %d, want %d: %s", c.args, code, c.want, out.String())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// latencyBuckets are the upper bounds, in seconds, of the request
+// duration histogram.
+var latencyBuckets = []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// opStats are the metrics of one operation.
+type opStats struct {
+	success uint64
+	errors  map[string]uint64 // by error code
+	buckets []uint64          // per bucket, not cumulative; the last is +Inf
+	sum     float64
+}
+
+// metricsRegistry collects per-operation metrics of every request that
+// goes through dispatch and renders them in the Prometheus text format.
+type metricsRegistry struct {
+	mu  sync.Mutex
+	ops map[[2]string]*opStats
+}
+
+var metrics = &metricsRegistry{ops: map[[2]string]*opStats{}}
+
+// observe records a request that took d.
+func (m *metricsRegistry) observe(algorithm, operation string, res *Result, d time.Duration) {
+	key := [2]string{algorithm, operation}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.ops[key]
+	if s == nil {
+		s = &opStats{errors: map[string]uint64{}, buckets: make([]uint64, len(latencyBuckets)+1)}
+		m.ops[key] = s
+	}
+	if res.Status == statusSuccess {
+		s.success++
+	} else {
+		s.errors[res.ErrorCode]++
+	}
+	secs := d.Seconds()
+	s.sum += secs
+	s.buckets[sort.SearchFloat64s(latencyBuckets, secs)]++
+}
+
+// serveMetrics serves /metrics on addr in the background, for the server
+// modes without an HTTP server of their own. The listening event goes to
+// stderr.
+func serveMetrics(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	json.NewEncoder(stderr).Encode(&serverEvent{Event: "listening", Time: now(), Address: ln.Addr().String(), Protocol: "metrics"})
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics)
+	go http.Serve(ln, mux)
+	return nil
+}
+
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.write(w)
+}
+
+// write renders the metrics in the Prometheus text exposition format.
+func (m *metricsRegistry) write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([][2]string, 0, len(m.ops))
+	for k := range m.ops {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i][0] < keys[j][0] || keys[i][0] == keys[j][0] && keys[i][1] < keys[j][1]
+	})
+	labels := func(k [2]string) string {
+		return fmt.Sprintf("algorithm=%q,operation=%q", k[0], k[1])
+	}
+	float := func(f float64) string { return strconv.FormatFloat(f, 'g', -1, 64) }
+
+	var b strings.Builder
+	b.WriteString("# HELP wrapper_requests_total Requests by operation and status.\n")
+	b.WriteString("# TYPE wrapper_requests_total counter\n")
+	for _, k := range keys {
+		s := m.ops[k]
+		var failed uint64
+		for _, n := range s.errors {
+			failed += n
+		}
+		fmt.Fprintf(&b, "wrapper_requests_total{%s,status=\"success\"} %d\n", labels(k), s.success)
+		fmt.Fprintf(&b, "wrapper_requests_total{%s,status=\"error\"} %d\n", labels(k), failed)
+	}
+	b.WriteString("# HELP wrapper_errors_total Failed requests by operation and error code.\n")
+	b.WriteString("# TYPE wrapper_errors_total counter\n")
+	for _, k := range keys {
+		s := m.ops[k]
+		for _, code := range sortedKeys(s.errors) {
+			fmt.Fprintf(&b, "wrapper_errors_total{%s,error_code=%q} %d\n", labels(k), code, s.errors[code])
+		}
+	}
+	b.WriteString("# HELP wrapper_request_duration_seconds Request latency by operation.\n")
+	b.WriteString("# TYPE wrapper_request_duration_seconds histogram\n")
+	for _, k := range keys {
+		s := m.ops[k]
+		var cum uint64
+		for i, n := range s.buckets {
+			cum += n
+			le := "+Inf"
+			if i < len(latencyBuckets) {
+				le = float(latencyBuckets[i])
+			}
+			fmt.Fprintf(&b, "wrapper_request_duration_seconds_bucket{%s,le=%q} %d\n", labels(k), le, cum)
+		}
+		fmt.Fprintf(&b, "wrapper_request_duration_seconds_sum{%s} %s\n", labels(k), float(s.sum))
+		fmt.Fprintf(&b, "wrapper_request_duration_seconds_count{%s} %d\n", labels(k), cum)
+	}
+	io.WriteString(w, b.String())
+}
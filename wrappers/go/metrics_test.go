@@ -0,0 +1,52 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	saved := metrics
+	defer func() { metrics = saved }()
+	metrics = &metricsRegistry{ops: map[[2]string]*opStats{}}
+
+	for i := 0; i < 3; i++ {
+		dispatch("sm3", "hash", map[string]interface{}{"data": "abc"})
+	}
+	dispatch("sm4", "encrypt", map[string]interface{}{"key": "00", "plaintext": "x"})
+	dispatch("sm5", "hash", map[string]interface{}{})
+
+	srv := httptest.NewServer(newHTTPHandler(nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q", ct)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := string(b)
+	for _, want := range []string{
+		"# TYPE wrapper_requests_total counter\n",
+		`wrapper_requests_total{algorithm="sm3",operation="hash",status="success"} 3` + "\n",
+		`wrapper_requests_total{algorithm="sm3",operation="hash",status="error"} 0` + "\n",
+		`wrapper_requests_total{algorithm="sm4",operation="encrypt",status="error"} 1` + "\n",
+		`wrapper_requests_total{algorithm="unknown",operation="unknown",status="error"} 1` + "\n",
+		`wrapper_errors_total{algorithm="sm4",operation="encrypt",error_code="ERR_INVALID_KEY_LENGTH"} 1` + "\n",
+		"# TYPE wrapper_request_duration_seconds histogram\n",
+		`wrapper_request_duration_seconds_bucket{algorithm="sm3",operation="hash",le="+Inf"} 3` + "\n",
+		`wrapper_request_duration_seconds_count{algorithm="sm3",operation="hash"} 3` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics lack %q:\n%s", want, body)
+		}
+	}
+}
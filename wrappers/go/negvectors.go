@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// negativeGenerators build negative vector i of an algorithm: a valid
+// request with one part corrupted, and the error class every wrapper is
+// expected to reject it with. SM3 has no input to reject.
+var negativeGenerators = map[string]func(g *vectorGen, i int) (*vector, error){
+	"sm2": sm2NegativeVector,
+	"sm4": sm4NegativeVector,
+	"zuc": zucNegativeVector,
+}
+
+// rejected is the expected result of a request that fails with code.
+func rejected(code string) map[string]interface{} {
+	return map[string]interface{}{"status": statusError, "error_code": code}
+}
+
+// invalid is the expected result of a verification that fails.
+func invalid() map[string]interface{} {
+	return map[string]interface{}{"status": statusSuccess, "valid": false}
+}
+
+// reject makes a negative vector of the request in, corrupted as tamper
+// describes. The wrapper must give the expected result for it, so that
+// every vector it generates is one it passes itself.
+func (g *vectorGen) reject(algorithm, operation string, in map[string]interface{}, tamper string, expected map[string]interface{}) (*vector, error) {
+	v := &vector{Algorithm: algorithm, Operation: operation, Tamper: tamper, Request: in, Expected: expected}
+	if r := checkVector(v, dispatch(algorithm, operation, maps.Clone(in))); !r.Passed {
+		return nil, fmt.Errorf("%s %s with %s: %+v", algorithm, operation, tamper, r.Diffs)
+	}
+	return v, nil
+}
+
+// flipBit flips a random bit of the hex string s in the bytes from
+// offset on.
+func (g *vectorGen) flipBit(s interface{}, offset int) string {
+	b, err := hex.DecodeString(s.(string))
+	if err != nil {
+		panic(err)
+	}
+	b[offset+g.r.IntN(len(b)-offset)] ^= 1 << g.r.IntN(8)
+	return hex.EncodeToString(b)
+}
+
+// offCurve returns the uncompressed point p with the low bit of y
+// flipped, which takes it off the curve.
+func offCurve(p interface{}) string {
+	b, err := hex.DecodeString(p.(string))
+	if err != nil {
+		panic(err)
+	}
+	b[64] ^= 1
+	return hex.EncodeToString(b)
+}
+
+// sm4NegativeVector cycles through a flipped GCM ciphertext bit, a
+// flipped CCM tag bit, wrong CBC padding, a truncated ECB ciphertext and
+// a flipped CMAC bit.
+func sm4NegativeVector(g *vectorGen, i int) (*vector, error) {
+	key := g.hex(16)
+	n := max(g.length(i), 1)
+	switch i % 5 {
+	case 0, 1:
+		mode := []string{"GCM", "CCM"}[i%5]
+		enc, err := g.run("sm4", "encrypt", map[string]interface{}{"key": key, "mode": mode, "iv": g.hex(12), "plaintext_hex": g.hex(n)})
+		if err != nil {
+			return nil, err
+		}
+		in := map[string]interface{}{"key": key, "mode": mode, "iv": enc.Request["iv"], "ciphertext": enc.Expected["output"],
+			"tag": enc.Expected["tag"], "plaintext_encoding": encodingHex}
+		tamper := "flipped ciphertext bit"
+		if mode == "CCM" {
+			in["tag"], tamper = g.flipBit(in["tag"], 0), "flipped tag bit"
+		} else {
+			in["ciphertext"] = g.flipBit(in["ciphertext"], 0)
+		}
+		return g.reject("sm4", "decrypt", in, tamper, rejected(codeAuthenticationFailed))
+	case 2:
+		// A last byte of zero is not PKCS #7 padding.
+		data := g.bytes((n + 15) / 16 * 16)
+		data[len(data)-1] = 0
+		iv := g.hex(16)
+		enc, err := g.run("sm4", "encrypt", map[string]interface{}{"key": key, "mode": "CBC", "iv": iv, "padding": paddingNone, "plaintext_hex": hex.EncodeToString(data)})
+		if err != nil {
+			return nil, err
+		}
+		in := map[string]interface{}{"key": key, "mode": "CBC", "iv": iv, "ciphertext": enc.Expected["output"], "plaintext_encoding": encodingHex}
+		return g.reject("sm4", "decrypt", in, "wrong padding", rejected(codeBadPadding))
+	case 3:
+		enc, err := g.run("sm4", "encrypt", map[string]interface{}{"key": key, "mode": "ECB", "plaintext_hex": g.hex(n)})
+		if err != nil {
+			return nil, err
+		}
+		ct := enc.Expected["output"].(string)
+		in := map[string]interface{}{"key": key, "mode": "ECB", "ciphertext": ct[:len(ct)-2], "plaintext_encoding": encodingHex}
+		return g.reject("sm4", "decrypt", in, "truncated ciphertext", rejected(codeInvalidDataLength))
+	}
+	data := g.text(n)
+	mac, err := g.run("sm4", "cmac", map[string]interface{}{"key": key, "data": data})
+	if err != nil {
+		return nil, err
+	}
+	in := map[string]interface{}{"key": key, "data": data, "mac": g.flipBit(mac.Expected["output"], 0)}
+	return g.reject("sm4", "cmac", in, "flipped MAC bit", invalid())
+}
+
+// sm2NegativeVector cycles through a flipped signature bit, a truncated
+// signature, an off-curve public key, a flipped ciphertext bit and an
+// off-curve C1, each with a new key.
+func sm2NegativeVector(g *vectorGen, i int) (*vector, error) {
+	kp, err := g.run("sm2", "keygen", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	priv, pub := kp.Expected["private_key"], kp.Expected["public_key"]
+	msg := g.text(max(g.length(i), 1))
+	if i%5 < 3 {
+		format := sigFormatRS
+		if i%5 == 1 {
+			format = sigFormatDER
+		}
+		sig, err := g.run("sm2", "sign", map[string]interface{}{"message": msg, "private_key": priv, "signature_format": format})
+		if err != nil {
+			return nil, err
+		}
+		in := map[string]interface{}{"message": msg, "public_key": pub, "signature": sig.Expected["output"], "signature_format": format}
+		switch i % 5 {
+		case 0:
+			in["signature"] = g.flipBit(in["signature"], 0)
+			return g.reject("sm2", "verify", in, "flipped signature bit", invalid())
+		case 1:
+			s := in["signature"].(string)
+			in["signature"] = s[:2*(1+g.r.IntN(len(s)/2-1))]
+			return g.reject("sm2", "verify", in, "truncated signature", invalid())
+		}
+		in["public_key"] = offCurve(pub)
+		return g.reject("sm2", "verify", in, "off-curve public key", rejected(codePointNotOnCurve))
+	}
+	ct, err := g.run("sm2", "encrypt", map[string]interface{}{"plaintext": msg, "public_key": pub})
+	if err != nil {
+		return nil, err
+	}
+	in := map[string]interface{}{"ciphertext": ct.Expected["output"], "private_key": priv, "ciphertext_format": ctFormatC1C3C2}
+	if i%5 == 3 {
+		// Past C1, in C3 or C2.
+		in["ciphertext"] = g.flipBit(in["ciphertext"], sm2C1Size)
+		return g.reject("sm2", "decrypt", in, "flipped ciphertext bit", rejected(codeDecryptionFailed))
+	}
+	in["ciphertext"] = offCurve(in["ciphertext"])
+	return g.reject("sm2", "decrypt", in, "off-curve C1", rejected(codePointNotOnCurve))
+}
+
+// zucNegativeVector alternates between flipped 128-EIA3 and ZUC-256 MAC
+// bits.
+func zucNegativeVector(g *vectorGen, i int) (*vector, error) {
+	in := map[string]interface{}{"data_hex": g.hex(g.length(i))}
+	if i%2 == 0 {
+		in["key"] = g.hex(16)
+		in["count"] = json.Number(fmt.Sprint(g.r.Uint32()))
+		in["bearer"] = json.Number(fmt.Sprint(g.r.IntN(32)))
+		in["direction"] = json.Number(fmt.Sprint(g.r.IntN(2)))
+	} else {
+		in["key"], in["iv"] = g.hex(32), g.hex(23)
+	}
+	mac, err := g.run("zuc", "mac", maps.Clone(in))
+	if err != nil {
+		return nil, err
+	}
+	in["mac"] = g.flipBit(mac.Expected["output"], 0)
+	return g.reject("zuc", "mac", in, "flipped MAC bit", invalid())
+}
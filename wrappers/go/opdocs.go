@@ -0,0 +1,126 @@
+package main
+
+// opDocs documents the result of every operation in handlers for its
+// --help text, as in the operations table of the README. Field names are
+// quoted in backticks. The request fields come from requestFields.
+var opDocs = map[string]map[string]string{
+	"sm3": {
+		"hash":   "`output` (digest), or `valid` when `expected` is given",
+		"hmac":   "`output` (HMAC-SM3), or `valid` when `mac` is given",
+		"kdf":    "`output` (GB/T 32918 KDF output)",
+		"pbkdf2": "`output` (PBKDF2-HMAC-SM3 key)",
+		"hkdf":   "`output` (HKDF-SM3 key, or PRK for `extract`)",
+		"mgf1":   "`output` (MGF1-SM3 mask)",
+		"init":   "`context`",
+		"update": "`context`",
+		"final":  "`output` (digest)",
+	},
+	"sm4": {
+		"encrypt":        "`output` (ciphertext), `iv` if generated",
+		"decrypt":        "`output` (plaintext)",
+		"wrap":           "`output` (RFC 3394 wrapped key)",
+		"unwrap":         "`output` (key data)",
+		"cmac":           "`output` (MAC), or `valid` when `mac` is given",
+		"cbcmac":         "`output` (MAC), or `valid` when `mac` is given",
+		"cose-encrypt":   "`output` (COSE_Encrypt0, hex CBOR)",
+		"cose-decrypt":   "`output` (plaintext)",
+		"encrypt-init":   "`context`, `iv` if generated",
+		"encrypt-update": "`output` (ciphertext so far), `context`",
+		"encrypt-final":  "`output` (rest of the ciphertext)",
+		"decrypt-init":   "`context`, `iv` if generated",
+		"decrypt-update": "`output` (plaintext so far), `context`",
+		"decrypt-final":  "`output` (rest of the plaintext)",
+	},
+	"zuc": {
+		"encrypt":   "`output` (ciphertext)",
+		"decrypt":   "`output` (plaintext)",
+		"mac":       "`output` (MAC), or `valid` when `mac` is given",
+		"keystream": "`output` (ZUC keystream)",
+	},
+	"sm2": {
+		"keygen":               "`private_key`, `public_key`, `public_key_compressed`, `fingerprint` (SM3 of `public_key`)",
+		"derive-pub":           "`public_key`, `public_key_compressed`, `fingerprint`",
+		"validate-key":         "`valid`, `reason` when invalid",
+		"sign":                 "`output` (signature), `public_key`, `private_key` if generated",
+		"verify":               "`valid`",
+		"verify-batch":         "`valid` (all items), `valid_items`",
+		"recover-pub":          "`outputs` (candidate public keys)",
+		"envelope-encrypt":     "`output` (DER EnvelopedData)",
+		"envelope-decrypt":     "`output` (plaintext)",
+		"cms-encrypt":          "`output` (DER CMS EnvelopedData or AuthEnvelopedData)",
+		"cms-decrypt":          "`output` (plaintext)",
+		"cosign-keygen-client": "`private_key` (d1), `public_share` (P1)",
+		"cosign-keygen-server": "`private_key` (d2), `public_key` (joint key)",
+		"cosign-sign-client":   "`output` (digest e), `ephemeral_private_key` (k1), `ephemeral_public_key` (Q1)",
+		"cosign-sign-server":   "`output` (partial signature)",
+		"cosign-sign-finish":   "`output` (signature)",
+		"key-split":            "`outputs` (shares), `fingerprint`",
+		"key-combine":          "`private_key`, `public_key`, `fingerprint`",
+		"p7-sign":              "`output` (DER SignedData)",
+		"p7-verify":            "`valid`, `output` (content)",
+		"cert-selfsign":        "`output` (DER certificate), key pair if generated",
+		"cert-issue":           "`output` (DER certificate)",
+		"cert-verify-chain":    "`valid`, `outputs` (chain subjects), or `reason` and `output` (failing subject)",
+		"cert-parse":           "`certificate` (object, see below)",
+		"csr-create":           "`output` (DER PKCS #10 request), key pair if generated",
+		"csr-verify":           "`valid`, `public_key`",
+		"crl-create":           "`output` (DER CRL)",
+		"crl-check":            "`valid`, or `reason`, `revocation_time` and `revocation_reason`",
+		"ocsp-request":         "`output` (DER OCSP request), `nonce`",
+		"ocsp-respond":         "`output` (DER OCSP response)",
+		"ocsp-verify":          "`valid`, or `reason`, `revocation_time` and `revocation_reason`",
+		"tsa-request":          "`output` (DER time-stamp request), `nonce`",
+		"tsa-sign":             "`output` (DER time-stamp response)",
+		"tsa-verify":           "`valid`, `gen_time` and `serial`, or `reason`",
+		"jws-sign":             "`output` (compact JWS), `public_key`, `private_key` if generated",
+		"jws-verify":           "`valid`, `header` and `output` (payload), or `reason`",
+		"jwt-sign":             "`output` (JWT), `public_key`, `private_key` if generated",
+		"jwt-verify":           "`valid`, `header` and `claims`, or `reason`",
+		"jwe-encrypt":          "`output` (compact JWE), key pair if generated",
+		"jwe-decrypt":          "`output` (plaintext), `header`",
+		"cose-sign":            "`output` (COSE_Sign1, hex CBOR), `public_key`, `private_key` if generated",
+		"cose-verify":          "`valid` and `output` (payload), or `reason`",
+		"p12-create":           "`output` (DER PKCS #12 file)",
+		"p12-parse":            "`private_key`, `public_key`, `outputs` (DER certificates), `friendly_name`",
+		"digest":               "`output` (e = SM3(ZA || M))",
+		"convert-signature":    "`output` (signature in the other format)",
+		"encrypt":              "`output` (ciphertext), key pair if generated",
+		"decrypt":              "`output` (plaintext)",
+		"keyexchange-init":     "`ephemeral_private_key`, `ephemeral_public_key`",
+		"keyexchange-respond":  "`output` (KB), `ephemeral_public_key`, `confirmation` (SB)",
+		"keyexchange-confirm":  "`output` (shared key), `valid`, `confirmation` (SA)",
+		"encapsulate":          "`output` (shared key), `encapsulation`, key pair if generated",
+		"decapsulate":          "`output` (shared key)",
+		"ecdh":                 "`output` (x-coordinate), `derived_key`",
+	},
+	"keystore": {
+		"create": "`output` (path)",
+		"list":   "`outputs` (entry names)",
+		"put":    "`key_type`, `public_key` for SM2",
+		"get":    "`key_type`, key pair (SM2) or `output` (SM4 key)",
+		"delete": "`output` (name)",
+	},
+	"sm9": {
+		"master-keygen": "`private_key` (ks or ke), `public_key` (Ppub-s or Ppub-e)",
+		"user-keygen":   "`private_key` (user key)",
+		"sign":          "`output` (signature)",
+		"verify":        "`valid`",
+		"encrypt":       "`output` (ciphertext)",
+		"decrypt":       "`output` (plaintext)",
+		"encapsulate":   "`output` (shared key), `encapsulation`",
+		"decapsulate":   "`output` (shared key)",
+	},
+	"tlcp": {
+		"client": "`protocol`, `cipher_suite`, `peer_certificates`, `output` (response)",
+		"server": "`output` (listening address), `handshakes`",
+	},
+	"tls13": {
+		"client": "`protocol`, `cipher_suite`, `peer_certificates`, `output` (response)",
+		"server": "`output` (listening address), `handshakes`",
+	},
+	"wrapper": {
+		"capabilities": "`capabilities`: `operations` (`algorithm`, `operation`, `files` when `input_file` and `output_file` apply, `required` fields), `sm4_modes`, `padding`, `encodings` (`input`, `output`), `options` (accepted values by algorithm and field)",
+		"selftest":     "`valid`, `output` (tests passed), `self_test` (`id`, `algorithm`, `operation`, `passed`, `diffs`, `standard` of each known answer test)",
+		"version":      "`output` (wrapper version), `version` (library version and commit, Go version, platform, `algorithms` with their operations, `sm4_modes`)",
+	},
+}
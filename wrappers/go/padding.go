@@ -0,0 +1,114 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Padding schemes accepted in the "padding" field of the block modes.
+const (
+	paddingPKCS7   = "pkcs7"
+	paddingZeros   = "zeros"
+	paddingISO7816 = "iso7816"
+	paddingNone    = "none"
+)
+
+var paddings = []string{paddingPKCS7, paddingZeros, paddingISO7816, paddingNone}
+
+var errPKCS7Padding = &codedError{codeBadPadding, errors.New("invalid PKCS#7 padding")}
+
+// checkPadding normalizes the padding scheme p; "" is PKCS#7.
+func checkPadding(p string) (string, error) {
+	if p == "" {
+		return paddingPKCS7, nil
+	}
+	if p = strings.ToLower(p); slices.Contains(paddings, p) {
+		return p, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported padding %q (supported: %s)", p, strings.Join(paddings, ", "))}
+}
+
+// pad extends data to a multiple of blockSize. PKCS#7 and ISO/IEC 7816-4
+// always add at least one byte; zero padding only fills the last partial
+// block, or emits one zero block for empty input as BouncyCastle's
+// ZeroBytePadding does, and "none" requires aligned input.
+func pad(scheme string, data []byte, blockSize int) ([]byte, error) {
+	n := blockSize - len(data)%blockSize
+	out := make([]byte, len(data), len(data)+n)
+	copy(out, data)
+	switch scheme {
+	case paddingPKCS7:
+		for i := 0; i < n; i++ {
+			out = append(out, byte(n))
+		}
+	case paddingISO7816:
+		out = append(out, 0x80)
+		out = append(out, make([]byte, n-1)...)
+	case paddingZeros:
+		if n != blockSize || len(data) == 0 {
+			out = append(out, make([]byte, n)...)
+		}
+	case paddingNone:
+		if n != blockSize {
+			return nil, &codedError{codeInvalidDataLength, fmt.Errorf("plaintext length %d is not a multiple of %d and padding is none", len(data), blockSize)}
+		}
+	}
+	return out, nil
+}
+
+// unpad removes the padding added by pad from data, a whole number of
+// blocks. Only the last block is examined, by unpadLastBlock.
+func unpad(scheme string, data []byte, blockSize int) ([]byte, error) {
+	if len(data)%blockSize != 0 {
+		return nil, &codedError{codeInvalidDataLength, fmt.Errorf("data length %d is not a multiple of %d", len(data), blockSize)}
+	}
+	k := max(len(data)-blockSize, 0)
+	last, err := unpadLastBlock(scheme, data[k:])
+	if err != nil {
+		return nil, err
+	}
+	return data[:k+len(last)], nil
+}
+
+// unpadLastBlock removes the padding added by pad from last, the final
+// block of a message, or nothing for an empty message. The in-memory,
+// file and incremental paths all unpad through it, so they agree on
+// ambiguous input. Zero padding is ambiguous: as in BouncyCastle's
+// ZeroBytePadding, the trailing zero bytes of the last block are removed,
+// and zeros in earlier blocks are plaintext.
+func unpadLastBlock(scheme string, last []byte) ([]byte, error) {
+	switch scheme {
+	case paddingPKCS7:
+		if len(last) == 0 {
+			return nil, errPKCS7Padding
+		}
+		n := int(last[len(last)-1])
+		if n == 0 || n > len(last) {
+			return nil, errPKCS7Padding
+		}
+		for _, b := range last[len(last)-n:] {
+			if int(b) != n {
+				return nil, errPKCS7Padding
+			}
+		}
+		return last[:len(last)-n], nil
+	case paddingISO7816:
+		i := len(last) - 1
+		for i >= 0 && last[i] == 0 {
+			i--
+		}
+		if i < 0 || last[i] != 0x80 {
+			return nil, &codedError{codeBadPadding, errors.New("invalid ISO/IEC 7816-4 padding")}
+		}
+		return last[:i], nil
+	case paddingZeros:
+		i := len(last)
+		for i > 0 && last[i-1] == 0 {
+			i--
+		}
+		return last[:i], nil
+	}
+	return last, nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPadding(t *testing.T) {
+	cases := []struct {
+		scheme string
+		in     string
+		want   []byte
+	}{
+		{paddingPKCS7, "abc", append([]byte("abc"), bytes.Repeat([]byte{5}, 5)...)},
+		{paddingPKCS7, "abcdefgh", append([]byte("abcdefgh"), bytes.Repeat([]byte{8}, 8)...)},
+		{paddingISO7816, "abc", append([]byte("abc"), 0x80, 0, 0, 0, 0)},
+		{paddingISO7816, "abcdefgh", append([]byte("abcdefgh"), 0x80, 0, 0, 0, 0, 0, 0, 0)},
+		{paddingZeros, "abc", append([]byte("abc"), 0, 0, 0, 0, 0)},
+		{paddingZeros, "abcdefgh", []byte("abcdefgh")},
+		{paddingZeros, "", make([]byte, 8)},
+		{paddingNone, "abcdefgh", []byte("abcdefgh")},
+	}
+	for _, c := range cases {
+		got, err := pad(c.scheme, []byte(c.in), 8)
+		if err != nil {
+			t.Fatalf("pad(%s, %q): %v", c.scheme, c.in, err)
+		}
+		if !bytes.Equal(got, c.want) {
+			t.Errorf("pad(%s, %q) = %x, want %x", c.scheme, c.in, got, c.want)
+		}
+		back, err := unpad(c.scheme, got, 8)
+		if err != nil || string(back) != c.in {
+			t.Errorf("unpad(%s, %x) = %q, %v", c.scheme, got, back, err)
+		}
+	}
+	if _, err := pad(paddingNone, []byte("abc"), 8); err == nil {
+		t.Error("pad(none) accepted unaligned input")
+	}
+	for _, bad := range [][]byte{
+		{1, 2, 3, 4, 5, 6, 7, 0},
+		{1, 2, 3, 4, 5, 6, 7, 9},
+		{1, 2, 3, 4, 5, 6, 2, 3},
+	} {
+		if _, err := unpad(paddingPKCS7, bad, 8); err == nil {
+			t.Errorf("unpad(pkcs7, %x) accepted bad padding", bad)
+		}
+	}
+	if _, err := unpad(paddingISO7816, []byte{1, 2, 3, 4, 5, 6, 7, 0}, 8); err == nil {
+		t.Error("unpad(iso7816) accepted missing 0x80 marker")
+	}
+}
+
+func TestSM4PaddingField(t *testing.T) {
+	for _, p := range []string{"pkcs7", "zeros", "ISO7816"} {
+		enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": "CBC", "padding": p, "plaintext": "padded",
+		})
+		dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": "CBC", "padding": p, "iv": enc.IV, "ciphertext": enc.Output,
+		})
+		if dec.Output != "padded" {
+			t.Errorf("%s: decrypt = %q", p, dec.Output)
+		}
+	}
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "none", "plaintext": "exactly16bytes!!",
+	})
+	if len(enc.Output) != 32 {
+		t.Fatalf("padding none added a block: %s", enc.Output)
+	}
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "none", "plaintext": "short",
+	})
+	// Without padding, empty plaintext and ciphertext correspond.
+	if dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "padding": "none", "ciphertext": ""}); dec.Output != "" {
+		t.Errorf("empty ciphertext decrypts to %q", dec.Output)
+	}
+	// So with zero padding, which some libraries omit for empty input.
+	if dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "padding": "zeros", "ciphertext": ""}); dec.Output != "" {
+		t.Errorf("empty ciphertext decrypts to %q", dec.Output)
+	}
+	mustFail(t, "sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "ciphertext": ""})
+	// Zero padding emits a block for empty plaintext, so the ciphertext
+	// is never empty.
+	for _, mode := range []string{"ECB", "CBC"} {
+		enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": mode, "padding": "zeros", "plaintext": "",
+		})
+		if len(enc.Output) != 32 {
+			t.Fatalf("%s: zero padding of empty plaintext gave %q", mode, enc.Output)
+		}
+		dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": mode, "padding": "zeros", "iv": enc.IV, "ciphertext": enc.Output,
+		})
+		if dec.Output != "" {
+			t.Errorf("%s: empty plaintext decrypts to %q", mode, dec.Output)
+		}
+	}
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "ansi", "plaintext": "short",
+	})
+}
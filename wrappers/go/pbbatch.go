@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pb"
+)
+
+// runProtobufBatch answers the length-delimited InvokeRequest messages on
+// standard input with one length-delimited InvokeResponse each, in order:
+// the framing of protobuf's writeDelimitedTo and parseDelimitedFrom.
+// Unlike the gRPC Invoke method, failed requests are answered too, with
+// their error Result as the result_json. An empty algorithm or operation
+// defaults to that of the command line. A message that cannot be read ends
+// the batch, which cannot be resynchronized, with an error Result and exit
+// code 1.
+func runProtobufBatch(inv *invocation, stdout io.Writer) int {
+	r := bufio.NewReader(stdin)
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = errReader{errNoStdin}
+	for {
+		msg, err := readDelimited(r)
+		if err == io.EOF {
+			return 0
+		}
+		var res *Result
+		if err != nil {
+			res = errorResult(err)
+		} else {
+			res = protobufRequest(inv, msg)
+		}
+		if werr := writeDelimited(stdout, invokeResponse(res)); werr != nil {
+			fmt.Fprintln(os.Stderr, werr)
+			return 1
+		}
+		if err != nil {
+			return 1
+		}
+	}
+}
+
+// protobufRequest runs one InvokeRequest.
+func protobufRequest(inv *invocation, msg []byte) *Result {
+	fields, err := pb.Parse(msg)
+	if err != nil {
+		return errorResult(&codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)})
+	}
+	algorithm, operation, input := inv.algorithm, inv.operation, ""
+	for _, f := range fields {
+		dst := map[int]*string{1: &algorithm, 2: &operation, 3: &input}[f.Num]
+		if dst == nil {
+			continue
+		}
+		if err := f.Check(pb.TypeBytes); err != nil {
+			return errorResult(&codedError{codeInvalidRequest, fmt.Errorf("invalid request: %v", err)})
+		}
+		*dst = string(f.Bytes)
+	}
+	if algorithm == "" || operation == "" {
+		return errorResult(&codedError{codeInvalidRequest, errors.New("request without an algorithm and an operation")})
+	}
+	in, _, err := readInput(strings.NewReader(input), true)
+	if err != nil {
+		return errorResult(err)
+	}
+	return dispatch(strings.ToLower(algorithm), strings.ToLower(operation), in)
+}
+
+// invokeResponse encodes res as an InvokeResponse.
+func invokeResponse(res *Result) []byte {
+	b, err := json.Marshal(res)
+	if err != nil {
+		panic(err)
+	}
+	return pb.AppendBytes(nil, 1, b)
+}
+
+// readDelimited reads a message preceded by its length as a varint. It
+// returns io.EOF only at the end of the input, before a length.
+func readDelimited(r *bufio.Reader) ([]byte, error) {
+	if _, err := r.Peek(1); err == io.EOF {
+		return nil, io.EOF
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, &codedError{codeInvalidRequest, errors.New("truncated or malformed message length")}
+	}
+	if n > maxFrameSize {
+		return nil, &codedError{codeInvalidRequest, fmt.Errorf("message of %d bytes exceeds the limit of %d", n, maxFrameSize)}
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, &codedError{codeInvalidRequest, errors.New("truncated message")}
+	}
+	return b, nil
+}
+
+func writeDelimited(w io.Writer, msg []byte) error {
+	_, err := w.Write(append(binary.AppendUvarint(nil, uint64(len(msg))), msg...))
+	return err
+}
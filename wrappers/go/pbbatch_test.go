@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pb"
+)
+
+func TestProtobufBatch(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+
+	invoke := func(algorithm, operation, input string) []byte {
+		return pb.AppendString(pb.AppendString(pb.AppendString(nil, 1, algorithm), 2, operation), 3, input)
+	}
+	delimited := func(messages ...[]byte) *bytes.Buffer {
+		var b bytes.Buffer
+		for _, msg := range messages {
+			writeDelimited(&b, msg)
+		}
+		return &b
+	}
+	batch := func(args []string, want int, in *bytes.Buffer) []Result {
+		t.Helper()
+		stdin = in
+		var out bytes.Buffer
+		if code := run(args, &out); code != want {
+			t.Fatalf("%q exited %d, want %d", args, code, want)
+		}
+		var results []Result
+		r := bufio.NewReader(&out)
+		for {
+			msg, err := readDelimited(r)
+			if err != nil {
+				break
+			}
+			fields, err := pb.Parse(msg)
+			if err != nil || len(fields) != 1 || fields[0].Num != 1 {
+				t.Fatalf("response %x: %v", msg, err)
+			}
+			var res Result
+			if err := json.Unmarshal(fields[0].Bytes, &res); err != nil {
+				t.Fatalf("result_json %q", fields[0].Bytes)
+			}
+			results = append(results, res)
+		}
+		return results
+	}
+
+	res := batch([]string{"--batch", "--format", "protobuf"}, 0, delimited(
+		invoke("SM3", "hash", `{"data": "abc"}`),
+		invoke("sm2", "keygen", ""),
+		invoke("sm3", "", ""),
+		invoke("sm3", "hash", "not json"),
+		[]byte{0x0a},
+		invoke("sm3", "hash", `{"data": "abc", "output_encoding": "base64"}`),
+	))
+	if len(res) != 6 {
+		t.Fatalf("%d results, want 6: %+v", len(res), res)
+	}
+	if res[0].Output != abc || res[1].Status != statusSuccess || res[5].Output != "Zsfw9GLu7dnR8tRr3BDk4kFnxIdc8veiKX2gK49LqOA=" {
+		t.Errorf("results: %+v", res)
+	}
+	for i := 2; i <= 4; i++ {
+		if res[i].Status != statusError || res[i].ErrorCode != codeInvalidRequest {
+			t.Errorf("message %d: %+v", i, res[i])
+		}
+	}
+
+	// The command line supplies the operation; a truncated message ends
+	// the batch with exit code 1.
+	in := delimited(pb.AppendString(nil, 3, `{"data": "abc"}`))
+	in.Write(binary.AppendUvarint(nil, 10))
+	in.WriteString("short")
+	res = batch([]string{"sm3", "hash", "--batch", "--format=protobuf"}, 1, in)
+	if len(res) != 2 || res[0].Output != abc || res[1].ErrorCode != codeInvalidRequest {
+		t.Errorf("results: %+v", res)
+	}
+}
@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+const reportUsage = "report [--format json|html] [--output <file>] <results.ndjson>..."
+
+// cellResult is one cross-language test, as a runner records it: the
+// request the consumer was given for what the producer made (a ciphertext
+// to decrypt, a signature to verify, a hash to compare), and whether it
+// passed. A runner writes one JSON object per line.
+type cellResult struct {
+	Algorithm string `json:"algorithm"`
+	// Case names the combination tested, such as "encrypt/decrypt CBC".
+	Case string `json:"case,omitempty"`
+	// Producer encrypted or signed; Consumer decrypted or verified.
+	Producer string `json:"producer"`
+	Consumer string `json:"consumer"`
+	Passed   *bool  `json:"passed"`
+	Error    string `json:"error,omitempty"`
+	// Request and Result are the consumer's, to reproduce a failure.
+	Request map[string]interface{} `json:"request,omitempty"`
+	Result  map[string]interface{} `json:"result,omitempty"`
+}
+
+// compatReport is the output of report.
+type compatReport struct {
+	// Implementations are the producers and consumers, in the order of
+	// the rows and columns of every matrix.
+	Implementations []string       `json:"implementations"`
+	Total           int            `json:"total"`
+	Passed          int            `json:"passed"`
+	Failed          int            `json:"failed"`
+	Matrices        []compatMatrix `json:"matrices"`
+}
+
+// compatMatrix holds the cells of an algorithm and case.
+type compatMatrix struct {
+	Algorithm string       `json:"algorithm"`
+	Case      string       `json:"case,omitempty"`
+	Cells     []compatCell `json:"cells"`
+}
+
+// compatCell counts the results of a producer and consumer; Failures
+// drill down to each failed request.
+type compatCell struct {
+	Producer string       `json:"producer"`
+	Consumer string       `json:"consumer"`
+	Passed   int          `json:"passed"`
+	Failed   int          `json:"failed"`
+	Failures []cellResult `json:"failures,omitempty"`
+}
+
+// reportCommand reads the cellResults of runner output files, given after
+// the flags ("-" is standard input), and writes the compatibility matrix
+// as a compatReport or an HTML page. The exit code is exitInvalid if a
+// test failed.
+func reportCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	format := fs.String("format", "json", "output format: json or html")
+	output := fs.String("output", "", "file to write instead of standard output")
+	if ok, code := parseCommand(fs, reportUsage, args, -1, stdout); !ok {
+		return code
+	}
+	if *format != "json" && *format != "html" {
+		return commandFailed(stdout, fmt.Errorf("unsupported --format %q (supported: json, html)", *format))
+	}
+	var cells []cellResult
+	for _, path := range fs.Args() {
+		c, err := readCellResults(path)
+		if err != nil {
+			return commandFailed(stdout, err)
+		}
+		cells = append(cells, c...)
+	}
+	report := buildReport(cells)
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return commandFailed(stdout, &codedError{codeIO, err})
+		}
+		defer f.Close()
+		w = f
+	}
+	var err error
+	if *format == "html" {
+		err = reportPage.Execute(w, report)
+	} else {
+		enc := json.NewEncoder(w)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(report)
+	}
+	if err != nil {
+		return commandFailed(stdout, &codedError{codeIO, err})
+	}
+	if report.Failed > 0 {
+		return exitInvalid
+	}
+	return exitSuccess
+}
+
+// readCellResults reads the cellResults of a file, one JSON object after
+// another.
+func readCellResults(path string) ([]cellResult, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, &codedError{codeIO, err}
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var cells []cellResult
+	for i := 1; ; i++ {
+		var c cellResult
+		err := dec.Decode(&c)
+		if errors.Is(err, io.EOF) {
+			return cells, nil
+		}
+		if err == nil {
+			switch {
+			case c.Algorithm == "":
+				err = errors.New(`missing "algorithm"`)
+			case c.Producer == "" || c.Consumer == "":
+				err = errors.New(`missing "producer" or "consumer"`)
+			case c.Passed == nil:
+				err = errors.New(`missing "passed"`)
+			}
+		}
+		if err != nil {
+			return nil, &codedError{codeInvalidRequest, fmt.Errorf("%s: result %d: %v", path, i, err)}
+		}
+		cells = append(cells, c)
+	}
+}
+
+// buildReport groups cells by algorithm and case, in the order they first
+// appear, and within each by producer and consumer.
+func buildReport(cells []cellResult) *compatReport {
+	report := &compatReport{Implementations: []string{}, Matrices: []compatMatrix{}}
+	impls := map[string]bool{}
+	matrices := map[[2]string]*compatMatrix{}
+	var order [][2]string
+	for _, c := range cells {
+		impls[c.Producer], impls[c.Consumer] = true, true
+		key := [2]string{c.Algorithm, c.Case}
+		m, ok := matrices[key]
+		if !ok {
+			m = &compatMatrix{Algorithm: c.Algorithm, Case: c.Case}
+			matrices[key] = m
+			order = append(order, key)
+		}
+		i := slices.IndexFunc(m.Cells, func(cell compatCell) bool {
+			return cell.Producer == c.Producer && cell.Consumer == c.Consumer
+		})
+		if i < 0 {
+			m.Cells = append(m.Cells, compatCell{Producer: c.Producer, Consumer: c.Consumer})
+			i = len(m.Cells) - 1
+		}
+		report.Total++
+		if *c.Passed {
+			m.Cells[i].Passed++
+			report.Passed++
+		} else {
+			m.Cells[i].Failed++
+			m.Cells[i].Failures = append(m.Cells[i].Failures, c)
+			report.Failed++
+		}
+	}
+	report.Implementations = sortedKeys(impls)
+	for _, key := range order {
+		m := matrices[key]
+		slices.SortFunc(m.Cells, func(a, b compatCell) int {
+			return strings.Compare(a.Producer+"\x00"+a.Consumer, b.Producer+"\x00"+b.Consumer)
+		})
+		report.Matrices = append(report.Matrices, *m)
+	}
+	return report
+}
+
+// Total is the number of tests of the cell.
+func (c compatCell) Total() int {
+	return c.Passed + c.Failed
+}
+
+// Cell returns the cell of producer and consumer, nil if no test ran.
+func (m compatMatrix) Cell(producer, consumer string) *compatCell {
+	for i := range m.Cells {
+		if m.Cells[i].Producer == producer && m.Cells[i].Consumer == consumer {
+			return &m.Cells[i]
+		}
+	}
+	return nil
+}
+
+// reportPage renders a compatReport as a page with a table per matrix,
+// producers down and consumers across. A cell with failures links to
+// their requests and results below the tables.
+var reportPage = template.Must(template.New("report").Funcs(template.FuncMap{
+	"anchor": func(m int, producer, consumer string) string {
+		return fmt.Sprintf("m%d-%s-%s", m, producer, consumer)
+	},
+	"json": func(v interface{}) (string, error) {
+		// The template escapes the text.
+		var b strings.Builder
+		enc := json.NewEncoder(&b)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(v)
+		return strings.TrimSuffix(b.String(), "\n"), err
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>SM compatibility matrix</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.4em 0.8em; text-align: center; }
+td.pass { background: #d4f4d4; }
+td.fail { background: #f8d0d0; }
+td.none { color: #999; }
+pre { background: #f4f4f4; padding: 0.6em; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>SM compatibility matrix</h1>
+<p>{{.Passed}} of {{.Total}} passed, {{.Failed}} failed. Rows are producers (encrypted or signed), columns consumers (decrypted or verified).</p>
+{{- $impls := .Implementations}}
+{{- range $i, $m := .Matrices}}
+<h2>{{$m.Algorithm}}{{with $m.Case}} {{.}}{{end}}</h2>
+<table>
+<tr><th>producer \ consumer</th>{{range $impls}}<th>{{.}}</th>{{end}}</tr>
+{{- range $p := $impls}}
+<tr><th>{{$p}}</th>
+{{- range $c := $impls}}
+{{- with $m.Cell $p $c}}
+{{- if .Failed}}<td class="fail"><a href="#{{anchor $i .Producer .Consumer}}">{{.Passed}}/{{.Total}}</a></td>
+{{- else}}<td class="pass">{{.Passed}}/{{.Total}}</td>{{end}}
+{{- else}}<td class="none">&ndash;</td>{{end}}
+{{- end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- range $i, $m := .Matrices}}{{range $m.Cells}}{{if .Failed}}
+<h3 id="{{anchor $i .Producer .Consumer}}">{{$m.Algorithm}}{{with $m.Case}} {{.}}{{end}}: {{.Producer}} &rarr; {{.Consumer}}, {{.Failed}} failed</h3>
+{{- range .Failures}}
+<details>
+<summary>{{if .Error}}{{.Error}}{{else}}failed{{end}}</summary>
+{{- with .Request}}
+<p>Request</p>
+<pre>{{json .}}</pre>
+{{- end}}
+{{- with .Result}}
+<p>Result</p>
+<pre>{{json .}}</pre>
+{{- end}}
+</details>
+{{- end}}
+{{- end}}{{end}}{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+	results := filepath.Join(dir, "results.ndjson")
+	os.WriteFile(results, []byte(`{"algorithm": "sm4", "case": "encrypt/decrypt CBC", "producer": "py", "consumer": "go", "passed": true}
+{"algorithm": "sm4", "case": "encrypt/decrypt CBC", "producer": "go", "consumer": "py", "passed": true}
+{"algorithm": "sm4", "case": "encrypt/decrypt CBC", "producer": "js", "consumer": "go", "passed": false,
+ "error": "plaintext differs", "request": {"key": "00", "ciphertext": "<bad>"}, "result": {"status": "success", "output": "x"}}
+{"algorithm": "sm4", "case": "encrypt/decrypt CBC", "producer": "js", "consumer": "go", "passed": true}
+{"algorithm": "sm2", "case": "sign/verify", "producer": "go", "consumer": "js", "passed": true}
+`), 0o644)
+
+	var out bytes.Buffer
+	if code := run([]string{"report", results}, &out); code != exitInvalid {
+		t.Fatalf("exit %d: %s", code, out.String())
+	}
+	var report compatReport
+	if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Total != 5 || report.Passed != 4 || report.Failed != 1 || strings.Join(report.Implementations, ",") != "go,js,py" {
+		t.Fatalf("%+v", report)
+	}
+	if len(report.Matrices) != 2 || report.Matrices[0].Algorithm != "sm4" || report.Matrices[1].Case != "sign/verify" {
+		t.Fatalf("matrices: %+v", report.Matrices)
+	}
+	cbc := report.Matrices[0]
+	c := cbc.Cell("js", "go")
+	if len(cbc.Cells) != 3 || c == nil || c.Passed != 1 || c.Failed != 1 || c.Failures[0].Request["ciphertext"] != "<bad>" {
+		t.Fatalf("cells: %+v", cbc.Cells)
+	}
+	if cbc.Cell("go", "js") != nil {
+		t.Error("a cell without tests")
+	}
+
+	// The page links a failed cell to its request, escaped.
+	page := filepath.Join(dir, "report.html")
+	out.Reset()
+	if code := run([]string{"report", "--format", "html", "--output", page, results}, &out); code != exitInvalid || out.Len() != 0 {
+		t.Fatalf("exit %d: %s", code, out.String())
+	}
+	b, _ := os.ReadFile(page)
+	for _, want := range []string{`<a href="#m0-js-go">1/2</a>`, `<h3 id="m0-js-go">`, "plaintext differs", "&lt;bad&gt;", `<td class="pass">1/1</td>`} {
+		if !bytes.Contains(b, []byte(want)) {
+			t.Errorf("page lacks %q:\n%s", want, b)
+		}
+	}
+
+	passing := filepath.Join(dir, "passing.ndjson")
+	os.WriteFile(passing, []byte(`{"algorithm": "sm3", "producer": "go", "consumer": "py", "passed": true}`), 0o644)
+	out.Reset()
+	if code := run([]string{"report", passing}, &out); code != 0 {
+		t.Errorf("passing results: exit %d: %s", code, out.String())
+	}
+
+	bad := filepath.Join(dir, "bad.ndjson")
+	os.WriteFile(bad, []byte(`{"algorithm": "sm3", "producer": "go", "consumer": "py"}`), 0o644)
+	for _, args := range [][]string{nil, {bad}, {"--format", "pdf", passing}, {filepath.Join(dir, "missing")}} {
+		out.Reset()
+		if code := run(append([]string{"report"}, args...), &out); code == 0 || code == exitInvalid || !bytes.Contains(out.Bytes(), []byte(`"status":"error"`)) {
+			t.Errorf("report %q exited %d: %s", args, code, out.String())
+		}
+	}
+}
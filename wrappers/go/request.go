@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// hexBytes is a request field holding hex-encoded bytes.
+type hexBytes []byte
+
+// textBytes is a request field of bytes given as UTF-8 text in name, or as
+// name+"_hex" or name+"_base64" (see bytesField).
+type textBytes []byte
+
+// encodedBytes is a request field of text decoded as selected by
+// name+"_encoding" (see encodedField).
+type encodedBytes []byte
+
+// keyText is a request field holding a key in the encoding selected by
+// another field, such as "key_format". A CBOR byte string is taken as the
+// hex encoding of its bytes.
+type keyText string
+
+var (
+	hexBytesType     = reflect.TypeOf(hexBytes(nil))
+	textBytesType    = reflect.TypeOf(textBytes(nil))
+	encodedBytesType = reflect.TypeOf(encodedBytes(nil))
+	keyTextType      = reflect.TypeOf(keyText(""))
+)
+
+// decodeRequest fills the struct that v points to from the request in.
+// Each exported field is read from the request field named by its json
+// tag, and a `validate:"required"` tag makes it required. Fields may be
+// strings, hexBytes, textBytes, encodedBytes, keyText, ints, bools or
+// string slices, or pointers to them for optional fields whose zero value
+// means something; such a pointer stays nil when the field is absent. The
+// fields of embedded structs are read as if they were declared in v, so
+// operations can share a request struct. As in schema.validate, every
+// missing or mistyped field is reported at once.
+func decodeRequest(in map[string]interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v).Elem()
+	fields := reflect.VisibleFields(rv.Type())
+	ve := &validationError{}
+	var missing []fieldError
+	checked := map[string]bool{}
+	for _, f := range fields {
+		name := requestFieldName(f)
+		if name == "" {
+			continue
+		}
+		kind := requestFieldKind(f.Type)
+		if !requestFieldSet(in, f.Type, name) && f.Tag.Get("validate") == "required" {
+			missing = append(missing, fieldError{Field: name, Problem: "missing", Expected: kind.String()})
+		}
+		for _, form := range requestFieldForms(f.Type, name) {
+			if checked[form] {
+				continue
+			}
+			checked[form] = true
+			if val, ok := in[form]; ok && val != nil && !kind.accepts(val) {
+				ve.fields = append(ve.fields, fieldError{Field: form, Problem: "invalid", Expected: kind.String(), Actual: jsonType(val)})
+			}
+		}
+	}
+	code := codeInvalidField
+	if len(ve.fields) == 0 {
+		code = codeMissingField
+	}
+	if ve.fields = append(ve.fields, missing...); len(ve.fields) > 0 {
+		return &codedError{code, ve}
+	}
+	for _, f := range fields {
+		name := requestFieldName(f)
+		if name == "" || !requestFieldSet(in, f.Type, name) {
+			continue
+		}
+		if err := setRequestField(rv.FieldByIndex(f.Index), in, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// requestFieldName returns the request field a struct field is read
+// from, or "" if it is not read from the request.
+func requestFieldName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" || !f.IsExported() || f.Anonymous {
+		return ""
+	}
+	return name
+}
+
+// requestFieldForms lists the request fields that a struct field of type t
+// read from name may take its value from. For encodedBytes the second is
+// the encoding rather than an alternative.
+func requestFieldForms(t reflect.Type, name string) []string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t {
+	case textBytesType:
+		return []string{name, name + "_hex", name + "_base64"}
+	case encodedBytesType:
+		return []string{name, name + "_encoding"}
+	}
+	return []string{name}
+}
+
+// requestFieldSet reports whether in gives the struct field of type t read
+// from name a value.
+func requestFieldSet(in map[string]interface{}, t reflect.Type, name string) bool {
+	forms := requestFieldForms(t, name)
+	if t == encodedBytesType || (t.Kind() == reflect.Pointer && t.Elem() == encodedBytesType) {
+		forms = forms[:1]
+	}
+	for _, form := range forms {
+		if in[form] != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// requestFieldKind maps the Go type of a request struct field to the kind
+// of its JSON value.
+func requestFieldKind(t reflect.Type) fieldKind {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t.Kind() == reflect.String, t == hexBytesType, t == textBytesType, t == encodedBytesType:
+		return fieldText
+	case t.Kind() == reflect.Int:
+		return fieldInt
+	case t.Kind() == reflect.Bool:
+		return fieldBool
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String:
+		return fieldList
+	}
+	panic(fmt.Sprintf("unsupported request field type %v", t))
+}
+
+// setRequestField decodes the field name of in, whose type has been
+// checked, into dst.
+func setRequestField(dst reflect.Value, in map[string]interface{}, name string) error {
+	if dst.Kind() == reflect.Pointer {
+		dst.Set(reflect.New(dst.Type().Elem()))
+		dst = dst.Elem()
+	}
+	var (
+		b   []byte
+		err error
+	)
+	switch {
+	case dst.Type() == hexBytesType:
+		b, _, err = hexField(in, name)
+		dst.SetBytes(b)
+	case dst.Type() == textBytesType:
+		b, _, err = bytesField(in, name)
+		dst.SetBytes(b)
+	case dst.Type() == encodedBytesType:
+		b, err = encodedField(in, name)
+		dst.SetBytes(b)
+	case dst.Type() == keyTextType:
+		var k *keyText
+		if k, err = keyTextField(in, name); err == nil {
+			dst.SetString(string(*k))
+		}
+	case dst.Kind() == reflect.String:
+		var s string
+		s, _, err = stringField(in, name)
+		dst.SetString(s)
+	case dst.Kind() == reflect.Int:
+		var n int
+		n, _, err = intField(in, name)
+		dst.SetInt(int64(n))
+	case dst.Kind() == reflect.Bool:
+		var v bool
+		v, err = boolField(in, name)
+		dst.SetBool(v)
+	default:
+		var list []string
+		list, _, err = stringListField(in, name)
+		dst.Set(reflect.ValueOf(list))
+	}
+	return err
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeRequest(t *testing.T) {
+	type common struct {
+		Key hexBytes `json:"key" validate:"required"`
+	}
+	type request struct {
+		common
+		Text   string   `json:"text"`
+		Length int      `json:"length" validate:"required"`
+		Flag   bool     `json:"flag"`
+		Names  []string `json:"names"`
+		Count  *int     `json:"count"`
+		Ignore string
+	}
+	var req request
+	in := map[string]interface{}{
+		"key": "0102", "text": "hi", "length": json.Number("16"), "flag": true,
+		"names": []interface{}{"a", "b"}, "count": json.Number("0"), "Ignore": "x",
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		t.Fatal(err)
+	}
+	zero := 0
+	want := request{common: common{Key: hexBytes{1, 2}}, Text: "hi", Length: 16, Flag: true, Names: []string{"a", "b"}, Count: &zero}
+	if !reflect.DeepEqual(req, want) {
+		t.Errorf("decoded %+v, want %+v", req, want)
+	}
+	// A JSON number decoded without UseNumber is accepted as well, and an
+	// absent optional pointer field stays nil.
+	req = request{}
+	if err := decodeRequest(map[string]interface{}{"key": "", "length": 16.0}, &req); err != nil || req.Length != 16 || req.Count != nil {
+		t.Errorf("float64 length: %v, %+v", err, req)
+	}
+
+	err := decodeRequest(map[string]interface{}{"length": "16", "names": []interface{}{1}}, &req)
+	ve, ok := err.(*codedError).err.(*validationError)
+	if !ok || errorCode(err) != codeInvalidField {
+		t.Fatalf("decodeRequest = %v", err)
+	}
+	wantErrs := []fieldError{
+		{Field: "length", Problem: "invalid", Expected: "integer", Actual: "string"},
+		{Field: "names", Problem: "invalid", Expected: "array of strings", Actual: "array"},
+		{Field: "key", Problem: "missing", Expected: "string"},
+	}
+	if !reflect.DeepEqual(ve.fields, wantErrs) {
+		t.Errorf("errors %+v, want %+v", ve.fields, wantErrs)
+	}
+	if err := decodeRequest(map[string]interface{}{"key": "zz", "length": 1.0}, &req); errorCode(err) != codeInvalidEncoding {
+		t.Errorf("invalid hex: %v", err)
+	}
+}
+
+func TestDecodeRequestForms(t *testing.T) {
+	type request struct {
+		Message textBytes     `json:"message" validate:"required"`
+		Data    *encodedBytes `json:"data"`
+		Key     *keyText      `json:"key"`
+	}
+	for _, c := range []struct {
+		in   map[string]interface{}
+		want request
+	}{
+		{map[string]interface{}{"message": "hi"}, request{Message: textBytes("hi")}},
+		{map[string]interface{}{"message_hex": "6869", "data": "aGk=", "data_encoding": "base64"},
+			request{Message: textBytes("hi"), Data: (*encodedBytes)(&[]byte{'h', 'i'})}},
+		{map[string]interface{}{"message_base64": "aGk=", "key": []byte{1, 2}},
+			request{Message: textBytes("hi"), Key: (*keyText)(ptrTo("0102"))}},
+	} {
+		var req request
+		if err := decodeRequest(c.in, &req); err != nil {
+			t.Errorf("decodeRequest(%v): %v", c.in, err)
+		} else if !reflect.DeepEqual(req, c.want) {
+			t.Errorf("decodeRequest(%v) = %+v, want %+v", c.in, req, c.want)
+		}
+	}
+
+	var req request
+	err := decodeRequest(map[string]interface{}{"message_hex": json.Number("1"), "data": "x", "data_encoding": true}, &req)
+	ve, ok := err.(*codedError).err.(*validationError)
+	if !ok || errorCode(err) != codeInvalidField {
+		t.Fatalf("decodeRequest = %v", err)
+	}
+	wantErrs := []fieldError{
+		{Field: "message_hex", Problem: "invalid", Expected: "string", Actual: "integer"},
+		{Field: "data_encoding", Problem: "invalid", Expected: "string", Actual: "boolean"},
+	}
+	if !reflect.DeepEqual(ve.fields, wantErrs) {
+		t.Errorf("errors %+v, want %+v", ve.fields, wantErrs)
+	}
+	// The encoding alone does not give the encoded field a value.
+	req = request{}
+	if err := decodeRequest(map[string]interface{}{"message": "a", "data_encoding": "hex"}, &req); err != nil || req.Data != nil {
+		t.Errorf("data_encoding alone: %v, %+v", err, req)
+	}
+	if err := decodeRequest(map[string]interface{}{"message": "a", "message_hex": "62"}, &req); errorCode(err) != codeInvalidField {
+		t.Errorf("two forms of message: %v", err)
+	}
+}
+
+func ptrTo[T any](v T) *T { return &v }
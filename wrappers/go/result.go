@@ -0,0 +1,186 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cose"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm9"
+)
+
+const (
+	statusSuccess = "success"
+	statusError   = "error"
+)
+
+// Result is the JSON document written for every request. Binary values are
+// hex encoded; fields that do not apply to an operation are omitted.
+type Result struct {
+	Status  string `json:"status"`
+	Output  string `json:"output,omitempty"`
+	Message string `json:"message,omitempty"`
+	// Outputs replaces Output for operations with one result per item.
+	Outputs []string `json:"outputs,omitempty"`
+	// ErrorCode is a stable identifier of the failure, one of the code
+	// constants, so that the harness need not match on Message.
+	ErrorCode string `json:"error_code,omitempty"`
+	// FieldErrors lists every missing or mistyped field of a request that
+	// failed validation.
+	FieldErrors []fieldError `json:"field_errors,omitempty"`
+	// Warning flags a request that succeeded but used questionable input.
+	Warning string `json:"warning,omitempty"`
+
+	// IV is set when the wrapper generated the IV itself.
+	IV string `json:"iv,omitempty"`
+	// Tag is the authentication tag of AEAD modes.
+	Tag string `json:"tag,omitempty"`
+	// PrivateKey is set when the wrapper generated the key pair itself.
+	PrivateKey string `json:"private_key,omitempty"`
+	PublicKey  string `json:"public_key,omitempty"`
+	// PublicKeyCompressed and Fingerprint describe generated SM2 keys.
+	PublicKeyCompressed string `json:"public_key_compressed,omitempty"`
+	Fingerprint         string `json:"fingerprint,omitempty"`
+	// Context is the state token of multi-call operations.
+	Context string `json:"context,omitempty"`
+	// Bytes is the number of bytes written to an output file.
+	Bytes int64 `json:"bytes,omitempty"`
+	// Valid carries the outcome of verify operations.
+	Valid *bool `json:"valid,omitempty"`
+	// Reason is a code explaining why Valid is false.
+	Reason string `json:"reason,omitempty"`
+	// ValidItems holds one outcome per item of batch operations; Valid
+	// is then true only if every item is.
+	ValidItems []bool `json:"valid_items,omitempty"`
+
+	// EphemeralPrivateKey, EphemeralPublicKey and Confirmation carry the
+	// per-run values of the SM2 key exchange.
+	EphemeralPrivateKey string `json:"ephemeral_private_key,omitempty"`
+	EphemeralPublicKey  string `json:"ephemeral_public_key,omitempty"`
+	Confirmation        string `json:"confirmation,omitempty"`
+	// PublicShare is the client's P1 in two-party SM2 key generation.
+	PublicShare string `json:"public_share,omitempty"`
+	// Certificate describes the certificate given to cert-parse.
+	Certificate *certificateInfo `json:"certificate,omitempty"`
+	// FriendlyName is the label of the key in a PKCS #12 file.
+	FriendlyName string `json:"friendly_name,omitempty"`
+	// RevocationTime and RevocationReason describe a revoked certificate.
+	RevocationTime   string `json:"revocation_time,omitempty"`
+	RevocationReason string `json:"revocation_reason,omitempty"`
+	// Nonce is the nonce placed in an OCSP or time-stamp request.
+	Nonce string `json:"nonce,omitempty"`
+	// GenTime and Serial describe a verified time-stamp token.
+	GenTime string `json:"gen_time,omitempty"`
+	Serial  string `json:"serial,omitempty"`
+	// KeyType is the kind of key in a keystore entry, sm2 or sm4.
+	KeyType string `json:"key_type,omitempty"`
+	// Header is the protected header of a verified JWS or a decrypted
+	// JWE, and Claims the claims set of a verified JWT.
+	Header map[string]interface{} `json:"header,omitempty"`
+	Claims map[string]interface{} `json:"claims,omitempty"`
+	// Encapsulation is the ciphertext of a key encapsulation, whose shared
+	// key is in Output.
+	Encapsulation string `json:"encapsulation,omitempty"`
+	// DerivedKey is the KDF output over an ECDH shared secret.
+	DerivedKey string `json:"derived_key,omitempty"`
+	// Protocol and CipherSuite are the parameters of a completed TLCP or
+	// TLS handshake and PeerCertificates the subjects of the certificates
+	// the peer sent.
+	Protocol         string   `json:"protocol,omitempty"`
+	CipherSuite      string   `json:"cipher_suite,omitempty"`
+	PeerCertificates []string `json:"peer_certificates,omitempty"`
+	// Handshakes logs the connections of a server mode.
+	Handshakes []*serverEvent `json:"handshakes,omitempty"`
+	// Version describes the build for "wrapper version".
+	Version *versionInfo `json:"version,omitempty"`
+	// Capabilities lists what the wrapper supports for
+	// "wrapper capabilities".
+	Capabilities *capabilities `json:"capabilities,omitempty"`
+	// SelfTest reports each known answer test of "wrapper selftest".
+	SelfTest []knownAnswerResult `json:"self_test,omitempty"`
+}
+
+// Error codes of Result.ErrorCode. They are part of the interface: once
+// published, a code keeps its meaning.
+const (
+	// codeUsage is a command line the wrapper does not understand.
+	codeUsage = "ERR_USAGE"
+	// codeInvalidRequest is a request that is not a JSON object.
+	codeInvalidRequest       = "ERR_INVALID_REQUEST"
+	codeUnsupportedOperation = "ERR_UNSUPPORTED_OPERATION"
+	codeMissingField         = "ERR_MISSING_FIELD"
+	// codeInvalidField is a field of the wrong type or out of range.
+	codeInvalidField = "ERR_INVALID_FIELD"
+	// codeInvalidEncoding is a hex, base64 or UTF-8 field that does not
+	// decode.
+	codeInvalidEncoding = "ERR_INVALID_ENCODING"
+	// codeUnsupportedOption is an unknown mode, padding, format or the
+	// like.
+	codeUnsupportedOption = "ERR_UNSUPPORTED_OPTION"
+	codeInvalidKeyLength  = "ERR_INVALID_KEY_LENGTH"
+	codeInvalidIVLength   = "ERR_INVALID_IV_LENGTH"
+	// codeInvalidDataLength is input that is not a whole number of blocks.
+	codeInvalidDataLength = "ERR_INVALID_DATA_LENGTH"
+	codeWeakKey           = "ERR_WEAK_KEY"
+	codeBadPadding        = "ERR_BAD_PADDING"
+	codePointNotOnCurve   = "ERR_POINT_NOT_ON_CURVE"
+	codeDecryptionFailed  = "ERR_DECRYPTION_FAILED"
+	// codeAuthenticationFailed is an AEAD tag or key wrap integrity check
+	// that does not match.
+	codeAuthenticationFailed = "ERR_AUTHENTICATION_FAILED"
+	// codeIO is a file that cannot be read or written.
+	codeIO = "ERR_IO"
+	// codeOperationFailed is any other failure.
+	codeOperationFailed = "ERR_OPERATION_FAILED"
+)
+
+// codedError attaches an error code to err.
+type codedError struct {
+	code string
+	err  error
+}
+
+func (e *codedError) Error() string { return e.err.Error() }
+func (e *codedError) Unwrap() error { return e.err }
+
+// errorCodes classifies the errors of the internal packages.
+var errorCodes = map[error]string{
+	sm2.ErrNotOnCurve:       codePointNotOnCurve,
+	sm2.ErrDecryption:       codeDecryptionFailed,
+	sm9.ErrDecryption:       codeDecryptionFailed,
+	cose.ErrDecryption:      codeDecryptionFailed,
+	modes.ErrAuthentication: codeAuthenticationFailed,
+	modes.ErrNonceSize:      codeInvalidIVLength,
+	modes.ErrTagSize:        codeInvalidField,
+	pbes2.ErrIterations:     codeInvalidField,
+}
+
+// errorCode returns the code of err: that of the outermost codedError,
+// else of a known internal error, else codeOperationFailed.
+func errorCode(err error) string {
+	var ce *codedError
+	if errors.As(err, &ce) {
+		return ce.code
+	}
+	for e, code := range errorCodes {
+		if errors.Is(err, e) {
+			return code
+		}
+	}
+	var pe *fs.PathError
+	if errors.As(err, &pe) {
+		return codeIO
+	}
+	return codeOperationFailed
+}
+
+func errorResult(err error) *Result {
+	res := &Result{Status: statusError, Message: err.Error(), ErrorCode: errorCode(err)}
+	var ve *validationError
+	if errors.As(err, &ve) {
+		res.FieldErrors = ve.fields
+	}
+	return res
+}
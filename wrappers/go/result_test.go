@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+)
+
+func TestErrorCodes(t *testing.T) {
+	keys := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	ct, _ := hex.DecodeString(mustCall(t, "sm2", "encrypt", map[string]interface{}{"public_key": keys.PublicKey, "plaintext": "abc"}).Output)
+	ct[len(ct)-1] ^= 1
+	tampered := hex.EncodeToString(ct)
+	gcm := mustCall(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "plaintext": "abc"})
+	xtsKey := strings.Repeat("01", 16) + strings.Repeat("02", 16)
+	badTag := strings.Repeat("0", len(gcm.Tag))
+	if badTag == gcm.Tag {
+		badTag = strings.Repeat("1", len(gcm.Tag))
+	}
+	for _, c := range []struct {
+		algorithm, operation string
+		in                   map[string]interface{}
+		code                 string
+	}{
+		{"sm5", "hash", map[string]interface{}{}, codeUnsupportedOperation},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key}, codeMissingField},
+		{"sm4", "encrypt", map[string]interface{}{"key": 1, "plaintext": "x"}, codeInvalidField},
+		{"sm4", "encrypt", map[string]interface{}{"key": "zz", "plaintext": "x"}, codeInvalidEncoding},
+		{"sm4", "encrypt", map[string]interface{}{"key": "00", "plaintext": "x"}, codeInvalidKeyLength},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "XYZ", "plaintext": "x"}, codeUnsupportedOption},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "CBC", "iv": "00", "plaintext": "x"}, codeInvalidIVLength},
+		{"sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "ciphertext": "00"}, codeInvalidDataLength},
+		{"sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "mode": "CBC", "iv": strings.Repeat("00", 16), "ciphertext": strings.Repeat("00", 32)}, codeBadPadding},
+		{"sm4", "decrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "iv": gcm.IV, "tag": badTag, "ciphertext": gcm.Output}, codeAuthenticationFailed},
+		{"sm4", "encrypt", map[string]interface{}{"key": strings.Repeat("00", 16), "plaintext": "x", "reject_weak_key": true}, codeWeakKey},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "input_file": filepath.Join(t.TempDir(), "missing")}, codeIO},
+		{"sm2", "encrypt", map[string]interface{}{"public_key": "04" + strings.Repeat("01", 64), "plaintext": "x"}, codePointNotOnCurve},
+		{"sm2", "decrypt", map[string]interface{}{"private_key": keys.PrivateKey, "ciphertext": tampered}, codeDecryptionFailed},
+		// Bad hex or base64 anywhere, and conflicting fields.
+		{"sm3", "hmac", map[string]interface{}{"key": "00", "data": "zz", "data_encoding": "hex"}, codeInvalidEncoding},
+		{"sm3", "hash", map[string]interface{}{"data_list": []interface{}{"!"}, "data_encoding": "base64"}, codeInvalidEncoding},
+		{"sm2", "key-combine", map[string]interface{}{"shares": []interface{}{"0102", "0304"}}, codeInvalidEncoding},
+		{"sm2", "verify-batch", map[string]interface{}{"items": []interface{}{map[string]interface{}{"message": "m", "signature": "zz", "public_key": keys.PublicKey}}}, codeInvalidEncoding},
+		{"sm2", "verify-batch", map[string]interface{}{"items": []interface{}{}}, codeInvalidField},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "plaintext": "x", "plaintext_hex": "00"}, codeInvalidField},
+		{"sm3", "hash", map[string]interface{}{"data": "x", "stdin": true}, codeInvalidField},
+		// Sizes and parameters out of range.
+		{"sm4", "encrypt", map[string]interface{}{"key": xtsKey, "mode": "XTS", "tweak": "00", "plaintext": strings.Repeat("x", 16)}, codeInvalidIVLength},
+		{"sm4", "encrypt", map[string]interface{}{"key": xtsKey, "mode": "XTS", "tweak": strings.Repeat("00", 16), "sector": 1, "plaintext": strings.Repeat("x", 16)}, codeInvalidField},
+		{"sm4", "encrypt", map[string]interface{}{"key": xtsKey, "mode": "XTS", "plaintext": strings.Repeat("x", 16)}, codeMissingField},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "CFB", "feedback_size": 7, "plaintext": "x"}, codeInvalidField},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "tag_length": 8, "plaintext": "x"}, codeInvalidField},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "iv": "00112233", "tag_length": 12, "plaintext": "x"}, codeInvalidIVLength},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "CCM", "iv": "001122", "plaintext": "x"}, codeInvalidIVLength},
+		{"sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "CCM", "iv": "00112233445566", "tag_length": 5, "plaintext": "x"}, codeInvalidField},
+		{"sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "x", "mac": "00"}, codeInvalidField},
+		{"sm3", "kdf", map[string]interface{}{"shared_secret": "00", "key_length": 0}, codeInvalidField},
+		{"sm3", "pbkdf2", map[string]interface{}{"password": "p", "salt": "00", "iterations": 0, "key_length": 16}, codeInvalidField},
+		{"sm2", "convert-signature", map[string]interface{}{"signature": "00", "signature_format": "rs"}, codeInvalidField},
+		{"sm2", "sign", map[string]interface{}{"private_key": keys.PrivateKey, "prehashed": true, "digest": "00"}, codeInvalidField},
+	} {
+		if res := mustFail(t, c.algorithm, c.operation, c.in); res.ErrorCode != c.code {
+			t.Errorf("%s %s %v: error_code = %q (%s), want %q", c.algorithm, c.operation, c.in, res.ErrorCode, res.Message, c.code)
+		}
+	}
+
+	// Errors of the internal packages are classified wherever they are
+	// wrapped.
+	if code := errorCode(fmt.Errorf("pkcs12: %w", pbes2.ErrIterations)); code != codeInvalidField {
+		t.Errorf("iteration count out of range: error_code = %q, want %q", code, codeInvalidField)
+	}
+
+	var out bytes.Buffer
+	run([]string{"sm3", "hash", "--bogus"}, &out)
+	var res Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil || res.ErrorCode != codeUsage {
+		t.Errorf("bad flag: %s", out.String())
+	}
+	out.Reset()
+	run([]string{"sm3", "hash", "--input", "[]"}, &out)
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil || res.ErrorCode != codeInvalidRequest {
+		t.Errorf("bad request: %s", out.String())
+	}
+}
+
+// Requests that are malformed beyond their field types are bad input,
+// not failed operations.
+func TestRequestValidationCodes(t *testing.T) {
+	keys := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	encCtx := mustCall(t, "sm4", "encrypt-init", map[string]interface{}{"key": testSM4Key}).Context
+	decCtx := mustCall(t, "sm4", "decrypt-init", map[string]interface{}{"key": testSM4Key}).Context
+	subject := map[string]interface{}{"CN": "test"}
+	for _, c := range []struct {
+		algorithm, operation string
+		in                   map[string]interface{}
+		code                 string
+	}{
+		{"sm2", "sign", map[string]interface{}{"private_key": keys.PrivateKey, "message": "m", "user_id": strings.Repeat("u", 8192)}, codeInvalidField},
+		{"sm2", "validate-key", map[string]interface{}{}, codeMissingField},
+		{"sm2", "encrypt", map[string]interface{}{"public_key": keys.PublicKey, "plaintext": "x", "encoding": "asn1", "ciphertext_format": "c1c2c3"}, codeUnsupportedOption},
+		{"sm3", "hash", map[string]interface{}{"data_list": []interface{}{"a"}, "per_item": true, "expected": "00"}, codeInvalidField},
+		{"sm3", "update", map[string]interface{}{"context": "00", "data": "a"}, codeInvalidEncoding},
+		{"sm4", "encrypt-update", map[string]interface{}{"context": "eA", "plaintext": "x"}, codeInvalidEncoding},
+		{"sm4", "decrypt-update", map[string]interface{}{"context": encCtx, "ciphertext": "00"}, codeInvalidField},
+		{"sm4", "encrypt-init", map[string]interface{}{"key": testSM4Key, "mode": "GCM"}, codeUnsupportedOption},
+		{"sm4", "decrypt-final", map[string]interface{}{"context": decCtx, "ciphertext": "00"}, codeInvalidDataLength},
+		{"keystore", "create", map[string]interface{}{"keystore_file": filepath.Join(t.TempDir(), "ks"), "password": ""}, codeInvalidField},
+		{"sm2", "cert-selfsign", map[string]interface{}{"subject": subject, "validity_days": 0}, codeInvalidField},
+		{"sm2", "jws-sign", map[string]interface{}{"payload": "p", "header": "x"}, codeInvalidField},
+		{"sm2", "jwt-sign", map[string]interface{}{"claims": "x"}, codeInvalidField},
+		{"sm2", "keygen", map[string]interface{}{"input_file": "x"}, codeInvalidField},
+		{"sm2", "verify", map[string]interface{}{"public_key": keys.PublicKey, "message": "m", "signature": "00", "output_file": "x"}, codeInvalidField},
+	} {
+		if res := mustFail(t, c.algorithm, c.operation, c.in); res.ErrorCode != c.code {
+			t.Errorf("%s %s: error_code = %q (%s), want %q", c.algorithm, c.operation, res.ErrorCode, res.Message, c.code)
+		}
+	}
+}
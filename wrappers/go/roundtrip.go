@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"strings"
+	"time"
+)
+
+const roundtripUsage = "roundtrip [--algorithm sm2|sm4|sm9|zuc|all] [--iterations N] [--seed N]"
+
+// roundTrip is a combination of operations and options whose inverse must
+// give back its input. Each iteration draws a fresh key and input.
+type roundTrip struct {
+	algorithm string
+	name      string
+	run       func(g *vectorGen) error
+}
+
+// roundtripReport is the output of roundtrip.
+type roundtripReport struct {
+	Seed         int64              `json:"seed"`
+	Iterations   int                `json:"iterations"`
+	Passed       int                `json:"passed"`
+	Failed       int                `json:"failed"`
+	Combinations []roundtripOutcome `json:"combinations"`
+}
+
+type roundtripOutcome struct {
+	Algorithm   string `json:"algorithm"`
+	Combination string `json:"combination"`
+	Passed      bool   `json:"passed"`
+	// Failures counts the failed iterations, and Error describes the
+	// first.
+	Failures int    `json:"failures,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// roundtripCommand runs every roundTrip of the selected algorithms for a
+// number of iterations and writes a roundtripReport. As in gen-vectors,
+// the wrapper's randomness comes from the seed, so a failure can be
+// replayed. The exit code is exitInvalid if a combination failed.
+func roundtripCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("roundtrip", flag.ContinueOnError)
+	algorithm := fs.String("algorithm", "all", "algorithm to test: sm2, sm4, sm9, zuc or all")
+	iterations := fs.Int("iterations", 10, "iterations of each combination")
+	seed := fs.Int64("seed", 0, "seed of the keys and inputs (default: from the clock)")
+	if ok, code := parseCommand(fs, roundtripUsage, args, 0, stdout); !ok {
+		return code
+	}
+	trips := roundTrips()
+	algorithms := map[string]bool{}
+	for _, t := range trips {
+		algorithms[t.algorithm] = true
+	}
+	if *algorithm != "all" && !algorithms[*algorithm] {
+		return commandFailed(stdout, fmt.Errorf("unsupported --algorithm %q (supported: %s, all)", *algorithm, strings.Join(sortedKeys(algorithms), ", ")))
+	}
+	if *iterations < 1 {
+		return commandFailed(stdout, fmt.Errorf("--iterations must be positive, got %d", *iterations))
+	}
+	seeded := false
+	fs.Visit(func(f *flag.Flag) { seeded = seeded || f.Name == "seed" })
+	if !seeded {
+		*seed = time.Now().UnixNano()
+	}
+
+	g, restore := seededGen(*seed)
+	defer restore()
+	report := &roundtripReport{Seed: *seed, Iterations: *iterations, Combinations: []roundtripOutcome{}}
+	for _, t := range trips {
+		if *algorithm != "all" && t.algorithm != *algorithm {
+			continue
+		}
+		o := roundtripOutcome{Algorithm: t.algorithm, Combination: t.name}
+		for i := 0; i < *iterations; i++ {
+			if err := t.run(g); err != nil {
+				if o.Failures == 0 {
+					o.Error = fmt.Sprintf("iteration %d: %v", i, err)
+				}
+				o.Failures++
+			}
+		}
+		if o.Passed = o.Failures == 0; o.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Combinations = append(report.Combinations, o)
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if report.Failed > 0 {
+		return exitInvalid
+	}
+	return exitSuccess
+}
+
+// roundTrips lists the combinations: SM4 in every mode, and with every
+// padding in ECB and CBC, SM4 key wrapping, SM2
+// signatures and ciphertexts in each format, SM2 and SM9 key
+// encapsulation, SM9 signatures and encryption, and ZUC-128 and ZUC-256.
+func roundTrips() []roundTrip {
+	var trips []roundTrip
+	for _, mode := range sm4Modes {
+		pads := []string{""}
+		if mode == "ECB" || mode == "CBC" {
+			pads = paddings
+		}
+		for _, pad := range pads {
+			name := strings.TrimSpace(mode + " " + pad)
+			trips = append(trips, roundTrip{"sm4", "encrypt/decrypt " + name, func(g *vectorGen) error { return sm4RoundTrip(g, mode, pad) }})
+		}
+	}
+	trips = append(trips, roundTrip{"sm4", "wrap/unwrap", sm4WrapRoundTrip})
+	for _, format := range []string{sigFormatDER, sigFormatRS} {
+		trips = append(trips, roundTrip{"sm2", "sign/verify " + format, func(g *vectorGen) error { return sm2SignRoundTrip(g, format) }})
+	}
+	for _, f := range [][2]string{{ctFormatC1C3C2, "raw"}, {ctFormatC1C2C3, "raw"}, {ctFormatC1C3C2, "asn1"}} {
+		trips = append(trips, roundTrip{"sm2", "encrypt/decrypt " + f[0] + " " + f[1], func(g *vectorGen) error { return sm2EncryptRoundTrip(g, f[0], f[1]) }})
+	}
+	trips = append(trips,
+		roundTrip{"sm2", "encapsulate/decapsulate", sm2KEMRoundTrip},
+		roundTrip{"sm9", "sign/verify", sm9SignRoundTrip},
+		roundTrip{"sm9", "encrypt/decrypt", sm9EncryptRoundTrip},
+		roundTrip{"sm9", "encapsulate/decapsulate", sm9KEMRoundTrip},
+		roundTrip{"zuc", "encrypt/decrypt 128", func(g *vectorGen) error { return zucRoundTrip(g, false) }},
+		roundTrip{"zuc", "encrypt/decrypt 256", func(g *vectorGen) error { return zucRoundTrip(g, true) }},
+	)
+	return trips
+}
+
+// call runs an operation that must succeed.
+func (g *vectorGen) call(algorithm, operation string, in map[string]interface{}) (*Result, error) {
+	res := dispatch(algorithm, operation, in)
+	if res.Status != statusSuccess {
+		return nil, fmt.Errorf("%s %s failed: %s", algorithm, operation, res.Message)
+	}
+	return res, nil
+}
+
+// same reports got if it differs from want.
+func same(what, got, want string) error {
+	if got != want {
+		return fmt.Errorf("%s %q, want %q", what, got, want)
+	}
+	return nil
+}
+
+// valid reports a verification that failed.
+func valid(res *Result) error {
+	if res.Valid == nil || !*res.Valid {
+		return fmt.Errorf("verification failed: %s", res.Reason)
+	}
+	return nil
+}
+
+func sm4RoundTrip(g *vectorGen, mode, pad string) error {
+	n := g.r.IntN(257)
+	keySize := 16
+	in := map[string]interface{}{"mode": mode}
+	switch {
+	case mode == "XTS":
+		keySize = 32
+		n = 16 + g.r.IntN(241)
+		in["tweak"] = g.hex(16)
+	case pad == paddingNone:
+		n -= n % 16
+	}
+	if pad != "" {
+		in["padding"] = pad
+	}
+	in["key"] = g.hex(keySize)
+	data := g.bytes(n)
+	if pad == paddingZeros && n > 0 && data[n-1] == 0 {
+		// Zero padding cannot be told from trailing zero bytes.
+		data[n-1] = 1
+	}
+	plaintext := hex.EncodeToString(data)
+	enc := maps.Clone(in)
+	enc["plaintext_hex"] = plaintext
+	res, err := g.call("sm4", "encrypt", enc)
+	if err != nil {
+		return err
+	}
+	in["ciphertext"], in["plaintext_encoding"] = res.Output, encodingHex
+	if res.IV != "" {
+		in["iv"] = res.IV
+	}
+	if res.Tag != "" {
+		in["tag"] = res.Tag
+	}
+	if res, err = g.call("sm4", "decrypt", in); err != nil {
+		return err
+	}
+	return same("decrypted", res.Output, plaintext)
+}
+
+func sm4WrapRoundTrip(g *vectorGen) error {
+	kek, data := g.hex(16), g.hex(16+8*g.r.IntN(5))
+	res, err := g.call("sm4", "wrap", map[string]interface{}{"key": kek, "key_data": data})
+	if err != nil {
+		return err
+	}
+	if res, err = g.call("sm4", "unwrap", map[string]interface{}{"key": kek, "wrapped_key": res.Output}); err != nil {
+		return err
+	}
+	return same("unwrapped", res.Output, data)
+}
+
+func sm2SignRoundTrip(g *vectorGen, format string) error {
+	msg := g.text(g.r.IntN(257))
+	res, err := g.call("sm2", "sign", map[string]interface{}{"message": msg, "signature_format": format})
+	if err != nil {
+		return err
+	}
+	if res, err = g.call("sm2", "verify", map[string]interface{}{"message": msg, "signature_format": format,
+		"public_key": res.PublicKey, "signature": res.Output}); err != nil {
+		return err
+	}
+	return valid(res)
+}
+
+func sm2EncryptRoundTrip(g *vectorGen, format, encoding string) error {
+	msg := g.text(1 + g.r.IntN(256))
+	res, err := g.call("sm2", "encrypt", map[string]interface{}{"plaintext": msg, "ciphertext_format": format, "encoding": encoding})
+	if err != nil {
+		return err
+	}
+	if res, err = g.call("sm2", "decrypt", map[string]interface{}{"ciphertext": res.Output, "ciphertext_format": format,
+		"encoding": encoding, "private_key": res.PrivateKey}); err != nil {
+		return err
+	}
+	return same("decrypted", res.Output, msg)
+}
+
+func sm2KEMRoundTrip(g *vectorGen) error {
+	klen := json.Number(fmt.Sprint(1 + g.r.IntN(64)))
+	res, err := g.call("sm2", "encapsulate", map[string]interface{}{"key_length": klen})
+	if err != nil {
+		return err
+	}
+	key := res.Output
+	if res, err = g.call("sm2", "decapsulate", map[string]interface{}{"encapsulation": res.Encapsulation,
+		"private_key": res.PrivateKey, "key_length": klen}); err != nil {
+		return err
+	}
+	return same("decapsulated", res.Output, key)
+}
+
+// sm9Keys returns a master public key of type and the key of a random
+// user under it.
+func sm9Keys(g *vectorGen, typ string) (master, user, id string, err error) {
+	res, err := g.call("sm9", "master-keygen", map[string]interface{}{"type": typ})
+	if err != nil {
+		return "", "", "", err
+	}
+	id = g.text(1 + g.r.IntN(32))
+	u, err := g.call("sm9", "user-keygen", map[string]interface{}{"type": typ, "master_private_key": res.PrivateKey, "id": id})
+	if err != nil {
+		return "", "", "", err
+	}
+	return res.PublicKey, u.PrivateKey, id, nil
+}
+
+func sm9SignRoundTrip(g *vectorGen) error {
+	master, user, id, err := sm9Keys(g, "sign")
+	if err != nil {
+		return err
+	}
+	msg := g.text(g.r.IntN(257))
+	res, err := g.call("sm9", "sign", map[string]interface{}{"message": msg, "private_key": user, "master_public_key": master})
+	if err != nil {
+		return err
+	}
+	if res, err = g.call("sm9", "verify", map[string]interface{}{"message": msg, "signature": res.Output,
+		"id": id, "master_public_key": master}); err != nil {
+		return err
+	}
+	return valid(res)
+}
+
+func sm9EncryptRoundTrip(g *vectorGen) error {
+	master, user, id, err := sm9Keys(g, "encrypt")
+	if err != nil {
+		return err
+	}
+	msg := g.text(1 + g.r.IntN(256))
+	res, err := g.call("sm9", "encrypt", map[string]interface{}{"plaintext": msg, "id": id, "master_public_key": master})
+	if err != nil {
+		return err
+	}
+	if res, err = g.call("sm9", "decrypt", map[string]interface{}{"ciphertext": res.Output, "id": id, "private_key": user}); err != nil {
+		return err
+	}
+	return same("decrypted", res.Output, msg)
+}
+
+func sm9KEMRoundTrip(g *vectorGen) error {
+	master, user, id, err := sm9Keys(g, "encrypt")
+	if err != nil {
+		return err
+	}
+	klen := json.Number(fmt.Sprint(1 + g.r.IntN(64)))
+	res, err := g.call("sm9", "encapsulate", map[string]interface{}{"id": id, "master_public_key": master, "key_length": klen})
+	if err != nil {
+		return err
+	}
+	key := res.Output
+	if res, err = g.call("sm9", "decapsulate", map[string]interface{}{"encapsulation": res.Encapsulation,
+		"id": id, "private_key": user, "key_length": klen}); err != nil {
+		return err
+	}
+	return same("decapsulated", res.Output, key)
+}
+
+func zucRoundTrip(g *vectorGen, zuc256 bool) error {
+	in := map[string]interface{}{}
+	if zuc256 {
+		in["key"], in["iv"] = g.hex(32), g.hex(23)
+	} else {
+		in["key"] = g.hex(16)
+		in["count"] = json.Number(fmt.Sprint(g.r.Uint32()))
+		in["bearer"] = json.Number(fmt.Sprint(g.r.IntN(32)))
+		in["direction"] = json.Number(fmt.Sprint(g.r.IntN(2)))
+	}
+	plaintext := g.hex(g.r.IntN(257))
+	enc := maps.Clone(in)
+	enc["plaintext_hex"] = plaintext
+	res, err := g.call("zuc", "encrypt", enc)
+	if err != nil {
+		return err
+	}
+	in["ciphertext"], in["plaintext_encoding"] = res.Output, encodingHex
+	if res, err = g.call("zuc", "decrypt", in); err != nil {
+		return err
+	}
+	return same("decrypted", res.Output, plaintext)
+}
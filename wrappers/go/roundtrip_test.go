@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestRoundtrip(t *testing.T) {
+	roundtrip := func(args ...string) (*roundtripReport, int) {
+		t.Helper()
+		var out bytes.Buffer
+		code := run(append([]string{"roundtrip"}, args...), &out)
+		var report roundtripReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("roundtrip %q: %s", args, out.String())
+		}
+		return &report, code
+	}
+	report, code := roundtrip("--iterations", "2", "--seed", "3")
+	if code != 0 || report.Failed != 0 || report.Passed != len(roundTrips()) || report.Seed != 3 {
+		t.Fatalf("exit %d: %+v", code, report)
+	}
+	again, _ := roundtrip("--iterations", "2", "--seed", "3")
+	if len(again.Combinations) != len(report.Combinations) {
+		t.Errorf("the same seed gave %+v", again)
+	}
+
+	// A decryption that loses a byte fails both ZUC combinations.
+	decrypt := handlers["zuc"]["decrypt"]
+	defer func() { handlers["zuc"]["decrypt"] = decrypt }()
+	handlers["zuc"]["decrypt"] = func(in map[string]interface{}) (*Result, error) {
+		res, err := decrypt(in)
+		if err == nil && res.Output != "" {
+			res.Output = res.Output[2:]
+		}
+		return res, err
+	}
+	report, code = roundtrip("--algorithm", "zuc", "--iterations", "4")
+	if code != exitInvalid || report.Failed != 2 || report.Passed != 0 {
+		t.Fatalf("broken ZUC: exit %d, %+v", code, report)
+	}
+	for _, o := range report.Combinations {
+		if o.Passed || o.Failures == 0 || !strings.HasPrefix(o.Error, "iteration ") {
+			t.Errorf("%s: %+v", o.Combination, o)
+		}
+	}
+
+	for _, args := range [][]string{{"roundtrip", "--algorithm", "sm3"}, {"roundtrip", "--iterations", "0"}, {"roundtrip", "extra"}} {
+		var out bytes.Buffer
+		if code := run(args, &out); code != exitUsage {
+			t.Errorf("%q exited %d: %s", args, code, out.String())
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// JSON-RPC 2.0 error codes. A failed operation is rpcOperationFailed,
+// with its error Result as the data.
+const (
+	rpcParseError      = -32700
+	rpcInvalidRequest  = -32600
+	rpcMethodNotFound  = -32601
+	rpcInvalidParams   = -32602
+	rpcOperationFailed = -32000
+)
+
+type rpcRequest struct {
+	JSONRPC string `json:"jsonrpc"`
+	// ID is nil for notifications, which get no response.
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  *Result         `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int     `json:"code"`
+	Message string  `json:"message"`
+	Data    *Result `json:"data,omitempty"`
+}
+
+// serveStdio speaks JSON-RPC 2.0 on standard input and output, one
+// message per line, until the input ends. See rpcAnswer.
+func serveStdio(stdout io.Writer) int {
+	return serveLines(stdout, rpcAnswer)
+}
+
+// rpcAnswer answers one JSON-RPC message, or returns nil when no answer is
+// due. The method is "<algorithm>.<operation>" and the params are the
+// request object; the result is the Result. Batches (arrays) are answered
+// with arrays.
+func rpcAnswer(msg []byte) interface{} {
+	msg = bytes.TrimSpace(msg)
+	if len(msg) == 0 || msg[0] != '[' {
+		if res := rpcCall(msg); res != nil {
+			return res
+		}
+		return nil
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(msg, &batch); err != nil {
+		return rpcFailure(nil, rpcParseError, err.Error())
+	}
+	if len(batch) == 0 {
+		return rpcFailure(nil, rpcInvalidRequest, "empty batch")
+	}
+	var replies []*rpcResponse
+	for _, m := range batch {
+		if res := rpcCall(m); res != nil {
+			replies = append(replies, res)
+		}
+	}
+	if len(replies) == 0 {
+		return nil
+	}
+	return replies
+}
+
+func rpcFailure(id json.RawMessage, code int, message string) *rpcResponse {
+	return &rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}}
+}
+
+// rpcCall runs one JSON-RPC message and returns its response, or nil for a
+// notification.
+func rpcCall(msg []byte) *rpcResponse {
+	var req rpcRequest
+	if err := json.Unmarshal(msg, &req); err != nil {
+		if _, ok := err.(*json.UnmarshalTypeError); ok {
+			return rpcFailure(nil, rpcInvalidRequest, "a request must be an object")
+		}
+		return rpcFailure(nil, rpcParseError, err.Error())
+	}
+	if req.JSONRPC != "2.0" || req.Method == "" {
+		return rpcFailure(req.ID, rpcInvalidRequest, "a request needs \"jsonrpc\": \"2.0\" and a \"method\"")
+	}
+	res := rpcDispatch(req)
+	if req.ID == nil {
+		return nil
+	}
+	res.ID = req.ID
+	return res
+}
+
+func rpcDispatch(req rpcRequest) *rpcResponse {
+	algorithm, operation, ok := strings.Cut(strings.ToLower(req.Method), ".")
+	if !ok {
+		return rpcFailure(nil, rpcMethodNotFound, fmt.Sprintf("method %q is not <algorithm>.<operation>", req.Method))
+	}
+	if _, err := lookup(algorithm, operation); err != nil {
+		return rpcFailure(nil, rpcMethodNotFound, err.Error())
+	}
+	in := map[string]interface{}{}
+	if len(req.Params) > 0 && string(req.Params) != "null" {
+		dec := json.NewDecoder(bytes.NewReader(req.Params))
+		dec.UseNumber()
+		if err := dec.Decode(&in); err != nil || in == nil {
+			return rpcFailure(nil, rpcInvalidParams, "params must be an object")
+		}
+	}
+	res := serverDispatch(algorithm, operation, in)
+	if res.Status != statusSuccess {
+		return &rpcResponse{JSONRPC: "2.0", Error: &rpcError{Code: rpcOperationFailed, Message: res.Message, Data: res}}
+	}
+	return &rpcResponse{JSONRPC: "2.0", Result: res}
+}
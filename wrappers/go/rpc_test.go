@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestServeStdio(t *testing.T) {
+	saved := stdin
+	defer func() { stdin = saved }()
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+
+	stdin = strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "sm3.hash", "params": {"data": "abc"}}
+{"jsonrpc": "2.0", "method": "sm2.keygen"}
+{"jsonrpc": "2.0", "id": "b", "method": "sm4.encrypt", "params": {"key": "00", "plaintext": "x"}}
+{"jsonrpc": "2.0", "id": 3, "method": "sm3.digest"}
+{"jsonrpc": "2.0", "id": 4, "method": "sm3.hash", "params": [1]}
+not json
+[{"jsonrpc": "2.0", "id": 5, "method": "sm3.hash", "params": {"data": "abc"}}, {"jsonrpc": "2.0", "method": "sm2.keygen"}, 1]
+{"id": 6, "method": "sm3.hash"}`)
+	var out bytes.Buffer
+	if code := run([]string{"--serve-stdio"}, &out); code != 0 {
+		t.Fatalf("exit %d at EOF", code)
+	}
+
+	type response struct {
+		JSONRPC string          `json:"jsonrpc"`
+		ID      json.RawMessage `json:"id"`
+		Result  *Result         `json:"result"`
+		Error   *struct {
+			Code int     `json:"code"`
+			Data *Result `json:"data"`
+		} `json:"error"`
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	// The notification gets no response.
+	if len(lines) != 7 {
+		t.Fatalf("%d responses, want 7:\n%s", len(lines), out.String())
+	}
+	var r []response
+	for i, line := range lines {
+		if i == 5 {
+			var batch []response
+			if err := json.Unmarshal([]byte(line), &batch); err != nil || len(batch) != 2 {
+				t.Fatalf("batch response %q", line)
+			}
+			if batch[0].Result == nil || batch[0].Result.Output != abc || batch[1].Error.Code != rpcInvalidRequest {
+				t.Errorf("batch response %q", line)
+			}
+			continue
+		}
+		var res response
+		if err := json.Unmarshal([]byte(line), &res); err != nil || res.JSONRPC != "2.0" {
+			t.Fatalf("response %q", line)
+		}
+		r = append(r, res)
+	}
+	if string(r[0].ID) != "1" || r[0].Result == nil || r[0].Result.Output != abc {
+		t.Errorf("sm3.hash: %+v", r[0])
+	}
+	if string(r[1].ID) != `"b"` || r[1].Error.Code != rpcOperationFailed || r[1].Error.Data.ErrorCode != codeInvalidKeyLength {
+		t.Errorf("failed operation: %+v", r[1])
+	}
+	for i, want := range []int{rpcMethodNotFound, rpcInvalidParams, rpcParseError, rpcInvalidRequest} {
+		if res := r[i+2]; res.Error == nil || res.Error.Code != want {
+			t.Errorf("response %d: %+v, want error %d", i+2, res, want)
+		}
+	}
+
+	for _, args := range [][]string{{"--serve-stdio", "--batch"}, {"sm3", "hash", "--serve-stdio"}} {
+		if code := run(args, &out); code == 0 {
+			t.Errorf("%q was accepted", args)
+		}
+	}
+}
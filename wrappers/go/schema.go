@@ -0,0 +1,466 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// requestFields lists, by operation, the request fields its handler reads,
+// by their plain names: a byte field also takes its _hex and _base64 forms.
+// The fields read around every handler (commonFields, the files of ioSpecs,
+// "plaintext_encoding" of a plaintext output and "reject_weak_key" of the
+// sm4 operations) are added by operationSchema. The schemas and the --help
+// of the operations are built from this table.
+var requestFields = map[string]map[string][]string{
+	"sm2": {
+		"sign":                 {"deterministic", "digest", "input_file", "key_cipher", "key_format", "message", "passphrase", "point_format", "prehashed", "private_key", "signature_format", "user_id"},
+		"verify":               {"digest", "input_file", "key_format", "message", "passphrase", "prehashed", "public_key", "signature", "signature_format", "user_id"},
+		"encrypt":              {"ciphertext_format", "encoding", "key_cipher", "key_format", "passphrase", "plaintext", "point_format", "public_key"},
+		"decrypt":              {"ciphertext", "ciphertext_format", "encoding", "key_format", "passphrase", "plaintext_encoding", "private_key"},
+		"keygen":               {"key_cipher", "key_format", "passphrase", "point_format"},
+		"digest":               {"input_file", "key_format", "message", "passphrase", "public_key", "user_id"},
+		"verify-batch":         {"items"},
+		"recover-pub":          {"digest", "input_file", "key_cipher", "key_format", "message", "passphrase", "point_format", "signature", "signature_format", "user_id"},
+		"envelope-encrypt":     {"certificate", "content_cipher", "key_cipher", "key_format", "passphrase", "plaintext", "point_format", "public_key"},
+		"envelope-decrypt":     {"ciphertext", "key_cipher", "key_format", "passphrase", "plaintext_encoding", "point_format", "private_key"},
+		"cms-encrypt":          {"certificate", "content_cipher", "key_cipher", "key_format", "passphrase", "plaintext", "point_format", "public_key"},
+		"cms-decrypt":          {"ciphertext", "key_cipher", "key_format", "passphrase", "plaintext_encoding", "point_format", "private_key"},
+		"key-split":            {"key_cipher", "key_format", "passphrase", "point_format", "private_key", "share_count", "threshold"},
+		"key-combine":          {"key_cipher", "key_format", "passphrase", "point_format", "shares"},
+		"p7-sign":              {"certificate", "detached", "key_cipher", "key_format", "message", "passphrase", "point_format", "private_key", "signed_attributes", "user_id"},
+		"p7-verify":            {"certificate", "key_cipher", "key_format", "message", "passphrase", "plaintext_encoding", "point_format", "public_key", "signed_data", "user_id"},
+		"cert-selfsign":        {"dns_names", "emails", "ext_key_usage", "ip_addresses", "is_ca", "key_cipher", "key_format", "key_usage", "max_path_len", "not_after", "not_before", "passphrase", "point_format", "private_key", "serial", "subject", "user_id", "validity_days"},
+		"cert-issue":           {"csr", "csr_user_id", "dns_names", "emails", "ext_key_usage", "ip_addresses", "is_ca", "issuer_certificate", "key_cipher", "key_format", "key_usage", "max_path_len", "not_after", "not_before", "passphrase", "point_format", "private_key", "public_key", "serial", "subject", "user_id", "validity_days"},
+		"cert-verify-chain":    {"certificate", "ext_key_usage", "intermediates", "roots", "time", "user_id"},
+		"cert-parse":           {"certificate"},
+		"csr-create":           {"dns_names", "emails", "ext_key_usage", "ip_addresses", "is_ca", "key_cipher", "key_format", "key_usage", "max_path_len", "passphrase", "point_format", "private_key", "subject", "user_id"},
+		"csr-verify":           {"csr", "key_cipher", "key_format", "passphrase", "point_format", "user_id"},
+		"p12-create":           {"ca_certificates", "certificate", "friendly_name", "key_cipher", "key_format", "passphrase", "password", "point_format", "private_key"},
+		"p12-parse":            {"key_cipher", "key_format", "passphrase", "password", "pfx", "point_format"},
+		"crl-create":           {"crl_number", "issuer_certificate", "key_cipher", "key_format", "next_update", "passphrase", "point_format", "private_key", "revoked", "this_update", "user_id", "validity_days"},
+		"crl-check":            {"certificate", "crl", "issuer_certificate", "time", "user_id"},
+		"ocsp-request":         {"certificate", "hash_algorithm", "issuer_certificate", "no_nonce", "nonce"},
+		"ocsp-respond":         {"error_status", "issuer_certificate", "key_cipher", "key_format", "next_update", "passphrase", "point_format", "private_key", "request", "responder_certificate", "revocation_reason", "revocation_time", "status", "this_update", "user_id"},
+		"ocsp-verify":          {"certificate", "issuer_certificate", "nonce", "response", "time", "user_id"},
+		"tsa-request":          {"cert_req", "digest", "hash_algorithm", "message", "no_nonce", "nonce", "policy"},
+		"tsa-sign":             {"accuracy_ms", "certificate", "error_status", "key_cipher", "key_format", "passphrase", "point_format", "policy", "private_key", "request", "serial", "time", "user_id"},
+		"tsa-verify":           {"certificate", "digest", "message", "nonce", "response", "token", "user_id"},
+		"jws-sign":             {"detached", "header", "key_cipher", "key_format", "kid", "passphrase", "payload", "point_format", "private_key", "user_id"},
+		"jws-verify":           {"key_cipher", "key_format", "passphrase", "payload", "plaintext_encoding", "point_format", "public_key", "token", "user_id"},
+		"jwt-sign":             {"claims", "expires_in", "header", "key_cipher", "key_format", "kid", "passphrase", "point_format", "private_key", "time", "user_id"},
+		"jwt-verify":           {"audience", "clock_skew", "issuer", "key_cipher", "key_format", "passphrase", "point_format", "public_key", "require_exp", "time", "token", "user_id"},
+		"jwe-encrypt":          {"header", "key_cipher", "key_format", "kid", "passphrase", "plaintext", "point_format", "public_key"},
+		"jwe-decrypt":          {"key_cipher", "key_format", "passphrase", "plaintext_encoding", "point_format", "private_key", "token"},
+		"cose-sign":            {"detached", "deterministic", "external_aad", "key_cipher", "key_format", "kid", "passphrase", "payload", "point_format", "private_key", "untagged", "user_id"},
+		"cose-verify":          {"external_aad", "key_cipher", "key_format", "message", "passphrase", "payload", "plaintext_encoding", "point_format", "public_key", "user_id"},
+		"convert-signature":    {"signature", "signature_format"},
+		"derive-pub":           {"key_cipher", "key_format", "passphrase", "point_format", "private_key"},
+		"validate-key":         {"key_format", "passphrase", "private_key", "public_key"},
+		"keyexchange-init":     {},
+		"keyexchange-respond":  {"ephemeral_private_key", "key_cipher", "key_format", "key_length", "passphrase", "peer_ephemeral_public_key", "peer_public_key", "peer_user_id", "point_format", "private_key", "user_id"},
+		"keyexchange-confirm":  {"confirmation", "ephemeral_private_key", "key_cipher", "key_format", "key_length", "passphrase", "peer_ephemeral_public_key", "peer_public_key", "peer_user_id", "point_format", "private_key", "role", "user_id"},
+		"encapsulate":          {"key_cipher", "key_format", "key_length", "passphrase", "point_format", "public_key"},
+		"decapsulate":          {"encapsulation", "key_cipher", "key_format", "key_length", "passphrase", "point_format", "private_key"},
+		"ecdh":                 {"key_cipher", "key_format", "key_length", "passphrase", "peer_public_key", "point_format", "private_key"},
+		"cosign-keygen-client": {},
+		"cosign-keygen-server": {"key_cipher", "key_format", "passphrase", "point_format", "public_share"},
+		"cosign-sign-client":   {"digest", "input_file", "key_format", "message", "passphrase", "prehashed", "public_key", "user_id"},
+		"cosign-sign-server":   {"digest", "peer_ephemeral_public_key", "private_key"},
+		"cosign-sign-finish":   {"ephemeral_private_key", "partial_signature", "private_key", "signature_format"},
+	},
+	"sm3": {
+		"hash":   {"data", "data_encoding", "data_list", "expected", "input_file", "per_item", "stdin"},
+		"hmac":   {"data", "data_encoding", "key", "mac"},
+		"hkdf":   {"info", "key", "key_length", "salt", "stage"},
+		"kdf":    {"key_length", "shared_secret"},
+		"pbkdf2": {"iterations", "key_length", "password", "salt"},
+		"mgf1":   {"length", "seed"},
+		"init":   {},
+		"update": {"context", "data", "data_encoding"},
+		"final":  {"context", "data", "data_encoding"},
+	},
+	"keystore": {
+		"create": {"keystore_file", "overwrite", "password"},
+		"list":   {"keystore_file", "password"},
+		"get":    {"key_cipher", "key_format", "keystore_file", "name", "passphrase", "password", "point_format"},
+		"put":    {"key", "key_cipher", "key_format", "keystore_file", "name", "overwrite", "passphrase", "password", "point_format", "private_key", "type"},
+		"delete": {"keystore_file", "name", "password"},
+	},
+	"sm9": {
+		"master-keygen": {"master_private_key", "type"},
+		"user-keygen":   {"hid", "id", "master_private_key", "type"},
+		"sign":          {"master_public_key", "message", "private_key", "signature_format"},
+		"verify":        {"hid", "id", "master_public_key", "message", "signature", "signature_format"},
+		"encrypt":       {"encoding", "hid", "id", "master_public_key", "plaintext"},
+		"decrypt":       {"ciphertext", "encoding", "id", "plaintext_encoding", "private_key"},
+		"encapsulate":   {"hid", "id", "key_length", "master_public_key"},
+		"decapsulate":   {"encapsulation", "id", "key_length", "private_key"},
+	},
+	"sm4": {
+		"encrypt":        {"aad", "feedback_size", "input_file", "iv", "key", "mode", "output_file", "padding", "plaintext", "sector", "tag_length", "tweak"},
+		"decrypt":        {"aad", "ciphertext", "feedback_size", "input_file", "iv", "key", "mode", "output_file", "padding", "plaintext_encoding", "sector", "tag", "tag_length", "tweak"},
+		"wrap":           {"key", "key_data"},
+		"unwrap":         {"key", "wrapped_key"},
+		"cmac":           {"data", "data_encoding", "key", "mac"},
+		"cbcmac":         {"data", "data_encoding", "key", "mac", "variant"},
+		"cose-encrypt":   {"external_aad", "iv", "key", "kid", "plaintext", "untagged"},
+		"cose-decrypt":   {"external_aad", "key", "message", "plaintext_encoding"},
+		"encrypt-init":   {"feedback_size", "iv", "key", "mode", "padding"},
+		"encrypt-update": {"context", "plaintext"},
+		"encrypt-final":  {"context", "plaintext"},
+		"decrypt-init":   {"feedback_size", "iv", "key", "mode", "padding"},
+		"decrypt-update": {"ciphertext", "context", "plaintext_encoding"},
+		"decrypt-final":  {"ciphertext", "context", "plaintext_encoding"},
+	},
+	"zuc": {
+		"encrypt":   {"bearer", "count", "direction", "iv", "key", "length", "plaintext"},
+		"decrypt":   {"bearer", "ciphertext", "count", "direction", "iv", "key", "length", "plaintext_encoding"},
+		"mac":       {"bearer", "count", "data", "direction", "iv", "key", "length", "mac", "mac_length"},
+		"keystream": {"iv", "key", "length"},
+	},
+	"tlcp": {
+		"client": {"address", "cipher_suites", "enc_certificate", "enc_private_key", "insecure_skip_verify", "intermediates", "key_cipher", "key_format", "passphrase", "point_format", "probe", "response_length", "roots", "sign_certificate", "sign_private_key", "timeout", "user_id"},
+		"server": {"accept_timeout", "address", "cipher_suites", "client_auth", "connections", "enc_certificate", "enc_private_key", "insecure_skip_verify", "intermediates", "key_cipher", "key_format", "log_file", "passphrase", "point_format", "response", "roots", "sign_certificate", "sign_private_key", "timeout", "user_id"},
+	},
+	"tls13": {
+		"client": {"address", "certificate", "cipher_suites", "insecure_skip_verify", "intermediates", "key_cipher", "key_format", "passphrase", "point_format", "private_key", "probe", "response_length", "roots", "server_name", "signature_user_id", "timeout", "user_id"},
+		"server": {"accept_timeout", "address", "certificate", "cipher_suites", "client_auth", "connections", "insecure_skip_verify", "intermediates", "key_cipher", "key_format", "log_file", "passphrase", "point_format", "private_key", "response", "roots", "signature_user_id", "timeout", "user_id"},
+	},
+	"wrapper": {
+		"capabilities": {},
+		"selftest":     {},
+		"version":      {},
+	},
+}
+
+// requiredFields lists, by operation, the fields a request cannot do
+// without. Alternatives are separated by "|". Byte fields are also
+// satisfied by their _hex and _base64 forms, and the payload field of an
+// operation by "input_file". Fields that are only required in some
+// configurations (the IV of a mode, the certificate of an alternative) are
+// left to the handlers.
+var requiredFields = map[string]map[string][]string{
+	"sm2": {
+		"sign":                 {"message|input_file|digest"},
+		"verify":               {"message|input_file|digest", "public_key", "signature"},
+		"digest":               {"message", "public_key"},
+		"decrypt":              {"ciphertext", "private_key"},
+		"encrypt":              {"plaintext"},
+		"derive-pub":           {"private_key"},
+		"validate-key":         {"private_key|public_key"},
+		"verify-batch":         {"items"},
+		"recover-pub":          {"digest", "signature"},
+		"convert-signature":    {"signature"},
+		"envelope-encrypt":     {"plaintext", "public_key|certificate"},
+		"envelope-decrypt":     {"ciphertext", "private_key"},
+		"cms-encrypt":          {"plaintext", "public_key|certificate"},
+		"cms-decrypt":          {"ciphertext", "private_key"},
+		"key-split":            {"private_key", "share_count", "threshold"},
+		"key-combine":          {"shares"},
+		"p7-sign":              {"message", "private_key"},
+		"p7-verify":            {"signed_data"},
+		"cert-selfsign":        {"subject"},
+		"cert-issue":           {"issuer_certificate", "private_key"},
+		"cert-verify-chain":    {"certificate"},
+		"cert-parse":           {"certificate"},
+		"csr-create":           {"subject"},
+		"csr-verify":           {"csr"},
+		"p12-create":           {"private_key", "certificate", "password"},
+		"p12-parse":            {"pfx", "password"},
+		"crl-create":           {"issuer_certificate", "private_key"},
+		"crl-check":            {"crl", "certificate"},
+		"ocsp-request":         {"certificate", "issuer_certificate"},
+		"ocsp-verify":          {"response"},
+		"tsa-request":          {"message|digest"},
+		"tsa-verify":           {"response|token"},
+		"jws-sign":             {"payload"},
+		"jws-verify":           {"token", "public_key"},
+		"jwt-verify":           {"token", "public_key"},
+		"jwe-decrypt":          {"token", "private_key"},
+		"jwe-encrypt":          {"plaintext"},
+		"cose-sign":            {"payload"},
+		"cose-verify":          {"message"},
+		"keyexchange-respond":  {"private_key", "peer_public_key", "peer_ephemeral_public_key"},
+		"keyexchange-confirm":  {"private_key", "peer_public_key", "peer_ephemeral_public_key", "ephemeral_private_key"},
+		"encapsulate":          {"key_length"},
+		"decapsulate":          {"encapsulation", "private_key", "key_length"},
+		"ecdh":                 {"private_key", "peer_public_key"},
+		"cosign-keygen-server": {"public_share"},
+		"cosign-sign-client":   {"public_key", "message"},
+		"cosign-sign-server":   {"private_key", "digest", "peer_ephemeral_public_key"},
+		"cosign-sign-finish":   {"private_key", "ephemeral_private_key", "partial_signature"},
+	},
+	"sm3": {
+		"hash":   {"data|data_list|input_file|stdin"},
+		"hmac":   {"key", "data"},
+		"hkdf":   {"key"},
+		"kdf":    {"shared_secret", "key_length"},
+		"pbkdf2": {"password", "salt", "iterations", "key_length"},
+		"mgf1":   {"seed", "length"},
+		"update": {"context", "data"},
+		"final":  {"context"},
+	},
+	"sm4": {
+		"encrypt":        {"key", "plaintext"},
+		"decrypt":        {"key", "ciphertext"},
+		"wrap":           {"key", "key_data"},
+		"unwrap":         {"key", "wrapped_key"},
+		"cmac":           {"key", "data"},
+		"cbcmac":         {"key", "data"},
+		"cose-encrypt":   {"key", "plaintext"},
+		"cose-decrypt":   {"key", "message"},
+		"encrypt-init":   {"key"},
+		"encrypt-update": {"context", "plaintext"},
+		"encrypt-final":  {"context"},
+		"decrypt-init":   {"key"},
+		"decrypt-update": {"context", "ciphertext"},
+		"decrypt-final":  {"context"},
+	},
+	"zuc": {
+		"encrypt":   {"key", "plaintext"},
+		"decrypt":   {"key", "ciphertext"},
+		"mac":       {"key", "data"},
+		"keystream": {"key", "iv", "length"},
+	},
+	"keystore": {
+		"create": {"keystore_file", "password"},
+		"list":   {"keystore_file", "password"},
+		"get":    {"keystore_file", "password", "name"},
+		"put":    {"keystore_file", "password", "name"},
+		"delete": {"keystore_file", "password", "name"},
+	},
+	"sm9": {
+		"user-keygen": {"master_private_key", "id"},
+		"sign":        {"message", "private_key"},
+		"verify":      {"message", "signature", "id", "master_public_key"},
+		"encrypt":     {"plaintext", "id", "master_public_key"},
+		"decrypt":     {"ciphertext", "id", "private_key"},
+		"encapsulate": {"id", "master_public_key", "key_length"},
+		"decapsulate": {"encapsulation", "id", "private_key", "key_length"},
+	},
+	"tlcp": {
+		"client": {"address"},
+	},
+	"tls13": {
+		"client": {"address"},
+		"server": {"certificate", "private_key"},
+	},
+}
+
+// commonFields are accepted by every operation.
+var commonFields = []string{"output_encoding", "key_dir"}
+
+// schema describes the request of an operation: the kinds of the fields it
+// reads and the groups of alternatives of which one field is required.
+type schema struct {
+	fields   map[string]fieldKind
+	required [][]string
+	// payload is the field that "input_file" replaces.
+	payload string
+}
+
+// schemas holds the schema of every operation, built on first use.
+var schemas = sync.OnceValue(func() map[[2]string]*schema {
+	m := map[[2]string]*schema{}
+	for alg, ops := range requestFields {
+		for op := range ops {
+			m[[2]string{alg, op}] = operationSchema(alg, op)
+		}
+	}
+	return m
+})
+
+// operationSchema builds the schema of an operation from requestFields and
+// requiredFields.
+func operationSchema(algorithm, operation string) *schema {
+	spec, hasFiles := ioSpecs[algorithm][operation]
+	s := &schema{fields: map[string]fieldKind{}, payload: spec.field}
+	add := func(name string) {
+		s.fields[name] = fieldKinds[name]
+	}
+	for _, name := range requestFields[algorithm][operation] {
+		add(name)
+	}
+	for _, group := range requiredFields[algorithm][operation] {
+		names := strings.Split(group, "|")
+		s.required = append(s.required, names)
+		for _, name := range names {
+			add(name)
+		}
+	}
+	for _, name := range commonFields {
+		add(name)
+	}
+	if hasFiles {
+		add("input_file")
+		add("output_file")
+	}
+	if spec.out == outputPlaintext {
+		add("plaintext_encoding")
+	}
+	if algorithm == "sm4" {
+		add("reject_weak_key")
+	}
+	return s
+}
+
+// fieldError is a missing or mistyped field, as reported in
+// Result.FieldErrors.
+type fieldError struct {
+	Field string `json:"field"`
+	// Alternatives are the other fields that would satisfy a missing
+	// required field.
+	Alternatives []string `json:"alternatives,omitempty"`
+	// Problem is "missing" or "invalid".
+	Problem  string `json:"problem"`
+	Expected string `json:"expected"`
+	// Actual is the JSON type of an invalid field.
+	Actual string `json:"actual,omitempty"`
+}
+
+// validationError collects every fieldError of a request.
+type validationError struct {
+	fields []fieldError
+}
+
+func (e *validationError) Error() string {
+	var msgs []string
+	for _, f := range e.fields {
+		if f.Problem == "missing" {
+			names := fmt.Sprintf("%q", f.Field)
+			for _, alt := range f.Alternatives {
+				names += fmt.Sprintf(" or %q", alt)
+			}
+			msgs = append(msgs, fmt.Sprintf("missing required field %s (%s)", names, f.Expected))
+		} else {
+			msgs = append(msgs, fmt.Sprintf("field %q must be %s, not %s", f.Field, article(f.Expected), f.Actual))
+		}
+	}
+	return "invalid request: " + strings.Join(msgs, "; ")
+}
+
+// validate checks the request in against s and reports every problem at
+// once: ERR_INVALID_FIELD if a field has the wrong type, else
+// ERR_MISSING_FIELD. Values are checked by the handlers.
+func (s *schema) validate(in map[string]interface{}) error {
+	ve := &validationError{}
+	for _, name := range sortedKeys(s.fields) {
+		v, ok := in[name]
+		if !ok || v == nil {
+			continue
+		}
+		if actual := jsonType(v); !s.fields[name].accepts(v) {
+			ve.fields = append(ve.fields, fieldError{Field: name, Problem: "invalid", Expected: s.fields[name].String(), Actual: actual})
+		}
+	}
+	code := codeInvalidField
+	if len(ve.fields) == 0 {
+		code = codeMissingField
+	}
+	for _, group := range s.required {
+		if !s.present(in, group) {
+			ve.fields = append(ve.fields, fieldError{
+				Field: group[0], Alternatives: group[1:], Problem: "missing", Expected: s.fields[group[0]].String(),
+			})
+		}
+	}
+	if len(ve.fields) == 0 {
+		return nil
+	}
+	return &codedError{code, ve}
+}
+
+// present reports whether in sets one of the fields of group, in any of
+// their forms.
+func (s *schema) present(in map[string]interface{}, group []string) bool {
+	for _, name := range group {
+		forms := []string{name, name + "_hex", name + "_base64"}
+		if name == s.payload {
+			forms = append(forms, "input_file")
+		}
+		for _, f := range forms {
+			if v, ok := in[f]; ok && v != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// accepts reports whether the JSON value v has the type of kind.
+func (k fieldKind) accepts(v interface{}) bool {
+	switch k {
+	case fieldInt:
+		switch v := v.(type) {
+		case json.Number:
+			_, err := v.Int64()
+			return err == nil
+		case float64:
+			return v == float64(int64(v))
+		}
+		return false
+	case fieldBool:
+		_, ok := v.(bool)
+		return ok
+	case fieldList:
+		list, ok := v.([]interface{})
+		for _, e := range list {
+			if _, isString := e.(string); !isString {
+				return false
+			}
+		}
+		return ok
+	case fieldJSON:
+		return true
+	}
+	switch v.(type) {
+	case string, []byte:
+		return true
+	}
+	return false
+}
+
+func (k fieldKind) String() string {
+	switch k {
+	case fieldInt:
+		return "integer"
+	case fieldBool:
+		return "boolean"
+	case fieldList:
+		return "array of strings"
+	case fieldJSON:
+		return "JSON value"
+	}
+	return "string"
+}
+
+// jsonType names the JSON type of a decoded value.
+func jsonType(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "string"
+	case []byte:
+		return "byte string"
+	case bool:
+		return "boolean"
+	case json.Number, float64:
+		if fieldInt.accepts(v) {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return "null"
+}
+
+func article(s string) string {
+	if strings.IndexByte("aeiou", s[0]) >= 0 {
+		return "an " + s
+	}
+	return "a " + s
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateRequest(t *testing.T) {
+	res, code := call(t, "sm4", "encrypt", map[string]interface{}{
+		"tag_length": "12", "padding": 5, "roots": "x",
+	})
+	if code != exitBadInput || res.ErrorCode != codeInvalidField {
+		t.Fatalf("exit %d: %+v", code, res)
+	}
+	want := []fieldError{
+		{Field: "padding", Problem: "invalid", Expected: "string", Actual: "integer"},
+		{Field: "tag_length", Problem: "invalid", Expected: "integer", Actual: "string"},
+		{Field: "key", Problem: "missing", Expected: "string"},
+		{Field: "plaintext", Problem: "missing", Expected: "string"},
+	}
+	if !reflect.DeepEqual(res.FieldErrors, want) {
+		t.Errorf("field_errors = %+v, want %+v", res.FieldErrors, want)
+	}
+	for _, s := range []string{`"padding" must be a string, not integer`, `"tag_length" must be an integer`, `missing required field "key"`} {
+		if !strings.Contains(res.Message, s) {
+			t.Errorf("message %q lacks %q", res.Message, s)
+		}
+	}
+
+	res = mustFail(t, "sm2", "tsa-verify", map[string]interface{}{})
+	if res.ErrorCode != codeMissingField || len(res.FieldErrors) != 1 ||
+		!reflect.DeepEqual(res.FieldErrors[0].Alternatives, []string{"token"}) ||
+		!strings.Contains(res.Message, `"response" or "token"`) {
+		t.Errorf("tsa-verify {} = %+v", res)
+	}
+
+	// Alternative forms satisfy a required field.
+	s := schemas()[[2]string{"sm4", "encrypt"}]
+	for _, in := range []map[string]interface{}{
+		{"key": testSM4Key, "plaintext": "x"},
+		{"key": testSM4Key, "plaintext_hex": "00"},
+		{"key": testSM4Key, "input_file": "in.bin", "tag_length": json.Number("12")},
+		{"key": testSM4Key, "plaintext": "x", "tag_length": 12.0, "unknown": 1},
+	} {
+		if err := s.validate(in); err != nil {
+			t.Errorf("validate(%v) = %v", in, err)
+		}
+	}
+	if err := s.validate(map[string]interface{}{"key": testSM4Key, "plaintext": "x", "tag_length": 1.5}); err == nil {
+		t.Error("non-integer tag_length accepted")
+	}
+}
+
+func TestRequiredFields(t *testing.T) {
+	for alg, ops := range requiredFields {
+		for op, groups := range ops {
+			h, err := lookup(alg, op)
+			if err != nil {
+				t.Errorf("requiredFields[%s][%s]: %v", alg, op, err)
+				continue
+			}
+			if len(groups) == 0 {
+				t.Errorf("requiredFields[%s][%s] is empty", alg, op)
+			}
+			// An operation with required fields cannot succeed without
+			// them.
+			if _, err := h(map[string]interface{}{}); err == nil {
+				t.Errorf("%s %s succeeded without its required fields", alg, op)
+			}
+		}
+	}
+}
+
+func TestRequestFields(t *testing.T) {
+	for alg, ops := range handlers {
+		for op := range ops {
+			if _, ok := requestFields[alg][op]; !ok {
+				t.Errorf("%s %s has no requestFields entry", alg, op)
+			}
+		}
+	}
+	used := map[string]bool{}
+	for _, s := range schemas() {
+		for name := range s.fields {
+			used[name] = true
+		}
+	}
+	for name := range fieldKinds {
+		if !used[name] {
+			t.Errorf("fieldKinds[%q] is read by no operation", name)
+		}
+	}
+
+	// Every field an operation reads is type-checked, not only the ones
+	// its --help mentions.
+	for _, c := range []struct {
+		alg, op string
+		in      map[string]interface{}
+		want    []string
+	}{
+		{"sm2", "sign", map[string]interface{}{"message": "m", "deterministic": "yes", "passphrase": 1}, []string{"deterministic", "passphrase"}},
+		{"sm3", "hash", map[string]interface{}{"data": "d", "per_item": "true", "data_encoding": false}, []string{"data_encoding", "per_item"}},
+	} {
+		res := mustFail(t, c.alg, c.op, c.in)
+		var got []string
+		for _, f := range res.FieldErrors {
+			got = append(got, f.Field)
+		}
+		if res.ErrorCode != codeInvalidField || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s %s: %s, field_errors %v, want %v", c.alg, c.op, res.ErrorCode, got, c.want)
+		}
+	}
+}
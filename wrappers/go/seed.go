@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"sync"
+)
+
+// seededSource returns the ChaCha8 DRBG keyed by seed that replaces
+// crypto/rand when randomness must be reproducible: in generated vectors
+// and round trips, and under --seed.
+func seededSource(seed int64) *mrand.ChaCha8 {
+	var key [32]byte
+	binary.LittleEndian.PutUint64(key[:], uint64(seed))
+	return mrand.NewChaCha8(key)
+}
+
+// lockedReader serializes the reads of a source that is not safe for
+// concurrent use, for the server modes.
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(p)
+}
+
+// useSeed makes the DRBG of seed the wrapper's randomness source, so that
+// the same requests in the same order get the same keys, IVs and SM2 and
+// SM9 nonces on any machine, and warns on stderr that the output is not
+// secret. Concurrent requests of the server modes draw in the order they
+// arrive, which is not reproducible. It returns a function restoring the
+// previous source.
+func useSeed(seed int64) (restore func()) {
+	fmt.Fprintf(stderr, "wrapper: INSECURE: --seed %d derives every key, IV and nonce from the seed; use it only to replay tests\n", seed)
+	saved := rand
+	rand = &lockedReader{r: seededSource(seed)}
+	return func() { rand = saved }
+}
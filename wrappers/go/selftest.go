@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"strings"
+)
+
+// knownAnswer is an example from a standard's appendix, replayed through
+// the wrapper's handlers so that the request fields and encodings are
+// checked along with the primitives. Examples that depend on a fixed
+// nonce (SM2 signing and encryption) become vectors of their inverse.
+type knownAnswer struct {
+	vector
+	// Standard names the document and the example.
+	Standard string
+}
+
+// The SM2 examples use the recommended curve and the default user ID.
+// Signing and encryption share a key pair.
+const (
+	katSM2Private = "3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8"
+	katSM2Public  = "0409f9df311e5421a150dd7d161e4bc5c672179fad1833fc076bb08ff356f35020" +
+		"ccea490ce26775a52dc6ea718cc1aa600aed05fbf35e084a6632f6072da9ad13"
+
+	// Users A and B of the key exchange, with their ephemeral keys.
+	katSM2A  = "81eb26e941bb5af16df116495f90695272ae2cd63d6c4ae1678418be48230029"
+	katSM2PA = "04160e12897df4edb61dd812feb96748fbd3ccf4ffe26aa6f6db9540af49c94232" +
+		"4a7dad08bb9a459531694beb20aa489d6649975e1bfcf8c4741b78b4b223007f"
+	katSM2RA = "d4de15474db74d06491c440d305e012400990f3e390c7e87153c12db2ea60bb3"
+	katSM2EA = "0464ced1bdbc99d590049b434d0fd73428cf608a5db8fe5ce07f15026940bae40e" +
+		"376629c7ab21e7db260922499ddb118f07ce8eaae3e7720afef6a5cc062070c0"
+	katSM2B  = "785129917d45a9ea5437a59356b82338eaadda6ceb199088f14ae10defa229b5"
+	katSM2PB = "046ae848c57c53c7b1b5fa99eb2286af078ba64c64591b8b566f7357d576f16dfb" +
+		"ee489d771621a27b36c5c7992062e9cd09a9264386f3fbea54dff69305621c4d"
+	katSM2RB = "7e07124814b309489125eaed101113164ebf0f3458c5bd88335c1f9d596243d6"
+	katSM2EB = "04acc27688a6f7b706098bc91ff3ad1bff7dc2802cdb14ccccdb0a90471f9bd707" +
+		"2fedac0494b2ffc4d6853876c79b8f301c6573ad0aa50f39fc87181e1a1b46fe"
+	katSM2SA = "18c7894b3816df16cf07b05c5ec0bef5d655d58f779cc1b400a4f3884644db88"
+	katSM2SB = "d3a0fe15dee185ceae907a6b595cc32a266ed7b3367e9983a896dc32fa20f8eb"
+	katSM2K  = "6c89347354de2484c60b4ab1fde4c6e5"
+)
+
+// knownAnswers are run by "wrapper selftest".
+var knownAnswers = []knownAnswer{
+	{vector{ID: "sm3-hash-abc", Algorithm: "sm3", Operation: "hash",
+		Request:  map[string]interface{}{"data": "abc"},
+		Expected: map[string]interface{}{"output": "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"},
+	}, "GB/T 32905-2016 A.1"},
+	{vector{ID: "sm3-hash-abcd", Algorithm: "sm3", Operation: "hash",
+		Request:  map[string]interface{}{"data": strings.Repeat("abcd", 16)},
+		Expected: map[string]interface{}{"output": "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"},
+	}, "GB/T 32905-2016 A.2"},
+
+	{vector{ID: "sm4-encrypt", Algorithm: "sm4", Operation: "encrypt",
+		Request: map[string]interface{}{"key": "0123456789abcdeffedcba9876543210", "mode": "ECB", "padding": "none",
+			"plaintext_hex": "0123456789abcdeffedcba9876543210"},
+		Expected: map[string]interface{}{"output": "681edf34d206965e86b3e94f536e4246"},
+	}, "GB/T 32907-2016 A.1"},
+	{vector{ID: "sm4-decrypt", Algorithm: "sm4", Operation: "decrypt",
+		Request: map[string]interface{}{"key": "0123456789abcdeffedcba9876543210", "mode": "ECB", "padding": "none",
+			"ciphertext": "681edf34d206965e86b3e94f536e4246", "plaintext_encoding": encodingHex},
+		Expected: map[string]interface{}{"output": "0123456789abcdeffedcba9876543210"},
+	}, "GB/T 32907-2016 A.1"},
+
+	{vector{ID: "sm2-derive-pub", Algorithm: "sm2", Operation: "derive-pub",
+		Request:  map[string]interface{}{"private_key": katSM2Private},
+		Expected: map[string]interface{}{"public_key": katSM2Public},
+	}, "GM/T 0003.5-2012"},
+	{vector{ID: "sm2-digest", Algorithm: "sm2", Operation: "digest",
+		Request:  map[string]interface{}{"public_key": katSM2Public, "message": "message digest"},
+		Expected: map[string]interface{}{"output": "f0b43e94ba45accaace692ed534382eb17e6ab5a19ce7b31f4486fdfc0d28640"},
+	}, "GM/T 0003.5-2012"},
+	{vector{ID: "sm2-verify", Algorithm: "sm2", Operation: "verify",
+		Request: map[string]interface{}{"public_key": katSM2Public, "message": "message digest", "signature_format": sigFormatRS,
+			"signature": "f5a03b0648d2c4630eeac513e1bb81a15944da3827d5b74143ac7eaceee720b3" +
+				"b1b6aa29df212fd8763182bc0d421ca1bb9038fd1f7f42d4840b69c485bbc1aa"},
+		Expected: map[string]interface{}{"valid": true},
+	}, "GM/T 0003.5-2012"},
+	{vector{ID: "sm2-decrypt", Algorithm: "sm2", Operation: "decrypt",
+		Request: map[string]interface{}{"private_key": katSM2Private, "ciphertext_format": ctFormatC1C3C2,
+			"ciphertext": "0404ebfc718e8d1798620432268e77feb6415e2ede0e073c0f4f640ecd2e149a73" +
+				"e858f9d81e5430a57b36daab8f950a3c64e6ee6a63094d99283aff767e124df0" +
+				"59983c18f809e262923c53aec295d30383b54e39d609d160afcb1908d0bd8766" +
+				"21886ca989ca9c7d58087307ca93092d651efa"},
+		Expected: map[string]interface{}{"output": "encryption standard"},
+	}, "GM/T 0003.5-2012"},
+	{vector{ID: "sm2-keyexchange-respond", Algorithm: "sm2", Operation: "keyexchange-respond",
+		Request: map[string]interface{}{"private_key": katSM2B, "ephemeral_private_key": katSM2RB,
+			"peer_public_key": katSM2PA, "peer_ephemeral_public_key": katSM2EA, "key_length": json.Number("16")},
+		Expected: map[string]interface{}{"output": katSM2K, "ephemeral_public_key": katSM2EB, "confirmation": katSM2SB},
+	}, "GB/T 32918.5-2017"},
+	{vector{ID: "sm2-keyexchange-confirm", Algorithm: "sm2", Operation: "keyexchange-confirm",
+		Request: map[string]interface{}{"private_key": katSM2A, "ephemeral_private_key": katSM2RA,
+			"peer_public_key": katSM2PB, "peer_ephemeral_public_key": katSM2EB, "key_length": json.Number("16"),
+			"confirmation": katSM2SB},
+		Expected: map[string]interface{}{"output": katSM2K, "confirmation": katSM2SA, "valid": true},
+	}, "GB/T 32918.5-2017"},
+}
+
+// wrapper selftest looks up the handlers of its tests, so it cannot be in
+// the handlers literal itself.
+func init() {
+	handlers["wrapper"]["selftest"] = wrapperSelfTest
+}
+
+// knownAnswerResult is an element of Result.SelfTest.
+type knownAnswerResult struct {
+	vectorResult
+	Standard string `json:"standard"`
+}
+
+// wrapperSelfTest runs the knownAnswers, without the defaults of
+// loadDefaults, which could change their requests. Like a verification, a
+// failed test is a successful call with Valid set to false; Output counts
+// the tests that passed.
+func wrapperSelfTest(in map[string]interface{}) (*Result, error) {
+	res := &Result{SelfTest: []knownAnswerResult{}}
+	passed := 0
+	for _, ka := range knownAnswers {
+		var r vectorResult
+		if h, err := lookup(ka.Algorithm, ka.Operation); err != nil {
+			r = checkVector(&ka.vector, errorResult(err))
+		} else {
+			r = checkVector(&ka.vector, runHandler(ka.Algorithm, ka.Operation, h, maps.Clone(ka.Request)))
+		}
+		if r.Passed {
+			passed++
+		}
+		res.SelfTest = append(res.SelfTest, knownAnswerResult{r, ka.Standard})
+	}
+	res.Output = fmt.Sprintf("%d/%d passed", passed, len(knownAnswers))
+	res.Valid = boolPtr(passed == len(knownAnswers))
+	return res, nil
+}
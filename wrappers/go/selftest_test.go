@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestSelfTest(t *testing.T) {
+	// Defaults must not change the known answer requests.
+	t.Setenv("SM_WRAPPER_OUTPUT_ENCODING", "base64")
+	t.Setenv("SM_WRAPPER_SM2_SIGNATURE_FORMAT", "der")
+	res := mustCall(t, "wrapper", "selftest", map[string]interface{}{})
+	if !*res.Valid || len(res.SelfTest) != len(knownAnswers) {
+		t.Fatalf("selftest: %+v", res)
+	}
+	for _, r := range res.SelfTest {
+		if !r.Passed || r.Standard == "" {
+			t.Errorf("%s: %+v", r.ID, r)
+		}
+	}
+
+	// A wrong answer fails the self-test, and --selftest stops a request
+	// before it runs.
+	saved := knownAnswers[0].Expected
+	defer func() { knownAnswers[0].Expected = saved }()
+	knownAnswers[0].Expected = map[string]interface{}{"output": "00"}
+	res = mustCall(t, "wrapper", "selftest", map[string]interface{}{})
+	if *res.Valid || res.Output != "9/10 passed" || res.SelfTest[0].Passed || res.SelfTest[0].Diffs[0].Field != "output" {
+		t.Errorf("failed selftest: %+v", res)
+	}
+	for _, args := range [][]string{{"--selftest"}, {"sm3", "hash", "--data", "abc", "--selftest"}, {"--batch", "--selftest"}} {
+		var out bytes.Buffer
+		code := run(args, &out)
+		var res Result
+		if err := json.Unmarshal(out.Bytes(), &res); err != nil || code != exitInvalid || res.SelfTest == nil {
+			t.Errorf("%q exited %d: %s", args, code, out.String())
+		}
+	}
+}
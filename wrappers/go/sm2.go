@@ -0,0 +1,651 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// sm2Keygen generates a key pair. The fingerprint is the SM3 digest of
+// the uncompressed public key.
+func sm2Keygen(in map[string]interface{}) (*Result, error) {
+	var req sm2KeyEncoding
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	priv, err := sm2.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return req.keyPairResult(&priv.PublicKey, priv)
+}
+
+// sm2DerivePub returns the public key of "private_key".
+func sm2DerivePub(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyEncoding
+		PrivateKey *keyText `json:"private_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	priv, err := req.privateKey("private_key", req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return req.keyPairResult(&priv.PublicKey, nil)
+}
+
+// Reason codes of sm2ValidateKey.
+const (
+	reasonPrivateKeyRange   = "PRIVATE_KEY_OUT_OF_RANGE"
+	reasonPublicKeyEncoding = "PUBLIC_KEY_MALFORMED"
+	reasonPublicKeyInfinity = "PUBLIC_KEY_IS_INFINITY"
+	reasonPublicKeyCurve    = "PUBLIC_KEY_NOT_ON_CURVE"
+	reasonKeyMismatch       = "KEY_PAIR_MISMATCH"
+)
+
+// sm2ValidateKey checks "private_key" (in [1, n-2]), "public_key" (a
+// well-formed point on the curve other than the identity) and, when both
+// are given, that they belong together. A failed check is a successful
+// call with Valid false and a Reason code.
+func sm2ValidateKey(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyFormat
+		PrivateKey *keyText `json:"private_key"`
+		PublicKey  *keyText `json:"public_key"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	d, hasPriv, err := req.privateKeyBytes("private_key", req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	q, hasPub, err := req.publicKeyBytes("public_key", req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !hasPriv && !hasPub {
+		return nil, &codedError{codeMissingField, errors.New("missing \"private_key\" or \"public_key\"")}
+	}
+	invalid := func(reason string) (*Result, error) {
+		return &Result{Valid: boolPtr(false), Reason: reason}, nil
+	}
+
+	var priv *sm2.PrivateKey
+	if hasPriv {
+		if len(d) > 32 {
+			return invalid(reasonPrivateKeyRange)
+		}
+		if priv, err = sm2.NewPrivateKey(d); err != nil {
+			return invalid(reasonPrivateKeyRange)
+		}
+	}
+	if hasPub {
+		switch {
+		case len(q) == 1 && q[0] == 0:
+			return invalid(reasonPublicKeyInfinity)
+		case !(len(q) == 65 && q[0] == 4) && !(len(q) == 33 && (q[0] == 2 || q[0] == 3)):
+			return invalid(reasonPublicKeyEncoding)
+		}
+		pub, err := sm2.ParsePublicKey(q)
+		if err != nil {
+			return invalid(reasonPublicKeyCurve)
+		}
+		if priv != nil && (pub.X.Cmp(priv.X) != 0 || pub.Y.Cmp(priv.Y) != 0) {
+			return invalid(reasonKeyMismatch)
+		}
+	}
+	return &Result{Valid: boolPtr(true)}, nil
+}
+
+// keyPairResult describes pub, and priv when it is not nil, with the keys
+// in "key_format". The compressed point and fingerprint are always hex.
+func (e sm2KeyEncoding) keyPairResult(pub *sm2.PublicKey, priv *sm2.PrivateKey) (*Result, error) {
+	fp := sm3.Sum(pub.Bytes())
+	res := &Result{
+		PublicKeyCompressed: hex.EncodeToString(pub.CompressedBytes()),
+		Fingerprint:         hex.EncodeToString(fp[:]),
+	}
+	var err error
+	if res.PublicKey, err = e.encodePublicKey(pub); err != nil {
+		return nil, err
+	}
+	if priv != nil {
+		if res.PrivateKey, err = e.encodePrivateKey(priv); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// keyPairResult describes the key pair as sm2KeyEncoding.keyPairResult
+// does for the key encoding fields of in.
+func keyPairResult(in map[string]interface{}, pub *sm2.PublicKey, priv *sm2.PrivateKey) (*Result, error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return nil, err
+	}
+	return e.keyPairResult(pub, priv)
+}
+
+// sm2UserID returns the signer identity used in ZA: "user_id" as UTF-8
+// text (or "user_id_hex" / "user_id_base64"), by default
+// sm2.DefaultUID.
+func sm2UserID(in map[string]interface{}) ([]byte, error) {
+	return userIDField(in, "user_id")
+}
+
+// userIDField reads an identity in the forms accepted by sm2UserID.
+func userIDField(in map[string]interface{}, name string) ([]byte, error) {
+	uid, ok, err := bytesField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return checkUserID(name, nil)
+	}
+	return checkUserID(name, (*textBytes)(&uid))
+}
+
+// checkUserID returns the identity uid read from the field name, or
+// sm2.DefaultUID when it is nil.
+func checkUserID(name string, uid *textBytes) ([]byte, error) {
+	if uid == nil {
+		return []byte(sm2.DefaultUID), nil
+	}
+	if len(*uid) >= 8192 {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("%s is %d bytes; ZA limits it to 8191", name, len(*uid))}
+	}
+	return *uid, nil
+}
+
+// Signature encodings selected by "signature_format".
+const (
+	sigFormatDER = "der" // ASN.1 SEQUENCE { r INTEGER, s INTEGER }
+	sigFormatRS  = "rs"  // 64 bytes, r || s, each left-padded to 32 bytes
+)
+
+// signatureFormat reads "signature_format", DER by default.
+func signatureFormat(in map[string]interface{}) (string, error) {
+	f, _, err := stringField(in, "signature_format")
+	if err != nil {
+		return "", err
+	}
+	return checkSignatureFormat(f)
+}
+
+// checkSignatureFormat normalizes the signature_format f; "" is DER.
+func checkSignatureFormat(f string) (string, error) {
+	switch f = strings.ToLower(f); f {
+	case "":
+		return sigFormatDER, nil
+	case sigFormatDER, sigFormatRS:
+		return f, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported signature_format %q (supported: der, rs)", f)}
+}
+
+func encodeSignature(format string, r, s *big.Int) ([]byte, error) {
+	if format == sigFormatRS {
+		out := make([]byte, 64)
+		r.FillBytes(out[:32])
+		s.FillBytes(out[32:])
+		return out, nil
+	}
+	return sm2.MarshalSignature(r, s)
+}
+
+func decodeSignature(format string, sig []byte) (r, s *big.Int, err error) {
+	if format == sigFormatRS {
+		if len(sig) != 64 {
+			return nil, nil, &codedError{codeInvalidField, fmt.Errorf("rs signature must be 64 bytes, got %d", len(sig))}
+		}
+		return new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:]), nil
+	}
+	return sm2.UnmarshalSignature(sig)
+}
+
+// sm2Message holds the fields that say what an SM2 signature covers:
+// the UTF-8 "message" or the contents of "input_file", under "user_id".
+type sm2Message struct {
+	Message   *string    `json:"message"`
+	InputFile *string    `json:"input_file"`
+	UserID    *textBytes `json:"user_id"`
+}
+
+// digest returns e = SM3(ZA || M) for m and pub. Files are hashed in
+// fileChunkSize pieces, so their size is not limited by memory.
+func (m *sm2Message) digest(pub *sm2.PublicKey) ([]byte, error) {
+	uid, err := checkUserID("user_id", m.UserID)
+	if err != nil {
+		return nil, err
+	}
+	za, err := sm2.ZA(pub, uid)
+	if err != nil {
+		return nil, err
+	}
+	h := newSM3()
+	h.Write(za)
+	switch {
+	case m.InputFile == nil && m.Message == nil:
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"message\"")}
+	case m.InputFile == nil:
+		h.Write([]byte(*m.Message))
+		return h.Sum(nil), nil
+	case m.Message != nil:
+		return nil, &codedError{codeInvalidField, errors.New("fields \"message\" and \"input_file\" are mutually exclusive")}
+	}
+	f, err := os.Open(*m.InputFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if _, err := io.CopyBuffer(h, f, make([]byte, fileChunkSize)); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
+
+// sm2Prehash holds the "digest" e = SM3(ZA || M) that a "prehashed" sign
+// or verify takes instead of the message.
+type sm2Prehash struct {
+	Prehashed bool     `json:"prehashed"`
+	Digest    hexBytes `json:"digest"`
+}
+
+// signedDigest returns the digest of m for pub, or with "prehashed" the
+// hex "digest" as given.
+func (p *sm2Prehash) signedDigest(m *sm2Message, pub *sm2.PublicKey) ([]byte, error) {
+	if !p.Prehashed {
+		return m.digest(pub)
+	}
+	if p.Digest == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"digest\"")}
+	}
+	if len(p.Digest) != sm3.Size {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("digest must be %d bytes, got %d", sm3.Size, len(p.Digest))}
+	}
+	return p.Digest, nil
+}
+
+// sm2Digest returns e = SM3(ZA || M) for "message", "user_id" and
+// "public_key": the value a prehashed sign or verify takes.
+func sm2Digest(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyFormat
+		sm2Message
+		PublicKey *keyText `json:"public_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	pub, err := req.publicKey("public_key", req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	e, err := req.digest(pub)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(e)}, nil
+}
+
+// sm2Sign signs the UTF-8 "message" (or "input_file") under "user_id" and
+// returns the signature in "signature_format" (DER by default) together
+// with the signer's public key. With "prehashed" it signs the 32-byte "digest"
+// e = SM3(ZA || M) instead, which needs the caller's "private_key". With
+// "deterministic" the nonce comes from RFC 6979 with HMAC-SM3 instead of
+// the CSPRNG.
+func sm2Sign(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyEncoding
+		sm2Message
+		sm2Prehash
+		PrivateKey      *keyText `json:"private_key"`
+		SignatureFormat string   `json:"signature_format"`
+		Deterministic   bool     `json:"deterministic"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := checkSignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	var priv *sm2.PrivateKey
+	generated := false
+	if req.Prehashed {
+		priv, err = req.privateKey("private_key", req.PrivateKey)
+	} else {
+		priv, generated, err = req.privateKeyOrNew(req.PrivateKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	e, err := req.signedDigest(&req.sm2Message, &priv.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var r, s *big.Int
+	if req.Deterministic {
+		r, s, err = sm2.SignDigestDeterministic(priv, e)
+	} else {
+		r, s, err = sm2SignDigest(rand, priv, e)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sig, err := encodeSignature(format, r, s)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Output: hex.EncodeToString(sig)}
+	if res.PublicKey, err = req.encodePublicKey(&priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if generated {
+		if res.PrivateKey, err = req.encodePrivateKey(priv); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// sm2Verify checks a "signature" in "signature_format" over "message" (or
+// "input_file") and "user_id", or over the "digest" when "prehashed",
+// against "public_key".
+// An invalid signature is a successful call with Valid set to false.
+func sm2Verify(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyFormat
+		sm2Message
+		sm2Prehash
+		PublicKey       *keyText `json:"public_key" validate:"required"`
+		Signature       hexBytes `json:"signature" validate:"required"`
+		SignatureFormat string   `json:"signature_format"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := checkSignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := req.publicKey("public_key", req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	e, err := req.signedDigest(&req.sm2Message, pub)
+	if err != nil {
+		return nil, err
+	}
+	valid := false
+	if r, s, err := decodeSignature(format, req.Signature); err == nil {
+		valid = sm2VerifyDigest(pub, e, r, s)
+	}
+	return &Result{Valid: boolPtr(valid)}, nil
+}
+
+// sm2RecoverPub returns in Outputs the public keys under which
+// "signature" is valid for the 32-byte "digest" e, in "key_format" and
+// "point_format". A "message" (with "user_id") keeps only the candidates
+// whose ZA reproduces e, which is normally just the signer's key.
+func sm2RecoverPub(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyEncoding
+		sm2Message
+		Digest          hexBytes `json:"digest" validate:"required"`
+		Signature       hexBytes `json:"signature" validate:"required"`
+		SignatureFormat string   `json:"signature_format"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := checkSignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := decodeSignature(format, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	e := []byte(req.Digest)
+	keys, err := sm2.RecoverPublicKeys(e, r, s)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	for _, pub := range keys {
+		if req.Message != nil {
+			got, err := req.digest(pub)
+			if err != nil {
+				return nil, err
+			}
+			if !bytes.Equal(got, e) {
+				continue
+			}
+		}
+		out, err := req.encodePublicKey(pub)
+		if err != nil {
+			return nil, err
+		}
+		res.Outputs = append(res.Outputs, out)
+	}
+	if len(res.Outputs) == 0 {
+		return nil, errors.New("no public key yields this signature")
+	}
+	return res, nil
+}
+
+// sm2ConvertSignature re-encodes "signature", given in
+// "signature_format", in the other format.
+func sm2ConvertSignature(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Signature       hexBytes `json:"signature" validate:"required"`
+		SignatureFormat string   `json:"signature_format"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	from, err := checkSignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	r, s, err := decodeSignature(from, req.Signature)
+	if err != nil {
+		return nil, err
+	}
+	if r.Sign() <= 0 || s.Sign() <= 0 || r.BitLen() > 256 || s.BitLen() > 256 {
+		return nil, &codedError{codeInvalidField, errors.New("signature components must be positive 256-bit integers")}
+	}
+	to := sigFormatRS
+	if from == sigFormatRS {
+		to = sigFormatDER
+	}
+	out, err := encodeSignature(to, r, s)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(out)}, nil
+}
+
+// Ciphertext component orders selected by "ciphertext_format". C1 is the
+// 65-byte uncompressed point and C3 the 32-byte SM3 check value.
+const (
+	ctFormatC1C3C2 = "c1c3c2" // GB/T 32918-2016
+	ctFormatC1C2C3 = "c1c2c3" // the 2010 draft order used by older libraries
+	ctFormatAuto   = "auto"   // decryption only: try C1C3C2, then C1C2C3
+)
+
+const (
+	sm2C1Size = 65
+	sm2C3Size = 32
+)
+
+// ciphertextFormat normalizes the ciphertext_format f; "" is C1C3C2.
+func ciphertextFormat(f string, decrypt bool) (string, error) {
+	switch f = strings.ToLower(f); f {
+	case "":
+		return ctFormatC1C3C2, nil
+	case ctFormatC1C3C2, ctFormatC1C2C3:
+		return f, nil
+	case ctFormatAuto:
+		if decrypt {
+			return f, nil
+		}
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported ciphertext_format %q", f)}
+}
+
+// ciphertextEncoding checks the "encoding" enc: "raw" (the default)
+// concatenated components, or "asn1" for the DER SM2Cipher of GM/T 0009.
+// The ASN.1 form fixes the component order, so it cannot be combined with
+// C1C2C3.
+func ciphertextEncoding(enc, format string) (asn1 bool, err error) {
+	switch strings.ToLower(enc) {
+	case "", "raw":
+		return false, nil
+	case "asn1":
+		if format == ctFormatC1C2C3 {
+			return false, &codedError{codeUnsupportedOption, errors.New("ASN.1 ciphertexts have a fixed component order; ciphertext_format c1c2c3 does not apply")}
+		}
+		return true, nil
+	}
+	return false, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported encoding %q (supported: raw, asn1)", enc)}
+}
+
+// swapC2C3 converts between C1C3C2 and C1C2C3. c3First says whether ct is
+// currently in C1C3C2 order.
+func swapC2C3(ct []byte, c3First bool) []byte {
+	if len(ct) < sm2C1Size+sm2C3Size {
+		return ct
+	}
+	out := make([]byte, 0, len(ct))
+	out = append(out, ct[:sm2C1Size]...)
+	body := ct[sm2C1Size:]
+	if c3First {
+		return append(append(out, body[sm2C3Size:]...), body[:sm2C3Size]...)
+	}
+	split := len(body) - sm2C3Size
+	return append(append(out, body[split:]...), body[:split]...)
+}
+
+// sm2Encrypt encrypts "plaintext" (or "plaintext_hex" / "plaintext_base64")
+// to "public_key" in
+// "ciphertext_format" (C1C3C2 by default) and "encoding". When no public
+// key is given a key pair is generated and returned.
+func sm2Encrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyEncoding
+		Plaintext        textBytes `json:"plaintext" validate:"required"`
+		PublicKey        *keyText  `json:"public_key"`
+		CiphertextFormat string    `json:"ciphertext_format"`
+		Encoding         string    `json:"encoding"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := ciphertextFormat(req.CiphertextFormat, false)
+	if err != nil {
+		return nil, err
+	}
+	useASN1, err := ciphertextEncoding(req.Encoding, format)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	var pub *sm2.PublicKey
+	if req.PublicKey != nil {
+		if pub, err = req.publicKey("public_key", req.PublicKey); err != nil {
+			return nil, err
+		}
+	} else {
+		priv, err := sm2.GenerateKey(rand)
+		if err != nil {
+			return nil, err
+		}
+		pub = &priv.PublicKey
+		if res.PrivateKey, err = req.encodePrivateKey(priv); err != nil {
+			return nil, err
+		}
+		if res.PublicKey, err = req.encodePublicKey(pub); err != nil {
+			return nil, err
+		}
+	}
+	ct, err := sm2EncryptC1C3C2(rand, pub, req.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if useASN1 {
+		if ct, err = sm2.MarshalCiphertext(ct); err != nil {
+			return nil, err
+		}
+	} else if format == ctFormatC1C2C3 {
+		ct = swapC2C3(ct, true)
+	}
+	res.Output = hex.EncodeToString(ct)
+	return res, nil
+}
+
+// sm2Decrypt decrypts "ciphertext" in "ciphertext_format" and "encoding"
+// with "private_key" and returns the plaintext per "plaintext_encoding".
+// With "auto" the C1C3C2 order is tried first; the C3 check value makes a
+// wrong guess fail rather than return garbage.
+func sm2Decrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyFormat
+		plaintextEncoding
+		PrivateKey       *keyText `json:"private_key" validate:"required"`
+		Ciphertext       hexBytes `json:"ciphertext" validate:"required"`
+		CiphertextFormat string   `json:"ciphertext_format"`
+		Encoding         string   `json:"encoding"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	priv, err := req.privateKey("private_key", req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	format, err := ciphertextFormat(req.CiphertextFormat, true)
+	if err != nil {
+		return nil, err
+	}
+	useASN1, err := ciphertextEncoding(req.Encoding, format)
+	if err != nil {
+		return nil, err
+	}
+	ct := []byte(req.Ciphertext)
+	if useASN1 {
+		if ct, err = sm2.UnmarshalCiphertext(ct); err != nil {
+			return nil, err
+		}
+		format = ctFormatC1C3C2
+	} else if format == ctFormatC1C2C3 {
+		ct = swapC2C3(ct, false)
+	}
+	pt, err := sm2DecryptC1C3C2(priv, ct)
+	if err != nil && format == ctFormatAuto {
+		if alt, altErr := sm2DecryptC1C3C2(priv, swapC2C3(ct, false)); altErr == nil {
+			pt, err = alt, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	out, err := req.encode(pt)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: out}, nil
+}
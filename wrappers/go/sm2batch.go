@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+)
+
+// maxBatchItems bounds "items" so one request cannot exhaust memory.
+const maxBatchItems = 100_000
+
+// sm2VerifyBatch verifies every object in "items" as sm2Verify would.
+// Fields missing from an item (such as "user_id", "signature_format",
+// "key_format" or even "public_key") are taken from the request itself.
+// The items are checked in parallel; a malformed item fails the request.
+func sm2VerifyBatch(in map[string]interface{}) (*Result, error) {
+	list, ok := in["items"].([]interface{})
+	if !ok {
+		return nil, &codedError{codeInvalidField, errors.New("field \"items\" must be an array of objects")}
+	}
+	if len(list) == 0 || len(list) > maxBatchItems {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("items must hold between 1 and %d entries, got %d", maxBatchItems, len(list))}
+	}
+	reqs := make([]map[string]interface{}, len(list))
+	for i, v := range list {
+		item, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("items[%d] must be an object", i)}
+		}
+		req := make(map[string]interface{}, len(in)+len(item))
+		for k, v := range in {
+			if k != "items" {
+				req[k] = v
+			}
+		}
+		for k, v := range item {
+			req[k] = v
+		}
+		reqs[i] = req
+	}
+
+	valid := make([]bool, len(reqs))
+	errs := make([]error, len(reqs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(runtime.GOMAXPROCS(0), len(reqs)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				res, err := sm2Verify(reqs[i])
+				if err != nil {
+					errs[i] = fmt.Errorf("items[%d]: %w", i, err)
+					continue
+				}
+				valid[i] = *res.Valid
+			}
+		}()
+	}
+	for i := range reqs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	all := true
+	for i := range reqs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		all = all && valid[i]
+	}
+	return &Result{Valid: boolPtr(all), ValidItems: valid}, nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSM2VerifyBatch(t *testing.T) {
+	k := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	var items []interface{}
+	for i := 0; i < 20; i++ {
+		msg := fmt.Sprintf("message %d", i)
+		sig := mustCall(t, "sm2", "sign", map[string]interface{}{"message": msg, "private_key": k.PrivateKey, "user_id": "batch"})
+		items = append(items, map[string]interface{}{"message": msg, "signature": sig.Output})
+	}
+	// The standard example, with its own key, identity and format.
+	items = append(items, map[string]interface{}{
+		"message": "message digest", "public_key": examplePub, "user_id": "1234567812345678",
+		"signature": exampleR + exampleS, "signature_format": "rs",
+	})
+	in := map[string]interface{}{"items": items, "public_key": k.PublicKey, "user_id": "batch"}
+
+	res := mustCall(t, "sm2", "verify-batch", in)
+	if res.Valid == nil || !*res.Valid || len(res.ValidItems) != len(items) {
+		t.Fatalf("batch = %+v", res)
+	}
+
+	items[3].(map[string]interface{})["message"] = "tampered"
+	res = mustCall(t, "sm2", "verify-batch", in)
+	if res.Valid == nil || *res.Valid {
+		t.Fatal("batch with a bad signature is valid")
+	}
+	for i, v := range res.ValidItems {
+		if v != (i != 3) {
+			t.Errorf("item %d: valid = %v", i, v)
+		}
+	}
+
+	items[5] = map[string]interface{}{"signature": "00"}
+	mustFail(t, "sm2", "verify-batch", in)
+	mustFail(t, "sm2", "verify-batch", map[string]interface{}{"items": []interface{}{}})
+	mustFail(t, "sm2", "verify-batch", map[string]interface{}{"items": []interface{}{"x"}})
+}
@@ -0,0 +1,541 @@
+package main
+
+import (
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// defaultValidityDays is the lifetime of a certificate without
+// "not_after" or "validity_days".
+const defaultValidityDays = 365
+
+// keyUsages maps the RFC 5280 names accepted in "key_usage".
+var keyUsages = map[string]cert.KeyUsage{
+	"digitalsignature":  cert.KeyUsageDigitalSignature,
+	"nonrepudiation":    cert.KeyUsageContentCommitment,
+	"contentcommitment": cert.KeyUsageContentCommitment,
+	"keyencipherment":   cert.KeyUsageKeyEncipherment,
+	"dataencipherment":  cert.KeyUsageDataEncipherment,
+	"keyagreement":      cert.KeyUsageKeyAgreement,
+	"keycertsign":       cert.KeyUsageCertSign,
+	"crlsign":           cert.KeyUsageCRLSign,
+	"encipheronly":      cert.KeyUsageEncipherOnly,
+	"decipheronly":      cert.KeyUsageDecipherOnly,
+}
+
+// extKeyUsages maps the names accepted in "ext_key_usage"; dotted OIDs
+// are accepted too.
+var extKeyUsages = map[string]asn1.ObjectIdentifier{
+	"serverauth":      cert.OIDExtKeyUsageServerAuth,
+	"clientauth":      cert.OIDExtKeyUsageClientAuth,
+	"codesigning":     cert.OIDExtKeyUsageCodeSigning,
+	"emailprotection": cert.OIDExtKeyUsageEmailProtection,
+	"timestamping":    cert.OIDExtKeyUsageTimeStamping,
+	"ocspsigning":     cert.OIDExtKeyUsageOCSPSigning,
+}
+
+// nameField reads a distinguished name given as an object keyed by RFC
+// 4514 attribute names (CN, O, OU, C, ST, L, STREET, POSTALCODE,
+// SERIALNUMBER); values are strings or arrays of strings.
+func nameField(in map[string]interface{}, name string) (pkix.Name, bool, error) {
+	var n pkix.Name
+	v, ok := in[name]
+	if !ok || v == nil {
+		return n, false, nil
+	}
+	attrs, ok := v.(map[string]interface{})
+	if !ok {
+		return n, false, &codedError{codeInvalidField, fmt.Errorf("field %q must be an object of name attributes", name)}
+	}
+	for attr, v := range attrs {
+		values := []string{}
+		if s, ok := v.(string); ok {
+			values = append(values, s)
+		} else if values, _, _ = stringListField(attrs, attr); values == nil {
+			return n, false, &codedError{codeInvalidField, fmt.Errorf("%s.%s must be a string or an array of strings", name, attr)}
+		}
+		switch strings.ToUpper(attr) {
+		case "CN":
+			if len(values) != 1 {
+				return n, false, &codedError{codeInvalidField, fmt.Errorf("%s.CN must be a single string", name)}
+			}
+			n.CommonName = values[0]
+		case "SERIALNUMBER":
+			if len(values) != 1 {
+				return n, false, &codedError{codeInvalidField, fmt.Errorf("%s.SERIALNUMBER must be a single string", name)}
+			}
+			n.SerialNumber = values[0]
+		case "O":
+			n.Organization = values
+		case "OU":
+			n.OrganizationalUnit = values
+		case "C":
+			n.Country = values
+		case "ST":
+			n.Province = values
+		case "L":
+			n.Locality = values
+		case "STREET":
+			n.StreetAddress = values
+		case "POSTALCODE":
+			n.PostalCode = values
+		default:
+			return n, false, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported attribute %q in %q (supported: CN, O, OU, C, ST, L, STREET, POSTALCODE, SERIALNUMBER)", attr, name)}
+		}
+	}
+	return n, true, nil
+}
+
+// timeField reads an RFC 3339 timestamp.
+func timeField(in map[string]interface{}, name string) (time.Time, bool, error) {
+	s, ok, err := stringField(in, name)
+	if err != nil || !ok {
+		return time.Time{}, ok, err
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false, &codedError{codeInvalidField, fmt.Errorf("field %q is not an RFC 3339 time: %v", name, err)}
+	}
+	return t, true, nil
+}
+
+// parseOID parses a dotted object identifier.
+func parseOID(s string) (asn1.ObjectIdentifier, error) {
+	parts := strings.Split(s, ".")
+	if len(parts) < 2 {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("%q is not a dotted object identifier", s)}
+	}
+	oid := make(asn1.ObjectIdentifier, len(parts))
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("%q is not a dotted object identifier", s)}
+		}
+		oid[i] = n
+	}
+	return oid, nil
+}
+
+// requestTemplate reads the fields that certificates and certificate
+// requests share: "subject", "key_usage", "ext_key_usage", "is_ca",
+// "max_path_len", "dns_names", "ip_addresses", "emails" and the signer's
+// "user_id". A missing subject is left empty for the caller to check.
+func requestTemplate(in map[string]interface{}) (*cert.Template, error) {
+	subject, _, err := nameField(in, "subject")
+	if err != nil {
+		return nil, err
+	}
+	tmpl := &cert.Template{Subject: subject, MaxPathLen: -1}
+
+	usages, _, err := stringListField(in, "key_usage")
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range usages {
+		ku, ok := keyUsages[strings.ToLower(u)]
+		if !ok {
+			return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported key_usage %q", u)}
+		}
+		tmpl.KeyUsage |= ku
+	}
+	if tmpl.ExtKeyUsage, err = extKeyUsageField(in, "ext_key_usage"); err != nil {
+		return nil, err
+	}
+
+	if tmpl.IsCA, err = boolField(in, "is_ca"); err != nil {
+		return nil, err
+	}
+	pathLen, ok, err := intField(in, "max_path_len")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if !tmpl.IsCA || pathLen < 0 {
+			return nil, &codedError{codeInvalidField, errors.New("field \"max_path_len\" needs \"is_ca\" and must not be negative")}
+		}
+		tmpl.MaxPathLen = pathLen
+	}
+
+	if tmpl.DNSNames, _, err = stringListField(in, "dns_names"); err != nil {
+		return nil, err
+	}
+	if tmpl.EmailAddresses, _, err = stringListField(in, "emails"); err != nil {
+		return nil, err
+	}
+	ips, _, err := stringListField(in, "ip_addresses")
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range ips {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("ip_addresses: %q is not an IP address", s)}
+		}
+		tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+	}
+
+	if tmpl.SignerUID, err = sm2UserID(in); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// extKeyUsageField reads a list of extended key usage names or dotted
+// OIDs.
+func extKeyUsageField(in map[string]interface{}, name string) ([]asn1.ObjectIdentifier, error) {
+	usages, _, err := stringListField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	var oids []asn1.ObjectIdentifier
+	for _, u := range usages {
+		oid, ok := extKeyUsages[strings.ToLower(u)]
+		if !ok {
+			if oid, err = parseOID(u); err != nil {
+				return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported %s %q", name, u)}
+			}
+		}
+		oids = append(oids, oid)
+	}
+	return oids, nil
+}
+
+// certTemplate adds the certificate fields to requestTemplate: "serial"
+// (hex), and "not_before" and "not_after" (RFC 3339) or "validity_days".
+func certTemplate(in map[string]interface{}) (*cert.Template, error) {
+	tmpl, err := requestTemplate(in)
+	if err != nil {
+		return nil, err
+	}
+	serial, ok, err := hexField(in, "serial")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		tmpl.SerialNumber = new(big.Int).SetBytes(serial)
+	}
+
+	notBefore, ok, err := timeField(in, "not_before")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		notBefore = time.Now()
+	}
+	notAfter, hasNotAfter, err := timeField(in, "not_after")
+	if err != nil {
+		return nil, err
+	}
+	days, hasDays, err := intField(in, "validity_days")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasNotAfter && hasDays:
+		return nil, &codedError{codeInvalidField, errors.New("fields \"not_after\" and \"validity_days\" are mutually exclusive")}
+	case !hasNotAfter:
+		if !hasDays {
+			days = defaultValidityDays
+		}
+		if days <= 0 {
+			return nil, &codedError{codeInvalidField, errors.New("field \"validity_days\" must be positive")}
+		}
+		notAfter = notBefore.AddDate(0, 0, days)
+	}
+	tmpl.NotBefore, tmpl.NotAfter = notBefore, notAfter
+	return tmpl, nil
+}
+
+// requireSubject reports a missing "subject".
+func requireSubject(tmpl *cert.Template) error {
+	if len(tmpl.Subject.ToRDNSequence()) == 0 {
+		return &codedError{codeMissingField, errors.New("missing required field \"subject\"")}
+	}
+	return nil
+}
+
+// sm2CertSelfSign creates a self-signed SM2-with-SM3 certificate for
+// "private_key", generating a key pair when it is absent. The output is
+// the hex DER certificate.
+func sm2CertSelfSign(in map[string]interface{}) (*Result, error) {
+	tmpl, err := certTemplate(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSubject(tmpl); err != nil {
+		return nil, err
+	}
+	priv, generated, err := sm2PrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	der, err := cert.Create(rand, tmpl, &priv.PublicKey, nil, priv)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if generated {
+		if res, err = keyPairResult(in, &priv.PublicKey, priv); err != nil {
+			return nil, err
+		}
+	}
+	res.Output = hex.EncodeToString(der)
+	return res, nil
+}
+
+// sm2CertIssue creates a certificate signed by the issuer's "private_key"
+// under "issuer_certificate" (PEM or hex DER). The subject's key and name
+// come from "public_key" and "subject", or from a PKCS #10 "csr" whose
+// signature is checked under "csr_user_id"; a "subject" or extension field
+// given alongside the request overrides what it asks for. The output is
+// the hex DER certificate.
+func sm2CertIssue(in map[string]interface{}) (*Result, error) {
+	tmpl, err := certTemplate(in)
+	if err != nil {
+		return nil, err
+	}
+	var pub *sm2.PublicKey
+	csr, ok, err := derField(in, "csr", "CERTIFICATE REQUEST")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if _, ok := in["public_key"]; ok {
+			return nil, &codedError{codeInvalidField, errors.New("fields \"public_key\" and \"csr\" are mutually exclusive")}
+		}
+		req, err := verifiedRequest(in, csr)
+		if err != nil {
+			return nil, err
+		}
+		pub = req.PublicKey
+		applyRequest(in, tmpl, req)
+	} else if pub, err = sm2PublicKey(in); err != nil {
+		return nil, err
+	}
+	if err := requireSubject(tmpl); err != nil {
+		return nil, err
+	}
+
+	issuer, err := requireCertificate(in, "issuer_certificate")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	der, err := cert.Create(rand, tmpl, pub, issuer, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der)}, nil
+}
+
+// verifiedRequest parses a PKCS #10 request and checks its signature under
+// "csr_user_id".
+func verifiedRequest(in map[string]interface{}, der []byte) (*cert.Request, error) {
+	req, err := cert.ParseRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := userIDField(in, "csr_user_id")
+	if err != nil {
+		return nil, err
+	}
+	if err := req.CheckSignature(uid); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// applyRequest fills the fields of tmpl that the request names and the
+// input does not.
+func applyRequest(in map[string]interface{}, tmpl *cert.Template, req *cert.Request) {
+	if _, ok := in["subject"]; !ok {
+		tmpl.Subject = req.Subject
+	}
+	if _, ok := in["key_usage"]; !ok {
+		tmpl.KeyUsage = req.KeyUsage
+	}
+	if _, ok := in["ext_key_usage"]; !ok {
+		tmpl.ExtKeyUsage = req.ExtKeyUsage
+	}
+	if _, ok := in["dns_names"]; !ok {
+		tmpl.DNSNames = req.DNSNames
+	}
+	if _, ok := in["emails"]; !ok {
+		tmpl.EmailAddresses = req.EmailAddresses
+	}
+	if _, ok := in["ip_addresses"]; !ok {
+		tmpl.IPAddresses = req.IPAddresses
+	}
+}
+
+// sm2CSRCreate creates a PKCS #10 request for "private_key", generating a
+// key pair when it is absent, signed under "user_id". It takes the
+// subject and extension fields of cert-selfsign. The output is the hex DER
+// request.
+func sm2CSRCreate(in map[string]interface{}) (*Result, error) {
+	tmpl, err := requestTemplate(in)
+	if err != nil {
+		return nil, err
+	}
+	if err := requireSubject(tmpl); err != nil {
+		return nil, err
+	}
+	priv, generated, err := sm2PrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	der, err := cert.CreateRequest(rand, tmpl, priv)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if generated {
+		if res, err = keyPairResult(in, &priv.PublicKey, priv); err != nil {
+			return nil, err
+		}
+	}
+	res.Output = hex.EncodeToString(der)
+	return res, nil
+}
+
+// sm2CSRVerify checks the self-signature of the PKCS #10 "csr" (PEM or hex
+// DER) under "user_id" and returns the requested public key.
+func sm2CSRVerify(in map[string]interface{}) (*Result, error) {
+	der, ok, err := derField(in, "csr", "CERTIFICATE REQUEST")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"csr\"")}
+	}
+	req, err := cert.ParseRequest(der)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	err = req.CheckSignature(uid)
+	if err != nil && !errors.Is(err, cert.ErrSignature) {
+		return nil, err
+	}
+	res := &Result{Valid: boolPtr(err == nil)}
+	if res.PublicKey, err = encodePublicKey(in, req.PublicKey); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// requireCertificate parses the certificate (PEM or hex DER) in field
+// name.
+func requireCertificate(in map[string]interface{}, name string) (*cert.Certificate, error) {
+	der, ok, err := certificateField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return cert.Parse(der)
+}
+
+// certificateListField parses the array of certificates (PEM or hex DER)
+// in field name.
+func certificateListField(in map[string]interface{}, name string) ([]*cert.Certificate, error) {
+	list, _, err := stringListField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	certs := make([]*cert.Certificate, len(list))
+	for i, s := range list {
+		der, err := decodeDER(s, fmt.Sprintf("%s[%d]", name, i), "CERTIFICATE")
+		if err != nil {
+			return nil, err
+		}
+		if certs[i], err = cert.Parse(der); err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", name, i, err)
+		}
+	}
+	return certs, nil
+}
+
+// Reason codes of sm2CertVerifyChain.
+var chainReasons = map[cert.InvalidReason]string{
+	cert.UnknownIssuer:              "UNKNOWN_ISSUER",
+	cert.BadSignature:               "BAD_SIGNATURE",
+	cert.Expired:                    "CERT_EXPIRED",
+	cert.NotYetValid:                "CERT_NOT_YET_VALID",
+	cert.NotCA:                      "NOT_CA",
+	cert.KeyUsageMismatch:           "KEY_USAGE",
+	cert.TooManyIntermediates:       "PATH_LENGTH_EXCEEDED",
+	cert.ExtKeyUsageMismatch:        "EXT_KEY_USAGE",
+	cert.UnhandledCriticalExtension: "UNHANDLED_CRITICAL_EXTENSION",
+}
+
+// sm2CertVerifyChain checks "certificate" against the "intermediates" and
+// trusted "roots" (arrays of PEM or hex DER certificates) at "time" (RFC
+// 3339, default now), requiring the leaf to allow "ext_key_usage". A valid
+// chain gives the subjects from leaf to root in Outputs; a rejected one is
+// a successful call with Valid false, a Reason code and the subject of the
+// offending certificate in Output.
+func sm2CertVerifyChain(in map[string]interface{}) (*Result, error) {
+	der, ok, err := certificateField(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"certificate\"")}
+	}
+	leaf, err := cert.Parse(der)
+	if err != nil {
+		return nil, err
+	}
+	var opts cert.VerifyOptions
+	if opts.Roots, err = certificateListField(in, "roots"); err != nil {
+		return nil, err
+	}
+	if len(opts.Roots) == 0 {
+		return nil, &codedError{codeMissingField, errors.New("field \"roots\" must hold at least one certificate")}
+	}
+	if opts.Intermediates, err = certificateListField(in, "intermediates"); err != nil {
+		return nil, err
+	}
+	if opts.CurrentTime, _, err = timeField(in, "time"); err != nil {
+		return nil, err
+	}
+	if opts.ExtKeyUsage, err = extKeyUsageField(in, "ext_key_usage"); err != nil {
+		return nil, err
+	}
+	if opts.SignerUID, err = sm2UserID(in); err != nil {
+		return nil, err
+	}
+
+	chain, err := leaf.Verify(opts)
+	var ve *cert.VerifyError
+	if errors.As(err, &ve) {
+		return &Result{Valid: boolPtr(false), Reason: chainReasons[ve.Reason], Output: ve.Cert.Subject.String()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Valid: boolPtr(true)}
+	for _, c := range chain {
+		res.Outputs = append(res.Outputs, c.Subject.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+)
+
+func parseCertOutput(t *testing.T, res *Result) *cert.Certificate {
+	t.Helper()
+	der, err := hex.DecodeString(res.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := cert.Parse(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func TestSM2CertSelfSignAndIssue(t *testing.T) {
+	root := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject":      map[string]interface{}{"CN": "Root CA", "O": []interface{}{"sm-bc-test"}, "C": "CN"},
+		"serial":       "01",
+		"not_before":   "2026-01-01T00:00:00Z",
+		"not_after":    "2036-01-01T00:00:00Z",
+		"is_ca":        true,
+		"max_path_len": 1,
+		"key_usage":    []interface{}{"keyCertSign", "cRLSign"},
+	})
+	if root.PrivateKey == "" || root.PublicKey == "" {
+		t.Fatalf("generated key not returned: %+v", root)
+	}
+	ca := parseCertOutput(t, root)
+	if err := ca.CheckSignature(ca.PublicKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if ca.Subject.String() != "CN=Root CA,O=sm-bc-test,C=CN" || ca.SerialNumber.Int64() != 1 || !ca.IsCA || ca.MaxPathLen != 1 ||
+		ca.KeyUsage != cert.KeyUsageCertSign|cert.KeyUsageCRLSign || ca.NotAfter.Year() != 2036 {
+		t.Errorf("root: %q serial %v CA %v/%d usage %b until %v", ca.Subject, ca.SerialNumber, ca.IsCA, ca.MaxPathLen, ca.KeyUsage, ca.NotAfter)
+	}
+
+	leafKey := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	leaf := parseCertOutput(t, mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+		"subject":            map[string]interface{}{"CN": "localhost"},
+		"public_key":         leafKey.PublicKey,
+		"issuer_certificate": root.Output,
+		"private_key":        root.PrivateKey,
+		"validity_days":      30,
+		"key_usage":          []interface{}{"digitalSignature", "keyEncipherment"},
+		"ext_key_usage":      []interface{}{"serverAuth", "1.3.6.1.5.5.7.3.2"},
+		"dns_names":          []interface{}{"localhost"},
+		"ip_addresses":       []interface{}{"127.0.0.1"},
+		"emails":             []interface{}{"ops@example.test"},
+	}))
+	if err := leaf.CheckSignature(ca.PublicKey, nil); err != nil {
+		t.Fatal(err)
+	}
+	if hex.EncodeToString(leaf.PublicKey.Bytes()) != leafKey.PublicKey || leaf.Issuer.CommonName != "Root CA" ||
+		len(leaf.ExtKeyUsage) != 2 || leaf.DNSNames[0] != "localhost" || leaf.IPAddresses[0].String() != "127.0.0.1" ||
+		leaf.NotAfter.Sub(leaf.NotBefore) != 30*24*time.Hour {
+		t.Errorf("leaf: %+v", leaf)
+	}
+
+	// A self-signed certificate for a given key, signed the way OpenSSL does.
+	own := parseCertOutput(t, mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "openssl style"}, "private_key": leafKey.PrivateKey, "user_id": "",
+	}))
+	if err := own.CheckSignature(own.PublicKey, []byte{}); err != nil {
+		t.Fatal(err)
+	}
+
+	other := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	for _, in := range []map[string]interface{}{
+		{},
+		{"subject": "CN=x"},
+		{"subject": map[string]interface{}{"X": "y"}},
+		{"subject": map[string]interface{}{"CN": "x"}, "key_usage": []interface{}{"everything"}},
+		{"subject": map[string]interface{}{"CN": "x"}, "not_after": "2030-01-01T00:00:00Z", "validity_days": 1},
+		{"subject": map[string]interface{}{"CN": "x"}, "max_path_len": 0},
+	} {
+		mustFail(t, "sm2", "cert-selfsign", in)
+	}
+	mustFail(t, "sm2", "cert-issue", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "x"}, "public_key": leafKey.PublicKey,
+		"issuer_certificate": root.Output, "private_key": other.PrivateKey,
+	})
+}
+
+// testRequestPEM is a PKCS #10 request for the key of testSignerKeyPEM,
+// with a subject alternative name and key usage, written by OpenSSL 3.0.
+const testRequestPEM = `-----BEGIN CERTIFICATE REQUEST-----
+MIIBITCByQIBADArMRQwEgYDVQQDDAtjc3Igc3ViamVjdDETMBEGA1UECgwKc20t
+YmMtdGVzdDBZMBMGByqGSM49AgEGCCqBHM9VAYItA0IABFXO7PPirydmOGNbhV0J
+glN7KpsQ1Y4kqfa6FvTR8T2qo2IuMRWrZq5eHqUf0m6RGUN59bGnQfGunsRLThIF
+wh+gPDA6BgkqhkiG9w0BCQ4xLTArMBkGA1UdEQQSMBCCCGNzci50ZXN0hwQKAQID
+MA4GA1UdDwEB/wQEAwIHgDAKBggqgRzPVQGDdQNHADBEAiAMREBMXazYN4gN47lr
+bk6OepTA0J//toKuB696PzPjdgIgJOAie2mSqNc7NFRY0sfKWNqyQ5kWe4Z2O8ew
+HrIpqLs=
+-----END CERTIFICATE REQUEST-----
+`
+
+func TestSM2CSR(t *testing.T) {
+	csr := mustCall(t, "sm2", "csr-create", map[string]interface{}{
+		"subject":   map[string]interface{}{"CN": "client", "O": "sm-bc-test"},
+		"dns_names": []interface{}{"client.test"},
+		"key_usage": []interface{}{"digitalSignature"},
+	})
+	if csr.PrivateKey == "" {
+		t.Fatalf("generated key not returned: %+v", csr)
+	}
+	res := mustCall(t, "sm2", "csr-verify", map[string]interface{}{"csr": csr.Output})
+	if res.Valid == nil || !*res.Valid || res.PublicKey != csr.PublicKey {
+		t.Fatalf("csr-verify = %+v", res)
+	}
+	// OpenSSL signs requests with an empty identity.
+	res = mustCall(t, "sm2", "csr-verify", map[string]interface{}{"csr": testRequestPEM})
+	if res.Valid == nil || *res.Valid {
+		t.Fatalf("OpenSSL request under the default identity: %+v", res)
+	}
+	res = mustCall(t, "sm2", "csr-verify", map[string]interface{}{"csr": testRequestPEM, "user_id": ""})
+	if res.Valid == nil || !*res.Valid {
+		t.Fatalf("OpenSSL request: %+v", res)
+	}
+
+	root := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "Root CA"}, "is_ca": true, "key_usage": []interface{}{"keyCertSign"},
+	})
+	leaf := parseCertOutput(t, mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+		"csr": csr.Output, "issuer_certificate": root.Output, "private_key": root.PrivateKey,
+	}))
+	if hex.EncodeToString(leaf.PublicKey.Bytes()) != csr.PublicKey || leaf.Subject.CommonName != "client" ||
+		leaf.DNSNames[0] != "client.test" || leaf.KeyUsage != cert.KeyUsageDigitalSignature {
+		t.Errorf("issued from request: %+v", leaf)
+	}
+	leaf = parseCertOutput(t, mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+		"csr": testRequestPEM, "csr_user_id": "", "subject": map[string]interface{}{"CN": "renamed"},
+		"issuer_certificate": root.Output, "private_key": root.PrivateKey,
+	}))
+	if leaf.Subject.String() != "CN=renamed" || leaf.IPAddresses[0].String() != "10.1.2.3" {
+		t.Errorf("issued from OpenSSL request: %+v", leaf)
+	}
+	mustFail(t, "sm2", "cert-issue", map[string]interface{}{
+		"csr": testRequestPEM, "issuer_certificate": root.Output, "private_key": root.PrivateKey,
+	})
+	mustFail(t, "sm2", "csr-verify", map[string]interface{}{"csr": testSignerCertPEM})
+	mustFail(t, "sm2", "csr-create", map[string]interface{}{"dns_names": []interface{}{"no.subject"}})
+}
+
+func TestSM2CertVerifyChain(t *testing.T) {
+	validity := map[string]interface{}{"not_before": "2026-01-01T00:00:00Z", "not_after": "2027-01-01T00:00:00Z"}
+	with := func(base map[string]interface{}, extra map[string]interface{}) map[string]interface{} {
+		out := map[string]interface{}{}
+		for _, m := range []map[string]interface{}{validity, base, extra} {
+			for k, v := range m {
+				out[k] = v
+			}
+		}
+		return out
+	}
+	root := mustCall(t, "sm2", "cert-selfsign", with(map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "Root"}, "is_ca": true, "key_usage": []interface{}{"keyCertSign"},
+	}, nil))
+	interKey := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	inter := mustCall(t, "sm2", "cert-issue", with(map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "Intermediate"}, "is_ca": true, "max_path_len": 0,
+		"public_key": interKey.PublicKey, "issuer_certificate": root.Output, "private_key": root.PrivateKey,
+	}, nil))
+	leafKey := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	leaf := mustCall(t, "sm2", "cert-issue", with(map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "Leaf"}, "ext_key_usage": []interface{}{"clientAuth"},
+		"public_key": leafKey.PublicKey, "issuer_certificate": inter.Output, "private_key": interKey.PrivateKey,
+	}, nil))
+
+	in := map[string]interface{}{
+		"certificate":   leaf.Output,
+		"intermediates": []interface{}{inter.Output},
+		"roots":         []interface{}{root.Output},
+		"time":          "2026-06-01T00:00:00Z",
+	}
+	res := mustCall(t, "sm2", "cert-verify-chain", in)
+	if res.Valid == nil || !*res.Valid || len(res.Outputs) != 3 || res.Outputs[0] != "CN=Leaf" || res.Outputs[2] != "CN=Root" {
+		t.Fatalf("cert-verify-chain = %+v", res)
+	}
+
+	for _, tc := range []struct {
+		field  string
+		value  interface{}
+		reason string
+		cert   string
+	}{
+		{"time", "2027-06-01T00:00:00Z", "CERT_EXPIRED", "CN=Leaf"},
+		{"intermediates", []interface{}{}, "UNKNOWN_ISSUER", "CN=Leaf"},
+		{"ext_key_usage", []interface{}{"serverAuth"}, "EXT_KEY_USAGE", "CN=Leaf"},
+		{"user_id", "", "BAD_SIGNATURE", "CN=Leaf"},
+	} {
+		bad := with(in, map[string]interface{}{tc.field: tc.value})
+		res := mustCall(t, "sm2", "cert-verify-chain", bad)
+		if res.Valid == nil || *res.Valid || res.Reason != tc.reason || res.Output != tc.cert {
+			t.Errorf("%s: %+v", tc.field, res)
+		}
+	}
+	mustFail(t, "sm2", "cert-verify-chain", map[string]interface{}{"certificate": leaf.Output})
+	mustFail(t, "sm2", "cert-verify-chain", map[string]interface{}{"certificate": leaf.Output, "roots": []interface{}{"zz"}})
+}
+
+func TestSM2CertParse(t *testing.T) {
+	res := mustCall(t, "sm2", "cert-parse", map[string]interface{}{"certificate": testSignerCertPEM})
+	c := res.Certificate
+	if c == nil {
+		t.Fatalf("cert-parse = %+v", res)
+	}
+	if c.Version != 3 || c.Serial != "1234" || c.Subject != "CN=pkcs7 signer,O=sm-bc-test" || c.Issuer != c.Subject ||
+		c.NotAfter != "2126-09-22T00:15:57Z" || c.SignatureAlgorithm != "SM2-with-SM3" ||
+		c.PublicKeyAlgorithm != "ecPublicKey" || c.PublicKeyCurve != "sm2" || !c.SelfSigned {
+		t.Errorf("fields: %+v", c)
+	}
+	if !strings.HasPrefix(c.PublicKey, "0455ceecf3e2af2766") || len(c.PublicKey) != 130 || c.SubjectKeyID != "6a55806e928d5c297e3b189d48a10f492fa2ad49" || c.AuthorityKeyID != c.SubjectKeyID ||
+		c.IsCA == nil || !*c.IsCA || c.MaxPathLen != nil || len(c.Extensions) != 3 || c.Extensions[2].Name != "basicConstraints" ||
+		!c.Extensions[2].Critical {
+		t.Errorf("extensions: %+v", c)
+	}
+
+	leaf := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject":       map[string]interface{}{"CN": "parsed", "OU": []interface{}{"a", "b"}},
+		"serial":        "00ff01",
+		"not_before":    "2026-03-01T12:00:00+08:00",
+		"validity_days": 1,
+		"key_usage":     []interface{}{"digitalSignature", "keyAgreement"},
+		"ext_key_usage": []interface{}{"serverAuth", "1.2.3.4"},
+		"is_ca":         true,
+		"max_path_len":  0,
+		"dns_names":     []interface{}{"a.test"},
+		"ip_addresses":  []interface{}{"::1"},
+		"emails":        []interface{}{"x@a.test"},
+	})
+	c = mustCall(t, "sm2", "cert-parse", map[string]interface{}{"certificate": leaf.Output}).Certificate
+	if c.Serial != "ff01" || c.NotBefore != "2026-03-01T04:00:00Z" || c.NotAfter != "2026-03-02T04:00:00Z" ||
+		c.PublicKey != leaf.PublicKey || !c.SelfSigned || c.AuthorityKeyID != "" {
+		t.Errorf("selfsign: %+v", c)
+	}
+	if len(c.KeyUsage) != 2 || c.KeyUsage[1] != "keyAgreement" || c.ExtKeyUsage[0] != "serverAuth" || c.ExtKeyUsage[1] != "1.2.3.4" ||
+		c.MaxPathLen == nil || *c.MaxPathLen != 0 || c.DNSNames[0] != "a.test" || c.IPAddresses[0] != "::1" || c.Emails[0] != "x@a.test" {
+		t.Errorf("selfsign extensions: %+v", c)
+	}
+	mustFail(t, "sm2", "cert-parse", map[string]interface{}{"certificate": "3000"})
+	mustFail(t, "sm2", "cert-parse", map[string]interface{}{})
+}
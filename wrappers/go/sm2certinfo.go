@@ -0,0 +1,158 @@
+package main
+
+import (
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// keyUsageNames are the RFC 5280 names of the key usage bits, bit 0
+// first.
+var keyUsageNames = []string{
+	"digitalSignature", "contentCommitment", "keyEncipherment", "dataEncipherment",
+	"keyAgreement", "keyCertSign", "cRLSign", "encipherOnly", "decipherOnly",
+}
+
+// oidNames names the object identifiers that cert-parse reports.
+var oidNames = map[string]string{
+	"1.2.156.10197.1.501":  "SM2-with-SM3",
+	"1.2.156.10197.1.301":  "sm2",
+	"1.2.840.10045.2.1":    "ecPublicKey",
+	"2.5.29.14":            "subjectKeyIdentifier",
+	"2.5.29.15":            "keyUsage",
+	"2.5.29.17":            "subjectAltName",
+	"2.5.29.19":            "basicConstraints",
+	"2.5.29.31":            "cRLDistributionPoints",
+	"2.5.29.32":            "certificatePolicies",
+	"2.5.29.35":            "authorityKeyIdentifier",
+	"2.5.29.37":            "extKeyUsage",
+	"1.3.6.1.5.5.7.1.1":    "authorityInfoAccess",
+	"1.3.6.1.5.5.7.3.1":    "serverAuth",
+	"1.3.6.1.5.5.7.3.2":    "clientAuth",
+	"1.3.6.1.5.5.7.3.3":    "codeSigning",
+	"1.3.6.1.5.5.7.3.4":    "emailProtection",
+	"1.3.6.1.5.5.7.3.8":    "timeStamping",
+	"1.3.6.1.5.5.7.3.9":    "OCSPSigning",
+	"2.5.29.37.0":          "anyExtendedKeyUsage",
+	"1.2.840.113549.1.1.1": "rsaEncryption",
+}
+
+// oidName returns the name of oid, or its dotted form.
+func oidName(oid asn1.ObjectIdentifier) string {
+	if name, ok := oidNames[oid.String()]; ok {
+		return name
+	}
+	return oid.String()
+}
+
+// certificateInfo is the JSON description of a certificate written by
+// cert-parse. Binary values are hex and times RFC 3339 in UTC.
+type certificateInfo struct {
+	Version            int             `json:"version"`
+	Serial             string          `json:"serial"`
+	Subject            string          `json:"subject"`
+	Issuer             string          `json:"issuer"`
+	NotBefore          string          `json:"not_before"`
+	NotAfter           string          `json:"not_after"`
+	SignatureAlgorithm string          `json:"signature_algorithm"`
+	Signature          string          `json:"signature"`
+	PublicKeyAlgorithm string          `json:"public_key_algorithm"`
+	PublicKeyCurve     string          `json:"public_key_curve,omitempty"`
+	PublicKey          string          `json:"public_key,omitempty"`
+	SelfSigned         bool            `json:"self_signed"`
+	Fingerprint        string          `json:"fingerprint"`
+	Extensions         []extensionInfo `json:"extensions,omitempty"`
+
+	SubjectKeyID   string   `json:"subject_key_id,omitempty"`
+	AuthorityKeyID string   `json:"authority_key_id,omitempty"`
+	KeyUsage       []string `json:"key_usage,omitempty"`
+	ExtKeyUsage    []string `json:"ext_key_usage,omitempty"`
+	IsCA           *bool    `json:"is_ca,omitempty"`
+	MaxPathLen     *int     `json:"max_path_len,omitempty"`
+	DNSNames       []string `json:"dns_names,omitempty"`
+	IPAddresses    []string `json:"ip_addresses,omitempty"`
+	Emails         []string `json:"emails,omitempty"`
+}
+
+type extensionInfo struct {
+	OID      string `json:"oid"`
+	Name     string `json:"name,omitempty"`
+	Critical bool   `json:"critical"`
+	Value    string `json:"value"`
+}
+
+func describeCertificate(c *cert.Certificate) *certificateInfo {
+	fp := sm3.Sum(c.Raw)
+	info := &certificateInfo{
+		Version:            c.Version,
+		Serial:             hex.EncodeToString(c.SerialNumber.Bytes()),
+		Subject:            c.Subject.String(),
+		Issuer:             c.Issuer.String(),
+		NotBefore:          c.NotBefore.UTC().Format(time.RFC3339),
+		NotAfter:           c.NotAfter.UTC().Format(time.RFC3339),
+		SignatureAlgorithm: oidName(c.SignatureAlgorithm),
+		Signature:          hex.EncodeToString(c.Signature),
+		PublicKeyAlgorithm: oidName(c.PublicKeyAlgorithm),
+		Fingerprint:        hex.EncodeToString(fp[:]),
+		SubjectKeyID:       hex.EncodeToString(c.SubjectKeyID),
+		AuthorityKeyID:     hex.EncodeToString(c.AuthorityKeyID),
+		DNSNames:           c.DNSNames,
+		Emails:             c.EmailAddresses,
+	}
+	if c.PublicKeyCurve != nil {
+		info.PublicKeyCurve = oidName(c.PublicKeyCurve)
+	}
+	if c.PublicKey != nil {
+		info.PublicKey = hex.EncodeToString(c.PublicKey.Bytes())
+		info.SelfSigned = c.IsSelfIssued() &&
+			(c.CheckSignature(c.PublicKey, nil) == nil || c.CheckSignature(c.PublicKey, []byte{}) == nil)
+	}
+	for _, ext := range c.Extensions {
+		info.Extensions = append(info.Extensions, extensionInfo{
+			OID:      ext.Id.String(),
+			Name:     oidNames[ext.Id.String()],
+			Critical: ext.Critical,
+			Value:    hex.EncodeToString(ext.Value),
+		})
+	}
+	for i, name := range keyUsageNames {
+		if c.KeyUsage&(1<<i) != 0 {
+			info.KeyUsage = append(info.KeyUsage, name)
+		}
+	}
+	for _, oid := range c.ExtKeyUsage {
+		info.ExtKeyUsage = append(info.ExtKeyUsage, oidName(oid))
+	}
+	if c.BasicConstraintsValid {
+		info.IsCA = boolPtr(c.IsCA)
+		if c.MaxPathLen >= 0 {
+			n := c.MaxPathLen
+			info.MaxPathLen = &n
+		}
+	}
+	for _, ip := range c.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	return info
+}
+
+// sm2CertParse describes "certificate" (PEM or hex DER) in the
+// "certificate" object of the result.
+func sm2CertParse(in map[string]interface{}) (*Result, error) {
+	der, ok, err := certificateField(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"certificate\"")}
+	}
+	c, err := cert.Parse(der)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Certificate: describeCertificate(c)}, nil
+}
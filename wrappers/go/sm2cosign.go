@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// Two-party SM2 signing. The key shares and ephemeral secrets are hex
+// scalars in every key_format; only the joint public key follows
+// "key_format".
+
+// partialSignatureSize is the length of r || s2 || s3 sent by the server.
+const partialSignatureSize = 96
+
+// scalarField reads the hex scalar field name, which must lie in
+// [1, n-1].
+func scalarField(in map[string]interface{}, name string) (*big.Int, error) {
+	b, err := requireHex(in, name)
+	if err != nil {
+		return nil, err
+	}
+	k, err := sm2.ParseScalar(b)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: %v", name, err)
+	}
+	return k, nil
+}
+
+func scalarHex(k *big.Int) string {
+	return hex.EncodeToString(k.FillBytes(make([]byte, 32)))
+}
+
+// sm2CoSignKeygenClient draws the client share d1, returned as
+// "private_key", and the "public_share" P1 for the server.
+func sm2CoSignKeygenClient(in map[string]interface{}) (*Result, error) {
+	d1, p1, err := sm2.CoSignClientShare(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{PrivateKey: scalarHex(d1), PublicShare: hex.EncodeToString(p1.Bytes())}, nil
+}
+
+// sm2CoSignKeygenServer draws the server share d2 for the client's
+// "public_share" and returns it as "private_key" with the joint
+// "public_key".
+func sm2CoSignKeygenServer(in map[string]interface{}) (*Result, error) {
+	b, err := requireHex(in, "public_share")
+	if err != nil {
+		return nil, err
+	}
+	p1, err := sm2.ParsePublicKey(b)
+	if err != nil {
+		return nil, err
+	}
+	d2, pub, err := sm2.CoSignServerShare(rand, p1)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{PrivateKey: scalarHex(d2)}
+	if res.PublicKey, err = encodePublicKey(in, pub); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// sm2CoSignSignClient starts a signature under the joint "public_key": it
+// returns the digest e of "message" (or "input_file", or the prehashed
+// "digest") in Output, and the nonce share k1 and Q1 = k1·G as the
+// ephemeral key pair.
+func sm2CoSignSignClient(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm2KeyFormat
+		sm2Message
+		sm2Prehash
+		PublicKey *keyText `json:"public_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	pub, err := req.publicKey("public_key", req.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	e, err := req.signedDigest(&req.sm2Message, pub)
+	if err != nil {
+		return nil, err
+	}
+	k1, q1, err := sm2.CoSignClientStart(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		Output:              hex.EncodeToString(e),
+		EphemeralPrivateKey: scalarHex(k1),
+		EphemeralPublicKey:  hex.EncodeToString(q1.Bytes()),
+	}, nil
+}
+
+// sm2CoSignSignServer answers the client's "digest" and
+// "peer_ephemeral_public_key" (Q1) with the partial signature
+// r || s2 || s3, using the server share in "private_key".
+func sm2CoSignSignServer(in map[string]interface{}) (*Result, error) {
+	d2, err := scalarField(in, "private_key")
+	if err != nil {
+		return nil, err
+	}
+	var req struct {
+		Digest hexBytes `json:"digest"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	p := sm2Prehash{Prehashed: true, Digest: req.Digest}
+	e, err := p.signedDigest(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, err := requireHex(in, "peer_ephemeral_public_key")
+	if err != nil {
+		return nil, err
+	}
+	q1, err := sm2.ParsePublicKey(b)
+	if err != nil {
+		return nil, err
+	}
+	r, s2, s3, err := sm2.CoSignServer(rand, d2, e, q1)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: scalarHex(r) + scalarHex(s2) + scalarHex(s3)}, nil
+}
+
+// sm2CoSignSignFinish completes the signature from the server's
+// "partial_signature" with the client share "private_key" and the
+// "ephemeral_private_key" k1, in "signature_format".
+func sm2CoSignSignFinish(in map[string]interface{}) (*Result, error) {
+	d1, err := scalarField(in, "private_key")
+	if err != nil {
+		return nil, err
+	}
+	k1, err := scalarField(in, "ephemeral_private_key")
+	if err != nil {
+		return nil, err
+	}
+	partial, err := requireHex(in, "partial_signature")
+	if err != nil {
+		return nil, err
+	}
+	if len(partial) != partialSignatureSize {
+		return nil, fmt.Errorf("partial_signature must be %d bytes, got %d", partialSignatureSize, len(partial))
+	}
+	format, err := signatureFormat(in)
+	if err != nil {
+		return nil, err
+	}
+	r := new(big.Int).SetBytes(partial[:32])
+	s2 := new(big.Int).SetBytes(partial[32:64])
+	s3 := new(big.Int).SetBytes(partial[64:])
+	s, err := sm2.CoSignClientFinish(d1, k1, r, s2, s3)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := encodeSignature(format, r, s)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(sig)}, nil
+}
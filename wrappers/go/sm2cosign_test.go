@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestSM2CoSign(t *testing.T) {
+	client := mustCall(t, "sm2", "cosign-keygen-client", map[string]interface{}{})
+	server := mustCall(t, "sm2", "cosign-keygen-server", map[string]interface{}{"public_share": client.PublicShare})
+
+	start := mustCall(t, "sm2", "cosign-sign-client", map[string]interface{}{"message": "approve transfer", "public_key": server.PublicKey})
+	partial := mustCall(t, "sm2", "cosign-sign-server", map[string]interface{}{
+		"private_key": server.PrivateKey, "digest": start.Output, "peer_ephemeral_public_key": start.EphemeralPublicKey,
+	})
+	for _, format := range []string{"der", "rs"} {
+		sig := mustCall(t, "sm2", "cosign-sign-finish", map[string]interface{}{
+			"private_key": client.PrivateKey, "ephemeral_private_key": start.EphemeralPrivateKey,
+			"partial_signature": partial.Output, "signature_format": format,
+		})
+		res := mustCall(t, "sm2", "verify", map[string]interface{}{
+			"message": "approve transfer", "public_key": server.PublicKey, "signature": sig.Output, "signature_format": format,
+		})
+		if res.Valid == nil || !*res.Valid {
+			t.Fatalf("%s: co-signature does not verify", format)
+		}
+	}
+
+	// Either share alone signs nothing useful.
+	other := mustCall(t, "sm2", "cosign-keygen-client", map[string]interface{}{})
+	sig := mustCall(t, "sm2", "cosign-sign-finish", map[string]interface{}{
+		"private_key": other.PrivateKey, "ephemeral_private_key": start.EphemeralPrivateKey, "partial_signature": partial.Output,
+	})
+	res := mustCall(t, "sm2", "verify", map[string]interface{}{"message": "approve transfer", "public_key": server.PublicKey, "signature": sig.Output})
+	if res.Valid == nil || *res.Valid {
+		t.Fatal("signature with the wrong client share verifies")
+	}
+
+	mustFail(t, "sm2", "cosign-sign-finish", map[string]interface{}{
+		"private_key": client.PrivateKey, "ephemeral_private_key": start.EphemeralPrivateKey, "partial_signature": partial.Output[:64],
+	})
+	mustFail(t, "sm2", "cosign-sign-server", map[string]interface{}{
+		"private_key": "00", "digest": start.Output, "peer_ephemeral_public_key": start.EphemeralPublicKey,
+	})
+	mustFail(t, "sm2", "cosign-keygen-server", map[string]interface{}{"public_share": "04"})
+}
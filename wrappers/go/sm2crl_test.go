@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestSM2CRL(t *testing.T) {
+	ca := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject":   map[string]interface{}{"CN": "crl ca"},
+		"is_ca":     true,
+		"key_usage": []interface{}{"keyCertSign", "cRLSign"},
+	})
+	issue := func(cn, serial string) string {
+		return mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+			"subject":            map[string]interface{}{"CN": cn},
+			"serial":             serial,
+			"public_key":         ca.PublicKey,
+			"issuer_certificate": ca.Output,
+			"private_key":        ca.PrivateKey,
+		}).Output
+	}
+	good, bad := issue("good", "01"), issue("bad", "0abc")
+
+	crl := mustCall(t, "sm2", "crl-create", map[string]interface{}{
+		"issuer_certificate": ca.Output,
+		"private_key":        ca.PrivateKey,
+		"crl_number":         3,
+		"this_update":        "2026-05-01T00:00:00Z",
+		"validity_days":      7,
+		"revoked": []interface{}{
+			map[string]interface{}{"serial": "0abc", "revocation_time": "2026-04-30T12:00:00Z", "reason": "KeyCompromise"},
+			map[string]interface{}{"serial": "77"},
+		},
+	}).Output
+	check := func(certificate, at string, extra map[string]interface{}) *Result {
+		in := map[string]interface{}{"crl": crl, "certificate": certificate, "issuer_certificate": ca.Output, "time": at}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "crl-check", in)
+	}
+
+	if res := check(good, "2026-05-02T00:00:00Z", nil); !*res.Valid || res.Reason != "" {
+		t.Errorf("good: %+v", res)
+	}
+	res := check(bad, "2026-05-02T00:00:00Z", nil)
+	if *res.Valid || res.Reason != "REVOKED" || res.RevocationTime != "2026-04-30T12:00:00Z" || res.RevocationReason != "keyCompromise" {
+		t.Errorf("bad: %+v", res)
+	}
+	for _, tc := range []struct {
+		at     string
+		extra  map[string]interface{}
+		reason string
+	}{
+		{"2026-05-09T00:00:01Z", nil, "CRL_EXPIRED"},
+		{"2026-04-30T00:00:00Z", nil, "CRL_NOT_YET_VALID"},
+		{"2026-05-02T00:00:00Z", map[string]interface{}{"user_id": ""}, "CRL_BAD_SIGNATURE"},
+		{"2026-05-02T00:00:00Z", map[string]interface{}{"issuer_certificate": testSignerCertPEM}, "CRL_ISSUER_MISMATCH"},
+	} {
+		if res := check(good, tc.at, tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%s %v: %+v", tc.at, tc.extra, res)
+		}
+	}
+
+	// A CRL for the OpenSSL fixture, signed with OpenSSL's empty identity.
+	ossl := mustCall(t, "sm2", "crl-create", map[string]interface{}{
+		"issuer_certificate": testSignerCertPEM,
+		"private_key":        testSignerKeyPEM,
+		"key_format":         "pem",
+		"user_id":            "",
+		"revoked":            []interface{}{map[string]interface{}{"serial": "1234", "reason": "superseded"}},
+	})
+	res = mustCall(t, "sm2", "crl-check", map[string]interface{}{
+		"crl": ossl.Output, "certificate": testSignerCertPEM, "issuer_certificate": testSignerCertPEM, "user_id": "",
+	})
+	if *res.Valid || res.RevocationReason != "superseded" {
+		t.Errorf("OpenSSL identity: %+v", res)
+	}
+
+	mustFail(t, "sm2", "crl-create", map[string]interface{}{"issuer_certificate": ca.Output, "private_key": ca.PrivateKey, "revoked": []interface{}{map[string]interface{}{"serial": "01", "reason": "bored"}}})
+	mustFail(t, "sm2", "crl-create", map[string]interface{}{"issuer_certificate": ca.Output, "private_key": ca.PrivateKey, "revoked": []interface{}{"01"}})
+	mustFail(t, "sm2", "crl-create", map[string]interface{}{"issuer_certificate": ca.Output, "private_key": ca.PrivateKey, "validity_days": 1, "next_update": "2030-01-01T00:00:00Z"})
+	mustFail(t, "sm2", "crl-check", map[string]interface{}{"crl": "3000", "certificate": good, "issuer_certificate": ca.Output})
+}
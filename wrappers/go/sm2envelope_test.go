@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestSM2Envelope(t *testing.T) {
+	k := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	for _, cc := range []string{"", "sm4-gcm", "sm4-cbc"} {
+		in := map[string]interface{}{"plaintext_hex": "00ff10", "public_key": k.PublicKey}
+		if cc != "" {
+			in["content_cipher"] = cc
+		}
+		env := mustCall(t, "sm2", "envelope-encrypt", in)
+		dec := mustCall(t, "sm2", "envelope-decrypt", map[string]interface{}{
+			"ciphertext": env.Output, "private_key": k.PrivateKey, "plaintext_encoding": "hex",
+		})
+		if dec.Output != "00ff10" {
+			t.Fatalf("%q: content = %s", cc, dec.Output)
+		}
+	}
+
+	env := mustCall(t, "sm2", "envelope-encrypt", map[string]interface{}{"plaintext": "secret", "public_key": k.PublicKey})
+	other := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	mustFail(t, "sm2", "envelope-decrypt", map[string]interface{}{"ciphertext": env.Output, "private_key": other.PrivateKey})
+	mustFail(t, "sm2", "envelope-encrypt", map[string]interface{}{"plaintext": "p", "public_key": k.PublicKey, "content_cipher": "sm4-ecb"})
+	mustFail(t, "sm2", "envelope-encrypt", map[string]interface{}{"plaintext": "p"})
+}
+
+func TestSM2CMSEnvelope(t *testing.T) {
+	pub := mustCall(t, "sm2", "derive-pub", map[string]interface{}{"private_key": testSignerKeyPEM, "key_format": "pem"}).PublicKey
+	for _, cc := range []string{"sm4-gcm", "sm4-cbc"} {
+		for _, recipient := range []map[string]interface{}{
+			{"certificate": testSignerCertPEM},
+			{"public_key": pub},
+		} {
+			in := map[string]interface{}{"plaintext": "cms content", "content_cipher": cc, "key_format": "pem"}
+			for k, v := range recipient {
+				in[k] = v
+			}
+			env := mustCall(t, "sm2", "cms-encrypt", in)
+			dec := mustCall(t, "sm2", "cms-decrypt", map[string]interface{}{"ciphertext": env.Output, "private_key": testSignerKeyPEM, "key_format": "pem"})
+			if dec.Output != "cms content" {
+				t.Fatalf("%s: content = %q", cc, dec.Output)
+			}
+		}
+	}
+	mustFail(t, "sm2", "cms-encrypt", map[string]interface{}{
+		"plaintext": "p", "certificate": testSignerCertPEM, "public_key": examplePub,
+	})
+}
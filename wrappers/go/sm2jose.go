@@ -0,0 +1,330 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/jose"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// headerField reads a JOSE header given as a JSON object.
+func headerField(in map[string]interface{}, name string) (jose.Header, error) {
+	v, ok := in[name]
+	if !ok || v == nil {
+		return jose.Header{}, nil
+	}
+	h, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("field %q must be a JSON object", name)}
+	}
+	return jose.Header(h), nil
+}
+
+// sm2JWSSign signs "payload" (or "payload_hex" / "payload_base64") as a
+// compact JWS with alg SM2-SM3, under "user_id", with "private_key" or a
+// generated key. "header" adds parameters to the protected header and
+// "kid" sets its key ID. "detached" leaves the payload out of the token.
+func sm2JWSSign(in map[string]interface{}) (*Result, error) {
+	payload, err := requireBytes(in, "payload")
+	if err != nil {
+		return nil, err
+	}
+	header, err := headerField(in, "header")
+	if err != nil {
+		return nil, err
+	}
+	kid, ok, err := stringField(in, "kid")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		header["kid"] = kid
+	}
+	detached, err := boolField(in, "detached")
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	priv, generated, err := sm2PrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jose.Sign(rand, header, payload, priv, uid, detached)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Output: token}
+	if res.PublicKey, err = encodePublicKey(in, &priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if generated {
+		if res.PrivateKey, err = encodePrivateKey(in, priv); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Reason codes of sm2JWSVerify for tokens that do not verify.
+var jwsReasons = map[error]string{
+	jose.ErrAlgorithm: "JWS_UNSUPPORTED_ALG",
+	jose.ErrCritical:  "JWS_UNSUPPORTED_CRIT",
+	jose.ErrSignature: "JWS_BAD_SIGNATURE",
+}
+
+// sm2JWSVerify checks the compact JWS "token" against "public_key" under
+// "user_id". A token with a detached payload takes it in "payload" (or
+// "payload_hex" / "payload_base64"). A valid token gives its header and
+// its payload in "plaintext_encoding"; a token that cannot be parsed is
+// invalid with reason JWS_MALFORMED.
+func sm2JWSVerify(in map[string]interface{}) (*Result, error) {
+	token, err := requireString(in, "token")
+	if err != nil {
+		return nil, err
+	}
+	detached, _, err := bytesField(in, "payload")
+	if err != nil {
+		return nil, err
+	}
+	pub, err := sm2PublicKey(in)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	jws, err := jose.ParseJWS(token, detached)
+	if err != nil {
+		return &Result{Valid: boolPtr(false), Reason: "JWS_MALFORMED"}, nil
+	}
+	if err := jws.Verify(pub, uid); err != nil {
+		return &Result{Valid: boolPtr(false), Reason: jwsReasons[err]}, nil
+	}
+	res := &Result{Valid: boolPtr(true), Header: jws.Header}
+	if res.Output, err = encodePlaintext(in, jws.Payload); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// sm2JWTSign issues the "claims" object as a JWT signed like jws-sign.
+// "iat" is set to "time" (RFC 3339, default now) unless the claims hold
+// one, and "expires_in" (seconds) sets "exp" that long after it.
+func sm2JWTSign(in map[string]interface{}) (*Result, error) {
+	claims := jose.Claims{}
+	if v, ok := in["claims"]; ok && v != nil {
+		c, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, &codedError{codeInvalidField, errors.New("field \"claims\" must be a JSON object")}
+		}
+		for k, v := range c {
+			claims[k] = v
+		}
+	}
+	now, ok, err := timeField(in, "time")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		now = time.Now()
+	}
+	if _, ok := claims["iat"]; !ok {
+		claims["iat"] = now.Unix()
+	}
+	expiresIn, ok, err := intField(in, "expires_in")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if _, ok := claims["exp"]; ok {
+			return nil, &codedError{codeInvalidField, errors.New("fields \"expires_in\" and \"claims.exp\" are mutually exclusive")}
+		}
+		if expiresIn <= 0 {
+			return nil, &codedError{codeInvalidField, errors.New("expires_in must be positive")}
+		}
+		claims["exp"] = now.Unix() + int64(expiresIn)
+	}
+	header, err := headerField(in, "header")
+	if err != nil {
+		return nil, err
+	}
+	kid, ok, err := stringField(in, "kid")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		header["kid"] = kid
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	priv, generated, err := sm2PrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	token, err := jose.SignJWT(rand, header, claims, priv, uid)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Output: token}
+	if res.PublicKey, err = encodePublicKey(in, &priv.PublicKey); err != nil {
+		return nil, err
+	}
+	if generated {
+		if res.PrivateKey, err = encodePrivateKey(in, priv); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// Reason codes of sm2JWTVerify for tokens whose claims do not hold.
+var jwtReasons = map[error]string{
+	jose.ErrClaims:      "JWT_MALFORMED",
+	jose.ErrExpired:     "JWT_EXPIRED",
+	jose.ErrNotYetValid: "JWT_NOT_YET_VALID",
+	jose.ErrIssuer:      "JWT_WRONG_ISSUER",
+	jose.ErrAudience:    "JWT_WRONG_AUDIENCE",
+}
+
+// sm2JWTVerify checks the JWT "token" like jws-verify and then its claims
+// at "time" (RFC 3339, default now), allowing "clock_skew" seconds for
+// "exp" and "nbf". "issuer" and "audience", when given, must match "iss"
+// and "aud", and "require_exp" rejects tokens that never expire. A valid
+// token gives its header and claims.
+func sm2JWTVerify(in map[string]interface{}) (*Result, error) {
+	token, err := requireString(in, "token")
+	if err != nil {
+		return nil, err
+	}
+	pub, err := sm2PublicKey(in)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	v := jose.Validation{}
+	var ok bool
+	if v.Time, ok, err = timeField(in, "time"); err != nil {
+		return nil, err
+	}
+	if !ok {
+		v.Time = time.Now()
+	}
+	skew, _, err := intField(in, "clock_skew")
+	if err != nil {
+		return nil, err
+	}
+	if skew < 0 {
+		return nil, &codedError{codeInvalidField, errors.New("clock_skew must not be negative")}
+	}
+	v.Leeway = time.Duration(skew) * time.Second
+	if v.Issuer, _, err = stringField(in, "issuer"); err != nil {
+		return nil, err
+	}
+	if v.Audience, _, err = stringField(in, "audience"); err != nil {
+		return nil, err
+	}
+	if v.RequireExp, err = boolField(in, "require_exp"); err != nil {
+		return nil, err
+	}
+
+	invalid := func(reason string) (*Result, error) {
+		return &Result{Valid: boolPtr(false), Reason: reason}, nil
+	}
+	jws, claims, err := jose.ParseJWT(token)
+	if err == jose.ErrClaims {
+		return invalid("JWT_MALFORMED")
+	}
+	if err != nil {
+		return invalid("JWS_MALFORMED")
+	}
+	if err := jws.Verify(pub, uid); err != nil {
+		return invalid(jwsReasons[err])
+	}
+	if err := claims.Validate(v); err != nil {
+		return invalid(jwtReasons[err])
+	}
+	return &Result{Valid: boolPtr(true), Header: jws.Header, Claims: claims}, nil
+}
+
+// sm2JWEEncrypt encrypts "plaintext" (or "plaintext_hex" /
+// "plaintext_base64") as a compact JWE with alg SM2 and enc SM4-GCM for
+// "public_key"; without one a key pair is generated and returned.
+// "header" adds parameters to the protected header and "kid" sets its key
+// ID.
+func sm2JWEEncrypt(in map[string]interface{}) (*Result, error) {
+	plaintext, err := requireBytes(in, "plaintext")
+	if err != nil {
+		return nil, err
+	}
+	header, err := headerField(in, "header")
+	if err != nil {
+		return nil, err
+	}
+	kid, ok, err := stringField(in, "kid")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		header["kid"] = kid
+	}
+	res := &Result{}
+	var pub *sm2.PublicKey
+	if _, ok := in["public_key"]; ok {
+		if pub, err = sm2PublicKey(in); err != nil {
+			return nil, err
+		}
+	} else {
+		priv, err := sm2.GenerateKey(rand)
+		if err != nil {
+			return nil, err
+		}
+		pub = &priv.PublicKey
+		if res.PrivateKey, err = encodePrivateKey(in, priv); err != nil {
+			return nil, err
+		}
+		if res.PublicKey, err = encodePublicKey(in, pub); err != nil {
+			return nil, err
+		}
+	}
+	if res.Output, err = jose.Encrypt(rand, header, plaintext, pub); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// sm2JWEDecrypt decrypts the compact JWE "token" with "private_key" and
+// returns its header and its plaintext in "plaintext_encoding".
+func sm2JWEDecrypt(in map[string]interface{}) (*Result, error) {
+	token, err := requireString(in, "token")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	jwe, err := jose.ParseJWE(token)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := jwe.Decrypt(priv)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Header: jwe.Header}
+	if res.Output, err = encodePlaintext(in, plaintext); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
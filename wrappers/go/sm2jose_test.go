@@ -0,0 +1,131 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM2JWS(t *testing.T) {
+	signed := mustCall(t, "sm2", "jws-sign", map[string]interface{}{
+		"payload": `{"sub":"alice"}`,
+		"header":  map[string]interface{}{"typ": "JWT"},
+		"kid":     "gateway-1",
+	})
+	if signed.PrivateKey == "" || strings.Count(signed.Output, ".") != 2 {
+		t.Fatalf("sign: %+v", signed)
+	}
+	verify := func(extra map[string]interface{}) *Result {
+		in := map[string]interface{}{"token": signed.Output, "public_key": signed.PublicKey}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "jws-verify", in)
+	}
+	res := verify(nil)
+	if !*res.Valid || res.Output != `{"sub":"alice"}` || res.Header["alg"] != "SM2-SM3" || res.Header["kid"] != "gateway-1" || res.Header["typ"] != "JWT" {
+		t.Errorf("valid: %+v", res)
+	}
+	other := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	parts := strings.Split(signed.Output, ".")
+	for _, tc := range []struct {
+		extra  map[string]interface{}
+		reason string
+	}{
+		{map[string]interface{}{"public_key": other.PublicKey}, "JWS_BAD_SIGNATURE"},
+		{map[string]interface{}{"user_id": "other"}, "JWS_BAD_SIGNATURE"},
+		{map[string]interface{}{"token": "eyJhbGciOiJub25lIn0." + parts[1] + "."}, "JWS_UNSUPPORTED_ALG"},
+		{map[string]interface{}{"token": parts[0] + "." + parts[1]}, "JWS_MALFORMED"},
+	} {
+		if res := verify(tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%v: %+v, want %s", tc.extra, res, tc.reason)
+		}
+	}
+
+	detached := mustCall(t, "sm2", "jws-sign", map[string]interface{}{
+		"payload_hex": "00ff", "detached": true, "private_key": other.PrivateKey, "user_id": "gw",
+	})
+	if strings.Split(detached.Output, ".")[1] != "" {
+		t.Fatalf("detached: %s", detached.Output)
+	}
+	if res := verify(map[string]interface{}{"token": detached.Output, "public_key": other.PublicKey, "user_id": "gw", "payload_hex": "00ff", "plaintext_encoding": "hex"}); !*res.Valid || res.Output != "00ff" {
+		t.Errorf("detached: %+v", res)
+	}
+	mustFail(t, "sm2", "jws-sign", map[string]interface{}{"payload": "x", "header": map[string]interface{}{"alg": "ES256"}})
+	mustFail(t, "sm2", "jws-sign", map[string]interface{}{"payload": "x", "header": "typ=JWT"})
+}
+
+func TestSM2JWT(t *testing.T) {
+	issued := mustCall(t, "sm2", "jwt-sign", map[string]interface{}{
+		"claims":     map[string]interface{}{"iss": "https://idp.example", "aud": []interface{}{"api"}, "sub": "alice", "nbf": 1780000000},
+		"time":       "2026-05-28T20:26:40Z",
+		"expires_in": 300,
+	})
+	verify := func(extra map[string]interface{}) *Result {
+		in := map[string]interface{}{
+			"token": issued.Output, "public_key": issued.PublicKey,
+			"time": "2026-05-28T20:30:00Z", "issuer": "https://idp.example", "audience": "api",
+		}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "jwt-verify", in)
+	}
+	res := verify(nil)
+	if !*res.Valid || res.Header["typ"] != "JWT" || res.Claims["sub"] != "alice" || res.Claims["iat"] != 1780000000.0 || res.Claims["exp"] != 1780000300.0 {
+		t.Errorf("valid: %+v", res)
+	}
+	if res := verify(map[string]interface{}{"issuer": nil, "audience": nil}); !*res.Valid {
+		t.Errorf("without expectations: %+v", res)
+	}
+	for _, tc := range []struct {
+		extra  map[string]interface{}
+		reason string
+	}{
+		{map[string]interface{}{"time": "2026-05-28T20:31:40Z"}, "JWT_EXPIRED"},
+		{map[string]interface{}{"time": "2026-05-28T20:26:39Z"}, "JWT_NOT_YET_VALID"},
+		{map[string]interface{}{"issuer": "https://other.example"}, "JWT_WRONG_ISSUER"},
+		{map[string]interface{}{"audience": "admin"}, "JWT_WRONG_AUDIENCE"},
+		{map[string]interface{}{"user_id": "other"}, "JWS_BAD_SIGNATURE"},
+		{map[string]interface{}{"token": "x.y"}, "JWS_MALFORMED"},
+	} {
+		if res := verify(tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%v: %+v, want %s", tc.extra, res, tc.reason)
+		}
+	}
+	// Clock skew forgives a clock that runs a little ahead or behind.
+	for _, at := range []string{"2026-05-28T20:31:40Z", "2026-05-28T20:26:39Z"} {
+		if res := verify(map[string]interface{}{"time": at, "clock_skew": 5}); !*res.Valid {
+			t.Errorf("skew at %s: %+v", at, res)
+		}
+	}
+
+	open := mustCall(t, "sm2", "jwt-sign", map[string]interface{}{"private_key": issued.PrivateKey, "claims": map[string]interface{}{"sub": "bob"}})
+	if res := mustCall(t, "sm2", "jwt-verify", map[string]interface{}{"token": open.Output, "public_key": issued.PublicKey}); !*res.Valid {
+		t.Errorf("no exp: %+v", res)
+	}
+	if res := mustCall(t, "sm2", "jwt-verify", map[string]interface{}{"token": open.Output, "public_key": issued.PublicKey, "require_exp": true}); *res.Valid || res.Reason != "JWT_EXPIRED" {
+		t.Errorf("require_exp: %+v", res)
+	}
+	mustFail(t, "sm2", "jwt-sign", map[string]interface{}{"claims": map[string]interface{}{"exp": 1}, "expires_in": 60})
+	mustFail(t, "sm2", "jwt-sign", map[string]interface{}{"claims": []interface{}{}})
+	mustFail(t, "sm2", "jwt-verify", map[string]interface{}{"token": open.Output, "public_key": issued.PublicKey, "clock_skew": -1})
+}
+
+func TestSM2JWE(t *testing.T) {
+	enc := mustCall(t, "sm2", "jwe-encrypt", map[string]interface{}{"plaintext": "secret", "kid": "r1"})
+	if enc.PrivateKey == "" || strings.Count(enc.Output, ".") != 4 {
+		t.Fatalf("encrypt: %+v", enc)
+	}
+	res := mustCall(t, "sm2", "jwe-decrypt", map[string]interface{}{"token": enc.Output, "private_key": enc.PrivateKey})
+	if res.Output != "secret" || res.Header["alg"] != "SM2" || res.Header["enc"] != "SM4-GCM" || res.Header["kid"] != "r1" {
+		t.Errorf("decrypt: %+v", res)
+	}
+	bin := mustCall(t, "sm2", "jwe-encrypt", map[string]interface{}{"plaintext_hex": "00ff", "public_key": enc.PublicKey})
+	if res := mustCall(t, "sm2", "jwe-decrypt", map[string]interface{}{"token": bin.Output, "private_key": enc.PrivateKey, "plaintext_encoding": "hex"}); res.Output != "00ff" {
+		t.Errorf("binary: %+v", res)
+	}
+	other := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	mustFail(t, "sm2", "jwe-decrypt", map[string]interface{}{"token": enc.Output, "private_key": other.PrivateKey})
+	mustFail(t, "sm2", "jwe-decrypt", map[string]interface{}{"token": "a.b.c", "private_key": enc.PrivateKey})
+	mustFail(t, "sm2", "jwe-encrypt", map[string]interface{}{"plaintext": "x", "header": map[string]interface{}{"alg": "RSA-OAEP"}})
+}
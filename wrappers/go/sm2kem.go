@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/hex"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// sm2Encapsulate returns a shared key of "key_length" bytes for
+// "public_key" and the ephemeral point that carries it. When no public
+// key is given a key pair is generated and returned.
+func sm2Encapsulate(in map[string]interface{}) (*Result, error) {
+	klen, err := keyLengthField(in, "key_length")
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	var pub *sm2.PublicKey
+	if _, ok := in["public_key"]; ok {
+		if pub, err = sm2PublicKey(in); err != nil {
+			return nil, err
+		}
+	} else {
+		priv, err := sm2.GenerateKey(rand)
+		if err != nil {
+			return nil, err
+		}
+		pub = &priv.PublicKey
+		if res.PrivateKey, err = encodePrivateKey(in, priv); err != nil {
+			return nil, err
+		}
+		if res.PublicKey, err = encodePublicKey(in, pub); err != nil {
+			return nil, err
+		}
+	}
+	key, c, err := sm2.Encapsulate(rand, pub, klen)
+	if err != nil {
+		return nil, err
+	}
+	res.Output = hex.EncodeToString(key)
+	res.Encapsulation = hex.EncodeToString(c)
+	return res, nil
+}
+
+// sm2Decapsulate recovers the "key_length"-byte shared key of the hex
+// "encapsulation" with "private_key".
+func sm2Decapsulate(in map[string]interface{}) (*Result, error) {
+	klen, err := keyLengthField(in, "key_length")
+	if err != nil {
+		return nil, err
+	}
+	c, err := requireHex(in, "encapsulation")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sm2.Decapsulate(priv, c, klen)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(key)}, nil
+}
+
+// sm2ECDH returns the x-coordinate of [d]P for "private_key" d and
+// "peer_public_key" P and, with "key_length", KDF(x, key_length).
+func sm2ECDH(in map[string]interface{}) (*Result, error) {
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := requirePublicKey(in, "peer_public_key")
+	if err != nil {
+		return nil, err
+	}
+	x, err := sm2.ECDH(priv, peer)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Output: hex.EncodeToString(x)}
+	if _, ok := in["key_length"]; ok {
+		klen, err := keyLengthField(in, "key_length")
+		if err != nil {
+			return nil, err
+		}
+		res.DerivedKey = hex.EncodeToString(sm2.KDF(x, klen))
+	}
+	return res, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import "testing"
+
+func TestSM2Encapsulate(t *testing.T) {
+	enc := mustCall(t, "sm2", "encapsulate", map[string]interface{}{"key_length": 16})
+	if len(enc.Output) != 32 || len(enc.Encapsulation) != 130 || enc.PrivateKey == "" {
+		t.Fatalf("encapsulate: %+v", enc)
+	}
+	dec := map[string]interface{}{"encapsulation": enc.Encapsulation, "private_key": enc.PrivateKey, "key_length": 16}
+	if res := mustCall(t, "sm2", "decapsulate", dec); res.Output != enc.Output {
+		t.Errorf("decapsulated key %s, want %s", res.Output, enc.Output)
+	}
+
+	again := mustCall(t, "sm2", "encapsulate", map[string]interface{}{"public_key": enc.PublicKey, "key_length": 48})
+	if again.PrivateKey != "" || len(again.Output) != 96 {
+		t.Fatalf("encapsulate to a given key: %+v", again)
+	}
+	dec["encapsulation"], dec["key_length"] = again.Encapsulation, 48
+	if res := mustCall(t, "sm2", "decapsulate", dec); res.Output != again.Output {
+		t.Error("48-byte key does not round-trip")
+	}
+	dec["encapsulation"] = "04" + again.Encapsulation[4:]
+	mustFail(t, "sm2", "decapsulate", dec)
+	mustFail(t, "sm2", "encapsulate", map[string]interface{}{"public_key": enc.PublicKey})
+}
+
+func TestSM2ECDH(t *testing.T) {
+	a := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	b := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	ab := mustCall(t, "sm2", "ecdh", map[string]interface{}{"private_key": a.PrivateKey, "peer_public_key": b.PublicKey, "key_length": 16})
+	ba := mustCall(t, "sm2", "ecdh", map[string]interface{}{"private_key": b.PrivateKey, "peer_public_key": a.PublicKey, "key_length": 16})
+	if len(ab.Output) != 64 || ab.Output != ba.Output || len(ab.DerivedKey) != 32 || ab.DerivedKey != ba.DerivedKey {
+		t.Fatalf("a: %+v, b: %+v", ab, ba)
+	}
+	kdf := mustCall(t, "sm3", "kdf", map[string]interface{}{"shared_secret": ab.Output, "key_length": 16})
+	if kdf.Output != ab.DerivedKey {
+		t.Errorf("derived key %s, sm3 kdf %s", ab.DerivedKey, kdf.Output)
+	}
+	if raw := mustCall(t, "sm2", "ecdh", map[string]interface{}{"private_key": a.PrivateKey, "peer_public_key": b.PublicKey}); raw.DerivedKey != "" {
+		t.Errorf("derived key without key_length: %+v", raw)
+	}
+	mustFail(t, "sm2", "ecdh", map[string]interface{}{"private_key": a.PrivateKey})
+}
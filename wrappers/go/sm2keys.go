@@ -0,0 +1,364 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pbes2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// SM2 key encodings selected by "key_format". "hex" keys are the raw
+// scalar and point; "der" keys are hex encoded PKCS #8 PrivateKeyInfo and
+// SubjectPublicKeyInfo structures, and "pem" keys are the same structures
+// as PEM text.
+const (
+	keyFormatHex = "hex"
+	keyFormatDER = "der"
+	keyFormatPEM = "pem"
+)
+
+// sm2KeyFormat holds the request fields that say how the SM2 keys of a
+// request are encoded.
+type sm2KeyFormat struct {
+	KeyFormat  string  `json:"key_format"`
+	Passphrase *string `json:"passphrase"`
+}
+
+// sm2KeyEncoding adds the fields that say how the keys of a result are
+// encoded.
+type sm2KeyEncoding struct {
+	sm2KeyFormat
+	KeyCipher   string `json:"key_cipher"`
+	PointFormat string `json:"point_format"`
+}
+
+// keyEncoding reads the key encoding fields of in, for the handlers that
+// read their other fields one by one.
+func keyEncoding(in map[string]interface{}) (sm2KeyEncoding, error) {
+	var e sm2KeyEncoding
+	err := decodeRequest(in, &e)
+	return e, err
+}
+
+// keyTextField reads the key field name of in, nil when it is absent.
+func keyTextField(in map[string]interface{}, name string) (*keyText, error) {
+	if raw, ok := in[name].([]byte); ok {
+		k := keyText(hex.EncodeToString(raw))
+		return &k, nil
+	}
+	s, ok, err := stringField(in, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	k := keyText(s)
+	return &k, nil
+}
+
+// format normalizes "key_format", hex by default.
+func (f sm2KeyFormat) format() (string, error) {
+	switch k := strings.ToLower(f.KeyFormat); k {
+	case "":
+		return keyFormatHex, nil
+	case keyFormatHex, keyFormatDER, keyFormatPEM:
+		return k, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported key_format %q (supported: hex, der, pem)", f.KeyFormat)}
+}
+
+// keyDER returns the DER bytes of the key in field name, which is hex
+// for "der" and PEM text of one of the allowed block types for "pem".
+func keyDER(s, format, name string, pemTypes ...string) (der []byte, pemType string, err error) {
+	if format == keyFormatDER {
+		der, err = hex.DecodeString(s)
+		if err != nil {
+			return nil, "", &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid hex: %v", name, err)}
+		}
+		return der, "", nil
+	}
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, "", &codedError{codeInvalidEncoding, fmt.Errorf("field %q does not contain a PEM block", name)}
+	}
+	for _, t := range pemTypes {
+		if block.Type == t {
+			return block.Bytes, t, nil
+		}
+	}
+	return nil, "", &codedError{codeInvalidEncoding, fmt.Errorf("field %q holds a %q PEM block, want %s", name, block.Type, strings.Join(pemTypes, " or "))}
+}
+
+// passphrase returns the "passphrase" protecting PKCS #8 private keys.
+// It only applies to the der and pem key formats.
+func (f sm2KeyFormat) passphrase(format string) (string, bool, error) {
+	if f.Passphrase == nil {
+		return "", false, nil
+	}
+	if format == keyFormatHex {
+		return "", false, &codedError{codeInvalidField, errors.New("field \"passphrase\" requires key_format der or pem")}
+	}
+	return *f.Passphrase, true, nil
+}
+
+// privateKeyBytes returns the private scalar from key, the private key
+// field name, in "key_format" without range checking it. With a
+// "passphrase" the key is an EncryptedPrivateKeyInfo (PBES2).
+func (f sm2KeyFormat) privateKeyBytes(name string, key *keyText) ([]byte, bool, error) {
+	format, err := f.format()
+	if err != nil {
+		return nil, false, err
+	}
+	pass, encrypted, err := f.passphrase(format)
+	if err != nil || key == nil {
+		return nil, false, err
+	}
+	if format == keyFormatHex {
+		d, err := hex.DecodeString(string(*key))
+		if err != nil {
+			return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid hex: %v", name, err)}
+		}
+		return d, true, nil
+	}
+	der, pemType, err := keyDER(string(*key), format, name, "PRIVATE KEY", "EC PRIVATE KEY", "ENCRYPTED PRIVATE KEY")
+	if err != nil {
+		return nil, false, err
+	}
+	if pemType == "ENCRYPTED PRIVATE KEY" && !encrypted {
+		return nil, false, &codedError{codeMissingField, fmt.Errorf("field %q is encrypted; set \"passphrase\"", name)}
+	}
+	if encrypted {
+		if pemType != "" && pemType != "ENCRYPTED PRIVATE KEY" {
+			return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q holds a %q PEM block, want ENCRYPTED PRIVATE KEY", name, pemType)}
+		}
+		if der, err = pbes2.Decrypt(der, pass); err != nil {
+			return nil, false, err
+		}
+	}
+	var d []byte
+	if pemType == "EC PRIVATE KEY" {
+		d, err = sm2.UnmarshalECPrivateKey(der)
+	} else {
+		d, err = sm2.UnmarshalPKCS8PrivateKey(der)
+	}
+	return d, err == nil, err
+}
+
+// publicKeyBytes returns the encoded point from key, the public key field
+// name, in "key_format" without validating it.
+func (f sm2KeyFormat) publicKeyBytes(name string, key *keyText) ([]byte, bool, error) {
+	format, err := f.format()
+	if err != nil || key == nil {
+		return nil, false, err
+	}
+	if format == keyFormatHex {
+		q, err := hex.DecodeString(string(*key))
+		if err != nil {
+			return nil, false, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is not valid hex: %v", name, err)}
+		}
+		return q, true, nil
+	}
+	der, _, err := keyDER(string(*key), format, name, "PUBLIC KEY")
+	if err != nil {
+		return nil, false, err
+	}
+	point, err := sm2.UnmarshalPKIXPublicKey(der)
+	return point, err == nil, err
+}
+
+// privateKeyOrNew loads the "private_key" key, generating a key pair when
+// it is absent. generated reports whether the caller should echo the new
+// key.
+func (f sm2KeyFormat) privateKeyOrNew(key *keyText) (priv *sm2.PrivateKey, generated bool, err error) {
+	d, ok, err := f.privateKeyBytes("private_key", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		priv, err = sm2.GenerateKey(rand)
+		return priv, true, err
+	}
+	priv, err = sm2.NewPrivateKey(d)
+	return priv, false, err
+}
+
+// privateKey loads the required private key field name from key.
+func (f sm2KeyFormat) privateKey(name string, key *keyText) (*sm2.PrivateKey, error) {
+	d, ok, err := f.privateKeyBytes(name, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return sm2.NewPrivateKey(d)
+}
+
+// publicKey loads the required public key field name from key, compressed
+// or uncompressed.
+func (f sm2KeyFormat) publicKey(name string, key *keyText) (*sm2.PublicKey, error) {
+	b, ok, err := f.publicKeyBytes(name, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return sm2.ParsePublicKey(b)
+}
+
+// sm2PrivateKey loads "private_key", generating a key pair when it is
+// absent. generated reports whether the caller should echo the new key.
+func sm2PrivateKey(in map[string]interface{}) (priv *sm2.PrivateKey, generated bool, err error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return nil, false, err
+	}
+	key, err := keyTextField(in, "private_key")
+	if err != nil {
+		return nil, false, err
+	}
+	return e.privateKeyOrNew(key)
+}
+
+func requirePrivateKey(in map[string]interface{}) (*sm2.PrivateKey, error) {
+	return requirePrivateKeyField(in, "private_key")
+}
+
+func requirePrivateKeyField(in map[string]interface{}, name string) (*sm2.PrivateKey, error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyTextField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	return e.privateKey(name, key)
+}
+
+// sm2PublicKey loads "public_key", compressed or uncompressed.
+func sm2PublicKey(in map[string]interface{}) (*sm2.PublicKey, error) {
+	return requirePublicKey(in, "public_key")
+}
+
+func requirePublicKey(in map[string]interface{}, name string) (*sm2.PublicKey, error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keyTextField(in, name)
+	if err != nil {
+		return nil, err
+	}
+	return e.publicKey(name, key)
+}
+
+// encodePrivateKey renders priv in "key_format". With a "passphrase" the
+// PrivateKeyInfo is encrypted with the PBES2 scheme named by "key_cipher":
+// "sm4" (default, SM4-CBC with HMAC-SM3) or "aes" (AES-256-CBC with
+// HMAC-SHA256, readable by OpenSSL).
+func (e sm2KeyEncoding) encodePrivateKey(priv *sm2.PrivateKey) (string, error) {
+	format, err := e.format()
+	if err != nil {
+		return "", err
+	}
+	pass, encrypted, err := e.passphrase(format)
+	if err != nil {
+		return "", err
+	}
+	if format == keyFormatHex {
+		return hex.EncodeToString(priv.Bytes()), nil
+	}
+	der, err := sm2.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	if !encrypted {
+		return encodeKeyDER(format, "PRIVATE KEY", der), nil
+	}
+	scheme, err := keyCipherScheme(e.KeyCipher)
+	if err != nil {
+		return "", err
+	}
+	if der, err = pbes2.Encrypt(rand, der, pass, scheme); err != nil {
+		return "", err
+	}
+	return encodeKeyDER(format, "ENCRYPTED PRIVATE KEY", der), nil
+}
+
+// keyCipherScheme returns the PBES2 scheme named by the key_cipher c, SM4
+// by default.
+func keyCipherScheme(c string) (pbes2.Scheme, error) {
+	switch strings.ToLower(c) {
+	case "", "sm4":
+		return pbes2.SM4CBCWithHMACSM3, nil
+	case "aes":
+		return pbes2.AES256CBCWithHMACSHA256, nil
+	}
+	return 0, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported key_cipher %q (supported: sm4, aes)", c)}
+}
+
+// keyCipher returns the PBES2 scheme named by "key_cipher".
+func keyCipher(in map[string]interface{}) (pbes2.Scheme, error) {
+	c, _, err := stringField(in, "key_cipher")
+	if err != nil {
+		return 0, err
+	}
+	return keyCipherScheme(c)
+}
+
+// encodePublicKey renders pub in "key_format", with the point as selected
+// by "point_format": "uncompressed" (default, 04 || X || Y) or
+// "compressed" (02/03 || X).
+func (e sm2KeyEncoding) encodePublicKey(pub *sm2.PublicKey) (string, error) {
+	point := pub.Bytes()
+	switch strings.ToLower(e.PointFormat) {
+	case "", "uncompressed":
+	case "compressed":
+		point = pub.CompressedBytes()
+	default:
+		return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported point_format %q (supported: uncompressed, compressed)", e.PointFormat)}
+	}
+	format, err := e.format()
+	if err != nil {
+		return "", err
+	}
+	if format == keyFormatHex {
+		return hex.EncodeToString(point), nil
+	}
+	der, err := sm2.MarshalPKIXPublicKey(point)
+	if err != nil {
+		return "", err
+	}
+	return encodeKeyDER(format, "PUBLIC KEY", der), nil
+}
+
+// encodePrivateKey renders priv as sm2KeyEncoding.encodePrivateKey does
+// for the key encoding fields of in.
+func encodePrivateKey(in map[string]interface{}, priv *sm2.PrivateKey) (string, error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return "", err
+	}
+	return e.encodePrivateKey(priv)
+}
+
+// encodePublicKey renders pub as sm2KeyEncoding.encodePublicKey does for
+// the key encoding fields of in.
+func encodePublicKey(in map[string]interface{}, pub *sm2.PublicKey) (string, error) {
+	e, err := keyEncoding(in)
+	if err != nil {
+		return "", err
+	}
+	return e.encodePublicKey(pub)
+}
+
+func encodeKeyDER(format, pemType string, der []byte) string {
+	if format == keyFormatPEM {
+		return string(pem.EncodeToMemory(&pem.Block{Type: pemType, Bytes: der}))
+	}
+	return hex.EncodeToString(der)
+}
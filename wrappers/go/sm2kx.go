@@ -0,0 +1,180 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// reasonConfirmationMismatch is the Reason when the peer's key
+// confirmation hash (SA or SB) does not match.
+const reasonConfirmationMismatch = "CONFIRMATION_MISMATCH"
+
+// Key exchange roles selected by "role".
+const (
+	roleInitiator = "initiator"
+	roleResponder = "responder"
+)
+
+// ephemeralKey loads the hex "ephemeral_private_key", generating one
+// when it is absent. generated reports whether the caller should echo it.
+func ephemeralKey(in map[string]interface{}) (priv *sm2.PrivateKey, generated bool, err error) {
+	d, ok, err := hexField(in, "ephemeral_private_key")
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		priv, err = sm2.GenerateKey(rand)
+		return priv, true, err
+	}
+	priv, err = sm2.NewPrivateKey(d)
+	return priv, false, err
+}
+
+// keyExchange assembles one party's view of the protocol from the
+// request: its static "private_key" and ephemeral key, the peer's static
+// "peer_public_key" and hex "peer_ephemeral_public_key", and both
+// identities ("user_id" and "peer_user_id").
+func keyExchange(in map[string]interface{}, initiator bool, ephemeral *sm2.PrivateKey) (*sm2.KeyExchange, error) {
+	static, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	peer, err := requirePublicKey(in, "peer_public_key")
+	if err != nil {
+		return nil, err
+	}
+	b, err := requireHex(in, "peer_ephemeral_public_key")
+	if err != nil {
+		return nil, err
+	}
+	peerEphemeral, err := sm2.ParsePublicKey(b)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	peerUID, err := userIDField(in, "peer_user_id")
+	if err != nil {
+		return nil, err
+	}
+	return &sm2.KeyExchange{
+		Initiator: initiator,
+		Static:    static,
+		Ephemeral: ephemeral,
+		UID:       uid,
+
+		PeerStatic:    peer,
+		PeerEphemeral: peerEphemeral,
+		PeerUID:       peerUID,
+	}, nil
+}
+
+// sm2KeyExchangeInit starts an exchange as the initiator (user A) by
+// drawing the ephemeral key pair (rA, RA). RA goes to the responder; rA is
+// needed again for sm2KeyExchangeConfirm.
+func sm2KeyExchangeInit(in map[string]interface{}) (*Result, error) {
+	eph, err := sm2.GenerateKey(rand)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{
+		EphemeralPrivateKey: hex.EncodeToString(eph.Bytes()),
+		EphemeralPublicKey:  hex.EncodeToString(eph.PublicKey.Bytes()),
+	}, nil
+}
+
+// sm2KeyExchangeRespond runs the responder (user B) side: it derives KB
+// of "key_length" bytes from the initiator's RA and returns RB and the
+// confirmation hash SB. "ephemeral_private_key" fixes rB for test vectors.
+func sm2KeyExchangeRespond(in map[string]interface{}) (*Result, error) {
+	eph, generated, err := ephemeralKey(in)
+	if err != nil {
+		return nil, err
+	}
+	kx, err := keyExchange(in, false, eph)
+	if err != nil {
+		return nil, err
+	}
+	klen, err := keyLengthField(in, "key_length")
+	if err != nil {
+		return nil, err
+	}
+	key, sb, _, err := kx.Agree(klen)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{
+		Output:             hex.EncodeToString(key),
+		EphemeralPublicKey: hex.EncodeToString(eph.PublicKey.Bytes()),
+		Confirmation:       hex.EncodeToString(sb),
+	}
+	if generated {
+		res.EphemeralPrivateKey = hex.EncodeToString(eph.Bytes())
+	}
+	return res, nil
+}
+
+// sm2KeyExchangeConfirm finishes an exchange. As the initiator (the
+// default "role") it derives KA from RB, checks the responder's SB when
+// "confirmation" is given and returns SA. As the responder it checks the
+// initiator's SA in "confirmation". Both take this party's
+// "ephemeral_private_key"; a mismatch is reported through Valid and
+// Reason, and withholds the key.
+func sm2KeyExchangeConfirm(in map[string]interface{}) (*Result, error) {
+	role, ok, err := stringField(in, "role")
+	if err != nil {
+		return nil, err
+	}
+	role = strings.ToLower(role)
+	if !ok {
+		role = roleInitiator
+	}
+	if role != roleInitiator && role != roleResponder {
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported role %q (supported: initiator, responder)", role)}
+	}
+	d, err := requireHex(in, "ephemeral_private_key")
+	if err != nil {
+		return nil, err
+	}
+	eph, err := sm2.NewPrivateKey(d)
+	if err != nil {
+		return nil, err
+	}
+	kx, err := keyExchange(in, role == roleInitiator, eph)
+	if err != nil {
+		return nil, err
+	}
+	klen, err := keyLengthField(in, "key_length")
+	if err != nil {
+		return nil, err
+	}
+	peerConfirm, hasConfirm, err := hexField(in, "confirmation")
+	if err != nil {
+		return nil, err
+	}
+	if !hasConfirm && role == roleResponder {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", "confirmation")}
+	}
+	key, send, expect, err := kx.Agree(klen)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if hasConfirm {
+		if subtle.ConstantTimeCompare(peerConfirm, expect) != 1 {
+			return &Result{Valid: boolPtr(false), Reason: reasonConfirmationMismatch}, nil
+		}
+		res.Valid = boolPtr(true)
+	}
+	res.Output = hex.EncodeToString(key)
+	if role == roleInitiator {
+		res.Confirmation = hex.EncodeToString(send)
+	}
+	return res, nil
+}
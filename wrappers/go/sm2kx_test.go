@@ -0,0 +1,71 @@
+package main
+
+import "testing"
+
+func TestSM2KeyExchange(t *testing.T) {
+	a := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	b := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	ra := mustCall(t, "sm2", "keyexchange-init", map[string]interface{}{})
+
+	resp := mustCall(t, "sm2", "keyexchange-respond", map[string]interface{}{
+		"private_key": b.PrivateKey, "user_id": "bob@example.com",
+		"peer_public_key": a.PublicKey, "peer_user_id": "alice@example.com",
+		"peer_ephemeral_public_key": ra.EphemeralPublicKey, "key_length": 16,
+	})
+	initiator := map[string]interface{}{
+		"private_key": a.PrivateKey, "ephemeral_private_key": ra.EphemeralPrivateKey, "user_id": "alice@example.com",
+		"peer_public_key": b.PublicKey, "peer_user_id": "bob@example.com",
+		"peer_ephemeral_public_key": resp.EphemeralPublicKey, "key_length": 16,
+		"confirmation": resp.Confirmation,
+	}
+	conf := mustCall(t, "sm2", "keyexchange-confirm", initiator)
+	if conf.Valid == nil || !*conf.Valid || conf.Output != resp.Output || len(conf.Output) != 32 {
+		t.Fatalf("initiator: %+v, responder key %s", conf, resp.Output)
+	}
+	check := mustCall(t, "sm2", "keyexchange-confirm", map[string]interface{}{
+		"role": "responder", "private_key": b.PrivateKey, "ephemeral_private_key": resp.EphemeralPrivateKey,
+		"user_id": "bob@example.com", "peer_public_key": a.PublicKey, "peer_user_id": "alice@example.com",
+		"peer_ephemeral_public_key": ra.EphemeralPublicKey, "key_length": 16, "confirmation": conf.Confirmation,
+	})
+	if check.Valid == nil || !*check.Valid || check.Output != resp.Output {
+		t.Fatalf("responder: %+v", check)
+	}
+
+	// A different identity changes ZA, so SB no longer matches.
+	initiator["peer_user_id"] = "mallory@example.com"
+	bad := mustCall(t, "sm2", "keyexchange-confirm", initiator)
+	if bad.Valid == nil || *bad.Valid || bad.Reason != reasonConfirmationMismatch || bad.Output != "" {
+		t.Fatalf("mismatch: %+v", bad)
+	}
+	delete(initiator, "ephemeral_private_key")
+	mustFail(t, "sm2", "keyexchange-confirm", initiator)
+}
+
+func TestSM2KeyExchangeVector(t *testing.T) {
+	// GB/T 32918.5 example with the default identities.
+	const (
+		dA = "81eb26e941bb5af16df116495f90695272ae2cd63d6c4ae1678418be48230029"
+		dB = "785129917d45a9ea5437a59356b82338eaadda6ceb199088f14ae10defa229b5"
+		rA = "d4de15474db74d06491c440d305e012400990f3e390c7e87153c12db2ea60bb3"
+		rB = "7e07124814b309489125eaed101113164ebf0f3458c5bd88335c1f9d596243d6"
+	)
+	pub := func(d string) string {
+		return mustCall(t, "sm2", "derive-pub", map[string]interface{}{"private_key": d}).PublicKey
+	}
+	resp := mustCall(t, "sm2", "keyexchange-respond", map[string]interface{}{
+		"private_key": dB, "ephemeral_private_key": rB, "peer_public_key": pub(dA),
+		"peer_ephemeral_public_key": pub(rA), "key_length": 16,
+	})
+	if resp.Output != "6c89347354de2484c60b4ab1fde4c6e5" ||
+		resp.Confirmation != "d3a0fe15dee185ceae907a6b595cc32a266ed7b3367e9983a896dc32fa20f8eb" ||
+		resp.EphemeralPrivateKey != "" {
+		t.Fatalf("respond = %+v", resp)
+	}
+	conf := mustCall(t, "sm2", "keyexchange-confirm", map[string]interface{}{
+		"private_key": dA, "ephemeral_private_key": rA, "peer_public_key": pub(dB),
+		"peer_ephemeral_public_key": resp.EphemeralPublicKey, "key_length": 16, "confirmation": resp.Confirmation,
+	})
+	if conf.Confirmation != "18c7894b3816df16cf07b05c5ec0bef5d655d58f779cc1b400a4f3884644db88" {
+		t.Fatalf("SA = %s", conf.Confirmation)
+	}
+}
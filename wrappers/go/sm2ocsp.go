@@ -0,0 +1,281 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/ocsp"
+)
+
+// ocspHashes maps the names accepted in "hash_algorithm".
+var ocspHashes = map[string]ocsp.HashAlgorithm{
+	"sm3":    ocsp.SM3,
+	"sha1":   ocsp.SHA1,
+	"sha256": ocsp.SHA256,
+}
+
+// ocspErrorStatuses maps the unsuccessful response statuses of RFC 6960
+// to the names accepted in "error_status" and the reasons of ocsp-verify.
+var ocspErrorStatuses = []struct {
+	status ocsp.ResponseStatus
+	name   string
+	reason string
+}{
+	{ocsp.MalformedRequest, "malformedRequest", "OCSP_MALFORMED_REQUEST"},
+	{ocsp.InternalError, "internalError", "OCSP_INTERNAL_ERROR"},
+	{ocsp.TryLater, "tryLater", "OCSP_TRY_LATER"},
+	{ocsp.SigRequired, "sigRequired", "OCSP_SIG_REQUIRED"},
+	{ocsp.Unauthorized, "unauthorized", "OCSP_UNAUTHORIZED"},
+}
+
+// ocspNonceSize is the length of nonces generated by ocsp-request.
+const ocspNonceSize = 16
+
+// sm2OCSPRequest builds an OCSP request for "certificate", issued by
+// "issuer_certificate", with the CertID hashed by "hash_algorithm" (sm3,
+// the default, sha1 or sha256). The request carries "nonce" (hex), or a
+// random one unless "no_nonce" is set; the nonce is returned alongside the
+// hex DER request.
+func sm2OCSPRequest(in map[string]interface{}) (*Result, error) {
+	c, err := requireCertificate(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := requireCertificate(in, "issuer_certificate")
+	if err != nil {
+		return nil, err
+	}
+	h := ocsp.SM3
+	name, ok, err := stringField(in, "hash_algorithm")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if h, ok = ocspHashes[strings.ToLower(name)]; !ok {
+			return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported hash_algorithm %q (supported: sm3, sha1, sha256)", name)}
+		}
+	}
+	nonce, ok, err := hexField(in, "nonce")
+	if err != nil {
+		return nil, err
+	}
+	noNonce, err := boolField(in, "no_nonce")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ok && noNonce:
+		return nil, &codedError{codeInvalidField, errors.New("fields \"nonce\" and \"no_nonce\" are mutually exclusive")}
+	case !ok && !noNonce:
+		nonce = make([]byte, ocspNonceSize)
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			return nil, err
+		}
+	}
+	der, err := ocsp.CreateRequest(c, issuer, h, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der), Nonce: hex.EncodeToString(nonce)}, nil
+}
+
+// sm2OCSPRespond answers the hex DER OCSP "request" for a certificate of
+// "issuer_certificate". The response is signed under "user_id" by
+// "private_key", which belongs to the issuer or, when given, to the
+// delegated "responder_certificate". It reports "status" (good, the
+// default, revoked or unknown) with "revocation_time" and
+// "revocation_reason" for revoked certificates, and "this_update" and the
+// optional "next_update". "error_status" instead writes an unsuccessful
+// response. The output is the hex DER response.
+func sm2OCSPRespond(in map[string]interface{}) (*Result, error) {
+	errStatus, ok, err := stringField(in, "error_status")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for _, s := range ocspErrorStatuses {
+			if strings.EqualFold(s.name, errStatus) {
+				der, err := ocsp.CreateErrorResponse(s.status)
+				if err != nil {
+					return nil, err
+				}
+				return &Result{Output: hex.EncodeToString(der)}, nil
+			}
+		}
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported error_status %q", errStatus)}
+	}
+
+	reqDER, err := requireHex(in, "request")
+	if err != nil {
+		return nil, err
+	}
+	req, err := ocsp.ParseRequest(reqDER)
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := requireCertificate(in, "issuer_certificate")
+	if err != nil {
+		return nil, err
+	}
+	var responder *cert.Certificate
+	if _, ok := in["responder_certificate"]; ok {
+		if responder, err = requireCertificate(in, "responder_certificate"); err != nil {
+			return nil, err
+		}
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tmpl := &ocsp.ResponseTemplate{ProducedAt: now}
+	status, _, err := stringField(in, "status")
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(status) {
+	case "", "good":
+		tmpl.Status = ocsp.Good
+	case "revoked":
+		tmpl.Status = ocsp.Revoked
+	case "unknown":
+		tmpl.Status = ocsp.Unknown
+	default:
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported status %q (supported: good, revoked, unknown)", status)}
+	}
+	if tmpl.Status == ocsp.Revoked {
+		t, ok, err := timeField(in, "revocation_time")
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			t = now
+		}
+		tmpl.RevokedAt = t
+		reason, ok, err := stringField(in, "revocation_reason")
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if tmpl.RevocationReason, err = revocationReason(reason); err != nil {
+				return nil, err
+			}
+		}
+	} else if _, ok := in["revocation_time"]; ok {
+		return nil, errors.New("field \"revocation_time\" needs \"status\": \"revoked\"")
+	}
+	thisUpdate, ok, err := timeField(in, "this_update")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		thisUpdate = now
+	}
+	tmpl.ThisUpdate = thisUpdate
+	if tmpl.NextUpdate, ok, err = timeField(in, "next_update"); err != nil {
+		return nil, err
+	}
+	if ok && !tmpl.NextUpdate.After(thisUpdate) {
+		return nil, errors.New("field \"next_update\" must be later than \"this_update\"")
+	}
+	if tmpl.SignerUID, err = sm2UserID(in); err != nil {
+		return nil, err
+	}
+
+	der, err := ocsp.CreateResponse(rand, req, tmpl, issuer, responder, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der)}, nil
+}
+
+// Reason codes of sm2OCSPVerify for responses that cannot be relied on.
+var ocspReasons = map[error]string{
+	cert.ErrSignature:             "OCSP_BAD_SIGNATURE",
+	ocsp.ErrUnauthorizedResponder: "OCSP_UNAUTHORIZED_RESPONDER",
+	ocsp.ErrExpired:               "OCSP_EXPIRED",
+	ocsp.ErrNotYetValid:           "OCSP_NOT_YET_VALID",
+	ocsp.ErrNoStatus:              "OCSP_NO_STATUS",
+}
+
+// sm2OCSPVerify checks the hex DER OCSP "response" for "certificate". The
+// response must be signed under "user_id" by "issuer_certificate" or by a
+// responder it authorized, be current at "time" (RFC 3339, default now)
+// and, when "nonce" (hex) is given, echo it. A good certificate is valid;
+// a revoked one gives Reason REVOKED with its revocation time and reason,
+// and one the responder does not know UNKNOWN. Responses that cannot be
+// relied on give one of the OCSP_ reasons.
+func sm2OCSPVerify(in map[string]interface{}) (*Result, error) {
+	der, err := requireHex(in, "response")
+	if err != nil {
+		return nil, err
+	}
+	c, err := requireCertificate(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	issuer, err := requireCertificate(in, "issuer_certificate")
+	if err != nil {
+		return nil, err
+	}
+	nonce, hasNonce, err := hexField(in, "nonce")
+	if err != nil {
+		return nil, err
+	}
+	now, ok, err := timeField(in, "time")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		now = time.Now()
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := func(reason string) (*Result, error) {
+		return &Result{Valid: boolPtr(false), Reason: reason}, nil
+	}
+	resp, err := ocsp.ParseResponse(der, c, issuer)
+	var re *ocsp.ResponseError
+	if errors.As(err, &re) {
+		for _, s := range ocspErrorStatuses {
+			if s.status == re.Status {
+				return invalid(s.reason)
+			}
+		}
+	}
+	if err == nil {
+		err = resp.Verify(issuer, now, uid)
+	}
+	if reason, ok := ocspReasons[err]; ok {
+		return invalid(reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if hasNonce && !bytes.Equal(resp.Nonce, nonce) {
+		return invalid("OCSP_NONCE_MISMATCH")
+	}
+
+	switch resp.Status {
+	case ocsp.Revoked:
+		return &Result{
+			Valid:            boolPtr(false),
+			Reason:           "REVOKED",
+			RevocationTime:   resp.RevokedAt.UTC().Format(time.RFC3339),
+			RevocationReason: reasonName(resp.RevocationReason),
+		}, nil
+	case ocsp.Unknown:
+		return invalid("UNKNOWN")
+	}
+	return &Result{Valid: boolPtr(true)}, nil
+}
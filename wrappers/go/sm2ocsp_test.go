@@ -0,0 +1,85 @@
+package main
+
+import "testing"
+
+func TestSM2OCSP(t *testing.T) {
+	ca := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "ocsp ca"},
+		"is_ca":   true,
+	})
+	issue := func(cn string, extra map[string]interface{}) *Result {
+		in := map[string]interface{}{
+			"subject":            map[string]interface{}{"CN": cn},
+			"issuer_certificate": ca.Output,
+			"private_key":        ca.PrivateKey,
+			"public_key":         mustCall(t, "sm2", "keygen", map[string]interface{}{}).PublicKey,
+		}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "cert-issue", in)
+	}
+	leaf := issue("leaf", nil).Output
+	responderKey := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	responder := issue("responder", map[string]interface{}{"public_key": responderKey.PublicKey, "ext_key_usage": []interface{}{"OCSPSigning"}}).Output
+
+	req := mustCall(t, "sm2", "ocsp-request", map[string]interface{}{"certificate": leaf, "issuer_certificate": ca.Output, "hash_algorithm": "sha1"})
+	if len(req.Nonce) != 32 {
+		t.Fatalf("nonce %q", req.Nonce)
+	}
+	respond := func(extra map[string]interface{}) string {
+		in := map[string]interface{}{"request": req.Output, "issuer_certificate": ca.Output, "private_key": ca.PrivateKey, "this_update": "2026-05-01T00:00:00Z", "next_update": "2026-05-02T00:00:00Z"}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "ocsp-respond", in).Output
+	}
+	verify := func(resp string, extra map[string]interface{}) *Result {
+		in := map[string]interface{}{"response": resp, "certificate": leaf, "issuer_certificate": ca.Output, "nonce": req.Nonce, "time": "2026-05-01T12:00:00Z"}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "ocsp-verify", in)
+	}
+
+	if res := verify(respond(nil), nil); !*res.Valid {
+		t.Errorf("good: %+v", res)
+	}
+	delegated := respond(map[string]interface{}{"responder_certificate": responder, "private_key": responderKey.PrivateKey, "status": "revoked", "revocation_time": "2026-04-01T00:00:00Z", "revocation_reason": "cessationOfOperation"})
+	res := verify(delegated, nil)
+	if *res.Valid || res.Reason != "REVOKED" || res.RevocationTime != "2026-04-01T00:00:00Z" || res.RevocationReason != "cessationOfOperation" {
+		t.Errorf("revoked: %+v", res)
+	}
+	for _, tc := range []struct {
+		resp   string
+		extra  map[string]interface{}
+		reason string
+	}{
+		{respond(map[string]interface{}{"status": "unknown"}), nil, "UNKNOWN"},
+		{respond(nil), map[string]interface{}{"time": "2026-05-03T00:00:00Z"}, "OCSP_EXPIRED"},
+		{respond(nil), map[string]interface{}{"time": "2026-04-30T00:00:00Z"}, "OCSP_NOT_YET_VALID"},
+		{respond(nil), map[string]interface{}{"user_id": "other"}, "OCSP_BAD_SIGNATURE"},
+		{respond(nil), map[string]interface{}{"nonce": "00"}, "OCSP_NONCE_MISMATCH"},
+		{respond(nil), map[string]interface{}{"certificate": responder}, "OCSP_NO_STATUS"},
+		{respond(map[string]interface{}{"error_status": "tryLater"}), nil, "OCSP_TRY_LATER"},
+	} {
+		if res := verify(tc.resp, tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%s: %+v", tc.reason, res)
+		}
+	}
+
+	// A responder certificate without OCSPSigning is not authorized.
+	plainKey := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	plain := issue("plain", map[string]interface{}{"public_key": plainKey.PublicKey}).Output
+	if res := verify(respond(map[string]interface{}{"responder_certificate": plain, "private_key": plainKey.PrivateKey}), nil); *res.Valid || res.Reason != "OCSP_UNAUTHORIZED_RESPONDER" {
+		t.Errorf("unauthorized responder: %+v", res)
+	}
+
+	mustFail(t, "sm2", "ocsp-request", map[string]interface{}{"certificate": leaf, "issuer_certificate": ca.Output, "hash_algorithm": "md5"})
+	mustFail(t, "sm2", "ocsp-request", map[string]interface{}{"certificate": leaf, "issuer_certificate": ca.Output, "nonce": "01", "no_nonce": true})
+	mustFail(t, "sm2", "ocsp-respond", map[string]interface{}{"request": req.Output, "issuer_certificate": ca.Output, "private_key": responderKey.PrivateKey})
+	mustFail(t, "sm2", "ocsp-respond", map[string]interface{}{"request": req.Output, "issuer_certificate": ca.Output, "responder_certificate": leaf, "private_key": responderKey.PrivateKey})
+	mustFail(t, "sm2", "ocsp-respond", map[string]interface{}{"request": req.Output, "issuer_certificate": ca.Output, "private_key": ca.PrivateKey, "status": "fine"})
+	mustFail(t, "sm2", "ocsp-respond", map[string]interface{}{"error_status": "busy"})
+	mustFail(t, "sm2", "ocsp-verify", map[string]interface{}{"response": "3000", "certificate": leaf, "issuer_certificate": ca.Output})
+}
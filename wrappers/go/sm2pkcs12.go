@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pkcs12"
+)
+
+// sm2P12Create bundles "private_key", its "certificate" and the optional
+// "ca_certificates" into a PKCS #12 file protected by "password" and
+// returns the hex DER PFX. "key_cipher" selects SM4-CBC with PBKDF2-SM3
+// and an HMAC-SM3 MAC ("sm4", default) or the AES-256-CBC and SHA-256
+// equivalents ("aes"); "friendly_name" labels the key.
+func sm2P12Create(in map[string]interface{}) (*Result, error) {
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	der, ok, err := certificateField(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"certificate\"")}
+	}
+	leaf, err := cert.Parse(der)
+	if err != nil {
+		return nil, err
+	}
+	if leaf.PublicKey == nil || leaf.PublicKey.X.Cmp(priv.X) != 0 || leaf.PublicKey.Y.Cmp(priv.Y) != 0 {
+		return nil, errors.New("certificate does not match private_key")
+	}
+	cas, err := certificateListField(in, "ca_certificates")
+	if err != nil {
+		return nil, err
+	}
+	certs := [][]byte{der}
+	for _, c := range cas {
+		certs = append(certs, c.Raw)
+	}
+	password, err := requireString(in, "password")
+	if err != nil {
+		return nil, err
+	}
+	scheme, err := keyCipher(in)
+	if err != nil {
+		return nil, err
+	}
+	name, _, err := stringField(in, "friendly_name")
+	if err != nil {
+		return nil, err
+	}
+	pfx, err := pkcs12.Encode(rand, priv, certs, password, scheme, name)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(pfx)}, nil
+}
+
+// sm2P12Parse opens the hex DER PKCS #12 "pfx" with "password". The key
+// pair is returned in "key_format" and the certificates in outputs as
+// hex DER, the one matching the key first.
+func sm2P12Parse(in map[string]interface{}) (*Result, error) {
+	pfx, err := requireHex(in, "pfx")
+	if err != nil {
+		return nil, err
+	}
+	password, err := requireString(in, "password")
+	if err != nil {
+		return nil, err
+	}
+	priv, certs, name, err := pkcs12.Decode(pfx, password)
+	if err != nil {
+		return nil, err
+	}
+	res, err := keyPairResult(in, &priv.PublicKey, priv)
+	if err != nil {
+		return nil, err
+	}
+	for i, der := range certs {
+		if _, err := cert.Parse(der); err != nil {
+			return nil, fmt.Errorf("certificate %d: %v", i, err)
+		}
+		res.Outputs = append(res.Outputs, hex.EncodeToString(der))
+	}
+	res.FriendlyName = name
+	return res, nil
+}
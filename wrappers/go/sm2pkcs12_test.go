@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM2P12(t *testing.T) {
+	ca := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "p12 ca"},
+		"is_ca":   true,
+	})
+	leaf := mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+		"subject":            map[string]interface{}{"CN": "p12 leaf"},
+		"public_key":         mustCall(t, "sm2", "derive-pub", map[string]interface{}{"private_key": testP12Key}).PublicKey,
+		"issuer_certificate": ca.Output,
+		"private_key":        ca.PrivateKey,
+	})
+
+	for _, c := range []string{"sm4", "aes"} {
+		pfx := mustCall(t, "sm2", "p12-create", map[string]interface{}{
+			"private_key":     testP12Key,
+			"certificate":     leaf.Output,
+			"ca_certificates": []interface{}{ca.Output},
+			"password":        "secret",
+			"key_cipher":      c,
+			"friendly_name":   "p12 test",
+		})
+		res := mustCall(t, "sm2", "p12-parse", map[string]interface{}{"pfx": pfx.Output, "password": "secret"})
+		if res.PrivateKey != testP12Key || res.FriendlyName != "p12 test" {
+			t.Errorf("%s: key %s, friendly_name %q", c, res.PrivateKey, res.FriendlyName)
+		}
+		if len(res.Outputs) != 2 || res.Outputs[0] != leaf.Output || res.Outputs[1] != ca.Output {
+			t.Errorf("%s: certificates %v", c, res.Outputs)
+		}
+		fail := mustFail(t, "sm2", "p12-parse", map[string]interface{}{"pfx": pfx.Output, "password": "wrong"})
+		if !strings.Contains(fail.Message, "MAC") {
+			t.Errorf("%s: wrong password: %s", c, fail.Message)
+		}
+	}
+
+	res := mustCall(t, "sm2", "p12-parse", map[string]interface{}{
+		"pfx":        mustCall(t, "sm2", "p12-create", map[string]interface{}{"private_key": testSignerKeyPEM, "key_format": "pem", "certificate": testSignerCertPEM, "password": ""}).Output,
+		"password":   "",
+		"key_format": "pem",
+	})
+	if res.PrivateKey != testSignerKeyPEM || len(res.Outputs) != 1 || res.FriendlyName != "" {
+		t.Errorf("PEM key: %+v", res)
+	}
+
+	mustFail(t, "sm2", "p12-create", map[string]interface{}{"private_key": testP12Key, "certificate": ca.Output, "password": "x"})
+	mustFail(t, "sm2", "p12-create", map[string]interface{}{"private_key": testP12Key, "certificate": leaf.Output})
+	mustFail(t, "sm2", "p12-create", map[string]interface{}{"private_key": testP12Key, "certificate": leaf.Output, "password": "x", "key_cipher": "des"})
+	mustFail(t, "sm2", "p12-parse", map[string]interface{}{"pfx": "3000", "password": "x"})
+}
+
+const testP12Key = "3945208f7b2144b13f36e38ac6d39f95889393692860b51a42fb81ef4df7c5b8"
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/pkcs7"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// certificateField reads a DER X.509 certificate from field name, given as
+// PEM text or hex.
+func certificateField(in map[string]interface{}, name string) ([]byte, bool, error) {
+	return derField(in, name, "CERTIFICATE")
+}
+
+// derField reads DER from field name, given as hex or as PEM text with a
+// block of pemType.
+func derField(in map[string]interface{}, name, pemType string) ([]byte, bool, error) {
+	s, ok, err := stringField(in, name)
+	if err != nil || !ok {
+		return nil, ok, err
+	}
+	der, err := decodeDER(s, name, pemType)
+	return der, err == nil, err
+}
+
+// decodeDER decodes s, the value of field name, as hex or as PEM text with
+// a block of pemType.
+func decodeDER(s, name, pemType string) ([]byte, error) {
+	if strings.HasPrefix(strings.TrimSpace(s), "-----BEGIN") {
+		block, _ := pem.Decode([]byte(s))
+		if block == nil || block.Type != pemType {
+			return nil, fmt.Errorf("field %q does not contain a %s PEM block", name, pemType)
+		}
+		return block.Bytes, nil
+	}
+	der, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, fmt.Errorf("field %q is neither PEM nor valid hex: %v", name, err)}
+	}
+	return der, nil
+}
+
+// sm2P7Sign signs "message" (or "message_hex" / "message_base64") as GM/T
+// 0010 SignedData with "private_key" and returns the hex DER ContentInfo.
+// "certificate" embeds the signer's certificate, "detached" leaves the
+// content out, and "signed_attributes": false signs the content directly.
+func sm2P7Sign(in map[string]interface{}) (*Result, error) {
+	content, err := requireBytes(in, "message")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	cert, _, err := certificateField(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	var opts pkcs7.SignOptions
+	if opts.Detached, err = boolField(in, "detached"); err != nil {
+		return nil, err
+	}
+	if _, ok := in["signed_attributes"]; ok {
+		with, err := boolField(in, "signed_attributes")
+		if err != nil {
+			return nil, err
+		}
+		opts.NoAttributes = !with
+	}
+	der, err := pkcs7.Sign(rand, content, pkcs7.Signer{Key: priv, UID: uid, Certificate: cert}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der)}, nil
+}
+
+// sm2P7Verify checks the hex DER SignedData in "signed_data". The signer's
+// key comes from "public_key", from "certificate", or from the certificate
+// embedded in the SignedData. Detached signatures take the content as
+// "message". A signature that does not match is a successful call with
+// Valid false; otherwise Output holds the signed content per
+// "plaintext_encoding".
+func sm2P7Verify(in map[string]interface{}) (*Result, error) {
+	der, err := requireHex(in, "signed_data")
+	if err != nil {
+		return nil, err
+	}
+	content, _, err := bytesField(in, "message")
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+	var pub *sm2.PublicKey
+	if _, ok := in["public_key"]; ok {
+		if pub, err = sm2PublicKey(in); err != nil {
+			return nil, err
+		}
+	}
+	cert, ok, err := certificateField(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		if pub != nil {
+			return nil, &codedError{codeInvalidField, errors.New("fields \"public_key\" and \"certificate\" are mutually exclusive")}
+		}
+		if pub, err = pkcs7.CertificatePublicKey(cert); err != nil {
+			return nil, err
+		}
+	}
+	signed, err := pkcs7.Verify(der, content, pub, uid)
+	if errors.Is(err, pkcs7.ErrSignature) {
+		return &Result{Valid: boolPtr(false)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Valid: boolPtr(true)}
+	if content == nil {
+		if res.Output, err = encodePlaintext(in, signed); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
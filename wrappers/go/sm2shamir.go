@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// sm2KeySplit splits "private_key" into "share_count" Shamir shares over
+// the curve order, any "threshold" of which recover it. Each share is hex:
+// a one-byte index and a 32-byte value. The fingerprint of the public key
+// lets holders check a recombined key.
+func sm2KeySplit(in map[string]interface{}) (*Result, error) {
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+	count, ok, err := intField(in, "share_count")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"share_count\"")}
+	}
+	threshold, ok, err := intField(in, "threshold")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"threshold\"")}
+	}
+	shares, err := sm2.SplitKey(rand, priv.D, count, threshold)
+	if err != nil {
+		return nil, err
+	}
+	fp := sm3.Sum(priv.PublicKey.Bytes())
+	res := &Result{Fingerprint: hex.EncodeToString(fp[:])}
+	for _, s := range shares {
+		res.Outputs = append(res.Outputs, hex.EncodeToString(s.Bytes()))
+	}
+	return res, nil
+}
+
+// sm2KeyCombine recovers a private key from the hex "shares" and returns
+// the key pair in "key_format". Too few shares give a different key, which
+// shows in the fingerprint.
+func sm2KeyCombine(in map[string]interface{}) (*Result, error) {
+	list, ok := in["shares"].([]interface{})
+	if !ok {
+		return nil, &codedError{codeInvalidField, errors.New("field \"shares\" must be an array of hex strings")}
+	}
+	shares := make([]sm2.Share, len(list))
+	for i, v := range list {
+		s, ok := v.(string)
+		if !ok {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("shares[%d] must be a string", i)}
+		}
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, &codedError{codeInvalidEncoding, fmt.Errorf("shares[%d] is not valid hex: %v", i, err)}
+		}
+		if shares[i], err = sm2.ParseShare(b); err != nil {
+			return nil, &codedError{codeInvalidEncoding, fmt.Errorf("shares[%d]: %v", i, err)}
+		}
+	}
+	d, err := sm2.CombineKey(shares)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := sm2.NewPrivateKey(d.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return keyPairResult(in, &priv.PublicKey, priv)
+}
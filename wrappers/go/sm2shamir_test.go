@@ -0,0 +1,24 @@
+package main
+
+import "testing"
+
+func TestSM2KeySplitCombine(t *testing.T) {
+	k := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+	split := mustCall(t, "sm2", "key-split", map[string]interface{}{"private_key": k.PrivateKey, "share_count": 5, "threshold": 3})
+	if len(split.Outputs) != 5 || split.Fingerprint != k.Fingerprint {
+		t.Fatalf("split = %+v", split)
+	}
+	s := split.Outputs
+	res := mustCall(t, "sm2", "key-combine", map[string]interface{}{"shares": []interface{}{s[4], s[0], s[2]}})
+	if res.PrivateKey != k.PrivateKey || res.PublicKey != k.PublicKey {
+		t.Fatalf("combined = %+v", res)
+	}
+	if res := mustCall(t, "sm2", "key-combine", map[string]interface{}{"shares": []interface{}{s[1], s[3]}}); res.Fingerprint == k.Fingerprint {
+		t.Fatal("two shares recovered a 3-of-5 key")
+	}
+
+	mustFail(t, "sm2", "key-split", map[string]interface{}{"private_key": k.PrivateKey, "share_count": 2, "threshold": 3})
+	mustFail(t, "sm2", "key-split", map[string]interface{}{"private_key": k.PrivateKey, "threshold": 2})
+	mustFail(t, "sm2", "key-combine", map[string]interface{}{"shares": []interface{}{s[0], s[0]}})
+	mustFail(t, "sm2", "key-combine", map[string]interface{}{"shares": []interface{}{s[0], "00" + s[1][2:]}})
+}
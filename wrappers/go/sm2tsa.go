@@ -0,0 +1,311 @@
+package main
+
+import (
+	"bytes"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tsp"
+)
+
+// tsaHashes maps the names accepted in "hash_algorithm".
+var tsaHashes = map[string]tsp.HashAlgorithm{
+	"sm3":    tsp.SM3,
+	"sha256": tsp.SHA256,
+	"sha1":   tsp.SHA1,
+}
+
+// tsaFailures maps the PKIFailureInfo bits of RFC 3161 to the names
+// accepted in "error_status" and the reasons of tsa-verify.
+var tsaFailures = []struct {
+	info   tsp.FailureInfo
+	name   string
+	reason string
+}{
+	{tsp.BadAlg, "badAlg", "TSA_BAD_ALG"},
+	{tsp.BadRequest, "badRequest", "TSA_BAD_REQUEST"},
+	{tsp.BadDataFormat, "badDataFormat", "TSA_BAD_DATA_FORMAT"},
+	{tsp.TimeNotAvailable, "timeNotAvailable", "TSA_TIME_NOT_AVAILABLE"},
+	{tsp.UnacceptedPolicy, "unacceptedPolicy", "TSA_UNACCEPTED_POLICY"},
+	{tsp.UnacceptedExtension, "unacceptedExtension", "TSA_UNACCEPTED_EXTENSION"},
+	{tsp.AddInfoNotAvailable, "addInfoNotAvailable", "TSA_ADD_INFO_NOT_AVAILABLE"},
+	{tsp.SystemFailure, "systemFailure", "TSA_SYSTEM_FAILURE"},
+}
+
+// tsaNonceSize is the length of nonces generated by tsa-request, 64 bits
+// as OpenSSL writes them.
+const tsaNonceSize = 8
+
+// tsaHashField reads "hash_algorithm" (sm3, the default, sha256 or sha1).
+func tsaHashField(in map[string]interface{}) (tsp.HashAlgorithm, error) {
+	name, ok, err := stringField(in, "hash_algorithm")
+	if err != nil || !ok {
+		return tsp.SM3, err
+	}
+	h, ok := tsaHashes[strings.ToLower(name)]
+	if !ok {
+		return 0, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported hash_algorithm %q (supported: sm3, sha256, sha1)", name)}
+	}
+	return h, nil
+}
+
+// policyField reads a dotted object identifier.
+func policyField(in map[string]interface{}, name string) (asn1.ObjectIdentifier, error) {
+	s, ok, err := stringField(in, name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	oid, err := parseOID(s)
+	if err != nil {
+		return nil, fmt.Errorf("field %q: %v", name, err)
+	}
+	return oid, nil
+}
+
+// sm2TSARequest builds an RFC 3161 request for the imprint of "message"
+// (or "message_hex" / "message_base64") under "hash_algorithm", or for
+// the hex "digest" already computed with it. It names the optional
+// "policy" and asks for the TSA certificate when "cert_req" is set. The
+// request carries "nonce" (hex), or a random one unless "no_nonce" is
+// set; the nonce is returned alongside the hex DER request.
+func sm2TSARequest(in map[string]interface{}) (*Result, error) {
+	h, err := tsaHashField(in)
+	if err != nil {
+		return nil, err
+	}
+	req := &tsp.Request{HashAlgorithm: h}
+	if req.HashedMessage, err = imprintField(in, h); err != nil {
+		return nil, err
+	}
+	if req.Policy, err = policyField(in, "policy"); err != nil {
+		return nil, err
+	}
+	if req.CertReq, err = boolField(in, "cert_req"); err != nil {
+		return nil, err
+	}
+	nonce, ok, err := hexField(in, "nonce")
+	if err != nil {
+		return nil, err
+	}
+	noNonce, err := boolField(in, "no_nonce")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case ok && noNonce:
+		return nil, &codedError{codeInvalidField, errors.New("fields \"nonce\" and \"no_nonce\" are mutually exclusive")}
+	case !ok && !noNonce:
+		nonce = make([]byte, tsaNonceSize)
+		if _, err := io.ReadFull(rand, nonce); err != nil {
+			return nil, err
+		}
+	}
+	if nonce != nil {
+		req.Nonce = new(big.Int).SetBytes(nonce)
+	}
+	der, err := tsp.CreateRequest(req)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der), Nonce: hex.EncodeToString(nonce)}, nil
+}
+
+// imprintField returns the hash under h of "message" (or "message_hex" /
+// "message_base64"), or the hex "digest" as given.
+func imprintField(in map[string]interface{}, h tsp.HashAlgorithm) ([]byte, error) {
+	msg, hasMsg, err := bytesField(in, "message")
+	if err != nil {
+		return nil, err
+	}
+	digest, hasDigest, err := hexField(in, "digest")
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case hasMsg && hasDigest:
+		return nil, &codedError{codeInvalidField, errors.New("fields \"message\" and \"digest\" are mutually exclusive")}
+	case hasMsg:
+		return h.Sum(msg), nil
+	case !hasDigest:
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"message\" (or \"digest\")")}
+	}
+	if len(digest) != len(h.Sum(nil)) {
+		return nil, fmt.Errorf("digest must be %d bytes, got %d", len(h.Sum(nil)), len(digest))
+	}
+	return digest, nil
+}
+
+// sm2TSASign answers the hex DER time-stamp "request" with a token signed
+// under "user_id" by "private_key", the key of the TSA "certificate". The
+// token is issued under "policy", which defaults to the one the request
+// names, with the hex "serial" (default random), the genTime "time" (RFC
+// 3339, default now) and the optional "accuracy_ms". "error_status"
+// instead writes a rejection. The output is the hex DER response.
+func sm2TSASign(in map[string]interface{}) (*Result, error) {
+	failure, ok, err := stringField(in, "error_status")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		for _, f := range tsaFailures {
+			if strings.EqualFold(f.name, failure) {
+				der, err := tsp.CreateErrorResponse(f.info, "")
+				if err != nil {
+					return nil, err
+				}
+				return &Result{Output: hex.EncodeToString(der)}, nil
+			}
+		}
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported error_status %q", failure)}
+	}
+
+	reqDER, err := requireHex(in, "request")
+	if err != nil {
+		return nil, err
+	}
+	req, err := tsp.ParseRequest(reqDER)
+	if err != nil {
+		return nil, err
+	}
+	tsa, err := requireCertificate(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	priv, err := requirePrivateKey(in)
+	if err != nil {
+		return nil, err
+	}
+
+	tmpl := &tsp.TokenTemplate{}
+	if tmpl.Policy, err = policyField(in, "policy"); err != nil {
+		return nil, err
+	}
+	if tmpl.Policy == nil {
+		if req.Policy == nil {
+			return nil, &codedError{codeMissingField, errors.New("missing required field \"policy\" (the request names none)")}
+		}
+		tmpl.Policy = req.Policy
+	}
+	serial, ok, err := hexField(in, "serial")
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		tmpl.SerialNumber = new(big.Int).SetBytes(serial)
+	}
+	if tmpl.GenTime, ok, err = timeField(in, "time"); err != nil {
+		return nil, err
+	}
+	if !ok {
+		tmpl.GenTime = time.Now()
+	}
+	ms, _, err := intField(in, "accuracy_ms")
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Accuracy = time.Duration(ms) * time.Millisecond
+	if tmpl.SignerUID, err = sm2UserID(in); err != nil {
+		return nil, err
+	}
+
+	der, err := tsp.CreateResponse(rand, req, tmpl, tsa, priv)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(der)}, nil
+}
+
+// Reason codes of sm2TSAVerify for tokens that cannot be relied on.
+var tsaReasons = map[error]string{
+	cert.ErrSignature:         "TSA_BAD_SIGNATURE",
+	tsp.ErrMessageDigest:      "TSA_BAD_SIGNATURE",
+	tsp.ErrWrongSigner:        "TSA_WRONG_SIGNER",
+	tsp.ErrSigningCertificate: "TSA_WRONG_SIGNER",
+	tsp.ErrExtKeyUsage:        "TSA_EXT_KEY_USAGE",
+}
+
+// sm2TSAVerify checks the hex DER time-stamp "response", or a bare
+// "token", against "message" (or "message_hex" / "message_base64", or
+// the hex "digest"). The token must be signed under "user_id" by the TSA
+// "certificate", stamp the message's imprint and, when "nonce" (hex) is
+// given, echo it. A valid token gives its genTime and serial; otherwise
+// the reason is one of the TSA_ codes, or for a rejected request the
+// failure it names.
+func sm2TSAVerify(in map[string]interface{}) (*Result, error) {
+	respDER, hasResp, err := hexField(in, "response")
+	if err != nil {
+		return nil, err
+	}
+	tokenDER, hasToken, err := hexField(in, "token")
+	if err != nil {
+		return nil, err
+	}
+	if hasResp == hasToken {
+		return nil, errors.New("exactly one of the fields \"response\" and \"token\" is required")
+	}
+	tsa, err := requireCertificate(in, "certificate")
+	if err != nil {
+		return nil, err
+	}
+	nonce, hasNonce, err := hexField(in, "nonce")
+	if err != nil {
+		return nil, err
+	}
+	uid, err := sm2UserID(in)
+	if err != nil {
+		return nil, err
+	}
+
+	invalid := func(reason string) (*Result, error) {
+		return &Result{Valid: boolPtr(false), Reason: reason}, nil
+	}
+	var tok *tsp.Token
+	if hasResp {
+		tok, err = tsp.ParseResponse(respDER)
+	} else {
+		tok, err = tsp.ParseToken(tokenDER)
+	}
+	var re *tsp.ResponseError
+	if errors.As(err, &re) {
+		for _, f := range tsaFailures {
+			if len(re.FailureInfo) > 0 && re.FailureInfo[0] == f.info {
+				return invalid(f.reason)
+			}
+		}
+		return invalid("TSA_REJECTED")
+	}
+	if err != nil {
+		return nil, err
+	}
+	// The imprint is read after the token so that it is hashed with the
+	// token's algorithm.
+	imprint, err := imprintField(in, tok.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	err = tok.Verify(tsa, uid)
+	if reason, ok := tsaReasons[err]; ok {
+		return invalid(reason)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(tok.HashedMessage, imprint) {
+		return invalid("TSA_IMPRINT_MISMATCH")
+	}
+	if hasNonce && (tok.Nonce == nil || tok.Nonce.Cmp(new(big.Int).SetBytes(nonce)) != 0) {
+		return invalid("TSA_NONCE_MISMATCH")
+	}
+	return &Result{
+		Valid:   boolPtr(true),
+		GenTime: tok.GenTime.UTC().Format(time.RFC3339Nano),
+		Serial:  hex.EncodeToString(tok.SerialNumber.Bytes()),
+	}, nil
+}
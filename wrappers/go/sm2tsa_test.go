@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestSM2TSA(t *testing.T) {
+	ca := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "tsa ca"},
+		"is_ca":   true,
+	})
+	issue := func(cn string, extKeyUsage []interface{}) *Result {
+		key := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+		key.Output = mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+			"subject":            map[string]interface{}{"CN": cn},
+			"public_key":         key.PublicKey,
+			"ext_key_usage":      extKeyUsage,
+			"issuer_certificate": ca.Output,
+			"private_key":        ca.PrivateKey,
+		}).Output
+		return key
+	}
+	tsa := issue("tsa", []interface{}{"timeStamping"})
+	other := issue("other tsa", []interface{}{"timeStamping"})
+	plain := issue("plain", []interface{}{"timeStamping", "serverAuth"})
+
+	req := mustCall(t, "sm2", "tsa-request", map[string]interface{}{"message": "contract", "cert_req": true})
+	if len(req.Nonce) != 16 {
+		t.Fatalf("nonce %q", req.Nonce)
+	}
+	sign := func(extra map[string]interface{}) string {
+		in := map[string]interface{}{"request": req.Output, "certificate": tsa.Output, "private_key": tsa.PrivateKey, "policy": "1.2.3.4.1", "serial": "2a", "time": "2026-05-01T12:00:00Z"}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "tsa-sign", in).Output
+	}
+	verify := func(extra map[string]interface{}) *Result {
+		in := map[string]interface{}{"response": sign(nil), "certificate": tsa.Output, "message": "contract", "nonce": req.Nonce}
+		for k, v := range extra {
+			in[k] = v
+		}
+		return mustCall(t, "sm2", "tsa-verify", in)
+	}
+
+	res := verify(nil)
+	if !*res.Valid || res.GenTime != "2026-05-01T12:00:00Z" || res.Serial != "2a" {
+		t.Errorf("valid: %+v", res)
+	}
+	// A digest computed elsewhere stands in for the message.
+	digest := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "contract"}).Output
+	if res := verify(map[string]interface{}{"message": nil, "digest": digest}); !*res.Valid {
+		t.Errorf("digest: %+v", res)
+	}
+	for _, tc := range []struct {
+		extra  map[string]interface{}
+		reason string
+	}{
+		{map[string]interface{}{"message": "forged"}, "TSA_IMPRINT_MISMATCH"},
+		{map[string]interface{}{"nonce": "01"}, "TSA_NONCE_MISMATCH"},
+		{map[string]interface{}{"user_id": "other"}, "TSA_BAD_SIGNATURE"},
+		{map[string]interface{}{"certificate": other.Output}, "TSA_WRONG_SIGNER"},
+		{map[string]interface{}{"response": sign(map[string]interface{}{"error_status": "timeNotAvailable"})}, "TSA_TIME_NOT_AVAILABLE"},
+		{map[string]interface{}{"response": sign(map[string]interface{}{"policy": "1.2.3.4.2", "request": mustCall(t, "sm2", "tsa-request", map[string]interface{}{"message": "contract", "policy": "1.2.3.4.9", "no_nonce": true}).Output})}, "TSA_UNACCEPTED_POLICY"},
+	} {
+		if res := verify(tc.extra); *res.Valid || res.Reason != tc.reason {
+			t.Errorf("%s: %+v", tc.reason, res)
+		}
+	}
+
+	mustFail(t, "sm2", "tsa-request", map[string]interface{}{"message": "contract", "hash_algorithm": "md5"})
+	mustFail(t, "sm2", "tsa-request", map[string]interface{}{"digest": "00"})
+	mustFail(t, "sm2", "tsa-request", map[string]interface{}{"message": "contract", "nonce": "01", "no_nonce": true})
+	mustFail(t, "sm2", "tsa-sign", map[string]interface{}{"request": req.Output, "certificate": tsa.Output, "private_key": tsa.PrivateKey})
+	mustFail(t, "sm2", "tsa-sign", map[string]interface{}{"request": req.Output, "certificate": plain.Output, "private_key": plain.PrivateKey, "policy": "1.2.3"})
+	mustFail(t, "sm2", "tsa-sign", map[string]interface{}{"request": req.Output, "certificate": other.Output, "private_key": tsa.PrivateKey, "policy": "1.2.3"})
+	mustFail(t, "sm2", "tsa-sign", map[string]interface{}{"error_status": "busy"})
+	mustFail(t, "sm2", "tsa-verify", map[string]interface{}{"certificate": tsa.Output, "message": "contract"})
+}
@@ -0,0 +1,289 @@
+package main
+
+import (
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/subtle"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// sm3HashRequest selects the input of sm3Hash and the digest, if any,
+// to compare the result with.
+type sm3HashRequest struct {
+	Data         *encodedBytes `json:"data"`
+	DataEncoding string        `json:"data_encoding"`
+	DataList     []string      `json:"data_list"`
+	PerItem      bool          `json:"per_item"`
+	InputFile    *string       `json:"input_file"`
+	Stdin        bool          `json:"stdin"`
+	Expected     hexBytes      `json:"expected"`
+}
+
+// sm3Hash hashes "data" (UTF-8 text, or hex or base64 as selected by
+// "data_encoding"), the contents of "input_file", or standard input when
+// "stdin" is true.
+func sm3Hash(in map[string]interface{}) (*Result, error) {
+	var req sm3HashRequest
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if req.DataList != nil {
+		return sm3HashList(&req)
+	}
+	h := newSM3()
+	if err := writeHashInput(h, &req); err != nil {
+		return nil, err
+	}
+	return digestResult(h.Sum(nil), req.Expected), nil
+}
+
+// sm3HashList hashes the strings of "data_list", decoded per
+// "data_encoding". It returns one digest over their concatenation, or with
+// "per_item" one digest per item in "outputs".
+func sm3HashList(req *sm3HashRequest) (*Result, error) {
+	for i, set := range []bool{req.Data != nil, req.InputFile != nil, req.Stdin} {
+		if set {
+			other := []string{"data", "input_file", "stdin"}[i]
+			return nil, &codedError{codeInvalidField, fmt.Errorf("fields \"data_list\" and %q are mutually exclusive", other)}
+		}
+	}
+	enc, err := textEncoding("data_encoding", req.DataEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	h := newSM3()
+	var outputs []string
+	for i, s := range req.DataList {
+		data, err := decodeText(s, enc)
+		if err != nil {
+			return nil, &codedError{codeInvalidEncoding, fmt.Errorf("data_list[%d] is not valid %s: %v", i, enc, err)}
+		}
+		if req.PerItem {
+			h.Reset()
+		}
+		h.Write(data)
+		if req.PerItem {
+			outputs = append(outputs, hex.EncodeToString(h.Sum(nil)))
+		}
+	}
+	if !req.PerItem {
+		return digestResult(h.Sum(nil), req.Expected), nil
+	}
+	if req.Expected != nil {
+		return nil, &codedError{codeInvalidField, errors.New("\"expected\" cannot be combined with \"per_item\"")}
+	}
+	return &Result{Outputs: outputs}, nil
+}
+
+// digestResult returns sum, or when an expected digest is given, the
+// outcome of a constant-time comparison against it.
+func digestResult(sum, expected []byte) *Result {
+	if expected == nil {
+		return &Result{Output: hex.EncodeToString(sum)}
+	}
+	valid := subtle.ConstantTimeCompare(sum, expected) == 1
+	return &Result{Valid: boolPtr(valid)}
+}
+
+// writeHashInput feeds the single input source selected by req to w.
+// Files and stdin are copied in fileChunkSize pieces rather than read
+// whole.
+func writeHashInput(w io.Writer, req *sm3HashRequest) error {
+	sources := 0
+	for _, set := range []bool{req.Data != nil, req.InputFile != nil, req.Stdin} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return &codedError{codeInvalidField, errors.New("only one of \"data\", \"input_file\" and \"stdin\" may be given")}
+	}
+
+	var r io.Reader
+	switch {
+	case req.InputFile != nil:
+		f, err := os.Open(*req.InputFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	case req.Stdin:
+		r = stdin
+	case req.Data != nil:
+		_, err := w.Write(*req.Data)
+		return err
+	default:
+		return &codedError{codeMissingField, errors.New("missing required field \"data\"")}
+	}
+	_, err := io.CopyBuffer(w, r, make([]byte, fileChunkSize))
+	return err
+}
+
+// maxDerivedKeySize bounds the output of the key derivation operations.
+const maxDerivedKeySize = 1 << 20
+
+// sm3KDF runs the GB/T 32918 key derivation function (the one inside SM2
+// encryption and key exchange) over the hex "shared_secret" and returns
+// "key_length" bytes.
+func sm3KDF(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		SharedSecret hexBytes `json:"shared_secret" validate:"required"`
+		KeyLength    int      `json:"key_length" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if err := checkKeyLength("key_length", req.KeyLength); err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(sm2.KDF(req.SharedSecret, req.KeyLength))}, nil
+}
+
+// keyLengthField reads a required output length in bytes.
+func keyLengthField(in map[string]interface{}, name string) (int, error) {
+	n, ok, err := intField(in, name)
+	if err != nil {
+		return 0, err
+	}
+	if !ok {
+		return 0, &codedError{codeMissingField, fmt.Errorf("missing required field %q", name)}
+	}
+	return n, checkKeyLength(name, n)
+}
+
+// checkKeyLength bounds the output length n, read from the field name.
+func checkKeyLength(name string, n int) error {
+	if n < 1 || n > maxDerivedKeySize {
+		return &codedError{codeInvalidField, fmt.Errorf("%s must be between 1 and %d bytes, got %d", name, maxDerivedKeySize, n)}
+	}
+	return nil
+}
+
+// maxPBKDF2Iterations bounds "iterations" so a request cannot run for hours.
+const maxPBKDF2Iterations = 10_000_000
+
+// sm3PBKDF2 derives "key_length" bytes from the UTF-8 "password" and hex
+// "salt" with PBKDF2 (RFC 8018) using HMAC-SM3 as the PRF.
+func sm3PBKDF2(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Password   string   `json:"password" validate:"required"`
+		Salt       hexBytes `json:"salt" validate:"required"`
+		Iterations int      `json:"iterations" validate:"required"`
+		KeyLength  int      `json:"key_length" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if req.Iterations < 1 || req.Iterations > maxPBKDF2Iterations {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("iterations must be between 1 and %d, got %d", maxPBKDF2Iterations, req.Iterations)}
+	}
+	if err := checkKeyLength("key_length", req.KeyLength); err != nil {
+		return nil, err
+	}
+	key, err := pbkdf2.Key(newSM3, req.Password, req.Salt, req.Iterations, req.KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(key)}, nil
+}
+
+// sm3HKDF runs HKDF (RFC 5869) with SM3. "stage" selects "extract" (PRK
+// from the hex "key" and optional "salt"), "expand" ("key_length" bytes
+// from a PRK in "key" and optional hex "info"), or both (the default).
+func sm3HKDF(in map[string]interface{}) (*Result, error) {
+	// "key_length" is checked by stage, as only extraction does without it.
+	var req struct {
+		Key       hexBytes `json:"key" validate:"required"`
+		Salt      hexBytes `json:"salt"`
+		Info      hexBytes `json:"info"`
+		Stage     string   `json:"stage"`
+		KeyLength *int     `json:"key_length"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	var out []byte
+	var err error
+	switch stage := strings.ToLower(req.Stage); stage {
+	case "extract":
+		out, err = hkdf.Extract(newSM3, req.Key, req.Salt)
+	case "expand", "":
+		if req.KeyLength == nil {
+			return nil, &codedError{codeMissingField, errors.New("missing required field \"key_length\"")}
+		}
+		n := *req.KeyLength
+		if err := checkKeyLength("key_length", n); err != nil {
+			return nil, err
+		}
+		if stage == "expand" {
+			out, err = hkdf.Expand(newSM3, req.Key, string(req.Info), n)
+		} else {
+			out, err = hkdf.Key(newSM3, req.Key, req.Salt, string(req.Info), n)
+		}
+	default:
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported HKDF stage %q (supported: extract, expand)", req.Stage)}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(out)}, nil
+}
+
+// sm3MGF1 returns "length" bytes of the MGF1 mask (PKCS #1 v2.2, B.2.1)
+// of the hex "seed" with SM3 as the hash.
+func sm3MGF1(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Seed   hexBytes `json:"seed" validate:"required"`
+		Length int      `json:"length" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if err := checkKeyLength("length", req.Length); err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(mgf1(req.Seed, req.Length))}, nil
+}
+
+// mgf1 differs from the GB/T 32918 KDF only in starting its counter at 0.
+func mgf1(seed []byte, n int) []byte {
+	out := make([]byte, 0, n+sm3.Size)
+	var ct [4]byte
+	for i := uint32(0); len(out) < n; i++ {
+		binary.BigEndian.PutUint32(ct[:], i)
+		h := newSM3()
+		h.Write(seed)
+		h.Write(ct[:])
+		out = h.Sum(out)
+	}
+	return out[:n]
+}
+
+// sm3HMAC computes HMAC-SM3 under the hex "key" over "data" (UTF-8 text,
+// or hex or base64 as selected by "data_encoding"), or verifies a hex
+// "mac" (optionally truncated) like sm4CMAC.
+func sm3HMAC(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Key  hexBytes     `json:"key" validate:"required"`
+		Data encodedBytes `json:"data" validate:"required"`
+		MAC  hexBytes     `json:"mac"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	mac := hmac.New(newSM3, req.Key)
+	mac.Write(req.Data)
+	return macResult(req.MAC, mac.Sum(nil))
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+func TestSM3HMAC(t *testing.T) {
+	// HMAC per RFC 2104 with SM3's 64-byte block, built from sm3.Sum. The
+	// 80-byte key is longer than a block and is hashed first.
+	for _, key := range [][]byte{[]byte("key"), bytes.Repeat([]byte{0xaa}, 80)} {
+		k := key
+		if len(k) > sm3.BlockSize {
+			sum := sm3.Sum(k)
+			k = sum[:]
+		}
+		ipad := make([]byte, sm3.BlockSize)
+		opad := make([]byte, sm3.BlockSize)
+		copy(ipad, k)
+		copy(opad, k)
+		for i := range ipad {
+			ipad[i] ^= 0x36
+			opad[i] ^= 0x5c
+		}
+		inner := sm3.Sum(append(ipad, "message"...))
+		outer := sm3.Sum(append(opad, inner[:]...))
+		want := hex.EncodeToString(outer[:])
+
+		in := map[string]interface{}{"key": hex.EncodeToString(key), "data": "message"}
+		if res := mustCall(t, "sm3", "hmac", in); res.Output != want {
+			t.Fatalf("hmac = %s, want %s", res.Output, want)
+		}
+		for mac, valid := range map[string]bool{want: true, want[:32]: true, strings.Repeat("00", 32): false} {
+			in["mac"] = mac
+			if res := mustCall(t, "sm3", "hmac", in); res.Valid == nil || *res.Valid != valid {
+				t.Errorf("verify %s = %v, want %v", mac, res.Valid, valid)
+			}
+		}
+	}
+	mustFail(t, "sm3", "hmac", map[string]interface{}{"data": "message"})
+
+	// Binary data: the hex and base64 forms of "message" give its MAC.
+	text := mustCall(t, "sm3", "hmac", map[string]interface{}{"key": "00", "data": "message"})
+	for enc, data := range map[string]string{"hex": hex.EncodeToString([]byte("message")), "base64": "bWVzc2FnZQ=="} {
+		res := mustCall(t, "sm3", "hmac", map[string]interface{}{"key": "00", "data": data, "data_encoding": enc})
+		if res.Output != text.Output {
+			t.Errorf("hmac with data_encoding %s = %s, want %s", enc, res.Output, text.Output)
+		}
+	}
+}
+
+func TestSM3HashSources(t *testing.T) {
+	// GB/T 32905 example 2: "abcd" repeated 16 times.
+	const want = "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"
+	data := strings.Repeat("abcd", 16)
+
+	path := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if res := mustCall(t, "sm3", "hash", map[string]interface{}{"input_file": path}); res.Output != want {
+		t.Fatalf("input_file hash = %s", res.Output)
+	}
+
+	saved := stdin
+	defer func() { stdin = saved }()
+	stdin = strings.NewReader(data)
+	if res := mustCall(t, "sm3", "hash", map[string]interface{}{"stdin": true}); res.Output != want {
+		t.Fatalf("stdin hash = %s", res.Output)
+	}
+
+	// A file spanning several chunks hashes like the same bytes in memory.
+	big := bytes.Repeat([]byte("0123456789"), fileChunkSize/5+3)
+	if err := os.WriteFile(path, big, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sum := sm3.Sum(big)
+	if res := mustCall(t, "sm3", "hash", map[string]interface{}{"input_file": path}); res.Output != hex.EncodeToString(sum[:]) {
+		t.Fatal("multi-chunk file hash differs from sm3.Sum")
+	}
+
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "x", "input_file": path})
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "x", "stdin": true})
+	mustFail(t, "sm3", "hash", map[string]interface{}{"input_file": filepath.Join(t.TempDir(), "missing")})
+	mustFail(t, "sm3", "hash", map[string]interface{}{"stdin": "yes"})
+}
+
+func TestSM3DataEncoding(t *testing.T) {
+	// GB/T 32905 example 1, "abc", in each encoding.
+	const want = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	for enc, data := range map[string]string{"utf8": "abc", "hex": "616263", "HEX": "616263", "base64": "YWJj"} {
+		res := mustCall(t, "sm3", "hash", map[string]interface{}{"data": data, "data_encoding": enc})
+		if res.Output != want {
+			t.Errorf("%s: hash = %s", enc, res.Output)
+		}
+	}
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "6162z3", "data_encoding": "hex"})
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "abc", "data_encoding": "latin1"})
+}
+
+func TestSM3KDF(t *testing.T) {
+	// In the GM/T 0003.5 encryption example C2 is the plaintext XORed with
+	// KDF(x2 || y2), where (x2, y2) = k·PB.
+	pub, _ := hex.DecodeString("09F9DF311E5421A150DD7D161E4BC5C672179FAD1833FC076BB08FF356F35020" +
+		"CCEA490CE26775A52DC6EA718CC1AA600AED05FBF35E084A6632F6072DA9AD13")
+	k, _ := hex.DecodeString("59276E27D506861A16680F3AD9C02DCCEF3CC1FA3CDBE4CE6D54B80DEAC1BC21")
+	x2, y2 := sm2.ScalarMult(new(big.Int).SetBytes(pub[:32]), new(big.Int).SetBytes(pub[32:]), k)
+	z := append(x2.FillBytes(make([]byte, 32)), y2.FillBytes(make([]byte, 32))...)
+
+	msg := "encryption standard"
+	res := mustCall(t, "sm3", "kdf", map[string]interface{}{"shared_secret": hex.EncodeToString(z), "key_length": len(msg)})
+	key, _ := hex.DecodeString(res.Output)
+	c2 := make([]byte, len(msg))
+	for i := range c2 {
+		c2[i] = msg[i] ^ key[i]
+	}
+	if got := hex.EncodeToString(c2); got != "21886ca989ca9c7d58087307ca93092d651efa" {
+		t.Fatalf("C2 = %s", got)
+	}
+
+	for _, n := range []interface{}{0, -1, maxDerivedKeySize + 1, 1.5, "16"} {
+		mustFail(t, "sm3", "kdf", map[string]interface{}{"shared_secret": "00", "key_length": n})
+	}
+	mustFail(t, "sm3", "kdf", map[string]interface{}{"shared_secret": "00"})
+}
+
+func TestSM3PBKDF2(t *testing.T) {
+	in := map[string]interface{}{"password": "password", "salt": "73616c74", "iterations": 1, "key_length": 40}
+	res := mustCall(t, "sm3", "pbkdf2", in)
+
+	// With one iteration block i is HMAC(password, salt || INT(i)).
+	for i, block := range []string{res.Output[:64], res.Output[64:]} {
+		mac := mustCall(t, "sm3", "hmac", map[string]interface{}{
+			"key":  hex.EncodeToString([]byte("password")),
+			"data": "salt\x00\x00\x00" + string(rune(i+1)),
+		})
+		if !strings.HasPrefix(mac.Output, block) {
+			t.Fatalf("block %d = %s, want prefix of %s", i+1, block, mac.Output)
+		}
+	}
+
+	in["iterations"] = 2
+	if again := mustCall(t, "sm3", "pbkdf2", in); again.Output == res.Output {
+		t.Fatal("iteration count ignored")
+	}
+	for _, iter := range []interface{}{0, maxPBKDF2Iterations + 1, nil} {
+		in["iterations"] = iter
+		mustFail(t, "sm3", "pbkdf2", in)
+	}
+}
+
+func TestSM3HKDF(t *testing.T) {
+	const ikm, salt, info = "0b0b0b0b0b0b0b0b0b0b0b", "000102030405060708090a0b0c", "f0f1f2f3f4f5f6f7f8f9"
+
+	hmacSM3 := func(key, data string) string {
+		mac := hmac.New(sm3.New, mustDecodeHex(t, key))
+		mac.Write(mustDecodeHex(t, data))
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	// PRK = HMAC-SM3(salt, IKM).
+	prk := mustCall(t, "sm3", "hkdf", map[string]interface{}{"key": ikm, "salt": salt, "stage": "extract"}).Output
+	if want := hmacSM3(salt, ikm); prk != want {
+		t.Fatalf("PRK = %s, want %s", prk, want)
+	}
+
+	full := mustCall(t, "sm3", "hkdf", map[string]interface{}{"key": ikm, "salt": salt, "info": info, "key_length": 42})
+	expand := mustCall(t, "sm3", "hkdf", map[string]interface{}{"key": prk, "info": info, "key_length": 42, "stage": "expand"})
+	if full.Output != expand.Output || len(full.Output) != 84 {
+		t.Fatalf("full = %s, expand = %s", full.Output, expand.Output)
+	}
+
+	// T(1) = HMAC-SM3(PRK, info || 0x01).
+	if want := hmacSM3(prk, info+"01"); full.Output[:64] != want {
+		t.Fatalf("T(1) = %s, want %s", full.Output[:64], want)
+	}
+
+	mustFail(t, "sm3", "hkdf", map[string]interface{}{"key": ikm, "key_length": 255*32 + 1})
+	mustFail(t, "sm3", "hkdf", map[string]interface{}{"key": ikm, "key_length": 16, "stage": "both"})
+}
+
+func mustDecodeHex(t testing.TB, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestSM3Incremental(t *testing.T) {
+	ctx := mustCall(t, "sm3", "init", map[string]interface{}{}).Context
+	for _, chunk := range []map[string]interface{}{
+		{"data": "abc"},
+		{"data": "6461626364", "data_encoding": "hex"},
+		{"data": strings.Repeat("abcd", 13)},
+	} {
+		chunk["context"] = ctx
+		ctx = mustCall(t, "sm3", "update", chunk).Context
+	}
+	res := mustCall(t, "sm3", "final", map[string]interface{}{"context": ctx, "data": "abcd"})
+	// The chunks spell out GB/T 32905 example 2.
+	if want := "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"; res.Output != want {
+		t.Fatalf("digest = %s", res.Output)
+	}
+	mustFail(t, "sm3", "update", map[string]interface{}{"context": "00", "data": "x"})
+	mustFail(t, "sm3", "final", map[string]interface{}{})
+}
+
+func TestSM3Expected(t *testing.T) {
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	for expected, valid := range map[string]bool{
+		abc:                  true,
+		strings.ToUpper(abc): true,
+		abc[:62] + "e1":      false,
+		abc[:32]:             false, // a truncated digest is not a match
+	} {
+		res := mustCall(t, "sm3", "hash", map[string]interface{}{"data": "abc", "expected": expected})
+		if res.Valid == nil || *res.Valid != valid || res.Output != "" {
+			t.Errorf("expected %s: %+v, want valid=%v", expected, res, valid)
+		}
+	}
+	mustFail(t, "sm3", "hash", map[string]interface{}{"data": "abc", "expected": "xyz"})
+}
+
+func TestSM3DataList(t *testing.T) {
+	const abc = "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"
+	list := make([]interface{}, 16)
+	for i := range list {
+		list[i] = "abcd"
+	}
+	res := mustCall(t, "sm3", "hash", map[string]interface{}{"data_list": list})
+	if want := "debe9ff92275b8a138604889c18e5a4d6fdb70e5387e5765293dcba39c0c5732"; res.Output != want {
+		t.Fatalf("concatenated digest = %s", res.Output)
+	}
+
+	res = mustCall(t, "sm3", "hash", map[string]interface{}{
+		"data_list": []interface{}{"616263", "", "616263"}, "data_encoding": "hex", "per_item": true,
+	})
+	empty := sm3.Sum(nil)
+	want := []string{abc, hex.EncodeToString(empty[:]), abc}
+	if strings.Join(res.Outputs, ",") != strings.Join(want, ",") || res.Output != "" {
+		t.Fatalf("per-item digests = %v", res.Outputs)
+	}
+
+	for _, in := range []map[string]interface{}{
+		{"data_list": "abc"},
+		{"data_list": []interface{}{"a", 1}},
+		{"data_list": []interface{}{"a"}, "data": "a"},
+		{"data_list": []interface{}{"a"}, "per_item": true, "expected": abc},
+	} {
+		mustFail(t, "sm3", "hash", in)
+	}
+}
+
+func TestSM3MGF1(t *testing.T) {
+	const seed = "0123456789"
+	res := mustCall(t, "sm3", "mgf1", map[string]interface{}{"seed": seed, "length": 70})
+	if len(res.Output) != 140 {
+		t.Fatalf("mask = %s", res.Output)
+	}
+	// The first block is SM3(seed || 00000000); the next ones continue
+	// where the GB/T 32918 KDF, whose counter starts at 1, begins.
+	first := sm3.Sum(mustDecodeHex(t, seed+"00000000"))
+	if res.Output[:64] != hex.EncodeToString(first[:]) {
+		t.Fatalf("first block = %s", res.Output[:64])
+	}
+	kdf := mustCall(t, "sm3", "kdf", map[string]interface{}{"shared_secret": seed, "key_length": 38})
+	if res.Output[64:] != kdf.Output {
+		t.Fatalf("blocks 2-3 = %s, KDF = %s", res.Output[64:], kdf.Output)
+	}
+	mustFail(t, "sm3", "mgf1", map[string]interface{}{"seed": seed, "length": 0})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding"
+	"encoding/hex"
+	"fmt"
+	"hash"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm3"
+)
+
+// The SM3 init, update and final operations carry the running hash in the
+// "context" field as the hex encoded state of sm3's MarshalBinary, which
+// uses Bouncy Castle's encoded digest state layout so the Java wrapper can
+// resume a token produced here and vice versa.
+
+// sm3Init returns the context of an empty SM3 hash.
+func sm3Init(in map[string]interface{}) (*Result, error) {
+	return sm3ContextResult(sm3.New())
+}
+
+// sm3Update absorbs "data" (decoded per "data_encoding") into "context".
+func sm3Update(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Context hexBytes     `json:"context" validate:"required"`
+		Data    encodedBytes `json:"data" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	h, err := sm3Resume(req.Context)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(req.Data)
+	return sm3ContextResult(h)
+}
+
+// sm3Final absorbs an optional last "data" chunk and returns the digest.
+func sm3Final(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Context hexBytes     `json:"context" validate:"required"`
+		Data    encodedBytes `json:"data"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	h, err := sm3Resume(req.Context)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(req.Data)
+	return &Result{Output: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+func sm3Resume(state []byte) (hash.Hash, error) {
+	h := sm3.New()
+	if err := h.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+		return nil, &codedError{codeInvalidEncoding, fmt.Errorf("malformed context: %v", err)}
+	}
+	return h, nil
+}
+
+func sm3ContextResult(h hash.Hash) (*Result, error) {
+	state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Context: hex.EncodeToString(state)}, nil
+}
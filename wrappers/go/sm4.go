@@ -0,0 +1,413 @@
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// sm4Params holds the key, mode and IV fields shared by the SM4 cipher
+// operations, including the incremental ones.
+type sm4Params struct {
+	Key          hexBytes `json:"key" validate:"required"`
+	Mode         string   `json:"mode"`
+	IV           hexBytes `json:"iv"`
+	Padding      string   `json:"padding"`
+	FeedbackSize *int     `json:"feedback_size"`
+}
+
+// sm4Request holds the fields of sm4Encrypt and sm4Decrypt other than
+// their input.
+type sm4Request struct {
+	sm4Params
+	AAD        hexBytes `json:"aad"`
+	TagLength  *int     `json:"tag_length"`
+	Tweak      hexBytes `json:"tweak"`
+	Sector     *int     `json:"sector"`
+	InputFile  *string  `json:"input_file"`
+	OutputFile *string  `json:"output_file"`
+}
+
+// sm4Encrypt encrypts "plaintext" (UTF-8, or binary via "plaintext_hex" or
+// "plaintext_base64") under the hex "key".
+// "mode" is ECB (default), CBC, CTR, CFB, OFB, GCM, CCM or XTS. ECB and CBC
+// pad as selected by "padding" (see pad); the others leave the length
+// unchanged. XTS is keyed and tweaked as described at sm4XTS. The remaining
+// modes take a hex "iv" (the initial counter block for CTR, the nonce for
+// GCM and CCM) and generate a random one, returned in Result.IV, when it is
+// omitted. GCM and CCM also accept hex "aad" and "tag_length" and return
+// the tag in Result.Tag. With "input_file" the data is streamed instead;
+// see sm4CryptFile.
+func sm4Encrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm4Request
+		Plaintext textBytes `json:"plaintext"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, mode, err := req.setup()
+	if err != nil {
+		return nil, err
+	}
+	if req.InputFile != nil {
+		return sm4CryptFile(&req.sm4Request, block, mode, false)
+	}
+	if req.Plaintext == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"plaintext\" (or \"plaintext_hex\" / \"plaintext_base64\")")}
+	}
+	plaintext := []byte(req.Plaintext)
+
+	padding, err := checkPadding(req.Padding)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &Result{}
+	var data []byte
+	switch mode {
+	case "ECB":
+		if data, err = pad(padding, plaintext, sm4.BlockSize); err != nil {
+			return nil, err
+		}
+		modes.NewECBEncrypter(block).CryptBlocks(data, data)
+	case "CBC":
+		iv, err := req.encryptIV(mode, res)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = pad(padding, plaintext, sm4.BlockSize); err != nil {
+			return nil, err
+		}
+		cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	case "CTR", "CFB", "OFB":
+		iv, err := req.encryptIV(mode, res)
+		if err != nil {
+			return nil, err
+		}
+		if mode == "CTR" {
+			// The counter block is always echoed so the stream can be replayed.
+			res.IV = hex.EncodeToString(iv)
+		}
+		stream, err := req.stream(block, mode, iv, false)
+		if err != nil {
+			return nil, err
+		}
+		data = plaintext
+		stream.XORKeyStream(data, data)
+	case "XTS":
+		x, tweak, err := sm4XTS(&req.sm4Request)
+		if err != nil {
+			return nil, err
+		}
+		data = plaintext
+		if err := x.Encrypt(data, data, tweak); err != nil {
+			return nil, err
+		}
+	case "GCM", "CCM":
+		iv, err := req.encryptIV(mode, res)
+		if err != nil {
+			return nil, err
+		}
+		tagSize := aeadMaxTagSize
+		if req.TagLength != nil {
+			tagSize = *req.TagLength
+		}
+		aead, err := aeadSetup(block, mode, len(iv), tagSize)
+		if err != nil {
+			return nil, err
+		}
+		sealed := aead.Seal(nil, iv, plaintext, req.AAD)
+		data = sealed[:len(plaintext)]
+		res.Tag = hex.EncodeToString(sealed[len(plaintext):])
+	}
+	res.Output = hex.EncodeToString(data)
+	return res, nil
+}
+
+// sm4Decrypt reverses sm4Encrypt for the hex "ciphertext", rendering the
+// plaintext as selected by "plaintext_encoding". Every mode but ECB and
+// XTS requires "iv"; GCM and CCM also require "tag".
+func sm4Decrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm4Request
+		plaintextEncoding
+		Ciphertext hexBytes `json:"ciphertext"`
+		Tag        hexBytes `json:"tag"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, mode, err := req.setup()
+	if err != nil {
+		return nil, err
+	}
+	if req.InputFile != nil {
+		return sm4CryptFile(&req.sm4Request, block, mode, true)
+	}
+	if req.Ciphertext == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"ciphertext\"")}
+	}
+	data := []byte(req.Ciphertext)
+
+	switch mode {
+	case "ECB", "CBC":
+		padding, err := checkPadding(req.Padding)
+		if err != nil {
+			return nil, err
+		}
+		// PKCS#7 and ISO/IEC 7816-4 make the ciphertext at least a block
+		// long. Without padding an empty plaintext encrypts to an empty
+		// ciphertext, as it does with zero padding in some other libraries.
+		padded := padding == paddingPKCS7 || padding == paddingISO7816
+		if (len(data) == 0 && padded) || len(data)%sm4.BlockSize != 0 {
+			return nil, &codedError{codeInvalidDataLength, fmt.Errorf("ciphertext length %d is not a positive multiple of %d", len(data), sm4.BlockSize)}
+		}
+		if mode == "ECB" {
+			modes.NewECBDecrypter(block).CryptBlocks(data, data)
+		} else {
+			iv, err := req.decryptIV(mode)
+			if err != nil {
+				return nil, err
+			}
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(data, data)
+		}
+		if data, err = unpad(padding, data, sm4.BlockSize); err != nil {
+			return nil, err
+		}
+	case "CTR", "CFB", "OFB":
+		iv, err := req.decryptIV(mode)
+		if err != nil {
+			return nil, err
+		}
+		stream, err := req.stream(block, mode, iv, true)
+		if err != nil {
+			return nil, err
+		}
+		stream.XORKeyStream(data, data)
+	case "XTS":
+		x, tweak, err := sm4XTS(&req.sm4Request)
+		if err != nil {
+			return nil, err
+		}
+		if err := x.Decrypt(data, data, tweak); err != nil {
+			return nil, err
+		}
+	case "GCM", "CCM":
+		iv, err := req.decryptIV(mode)
+		if err != nil {
+			return nil, err
+		}
+		tag := req.Tag
+		if tag == nil {
+			return nil, &codedError{codeMissingField, fmt.Errorf("missing required field \"tag\" for %s decryption", mode)}
+		}
+		if req.TagLength != nil && *req.TagLength != len(tag) {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("tag is %d bytes but tag_length is %d", len(tag), *req.TagLength)}
+		}
+		aead, err := aeadSetup(block, mode, len(iv), len(tag))
+		if err != nil {
+			return nil, err
+		}
+		sealed := append(data, tag...)
+		if data, err = aead.Open(sealed[:0], iv, sealed, req.AAD); err != nil {
+			return nil, &codedError{codeAuthenticationFailed, fmt.Errorf("%s authentication failed", mode)}
+		}
+	}
+	out, err := req.encode(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: out}, nil
+}
+
+// sm4Modes are the values of "mode".
+var sm4Modes = []string{"ECB", "CBC", "CTR", "CFB", "OFB", "GCM", "CCM", "XTS"}
+
+// sm4Mode normalizes the SM4 "mode" m; "" is ECB.
+func sm4Mode(m string) (string, error) {
+	if m == "" {
+		return "ECB", nil
+	}
+	if m = strings.ToUpper(m); !slices.Contains(sm4Modes, m) {
+		return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported SM4 mode %q", m)}
+	}
+	return m, nil
+}
+
+// setup builds the cipher from "key" and normalizes "mode".
+// XTS takes a double-length key, so its block is nil and sm4XTS reads the
+// key instead.
+func (p *sm4Params) setup() (cipher.Block, string, error) {
+	mode, err := sm4Mode(p.Mode)
+	if err != nil || mode == "XTS" {
+		return nil, mode, err
+	}
+	block, err := sm4Cipher(p.Key)
+	if err != nil {
+		return nil, "", err
+	}
+	return block, mode, nil
+}
+
+// sm4XTS builds XTS from a 32-byte "key" (data key then tweak key) and
+// returns the 16-byte tweak, given in req either as hex "tweak" or as an
+// integer "sector" encoded little-endian per IEEE 1619.
+func sm4XTS(req *sm4Request) (*modes.XTS, []byte, error) {
+	key := []byte(req.Key)
+	if err := checkSM4Key(key, 2*sm4.KeySize); err != nil {
+		return nil, nil, err
+	}
+	k1, err := newSM4(key[:sm4.KeySize])
+	if err != nil {
+		return nil, nil, err
+	}
+	k2, err := newSM4(key[sm4.KeySize:])
+	if err != nil {
+		return nil, nil, err
+	}
+	x, err := modes.NewXTS(k1, k2)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tweak := []byte(req.Tweak)
+	switch {
+	case req.Tweak != nil && req.Sector != nil:
+		return nil, nil, &codedError{codeInvalidField, errors.New("give either \"tweak\" or \"sector\", not both")}
+	case req.Tweak != nil:
+		if len(tweak) != sm4.BlockSize {
+			return nil, nil, &codedError{codeInvalidIVLength, fmt.Errorf("tweak must be %d bytes, got %d", sm4.BlockSize, len(tweak))}
+		}
+	case req.Sector != nil:
+		if *req.Sector < 0 {
+			return nil, nil, &codedError{codeInvalidField, errors.New("sector must not be negative")}
+		}
+		tweak = binary.LittleEndian.AppendUint64(nil, uint64(*req.Sector))
+		tweak = append(tweak, make([]byte, 8)...)
+	default:
+		return nil, nil, &codedError{codeMissingField, errors.New("XTS requires \"tweak\" or \"sector\"")}
+	}
+	return x, tweak, nil
+}
+
+const (
+	aeadDefaultNonceSize = 12
+	aeadMaxTagSize       = 16
+	gcmMinTagSize        = 12
+)
+
+// ivSize returns the IV length a mode requires and the length generated
+// when none is supplied. The AEAD modes check nonce lengths themselves.
+func ivSize(mode string) (required, generated int) {
+	if mode == "GCM" || mode == "CCM" {
+		return 0, aeadDefaultNonceSize
+	}
+	return sm4.BlockSize, sm4.BlockSize
+}
+
+// iv checks the "iv" of p for mode; ok is false when it is absent.
+func (p *sm4Params) iv(mode string) (iv []byte, ok bool, err error) {
+	if p.IV == nil {
+		return nil, false, nil
+	}
+	if required, _ := ivSize(mode); required != 0 && len(p.IV) != required {
+		return nil, false, &codedError{codeInvalidIVLength, fmt.Errorf("iv must be %d bytes, got %d", required, len(p.IV))}
+	}
+	if len(p.IV) == 0 {
+		return nil, false, &codedError{codeInvalidIVLength, errors.New("iv must not be empty")}
+	}
+	return p.IV, true, nil
+}
+
+// encryptIV returns the supplied "iv", or a fresh random one recorded in res.
+func (p *sm4Params) encryptIV(mode string, res *Result) ([]byte, error) {
+	iv, ok, err := p.iv(mode)
+	if err != nil || ok {
+		return iv, err
+	}
+	_, size := ivSize(mode)
+	iv = make([]byte, size)
+	if _, err := io.ReadFull(rand, iv); err != nil {
+		return nil, err
+	}
+	res.IV = hex.EncodeToString(iv)
+	return iv, nil
+}
+
+func (p *sm4Params) decryptIV(mode string) ([]byte, error) {
+	iv, ok, err := p.iv(mode)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field \"iv\" for %s decryption", mode)}
+	}
+	return iv, nil
+}
+
+// stream builds the keystream for the stream modes. CFB reads
+// "feedback_size" in bits: 128 (default) or 8.
+func (p *sm4Params) stream(block cipher.Block, mode string, iv []byte, decrypt bool) (cipher.Stream, error) {
+	bits, err := p.feedbackSize()
+	if err != nil {
+		return nil, err
+	}
+	return newSM4Stream(block, mode, iv, bits, decrypt)
+}
+
+func (p *sm4Params) feedbackSize() (int, error) {
+	if p.FeedbackSize == nil {
+		return 8 * sm4.BlockSize, nil
+	}
+	if bits := *p.FeedbackSize; bits != 8 && bits != 8*sm4.BlockSize {
+		return 0, &codedError{codeInvalidField, fmt.Errorf("feedback_size must be 8 or %d bits, got %d", 8*sm4.BlockSize, bits)}
+	}
+	return *p.FeedbackSize, nil
+}
+
+func newSM4Stream(block cipher.Block, mode string, iv []byte, feedbackBits int, decrypt bool) (cipher.Stream, error) {
+	switch mode {
+	case "CTR":
+		return cipher.NewCTR(block, iv), nil
+	case "OFB":
+		return modes.NewOFB(block, iv)
+	}
+	if decrypt {
+		return modes.NewCFBDecrypter(block, iv, feedbackBits/8)
+	}
+	return modes.NewCFBEncrypter(block, iv, feedbackBits/8)
+}
+
+// aeadSetup builds the GCM or CCM AEAD.
+func aeadSetup(block cipher.Block, mode string, nonceSize, tagSize int) (cipher.AEAD, error) {
+	if mode == "CCM" {
+		return modes.NewCCM(block, nonceSize, tagSize)
+	}
+	if tagSize < gcmMinTagSize || tagSize > aeadMaxTagSize {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("GCM tag must be between %d and %d bytes, got %d", gcmMinTagSize, aeadMaxTagSize, tagSize)}
+	}
+	var aead cipher.AEAD
+	var err error
+	switch {
+	case nonceSize == aeadDefaultNonceSize:
+		aead, err = cipher.NewGCMWithTagSize(block, tagSize)
+	case tagSize == aeadMaxTagSize:
+		aead, err = cipher.NewGCMWithNonceSize(block, nonceSize)
+	default:
+		err = errors.New("GCM with an iv other than 12 bytes requires a 16-byte tag")
+	}
+	if err != nil {
+		// The standard library rejects only nonce sizes.
+		return nil, &codedError{codeInvalidIVLength, err}
+	}
+	return aead, nil
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSM4Key = "0123456789abcdeffedcba9876543210"
+
+func TestSM4ECBRoundTrip(t *testing.T) {
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "plaintext": "hello, world",
+	})
+	if len(enc.Output) != 32 {
+		t.Fatalf("ciphertext %s is not one padded block", enc.Output)
+	}
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "ciphertext": enc.Output,
+	})
+	if dec.Output != "hello, world" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+}
+
+func TestSM4CBCKnownAnswer(t *testing.T) {
+	// A full block of plaintext gains a full block of PKCS#7 padding.
+	res := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key":       testSM4Key,
+		"mode":      "cbc",
+		"iv":        "000102030405060708090a0b0c0d0e0f",
+		"plaintext": "0123456789abcdef",
+	})
+	if res.IV != "" {
+		t.Errorf("supplied IV echoed back: %s", res.IV)
+	}
+	if len(res.Output) != 64 {
+		t.Fatalf("ciphertext %s is not two blocks", res.Output)
+	}
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key":        testSM4Key,
+		"mode":       "CBC",
+		"iv":         "000102030405060708090a0b0c0d0e0f",
+		"ciphertext": res.Output,
+	})
+	if dec.Output != "0123456789abcdef" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+}
+
+func TestSM4CBCGeneratedIV(t *testing.T) {
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CBC", "plaintext": "generated iv",
+	})
+	if len(enc.IV) != 32 {
+		t.Fatalf("generated IV = %q, want 16 hex-encoded bytes", enc.IV)
+	}
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CBC", "iv": enc.IV, "ciphertext": enc.Output,
+	})
+	if dec.Output != "generated iv" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+}
+
+func TestSM4Errors(t *testing.T) {
+	cases := []struct {
+		name, op string
+		in       map[string]interface{}
+		want     string
+	}{
+		{"short key", "encrypt", map[string]interface{}{"key": "0011", "plaintext": "x"}, "key size"},
+		{"short iv", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "CBC", "iv": "0011", "plaintext": "x"}, "iv must be 16 bytes"},
+		{"missing iv", "decrypt", map[string]interface{}{"key": testSM4Key, "mode": "CBC", "ciphertext": strings.Repeat("00", 16)}, "iv"},
+		{"bad mode", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "XYZ", "plaintext": "x"}, "unsupported SM4 mode"},
+		{"partial block", "decrypt", map[string]interface{}{"key": testSM4Key, "ciphertext": "00"}, "multiple of 16"},
+		{"bad padding", "decrypt", map[string]interface{}{"key": testSM4Key, "ciphertext": strings.Repeat("00", 16)}, "padding"},
+		{"string tag_length", "encrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "tag_length": "12", "plaintext": "x"}, "tag_length"},
+		{"missing tag", "decrypt", map[string]interface{}{"key": testSM4Key, "mode": "GCM", "iv": strings.Repeat("00", 12), "ciphertext": "00"}, "tag"},
+	}
+	for _, c := range cases {
+		res := mustFail(t, "sm4", c.op, c.in)
+		if !strings.Contains(res.Message, c.want) {
+			t.Errorf("%s: message %q does not mention %q", c.name, res.Message, c.want)
+		}
+	}
+}
+
+func TestSM4CTR(t *testing.T) {
+	iv := "f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CTR", "iv": iv, "plaintext": "seventeen bytes!!",
+	})
+	if len(enc.Output) != 34 {
+		t.Fatalf("CTR ciphertext %s was padded", enc.Output)
+	}
+	if enc.IV != iv {
+		t.Fatalf("counter block = %q, want %q", enc.IV, iv)
+	}
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CTR", "iv": enc.IV, "ciphertext": enc.Output,
+	})
+	if dec.Output != "seventeen bytes!!" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+
+	gen := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CTR", "plaintext": "x",
+	})
+	if len(gen.IV) != 32 || len(gen.Output) != 2 {
+		t.Fatalf("generated counter block %q, ciphertext %q", gen.IV, gen.Output)
+	}
+	mustFail(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CTR", "ciphertext": gen.Output,
+	})
+}
+
+func TestSM4GCM(t *testing.T) {
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "aad": "feedface", "plaintext": "authenticated",
+	})
+	if len(enc.IV) != 24 || len(enc.Tag) != 32 || len(enc.Output) != 26 {
+		t.Fatalf("unexpected GCM result: %+v", enc)
+	}
+	in := map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "iv": enc.IV, "aad": "feedface",
+		"ciphertext": enc.Output, "tag": enc.Tag,
+	}
+	if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != "authenticated" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+	in["aad"] = "feedfacf"
+	if res := mustFail(t, "sm4", "decrypt", in); !strings.Contains(res.Message, "authentication") {
+		t.Fatalf("message = %q", res.Message)
+	}
+
+	short := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "tag_length": 12, "plaintext": "short tag",
+	})
+	if len(short.Tag) != 24 {
+		t.Fatalf("tag = %q, want 12 bytes", short.Tag)
+	}
+	mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "iv": short.IV, "ciphertext": short.Output, "tag": short.Tag,
+	})
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "GCM", "tag_length": 8, "plaintext": "x",
+	})
+}
+
+func TestSM4FeedbackModes(t *testing.T) {
+	iv := "000102030405060708090a0b0c0d0e0f"
+	seen := map[string]bool{}
+	for _, c := range []map[string]interface{}{
+		{"mode": "CFB"},
+		{"mode": "cfb", "feedback_size": 8},
+		{"mode": "OFB"},
+	} {
+		in := map[string]interface{}{"key": testSM4Key, "iv": iv, "plaintext": "not a block multiple"}
+		for k, v := range c {
+			in[k] = v
+		}
+		enc := mustCall(t, "sm4", "encrypt", in)
+		if len(enc.Output) != 2*len("not a block multiple") {
+			t.Fatalf("%v: ciphertext %s was padded", c, enc.Output)
+		}
+		if seen[enc.Output] {
+			t.Fatalf("%v: ciphertext identical to another mode", c)
+		}
+		seen[enc.Output] = true
+
+		delete(in, "plaintext")
+		in["ciphertext"] = enc.Output
+		if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != "not a block multiple" {
+			t.Fatalf("%v: decrypt = %q", c, dec.Output)
+		}
+	}
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CFB", "feedback_size": 64, "plaintext": "x",
+	})
+}
+
+func TestSM4CCM(t *testing.T) {
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CCM", "iv": "00112233445566", "tag_length": 8,
+		"aad": "0102", "plaintext": "constrained device",
+	})
+	if len(enc.Tag) != 16 || enc.IV != "" {
+		t.Fatalf("unexpected CCM result: %+v", enc)
+	}
+	in := map[string]interface{}{
+		"key": testSM4Key, "mode": "CCM", "iv": "00112233445566", "aad": "0102",
+		"ciphertext": enc.Output, "tag": enc.Tag,
+	}
+	if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != "constrained device" {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+	in["tag"] = "00" + enc.Tag[2:]
+	mustFail(t, "sm4", "decrypt", in)
+
+	gen := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "mode": "CCM", "plaintext": "default nonce",
+	})
+	if len(gen.IV) != 24 || len(gen.Tag) != 32 {
+		t.Fatalf("unexpected defaults: %+v", gen)
+	}
+	for _, bad := range []map[string]interface{}{
+		{"iv": "001122334455"},
+		{"iv": "00112233445566", "tag_length": 7},
+	} {
+		in := map[string]interface{}{"key": testSM4Key, "mode": "CCM", "plaintext": "x"}
+		for k, v := range bad {
+			in[k] = v
+		}
+		mustFail(t, "sm4", "encrypt", in)
+	}
+}
+
+func TestSM4XTS(t *testing.T) {
+	key := testSM4Key + "fedcba98765432100123456789abcdef"
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": key, "mode": "XTS", "sector": 7, "plaintext": "a sector of 23 bytes...",
+	})
+	if len(enc.Output) != 46 || enc.IV != "" {
+		t.Fatalf("unexpected XTS result: %+v", enc)
+	}
+	// Sector 7 is the little-endian tweak 07 00 .. 00.
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": key, "mode": "XTS", "tweak": "07000000000000000000000000000000", "ciphertext": enc.Output,
+	})
+	if dec.Output != "a sector of 23 bytes..." {
+		t.Fatalf("decrypt = %q", dec.Output)
+	}
+	for _, bad := range []map[string]interface{}{
+		{"key": testSM4Key, "sector": 1, "plaintext": "sixteen bytes!!!"},
+		{"key": key, "plaintext": "sixteen bytes!!!"},
+		{"key": key, "sector": 1, "tweak": "00", "plaintext": "sixteen bytes!!!"},
+		{"key": key, "sector": 1, "plaintext": "too short"},
+	} {
+		bad["mode"] = "XTS"
+		mustFail(t, "sm4", "encrypt", bad)
+	}
+}
+
+// Every mode that needs an IV generates one when it is omitted, returns it,
+// and never reuses it.
+func TestSM4GeneratedIVs(t *testing.T) {
+	for mode, size := range map[string]int{"CBC": 16, "CTR": 16, "CFB": 16, "OFB": 16, "GCM": 12, "CCM": 12} {
+		var first *Result
+		for i := 0; i < 2; i++ {
+			in := map[string]interface{}{"key": testSM4Key, "mode": mode, "plaintext": "same plaintext"}
+			enc := mustCall(t, "sm4", "encrypt", in)
+			if len(enc.IV) != 2*size {
+				t.Fatalf("%s: generated iv %q, want %d bytes", mode, enc.IV, size)
+			}
+			if first != nil && (enc.IV == first.IV || enc.Output == first.Output) {
+				t.Fatalf("%s: iv or ciphertext repeated across calls", mode)
+			}
+			first = enc
+
+			delete(in, "plaintext")
+			in["iv"], in["ciphertext"], in["tag"] = enc.IV, enc.Output, enc.Tag
+			if dec := mustCall(t, "sm4", "decrypt", in); dec.Output != "same plaintext" {
+				t.Fatalf("%s: decrypt with returned iv = %q", mode, dec.Output)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+)
+
+// fileChunkSize is how much of "input_file" is read per step.
+const fileChunkSize = 64 << 10
+
+// sm4CryptFile streams "input_file" through the cipher into "output_file"
+// in fixed-size chunks, so memory use does not grow with the file size.
+// Only modes that can run incrementally are supported; the other fields
+// are interpreted as in sm4Encrypt and sm4Decrypt.
+func sm4CryptFile(req *sm4Request, block cipher.Block, mode string, decrypt bool) (*Result, error) {
+	if req.OutputFile == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"output_file\"")}
+	}
+	inPath, outPath := *req.InputFile, *req.OutputFile
+	switch mode {
+	case "ECB", "CBC", "CTR", "CFB", "OFB":
+	default:
+		return nil, fmt.Errorf("SM4 mode %s cannot stream a file", mode)
+	}
+
+	res := &Result{}
+	var iv []byte
+	var err error
+	if mode != "ECB" {
+		if decrypt {
+			iv, err = req.decryptIV(mode)
+		} else {
+			iv, err = req.encryptIV(mode, res)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if mode == "CTR" && !decrypt {
+			res.IV = hex.EncodeToString(iv)
+		}
+	}
+
+	src, err := os.Open(inPath)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	// Creating the output would truncate the input before it is read.
+	if si, err := src.Stat(); err == nil {
+		if di, err := os.Stat(outPath); err == nil && os.SameFile(si, di) {
+			return nil, &codedError{codeInvalidField, fmt.Errorf("\"input_file\" and \"output_file\" are the same file %s", outPath)}
+		}
+	}
+	dst, err := os.Create(outPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var n int64
+	switch mode {
+	case "ECB", "CBC":
+		var padding string
+		if padding, err = checkPadding(req.Padding); err != nil {
+			break
+		}
+		var bm cipher.BlockMode
+		switch {
+		case mode == "ECB" && decrypt:
+			bm = modes.NewECBDecrypter(block)
+		case mode == "ECB":
+			bm = modes.NewECBEncrypter(block)
+		case decrypt:
+			bm = cipher.NewCBCDecrypter(block, iv)
+		default:
+			bm = cipher.NewCBCEncrypter(block, iv)
+		}
+		n, err = cryptBlocksStream(dst, src, bm, padding, decrypt)
+	default:
+		var stream cipher.Stream
+		if stream, err = req.stream(block, mode, iv, decrypt); err != nil {
+			break
+		}
+		buf := make([]byte, fileChunkSize)
+		n, err = io.CopyBuffer(dst, cipher.StreamReader{S: stream, R: src}, buf)
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return nil, err
+	}
+	res.Bytes = n
+	return res, nil
+}
+
+// cryptBlocksStream runs a padded block mode over r. The trailing partial
+// block (or, when decrypting, the last full block) is held back until EOF
+// so that padding can be added or removed.
+func cryptBlocksStream(w io.Writer, r io.Reader, bm cipher.BlockMode, padding string, decrypt bool) (int64, error) {
+	bs := bm.BlockSize()
+	chunk := make([]byte, fileChunkSize)
+	var pending []byte
+	var written int64
+	for {
+		n, rerr := r.Read(chunk)
+		pending = append(pending, chunk[:n]...)
+		keep := len(pending) % bs
+		if decrypt && keep == 0 && len(pending) > 0 {
+			keep = bs
+		}
+		if ready := len(pending) - keep; ready > 0 {
+			bm.CryptBlocks(pending[:ready], pending[:ready])
+			if _, err := w.Write(pending[:ready]); err != nil {
+				return written, err
+			}
+			written += int64(ready)
+			pending = append(pending[:0], pending[ready:]...)
+		}
+		if rerr == io.EOF {
+			break
+		}
+		if rerr != nil {
+			return written, rerr
+		}
+	}
+
+	var tail []byte
+	if decrypt {
+		if len(pending)%bs != 0 {
+			return written, fmt.Errorf("ciphertext length is not a multiple of %d", bs)
+		}
+		bm.CryptBlocks(pending, pending)
+		var err error
+		if tail, err = unpadLastBlock(padding, pending); err != nil {
+			return written, err
+		}
+	} else {
+		var err error
+		if tail, err = pad(padding, pending, bs); err != nil {
+			return written, err
+		}
+		bm.CryptBlocks(tail, tail)
+	}
+	m, err := w.Write(tail)
+	return written + int64(m), err
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSM4FileStreaming(t *testing.T) {
+	dir := t.TempDir()
+	// Larger than one chunk and not block aligned.
+	content := strings.Repeat("stream me ", fileChunkSize/10+7)
+	plainPath := filepath.Join(dir, "plain")
+	if err := os.WriteFile(plainPath, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	iv := "000102030405060708090a0b0c0d0e0f"
+
+	for _, mode := range []string{"ECB", "CBC", "CTR", "CFB", "OFB"} {
+		encPath := filepath.Join(dir, mode+".enc")
+		decPath := filepath.Join(dir, mode+".dec")
+		base := map[string]interface{}{"key": testSM4Key, "mode": mode}
+		if mode != "ECB" {
+			base["iv"] = iv
+		}
+		with := func(extra map[string]interface{}) map[string]interface{} {
+			m := map[string]interface{}{}
+			for k, v := range base {
+				m[k] = v
+			}
+			for k, v := range extra {
+				m[k] = v
+			}
+			return m
+		}
+
+		enc := mustCall(t, "sm4", "encrypt", with(map[string]interface{}{"input_file": plainPath, "output_file": encPath}))
+		ct, _ := os.ReadFile(encPath)
+		if enc.Bytes != int64(len(ct)) {
+			t.Fatalf("%s: reported %d bytes, wrote %d", mode, enc.Bytes, len(ct))
+		}
+		// The streamed ciphertext must match the in-memory path.
+		mem := mustCall(t, "sm4", "encrypt", with(map[string]interface{}{"plaintext": content}))
+		if mem.Output != hex.EncodeToString(ct) {
+			t.Fatalf("%s: streamed ciphertext differs from in-memory ciphertext", mode)
+		}
+
+		mustCall(t, "sm4", "decrypt", with(map[string]interface{}{"input_file": encPath, "output_file": decPath}))
+		pt, _ := os.ReadFile(decPath)
+		if !bytes.Equal(pt, []byte(content)) {
+			t.Fatalf("%s: decrypted file differs", mode)
+		}
+	}
+
+	// Modes that cannot stream read the file into memory instead.
+	gcm := map[string]interface{}{"key": testSM4Key, "mode": "GCM", "iv": "000102030405060708090a0b"}
+	gcm["input_file"], gcm["output_file"] = plainPath, filepath.Join(dir, "gcm")
+	enc := mustCall(t, "sm4", "encrypt", gcm)
+	ct, _ := os.ReadFile(filepath.Join(dir, "gcm"))
+	if enc.Bytes != int64(len(content)) || len(ct) != len(content) || enc.Tag == "" {
+		t.Fatalf("GCM from a file: %+v", enc)
+	}
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "input_file": filepath.Join(dir, "missing"), "output_file": filepath.Join(dir, "x"),
+	})
+	mustFail(t, "sm4", "encrypt", map[string]interface{}{"key": testSM4Key, "input_file": plainPath})
+
+	// Encrypting a file onto itself, under any name, must leave it intact.
+	for _, out := range []string{plainPath, filepath.Join(dir, ".", "plain")} {
+		res := mustFail(t, "sm4", "encrypt", map[string]interface{}{
+			"key": testSM4Key, "mode": "CBC", "iv": iv, "input_file": plainPath, "output_file": out,
+		})
+		if res.ErrorCode != codeInvalidField {
+			t.Errorf("same input and output file: error_code %s, want %s", res.ErrorCode, codeInvalidField)
+		}
+	}
+	if got, _ := os.ReadFile(plainPath); string(got) != content {
+		t.Fatal("encrypting a file onto itself changed it")
+	}
+}
+
+// Zero padding is removed from the last block only, the same way whether
+// the ciphertext is decrypted in memory, streamed from a file or fed to an
+// incremental decryption.
+func TestSM4ZeroPaddingLastBlock(t *testing.T) {
+	plain := "abc" + strings.Repeat("\x00", 29)
+	enc := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "none", "plaintext": plain,
+	})
+	want := hex.EncodeToString([]byte(plain[:16]))
+
+	dec := mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "zeros", "ciphertext": enc.Output, "plaintext_encoding": "hex",
+	})
+	if dec.Output != want {
+		t.Errorf("in memory: decrypt = %s, want %s", dec.Output, want)
+	}
+
+	dir := t.TempDir()
+	ct, _ := hex.DecodeString(enc.Output)
+	encPath, decPath := filepath.Join(dir, "enc"), filepath.Join(dir, "dec")
+	if err := os.WriteFile(encPath, ct, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	mustCall(t, "sm4", "decrypt", map[string]interface{}{
+		"key": testSM4Key, "padding": "zeros", "input_file": encPath, "output_file": decPath,
+	})
+	if got, _ := os.ReadFile(decPath); hex.EncodeToString(got) != want {
+		t.Errorf("from a file: decrypt = %x, want %s", got, want)
+	}
+
+	ctx := mustCall(t, "sm4", "decrypt-init", map[string]interface{}{"key": testSM4Key, "padding": "zeros"}).Context
+	upd := mustCall(t, "sm4", "decrypt-update", map[string]interface{}{"context": ctx, "ciphertext": enc.Output})
+	fin := mustCall(t, "sm4", "decrypt-final", map[string]interface{}{"context": upd.Context})
+	if got := hex.EncodeToString([]byte(upd.Output + fin.Output)); got != want {
+		t.Errorf("incremental: decrypt = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// sm4Context is the state carried between the init, update and final
+// operations. Each CLI call is a separate process, so the state travels in
+// the "context" token. The token holds the key in the clear and must be
+// handled like the key itself.
+type sm4Context struct {
+	Op           string `json:"op"`
+	Mode         string `json:"mode"`
+	Key          string `json:"key"`
+	IV           string `json:"iv,omitempty"` // chaining value for the next block
+	Padding      string `json:"padding,omitempty"`
+	FeedbackSize int    `json:"feedback_size,omitempty"`
+	Pending      string `json:"pending,omitempty"` // buffered input, hex
+}
+
+func (c *sm4Context) token() (string, error) {
+	b, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func parseSM4Context(tok, op string) (*sm4Context, error) {
+	b, err := base64.RawURLEncoding.DecodeString(tok)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, fmt.Errorf("malformed context token: %v", err)}
+	}
+	var c sm4Context
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, &codedError{codeInvalidEncoding, fmt.Errorf("malformed context token: %v", err)}
+	}
+	if c.Op != op {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("context was created by %s-init, not %s-init", c.Op, op)}
+	}
+	return &c, nil
+}
+
+// sm4IncrementalInit returns the init operation for op ("encrypt" or
+// "decrypt"). It accepts the key, mode, iv, padding and feedback_size
+// fields of sm4Encrypt; GCM, CCM and XTS cannot run incrementally.
+func sm4IncrementalInit(op string) handler {
+	return func(in map[string]interface{}) (*Result, error) {
+		var req sm4Params
+		if err := decodeRequest(in, &req); err != nil {
+			return nil, err
+		}
+		_, mode, err := req.setup()
+		if err != nil {
+			return nil, err
+		}
+		switch mode {
+		case "ECB", "CBC", "CTR", "CFB", "OFB":
+		default:
+			return nil, &codedError{codeUnsupportedOption, fmt.Errorf("SM4 mode %s cannot run incrementally", mode)}
+		}
+		c := &sm4Context{Op: op, Mode: mode, Key: hex.EncodeToString(req.Key)}
+		res := &Result{}
+		if mode != "ECB" {
+			var iv []byte
+			if op == "encrypt" {
+				iv, err = req.encryptIV(mode, res)
+			} else {
+				iv, err = req.decryptIV(mode)
+			}
+			if err != nil {
+				return nil, err
+			}
+			c.IV = hex.EncodeToString(iv)
+		}
+		if mode == "ECB" || mode == "CBC" {
+			if c.Padding, err = checkPadding(req.Padding); err != nil {
+				return nil, err
+			}
+		}
+		if mode == "CFB" {
+			if c.FeedbackSize, err = req.feedbackSize(); err != nil {
+				return nil, err
+			}
+		}
+		if res.Context, err = c.token(); err != nil {
+			return nil, err
+		}
+		return res, nil
+	}
+}
+
+// sm4IncrementalUpdate processes the next chunk ("plaintext" or its binary
+// forms when encrypting, hex "ciphertext" when decrypting) and returns the
+// output produced so far together with the updated context.
+func sm4IncrementalUpdate(op string) handler {
+	return func(in map[string]interface{}) (*Result, error) {
+		return sm4IncrementalStep(in, op, false)
+	}
+}
+
+// sm4IncrementalFinal processes an optional last chunk, flushes buffered
+// input and applies or strips padding.
+func sm4IncrementalFinal(op string) handler {
+	return func(in map[string]interface{}) (*Result, error) {
+		return sm4IncrementalStep(in, op, true)
+	}
+}
+
+// sm4StepRequest holds the fields of the update and final operations. Only
+// the input of the direction of the context is read.
+type sm4StepRequest struct {
+	plaintextEncoding
+	Context    string     `json:"context" validate:"required"`
+	Plaintext  *textBytes `json:"plaintext"`
+	Ciphertext *hexBytes  `json:"ciphertext"`
+}
+
+func sm4IncrementalStep(in map[string]interface{}, op string, final bool) (*Result, error) {
+	var req sm4StepRequest
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	c, err := parseSM4Context(req.Context, op)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	field, ok := "plaintext", req.Plaintext != nil
+	if op == "decrypt" {
+		field, ok = "ciphertext", req.Ciphertext != nil
+	}
+	switch {
+	case ok && op == "encrypt":
+		data = *req.Plaintext
+	case ok:
+		data = *req.Ciphertext
+	case !final:
+		return nil, &codedError{codeMissingField, fmt.Errorf("missing required field %q", field)}
+	}
+
+	out, err := c.process(data, final)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if op == "encrypt" {
+		res.Output = hex.EncodeToString(out)
+	} else if res.Output, err = req.encode(out); err != nil {
+		return nil, err
+	}
+	if !final {
+		if res.Context, err = c.token(); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// process consumes data and returns the output that can be released. Only
+// whole blocks are processed before the final call, so the chaining state
+// always fits in a single IV-sized value.
+func (c *sm4Context) process(data []byte, final bool) ([]byte, error) {
+	key, err := hex.DecodeString(c.Key)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, errors.New("malformed context token: bad key")}
+	}
+	block, err := newSM4(key)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(c.IV)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, errors.New("malformed context token: bad iv")}
+	}
+	pending, err := hex.DecodeString(c.Pending)
+	if err != nil {
+		return nil, &codedError{codeInvalidEncoding, errors.New("malformed context token: bad pending data")}
+	}
+	buf := append(pending, data...)
+	decrypt := c.Op == "decrypt"
+	padded := c.Mode == "ECB" || c.Mode == "CBC"
+	bs := sm4.BlockSize
+
+	n := len(buf) / bs * bs
+	if !final && decrypt && padded && c.Padding != paddingNone && n == len(buf) && n > 0 {
+		// Hold back the block that may carry the padding.
+		n -= bs
+	}
+	if final {
+		if padded && !decrypt {
+			if buf, err = pad(c.Padding, buf, bs); err != nil {
+				return nil, err
+			}
+		}
+		if padded && len(buf)%bs != 0 {
+			return nil, &codedError{codeInvalidDataLength, fmt.Errorf("ciphertext length is not a multiple of %d", bs)}
+		}
+		n = len(buf)
+	}
+
+	out := make([]byte, n)
+	src := buf[:n]
+	switch c.Mode {
+	case "ECB":
+		if decrypt {
+			modes.NewECBDecrypter(block).CryptBlocks(out, src)
+		} else {
+			modes.NewECBEncrypter(block).CryptBlocks(out, src)
+		}
+	case "CBC":
+		if decrypt {
+			cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, src)
+		} else {
+			cipher.NewCBCEncrypter(block, iv).CryptBlocks(out, src)
+		}
+	default:
+		stream, err := newSM4Stream(block, c.Mode, iv, c.FeedbackSize, decrypt)
+		if err != nil {
+			return nil, err
+		}
+		stream.XORKeyStream(out, src)
+	}
+
+	if final {
+		if padded && decrypt {
+			k := max(len(out)-bs, 0)
+			last, err := unpadLastBlock(c.Padding, out[k:])
+			if err != nil {
+				return nil, err
+			}
+			return out[:k+len(last)], nil
+		}
+		return out, nil
+	}
+	if n >= bs {
+		c.IV = hex.EncodeToString(nextChainValue(c.Mode, iv, src, out, decrypt))
+	}
+	c.Pending = hex.EncodeToString(buf[n:])
+	return out, nil
+}
+
+// nextChainValue returns the IV that continues a mode after src was turned
+// into dst, both a non-empty whole number of blocks.
+func nextChainValue(mode string, iv, src, dst []byte, decrypt bool) []byte {
+	bs := sm4.BlockSize
+	ct := dst
+	if decrypt {
+		ct = src
+	}
+	lastCT := ct[len(ct)-bs:]
+	switch mode {
+	case "CTR":
+		next := append([]byte(nil), iv...)
+		for blocks := len(src) / bs; blocks > 0; blocks-- {
+			for i := len(next) - 1; i >= 0; i-- {
+				next[i]++
+				if next[i] != 0 {
+					break
+				}
+			}
+		}
+		return next
+	case "OFB":
+		// The last keystream block is the XOR of input and output.
+		ks := make([]byte, bs)
+		for i := range ks {
+			ks[i] = src[len(src)-bs+i] ^ dst[len(dst)-bs+i]
+		}
+		return ks
+	default:
+		// CBC and CFB chain on the last ciphertext block; for CFB8 this is
+		// the shift register after a whole number of blocks.
+		return append([]byte(nil), lastCT...)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM4IncrementalMatchesOneShot(t *testing.T) {
+	msg := strings.Repeat("incremental SM4 ", 5) + "tail"
+	chunks := []string{msg[:5], msg[5:40], msg[40:48], msg[48:]}
+	for _, tc := range []struct {
+		mode     string
+		feedback int
+	}{{"ECB", 0}, {"CBC", 0}, {"CTR", 0}, {"OFB", 0}, {"CFB", 128}, {"CFB", 8}} {
+		in := map[string]interface{}{"key": testSM4Key, "mode": tc.mode}
+		if tc.feedback != 0 {
+			in["feedback_size"] = tc.feedback
+		}
+		init := mustCall(t, "sm4", "encrypt-init", in)
+		if tc.mode != "ECB" && init.IV == "" {
+			t.Fatalf("%s: init did not return the generated IV", tc.mode)
+		}
+		ctx, ct := init.Context, ""
+		for _, chunk := range chunks {
+			res := mustCall(t, "sm4", "encrypt-update", map[string]interface{}{"context": ctx, "plaintext": chunk})
+			ctx, ct = res.Context, ct+res.Output
+		}
+		ct += mustCall(t, "sm4", "encrypt-final", map[string]interface{}{"context": ctx}).Output
+
+		in["plaintext"], in["iv"] = msg, init.IV
+		if tc.mode == "ECB" {
+			delete(in, "iv")
+		}
+		if want := mustCall(t, "sm4", "encrypt", in).Output; ct != want {
+			t.Fatalf("%s/%d: incremental %s, one-shot %s", tc.mode, tc.feedback, ct, want)
+		}
+
+		delete(in, "plaintext")
+		ctx = mustCall(t, "sm4", "decrypt-init", in).Context
+		var pt string
+		prev := 0
+		for _, cut := range []int{10, 32, 66, len(ct)} { // hex offsets
+			res := mustCall(t, "sm4", "decrypt-update", map[string]interface{}{"context": ctx, "ciphertext": ct[prev:cut]})
+			ctx, pt, prev = res.Context, pt+res.Output, cut
+		}
+		pt += mustCall(t, "sm4", "decrypt-final", map[string]interface{}{"context": ctx}).Output
+		if pt != msg {
+			t.Fatalf("%s/%d: incremental decrypt = %q", tc.mode, tc.feedback, pt)
+		}
+	}
+}
+
+func TestSM4IncrementalErrors(t *testing.T) {
+	for _, mode := range []string{"GCM", "CCM", "XTS"} {
+		mustFail(t, "sm4", "encrypt-init", map[string]interface{}{"key": testSM4Key, "mode": mode})
+	}
+	ctx := mustCall(t, "sm4", "encrypt-init", map[string]interface{}{"key": testSM4Key, "mode": "CBC"}).Context
+	mustFail(t, "sm4", "encrypt-update", map[string]interface{}{"context": ctx})
+	mustFail(t, "sm4", "decrypt-update", map[string]interface{}{"context": ctx, "ciphertext": "00"})
+	mustFail(t, "sm4", "encrypt-update", map[string]interface{}{"context": "!!", "plaintext": "a"})
+
+	ctx = mustCall(t, "sm4", "decrypt-init", map[string]interface{}{
+		"key": testSM4Key, "mode": "CBC", "iv": strings.Repeat("00", 16),
+	}).Context
+	ctx = mustCall(t, "sm4", "decrypt-update", map[string]interface{}{"context": ctx, "ciphertext": "0011"}).Context
+	mustFail(t, "sm4", "decrypt-final", map[string]interface{}{"context": ctx})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm4"
+)
+
+// sm4KeyField reads the hex "key" and checks that it is size bytes long.
+func sm4KeyField(in map[string]interface{}, size int) ([]byte, error) {
+	key, err := requireHex(in, "key")
+	if err != nil {
+		return nil, err
+	}
+	return key, checkSM4Key(key, size)
+}
+
+// checkSM4Key checks that the "key" key is size bytes long.
+func checkSM4Key(key []byte, size int) error {
+	if len(key) != size {
+		return &codedError{codeInvalidKeyLength,
+			fmt.Errorf("invalid SM4 key size %d, want %d", len(key), size)}
+	}
+	return nil
+}
+
+// sm4Cipher builds SM4 from the 16-byte "key" key.
+func sm4Cipher(key []byte) (cipher.Block, error) {
+	if err := checkSM4Key(key, sm4.KeySize); err != nil {
+		return nil, err
+	}
+	return newSM4(key)
+}
+
+// weakKey describes why key is weak, or returns "". A key is weak when
+// every byte is the same (including all zeros) or, for double-length XTS
+// keys, when the data key equals the tweak key.
+func weakKey(key []byte) string {
+	if len(key) == 0 {
+		return ""
+	}
+	if bytes.Count(key, key[:1]) == len(key) {
+		return fmt.Sprintf("weak key: every byte is 0x%02x", key[0])
+	}
+	if len(key) == 2*sm4.KeySize && bytes.Equal(key[:sm4.KeySize], key[sm4.KeySize:]) {
+		return "weak key: XTS data key and tweak key are equal"
+	}
+	return ""
+}
+
+// checkSM4Keys wraps every SM4 handler with the weak-key check. A weak
+// "key" of a valid length adds a warning to the result, or fails the
+// request with codeWeakKey when "reject_weak_key" is true. Length errors
+// are left to the handler.
+func checkSM4Keys(ops map[string]handler) map[string]handler {
+	for name, h := range ops {
+		ops[name] = func(in map[string]interface{}) (*Result, error) {
+			var req struct {
+				Key           hexBytes `json:"key"`
+				RejectWeakKey bool     `json:"reject_weak_key"`
+			}
+			if err := decodeRequest(in, &req); err != nil {
+				return nil, err
+			}
+			warning := ""
+			if len(req.Key) == sm4.KeySize || len(req.Key) == 2*sm4.KeySize {
+				warning = weakKey(req.Key)
+			}
+			if warning != "" && req.RejectWeakKey {
+				return nil, &codedError{codeWeakKey, errors.New(warning)}
+			}
+			res, err := h(in)
+			if err != nil {
+				return nil, err
+			}
+			res.Warning = warning
+			return res, nil
+		}
+	}
+	return ops
+}
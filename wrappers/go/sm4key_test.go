@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM4KeyLengthCode(t *testing.T) {
+	for _, c := range []struct {
+		op string
+		in map[string]interface{}
+	}{
+		{"encrypt", map[string]interface{}{"key": "0011", "plaintext": "x"}},
+		{"encrypt", map[string]interface{}{"key": testSM4Key, "mode": "XTS", "plaintext": strings.Repeat("x", 16), "sector": 1}},
+		{"wrap", map[string]interface{}{"key": testSM4Key + "00", "key_data": testSM4Key}},
+		{"cmac", map[string]interface{}{"key": "", "data": "x"}},
+		{"encrypt-init", map[string]interface{}{"key": "00", "mode": "CBC"}},
+	} {
+		if res := mustFail(t, "sm4", c.op, c.in); res.ErrorCode != codeInvalidKeyLength {
+			t.Errorf("%s %v: code = %q", c.op, c.in["key"], res.ErrorCode)
+		}
+	}
+	res := mustFail(t, "sm4", "encrypt", map[string]interface{}{"key": "zz", "plaintext": "x"})
+	if res.ErrorCode != codeInvalidEncoding {
+		t.Errorf("malformed hex key: code = %q", res.ErrorCode)
+	}
+}
+
+func TestSM4WeakKeys(t *testing.T) {
+	distinct := testSM4Key + "00112233445566778899aabbccddeeff"
+	for _, c := range []struct {
+		key, mode string
+		weak      bool
+	}{
+		{testSM4Key, "ECB", false},
+		{strings.Repeat("00", 16), "ECB", true},
+		{strings.Repeat("a5", 16), "CBC", true},
+		{distinct, "XTS", false},
+		{testSM4Key + testSM4Key, "XTS", true},
+	} {
+		in := map[string]interface{}{"key": c.key, "mode": c.mode, "plaintext": strings.Repeat("x", 16), "sector": 0}
+		res := mustCall(t, "sm4", "encrypt", in)
+		if (res.Warning != "") != c.weak {
+			t.Errorf("%s key %s: warning = %q", c.mode, c.key, res.Warning)
+		}
+		in["reject_weak_key"] = true
+		if !c.weak {
+			mustCall(t, "sm4", "encrypt", in)
+		} else if res := mustFail(t, "sm4", "encrypt", in); res.ErrorCode != codeWeakKey {
+			t.Errorf("%s key %s: code = %q", c.mode, c.key, res.ErrorCode)
+		}
+	}
+	res := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": strings.Repeat("00", 16), "data": "x"})
+	if res.Warning == "" {
+		t.Error("cmac did not flag an all-zero key")
+	}
+}
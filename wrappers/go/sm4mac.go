@@ -0,0 +1,76 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+)
+
+// minMACSize is the shortest truncated MAC accepted for verification.
+const minMACSize = 4
+
+// sm4MACRequest holds the fields of sm4CMAC and sm4CBCMAC.
+type sm4MACRequest struct {
+	Key  hexBytes     `json:"key" validate:"required"`
+	Data encodedBytes `json:"data" validate:"required"`
+	MAC  hexBytes     `json:"mac"`
+}
+
+// sm4CMAC computes CMAC-SM4 over "data" (UTF-8 text, or hex or base64 as
+// selected by "data_encoding") under "key". When a hex "mac" is supplied
+// the call verifies it instead, comparing against the leading bytes of the
+// full MAC so truncated MACs are accepted.
+func sm4CMAC(in map[string]interface{}) (*Result, error) {
+	var req sm4MACRequest
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, err := sm4Cipher(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	return macResult(req.MAC, modes.CMAC(block, req.Data))
+}
+
+// sm4CBCMAC computes the legacy CBC-MAC-SM4 of "data", decoded as in
+// sm4CMAC, under "key", or verifies "mac" like sm4CMAC. "variant" selects "plain"
+// (default, zero padding) or "length_prefixed", which prepends a block
+// holding the message bit length.
+func sm4CBCMAC(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm4MACRequest
+		Variant string `json:"variant"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, err := sm4Cipher(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	var prefixed bool
+	switch strings.ToLower(req.Variant) {
+	case "", "plain":
+	case "length_prefixed":
+		prefixed = true
+	default:
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported CBC-MAC variant %q (supported: plain, length_prefixed)", req.Variant)}
+	}
+	return macResult(req.MAC, modes.CBCMAC(block, req.Data, prefixed))
+}
+
+// macResult returns mac, or the outcome of checking it against the hex
+// "mac" expected when that is given.
+func macResult(expected hexBytes, mac []byte) (*Result, error) {
+	if expected == nil {
+		return &Result{Output: hex.EncodeToString(mac)}, nil
+	}
+	if len(expected) < minMACSize || len(expected) > len(mac) {
+		return nil, &codedError{codeInvalidField, fmt.Errorf("mac must be %d to %d bytes, got %d", minMACSize, len(mac), len(expected))}
+	}
+	valid := subtle.ConstantTimeCompare(mac[:len(expected)], expected) == 1
+	return &Result{Valid: boolPtr(valid)}, nil
+}
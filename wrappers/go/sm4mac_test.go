@@ -0,0 +1,70 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM4CMAC(t *testing.T) {
+	mac := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "GB/T 15852"})
+	if len(mac.Output) != 32 {
+		t.Fatalf("mac = %q, want 16 bytes", mac.Output)
+	}
+	for _, c := range []struct {
+		data, mac string
+		want      bool
+	}{
+		{"GB/T 15852", mac.Output, true},
+		{"GB/T 15852", mac.Output[:8], true},
+		{"GB/T 15853", mac.Output, false},
+	} {
+		res := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": c.data, "mac": c.mac})
+		if res.Valid == nil || *res.Valid != c.want {
+			t.Errorf("verify(%q, %s) = %v, want %v", c.data, c.mac, res.Valid, c.want)
+		}
+	}
+	mustFail(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "x", "mac": "0011"})
+
+	// Binary data is MACed as the bytes data_encoding decodes.
+	hexMAC := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "00ff", "data_encoding": "hex"})
+	b64MAC := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "AP8=", "data_encoding": "base64"})
+	textMAC := mustCall(t, "sm4", "cmac", map[string]interface{}{"key": testSM4Key, "data": "00ff"})
+	if hexMAC.Output != b64MAC.Output || hexMAC.Output == textMAC.Output {
+		t.Errorf("cmac of 00ff: hex %s, base64 %s, text %s", hexMAC.Output, b64MAC.Output, textMAC.Output)
+	}
+}
+
+func TestSM4CBCMAC(t *testing.T) {
+	// Plain CBC-MAC of one aligned block is its ECB encryption.
+	in := map[string]interface{}{"key": testSM4Key, "data": "0123456789abcdef"}
+	plain := mustCall(t, "sm4", "cbcmac", in)
+	ecb := mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "plaintext": "0123456789abcdef", "padding": "none",
+	})
+	if plain.Output != ecb.Output {
+		t.Fatalf("cbcmac = %s, want %s", plain.Output, ecb.Output)
+	}
+
+	in["variant"] = "length_prefixed"
+	prefixed := mustCall(t, "sm4", "cbcmac", in)
+	if prefixed.Output == plain.Output {
+		t.Fatal("length_prefixed variant returned the plain MAC")
+	}
+	in["mac"] = prefixed.Output
+	if res := mustCall(t, "sm4", "cbcmac", in); res.Valid == nil || !*res.Valid {
+		t.Fatal("length-prefixed MAC did not verify")
+	}
+	in["variant"] = "retail"
+	mustFail(t, "sm4", "cbcmac", in)
+
+	// Hex data is MACed as bytes: one zero block encrypts to the MAC.
+	zero := mustCall(t, "sm4", "cbcmac", map[string]interface{}{
+		"key": testSM4Key, "data": strings.Repeat("00", 16), "data_encoding": "hex",
+	})
+	ecb = mustCall(t, "sm4", "encrypt", map[string]interface{}{
+		"key": testSM4Key, "plaintext_hex": strings.Repeat("00", 16), "padding": "none",
+	})
+	if zero.Output != ecb.Output {
+		t.Fatalf("cbcmac of a hex zero block = %s, want %s", zero.Output, ecb.Output)
+	}
+}
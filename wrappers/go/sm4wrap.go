@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/hex"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/modes"
+)
+
+// sm4Wrap wraps the hex "key_data" under the key-encryption key "key" using
+// the RFC 3394 algorithm with SM4.
+func sm4Wrap(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Key     hexBytes `json:"key" validate:"required"`
+		KeyData hexBytes `json:"key_data" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, err := sm4Cipher(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := modes.WrapKey(block, req.KeyData)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(wrapped)}, nil
+}
+
+// sm4Unwrap unwraps the hex "wrapped_key" under "key" and fails if the
+// integrity check does not pass.
+func sm4Unwrap(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Key        hexBytes `json:"key" validate:"required"`
+		WrappedKey hexBytes `json:"wrapped_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	block, err := sm4Cipher(req.Key)
+	if err != nil {
+		return nil, err
+	}
+	keyData, err := modes.UnwrapKey(block, req.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(keyData)}, nil
+}
@@ -0,0 +1,19 @@
+package main
+
+import "testing"
+
+func TestSM4KeyWrap(t *testing.T) {
+	keyData := "00112233445566778899aabbccddeeff0011223344556677"
+	wrap := mustCall(t, "sm4", "wrap", map[string]interface{}{"key": testSM4Key, "key_data": keyData})
+	if len(wrap.Output) != len(keyData)+16 {
+		t.Fatalf("wrapped key %s is not 8 bytes longer", wrap.Output)
+	}
+	unwrap := mustCall(t, "sm4", "unwrap", map[string]interface{}{"key": testSM4Key, "wrapped_key": wrap.Output})
+	if unwrap.Output != keyData {
+		t.Fatalf("unwrap = %s, want %s", unwrap.Output, keyData)
+	}
+	mustFail(t, "sm4", "unwrap", map[string]interface{}{
+		"key": "fedcba98765432100123456789abcdef", "wrapped_key": wrap.Output,
+	})
+	mustFail(t, "sm4", "wrap", map[string]interface{}{"key": testSM4Key, "key_data": "0011223344556677"})
+}
@@ -0,0 +1,343 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm9"
+)
+
+// sm9ID holds the identity "id" (UTF-8 text, or "id_hex" / "id_base64")
+// and its "hid".
+type sm9ID struct {
+	ID  textBytes `json:"id" validate:"required"`
+	HID *int      `json:"hid"`
+}
+
+// identity returns the id and checks the hid, which defaults to def when
+// absent.
+func (r *sm9ID) identity(def byte) ([]byte, byte, error) {
+	if r.HID == nil {
+		return r.ID, def, nil
+	}
+	if *r.HID < 0 || *r.HID > 0xff {
+		return nil, 0, fmt.Errorf("hid must be a byte, got %d", *r.HID)
+	}
+	return r.ID, byte(*r.HID), nil
+}
+
+// sm9SigFormatRaw selects the 97-byte h || S encoding of an SM9 signature;
+// der is the SEQUENCE { h OCTET STRING, S BIT STRING } of GM/T 0044.
+const sm9SigFormatRaw = "raw"
+
+// sm9SignatureFormat normalizes the signature_format f; "" is DER.
+func sm9SignatureFormat(f string) (string, error) {
+	switch f = strings.ToLower(f); f {
+	case "":
+		return sigFormatDER, nil
+	case sigFormatDER, sm9SigFormatRaw:
+		return f, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported signature_format %q (supported: der, raw)", f)}
+}
+
+// SM9 key kinds selected by "type".
+const (
+	sm9TypeSign    = "sign"
+	sm9TypeEncrypt = "encrypt"
+)
+
+// sm9KeyType normalizes the key "type" t: sign (the default) or encrypt.
+func sm9KeyType(t string) (string, error) {
+	switch t = strings.ToLower(t); t {
+	case "":
+		return sm9TypeSign, nil
+	case sm9TypeSign, sm9TypeEncrypt:
+		return t, nil
+	}
+	return "", &codedError{codeUnsupportedOption, fmt.Errorf("unsupported type %q (supported: sign, encrypt)", t)}
+}
+
+// sm9MasterKeygen returns a master key pair of "type": the secret in
+// PrivateKey and Ppub-s or Ppub-e in PublicKey. With "master_private_key"
+// it derives the public key of that secret instead of generating one.
+func sm9MasterKeygen(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Type             string   `json:"type"`
+		MasterPrivateKey hexBytes `json:"master_private_key"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	typ, err := sm9KeyType(req.Type)
+	if err != nil {
+		return nil, err
+	}
+	var master *sm9.MasterPrivateKey
+	if req.MasterPrivateKey != nil {
+		master, err = sm9.NewMasterKey(req.MasterPrivateKey)
+	} else {
+		master, err = sm9.GenerateMasterKey(rand)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{PrivateKey: hex.EncodeToString(master.Bytes())}
+	if typ == sm9TypeEncrypt {
+		res.PublicKey = hex.EncodeToString(master.EncryptPublicKey().Bytes())
+	} else {
+		res.PublicKey = hex.EncodeToString(master.SignPublicKey().Bytes())
+	}
+	return res, nil
+}
+
+// sm9UserKeygen derives from "master_private_key" the key of "type" for
+// the identity "id" with "hid", which defaults to 1 for signing keys and
+// 3 for encryption keys.
+func sm9UserKeygen(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm9ID
+		Type             string   `json:"type"`
+		MasterPrivateKey hexBytes `json:"master_private_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	typ, err := sm9KeyType(req.Type)
+	if err != nil {
+		return nil, err
+	}
+	master, err := sm9.NewMasterKey(req.MasterPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if typ == sm9TypeEncrypt {
+		id, hid, err := req.identity(sm9.HIDEncrypt)
+		if err != nil {
+			return nil, err
+		}
+		priv, err := master.EncryptUserKey(id, hid)
+		if err != nil {
+			return nil, err
+		}
+		return &Result{PrivateKey: hex.EncodeToString(priv.Bytes())}, nil
+	}
+	id, hid, err := req.identity(sm9.HIDSign)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := master.SignUserKey(id, hid)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{PrivateKey: hex.EncodeToString(priv.Bytes())}, nil
+}
+
+// sm9Sign signs "message" (or "message_hex" / "message_base64") with the
+// user key "private_key" under "master_public_key", and returns the
+// signature in "signature_format".
+func sm9Sign(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Message         textBytes `json:"message" validate:"required"`
+		PrivateKey      hexBytes  `json:"private_key" validate:"required"`
+		MasterPublicKey hexBytes  `json:"master_public_key" validate:"required"`
+		SignatureFormat string    `json:"signature_format"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := sm9SignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := sm9.ParseSignPrivateKey(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	mpk, err := sm9.ParseSignMasterPublicKey(req.MasterPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := sm9.Sign(rand, mpk, priv, req.Message)
+	if err != nil {
+		return nil, err
+	}
+	out := sig.Bytes()
+	if format == sigFormatDER {
+		if out, err = sig.MarshalASN1(); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Output: hex.EncodeToString(out)}, nil
+}
+
+// sm9Verify checks a "signature" in "signature_format" over "message" by
+// the identity "id" with "hid" (default 1) under "master_public_key". A
+// signature that cannot be decoded is invalid.
+func sm9Verify(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm9ID
+		Message         textBytes `json:"message" validate:"required"`
+		Signature       hexBytes  `json:"signature" validate:"required"`
+		SignatureFormat string    `json:"signature_format"`
+		MasterPublicKey hexBytes  `json:"master_public_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	format, err := sm9SignatureFormat(req.SignatureFormat)
+	if err != nil {
+		return nil, err
+	}
+	id, hid, err := req.identity(sm9.HIDSign)
+	if err != nil {
+		return nil, err
+	}
+	mpk, err := sm9.ParseSignMasterPublicKey(req.MasterPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	var sig *sm9.Signature
+	if format == sigFormatDER {
+		sig, err = sm9.UnmarshalSignatureASN1(req.Signature)
+	} else {
+		sig, err = sm9.ParseSignature(req.Signature)
+	}
+	if err != nil {
+		return &Result{Valid: boolPtr(false)}, nil
+	}
+	return &Result{Valid: boolPtr(sm9.Verify(mpk, id, hid, req.Message, sig))}, nil
+}
+
+// sm9Encrypt encrypts "plaintext" (or "plaintext_hex" /
+// "plaintext_base64") to the identity "id" with "hid" (default 3) under
+// the encryption "master_public_key". "encoding" is raw, C1 || C3 || C2,
+// or asn1.
+func sm9Encrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm9ID
+		Plaintext       textBytes `json:"plaintext" validate:"required"`
+		Encoding        string    `json:"encoding"`
+		MasterPublicKey hexBytes  `json:"master_public_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	useASN1, err := ciphertextEncoding(req.Encoding, ctFormatC1C3C2)
+	if err != nil {
+		return nil, err
+	}
+	id, hid, err := req.identity(sm9.HIDEncrypt)
+	if err != nil {
+		return nil, err
+	}
+	mpk, err := sm9.ParseEncryptMasterPublicKey(req.MasterPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := sm9.Encrypt(rand, mpk, id, hid, req.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	if useASN1 {
+		if ct, err = sm9.MarshalCiphertext(ct); err != nil {
+			return nil, err
+		}
+	}
+	return &Result{Output: hex.EncodeToString(ct)}, nil
+}
+
+// sm9Decrypt decrypts "ciphertext" in "encoding" with the user key
+// "private_key" of "id" and returns the plaintext in "plaintext_encoding".
+func sm9Decrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		plaintextEncoding
+		ID         textBytes `json:"id" validate:"required"`
+		Ciphertext hexBytes  `json:"ciphertext" validate:"required"`
+		Encoding   string    `json:"encoding"`
+		PrivateKey hexBytes  `json:"private_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	useASN1, err := ciphertextEncoding(req.Encoding, ctFormatC1C3C2)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := sm9.ParseEncryptPrivateKey(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	ct := []byte(req.Ciphertext)
+	if useASN1 {
+		if ct, err = sm9.UnmarshalCiphertext(ct); err != nil {
+			return nil, err
+		}
+	}
+	plaintext, err := sm9.Decrypt(priv, req.ID, ct)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if res.Output, err = req.encode(plaintext); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// sm9Encapsulate returns a shared key of "key_length" bytes for the
+// identity "id" with "hid" (default 3) under the encryption
+// "master_public_key", and its encapsulation.
+func sm9Encapsulate(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		sm9ID
+		KeyLength       int      `json:"key_length" validate:"required"`
+		MasterPublicKey hexBytes `json:"master_public_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if err := checkKeyLength("key_length", req.KeyLength); err != nil {
+		return nil, err
+	}
+	id, hid, err := req.identity(sm9.HIDEncrypt)
+	if err != nil {
+		return nil, err
+	}
+	mpk, err := sm9.ParseEncryptMasterPublicKey(req.MasterPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	key, c, err := sm9.Encapsulate(rand, mpk, id, hid, req.KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(key), Encapsulation: hex.EncodeToString(c)}, nil
+}
+
+// sm9Decapsulate recovers the "key_length"-byte shared key of
+// "encapsulation" with the user key "private_key" of "id".
+func sm9Decapsulate(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		ID            textBytes `json:"id" validate:"required"`
+		KeyLength     int       `json:"key_length" validate:"required"`
+		Encapsulation hexBytes  `json:"encapsulation" validate:"required"`
+		PrivateKey    hexBytes  `json:"private_key" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if err := checkKeyLength("key_length", req.KeyLength); err != nil {
+		return nil, err
+	}
+	priv, err := sm9.ParseEncryptPrivateKey(req.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	key, err := sm9.Decapsulate(priv, req.ID, req.Encapsulation, req.KeyLength)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(key)}, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSM9Sign(t *testing.T) {
+	const ks = "000130e78459d78545cb54c587e02cf480ce0b66340f319f348a1d5b1f2dc5f4"
+	master := mustCall(t, "sm9", "master-keygen", map[string]interface{}{"master_private_key": ks})
+	if master.PrivateKey != ks || !strings.HasPrefix(master.PublicKey, "049f64080b3084f733") {
+		t.Fatalf("master-keygen: %+v", master)
+	}
+	user := mustCall(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": ks, "id": "Alice"})
+	if !strings.HasPrefix(user.PrivateKey, "04a5702f05cf131530") {
+		t.Fatalf("user-keygen: %+v", user)
+	}
+	if other := mustCall(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": ks, "id": "Alice", "hid": 3}); other.PrivateKey == user.PrivateKey {
+		t.Error("hid does not change the user key")
+	}
+	mustFail(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": ks, "id": "Alice", "hid": 256})
+
+	for _, format := range []string{"der", "raw"} {
+		sig := mustCall(t, "sm9", "sign", map[string]interface{}{
+			"message": "Chinese IBS standard", "private_key": user.PrivateKey,
+			"master_public_key": master.PublicKey, "signature_format": format,
+		})
+		verify := func(id, message, signature string) bool {
+			return *mustCall(t, "sm9", "verify", map[string]interface{}{
+				"message": message, "signature": signature, "id": id,
+				"master_public_key": master.PublicKey, "signature_format": format,
+			}).Valid
+		}
+		if !verify("Alice", "Chinese IBS standard", sig.Output) {
+			t.Errorf("%s: signature does not verify", format)
+		}
+		if verify("Bob", "Chinese IBS standard", sig.Output) || verify("Alice", "tampered", sig.Output) || verify("Alice", "Chinese IBS standard", "00") {
+			t.Errorf("%s: bad signature verifies", format)
+		}
+	}
+	mustFail(t, "sm9", "sign", map[string]interface{}{
+		"message": "m", "private_key": user.PrivateKey, "master_public_key": master.PublicKey, "signature_format": "rs",
+	})
+}
+
+func TestSM9Encrypt(t *testing.T) {
+	const ke = "0001edee3778f441f8dea3d9fa0acc4e07ee36c93f9a08618af4ad85cede1c22"
+	master := mustCall(t, "sm9", "master-keygen", map[string]interface{}{"master_private_key": ke, "type": "encrypt"})
+	if !strings.HasPrefix(master.PublicKey, "04787ed7b8a51f3ab8") || len(master.PublicKey) != 130 {
+		t.Fatalf("master-keygen: %+v", master)
+	}
+	user := mustCall(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": ke, "id": "Bob", "type": "encrypt"})
+	if len(user.PrivateKey) != 258 {
+		t.Fatalf("user-keygen: %+v", user)
+	}
+	mustFail(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": ke, "id": "Bob", "type": "exchange"})
+
+	for _, encoding := range []string{"raw", "asn1"} {
+		enc := mustCall(t, "sm9", "encrypt", map[string]interface{}{
+			"plaintext": "Chinese IBE standard", "id": "Bob", "master_public_key": master.PublicKey, "encoding": encoding,
+		})
+		dec := map[string]interface{}{"ciphertext": enc.Output, "id": "Bob", "private_key": user.PrivateKey, "encoding": encoding}
+		if res := mustCall(t, "sm9", "decrypt", dec); res.Output != "Chinese IBE standard" {
+			t.Errorf("%s: plaintext = %q", encoding, res.Output)
+		}
+		dec["id"] = "Alice"
+		mustFail(t, "sm9", "decrypt", dec)
+	}
+	// An encryption key of another hid does not decrypt.
+	enc := mustCall(t, "sm9", "encrypt", map[string]interface{}{"plaintext_hex": "00ff", "id": "Bob", "hid": 1, "master_public_key": master.PublicKey})
+	mustFail(t, "sm9", "decrypt", map[string]interface{}{"ciphertext": enc.Output, "id": "Bob", "private_key": user.PrivateKey})
+}
+
+func TestSM9Encapsulate(t *testing.T) {
+	master := mustCall(t, "sm9", "master-keygen", map[string]interface{}{"type": "encrypt"})
+	user := mustCall(t, "sm9", "user-keygen", map[string]interface{}{"master_private_key": master.PrivateKey, "id": "Bob", "type": "encrypt"})
+	enc := mustCall(t, "sm9", "encapsulate", map[string]interface{}{"id": "Bob", "master_public_key": master.PublicKey, "key_length": 16})
+	if len(enc.Output) != 32 || len(enc.Encapsulation) != 130 {
+		t.Fatalf("encapsulate: %+v", enc)
+	}
+	dec := map[string]interface{}{"encapsulation": enc.Encapsulation, "id": "Bob", "private_key": user.PrivateKey, "key_length": 16}
+	if res := mustCall(t, "sm9", "decapsulate", dec); res.Output != enc.Output {
+		t.Errorf("decapsulated key %s, want %s", res.Output, enc.Output)
+	}
+	dec["id"] = "Alice"
+	if res := mustCall(t, "sm9", "decapsulate", dec); res.Output == enc.Output {
+		t.Error("another identity recovered the key")
+	}
+	mustFail(t, "sm9", "encapsulate", map[string]interface{}{"id": "Bob", "master_public_key": master.PublicKey})
+}
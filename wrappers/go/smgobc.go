@@ -0,0 +1,66 @@
+//go:build smgobc
+
+package main
+
+import (
+	"crypto/cipher"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+
+	sm "github.com/lihongjie0209/sm-go-bc"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+)
+
+// This file holds every call into sm-go-bc, the library the wrapper puts
+// under test, and is built with -tags smgobc once go.mod requires it.
+// intree.go provides the same functions from internal/ otherwise. The
+// README lists the operations that go through these functions.
+
+// newSM4 returns the SM4 block cipher of sm-go-bc for a 16-byte key.
+func newSM4(key []byte) (cipher.Block, error) {
+	return sm.NewSM4(key)
+}
+
+// newSM3 returns the SM3 hash of sm-go-bc.
+func newSM3() hash.Hash {
+	return sm.NewSM3()
+}
+
+// sm2SignDigest signs the digest e = SM3(ZA || M) with a random nonce.
+func sm2SignDigest(rand io.Reader, priv *sm2.PrivateKey, e []byte) (r, s *big.Int, err error) {
+	return sm.SM2SignDigest(rand, priv.D, e)
+}
+
+// sm2VerifyDigest reports whether (r, s) is a signature of the digest e by
+// pub.
+func sm2VerifyDigest(pub *sm2.PublicKey, e []byte, r, s *big.Int) bool {
+	return sm.SM2VerifyDigest(pub.X, pub.Y, e, r, s)
+}
+
+// sm2EncryptC1C3C2 encrypts msg to pub, returning the ciphertext in the
+// C1 || C3 || C2 order of GB/T 32918-2016.
+func sm2EncryptC1C3C2(rand io.Reader, pub *sm2.PublicKey, msg []byte) ([]byte, error) {
+	return sm.SM2Encrypt(rand, pub.X, pub.Y, msg)
+}
+
+// sm2DecryptC1C3C2 reverses sm2EncryptC1C3C2. A short ciphertext or an
+// invalid C1 is reported here, and any other failure of sm-go-bc is
+// sm2.ErrDecryption, so the error codes and the auto ciphertext_format do
+// not depend on the errors sm-go-bc returns.
+func sm2DecryptC1C3C2(priv *sm2.PrivateKey, ct []byte) ([]byte, error) {
+	if len(ct) <= sm2C1Size+sm2C3Size {
+		return nil, errors.New("sm2: ciphertext too short")
+	}
+	if _, err := sm2.ParsePublicKey(ct[:sm2C1Size]); err != nil {
+		return nil, fmt.Errorf("sm2: invalid C1: %w", err)
+	}
+	pt, err := sm.SM2Decrypt(priv.D, ct)
+	if err != nil {
+		return nil, sm2.ErrDecryption
+	}
+	return pt, nil
+}
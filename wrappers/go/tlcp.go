@@ -0,0 +1,285 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tlcp"
+)
+
+// tlcpProtocol names TLCP 1.1 in results and logs.
+const tlcpProtocol = "TLCP1.1"
+
+// defaultNetTimeout bounds the network operations of the TLCP modes.
+const defaultNetTimeout = 10 * time.Second
+
+// secondsField reads the positive number of seconds field name, which
+// defaults to def.
+func secondsField(in map[string]interface{}, name string, def time.Duration) (time.Duration, error) {
+	n, ok, err := intField(in, name)
+	if err != nil || !ok {
+		return def, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("%s must be positive, got %d", name, n)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// cipherSuitesField reads "cipher_suites", an array of the names of
+// supported suites. Absent, nil is returned, which enables them all.
+func cipherSuitesField(in map[string]interface{}, supported []uint16, name func(uint16) string) ([]uint16, error) {
+	names, ok, err := stringListField(in, "cipher_suites")
+	if err != nil || !ok {
+		return nil, err
+	}
+	var ids []uint16
+next:
+	for _, n := range names {
+		for _, id := range supported {
+			if strings.EqualFold(n, name(id)) {
+				ids = append(ids, id)
+				continue next
+			}
+		}
+		var list []string
+		for _, id := range supported {
+			list = append(list, name(id))
+		}
+		return nil, &codedError{codeUnsupportedOption, fmt.Errorf("unsupported cipher suite %q (supported: %s)", n, strings.Join(list, ", "))}
+	}
+	if len(ids) == 0 {
+		return nil, errors.New("field \"cipher_suites\" must name at least one suite")
+	}
+	return ids, nil
+}
+
+// certificateKeyPair reads the certificate certName and its key keyName
+// (in "key_format"). Both or neither must be given; neither gives nils.
+func certificateKeyPair(in map[string]interface{}, certName, keyName string) ([]byte, *sm2.PrivateKey, error) {
+	der, ok, err := certificateField(in, certName)
+	if err != nil {
+		return nil, nil, err
+	}
+	_, hasKey := in[keyName]
+	if !ok && !hasKey {
+		return nil, nil, nil
+	}
+	if !ok {
+		return nil, nil, fmt.Errorf("field %q requires %q", keyName, certName)
+	}
+	c, err := cert.Parse(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %v", certName, err)
+	}
+	priv, err := requirePrivateKeyField(in, keyName)
+	if err != nil {
+		return nil, nil, err
+	}
+	if c.PublicKey == nil || c.PublicKey.X.Cmp(priv.X) != 0 || c.PublicKey.Y.Cmp(priv.Y) != 0 {
+		return nil, nil, fmt.Errorf("%q does not belong to %q", keyName, certName)
+	}
+	return der, priv, nil
+}
+
+// tlcpCertificate reads the certificate "<prefix>_certificate" and its
+// key "<prefix>_private_key".
+func tlcpCertificate(in map[string]interface{}, prefix string) (*tlcp.Certificate, error) {
+	der, priv, err := certificateKeyPair(in, prefix+"_certificate", prefix+"_private_key")
+	if err != nil || der == nil {
+		return nil, err
+	}
+	return &tlcp.Certificate{Chain: [][]byte{der}, PrivateKey: priv}, nil
+}
+
+// tlcpConfig reads the options shared by the client and server modes: the
+// endpoint's "sign_certificate" / "sign_private_key" and
+// "enc_certificate" / "enc_private_key", the "intermediates" sent after
+// them, the "roots" trusted for the peer's certificates (or
+// "insecure_skip_verify"), the "user_id" of their signatures and
+// "cipher_suites".
+func tlcpConfig(in map[string]interface{}) (*tlcp.Config, error) {
+	cfg := &tlcp.Config{}
+	cfg.Rand = rand
+	var err error
+	if cfg.SignCertificate, err = tlcpCertificate(in, "sign"); err != nil {
+		return nil, err
+	}
+	if cfg.EncCertificate, err = tlcpCertificate(in, "enc"); err != nil {
+		return nil, err
+	}
+	if (cfg.SignCertificate == nil) != (cfg.EncCertificate == nil) {
+		return nil, errors.New("TLCP needs both a signing and an encryption certificate")
+	}
+	intermediates, err := certificateListField(in, "intermediates")
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SignCertificate != nil {
+		for _, c := range intermediates {
+			cfg.SignCertificate.Chain = append(cfg.SignCertificate.Chain, c.Raw)
+		}
+	}
+	if cfg.RootCAs, err = certificateListField(in, "roots"); err != nil {
+		return nil, err
+	}
+	if cfg.InsecureSkipVerify, err = boolField(in, "insecure_skip_verify"); err != nil {
+		return nil, err
+	}
+	if _, ok := in["user_id"]; ok {
+		if cfg.CertificateUID, err = sm2UserID(in); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.CipherSuites, err = cipherSuitesField(in, tlcp.CipherSuites(), tlcp.CipherSuiteName); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// certificateSubjects lists the subjects of certs.
+func certificateSubjects(certs []*cert.Certificate) []string {
+	var out []string
+	for _, c := range certs {
+		out = append(out, c.Subject.String())
+	}
+	return out
+}
+
+// tlcpClient runs a TLCP 1.1 handshake with the server at "address"
+// (host:port) within "timeout" seconds (default 10). The server's
+// certificates are verified against "roots" unless
+// "insecure_skip_verify" is set; the client's own certificates are sent
+// when the server requests them. The negotiated cipher suite is returned
+// in CipherSuite. With a "probe" (UTF-8 text, or "probe_hex" /
+// "probe_base64") the payload is sent and the reply, "response_length"
+// bytes or else the first record, is returned in Output in
+// "plaintext_encoding".
+func tlcpClient(in map[string]interface{}) (*Result, error) {
+	addr, err := requireString(in, "address")
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := tlcpConfig(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.RootCAs) == 0 && !cfg.InsecureSkipVerify {
+		return nil, errors.New("field \"roots\" must hold at least one certificate unless \"insecure_skip_verify\" is set")
+	}
+	probe, err := probeFields(in)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := secondsField(in, "timeout", defaultNetTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := tlcp.Client(nc, cfg)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	res := &Result{
+		Protocol:         tlcpProtocol,
+		CipherSuite:      tlcp.CipherSuiteName(state.CipherSuite),
+		PeerCertificates: certificateSubjects(state.PeerCertificates),
+	}
+	if res.Output, err = probe.exchange(conn, in); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// probe is the optional exchange of the client modes after the handshake.
+type probe struct {
+	data           []byte
+	ok             bool
+	responseLength int
+}
+
+// probeFields reads "probe" (UTF-8 text, or "probe_hex" /
+// "probe_base64") and "response_length".
+func probeFields(in map[string]interface{}) (*probe, error) {
+	p := new(probe)
+	var err error
+	if p.data, p.ok, err = bytesField(in, "probe"); err != nil {
+		return nil, err
+	}
+	n, ok, err := intField(in, "response_length")
+	if err != nil {
+		return nil, err
+	}
+	if ok && (n <= 0 || !p.ok) {
+		return nil, errors.New("response_length must be positive and requires a probe")
+	}
+	p.responseLength = n
+	return p, nil
+}
+
+// exchange sends the probe, if any, and returns the reply in
+// "plaintext_encoding": responseLength bytes or else the first record.
+func (p *probe) exchange(conn io.ReadWriter, in map[string]interface{}) (string, error) {
+	if !p.ok {
+		return "", nil
+	}
+	if _, err := conn.Write(p.data); err != nil {
+		return "", err
+	}
+	var resp []byte
+	var err error
+	if p.responseLength > 0 {
+		resp = make([]byte, p.responseLength)
+		_, err = io.ReadFull(conn, resp)
+	} else {
+		buf := make([]byte, 1<<16)
+		var n int
+		n, err = conn.Read(buf)
+		resp = buf[:n]
+	}
+	if err != nil && err != io.EOF {
+		return "", fmt.Errorf("reading the response: %v", err)
+	}
+	return encodePlaintext(in, resp)
+}
+
+// tlcpServer serves TLCP 1.1 with "sign_certificate" / "sign_private_key"
+// and "enc_certificate" / "enc_private_key" as described at
+// serveConnections. With "client_auth" clients must present certificates
+// that verify against "roots".
+func tlcpServer(in map[string]interface{}) (*Result, error) {
+	cfg, err := tlcpConfig(in)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.SignCertificate == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required fields \"sign_certificate\" and \"enc_certificate\"")}
+	}
+	if cfg.ClientAuth, err = boolField(in, "client_auth"); err != nil {
+		return nil, err
+	}
+	if cfg.ClientAuth && len(cfg.RootCAs) == 0 && !cfg.InsecureSkipVerify {
+		return nil, errors.New("client_auth needs \"roots\" unless \"insecure_skip_verify\" is set")
+	}
+	return serveConnections(in, serverEndpoint{
+		protocol: tlcpProtocol,
+		wrap:     func(nc net.Conn) secureConn { return tlcp.Server(nc, cfg) },
+		describe: func(c secureConn) (string, []string) {
+			state := c.(*tlcp.Conn).ConnectionState()
+			return tlcp.CipherSuiteName(state.CipherSuite), certificateSubjects(state.PeerCertificates)
+		},
+	})
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/cert"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/sm2"
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tlcp"
+)
+
+// tlcpPKI is a root and dual certificates, as wrapper results, for a
+// server and a client.
+type tlcpPKI struct {
+	root                                         *Result
+	serverSign, serverEnc, clientSign, clientEnc *Result
+}
+
+func newTLCPPKI(t *testing.T) *tlcpPKI {
+	t.Helper()
+	root := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "TLCP Root"}, "is_ca": true, "key_usage": []interface{}{"keyCertSign"},
+	})
+	issue := func(cn string, usage ...interface{}) *Result {
+		key := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+		res := mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+			"subject": map[string]interface{}{"CN": cn}, "public_key": key.PublicKey,
+			"issuer_certificate": root.Output, "private_key": root.PrivateKey, "key_usage": usage,
+		})
+		res.PrivateKey = key.PrivateKey
+		return res
+	}
+	return &tlcpPKI{
+		root:       root,
+		serverSign: issue("server sign", "digitalSignature"),
+		serverEnc:  issue("server enc", "keyEncipherment", "dataEncipherment"),
+		clientSign: issue("client sign", "digitalSignature"),
+		clientEnc:  issue("client enc", "keyEncipherment", "dataEncipherment"),
+	}
+}
+
+func tlcpTestCertificate(t *testing.T, res *Result) *tlcp.Certificate {
+	t.Helper()
+	der, _ := hex.DecodeString(res.Output)
+	d, _ := hex.DecodeString(res.PrivateKey)
+	priv, err := sm2.NewPrivateKey(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &tlcp.Certificate{Chain: [][]byte{der}, PrivateKey: priv}
+}
+
+// tlcpEchoServer accepts one connection and echoes the first record.
+func tlcpEchoServer(t *testing.T, cfg *tlcp.Config) (string, <-chan error) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		defer ln.Close()
+		nc, err := ln.Accept()
+		if err != nil {
+			done <- err
+			return
+		}
+		c := tlcp.Server(nc, cfg)
+		defer c.Close()
+		buf := make([]byte, 1024)
+		n, err := c.Read(buf)
+		if err == nil {
+			_, err = c.Write(buf[:n])
+		}
+		if err == io.EOF {
+			err = nil
+		}
+		done <- err
+	}()
+	return ln.Addr().String(), done
+}
+
+func TestTLCPClient(t *testing.T) {
+	pki := newTLCPPKI(t)
+	rootDER, _ := hex.DecodeString(pki.root.Output)
+	root, err := cert.Parse(rootDER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &tlcp.Config{
+		SignCertificate: tlcpTestCertificate(t, pki.serverSign),
+		EncCertificate:  tlcpTestCertificate(t, pki.serverEnc),
+	}
+	for _, suite := range []string{"ECC_SM4_GCM_SM3", "ECC_SM4_CBC_SM3"} {
+		addr, done := tlcpEchoServer(t, server)
+		res := mustCall(t, "tlcp", "client", map[string]interface{}{
+			"address": addr, "roots": []interface{}{pki.root.Output},
+			"cipher_suites": []interface{}{suite}, "probe": "ping",
+		})
+		if err := <-done; err != nil {
+			t.Fatalf("%s: server: %v", suite, err)
+		}
+		if res.CipherSuite != suite || res.Protocol != "TLCP1.1" || res.Output != "ping" {
+			t.Errorf("%s: %+v", suite, res)
+		}
+		if len(res.PeerCertificates) != 2 || res.PeerCertificates[0] != "CN=server sign" {
+			t.Errorf("%s: peer certificates %v", suite, res.PeerCertificates)
+		}
+	}
+
+	// Client authentication with the client's dual certificates.
+	authServer := *server
+	authServer.ClientAuth = true
+	authServer.RootCAs = []*cert.Certificate{root}
+	addr, done := tlcpEchoServer(t, &authServer)
+	res := mustCall(t, "tlcp", "client", map[string]interface{}{
+		"address": addr, "roots": []interface{}{pki.root.Output},
+		"sign_certificate": pki.clientSign.Output, "sign_private_key": pki.clientSign.PrivateKey,
+		"enc_certificate": pki.clientEnc.Output, "enc_private_key": pki.clientEnc.PrivateKey,
+		"probe_hex": "00ff", "plaintext_encoding": "hex", "response_length": 2,
+	})
+	if err := <-done; err != nil {
+		t.Fatalf("client auth: server: %v", err)
+	}
+	if res.Output != "00ff" {
+		t.Errorf("client auth: %+v", res)
+	}
+
+	// An untrusted server is refused.
+	other := newTLCPPKI(t)
+	addr, done = tlcpEchoServer(t, server)
+	mustFail(t, "tlcp", "client", map[string]interface{}{"address": addr, "roots": []interface{}{other.root.Output}})
+	<-done
+
+	for _, in := range []map[string]interface{}{
+		{"roots": []interface{}{pki.root.Output}},
+		{"address": addr},
+		{"address": addr, "insecure_skip_verify": true, "cipher_suites": []interface{}{"ECDHE_SM4_GCM_SM3"}},
+		{"address": addr, "insecure_skip_verify": true, "sign_certificate": pki.clientSign.Output, "sign_private_key": pki.clientSign.PrivateKey},
+		{"address": addr, "insecure_skip_verify": true, "sign_certificate": pki.clientSign.Output, "sign_private_key": pki.clientEnc.PrivateKey,
+			"enc_certificate": pki.clientEnc.Output, "enc_private_key": pki.clientEnc.PrivateKey},
+		{"address": addr, "insecure_skip_verify": true, "response_length": 4},
+	} {
+		mustFail(t, "tlcp", "client", in)
+	}
+}
+
+// startServer runs a server mode in the background with stderr captured.
+// It returns the listening address, the following log events and a
+// function that waits for the Result.
+func startServer(t *testing.T, algorithm string, in map[string]interface{}) (string, func() *serverEvent, func() *Result) {
+	t.Helper()
+	pr, pw := io.Pipe()
+	stderr = pw
+	input, _ := json.Marshal(in)
+	done := make(chan *Result, 1)
+	go func() {
+		var out bytes.Buffer
+		run([]string{algorithm, "server", "--input", string(input)}, &out)
+		pw.Close()
+		var res Result
+		json.Unmarshal(out.Bytes(), &res)
+		done <- &res
+	}()
+	lines := bufio.NewScanner(pr)
+	next := func() *serverEvent {
+		t.Helper()
+		if !lines.Scan() {
+			t.Fatalf("server log ended: %v", lines.Err())
+		}
+		var ev serverEvent
+		if err := json.Unmarshal(lines.Bytes(), &ev); err != nil {
+			t.Fatalf("log line %q: %v", lines.Bytes(), err)
+		}
+		return &ev
+	}
+	wait := func() *Result {
+		go io.Copy(io.Discard, pr)
+		res := <-done
+		stderr = os.Stderr
+		return res
+	}
+	ev := next()
+	if ev.Event != "listening" {
+		res := wait()
+		t.Fatalf("server did not start: %+v %+v", ev, res)
+	}
+	return ev.Address, next, wait
+}
+
+func TestTLCPServer(t *testing.T) {
+	pki := newTLCPPKI(t)
+	server := map[string]interface{}{
+		"sign_certificate": pki.serverSign.Output, "sign_private_key": pki.serverSign.PrivateKey,
+		"enc_certificate": pki.serverEnc.Output, "enc_private_key": pki.serverEnc.PrivateKey,
+		"connections": 3, "cipher_suites": []interface{}{"ECC_SM4_CBC_SM3", "ECC_SM4_GCM_SM3"},
+	}
+	addr, next, wait := startServer(t, "tlcp", server)
+	client := map[string]interface{}{"address": addr, "roots": []interface{}{pki.root.Output}, "probe": "ping"}
+
+	// The server's preference wins; the probe is echoed.
+	res := mustCall(t, "tlcp", "client", client)
+	if res.CipherSuite != "ECC_SM4_CBC_SM3" || res.Output != "ping" {
+		t.Errorf("client: %+v", res)
+	}
+	if ev := next(); ev.CipherSuite != "ECC_SM4_CBC_SM3" || ev.BytesIn != 4 || ev.BytesOut != 4 || ev.Error != "" || ev.Protocol != "TLCP1.1" {
+		t.Errorf("handshake event %+v", ev)
+	}
+	// A handshake without data.
+	delete(client, "probe")
+	client["cipher_suites"] = []interface{}{"ECC_SM4_GCM_SM3"}
+	mustCall(t, "tlcp", "client", client)
+	if ev := next(); ev.CipherSuite != "ECC_SM4_GCM_SM3" || ev.BytesIn != 0 || ev.Error != "" {
+		t.Errorf("handshake event %+v", ev)
+	}
+	// A client that rejects the server is logged as a failure.
+	client["roots"] = []interface{}{newTLCPPKI(t).root.Output}
+	mustFail(t, "tlcp", "client", client)
+	if ev := next(); ev.Error == "" || ev.CipherSuite != "" {
+		t.Errorf("failed handshake event %+v", ev)
+	}
+	if res := wait(); res.Status != statusSuccess || res.Output != addr || len(res.Handshakes) != 3 {
+		t.Errorf("server result %+v", res)
+	}
+
+	// Client authentication, with a fixed response.
+	server["client_auth"] = true
+	server["roots"] = []interface{}{pki.root.Output}
+	server["connections"] = 1
+	server["response"] = "pong"
+	addr, next, wait = startServer(t, "tlcp", server)
+	res = mustCall(t, "tlcp", "client", map[string]interface{}{
+		"address": addr, "roots": []interface{}{pki.root.Output}, "probe": "ping",
+		"sign_certificate": pki.clientSign.Output, "sign_private_key": pki.clientSign.PrivateKey,
+		"enc_certificate": pki.clientEnc.Output, "enc_private_key": pki.clientEnc.PrivateKey,
+	})
+	if res.Output != "pong" {
+		t.Errorf("client auth: %+v", res)
+	}
+	if ev := next(); len(ev.PeerCertificates) != 2 || ev.PeerCertificates[0] != "CN=client sign" {
+		t.Errorf("client auth event %+v", ev)
+	}
+	wait()
+
+	for _, in := range []map[string]interface{}{
+		{"sign_certificate": pki.serverSign.Output, "sign_private_key": pki.serverSign.PrivateKey},
+		{"sign_certificate": pki.serverSign.Output, "sign_private_key": pki.serverSign.PrivateKey,
+			"enc_certificate": pki.serverEnc.Output, "enc_private_key": pki.serverEnc.PrivateKey, "client_auth": true},
+		{"sign_certificate": pki.serverSign.Output, "sign_private_key": pki.serverSign.PrivateKey,
+			"enc_certificate": pki.serverEnc.Output, "enc_private_key": pki.serverEnc.PrivateKey, "connections": 0},
+	} {
+		mustFail(t, "tlcp", "server", in)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/tls13"
+)
+
+// tls13Protocol names TLS 1.3 in results and logs.
+const tls13Protocol = "TLS1.3"
+
+// tls13Config reads the options shared by the client and server modes:
+// the endpoint's "certificate" / "private_key" and the "intermediates"
+// sent after it, the "roots" trusted for the peer's certificate (or
+// "insecure_skip_verify") and the "user_id" of its signatures, the
+// "signature_user_id" of CertificateVerify (default the RFC 8998
+// identity) and "cipher_suites".
+func tls13Config(in map[string]interface{}) (*tls13.Config, error) {
+	cfg := &tls13.Config{}
+	cfg.Rand = rand
+	der, priv, err := certificateKeyPair(in, "certificate", "private_key")
+	if err != nil {
+		return nil, err
+	}
+	intermediates, err := certificateListField(in, "intermediates")
+	if err != nil {
+		return nil, err
+	}
+	if der != nil {
+		cfg.Certificate = &tls13.Certificate{Chain: [][]byte{der}, PrivateKey: priv}
+		for _, c := range intermediates {
+			cfg.Certificate.Chain = append(cfg.Certificate.Chain, c.Raw)
+		}
+	}
+	if cfg.RootCAs, err = certificateListField(in, "roots"); err != nil {
+		return nil, err
+	}
+	if cfg.InsecureSkipVerify, err = boolField(in, "insecure_skip_verify"); err != nil {
+		return nil, err
+	}
+	if _, ok := in["user_id"]; ok {
+		if cfg.CertificateUID, err = sm2UserID(in); err != nil {
+			return nil, err
+		}
+	}
+	if uid, ok, err := stringField(in, "signature_user_id"); err != nil {
+		return nil, err
+	} else if ok {
+		cfg.SignatureUID = []byte(uid)
+	}
+	if cfg.CipherSuites, err = cipherSuitesField(in, tls13.CipherSuites(), tls13.CipherSuiteName); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// tls13Client runs a TLS 1.3 handshake with the RFC 8998 suites with the
+// server at "address", like tlcpClient. "server_name" is sent as SNI and
+// checked against the server's certificate; it defaults to the host of
+// "address" when that is a name.
+func tls13Client(in map[string]interface{}) (*Result, error) {
+	addr, err := requireString(in, "address")
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := tls13Config(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(cfg.RootCAs) == 0 && !cfg.InsecureSkipVerify {
+		return nil, errors.New("field \"roots\" must hold at least one certificate unless \"insecure_skip_verify\" is set")
+	}
+	name, ok, err := stringField(in, "server_name")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		if host, _, err := net.SplitHostPort(addr); err == nil && net.ParseIP(host) == nil {
+			name = host
+		}
+	}
+	cfg.ServerName = name
+	probe, err := probeFields(in)
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := secondsField(in, "timeout", defaultNetTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	nc, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn := tls13.Client(nc, cfg)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := conn.ConnectionState()
+	res := &Result{
+		Protocol:         tls13Protocol,
+		CipherSuite:      tls13.CipherSuiteName(state.CipherSuite),
+		PeerCertificates: certificateSubjects(state.PeerCertificates),
+	}
+	if res.Output, err = probe.exchange(conn, in); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// tls13Server serves TLS 1.3 with "certificate" / "private_key" as
+// described at serveConnections. With "client_auth" clients must present
+// a certificate that verifies against "roots".
+func tls13Server(in map[string]interface{}) (*Result, error) {
+	cfg, err := tls13Config(in)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Certificate == nil {
+		return nil, &codedError{codeMissingField, errors.New("missing required field \"certificate\"")}
+	}
+	if cfg.ClientAuth, err = boolField(in, "client_auth"); err != nil {
+		return nil, err
+	}
+	if cfg.ClientAuth && len(cfg.RootCAs) == 0 && !cfg.InsecureSkipVerify {
+		return nil, errors.New("client_auth needs \"roots\" unless \"insecure_skip_verify\" is set")
+	}
+	return serveConnections(in, serverEndpoint{
+		protocol: tls13Protocol,
+		wrap:     func(nc net.Conn) secureConn { return tls13.Server(nc, cfg) },
+		describe: func(c secureConn) (string, []string) {
+			state := c.(*tls13.Conn).ConnectionState()
+			return tls13.CipherSuiteName(state.CipherSuite), certificateSubjects(state.PeerCertificates)
+		},
+	})
+}
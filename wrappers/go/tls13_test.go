@@ -0,0 +1,91 @@
+package main
+
+import (
+	"net"
+	"testing"
+)
+
+func TestTLS13(t *testing.T) {
+	root := mustCall(t, "sm2", "cert-selfsign", map[string]interface{}{
+		"subject": map[string]interface{}{"CN": "TLS 1.3 Root"}, "is_ca": true, "key_usage": []interface{}{"keyCertSign"},
+	})
+	issue := func(cn string, names ...interface{}) *Result {
+		key := mustCall(t, "sm2", "keygen", map[string]interface{}{})
+		res := mustCall(t, "sm2", "cert-issue", map[string]interface{}{
+			"subject": map[string]interface{}{"CN": cn}, "public_key": key.PublicKey, "dns_names": names,
+			"issuer_certificate": root.Output, "private_key": root.PrivateKey, "key_usage": []interface{}{"digitalSignature"},
+		})
+		res.PrivateKey = key.PrivateKey
+		return res
+	}
+	server, client := issue("server", "localhost"), issue("client")
+
+	in := map[string]interface{}{
+		"certificate": server.Output, "private_key": server.PrivateKey,
+		"connections": 3, "cipher_suites": []interface{}{"TLS_SM4_CCM_SM3", "TLS_SM4_GCM_SM3"},
+	}
+	addr, next, wait := startServer(t, "tls13", in)
+	// The server name is taken from the address unless given.
+	_, port, _ := net.SplitHostPort(addr)
+	res := mustCall(t, "tls13", "client", map[string]interface{}{
+		"address": "localhost:" + port, "roots": []interface{}{root.Output}, "probe": "ping",
+	})
+	if res.Protocol != "TLS1.3" || res.CipherSuite != "TLS_SM4_CCM_SM3" || res.Output != "ping" {
+		t.Errorf("client: %+v", res)
+	}
+	if len(res.PeerCertificates) != 1 || res.PeerCertificates[0] != "CN=server" {
+		t.Errorf("peer certificates %v", res.PeerCertificates)
+	}
+	if ev := next(); ev.CipherSuite != "TLS_SM4_CCM_SM3" || ev.BytesIn != 4 || ev.Error != "" || ev.Protocol != "TLS1.3" {
+		t.Errorf("handshake event %+v", ev)
+	}
+	res = mustCall(t, "tls13", "client", map[string]interface{}{
+		"address": addr, "roots": []interface{}{root.Output}, "server_name": "localhost",
+		"cipher_suites": []interface{}{"TLS_SM4_GCM_SM3"}, "probe_hex": "00ff", "plaintext_encoding": "hex",
+	})
+	if res.CipherSuite != "TLS_SM4_GCM_SM3" || res.Output != "00ff" {
+		t.Errorf("client: %+v", res)
+	}
+	next()
+	// A name the certificate does not cover is refused.
+	mustFail(t, "tls13", "client", map[string]interface{}{"address": addr, "roots": []interface{}{root.Output}, "server_name": "example.com"})
+	if ev := next(); ev.Error == "" {
+		t.Errorf("failed handshake event %+v", ev)
+	}
+	if res := wait(); res.Status != statusSuccess || len(res.Handshakes) != 3 {
+		t.Errorf("server result %+v", res)
+	}
+
+	// Client authentication, with a fixed response.
+	in["client_auth"] = true
+	in["roots"] = []interface{}{root.Output}
+	in["connections"] = 1
+	in["response"] = "pong"
+	addr, next, wait = startServer(t, "tls13", in)
+	res = mustCall(t, "tls13", "client", map[string]interface{}{
+		"address": addr, "insecure_skip_verify": true, "probe": "ping",
+		"certificate": client.Output, "private_key": client.PrivateKey,
+	})
+	if res.Output != "pong" {
+		t.Errorf("client auth: %+v", res)
+	}
+	if ev := next(); len(ev.PeerCertificates) != 1 || ev.PeerCertificates[0] != "CN=client" {
+		t.Errorf("client auth event %+v", ev)
+	}
+	wait()
+
+	for _, in := range []map[string]interface{}{
+		{"roots": []interface{}{root.Output}},
+		{"address": addr},
+		{"address": addr, "insecure_skip_verify": true, "cipher_suites": []interface{}{"TLS_AES_128_GCM_SHA256"}},
+		{"address": addr, "insecure_skip_verify": true, "certificate": client.Output, "private_key": server.PrivateKey},
+	} {
+		mustFail(t, "tls13", "client", in)
+	}
+	for _, in := range []map[string]interface{}{
+		{},
+		{"certificate": server.Output, "private_key": server.PrivateKey, "client_auth": true},
+	} {
+		mustFail(t, "tls13", "server", in)
+	}
+}
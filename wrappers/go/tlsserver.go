@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"time"
+)
+
+// secureConn is the part of a TLCP or TLS connection the server modes
+// use.
+type secureConn interface {
+	net.Conn
+	Handshake() error
+}
+
+// serverEndpoint adapts a protocol to serveConnections: wrap starts the
+// server side of a connection and describe reports the negotiated cipher
+// suite and the subjects of the client's certificates.
+type serverEndpoint struct {
+	protocol string
+	wrap     func(net.Conn) secureConn
+	describe func(secureConn) (suite string, peers []string)
+}
+
+// serverEvent is one line of the JSON log of the server modes.
+type serverEvent struct {
+	Event            string   `json:"event"` // listening or handshake
+	Time             string   `json:"time"`
+	Address          string   `json:"address,omitempty"`
+	Remote           string   `json:"remote,omitempty"`
+	Protocol         string   `json:"protocol,omitempty"`
+	CipherSuite      string   `json:"cipher_suite,omitempty"`
+	PeerCertificates []string `json:"peer_certificates,omitempty"`
+	BytesIn          int      `json:"bytes_in"`
+	BytesOut         int      `json:"bytes_out"`
+	Error            string   `json:"error,omitempty"`
+}
+
+// defaultAcceptTimeout bounds the wait for each client of a server mode.
+const defaultAcceptTimeout = 60 * time.Second
+
+// serveConnections listens on "address" (default 127.0.0.1:0) and serves
+// "connections" clients (default 1) one after another. After the
+// handshake it reads one record and answers with "response" (UTF-8 text,
+// or "response_hex" / "response_base64"), or echoes the record. Each
+// client gets "timeout" seconds and is awaited for "accept_timeout"
+// seconds (default 60). A listening event and one event per handshake
+// are written as JSON lines to "log_file", or to stderr; the handshake
+// events are also returned in Handshakes, and Output is the address.
+func serveConnections(in map[string]interface{}, ep serverEndpoint) (*Result, error) {
+	addr, ok, err := stringField(in, "address")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		addr = "127.0.0.1:0"
+	}
+	count, ok, err := intField(in, "connections")
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		count = 1
+	}
+	if count <= 0 {
+		return nil, errors.New("connections must be positive")
+	}
+	response, hasResponse, err := bytesField(in, "response")
+	if err != nil {
+		return nil, err
+	}
+	timeout, err := secondsField(in, "timeout", defaultNetTimeout)
+	if err != nil {
+		return nil, err
+	}
+	acceptTimeout, err := secondsField(in, "accept_timeout", defaultAcceptTimeout)
+	if err != nil {
+		return nil, err
+	}
+	logOut := stderr
+	if name, ok, err := stringField(in, "log_file"); err != nil {
+		return nil, err
+	} else if ok {
+		f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		logOut = f
+	}
+	log := json.NewEncoder(logOut)
+	log.SetEscapeHTML(false)
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer ln.Close()
+	res := &Result{Output: ln.Addr().String()}
+	if err := log.Encode(&serverEvent{Event: "listening", Time: now(), Address: res.Output, Protocol: ep.protocol}); err != nil {
+		return nil, err
+	}
+	for i := 0; i < count; i++ {
+		ln.(*net.TCPListener).SetDeadline(time.Now().Add(acceptTimeout))
+		nc, err := ln.Accept()
+		if err != nil {
+			return nil, err
+		}
+		ev := serveOne(ep, nc, timeout, response, hasResponse)
+		res.Handshakes = append(res.Handshakes, ev)
+		if err := log.Encode(ev); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+func now() string { return time.Now().UTC().Format(time.RFC3339Nano) }
+
+// serveOne runs the handshake and one exchange on nc and describes them.
+func serveOne(ep serverEndpoint, nc net.Conn, timeout time.Duration, response []byte, hasResponse bool) *serverEvent {
+	ev := &serverEvent{Event: "handshake", Remote: nc.RemoteAddr().String(), Protocol: ep.protocol}
+	conn := ep.wrap(nc)
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	err := conn.Handshake()
+	ev.Time = now()
+	if err != nil {
+		ev.Error = err.Error()
+		return ev
+	}
+	ev.CipherSuite, ev.PeerCertificates = ep.describe(conn)
+	buf := make([]byte, 1<<16)
+	n, err := conn.Read(buf)
+	ev.BytesIn = n
+	if err != nil {
+		if err != io.EOF {
+			ev.Error = err.Error()
+		}
+		return ev
+	}
+	if !hasResponse {
+		response = buf[:n]
+	}
+	if ev.BytesOut, err = conn.Write(response); err != nil {
+		ev.Error = err.Error()
+	}
+	return ev
+}
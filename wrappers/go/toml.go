@@ -0,0 +1,347 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// parseTOML decodes a TOML document into the values readInput produces
+// for JSON: maps, slices, strings, bools and json.Numbers. It covers what
+// requests and config files use: bare and quoted keys, [table] headers
+// with dotted names, all four string forms (multi-line ones suit PEM),
+// integers, floats, booleans, arrays and inline tables. Dates and arrays of
+// tables are not supported. name prefixes the line of an error.
+func parseTOML(src, name string) (map[string]interface{}, error) {
+	p := &tomlParser{s: src, name: name}
+	root := map[string]interface{}{}
+	cur := root
+	defined := map[string]bool{}
+	for {
+		p.skipBlank()
+		if p.eof() {
+			return root, nil
+		}
+		if p.peek() == '[' {
+			p.pos++
+			var path []string
+			for {
+				p.skipSpace()
+				key, err := p.key()
+				if err != nil {
+					return nil, err
+				}
+				path = append(path, key)
+				p.skipSpace()
+				if p.eof() || p.peek() != '.' {
+					break
+				}
+				p.pos++
+			}
+			if p.eof() || p.peek() != ']' {
+				return nil, p.errorf("expected ] after the table name")
+			}
+			p.pos++
+			full := strings.Join(path, ".")
+			if defined[full] {
+				return nil, p.errorf("table [%s] is defined twice", full)
+			}
+			defined[full] = true
+			cur = root
+			for _, key := range path {
+				next, ok := cur[key]
+				if !ok {
+					next = map[string]interface{}{}
+					cur[key] = next
+				}
+				m, ok := next.(map[string]interface{})
+				if !ok {
+					return nil, p.errorf("%s is not a table", full)
+				}
+				cur = m
+			}
+		} else if err := p.keyValue(cur); err != nil {
+			return nil, err
+		}
+		if err := p.endOfLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	name string
+}
+
+func (p *tomlParser) eof() bool  { return p.pos >= len(p.s) }
+func (p *tomlParser) peek() byte { return p.s[p.pos] }
+
+func (p *tomlParser) errorf(format string, args ...interface{}) error {
+	line := strings.Count(p.s[:min(p.pos, len(p.s))], "\n") + 1
+	return fmt.Errorf("%s:%d: %s", p.name, line, fmt.Sprintf(format, args...))
+}
+
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, newlines and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r', '\n':
+			p.pos++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endOfLine accepts trailing whitespace and a comment before the newline.
+func (p *tomlParser) endOfLine() error {
+	p.skipSpace()
+	if !p.eof() && p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if !p.eof() && p.peek() == '\r' {
+		p.pos++
+	}
+	if p.eof() || p.peek() == '\n' {
+		return nil
+	}
+	return p.errorf("unexpected %q after the value", p.rest())
+}
+
+// rest returns the remainder of the current line, for error messages.
+func (p *tomlParser) rest() string {
+	s := p.s[p.pos:]
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
+
+var tomlBareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+func (p *tomlParser) key() (string, error) {
+	if !p.eof() && (p.peek() == '"' || p.peek() == '\'') {
+		v, err := p.value()
+		if err != nil {
+			return "", err
+		}
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+	}
+	k := tomlBareKey.FindString(p.s[p.pos:])
+	if k == "" {
+		return "", p.errorf("expected a key, found %q", p.rest())
+	}
+	p.pos += len(k)
+	return k, nil
+}
+
+func (p *tomlParser) keyValue(m map[string]interface{}) error {
+	key, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipSpace()
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected = after %s", key)
+	}
+	p.pos++
+	p.skipSpace()
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+	if _, ok := m[key]; ok {
+		return p.errorf("%s is set twice", key)
+	}
+	m[key] = v
+	return nil
+}
+
+var tomlNumber = regexp.MustCompile(`^[+-]?[0-9][0-9_]*(\.[0-9_]+)?([eE][+-]?[0-9_]+)?`)
+
+func (p *tomlParser) value() (interface{}, error) {
+	if p.eof() {
+		return nil, p.errorf("missing value")
+	}
+	s := p.s[p.pos:]
+	switch {
+	case strings.HasPrefix(s, `"""`):
+		return p.multiline(`"""`, true)
+	case strings.HasPrefix(s, "'''"):
+		return p.multiline("'''", false)
+	case s[0] == '"':
+		end := 1
+		for ; end < len(s) && s[end] != '"' && s[end] != '\n'; end++ {
+			if s[end] == '\\' {
+				end++
+			}
+		}
+		if end >= len(s) || s[end] != '"' {
+			return nil, p.errorf("unterminated string")
+		}
+		v, err := tomlUnescape(s[1:end])
+		if err != nil {
+			return nil, p.errorf("%v", err)
+		}
+		p.pos += end + 1
+		return v, nil
+	case s[0] == '\'':
+		end := strings.IndexAny(s[1:], "'\n")
+		if end < 0 || s[1+end] != '\'' {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos += end + 2
+		return s[1 : 1+end], nil
+	case s[0] == '[':
+		p.pos++
+		list := []interface{}{}
+		for {
+			p.skipBlank()
+			if p.eof() {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.peek() == ']' {
+				p.pos++
+				return list, nil
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			p.skipBlank()
+			if !p.eof() && p.peek() == ',' {
+				p.pos++
+			} else if p.eof() || p.peek() != ']' {
+				return nil, p.errorf("expected , or ] in array")
+			}
+		}
+	case s[0] == '{':
+		p.pos++
+		m := map[string]interface{}{}
+		for {
+			p.skipSpace()
+			if !p.eof() && p.peek() == '}' && len(m) == 0 {
+				p.pos++
+				return m, nil
+			}
+			if err := p.keyValue(m); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.eof() {
+				return nil, p.errorf("unterminated inline table")
+			}
+			switch p.peek() {
+			case ',':
+				p.pos++
+			case '}':
+				p.pos++
+				return m, nil
+			default:
+				return nil, p.errorf("expected , or } in inline table")
+			}
+		}
+	case strings.HasPrefix(s, "true"):
+		p.pos += 4
+		return true, nil
+	case strings.HasPrefix(s, "false"):
+		p.pos += 5
+		return false, nil
+	}
+	if n := tomlNumber.FindString(s); n != "" {
+		p.pos += len(n)
+		return json.Number(strings.TrimPrefix(strings.ReplaceAll(n, "_", ""), "+")), nil
+	}
+	return nil, p.errorf("unsupported value %q", p.rest())
+}
+
+// multiline reads a multi-line string that ends at delim. A newline right
+// after the opening delimiter is dropped, and in basic strings a backslash
+// at the end of a line removes the line break and the whitespace after it.
+func (p *tomlParser) multiline(delim string, basic bool) (interface{}, error) {
+	start := p.pos + len(delim)
+	end := strings.Index(p.s[start:], delim)
+	if end < 0 {
+		return nil, p.errorf("unterminated multi-line string")
+	}
+	body := p.s[start : start+end]
+	p.pos = start + end + len(delim)
+	body = strings.TrimPrefix(strings.TrimPrefix(body, "\r"), "\n")
+	if !basic {
+		return body, nil
+	}
+	body = tomlLineContinuation.ReplaceAllString(body, "")
+	v, err := tomlUnescape(body)
+	if err != nil {
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+var tomlLineContinuation = regexp.MustCompile(`\\[ \t]*\r?\n[ \t\r\n]*`)
+
+// tomlUnescape resolves the escapes of a basic string.
+func tomlUnescape(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+		if i++; i == len(s) {
+			return "", fmt.Errorf("string ends with a backslash")
+		}
+		switch s[i] {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(s[i])
+		case 'u', 'U':
+			n := 4
+			if s[i] == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("short \\%c escape", s[i])
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil || !utf8.ValidRune(rune(r)) {
+				return "", fmt.Errorf("invalid \\%c escape", s[i])
+			}
+			b.WriteRune(rune(r))
+			i += n
+		default:
+			return "", fmt.Errorf("invalid escape \\%c", s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	got, err := parseTOML(`# an SM2 request
+message = "hello\tworld"
+digest = '0123'
+count = -1_024
+ratio = 0.5
+detached = false
+roots = [
+  "a.pem", # first
+  'b.pem',
+]
+subject = { cn = "test", "o" = "Org" }
+certificate = """
+-----BEGIN CERTIFICATE-----
+MIIB
+-----END CERTIFICATE-----
+"""
+literal = '''
+C:\keys\'''
+folded = """one \
+         two"""
+
+[options.nested]
+depth = 2
+`, "test.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"message":     "hello\tworld",
+		"digest":      "0123",
+		"count":       json.Number("-1024"),
+		"ratio":       json.Number("0.5"),
+		"detached":    false,
+		"roots":       []interface{}{"a.pem", "b.pem"},
+		"subject":     map[string]interface{}{"cn": "test", "o": "Org"},
+		"certificate": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		"literal":     `C:\keys\`,
+		"folded":      "one two",
+		"options":     map[string]interface{}{"nested": map[string]interface{}{"depth": json.Number("2")}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseTOML = %#v, want %#v", got, want)
+	}
+
+	for _, bad := range []string{
+		"a = 1\na = 2\n",
+		"a = \"unterminated\n",
+		"a = 1 b = 2\n",
+		"[t]\n[t]\n",
+		"a = 1\n[a]\n",
+		"a = \"\\q\"\n",
+		"a = 1979-05-27\n",
+	} {
+		if _, err := parseTOML(bad, "bad.toml"); err == nil {
+			t.Errorf("parseTOML(%q) succeeded", bad)
+		}
+	}
+}
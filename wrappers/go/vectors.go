@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	mrand "math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+)
+
+// vector is one test case: a request and the result object the wrapper
+// gives for it. Vectors from other sources may list only some fields of
+// the result.
+type vector struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	Operation string `json:"operation"`
+	// Tamper describes how the request of a negative vector was
+	// corrupted.
+	Tamper   string                 `json:"tamper,omitempty"`
+	Request  map[string]interface{} `json:"request"`
+	Expected map[string]interface{} `json:"expected"`
+}
+
+// vectorFile is the JSON document of a set of vectors.
+type vectorFile struct {
+	// Generator names the program and version that wrote the vectors.
+	Generator string `json:"generator,omitempty"`
+	// Seed reproduces generated vectors with the same generator.
+	Seed    *int64   `json:"seed,omitempty"`
+	Vectors []vector `json:"vectors"`
+}
+
+// vectorGenerators build vector i of an algorithm. Every request they
+// produce is deterministic: randomized operations (SM2 signing and
+// encryption) become vectors of their inverse, which can be replayed.
+var vectorGenerators = map[string]func(g *vectorGen, i int) (*vector, error){
+	"sm2": sm2Vector,
+	"sm3": sm3Vector,
+	"sm4": sm4Vector,
+	"zuc": zucVector,
+}
+
+const genVectorsUsage = "gen-vectors [--algorithm sm2|sm3|sm4|zuc|all] [--count N] [--seed N] [--negative] [--output <file>]"
+
+// genVectorsCommand writes a vectorFile of generated vectors.
+func genVectorsCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("gen-vectors", flag.ContinueOnError)
+	algorithm := fs.String("algorithm", "all", "algorithm of the vectors: sm2, sm3, sm4, zuc or all")
+	count := fs.Int("count", 100, "number of vectors per algorithm")
+	seed := fs.Int64("seed", 1, "seed of the generator; the same seed gives the same vectors")
+	negative := fs.Bool("negative", false, "generate corrupted requests that must be rejected")
+	output := fs.String("output", "", "file to write instead of standard output")
+	if ok, code := parseCommand(fs, genVectorsUsage, args, 0, stdout); !ok {
+		return code
+	}
+	generators := vectorGenerators
+	if *negative {
+		generators = negativeGenerators
+	}
+	algorithms := []string{*algorithm}
+	if *algorithm == "all" {
+		algorithms = sortedKeys(generators)
+	} else if _, ok := generators[*algorithm]; !ok {
+		return commandFailed(stdout, fmt.Errorf("unsupported --algorithm %q (supported: %s, all)", *algorithm, strings.Join(sortedKeys(generators), ", ")))
+	}
+	if *count < 0 {
+		return commandFailed(stdout, fmt.Errorf("--count must not be negative, got %d", *count))
+	}
+	vf, err := generateVectors(generators, algorithms, *count, *seed)
+	if err != nil {
+		return commandFailed(stdout, err)
+	}
+	w := stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return commandFailed(stdout, &codedError{codeIO, err})
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(vf); err != nil {
+		return commandFailed(stdout, &codedError{codeIO, err})
+	}
+	return exitSuccess
+}
+
+// generateVectors generates count vectors of each algorithm from seed with
+// generators, vectorGenerators or negativeGenerators. The wrapper's
+// randomness source is the seeded generator meanwhile, so that generated
+// keys are reproducible too.
+func generateVectors(generators map[string]func(g *vectorGen, i int) (*vector, error), algorithms []string, count int, seed int64) (*vectorFile, error) {
+	g, restore := seededGen(seed)
+	defer restore()
+
+	vf := &vectorFile{Generator: "sm-bc-test go wrapper " + buildVersion().Wrapper, Seed: &seed, Vectors: []vector{}}
+	for _, alg := range algorithms {
+		for i := 0; i < count; i++ {
+			v, err := generators[alg](g, i)
+			if err != nil {
+				return nil, fmt.Errorf("generating %s vector %d: %v", alg, i, err)
+			}
+			v.ID = fmt.Sprintf("%s-%s-%04d", v.Algorithm, v.Operation, i)
+			if v.Tamper != "" {
+				v.ID = fmt.Sprintf("%s-%s-negative-%04d", v.Algorithm, v.Operation, i)
+			}
+			vf.Vectors = append(vf.Vectors, *v)
+		}
+	}
+	return vf, nil
+}
+
+// vectorGen draws the random parts of vectors.
+type vectorGen struct {
+	r *mrand.Rand
+}
+
+// seededGen returns a vectorGen drawing from seed, and makes the seeded
+// generator the wrapper's randomness source until restore is called.
+func seededGen(seed int64) (g *vectorGen, restore func()) {
+	src := seededSource(seed)
+	saved := rand
+	rand = src
+	return &vectorGen{r: mrand.New(src)}, func() { rand = saved }
+}
+
+func (g *vectorGen) bytes(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(g.r.Uint32())
+	}
+	return b
+}
+
+func (g *vectorGen) hex(n int) string {
+	return hex.EncodeToString(g.bytes(n))
+}
+
+// text returns n printable ASCII characters, for fields read as UTF-8.
+func (g *vectorGen) text(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(' ' + g.r.IntN('~'-' '+1))
+	}
+	return string(b)
+}
+
+// edgeLengths are the payload lengths of the first vectors of each
+// algorithm: empty, one byte, and around block and size boundaries.
+var edgeLengths = []int{0, 1, 15, 16, 17, 31, 32, 33, 63, 64, 65, 255, 256, 257, 4096}
+
+// length returns the payload length of vector i: an edge case for the
+// first ones, then a random length up to 4 KiB.
+func (g *vectorGen) length(i int) int {
+	if i < len(edgeLengths) {
+		return edgeLengths[i]
+	}
+	return g.r.IntN(4097)
+}
+
+// run makes a vector of the request in from the Result the wrapper gives
+// for it, which must succeed.
+func (g *vectorGen) run(algorithm, operation string, in map[string]interface{}) (*vector, error) {
+	res := dispatch(algorithm, operation, maps.Clone(in))
+	if res.Status != statusSuccess {
+		return nil, fmt.Errorf("%s %s failed: %s", algorithm, operation, res.Message)
+	}
+	return &vector{Algorithm: algorithm, Operation: operation, Request: in, Expected: resultObject(res)}, nil
+}
+
+// resultObject returns res as its JSON object.
+func resultObject(res *Result) map[string]interface{} {
+	b, err := json.Marshal(res)
+	if err != nil {
+		panic(err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func sm3Vector(g *vectorGen, i int) (*vector, error) {
+	n := g.length(i)
+	if i%2 == 0 {
+		return g.run("sm3", "hash", map[string]interface{}{"data": g.hex(n), "data_encoding": encodingHex})
+	}
+	return g.run("sm3", "hmac", map[string]interface{}{"key": g.hex(1 + g.r.IntN(64)), "data": g.text(n)})
+}
+
+// sm4VectorModes are the modes of SM4 vectors. XTS is left out, as it
+// needs at least a block of data.
+var sm4VectorModes = slices.DeleteFunc(slices.Clone(sm4Modes), func(m string) bool { return m == "XTS" })
+
+// sm4Vector alternates between rounds of encryption vectors, one per
+// mode, and rounds of decryption vectors.
+func sm4Vector(g *vectorGen, i int) (*vector, error) {
+	mode := sm4VectorModes[i%len(sm4VectorModes)]
+	in := map[string]interface{}{"key": g.hex(16), "mode": mode, "plaintext_hex": g.hex(g.length(i))}
+	switch mode {
+	case "GCM", "CCM":
+		in["iv"] = g.hex(12)
+	case "CBC", "CTR", "CFB", "OFB":
+		in["iv"] = g.hex(16)
+	}
+	enc, err := g.run("sm4", "encrypt", in)
+	if err != nil || (i/len(sm4VectorModes))%2 == 0 {
+		return enc, err
+	}
+	dec := map[string]interface{}{"key": in["key"], "mode": mode, "ciphertext": enc.Expected["output"], "plaintext_encoding": encodingHex}
+	if iv, ok := in["iv"]; ok {
+		dec["iv"] = iv
+	}
+	if enc.Expected["tag"] != nil {
+		dec["tag"] = enc.Expected["tag"]
+	}
+	return g.run("sm4", "decrypt", dec)
+}
+
+// sm2Vector cycles through verification of a fresh signature, decryption
+// of a fresh ciphertext and public key derivation, each with a new key.
+func sm2Vector(g *vectorGen, i int) (*vector, error) {
+	kp, err := g.run("sm2", "keygen", map[string]interface{}{})
+	if err != nil {
+		return nil, err
+	}
+	priv, pub := kp.Expected["private_key"], kp.Expected["public_key"]
+	msg := g.text(g.length(i))
+	switch i % 3 {
+	case 0:
+		sig, err := g.run("sm2", "sign", map[string]interface{}{"message": msg, "private_key": priv})
+		if err != nil {
+			return nil, err
+		}
+		return g.run("sm2", "verify", map[string]interface{}{"message": msg, "public_key": pub, "signature": sig.Expected["output"]})
+	case 1:
+		ct, err := g.run("sm2", "encrypt", map[string]interface{}{"plaintext": msg, "public_key": pub})
+		if err != nil {
+			return nil, err
+		}
+		return g.run("sm2", "decrypt", map[string]interface{}{"ciphertext": ct.Expected["output"], "private_key": priv})
+	}
+	return g.run("sm2", "derive-pub", map[string]interface{}{"private_key": priv})
+}
+
+// zucVector alternates between ZUC-128 encryption and MAC vectors.
+func zucVector(g *vectorGen, i int) (*vector, error) {
+	in := map[string]interface{}{
+		"key":       g.hex(16),
+		"count":     json.Number(fmt.Sprint(g.r.Uint32())),
+		"bearer":    json.Number(fmt.Sprint(g.r.IntN(32))),
+		"direction": json.Number(fmt.Sprint(g.r.IntN(2))),
+	}
+	data := g.hex(g.length(i))
+	if i%2 == 0 {
+		in["plaintext_hex"] = data
+		return g.run("zuc", "encrypt", in)
+	}
+	in["data_hex"] = data
+	return g.run("zuc", "mac", in)
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenVectors(t *testing.T) {
+	gen := func(args ...string) []byte {
+		t.Helper()
+		var out bytes.Buffer
+		if code := run(append([]string{"gen-vectors"}, args...), &out); code != 0 {
+			t.Fatalf("gen-vectors %q exited %d: %s", args, code, out.String())
+		}
+		return out.Bytes()
+	}
+	b := gen("--count", "20", "--seed", "42")
+	if !bytes.Equal(b, gen("--count", "20", "--seed", "42")) {
+		t.Error("the same seed gave different vectors")
+	}
+	if bytes.Equal(b, gen("--count", "20", "--seed", "43")) {
+		t.Error("different seeds gave the same vectors")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var vf vectorFile
+	if err := dec.Decode(&vf); err != nil {
+		t.Fatal(err)
+	}
+	if vf.Seed == nil || *vf.Seed != 42 || len(vf.Vectors) != 4*20 {
+		t.Fatalf("seed %v, %d vectors", vf.Seed, len(vf.Vectors))
+	}
+	ids := map[string]bool{}
+	ops := map[string]bool{}
+	for _, v := range vf.Vectors {
+		if ids[v.ID] {
+			t.Errorf("duplicate id %s", v.ID)
+		}
+		ids[v.ID] = true
+		ops[v.Algorithm+" "+v.Operation] = true
+		// Every vector replays to its expected result.
+		if got := resultObject(dispatch(v.Algorithm, v.Operation, maps.Clone(v.Request))); !reflect.DeepEqual(got, v.Expected) {
+			t.Errorf("%s: got %+v, want %+v", v.ID, got, v.Expected)
+		}
+	}
+	for _, op := range []string{"sm2 verify", "sm2 decrypt", "sm2 derive-pub", "sm3 hash", "sm3 hmac", "sm4 encrypt", "sm4 decrypt", "zuc encrypt", "zuc mac"} {
+		if !ops[op] {
+			t.Errorf("no %s vectors", op)
+		}
+	}
+
+	var sm4 vectorFile
+	if err := json.Unmarshal(gen("--algorithm", "sm4", "--count", "3"), &sm4); err != nil || len(sm4.Vectors) != 3 || sm4.Vectors[0].Algorithm != "sm4" {
+		t.Errorf("--algorithm sm4: %+v, %v", sm4, err)
+	}
+	for _, args := range [][]string{{"--algorithm", "sm9"}, {"--count", "-1"}, {"extra"}, {"--bogus"}} {
+		var out bytes.Buffer
+		if code := run(append([]string{"gen-vectors"}, args...), &out); code != exitUsage {
+			t.Errorf("gen-vectors %q exited %d: %s", args, code, out.String())
+		}
+	}
+}
+
+func TestVerifyVectors(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "vectors.json")
+	var out bytes.Buffer
+	if code := run([]string{"gen-vectors", "--count", "6", "--output", path}, &out); code != 0 {
+		t.Fatalf("gen-vectors: %s", out.String())
+	}
+	verify := func(args ...string) (*vectorReport, int) {
+		t.Helper()
+		var out bytes.Buffer
+		code := run(append([]string{"verify-vectors"}, args...), &out)
+		var report vectorReport
+		if err := json.Unmarshal(out.Bytes(), &report); err != nil {
+			t.Fatalf("verify-vectors %q: %s", args, out.String())
+		}
+		return &report, code
+	}
+	if report, code := verify(path); code != 0 || report.Passed != 24 || report.Failed != 0 || len(report.Results) != 24 {
+		t.Errorf("generated vectors: exit %d, %+v", code, report)
+	}
+
+	// A golden file from elsewhere: upper-case hex, partial results, and
+	// wrong expectations.
+	golden := filepath.Join(dir, "golden.json")
+	os.WriteFile(golden, []byte(`{"vectors": [
+		{"id": "abc", "algorithm": "sm3", "operation": "hash", "request": {"data": "abc"},
+		 "expected": {"output": "66C7F0F462EEEDD9D1F2D46BDC10E4E24167C4875CF2F7A2297DA02B8F4BA8E0"}},
+		{"id": "wrong", "algorithm": "sm3", "operation": "hash", "request": {"data": "abd"},
+		 "expected": {"output": "66c7f0f462eeedd9d1f2d46bdc10e4e24167c4875cf2f7a2297da02b8f4ba8e0"}},
+		{"id": "fails", "algorithm": "sm4", "operation": "encrypt", "request": {"key": "00"},
+		 "expected": {"output": "00"}},
+		{"id": "rejects", "algorithm": "sm4", "operation": "encrypt", "request": {"key": "00", "plaintext": "x"},
+		 "expected": {"status": "error", "error_code": "ERR_INVALID_KEY_LENGTH"}}
+	]}`), 0o644)
+	report, code := verify("--failures-only", golden)
+	if code != exitInvalid || report.Passed != 2 || report.Failed != 2 || len(report.Results) != 2 {
+		t.Fatalf("golden vectors: exit %d, %+v", code, report)
+	}
+	if r := report.Results[0]; r.ID != "wrong" || len(r.Diffs) != 1 || r.Diffs[0].Field != "output" || r.Diffs[0].Actual == nil {
+		t.Errorf("wrong digest: %+v", r)
+	}
+	if r := report.Results[1]; r.ID != "fails" || len(r.Diffs) != 3 || r.Diffs[2].Field != "message" {
+		t.Errorf("failed operation: %+v", r)
+	}
+
+	for _, args := range [][]string{nil, {path, path}, {filepath.Join(dir, "missing.json")}} {
+		var out bytes.Buffer
+		if code := run(append([]string{"verify-vectors"}, args...), &out); code == 0 || !bytes.Contains(out.Bytes(), []byte(`"status":"error"`)) {
+			t.Errorf("verify-vectors %q exited %d: %s", args, code, out.String())
+		}
+	}
+}
+
+func TestNegativeVectors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "negative.json")
+	var out bytes.Buffer
+	if code := run([]string{"gen-vectors", "--negative", "--count", "10", "--seed", "2", "--output", path}, &out); code != 0 {
+		t.Fatalf("gen-vectors --negative: %s", out.String())
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vf vectorFile
+	if err := json.Unmarshal(b, &vf); err != nil || len(vf.Vectors) != 30 {
+		t.Fatalf("%d vectors, %v", len(vf.Vectors), err)
+	}
+	tampers := map[string]bool{}
+	for _, v := range vf.Vectors {
+		tampers[v.Tamper] = true
+		if v.Expected["status"] == statusError && v.Expected["error_code"] == nil || v.Expected["status"] == statusSuccess && v.Expected["valid"] != false {
+			t.Errorf("%s: expected %v", v.ID, v.Expected)
+		}
+		if !strings.Contains(v.ID, "-negative-") {
+			t.Errorf("ID %s", v.ID)
+		}
+	}
+	if len(tampers) != 9 {
+		t.Errorf("tampering: %v", tampers)
+	}
+	out.Reset()
+	if code := run([]string{"verify-vectors", path}, &out); code != 0 {
+		t.Errorf("verify-vectors: %s", out.String())
+	}
+	out.Reset()
+	if code := run([]string{"gen-vectors", "--negative", "--algorithm", "sm3"}, &out); code != exitUsage {
+		t.Errorf("negative SM3 vectors: %s", out.String())
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"reflect"
+	"strings"
+)
+
+const verifyVectorsUsage = "verify-vectors [--failures-only] <file>"
+
+// vectorReport is the output of verify-vectors.
+type vectorReport struct {
+	Passed  int            `json:"passed"`
+	Failed  int            `json:"failed"`
+	Results []vectorResult `json:"results"`
+}
+
+type vectorResult struct {
+	ID        string `json:"id"`
+	Algorithm string `json:"algorithm"`
+	Operation string `json:"operation"`
+	Passed    bool   `json:"passed"`
+	// Diffs lists the fields of the expected result that differ.
+	Diffs []vectorDiff `json:"diffs,omitempty"`
+}
+
+// vectorDiff is a field of a result that differs from its vector. Actual
+// is null for a field the result lacks.
+type vectorDiff struct {
+	Field    string      `json:"field"`
+	Expected interface{} `json:"expected"`
+	Actual   interface{} `json:"actual"`
+}
+
+// verifyVectorsCommand replays every vector of a vectorFile and writes a
+// vectorReport. The exit code is exitInvalid if a vector failed.
+func verifyVectorsCommand(args []string, stdout io.Writer) int {
+	fs := flag.NewFlagSet("verify-vectors", flag.ContinueOnError)
+	failuresOnly := fs.Bool("failures-only", false, "report only the vectors that failed")
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		// The file may come before the flags.
+		args = append(args[1:], args[0])
+	}
+	if ok, code := parseCommand(fs, verifyVectorsUsage, args, 1, stdout); !ok {
+		return code
+	}
+	vf, err := readVectors(fs.Arg(0))
+	if err != nil {
+		return commandFailed(stdout, err)
+	}
+	report := verifyVectors(vf)
+	if *failuresOnly {
+		var failed []vectorResult
+		for _, r := range report.Results {
+			if !r.Passed {
+				failed = append(failed, r)
+			}
+		}
+		report.Results = failed
+	}
+	if report.Results == nil {
+		report.Results = []vectorResult{}
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return exitFailure
+	}
+	if report.Failed > 0 {
+		return exitInvalid
+	}
+	return exitSuccess
+}
+
+// readVectors reads the vectorFile at path, or standard input for "-".
+func readVectors(path string) (*vectorFile, error) {
+	var r io.Reader = stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, &codedError{codeIO, err}
+		}
+		defer f.Close()
+		r = f
+	}
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var vf vectorFile
+	if err := dec.Decode(&vf); err != nil {
+		return nil, &codedError{codeInvalidRequest, fmt.Errorf("invalid vector file: %v", err)}
+	}
+	if vf.Vectors == nil {
+		return nil, &codedError{codeInvalidRequest, errors.New("invalid vector file: no \"vectors\"")}
+	}
+	return &vf, nil
+}
+
+// verifyVectors runs every vector of vf.
+func verifyVectors(vf *vectorFile) *vectorReport {
+	report := &vectorReport{}
+	for i, v := range vf.Vectors {
+		in := maps.Clone(v.Request)
+		if in == nil {
+			in = map[string]interface{}{}
+		}
+		r := checkVector(&v, dispatch(strings.ToLower(v.Algorithm), strings.ToLower(v.Operation), in))
+		if r.ID == "" {
+			r.ID = fmt.Sprintf("#%d", i)
+		}
+		if r.Passed {
+			report.Passed++
+		} else {
+			report.Failed++
+		}
+		report.Results = append(report.Results, r)
+	}
+	return report
+}
+
+// checkVector compares the Result of v's request with v. It passes when
+// the result has every field of the expected result with the same value;
+// a missing "status" is expected to be "success".
+func checkVector(v *vector, res *Result) vectorResult {
+	r := vectorResult{ID: v.ID, Algorithm: v.Algorithm, Operation: v.Operation}
+	actual := resultObject(res)
+	expected := maps.Clone(v.Expected)
+	if expected == nil {
+		expected = map[string]interface{}{}
+	}
+	if _, ok := expected["status"]; !ok {
+		expected["status"] = statusSuccess
+	}
+	for _, field := range sortedKeys(expected) {
+		if !sameValue(expected[field], actual[field]) {
+			r.Diffs = append(r.Diffs, vectorDiff{Field: field, Expected: expected[field], Actual: actual[field]})
+		}
+	}
+	r.Passed = len(r.Diffs) == 0
+	if msg, ok := actual["message"]; ok && !r.Passed && expected["message"] == nil {
+		// The error explains a failure better than the fields it lacks.
+		r.Diffs = append(r.Diffs, vectorDiff{Field: "message", Actual: msg})
+	}
+	return r
+}
+
+// sameValue compares result values decoded from JSON. Hex strings match
+// in either case, as other wrappers may write upper-case hex.
+func sameValue(expected, actual interface{}) bool {
+	e, ok1 := expected.(string)
+	a, ok2 := actual.(string)
+	if ok1 && ok2 && strings.EqualFold(e, a) {
+		_, err := hex.DecodeString(a)
+		return e == a || err == nil
+	}
+	return reflect.DeepEqual(expected, actual)
+}
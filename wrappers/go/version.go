@@ -0,0 +1,133 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// libraryPath is the module path of sm-go-bc, the library under test.
+const libraryPath = "github.com/lihongjie0209/sm-go-bc"
+
+// version is the wrapper's version, set at build time with
+// -ldflags "-X main.version=<version>". Without it, the module version
+// recorded by the go command is used.
+var version string
+
+// versionInfo is the Result.Version of "wrapper version".
+type versionInfo struct {
+	Wrapper string `json:"wrapper"`
+	// Library describes the sm-go-bc module the binary was built with.
+	Library    libraryInfo         `json:"library"`
+	Go         string              `json:"go"`
+	Platform   string              `json:"platform"`
+	Algorithms map[string][]string `json:"algorithms"`
+	SM4Modes   []string            `json:"sm4_modes"`
+}
+
+type libraryInfo struct {
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Sum is the go.sum hash of the module.
+	Sum string `json:"sum,omitempty"`
+	// Commit and CommitTime are those of a pseudo-version, which names an
+	// untagged commit.
+	Commit     string `json:"commit,omitempty"`
+	CommitTime string `json:"commit_time,omitempty"`
+	// Modified is set when go.mod replaces the module, for instance with
+	// a local checkout.
+	Modified bool `json:"modified,omitempty"`
+}
+
+// buildVersion returns the version information of the running binary.
+func buildVersion() *versionInfo {
+	info := &versionInfo{
+		Wrapper:    version,
+		Library:    libraryInfo{Path: libraryPath, Version: "unknown"},
+		Go:         runtime.Version(),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		Algorithms: map[string][]string{},
+		SM4Modes:   sm4Modes,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if v := bi.Main.Version; info.Wrapper == "" && v != "" && v != "(devel)" {
+			info.Wrapper = v
+		}
+		for _, dep := range bi.Deps {
+			if dep.Path == libraryPath {
+				info.Library = dependencyInfo(dep)
+			}
+		}
+	}
+	if info.Wrapper == "" {
+		info.Wrapper = "devel"
+	}
+	// opDocs lists the operations of handlers, which cannot refer back to
+	// this handler.
+	for alg, ops := range opDocs {
+		info.Algorithms[alg] = sortedKeys(ops)
+	}
+	return info
+}
+
+// dependencyInfo describes the module dep, or what replaces it.
+func dependencyInfo(dep *debug.Module) libraryInfo {
+	lib := libraryInfo{Path: dep.Path, Version: dep.Version, Sum: dep.Sum}
+	if r := dep.Replace; r != nil {
+		lib = libraryInfo{Path: r.Path, Version: r.Version, Sum: r.Sum, Modified: true}
+		if lib.Version == "" || lib.Version == "(devel)" {
+			lib.Version = "devel"
+		}
+	}
+	if placeholderVersion(lib.Version) {
+		// go.mod requires the module without naming a commit, so the
+		// version says nothing about the code that was built.
+		lib.Version = "unknown"
+	}
+	lib.Commit, lib.CommitTime = pseudoVersionCommit(lib.Version)
+	return lib
+}
+
+// parsePseudoVersion splits the pseudo-version v, such as
+// v0.0.0-20240102150405-0123456789ab, into its commit hash and time.
+func parsePseudoVersion(v string) (rev string, t time.Time, ok bool) {
+	v, _, _ = strings.Cut(v, "+")
+	i := strings.LastIndexByte(v, '-')
+	if i < 15 || len(v)-i-1 != 12 || (v[i-15] != '-' && v[i-15] != '.') {
+		return "", time.Time{}, false
+	}
+	rev = v[i+1:]
+	t, err := time.Parse("20060102150405", v[i-14:i])
+	if err != nil || strings.Trim(rev, "0123456789abcdef") != "" {
+		return "", time.Time{}, false
+	}
+	return rev, t, true
+}
+
+// placeholderVersion reports whether v is a zero pseudo-version, such as
+// v0.0.0-00010101000000-000000000000, which go.mod uses for a module whose
+// version was never resolved.
+func placeholderVersion(v string) bool {
+	rev, t, ok := parsePseudoVersion(v)
+	return ok && (strings.Trim(rev, "0") == "" || t.Year() <= 1)
+}
+
+// pseudoVersionCommit returns the commit hash and RFC 3339 commit time
+// that the pseudo-version v names. It returns empty strings if v is not a
+// pseudo-version or is a placeholder.
+func pseudoVersionCommit(v string) (commit, commitTime string) {
+	rev, t, ok := parsePseudoVersion(v)
+	if !ok || placeholderVersion(v) {
+		return "", ""
+	}
+	return rev, t.Format(time.RFC3339)
+}
+
+// wrapperVersion reports the versions of the wrapper, its cryptographic
+// library and Go, and the operations it supports, for compatibility
+// reports. Output is the wrapper version.
+func wrapperVersion(in map[string]interface{}) (*Result, error) {
+	info := buildVersion()
+	return &Result{Output: info.Wrapper, Version: info}, nil
+}
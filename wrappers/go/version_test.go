@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	saved := version
+	defer func() { version = saved }()
+	version = "v1.2.3"
+	for _, args := range [][]string{{"--version"}, {"version"}, {"wrapper", "version"}} {
+		var out bytes.Buffer
+		if code := run(args, &out); code != 0 {
+			t.Fatalf("run(%q) exited %d: %s", args, code, out.String())
+		}
+		var res Result
+		if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+			t.Fatal(err)
+		}
+		v := res.Version
+		if res.Output != "v1.2.3" || v == nil || v.Wrapper != "v1.2.3" || v.Go != runtime.Version() {
+			t.Fatalf("run(%q) = %s", args, out.String())
+		}
+		if v.Library.Version == "" || !slices.Contains(v.Algorithms["sm2"], "sign") || !slices.Contains(v.SM4Modes, "GCM") {
+			t.Errorf("run(%q) = %s", args, out.String())
+		}
+	}
+	var out bytes.Buffer
+	if code := run([]string{"sm3", "--version"}, &out); code != exitUsage {
+		t.Errorf("--version after an algorithm exited %d", code)
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	lib := dependencyInfo(&debug.Module{Path: libraryPath, Version: "v0.3.1-0.20240102150405-0123456789ab", Sum: "h1:x"})
+	want := libraryInfo{Path: libraryPath, Version: "v0.3.1-0.20240102150405-0123456789ab", Sum: "h1:x",
+		Commit: "0123456789ab", CommitTime: "2024-01-02T15:04:05Z"}
+	if lib != want {
+		t.Errorf("pseudo-version: %+v, want %+v", lib, want)
+	}
+	lib = dependencyInfo(&debug.Module{Path: libraryPath, Version: "v0.3.0", Replace: &debug.Module{Path: "../sm-go-bc", Version: "(devel)"}})
+	if want := (libraryInfo{Path: "../sm-go-bc", Version: "devel", Modified: true}); lib != want {
+		t.Errorf("replaced: %+v, want %+v", lib, want)
+	}
+	// A placeholder names no commit, so the library version is unknown.
+	for _, dep := range []*debug.Module{
+		{Path: libraryPath, Version: "v0.0.0-00010101000000-000000000000"},
+		{Path: libraryPath, Version: "v0.3.0", Replace: &debug.Module{Path: libraryPath, Version: "v0.0.0-00010101000000-0123456789ab"}},
+	} {
+		lib := dependencyInfo(dep)
+		if lib.Version != "unknown" || lib.Commit != "" || lib.CommitTime != "" {
+			t.Errorf("placeholder %+v reported as %+v", dep, lib)
+		}
+	}
+	for _, v := range []string{"v0.3.0", "v0.0.0-00010101000000-000000000000", "v0.0.0-20240102150405-000000000000", "v1.0.0-rc.1", "unknown"} {
+		if commit, _ := pseudoVersionCommit(v); commit != "" {
+			t.Errorf("pseudoVersionCommit(%q) = %q", v, commit)
+		}
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	var out bytes.Buffer
+	if code := run([]string{"wrapper", "capabilities", "--input", "{}"}, &out); code != 0 {
+		t.Fatalf("exited %d: %s", code, out.String())
+	}
+	var res Result
+	if err := json.Unmarshal(out.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+	c := res.Capabilities
+	if c == nil {
+		t.Fatalf("no capabilities: %s", out.String())
+	}
+	n := 0
+	for _, op := range c.Operations {
+		if _, err := lookup(op.Algorithm, op.Operation); err != nil {
+			t.Errorf("%s %s: %v", op.Algorithm, op.Operation, err)
+		}
+		if op.Algorithm == "sm4" && op.Operation == "encrypt" && !op.Files {
+			t.Error("sm4 encrypt does not report file support")
+		}
+		n++
+	}
+	total := 0
+	for _, ops := range handlers {
+		total += len(ops)
+	}
+	if n != total {
+		t.Errorf("%d operations listed, %d handled", n, total)
+	}
+	// Every listed padding and mode must be accepted.
+	for _, p := range c.Padding {
+		if _, err := checkPadding(p); err != nil {
+			t.Error(err)
+		}
+	}
+	if !slices.Contains(c.SM4Modes, "XTS") || len(c.Options["tlcp"]["cipher_suites"]) == 0 {
+		t.Errorf("capabilities = %s", out.String())
+	}
+	for _, f := range c.Options["sm2"]["key_format"] {
+		if _, err := (sm2KeyFormat{KeyFormat: f}).format(); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -0,0 +1,409 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseYAML decodes a YAML document into the values readInput produces for
+// JSON. It covers the block style of test fixtures: nested mappings and
+// sequences by indentation, literal (|) and folded (>) block scalars for PEM
+// and long text, plain and quoted scalars, flow sequences and mappings on
+// one line, and comments. Anchors, aliases, tags and multiple documents
+// are not supported. As in YAML 1.2, a plain scalar of digits is a number,
+// so hex strings of digits only need quotes. name prefixes the line of an
+// error.
+func parseYAML(src, name string) (map[string]interface{}, error) {
+	p := &yamlParser{name: name}
+	for i, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		if i == 0 && strings.TrimSpace(line) == "---" {
+			line = ""
+		}
+		if strings.TrimSpace(line) == "..." {
+			break
+		}
+		p.lines = append(p.lines, line)
+	}
+	p.next()
+	if p.i == len(p.lines) {
+		return map[string]interface{}{}, nil
+	}
+	indent := p.indent()
+	if indent != 0 {
+		return nil, p.errorf("the document must start at column 1")
+	}
+	v, err := p.node(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.next(); p.i < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s: the document must be a mapping", name)
+	}
+	return m, nil
+}
+
+type yamlParser struct {
+	name  string
+	lines []string
+	i     int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s:%d: %s", p.name, p.i+1, fmt.Sprintf(format, args...))
+}
+
+// next skips blank and comment lines.
+func (p *yamlParser) next() {
+	for p.i < len(p.lines) {
+		if s := strings.TrimSpace(p.lines[p.i]); s != "" && s[0] != '#' {
+			return
+		}
+		p.i++
+	}
+}
+
+func (p *yamlParser) indent() int {
+	line := p.lines[p.i]
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// node parses the block node whose first line, the current one, is
+// indented by indent.
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	content := p.lines[p.i][indent:]
+	if strings.HasPrefix(content, "\t") {
+		return nil, p.errorf("tabs cannot indent YAML")
+	}
+	if content == "-" || strings.HasPrefix(content, "- ") {
+		return p.sequence(indent)
+	}
+	if _, _, ok := yamlKey(content); ok {
+		return p.mapping(indent)
+	}
+	p.i++
+	return yamlScalar(content)
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	m := map[string]interface{}{}
+	for p.next(); p.i < len(p.lines) && p.indent() == indent; p.next() {
+		key, rest, ok := yamlKey(p.lines[p.i][indent:])
+		if !ok {
+			return nil, p.errorf("expected key: value")
+		}
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("%s is set twice", key)
+		}
+		v, err := p.value(indent, rest, true)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	list := []interface{}{}
+	for p.next(); p.i < len(p.lines) && p.indent() == indent; p.next() {
+		line := p.lines[p.i]
+		if rest := line[indent:]; rest != "-" && !strings.HasPrefix(rest, "- ") {
+			break
+		}
+		// An item that starts with "key:" is a mapping indented as far as
+		// its first key.
+		item := strings.TrimLeft(line[indent+1:], " ")
+		if _, _, ok := yamlKey(item); ok {
+			p.lines[p.i] = strings.Repeat(" ", len(line)-len(item)) + item
+			v, err := p.mapping(len(line) - len(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+			continue
+		}
+		v, err := p.value(indent, item, false)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// value parses what follows "key:" or "- " on the current line, rest, and
+// the lines that belong to it. A mapping value may be a sequence at the
+// indentation of its key.
+func (p *yamlParser) value(indent int, rest string, inMapping bool) (interface{}, error) {
+	rest = yamlStripComment(rest)
+	if strings.HasPrefix(rest, "|") || strings.HasPrefix(rest, ">") {
+		return p.blockScalar(indent, rest)
+	}
+	p.i++
+	if rest != "" {
+		return yamlScalar(rest)
+	}
+	p.next()
+	if p.i == len(p.lines) {
+		return nil, nil
+	}
+	child := p.indent()
+	if child > indent {
+		return p.node(child)
+	}
+	if content := p.lines[p.i][child:]; inMapping && child == indent && (content == "-" || strings.HasPrefix(content, "- ")) {
+		return p.sequence(child)
+	}
+	return nil, nil
+}
+
+var yamlBlockHeader = regexp.MustCompile(`^([|>])([+-]?)$`)
+
+// blockScalar reads a literal or folded block scalar whose header is on the
+// current line.
+func (p *yamlParser) blockScalar(indent int, header string) (interface{}, error) {
+	m := yamlBlockHeader.FindStringSubmatch(header)
+	if m == nil {
+		return nil, p.errorf("unsupported block scalar header %q", header)
+	}
+	p.i++
+	var lines []string
+	block := -1
+	for ; p.i < len(p.lines); p.i++ {
+		line := p.lines[p.i]
+		if strings.TrimSpace(line) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " "))
+		if block < 0 {
+			if n <= indent {
+				break
+			}
+			block = n
+		}
+		if n < block {
+			break
+		}
+		lines = append(lines, line[block:])
+	}
+	// Trailing blank lines are chomped below, not part of the next node.
+	trailing := 0
+	for trailing < len(lines) && lines[len(lines)-1-trailing] == "" {
+		trailing++
+	}
+	body := lines[:len(lines)-trailing]
+	var s string
+	if m[1] == "|" {
+		s = strings.Join(body, "\n")
+	} else {
+		for i, line := range body {
+			switch {
+			case i == 0:
+			case line == "" || body[i-1] == "":
+				s += "\n"
+			default:
+				s += " "
+			}
+			s += line
+		}
+	}
+	switch {
+	case len(body) == 0:
+	case m[2] == "-":
+	case m[2] == "+":
+		s += strings.Repeat("\n", trailing+1)
+	default:
+		s += "\n"
+	}
+	return s, nil
+}
+
+// yamlKey splits "key: rest" (or "key:" at the end of the line). The key may
+// be quoted.
+func yamlKey(s string) (key, rest string, ok bool) {
+	end := -1
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		n := yamlQuotedLen(s)
+		if n < 0 {
+			return "", "", false
+		}
+		k, err := yamlScalar(s[:n])
+		if err != nil {
+			return "", "", false
+		}
+		key, _ = k.(string)
+		end = n
+		if !strings.HasPrefix(strings.TrimLeft(s[end:], " "), ":") {
+			return "", "", false
+		}
+		end += strings.Index(s[end:], ":")
+	} else {
+		for i := 0; i < len(s); i++ {
+			if s[i] == '#' && i > 0 && s[i-1] == ' ' {
+				return "", "", false
+			}
+			if s[i] == ':' && (i+1 == len(s) || s[i+1] == ' ') {
+				end = i
+				break
+			}
+		}
+		if end <= 0 || s[0] == '[' || s[0] == '{' {
+			return "", "", false
+		}
+		key = strings.TrimSpace(s[:end])
+	}
+	rest = strings.TrimSpace(s[end+1:])
+	if rest != "" && rest[0] == '#' {
+		rest = ""
+	}
+	return key, rest, true
+}
+
+// yamlQuotedLen returns the length of the quoted scalar at the start of s,
+// or -1 if it does not end on this line.
+func yamlQuotedLen(s string) int {
+	q := s[0]
+	for i := 1; i < len(s); i++ {
+		switch {
+		case q == '"' && s[i] == '\\':
+			i++
+		case q == '\'' && s[i] == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == q:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// yamlStripComment removes a comment after a value.
+func yamlStripComment(s string) string {
+	s = strings.TrimSpace(s)
+	if s != "" && (s[0] == '"' || s[0] == '\'') {
+		if n := yamlQuotedLen(s); n > 0 {
+			if rest := strings.TrimSpace(s[n:]); rest == "" || rest[0] == '#' {
+				return s[:n]
+			}
+		}
+		return s
+	}
+	if s != "" && s[0] == '#' {
+		return ""
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return s
+}
+
+var (
+	yamlInt   = regexp.MustCompile(`^[-+]?[0-9]+$`)
+	yamlFloat = regexp.MustCompile(`^[-+]?([0-9]+\.[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`)
+)
+
+// yamlScalar parses a scalar or a one-line flow collection.
+func yamlScalar(s string) (interface{}, error) {
+	s = yamlStripComment(s)
+	if s == "" {
+		return nil, nil
+	}
+	switch s[0] {
+	case '"':
+		if yamlQuotedLen(s) != len(s) {
+			return nil, fmt.Errorf("invalid double-quoted scalar %s", s)
+		}
+		v, err := strconv.Unquote(strings.ReplaceAll(s, `\/`, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid double-quoted scalar %s", s)
+		}
+		return v, nil
+	case '\'':
+		if yamlQuotedLen(s) != len(s) {
+			return nil, fmt.Errorf("invalid single-quoted scalar %s", s)
+		}
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'"), nil
+	case '[', '{':
+		return yamlFlow(s)
+	case '&', '*', '!':
+		return nil, fmt.Errorf("anchors, aliases and tags are not supported: %s", s)
+	}
+	switch s {
+	case "null", "Null", "NULL", "~":
+		return nil, nil
+	case "true", "True", "TRUE":
+		return true, nil
+	case "false", "False", "FALSE":
+		return false, nil
+	}
+	if yamlInt.MatchString(s) || yamlFloat.MatchString(s) {
+		return json.Number(strings.TrimPrefix(s, "+")), nil
+	}
+	return s, nil
+}
+
+// yamlFlow parses a flow sequence or mapping that fits on one line.
+func yamlFlow(s string) (interface{}, error) {
+	open, close := s[0], byte(']')
+	if open == '{' {
+		close = '}'
+	}
+	if s[len(s)-1] != close {
+		return nil, fmt.Errorf("unterminated flow collection %s", s)
+	}
+	var items []string
+	depth, start := 0, 1
+	for i := 1; i < len(s)-1; i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			n := yamlQuotedLen(s[i:])
+			if n < 0 {
+				return nil, fmt.Errorf("unterminated string in %s", s)
+			}
+			i += n - 1
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(s[start : len(s)-1]); last != "" || len(items) > 0 {
+		items = append(items, s[start:len(s)-1])
+	}
+	if open == '[' {
+		list := []interface{}{}
+		for _, item := range items {
+			v, err := yamlScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return list, nil
+	}
+	m := map[string]interface{}{}
+	for _, item := range items {
+		key, rest, ok := yamlKey(strings.TrimSpace(item))
+		if !ok {
+			return nil, fmt.Errorf("expected key: value in %s", s)
+		}
+		v, err := yamlScalar(rest)
+		if err != nil {
+			return nil, err
+		}
+		m[key] = v
+	}
+	return m, nil
+}
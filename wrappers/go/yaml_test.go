@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	got, err := parseYAML(`---
+# an SM2 request
+message: hello world   # plain
+digest: "0123"
+count: 3
+detached: yes
+strict: true
+nothing: ~
+certificate: |
+  -----BEGIN CERTIFICATE-----
+  MIIB
+  -----END CERTIFICATE-----
+
+text: >-
+  folded
+  lines
+roots:
+  - a.pem
+  - 'b''s.pem'
+roots_flow: [x, "y", 7]
+subject: {cn: test, o: "Org, Inc"}
+items:
+  - message: m1
+    signature: s1
+  - message: m2
+options:
+  nested:
+    depth: 2
+`, "test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"message":     "hello world",
+		"digest":      "0123",
+		"count":       json.Number("3"),
+		"detached":    "yes",
+		"strict":      true,
+		"nothing":     nil,
+		"certificate": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n",
+		"text":        "folded lines",
+		"roots":       []interface{}{"a.pem", "b's.pem"},
+		"roots_flow":  []interface{}{"x", "y", json.Number("7")},
+		"subject":     map[string]interface{}{"cn": "test", "o": "Org, Inc"},
+		"items": []interface{}{
+			map[string]interface{}{"message": "m1", "signature": "s1"},
+			map[string]interface{}{"message": "m2"},
+		},
+		"options": map[string]interface{}{"nested": map[string]interface{}{"depth": json.Number("2")}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseYAML = %#v, want %#v", got, want)
+	}
+
+	for _, bad := range []string{
+		"- a\n- b\n",
+		"a: 1\na: 2\n",
+		"key: &anchor x\n",
+		"key: \"unterminated\n",
+		"  indented: 1\n",
+		"a:\n  b: 1\n c: 2\n",
+		"a: [1, 2\n",
+	} {
+		if _, err := parseYAML(bad, "bad.yaml"); err == nil {
+			t.Errorf("parseYAML(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/lihongjie0209/sm-bc-test/wrappers/go/internal/zuc"
+)
+
+// zucParams holds the inputs of the ZUC operations: for a 16-byte key the
+// parameters of the 3GPP algorithms 128-EEA3 and 128-EIA3, for a 32-byte
+// key the ZUC-256 IV.
+type zucParams struct {
+	key               []byte
+	count             uint32
+	bearer, direction uint8
+	iv                []byte
+}
+
+// zucRequest holds the key parameters and bit length shared by the ZUC
+// operations: the hex "key" and, for ZUC-128, the integers "count" (32
+// bits), "bearer" (5 bits) and "direction" (0 or 1) or, for ZUC-256, the
+// hex "iv".
+type zucRequest struct {
+	Key       hexBytes `json:"key" validate:"required"`
+	IV        hexBytes `json:"iv"`
+	Count     int      `json:"count"`
+	Bearer    int      `json:"bearer"`
+	Direction int      `json:"direction"`
+	Length    *int     `json:"length"`
+}
+
+// params checks the key parameters of r.
+func (r *zucRequest) params() (*zucParams, error) {
+	p := &zucParams{key: r.Key}
+	switch len(r.Key) {
+	case zuc.KeySize:
+	case zuc.Key256Size:
+		if r.IV == nil {
+			return nil, &codedError{codeMissingField, errors.New("missing required field \"iv\"")}
+		}
+		p.iv = r.IV
+		return p, nil
+	default:
+		return nil, &codedError{codeInvalidKeyLength,
+			fmt.Errorf("invalid ZUC key size %d, want %d or %d", len(r.Key), zuc.KeySize, zuc.Key256Size)}
+	}
+	if r.Count < 0 || r.Count > 0xffffffff {
+		return nil, fmt.Errorf("count must be a 32-bit unsigned integer, got %d", r.Count)
+	}
+	p.count = uint32(r.Count)
+	if r.Bearer < 0 || r.Bearer > 0x1f {
+		return nil, fmt.Errorf("bearer must be between 0 and 31, got %d", r.Bearer)
+	}
+	p.bearer = uint8(r.Bearer)
+	if r.Direction != 0 && r.Direction != 1 {
+		return nil, fmt.Errorf("direction must be 0 or 1, got %d", r.Direction)
+	}
+	p.direction = uint8(r.Direction)
+	return p, nil
+}
+
+// length returns "length", the message length in bits, which defaults to
+// all of data.
+func (r *zucRequest) length(data []byte) (int, error) {
+	if r.Length == nil {
+		return 8 * len(data), nil
+	}
+	if n := *r.Length; n < 0 || n > 8*len(data) {
+		return 0, fmt.Errorf("length must be between 0 and %d bits, got %d", 8*len(data), n)
+	}
+	return *r.Length, nil
+}
+
+// crypt encrypts or decrypts the first length bits of data.
+func (p *zucParams) crypt(data []byte, length int) ([]byte, error) {
+	if p.iv != nil {
+		return zuc.Crypt256(p.key, p.iv, data, length)
+	}
+	return zuc.EEA3(p.key, p.count, p.bearer, p.direction, data, length)
+}
+
+// mac returns the size-byte MAC of the first length bits of data.
+func (p *zucParams) mac(data []byte, length, size int) ([]byte, error) {
+	if p.iv != nil {
+		return zuc.MAC256(p.key, p.iv, data, length, size)
+	}
+	if size != zuc.MACSize {
+		return nil, fmt.Errorf("128-EIA3 MACs are %d bytes, got mac_length %d", zuc.MACSize, size)
+	}
+	return zuc.EIA3(p.key, p.count, p.bearer, p.direction, data, length)
+}
+
+// zucEncrypt encrypts "plaintext" (or "plaintext_hex" /
+// "plaintext_base64") with 128-EEA3 or ZUC-256. Only the first "length" bits are
+// encrypted; the rest of the last byte is cleared.
+func zucEncrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		zucRequest
+		Plaintext textBytes `json:"plaintext" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	p, err := req.params()
+	if err != nil {
+		return nil, err
+	}
+	length, err := req.length(req.Plaintext)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := p.crypt(req.Plaintext, length)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Output: hex.EncodeToString(ct)}, nil
+}
+
+// zucDecrypt decrypts the hex "ciphertext" with 128-EEA3 or ZUC-256 and returns the
+// plaintext in "plaintext_encoding".
+func zucDecrypt(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		zucRequest
+		plaintextEncoding
+		Ciphertext hexBytes `json:"ciphertext" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	p, err := req.params()
+	if err != nil {
+		return nil, err
+	}
+	ct := []byte(req.Ciphertext)
+	length, err := req.length(ct)
+	if err != nil {
+		return nil, err
+	}
+	pt, err := p.crypt(ct, length)
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	if res.Output, err = req.encode(pt); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// zucMAC computes the 128-EIA3 MAC, or the ZUC-256 MAC of "mac_length"
+// bytes, of the first "length" bits of "data" (UTF-8 text, or "data_hex" /
+// "data_base64"), or verifies "mac" like sm4CMAC. "mac_length" defaults
+// to the size of "mac", or 4.
+func zucMAC(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		zucRequest
+		Data      textBytes `json:"data" validate:"required"`
+		MAC       hexBytes  `json:"mac"`
+		MACLength *int      `json:"mac_length"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	p, err := req.params()
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(req.Data)
+	length, err := req.length(data)
+	if err != nil {
+		return nil, err
+	}
+	size := zuc.MACSize
+	switch {
+	case req.MACLength != nil:
+		size = *req.MACLength
+	case req.MAC != nil:
+		size = len(req.MAC)
+	}
+	mac, err := p.mac(data, length, size)
+	if err != nil {
+		return nil, err
+	}
+	return macResult(req.MAC, mac)
+}
+
+// zucKeystream returns "length" bytes of raw ZUC-128 or ZUC-256 keystream
+// for the hex "key" and "iv".
+func zucKeystream(in map[string]interface{}) (*Result, error) {
+	var req struct {
+		Key    hexBytes `json:"key" validate:"required"`
+		IV     hexBytes `json:"iv" validate:"required"`
+		Length int      `json:"length" validate:"required"`
+	}
+	if err := decodeRequest(in, &req); err != nil {
+		return nil, err
+	}
+	if err := checkKeyLength("length", req.Length); err != nil {
+		return nil, err
+	}
+	var c *zuc.Cipher
+	var err error
+	if len(req.Key) == zuc.Key256Size {
+		c, err = zuc.NewCipher256(req.Key, req.IV)
+	} else {
+		c, err = zuc.NewCipher(req.Key, req.IV)
+	}
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, req.Length)
+	c.XORKeyStream(out, out)
+	return &Result{Output: hex.EncodeToString(out)}, nil
+}
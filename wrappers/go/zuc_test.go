@@ -0,0 +1,100 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestZUCEncrypt(t *testing.T) {
+	// Test set 1 of the 3GPP 128-EEA3 test data: 193 bits.
+	in := map[string]interface{}{
+		"key": "173d14ba5003731d7a60049470f00a29", "count": 0x66035492, "bearer": 0xf, "direction": 0,
+		"plaintext_hex": "6cf65340735552ab0c9752fa6f9025fe0bd675d9005875b200", "length": 193,
+	}
+	const want = "a6c85fc66afb8533aafc2518dfe784940ee1e4b030238cc800"
+	enc := mustCall(t, "zuc", "encrypt", in)
+	if enc.Output != want {
+		t.Fatalf("ciphertext %s, want %s", enc.Output, want)
+	}
+	in["ciphertext"], in["plaintext_encoding"] = want, "hex"
+	if dec := mustCall(t, "zuc", "decrypt", in); dec.Output != "6cf65340735552ab0c9752fa6f9025fe0bd675d9005875b200" {
+		t.Errorf("plaintext %s", dec.Output)
+	}
+
+	text := map[string]interface{}{"key": "00000000000000000000000000000000", "count": 7, "bearer": 3, "direction": 1, "plaintext": "hello"}
+	enc = mustCall(t, "zuc", "encrypt", text)
+	text["ciphertext"] = enc.Output
+	if dec := mustCall(t, "zuc", "decrypt", text); dec.Output != "hello" {
+		t.Errorf("round trip: %q", dec.Output)
+	}
+
+	for field, value := range map[string]interface{}{"bearer": 32, "direction": 2, "count": -1, "length": 41, "key": "00"} {
+		bad := map[string]interface{}{"key": "00000000000000000000000000000000", "plaintext": "hello"}
+		bad[field] = value
+		mustFail(t, "zuc", "encrypt", bad)
+	}
+}
+
+func TestZUCMAC(t *testing.T) {
+	// Test set 2 of the 3GPP 128-EIA3 test data: 90 bits.
+	in := map[string]interface{}{
+		"key": "47054125561eb2dda94059da05097850", "count": 0x561eb2dd, "bearer": 0x14, "direction": 0,
+		"data_hex": "000000000000000000000000", "length": 90,
+	}
+	if res := mustCall(t, "zuc", "mac", in); res.Output != "6719a088" {
+		t.Fatalf("MAC %s", res.Output)
+	}
+	in["mac"] = "6719a088"
+	if res := mustCall(t, "zuc", "mac", in); !*res.Valid {
+		t.Error("MAC does not verify")
+	}
+	in["direction"] = 1
+	if res := mustCall(t, "zuc", "mac", in); *res.Valid {
+		t.Error("MAC verifies in the other direction")
+	}
+	in["mac"] = "6719a0"
+	mustFail(t, "zuc", "mac", in)
+}
+
+func TestZUCKeystream(t *testing.T) {
+	res := mustCall(t, "zuc", "keystream", map[string]interface{}{
+		"key": "ffffffffffffffffffffffffffffffff", "iv": "ffffffffffffffffffffffffffffffff", "length": 8,
+	})
+	if res.Output != "0657cfa07096398b" {
+		t.Errorf("keystream %s", res.Output)
+	}
+}
+
+func TestZUC256(t *testing.T) {
+	zeros := strings.Repeat("00", 32)
+	res := mustCall(t, "zuc", "keystream", map[string]interface{}{"key": zeros, "iv": strings.Repeat("00", 23), "length": 8})
+	if res.Output != "58d03ad62e032ce2" {
+		t.Errorf("keystream %s", res.Output)
+	}
+
+	in := map[string]interface{}{"key": zeros, "iv": strings.Repeat("00", 25), "data_hex": strings.Repeat("00", 50)}
+	for size, want := range map[int]string{4: "9b972a74", 8: "673e54990034d38c", 16: "d85e54bbcb9600967084c952a1654b26"} {
+		in["mac_length"] = size
+		if res := mustCall(t, "zuc", "mac", in); res.Output != want {
+			t.Errorf("%d-byte MAC %s, want %s", size, res.Output, want)
+		}
+	}
+	delete(in, "mac_length")
+	in["mac"] = "673e54990034d38c"
+	if res := mustCall(t, "zuc", "mac", in); !*res.Valid {
+		t.Error("64-bit MAC does not verify")
+	}
+	in["mac_length"] = 12
+	mustFail(t, "zuc", "mac", in)
+	mustFail(t, "zuc", "mac", map[string]interface{}{"key": strings.Repeat("00", 16), "data": "x", "mac_length": 8})
+
+	enc := mustCall(t, "zuc", "encrypt", map[string]interface{}{"key": zeros, "iv": strings.Repeat("00", 23), "plaintext": "hello"})
+	if enc.Output != "30b556ba41" {
+		t.Errorf("ciphertext %s", enc.Output)
+	}
+	dec := mustCall(t, "zuc", "decrypt", map[string]interface{}{"key": zeros, "iv": strings.Repeat("00", 23), "ciphertext": enc.Output})
+	if dec.Output != "hello" {
+		t.Errorf("plaintext %q", dec.Output)
+	}
+	mustFail(t, "zuc", "encrypt", map[string]interface{}{"key": zeros, "plaintext": "hello"})
+}